@@ -0,0 +1,493 @@
+// Package client is a Go client for the tiny-bank HTTP API. Its methods
+// mirror service.Service, so callers do not need to know which verb and path
+// each operation uses. Writes carry an Idempotency-Key that is reused across
+// retries, which makes retrying a transfer or withdrawal safe.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	usersPath = "/api/v1/users/"
+	adminPath = "/api/v1/admin/"
+
+	idempotencyKeyHeader = "Idempotency-Key"
+	jsonContentType      = "application/json"
+
+	defaultRetries    = 2
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+type idempotencyKeyContextKey struct{}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a request is retried after a transport
+// error, a 409 from a concurrent attempt with the same idempotency key, a 429
+// or a 502, 503 or 504.
+func WithRetries(retries int) Option {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// WithBackoff sets the wait before the first retry and its cap. The wait
+// doubles on every further retry.
+func WithBackoff(backoff time.Duration, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithIdempotencyKey sets the key sent with writes made under ctx. Callers
+// that persist the key can retry safely even across process restarts;
+// without it every call gets a fresh key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest) (CreateUserResponse, error) {
+	res := CreateUserResponse{}
+
+	err := c.doJSON(ctx, http.MethodPost, usersPath, req, &res)
+
+	return res, err
+}
+
+func (c *Client) CreateAccount(ctx context.Context, req CreateAccountRequest) (CreateAccountResponse, error) {
+	res := CreateAccountResponse{}
+
+	err := c.doJSON(ctx, http.MethodPost, usersPath+path(req.UserID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) DeactivateUser(ctx context.Context, req DeactivateUserRequest) (DeactivateUserResponse, error) {
+	res := DeactivateUserResponse{}
+
+	err := c.doJSON(ctx, http.MethodDelete, usersPath+path(req.UserID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) ReactivateUser(ctx context.Context, req ReactivateUserRequest) error {
+	return c.doJSON(ctx, http.MethodPost, usersPath+path(req.UserID, "reactivate"), nil, nil)
+}
+
+func (c *Client) SubmitKYC(ctx context.Context, req SubmitKYCRequest) (KYCResponse, error) {
+	res := KYCResponse{}
+
+	err := c.doJSON(ctx, http.MethodPut, usersPath+path(req.UserID, "kyc"), req, &res)
+
+	return res, err
+}
+
+func (c *Client) KYC(ctx context.Context, req KYCRequest) (KYCResponse, error) {
+	res := KYCResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, usersPath+path(req.UserID, "kyc"), nil, &res)
+
+	return res, err
+}
+
+func (c *Client) Accounts(ctx context.Context, req AccountsRequest) (AccountsResponse, error) {
+	res := AccountsResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, usersPath+path(req.UserID, "accounts"), nil, &res)
+
+	return res, err
+}
+
+func (c *Client) Deposit(ctx context.Context, req DepositRequest) (DepositResponse, error) {
+	res := DepositResponse{}
+
+	err := c.doJSON(ctx, http.MethodPut, usersPath+path(req.UserID, "accounts", req.AccountID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) Withdraw(ctx context.Context, req WithdrawRequest) (WithdrawResponse, error) {
+	res := WithdrawResponse{}
+
+	err := c.doJSON(ctx, http.MethodPatch, usersPath+path(req.UserID, "accounts", req.AccountID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) Transfer(ctx context.Context, req TransferRequest) (TransferResponse, error) {
+	res := TransferResponse{}
+
+	err := c.doJSON(ctx, http.MethodPost, usersPath+path(req.SenderUserID, "accounts", req.SenderAccountID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) Balance(ctx context.Context, req BalanceRequest) (BalanceResponse, error) {
+	res := BalanceResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, usersPath+path(req.UserID, "accounts", req.AccountID), nil, &res)
+
+	return res, err
+}
+
+func (c *Client) Transactions(ctx context.Context, req TransactionsRequest) (TransactionsResponse, error) {
+	target := usersPath + path(req.UserID, "accounts", req.AccountID, "transactions")
+
+	query := url.Values{}
+
+	if req.Query != "" {
+		query.Set("q", req.Query)
+	}
+
+	if req.Reference != "" {
+		query.Set("reference", req.Reference)
+	}
+
+	for key, value := range req.Metadata {
+		query.Set("metadata["+key+"]", value)
+	}
+
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	res := TransactionsResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, target, nil, &res)
+
+	return res, err
+}
+
+func (c *Client) AddHolder(ctx context.Context, req AddHolderRequest) error {
+	return c.doJSON(ctx, http.MethodPost, usersPath+path(req.UserID, "accounts", req.AccountID, "holders"), req, nil)
+}
+
+func (c *Client) RemoveHolder(ctx context.Context, req RemoveHolderRequest) error {
+	return c.doJSON(ctx, http.MethodDelete, usersPath+path(req.UserID, "accounts", req.AccountID, "holders", req.HolderUserID), nil, nil)
+}
+
+func (c *Client) Holders(ctx context.Context, req HoldersRequest) (HoldersResponse, error) {
+	res := HoldersResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, usersPath+path(req.UserID, "accounts", req.AccountID, "holders"), nil, &res)
+
+	return res, err
+}
+
+func (c *Client) UpdateAccountRules(ctx context.Context, req UpdateAccountRulesRequest) error {
+	return c.doJSON(ctx, http.MethodPut, usersPath+path(req.UserID, "accounts", req.AccountID, "rules"), req, nil)
+}
+
+func (c *Client) SubmitBatch(ctx context.Context, req SubmitBatchRequest) (BatchResponse, error) {
+	query := url.Values{}
+
+	query.Set("format", req.Format)
+	query.Set("mode", "best_effort")
+
+	if req.AllOrNothing {
+		query.Set("mode", "all_or_nothing")
+	}
+
+	if req.Async {
+		query.Set("async", "true")
+	}
+
+	contentType := "text/csv"
+
+	if req.Format == BatchFormatPain001 {
+		contentType = "application/xml"
+	}
+
+	res := BatchResponse{}
+
+	err := c.do(ctx, http.MethodPost, usersPath+path(req.UserID, "accounts", req.AccountID, "batches")+"?"+query.Encode(), contentType, req.File, &res)
+
+	return res, err
+}
+
+func (c *Client) BatchStatus(ctx context.Context, req BatchStatusRequest) (BatchResponse, error) {
+	res := BatchResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, usersPath+path(req.UserID, "accounts", req.AccountID, "batches", req.BatchID), nil, &res)
+
+	return res, err
+}
+
+func (c *Client) CreateProduct(ctx context.Context, req CreateProductRequest) (ProductResponse, error) {
+	res := ProductResponse{}
+
+	err := c.doJSON(ctx, http.MethodPost, adminPath+"products", req, &res)
+
+	return res, err
+}
+
+func (c *Client) UpdateProduct(ctx context.Context, req UpdateProductRequest) (ProductResponse, error) {
+	res := ProductResponse{}
+
+	err := c.doJSON(ctx, http.MethodPut, adminPath+path("products", req.ProductID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) Product(ctx context.Context, req ProductRequest) (ProductResponse, error) {
+	target := adminPath + path("products", req.ProductID)
+
+	if req.Version > 0 {
+		target += "?version=" + strconv.Itoa(req.Version)
+	}
+
+	res := ProductResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, target, nil, &res)
+
+	return res, err
+}
+
+func (c *Client) Products(ctx context.Context, req ProductsRequest) (ProductsResponse, error) {
+	res := ProductsResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, adminPath+"products", nil, &res)
+
+	return res, err
+}
+
+func (c *Client) PutTier(ctx context.Context, req PutTierRequest) (TierResponse, error) {
+	res := TierResponse{}
+
+	err := c.doJSON(ctx, http.MethodPut, adminPath+path("tiers", req.TierID), req, &res)
+
+	return res, err
+}
+
+func (c *Client) Tier(ctx context.Context, req TierRequest) (TierResponse, error) {
+	res := TierResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, adminPath+path("tiers", req.TierID), nil, &res)
+
+	return res, err
+}
+
+func (c *Client) Tiers(ctx context.Context, req TiersRequest) (TiersResponse, error) {
+	res := TiersResponse{}
+
+	err := c.doJSON(ctx, http.MethodGet, adminPath+"tiers", nil, &res)
+
+	return res, err
+}
+
+func (c *Client) UpdateUserTier(ctx context.Context, req UpdateUserTierRequest) error {
+	return c.doJSON(ctx, http.MethodPut, adminPath+path("users", req.UserID, "tier"), req, nil)
+}
+
+func (c *Client) UpdateUserStatus(ctx context.Context, req UpdateUserStatusRequest) error {
+	return c.doJSON(ctx, http.MethodPut, adminPath+path("users", req.UserID, "status"), req, nil)
+}
+
+func (c *Client) Reconcile(ctx context.Context) (ReconciliationReport, error) {
+	res := ReconciliationReport{}
+
+	err := c.doJSON(ctx, http.MethodPost, adminPath+"reconciliations", nil, &res)
+
+	return res, err
+}
+
+func (c *Client) doJSON(ctx context.Context, method string, target string, req any, res any) error {
+	var body []byte
+
+	if req != nil {
+		var err error
+
+		body, err = json.Marshal(req)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.do(ctx, method, target, jsonContentType, body, res)
+}
+
+// do sends the request, retrying transient failures with the same
+// idempotency key, and decodes the response into res.
+func (c *Client) do(ctx context.Context, method string, target string, contentType string, body []byte, res any) error {
+	key := ""
+
+	if method != http.MethodGet {
+		key, _ = ctx.Value(idempotencyKeyContextKey{}).(string)
+
+		if key == "" {
+			key = uuid.NewString()
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, payload, err := c.send(ctx, method, target, contentType, body, key)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if attempt >= c.retries || (err == nil && !retryable(status, payload)) {
+			if err != nil {
+				return err
+			}
+
+			return decode(status, payload, res)
+		}
+
+		err = c.wait(ctx, attempt)
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, target string, contentType string, body []byte, key string) (int, []byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+target, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	if key != "" {
+		httpReq.Header.Set(idempotencyKeyHeader, key)
+	}
+
+	httpRes, err := c.httpClient.Do(httpReq)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer httpRes.Body.Close()
+
+	buf := bytes.Buffer{}
+
+	_, err = buf.ReadFrom(httpRes.Body)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return httpRes.StatusCode, buf.Bytes(), nil
+}
+
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	backoff := c.backoff << attempt
+
+	if backoff > c.maxBackoff || backoff <= 0 {
+		backoff = c.maxBackoff
+	}
+
+	timer := time.NewTimer(backoff)
+
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryable reports whether a failed response may succeed when sent again.
+// Only a 409 for a request with the same key still running is retried; any
+// other conflict is an outcome of the operation itself.
+func retryable(status int, payload []byte) bool {
+	switch status {
+	case http.StatusConflict:
+		return errorMessage(status, payload) == idempotencyInProgressMessage
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func decode(status int, payload []byte, res any) error {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		if res == nil || len(payload) == 0 {
+			return nil
+		}
+
+		return json.Unmarshal(payload, res)
+	}
+
+	return newError(status, errorMessage(status, payload))
+}
+
+func errorMessage(status int, payload []byte) string {
+	body := struct {
+		Error string `json:"error"`
+	}{}
+
+	err := json.Unmarshal(payload, &body)
+
+	if err != nil || body.Error == "" {
+		return strings.ToLower(http.StatusText(status))
+	}
+
+	return body.Error
+}
+
+func path(segments ...string) string {
+	escaped := make([]string, len(segments))
+
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(escaped, "/")
+}
@@ -0,0 +1,493 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/batch"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/handler"
+	"github.com/hetfdex/tiny-bank/internal/reconciler"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/batchrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+	"github.com/hetfdex/tiny-bank/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lossyTransport forwards every request but drops the response of the
+// first write, as if the connection broke after the server handled it.
+type lossyTransport struct {
+	mux     sync.Mutex
+	dropped bool
+	keys    []string
+	replays []string
+}
+
+func (t *lossyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := http.DefaultTransport.RoundTrip(req)
+
+	if err != nil || req.Method == http.MethodGet {
+		return res, err
+	}
+
+	t.mux.Lock()
+
+	defer t.mux.Unlock()
+
+	t.keys = append(t.keys, req.Header.Get(idempotencyKeyHeader))
+	t.replays = append(t.replays, res.Header.Get(handler.IdempotentReplayHeader))
+
+	if !t.dropped {
+		t.dropped = true
+
+		res.Body.Close()
+
+		return nil, errors.New("connection reset")
+	}
+
+	return res, nil
+}
+
+func TestClient_Ok(t *testing.T) {
+	c := New(setupServer(t))
+
+	ctx := context.Background()
+
+	userID, accountID := verifiedAccount(t, c, "joe")
+
+	receiverUserID, receiverAccountID := verifiedAccount(t, c, "mary")
+
+	depositRes, err := c.Deposit(
+		ctx,
+		DepositRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    NewMoney(5000, EUR),
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewMoney(5000, EUR), depositRes.Balance)
+
+	withdrawRes, err := c.Withdraw(
+		ctx,
+		WithdrawRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    NewMoney(1000, EUR),
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewMoney(4000, EUR), withdrawRes.Balance)
+
+	transferRes, err := c.Transfer(
+		ctx,
+		TransferRequest{
+			SenderUserID:      userID,
+			SenderAccountID:   accountID,
+			ReceiverUserID:    receiverUserID,
+			ReceiverAccountID: receiverAccountID,
+			Amount:            NewMoney(1500, EUR),
+			TransactionDetails: TransactionDetails{
+				Description: "Dinner",
+				Reference:   "RF18 5390 0754 7034",
+				Metadata: map[string]string{
+					"split": "2",
+				},
+			},
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewMoney(2500, EUR), transferRes.Balance)
+
+	balanceRes, err := c.Balance(
+		ctx,
+		BalanceRequest{
+			UserID:    receiverUserID,
+			AccountID: receiverAccountID,
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewMoney(1500, EUR), balanceRes.Balance)
+
+	transactionsRes, err := c.Transactions(
+		ctx,
+		TransactionsRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, 3, len(transactionsRes.Transactions))
+	assert.Equal(t, domain.OperationTransfer, transactionsRes.Transactions[2].Operation)
+
+	transactionsRes, err = c.Transactions(
+		ctx,
+		TransactionsRequest{
+			UserID:    receiverUserID,
+			AccountID: receiverAccountID,
+			Reference: "RF18 5390 0754 7034",
+			Metadata: map[string]string{
+				"split": "2",
+			},
+		},
+	)
+
+	require.Nil(t, err)
+	require.Equal(t, 1, len(transactionsRes.Transactions))
+	assert.Equal(t, "Dinner", transactionsRes.Transactions[0].Description)
+
+	accountsRes, err := c.Accounts(
+		ctx,
+		AccountsRequest{
+			UserID: userID,
+		},
+	)
+
+	require.Nil(t, err)
+	require.Equal(t, 1, len(accountsRes.Accounts))
+	assert.NotEmpty(t, accountsRes.Accounts[0].IBAN)
+
+	accountsRes.Accounts[0].IBAN = ""
+
+	assert.Equal(t, []AccountResponse{{AccountID: accountID, ProductID: "checking", ProductVersion: 1, Role: RoleOwner, Balance: NewMoney(2500, EUR)}}, accountsRes.Accounts)
+
+	report, err := c.Reconcile(ctx)
+
+	require.Nil(t, err)
+	assert.True(t, report.Consistent)
+}
+
+func TestClient_Errors(t *testing.T) {
+	c := New(setupServer(t))
+
+	ctx := context.Background()
+
+	userID, accountID := verifiedAccount(t, c, "joe")
+
+	_, err := c.Withdraw(
+		ctx,
+		WithdrawRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    NewMoney(100, EUR),
+		},
+	)
+
+	var apiErr *Error
+
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, "insuficient funds", apiErr.Message)
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	_, err = c.Balance(
+		ctx,
+		BalanceRequest{
+			UserID:    "9f1c2f52-6a39-4a55-8d1b-0d6bd0f3b1a4",
+			AccountID: accountID,
+		},
+	)
+
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	createUserRes, err := c.CreateUser(
+		ctx,
+		CreateUserRequest{
+			Name: "ann",
+		},
+	)
+
+	require.Nil(t, err)
+
+	err = c.UpdateUserStatus(
+		ctx,
+		UpdateUserStatusRequest{
+			UserID: createUserRes.UserID,
+			Status: UserVerified,
+		},
+	)
+
+	assert.True(t, errors.Is(err, ErrUserStatus))
+
+	_, err = c.Deposit(
+		ctx,
+		DepositRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+}
+
+func TestClient_RetryReusesIdempotencyKey(t *testing.T) {
+	transport := &lossyTransport{}
+
+	c := New(
+		setupServer(t),
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	userID, accountID := verifiedAccount(t, c, "joe")
+
+	transport.mux.Lock()
+
+	transport.keys = nil
+	transport.replays = nil
+	transport.dropped = false
+
+	transport.mux.Unlock()
+
+	res, err := c.Deposit(
+		context.Background(),
+		DepositRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    NewMoney(1000, EUR),
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewMoney(1000, EUR), res.Balance)
+
+	assert.Equal(t, 2, len(transport.keys))
+	assert.NotEmpty(t, transport.keys[0])
+	assert.Equal(t, transport.keys[0], transport.keys[1])
+	assert.Equal(t, []string{"", "true"}, transport.replays)
+
+	balanceRes, err := c.Balance(
+		context.Background(),
+		BalanceRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, NewMoney(1000, EUR), balanceRes.Balance)
+}
+
+func TestClient_RetryUnavailable(t *testing.T) {
+	attempts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Write([]byte(`{"tiers":[]}`))
+	}))
+
+	t.Cleanup(server.Close)
+
+	c := New(server.URL, WithBackoff(time.Millisecond, time.Millisecond))
+
+	res, err := c.Tiers(context.Background(), TiersRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, TiersResponse{Tiers: []TierResponse{}}, res)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+
+	_, err = New(server.URL, WithRetries(1), WithBackoff(time.Millisecond, time.Millisecond)).Tiers(context.Background(), TiersRequest{})
+
+	var apiErr *Error
+
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestClient_Conflicts(t *testing.T) {
+	attempts := 0
+
+	responses := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message := responses[attempts]
+
+		attempts++
+
+		switch message {
+		case "":
+			w.Write([]byte(`{"tiers":[]}`))
+		case idempotencyReusedMessage:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"` + message + `"}`))
+		default:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"` + message + `"}`))
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	c := New(server.URL, WithBackoff(time.Millisecond, time.Millisecond))
+
+	responses = []string{idempotencyInProgressMessage, ""}
+
+	_, err := c.Tiers(context.Background(), TiersRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	responses = []string{"auto-save rule changed", ""}
+
+	_, err = c.Tiers(context.Background(), TiersRequest{})
+
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.False(t, errors.Is(err, ErrIdempotencyConflict))
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	responses = []string{idempotencyReusedMessage}
+
+	_, err = c.Tiers(context.Background(), TiersRequest{})
+
+	assert.True(t, errors.Is(err, ErrIdempotencyConflict))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestClient_ErrContextCanceled(t *testing.T) {
+	c := New(setupServer(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	_, err := c.CreateUser(
+		ctx,
+		CreateUserRequest{
+			Name: "joe",
+		},
+	)
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+func setupServer(t *testing.T) string {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := userrepo.New(map[string]domain.User{})
+	accountRepo := accountrepo.New(map[string]domain.Account{})
+
+	svc := service.New(
+		userRepo,
+		accountRepo,
+		productrepo.New(map[string][]domain.Product{}),
+		tierrepo.New(map[string]domain.Tier{}),
+	)
+
+	_, err := svc.CreateProduct(
+		service.CreateProductRequest{
+			ProductID: "checking",
+			ProductTerms: service.ProductTerms{
+				Name: "Checking",
+				Type: domain.ProductChecking,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+					domain.OperationTransferIn,
+					domain.OperationTransferOut,
+				},
+			},
+		},
+	)
+
+	require.Nil(t, err)
+
+	handlers := []handler.Handler{
+		handler.New(svc),
+		handler.NewBatch(batch.New(svc, batchrepo.New(map[string]domain.Batch{}))),
+		handler.NewReconciler(reconciler.New(userRepo, accountRepo)),
+	}
+
+	router := gin.New()
+
+	router.Use(handler.Idempotency(time.Hour))
+
+	router.Use(handler.Validation(handler.Spec(handlers...)))
+
+	for _, hdl := range handlers {
+		hdl.ConfigHandlers(router)
+	}
+
+	server := httptest.NewServer(router)
+
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func verifiedAccount(t *testing.T, c *Client, name string) (string, string) {
+	ctx := context.Background()
+
+	createUserRes, err := c.CreateUser(
+		ctx,
+		CreateUserRequest{
+			Name: name,
+		},
+	)
+
+	require.Nil(t, err)
+
+	_, err = c.SubmitKYC(
+		ctx,
+		SubmitKYCRequest{
+			UserID:      createUserRes.UserID,
+			DateOfBirth: "1990-01-02",
+			Address: AddressTerms{
+				Line1:      "1 Main Street",
+				City:       "Lisbon",
+				PostalCode: "1000-001",
+				Country:    "PT",
+			},
+			IDNumber: "X1234567",
+		},
+	)
+
+	require.Nil(t, err)
+
+	err = c.UpdateUserStatus(
+		ctx,
+		UpdateUserStatusRequest{
+			UserID: createUserRes.UserID,
+			Status: UserVerified,
+		},
+	)
+
+	require.Nil(t, err)
+
+	createAccountRes, err := c.CreateAccount(
+		ctx,
+		CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	require.Nil(t, err)
+
+	return createUserRes.UserID, createAccountRes.AccountID
+}
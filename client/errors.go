@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrInvalidRequest      = errors.New("invalid request")
+	ErrNotFound            = errors.New("not found")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrClosed              = errors.New("closed")
+	ErrApprovalRequired    = errors.New("approval required")
+	ErrLimitReached        = errors.New("limit reached")
+	ErrUserStatus          = errors.New("user status does not allow operation")
+	ErrProductRule         = errors.New("product rule")
+	ErrIdempotencyConflict = errors.New("idempotency conflict")
+	ErrConflict            = errors.New("conflict")
+)
+
+// The messages the server answers an Idempotency-Key conflict with, told
+// apart from the 409s and 422s of the operations themselves.
+const (
+	idempotencyInProgressMessage = "request with this idempotency key in progress"
+	idempotencyReusedMessage     = "idempotency key reused with a different request"
+)
+
+// Error is a non-2xx response. errors.Is matches it against the Err values
+// above, so callers can branch on the kind of failure without parsing
+// messages.
+type Error struct {
+	StatusCode int
+	Message    string
+	kind       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("tiny-bank: %s (status %d)", e.Message, e.StatusCode)
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+func newError(statusCode int, message string) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Message:    message,
+		kind:       errorKind(statusCode, message),
+	}
+}
+
+func errorKind(statusCode int, message string) error {
+	switch {
+	case message == idempotencyInProgressMessage || message == idempotencyReusedMessage:
+		return ErrIdempotencyConflict
+	case statusCode == http.StatusBadRequest || strings.HasPrefix(message, "invalid "):
+		return ErrInvalidRequest
+	case statusCode == http.StatusNotFound || strings.HasSuffix(message, " not found"):
+		return ErrNotFound
+	case message == "unauthorized account id" || message == "insufficient role":
+		return ErrUnauthorized
+	case strings.HasPrefix(message, "insuficient funds"):
+		return ErrInsufficientFunds
+	case strings.HasSuffix(message, " closed"):
+		return ErrClosed
+	case message == "approval required":
+		return ErrApprovalRequired
+	case strings.HasPrefix(message, "velocity limit reached") || message == "monthly withdrawal limit reached":
+		return ErrLimitReached
+	case strings.Contains(message, " user cannot ") || strings.HasPrefix(message, "cannot move "):
+		return ErrUserStatus
+	case message == "operation not allowed by product" || message == "minimum balance":
+		return ErrProductRule
+	case statusCode == http.StatusConflict:
+		return ErrConflict
+	default:
+		return nil
+	}
+}
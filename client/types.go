@@ -0,0 +1,105 @@
+package client
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/batch"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/reconciler"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+// The API types are aliases of the server's own, so requests and responses
+// cannot drift from what the handlers bind and return.
+type (
+	Money       = money.Money
+	Currency    = money.Currency
+	Role        = domain.Role
+	UserStatus  = domain.UserStatus
+	ProductType = domain.ProductType
+	LimitScope  = domain.LimitScope
+
+	CreateUserRequest         = service.CreateUserRequest
+	CreateUserResponse        = service.CreateUserResponse
+	CreateAccountRequest      = service.CreateAccountRequest
+	CreateAccountResponse     = service.CreateAccountResponse
+	DeactivateUserRequest     = service.DeactivateUserRequest
+	DeactivateUserResponse    = service.DeactivateUserResponse
+	ClosingAccountResponse    = service.ClosingAccountResponse
+	ReactivateUserRequest     = service.ReactivateUserRequest
+	AddressTerms              = service.AddressTerms
+	SubmitKYCRequest          = service.SubmitKYCRequest
+	KYCRequest                = service.KYCRequest
+	KYCResponse               = service.KYCResponse
+	UpdateUserStatusRequest   = service.UpdateUserStatusRequest
+	AccountsRequest           = service.AccountsRequest
+	AccountsResponse          = service.AccountsResponse
+	AccountResponse           = service.AccountResponse
+	DepositRequest            = service.DepositRequest
+	DepositResponse           = service.DepositResponse
+	WithdrawRequest           = service.WithdrawRequest
+	WithdrawResponse          = service.WithdrawResponse
+	TransferRequest           = service.TransferRequest
+	TransferResponse          = service.TransferResponse
+	BalanceRequest            = service.BalanceRequest
+	BalanceResponse           = service.BalanceResponse
+	TransactionsRequest       = service.TransactionsRequest
+	TransactionsResponse      = service.TransactionsResponse
+	TransactionResponse       = service.TransactionResponse
+	TransactionDetails        = service.TransactionDetails
+	AddHolderRequest          = service.AddHolderRequest
+	RemoveHolderRequest       = service.RemoveHolderRequest
+	HoldersRequest            = service.HoldersRequest
+	HoldersResponse           = service.HoldersResponse
+	HolderResponse            = service.HolderResponse
+	UpdateAccountRulesRequest = service.UpdateAccountRulesRequest
+	ProductTerms              = service.ProductTerms
+	CreateProductRequest      = service.CreateProductRequest
+	UpdateProductRequest      = service.UpdateProductRequest
+	ProductRequest            = service.ProductRequest
+	ProductResponse           = service.ProductResponse
+	ProductsRequest           = service.ProductsRequest
+	ProductsResponse          = service.ProductsResponse
+	LimitTerms                = service.LimitTerms
+	PutTierRequest            = service.PutTierRequest
+	TierRequest               = service.TierRequest
+	TierResponse              = service.TierResponse
+	TiersRequest              = service.TiersRequest
+	TiersResponse             = service.TiersResponse
+	UpdateUserTierRequest     = service.UpdateUserTierRequest
+
+	SubmitBatchRequest = batch.SubmitRequest
+	BatchStatusRequest = batch.StatusRequest
+	BatchResponse      = batch.BatchResponse
+
+	ReconciliationReport = reconciler.Report
+)
+
+const (
+	EUR = money.EUR
+	USD = money.USD
+	GBP = money.GBP
+	CHF = money.CHF
+	JPY = money.JPY
+
+	RoleOwner   = domain.RoleOwner
+	RoleCoOwner = domain.RoleCoOwner
+	RoleViewer  = domain.RoleViewer
+
+	UserPending     = domain.UserPending
+	UserKYCInReview = domain.UserKYCInReview
+	UserVerified    = domain.UserVerified
+	UserRestricted  = domain.UserRestricted
+	UserSuspended   = domain.UserSuspended
+	UserClosed      = domain.UserClosed
+
+	BatchFormatCSV     = batch.FormatCSV
+	BatchFormatPain001 = batch.FormatPain001
+)
+
+func NewMoney(minor int64, currency Currency) Money {
+	return money.New(minor, currency)
+}
+
+func ParseMoney(value string, currency Currency) (Money, error) {
+	return money.Parse(value, currency)
+}
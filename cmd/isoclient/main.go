@@ -0,0 +1,136 @@
+// Command isoclient is a test acquirer for the ISO 8583 card host. It sends
+// the messages of one card flow over a single connection and prints each
+// response, for example:
+//
+//	go run ./cmd/isoclient -pan 4999990123456789 -expiry 2910 -cvv 123 -amount 1250 -flow completion
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/card"
+	"github.com/hetfdex/tiny-bank/internal/iso8583"
+)
+
+// flows are the MTIs sent, in order, for each flow.
+var flows = map[string][]string{
+	"auth":       {"0100"},
+	"purchase":   {"0200"},
+	"completion": {"0100", "0200"},
+	"reversal":   {"0100", "0400"},
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8583", "card host address")
+	specPath := flag.String("spec", "", "JSON field spec (default spec if empty)")
+	pan := flag.String("pan", "", "card number")
+	expiry := flag.String("expiry", "", "card expiry as YYMM")
+	cvv := flag.String("cvv", "", "card CVV, sent in field 48")
+	amount := flag.Int64("amount", 0, "amount in minor units")
+	currency := flag.String("currency", "978", "ISO 4217 numeric currency code")
+	merchant := flag.String("merchant", "Test Merchant", "merchant name and location")
+	rrn := flag.String("rrn", "", "retrieval reference number (generated if empty)")
+	flow := flag.String("flow", "auth", "auth, purchase, completion or reversal")
+
+	flag.Parse()
+
+	mtis, ok := flows[*flow]
+
+	if !ok {
+		log.Fatalf("unknown flow %q", *flow)
+	}
+
+	spec := iso8583.DefaultSpec()
+
+	if *specPath != "" {
+		file, err := os.Open(*specPath)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		spec, err = iso8583.LoadSpec(file)
+
+		file.Close()
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	now := time.Now().UTC()
+
+	if *rrn == "" {
+		*rrn = now.Format("060102150405")
+	}
+
+	client, err := iso8583.Dial(*addr, spec)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer client.Close()
+
+	for i, mti := range mtis {
+		req := iso8583.NewMessage(mti)
+
+		req.Fields[2] = *pan
+		req.Fields[3] = "000000"
+		req.Fields[4] = fmt.Sprintf("%012d", *amount)
+		req.Fields[7] = now.Format("0102150405")
+		req.Fields[11] = fmt.Sprintf("%06d", i+1)
+		req.Fields[14] = *expiry
+		req.Fields[37] = *rrn
+		req.Fields[41] = "TERM0001"
+		req.Fields[43] = *merchant
+		req.Fields[48] = *cvv
+		req.Fields[49] = *currency
+
+		printMessage(">", req)
+
+		res, err := client.Send(req)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printMessage("<", res)
+
+		if res.Fields[39] != "00" {
+			os.Exit(1)
+		}
+	}
+}
+
+// printMessage writes a message with its fields in order, masking the card
+// number and leaving out the CVV.
+func printMessage(direction string, msg iso8583.Message) {
+	numbers := make([]int, 0, len(msg.Fields))
+
+	for number := range msg.Fields {
+		numbers = append(numbers, number)
+	}
+
+	sort.Ints(numbers)
+
+	fmt.Printf("%s %s\n", direction, msg.MTI)
+
+	for _, number := range numbers {
+		value := msg.Fields[number]
+
+		switch number {
+		case 2:
+			value = card.Mask(value)
+		case 48:
+			value = "***"
+		}
+
+		fmt.Printf("  %3d %s\n", number, value)
+	}
+}
@@ -14,7 +14,6 @@ require (
 	github.com/cloudwego/iasm v0.2.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.5 // indirect
-	github.com/gin-contrib/sse v0.1.0 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-playground/validator/v10 v10.22.1 // indirect
@@ -40,6 +39,7 @@ require (
 )
 
 require (
+	github.com/gin-contrib/sse v0.1.0
 	github.com/google/uuid v1.6.0
 	github.com/stretchr/testify v1.9.0
 )
@@ -0,0 +1,241 @@
+package batch
+
+import (
+	"errors"
+	"log"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/batchrepo"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+type Batcher interface {
+	Submit(SubmitRequest) (BatchResponse, error)
+	Status(StatusRequest) (BatchResponse, error)
+}
+
+type batcher struct {
+	svc       service.Service
+	batchRepo batchrepo.Repo
+}
+
+func New(
+	svc service.Service,
+	batchRepo batchrepo.Repo,
+) Batcher {
+	return &batcher{
+		svc:       svc,
+		batchRepo: batchRepo,
+	}
+}
+
+func (b batcher) Submit(req SubmitRequest) (BatchResponse, error) {
+	if req.UserID == "" {
+		return BatchResponse{}, errors.New("invalid user id")
+	}
+
+	if req.AccountID == "" {
+		return BatchResponse{}, errors.New("invalid account id")
+	}
+
+	lines, err := parse(req.Format, req.File)
+
+	if err != nil {
+		return BatchResponse{}, err
+	}
+
+	batch, err := b.batchRepo.Create(
+		batchrepo.CreateRequest{
+			Batch: domain.Batch{
+				SenderUserID:    req.UserID,
+				SenderAccountID: req.AccountID,
+				Format:          req.Format,
+				AllOrNothing:    req.AllOrNothing,
+				Status:          domain.BatchPending,
+				Lines:           lines,
+			},
+		},
+	)
+
+	if err != nil {
+		return BatchResponse{}, err
+	}
+
+	if req.Async {
+		go func() {
+			_, err := b.process(batch)
+
+			if err != nil {
+				log.Printf("batch %s: %v", batch.ID, err)
+			}
+		}()
+
+		return batchResponse(batch), nil
+	}
+
+	batch, err = b.process(batch)
+
+	if err != nil {
+		return BatchResponse{}, err
+	}
+
+	return batchResponse(batch), nil
+}
+
+func (b batcher) Status(req StatusRequest) (BatchResponse, error) {
+	batch, err := b.batchRepo.Read(
+		batchrepo.ReadRequest{
+			ID: req.BatchID,
+		},
+	)
+
+	if err != nil {
+		return BatchResponse{}, err
+	}
+
+	if batch.SenderUserID != req.UserID || batch.SenderAccountID != req.AccountID {
+		return BatchResponse{}, errors.New("batch not found")
+	}
+
+	return batchResponse(batch), nil
+}
+
+func (b batcher) process(batch domain.Batch) (domain.Batch, error) {
+	batch.Status = domain.BatchProcessing
+
+	batch, err := b.batchRepo.Update(
+		batchrepo.UpdateRequest{
+			Batch: batch,
+		},
+	)
+
+	if err != nil {
+		return domain.Batch{}, err
+	}
+
+	pending := []int{}
+
+	for i, line := range batch.Lines {
+		if line.Status == domain.BatchLinePending {
+			pending = append(pending, i)
+		}
+	}
+
+	if batch.AllOrNothing && len(pending) != len(batch.Lines) {
+		for _, i := range pending {
+			batch.Lines[i].Status = domain.BatchLineSkipped
+		}
+
+		pending = nil
+	}
+
+	if len(pending) > 0 {
+		transfers := make([]service.TransferRequest, len(pending))
+
+		for j, i := range pending {
+			transfers[j] = service.TransferRequest{
+				ReceiverUserID:    batch.Lines[i].ReceiverUserID,
+				ReceiverAccountID: batch.Lines[i].ReceiverAccountID,
+				Amount:            batch.Lines[i].Amount,
+			}
+		}
+
+		res, err := b.svc.BatchTransfer(
+			service.BatchTransferRequest{
+				SenderUserID:    batch.SenderUserID,
+				SenderAccountID: batch.SenderAccountID,
+				AllOrNothing:    batch.AllOrNothing,
+				Transfers:       transfers,
+			},
+		)
+
+		if err != nil {
+			return domain.Batch{}, b.fail(batch, err)
+		}
+
+		for j, i := range pending {
+			batch.Lines[i].Status = res.Results[j].Status
+			batch.Lines[i].Error = res.Results[j].Error
+		}
+	}
+
+	batch.Status = batchStatus(batch.Lines)
+
+	batch, err = b.batchRepo.Update(
+		batchrepo.UpdateRequest{
+			Batch: batch,
+		},
+	)
+
+	if err != nil {
+		return domain.Batch{}, err
+	}
+
+	return batch, nil
+}
+
+func (b batcher) fail(batch domain.Batch, cause error) error {
+	batch.Status = domain.BatchFailed
+
+	for i := range batch.Lines {
+		if batch.Lines[i].Status == domain.BatchLinePending {
+			batch.Lines[i].Status = domain.BatchLineSkipped
+		}
+	}
+
+	_, err := b.batchRepo.Update(
+		batchrepo.UpdateRequest{
+			Batch: batch,
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func batchStatus(lines []domain.BatchLine) domain.BatchStatus {
+	completed := 0
+
+	for _, line := range lines {
+		if line.Status == domain.BatchLineCompleted || line.Status == domain.BatchLineHeld {
+			completed++
+		}
+	}
+
+	switch completed {
+	case len(lines):
+		return domain.BatchCompleted
+	case 0:
+		return domain.BatchFailed
+	default:
+		return domain.BatchCompletedWithErrors
+	}
+}
+
+func batchResponse(batch domain.Batch) BatchResponse {
+	lines := make([]LineResponse, len(batch.Lines))
+
+	for i, line := range batch.Lines {
+		lines[i] = LineResponse{
+			Line:              line.Line,
+			ReceiverUserID:    line.ReceiverUserID,
+			ReceiverAccountID: line.ReceiverAccountID,
+			Amount:            line.Amount,
+			Status:            line.Status,
+			Error:             line.Error,
+		}
+	}
+
+	return BatchResponse{
+		BatchID:      batch.ID,
+		CreatedAt:    batch.CreatedAt,
+		UpdatedAt:    batch.UpdatedAt,
+		Format:       batch.Format,
+		AllOrNothing: batch.AllOrNothing,
+		Status:       batch.Status,
+		Lines:        lines,
+	}
+}
@@ -0,0 +1,188 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatPain001 = "pain.001"
+)
+
+var csvColumns = []string{"receiver_user_id", "receiver_account_id", "amount"}
+
+type pain001Document struct {
+	Transactions []pain001Transaction `xml:"CstmrCdtTrfInitn>PmtInf>CdtTrfTxInf"`
+}
+
+type pain001Transaction struct {
+	Amount            pain001Amount `xml:"Amt>InstdAmt"`
+	ReceiverUserID    string        `xml:"Cdtr>Id>PrvtId>Othr>Id"`
+	ReceiverAccountID string        `xml:"CdtrAcct>Id>Othr>Id"`
+	ReceiverIBAN      string        `xml:"CdtrAcct>Id>IBAN"`
+}
+
+type pain001Amount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+func parse(format string, file []byte) ([]domain.BatchLine, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(file)
+	case FormatPain001:
+		return parsePain001(file)
+	default:
+		return nil, errors.New("invalid batch format")
+	}
+}
+
+func parseCSV(file []byte) ([]domain.BatchLine, error) {
+	reader := csv.NewReader(bytes.NewReader(file))
+
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return nil, errors.New("empty batch")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+
+	for _, name := range csvColumns {
+		if _, exists := columns[name]; !exists {
+			return nil, errors.New("missing column " + name)
+		}
+	}
+
+	lines := []domain.BatchLine{}
+
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		line := domain.BatchLine{
+			Line:   number,
+			Status: domain.BatchLinePending,
+		}
+
+		if len(record) != len(header) {
+			lines = append(lines, failLine(line, errors.New("invalid column count")))
+
+			continue
+		}
+
+		line.ReceiverUserID = strings.TrimSpace(record[columns["receiver_user_id"]])
+		line.ReceiverAccountID = strings.TrimSpace(record[columns["receiver_account_id"]])
+
+		currency := ""
+
+		if i, exists := columns["currency"]; exists {
+			currency = record[i]
+		}
+
+		amount, err := parseAmount(record[columns["amount"]], currency)
+
+		if err != nil {
+			lines = append(lines, failLine(line, err))
+
+			continue
+		}
+
+		line.Amount = amount
+
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return nil, errors.New("empty batch")
+	}
+
+	return lines, nil
+}
+
+func parsePain001(file []byte) ([]domain.BatchLine, error) {
+	var doc pain001Document
+
+	err := xml.Unmarshal(file, &doc)
+
+	if err != nil {
+		return nil, errors.New("invalid pain.001 document")
+	}
+
+	if len(doc.Transactions) == 0 {
+		return nil, errors.New("empty batch")
+	}
+
+	lines := make([]domain.BatchLine, 0, len(doc.Transactions))
+
+	for i, transaction := range doc.Transactions {
+		line := domain.BatchLine{
+			Line:              i + 1,
+			ReceiverUserID:    strings.TrimSpace(transaction.ReceiverUserID),
+			ReceiverAccountID: strings.TrimSpace(transaction.ReceiverAccountID),
+			Status:            domain.BatchLinePending,
+		}
+
+		if line.ReceiverAccountID == "" {
+			line.ReceiverAccountID = strings.TrimSpace(transaction.ReceiverIBAN)
+		}
+
+		amount, err := parseAmount(transaction.Amount.Value, transaction.Amount.Currency)
+
+		if err != nil {
+			lines = append(lines, failLine(line, err))
+
+			continue
+		}
+
+		line.Amount = amount
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+func parseAmount(value string, currency string) (money.Money, error) {
+	currency = strings.TrimSpace(currency)
+
+	if currency == "" {
+		currency = string(money.DefaultCurrency)
+	}
+
+	return money.Parse(strings.TrimSpace(value), money.Currency(strings.ToUpper(currency)))
+}
+
+func failLine(line domain.BatchLine, err error) domain.BatchLine {
+	line.Status = domain.BatchLineFailed
+	line.Error = err.Error()
+
+	return line
+}
@@ -0,0 +1,103 @@
+package batch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_ErrInvalidFormat(t *testing.T) {
+	res, err := parse("xlsx", nil)
+
+	assert.Nil(t, res)
+	assert.Equal(t, errors.New("invalid batch format"), err)
+}
+
+func TestParseCSV_ErrMissingColumn(t *testing.T) {
+	res, err := parse(FormatCSV, []byte("receiver_user_id,amount\nuser,10\n"))
+
+	assert.Nil(t, res)
+	assert.Equal(t, errors.New("missing column receiver_account_id"), err)
+}
+
+func TestParseCSV_Ok(t *testing.T) {
+	file := "amount,receiver_account_id,receiver_user_id,currency\n10,account,user,\n1.50,account,user,usd\n1.505,account,user,\n"
+
+	res, err := parse(FormatCSV, []byte(file))
+
+	assert.Equal(
+		t,
+		[]domain.BatchLine{
+			{
+				Line:              1,
+				ReceiverUserID:    "user",
+				ReceiverAccountID: "account",
+				Amount:            money.New(1000, money.EUR),
+				Status:            domain.BatchLinePending,
+			},
+			{
+				Line:              2,
+				ReceiverUserID:    "user",
+				ReceiverAccountID: "account",
+				Amount:            money.New(150, money.USD),
+				Status:            domain.BatchLinePending,
+			},
+			{
+				Line:              3,
+				ReceiverUserID:    "user",
+				ReceiverAccountID: "account",
+				Status:            domain.BatchLineFailed,
+				Error:             "invalid amount",
+			},
+		},
+		res,
+	)
+	assert.Nil(t, err)
+}
+
+func TestParsePain001_Ok(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">
+  <CstmrCdtTrfInitn>
+    <PmtInf>
+      <CdtTrfTxInf>
+        <Amt><InstdAmt Ccy="EUR">25.00</InstdAmt></Amt>
+        <Cdtr><Id><PrvtId><Othr><Id>user</Id></Othr></PrvtId></Id></Cdtr>
+        <CdtrAcct><Id><Othr><Id>account</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <Amt><InstdAmt Ccy="EUR">1.50</InstdAmt></Amt>
+        <Cdtr><Id><PrvtId><Othr><Id>user</Id></Othr></PrvtId></Id></Cdtr>
+        <CdtrAcct><Id><IBAN>NL91ABNA0417164300</IBAN></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`
+
+	res, err := parse(FormatPain001, []byte(file))
+
+	assert.Equal(
+		t,
+		[]domain.BatchLine{
+			{
+				Line:              1,
+				ReceiverUserID:    "user",
+				ReceiverAccountID: "account",
+				Amount:            money.New(2500, money.EUR),
+				Status:            domain.BatchLinePending,
+			},
+			{
+				Line:              2,
+				ReceiverUserID:    "user",
+				ReceiverAccountID: "NL91ABNA0417164300",
+				Amount:            money.New(150, money.EUR),
+				Status:            domain.BatchLinePending,
+			},
+		},
+		res,
+	)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,16 @@
+package batch
+
+type SubmitRequest struct {
+	UserID       string
+	AccountID    string
+	Format       string
+	AllOrNothing bool
+	Async        bool
+	File         []byte
+}
+
+type StatusRequest struct {
+	UserID    string
+	AccountID string
+	BatchID   string
+}
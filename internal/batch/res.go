@@ -0,0 +1,27 @@
+package batch
+
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
+
+type BatchResponse struct {
+	BatchID      string             `json:"batch_id"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	Format       string             `json:"format"`
+	AllOrNothing bool               `json:"all_or_nothing"`
+	Status       domain.BatchStatus `json:"status"`
+	Lines        []LineResponse     `json:"lines"`
+}
+
+type LineResponse struct {
+	Line              int                    `json:"line"`
+	ReceiverUserID    string                 `json:"receiver_user_id"`
+	ReceiverAccountID string                 `json:"receiver_account_id"`
+	Amount            money.Money            `json:"amount"`
+	Status            domain.BatchLineStatus `json:"status"`
+	Error             string                 `json:"error,omitempty"`
+}
@@ -0,0 +1,103 @@
+// Package card builds and checks ISO/IEC 7812 payment card numbers (PANs).
+package card
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hetfdex/tiny-bank/internal/checkdigit"
+)
+
+const (
+	minLength = 12
+	maxLength = 19
+
+	// panLength is the width of the PANs an Issuer builds: the BIN, the
+	// account number and the check digit.
+	panLength = 16
+)
+
+var (
+	ErrInvalid          = errors.New("invalid card number")
+	ErrInvalidBIN       = errors.New("invalid card bin")
+	ErrInvalidAccountNo = errors.New("invalid card account number")
+)
+
+// Parse accepts a PAN with or without spaces and returns its digits once
+// its length and check digit are valid.
+func Parse(value string) (string, error) {
+	pan := strings.ReplaceAll(value, " ", "")
+
+	if len(pan) < minLength || len(pan) > maxLength {
+		return "", ErrInvalid
+	}
+
+	check, ok := checkdigit.Luhn(pan[:len(pan)-1])
+
+	if !ok || fmt.Sprint(check) != pan[len(pan)-1:] {
+		return "", ErrInvalid
+	}
+
+	return pan, nil
+}
+
+// Mask keeps the BIN and the last four digits of a PAN, the most PCI DSS
+// allows to be shown.
+func Mask(pan string) string {
+	if len(pan) < minLength {
+		return strings.Repeat("*", len(pan))
+	}
+
+	return pan[:6] + strings.Repeat("*", len(pan)-10) + pan[len(pan)-4:]
+}
+
+// Issuer builds the PANs of one card range: the bank identification number
+// (BIN), an account number and the Luhn check digit.
+type Issuer struct {
+	bin string
+}
+
+func NewIssuer(bin string) (Issuer, error) {
+	if (len(bin) != 6 && len(bin) != 8) || strings.Trim(bin, "0123456789") != "" || bin[0] == '0' {
+		return Issuer{}, ErrInvalidBIN
+	}
+
+	return Issuer{
+		bin: bin,
+	}, nil
+}
+
+// MustNewIssuer is NewIssuer for fixed configuration; it panics on error.
+func MustNewIssuer(bin string) Issuer {
+	issuer, err := NewIssuer(bin)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return issuer
+}
+
+// MaxAccountNumber is the largest account number that fits after the BIN.
+func (i Issuer) MaxAccountNumber() uint64 {
+	largest := uint64(1)
+
+	for range panLength - 1 - len(i.bin) {
+		largest *= 10
+	}
+
+	return largest - 1
+}
+
+func (i Issuer) PAN(accountNumber uint64) (string, error) {
+	if accountNumber > i.MaxAccountNumber() {
+		return "", ErrInvalidAccountNo
+	}
+
+	payload := fmt.Sprintf("%s%0*d", i.bin, panLength-1-len(i.bin), accountNumber)
+
+	check, _ := checkdigit.Luhn(payload)
+
+	return fmt.Sprintf("%s%d", payload, check), nil
+}
@@ -0,0 +1,77 @@
+package card
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Ok(t *testing.T) {
+	for value, expected := range map[string]string{
+		"4111111111111111":    "4111111111111111",
+		"4111 1111 1111 1111": "4111111111111111",
+		"5555555555554444":    "5555555555554444",
+		"378282246310005":     "378282246310005",
+	} {
+		res, err := Parse(value)
+
+		assert.Equal(t, expected, res, value)
+		assert.Nil(t, err, value)
+	}
+}
+
+func TestParse_Err(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"4111111111111112",
+		"411111111111111a",
+		"41111111111",
+		"41111111111111111111",
+	} {
+		res, err := Parse(value)
+
+		assert.Empty(t, res, value)
+		assert.Equal(t, ErrInvalid, err, value)
+	}
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "411111******1111", Mask("4111111111111111"))
+	assert.Equal(t, "378282*****0005", Mask("378282246310005"))
+}
+
+func TestIssuer_Ok(t *testing.T) {
+	issuer, err := NewIssuer("411111")
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(999999999), issuer.MaxAccountNumber())
+
+	res, err := issuer.PAN(111111111)
+
+	assert.Equal(t, "4111111111111111", res)
+	assert.Nil(t, err)
+
+	res, err = issuer.PAN(42)
+
+	assert.Nil(t, err)
+
+	parsed, err := Parse(res)
+
+	assert.Equal(t, res, parsed)
+	assert.Nil(t, err)
+}
+
+func TestIssuer_Err(t *testing.T) {
+	for _, bin := range []string{"", "41111", "4111111", "41111a", "011111"} {
+		_, err := NewIssuer(bin)
+
+		assert.Equal(t, ErrInvalidBIN, err, bin)
+	}
+
+	issuer := MustNewIssuer("41111111")
+
+	res, err := issuer.PAN(10000000)
+
+	assert.Empty(t, res)
+	assert.Equal(t, ErrInvalidAccountNo, err)
+}
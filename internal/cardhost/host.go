@@ -0,0 +1,423 @@
+// Package cardhost is the card issuer's ISO 8583 host for authorisation
+// (0100), financial (0200) and reversal (0400) requests.
+package cardhost
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/iso8583"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+const (
+	fieldPAN                  = 2
+	fieldAmount               = 4
+	fieldExpirationDate       = 14
+	fieldRRN                  = 37
+	fieldApprovalCode         = 38
+	fieldResponseCode         = 39
+	fieldTerminalID           = 41
+	fieldMerchantID           = 42
+	fieldMerchantNameLocation = 43
+	fieldAdditionalData       = 48
+	fieldCurrencyCode         = 49
+
+	approvalCodeLength = 6
+)
+
+// Response codes, as in ISO 8583:1987 field 39.
+const (
+	Approved             = "00"
+	DoNotHonour          = "05"
+	InvalidTransaction   = "12"
+	InvalidAmount        = "13"
+	InvalidCardNumber    = "14"
+	RecordNotFound       = "25"
+	FormatError          = "30"
+	InsufficientFunds    = "51"
+	ExpiredCard          = "54"
+	ExceedsLimit         = "61"
+	RestrictedCard       = "62"
+	DuplicateTransaction = "94"
+)
+
+// echoedFields are copied from the request to the response so the acquirer
+// can match them up.
+var echoedFields = []int{2, 3, 4, 7, 11, 12, 13, 37, 41, 42, 49, 90}
+
+// currencies maps ISO 4217 numeric codes to the currencies accounts hold.
+var currencies = map[string]money.Currency{
+	"978": money.EUR,
+	"840": money.USD,
+	"826": money.GBP,
+	"756": money.CHF,
+	"392": money.JPY,
+}
+
+var errFormat = errors.New("invalid iso8583 request")
+
+// Host captures and reverses for the merchants it has keys for, by the
+// merchant named in the request.
+type Host struct {
+	svc          service.Service
+	spec         iso8583.Spec
+	merchantKeys map[string]string
+}
+
+func New(svc service.Service, spec iso8583.Spec, merchantKeys map[string]string) Host {
+	return Host{
+		svc:          svc,
+		spec:         spec,
+		merchantKeys: merchantKeys,
+	}
+}
+
+// Serve answers each connection's requests in order until the listener is
+// closed.
+func (h Host) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return err
+		}
+
+		go h.serveConn(conn)
+	}
+}
+
+func (h Host) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		data, err := iso8583.ReadFrame(conn)
+
+		if err != nil {
+			return
+		}
+
+		res, ok := h.answer(data)
+
+		if !ok {
+			log.Printf("cardhost: dropping connection from %s: unreadable message", conn.RemoteAddr())
+
+			return
+		}
+
+		data, err = h.spec.Pack(res)
+
+		if err != nil {
+			log.Printf("cardhost: packing %s response: %s", res.MTI, err)
+
+			return
+		}
+
+		err = iso8583.WriteFrame(conn, data)
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// answer returns a format error for a request MTI it cannot unpack, and ok
+// false when there is nothing to answer.
+func (h Host) answer(data []byte) (iso8583.Message, bool) {
+	req, err := h.spec.Unpack(data)
+
+	if err == nil {
+		return h.Handle(req), true
+	}
+
+	if len(data) < 4 {
+		return iso8583.Message{}, false
+	}
+
+	mti, ok := iso8583.ResponseMTI(string(data[:4]))
+
+	if !ok {
+		return iso8583.Message{}, false
+	}
+
+	res := iso8583.NewMessage(mti)
+
+	res.Fields[fieldResponseCode] = FormatError
+
+	return res, true
+}
+
+// Handle answers one request. Requests the host does not support, and
+// response MTIs, are answered with an invalid transaction code.
+func (h Host) Handle(req iso8583.Message) iso8583.Message {
+	mti, ok := iso8583.ResponseMTI(req.MTI)
+
+	if !ok {
+		mti = req.MTI
+	}
+
+	res := iso8583.NewMessage(mti)
+
+	for _, number := range echoedFields {
+		value, ok := req.Fields[number]
+
+		if ok {
+			res.Fields[number] = value
+		}
+	}
+
+	var authorisation service.CardAuthorisationResponse
+
+	var err error
+
+	switch req.MTI {
+	case "0100":
+		authorisation, err = h.authorise(req)
+	case "0200":
+		authorisation, err = h.purchase(req)
+	case "0400":
+		authorisation, err = h.reverse(req)
+	case "0800":
+		res.Fields[fieldResponseCode] = Approved
+
+		return res
+	default:
+		res.Fields[fieldResponseCode] = InvalidTransaction
+
+		return res
+	}
+
+	if err != nil {
+		res.Fields[fieldResponseCode] = responseCode(err)
+
+		return res
+	}
+
+	res.Fields[fieldResponseCode] = Approved
+
+	if req.MTI != "0400" {
+		res.Fields[fieldApprovalCode] = approvalCode(authorisation.AuthorisationID)
+	}
+
+	return res
+}
+
+func (h Host) authorise(req iso8583.Message) (service.CardAuthorisationResponse, error) {
+	amount, err := requestAmount(req)
+
+	if err != nil {
+		return service.CardAuthorisationResponse{}, err
+	}
+
+	expiry := req.Fields[fieldExpirationDate]
+
+	if len(expiry) != 4 {
+		return service.CardAuthorisationResponse{}, errFormat
+	}
+
+	year, err := strconv.Atoi(expiry[:2])
+
+	if err != nil {
+		return service.CardAuthorisationResponse{}, errFormat
+	}
+
+	month, err := strconv.Atoi(expiry[2:])
+
+	if err != nil {
+		return service.CardAuthorisationResponse{}, errFormat
+	}
+
+	return h.svc.AuthoriseCard(
+		service.AuthoriseCardRequest{
+			PAN:         req.Fields[fieldPAN],
+			ExpiryMonth: month,
+			ExpiryYear:  2000 + year,
+			CVV:         strings.TrimSpace(req.Fields[fieldAdditionalData]),
+			Amount:      amount,
+			Reference:   req.Fields[fieldRRN],
+			Merchant:    merchant(req),
+			MerchantKey: h.merchantKeys[merchant(req)],
+		},
+	)
+}
+
+// purchase completes an earlier 0100 with the same retrieval reference
+// number, or else authorises and captures at once.
+func (h Host) purchase(req iso8583.Message) (service.CardAuthorisationResponse, error) {
+	amount, err := requestAmount(req)
+
+	if err != nil {
+		return service.CardAuthorisationResponse{}, err
+	}
+
+	authorisation, err := h.referenced(req)
+
+	if err != nil && err.Error() != "authorisation not found" {
+		return service.CardAuthorisationResponse{}, err
+	}
+
+	if err == nil && authorisation.Status != domain.AuthorisationHeld {
+		return service.CardAuthorisationResponse{}, errors.New("duplicate transaction")
+	}
+
+	if err != nil {
+		authorisation, err = h.authorise(req)
+
+		if err != nil {
+			return service.CardAuthorisationResponse{}, err
+		}
+
+		captured, err := h.capture(req, authorisation, amount)
+
+		if err != nil {
+			return service.CardAuthorisationResponse{}, h.compensatePurchase(req, authorisation, err)
+		}
+
+		return captured, nil
+	}
+
+	return h.capture(req, authorisation, amount)
+}
+
+// reverse declines captured payments, which the merchant refunds instead.
+func (h Host) reverse(req iso8583.Message) (service.CardAuthorisationResponse, error) {
+	authorisation, err := h.referenced(req)
+
+	if err != nil {
+		return service.CardAuthorisationResponse{}, err
+	}
+
+	return h.svc.ReverseAuthorisation(
+		service.ReverseAuthorisationRequest{
+			CardID:          authorisation.CardID,
+			AuthorisationID: authorisation.AuthorisationID,
+			Merchant:        merchant(req),
+			MerchantKey:     h.merchantKeys[merchant(req)],
+		},
+	)
+}
+
+func (h Host) referenced(req iso8583.Message) (service.CardAuthorisationResponse, error) {
+	reference := req.Fields[fieldRRN]
+
+	if reference == "" {
+		return service.CardAuthorisationResponse{}, errors.New("authorisation not found")
+	}
+
+	return h.svc.MerchantAuthorisation(
+		service.MerchantAuthorisationRequest{
+			PAN:       req.Fields[fieldPAN],
+			Reference: reference,
+		},
+	)
+}
+
+func (h Host) capture(req iso8583.Message, authorisation service.CardAuthorisationResponse, amount money.Money) (service.CardAuthorisationResponse, error) {
+	return h.svc.CaptureAuthorisation(
+		service.CaptureAuthorisationRequest{
+			CardID:          authorisation.CardID,
+			AuthorisationID: authorisation.AuthorisationID,
+			Amount:          amount,
+			Merchant:        merchant(req),
+			MerchantKey:     h.merchantKeys[merchant(req)],
+		},
+	)
+}
+
+func (h Host) compensatePurchase(req iso8583.Message, authorisation service.CardAuthorisationResponse, cause error) error {
+	_, err := h.svc.ReverseAuthorisation(
+		service.ReverseAuthorisationRequest{
+			CardID:          authorisation.CardID,
+			AuthorisationID: authorisation.AuthorisationID,
+			Merchant:        merchant(req),
+			MerchantKey:     h.merchantKeys[merchant(req)],
+		},
+	)
+
+	return errors.Join(cause, err)
+}
+
+func requestAmount(req iso8583.Message) (money.Money, error) {
+	currency, ok := currencies[req.Fields[fieldCurrencyCode]]
+
+	if !ok {
+		return money.Money{}, errFormat
+	}
+
+	minor, err := strconv.ParseInt(req.Fields[fieldAmount], 10, 64)
+
+	if err != nil {
+		return money.Money{}, errFormat
+	}
+
+	return money.New(minor, currency), nil
+}
+
+// merchant names the merchant from the name and location field, falling
+// back to the merchant and terminal ids.
+func merchant(req iso8583.Message) string {
+	for _, number := range []int{fieldMerchantNameLocation, fieldMerchantID, fieldTerminalID} {
+		value := strings.TrimSpace(req.Fields[number])
+
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func approvalCode(authorisationID string) string {
+	code := strings.ToUpper(strings.ReplaceAll(authorisationID, "-", ""))
+
+	if len(code) > approvalCodeLength {
+		code = code[:approvalCodeLength]
+	}
+
+	return code
+}
+
+// responseCode goes by the error message, like the v2 handlers.
+func responseCode(err error) string {
+	var velocityErr service.VelocityLimitError
+
+	if errors.As(err, &velocityErr) {
+		return ExceedsLimit
+	}
+
+	msg := err.Error()
+
+	switch {
+	case errors.Is(err, errFormat):
+		return FormatError
+	case msg == "invalid card details":
+		return InvalidCardNumber
+	case msg == "card expired":
+		return ExpiredCard
+	case msg == "card frozen", msg == "card locked", msg == "card cancelled":
+		return RestrictedCard
+	case msg == "card limit exceeded", msg == "card daily limit exceeded":
+		return ExceedsLimit
+	case strings.HasPrefix(msg, "insuficient funds"):
+		return InsufficientFunds
+	case strings.HasPrefix(msg, "invalid amount"):
+		return InvalidAmount
+	case msg == "authorisation not found":
+		return RecordNotFound
+	case msg == "authorisation not held", msg == "authorisation expired":
+		return InvalidTransaction
+	case msg == "invalid merchant credentials":
+		return DoNotHonour
+	case msg == "duplicate transaction", msg == "duplicate merchant reference":
+		return DuplicateTransaction
+	case strings.HasPrefix(msg, "invalid "):
+		return FormatError
+	}
+
+	return DoNotHonour
+}
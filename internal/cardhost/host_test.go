@@ -0,0 +1,301 @@
+package cardhost
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/iso8583"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/service"
+	"github.com/hetfdex/tiny-bank/test/mock/servicemock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var merchantKeys = map[string]string{
+	"Coffee Shop": "coffee-key",
+}
+
+func makeRequest(mti string) iso8583.Message {
+	req := iso8583.NewMessage(mti)
+
+	req.Fields[2] = "4999990000000001"
+	req.Fields[4] = "000000001000"
+	req.Fields[11] = "000001"
+	req.Fields[14] = "2908"
+	req.Fields[37] = "000000000001"
+	req.Fields[42] = "MERCHANT01"
+	req.Fields[43] = "Coffee Shop"
+	req.Fields[48] = "123"
+	req.Fields[49] = "978"
+
+	return req
+}
+
+func TestHandle_Authorisation(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"AuthoriseCard",
+		service.AuthoriseCardRequest{
+			PAN:         "4999990000000001",
+			ExpiryMonth: 8,
+			ExpiryYear:  2029,
+			CVV:         "123",
+			Amount:      money.New(1000, money.EUR),
+			Reference:   "000000000001",
+			Merchant:    "Coffee Shop",
+			MerchantKey: "coffee-key",
+		},
+	).Return(
+		service.CardAuthorisationResponse{
+			AuthorisationID: "ab12cd34-0000-0000-0000-000000000000",
+			CardID:          "1",
+			Status:          domain.AuthorisationHeld,
+		},
+		nil,
+	)
+
+	res := New(svc, iso8583.DefaultSpec(), merchantKeys).Handle(makeRequest("0100"))
+
+	assert.Equal(t, "0110", res.MTI)
+	assert.Equal(t, Approved, res.Fields[39])
+	assert.Equal(t, "AB12CD", res.Fields[38])
+	assert.Equal(t, "000001", res.Fields[11])
+	assert.Equal(t, "000000000001", res.Fields[37])
+	assert.NotContains(t, res.Fields, 48)
+}
+
+func TestHandle_Declined(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"AuthoriseCard",
+		mock.Anything,
+	).Return(
+		service.CardAuthorisationResponse{},
+		errors.New("insuficient funds"),
+	)
+
+	res := New(svc, iso8583.DefaultSpec(), merchantKeys).Handle(makeRequest("0100"))
+
+	assert.Equal(t, "0110", res.MTI)
+	assert.Equal(t, InsufficientFunds, res.Fields[39])
+	assert.NotContains(t, res.Fields, 38)
+}
+
+func TestHandle_Completion(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	authorisation := service.CardAuthorisationResponse{
+		AuthorisationID: "2",
+		CardID:          "1",
+		Status:          domain.AuthorisationHeld,
+	}
+
+	svc.On(
+		"MerchantAuthorisation",
+		service.MerchantAuthorisationRequest{
+			PAN:       "4999990000000001",
+			Reference: "000000000001",
+		},
+	).Return(
+		authorisation,
+		nil,
+	)
+
+	svc.On(
+		"CaptureAuthorisation",
+		service.CaptureAuthorisationRequest{
+			CardID:          "1",
+			AuthorisationID: "2",
+			Amount:          money.New(1000, money.EUR),
+			Merchant:        "Coffee Shop",
+			MerchantKey:     "coffee-key",
+		},
+	).Return(
+		authorisation,
+		nil,
+	)
+
+	res := New(svc, iso8583.DefaultSpec(), merchantKeys).Handle(makeRequest("0200"))
+
+	assert.Equal(t, "0210", res.MTI)
+	assert.Equal(t, Approved, res.Fields[39])
+
+	svc.AssertNotCalled(t, "AuthoriseCard", mock.Anything)
+}
+
+func TestHandle_PurchaseCaptureFailed(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	authorisation := service.CardAuthorisationResponse{
+		AuthorisationID: "2",
+		CardID:          "1",
+		Status:          domain.AuthorisationHeld,
+	}
+
+	svc.On(
+		"MerchantAuthorisation",
+		mock.Anything,
+	).Return(
+		service.CardAuthorisationResponse{},
+		errors.New("authorisation not found"),
+	)
+
+	svc.On(
+		"AuthoriseCard",
+		mock.Anything,
+	).Return(
+		authorisation,
+		nil,
+	)
+
+	svc.On(
+		"CaptureAuthorisation",
+		mock.Anything,
+	).Return(
+		service.CardAuthorisationResponse{},
+		errors.New("card changed"),
+	)
+
+	svc.On(
+		"ReverseAuthorisation",
+		service.ReverseAuthorisationRequest{
+			CardID:          "1",
+			AuthorisationID: "2",
+			Merchant:        "Coffee Shop",
+			MerchantKey:     "coffee-key",
+		},
+	).Return(
+		authorisation,
+		nil,
+	)
+
+	res := New(svc, iso8583.DefaultSpec(), merchantKeys).Handle(makeRequest("0200"))
+
+	assert.Equal(t, DoNotHonour, res.Fields[39])
+
+	svc.AssertExpectations(t)
+}
+
+func TestHandle_ReversalNotFound(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"MerchantAuthorisation",
+		mock.Anything,
+	).Return(
+		service.CardAuthorisationResponse{},
+		errors.New("authorisation not found"),
+	)
+
+	res := New(svc, iso8583.DefaultSpec(), merchantKeys).Handle(makeRequest("0400"))
+
+	assert.Equal(t, "0410", res.MTI)
+	assert.Equal(t, RecordNotFound, res.Fields[39])
+}
+
+func TestHandle_Err(t *testing.T) {
+	tests := []struct {
+		name string
+		req  func() iso8583.Message
+		code string
+	}{
+		{
+			name: "unsupported mti",
+			req: func() iso8583.Message {
+				return makeRequest("0300")
+			},
+			code: InvalidTransaction,
+		},
+		{
+			name: "unknown currency",
+			req: func() iso8583.Message {
+				req := makeRequest("0100")
+
+				req.Fields[49] = "999"
+
+				return req
+			},
+			code: FormatError,
+		},
+		{
+			name: "missing expiry",
+			req: func() iso8583.Message {
+				req := makeRequest("0100")
+
+				delete(req.Fields, 14)
+
+				return req
+			},
+			code: FormatError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := New(&servicemock.Mock{}, iso8583.DefaultSpec(), merchantKeys).Handle(tt.req())
+
+			assert.Equal(t, tt.code, res.Fields[39])
+		})
+	}
+}
+
+func TestResponseCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{err: errors.New("invalid card details"), code: InvalidCardNumber},
+		{err: errors.New("card expired"), code: ExpiredCard},
+		{err: errors.New("card frozen"), code: RestrictedCard},
+		{err: errors.New("card locked"), code: RestrictedCard},
+		{err: errors.New("card daily limit exceeded"), code: ExceedsLimit},
+		{err: service.VelocityLimitError{}, code: ExceedsLimit},
+		{err: errors.New("invalid amount, above authorised amount"), code: InvalidAmount},
+		{err: errors.New("authorisation not held"), code: InvalidTransaction},
+		{err: errors.New("duplicate merchant reference"), code: DuplicateTransaction},
+		{err: errors.New("invalid merchant credentials"), code: DoNotHonour},
+		{err: errors.New("invalid merchant"), code: FormatError},
+		{err: errors.New("account closed"), code: DoNotHonour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.code, responseCode(tt.err))
+		})
+	}
+}
+
+func TestServe_FormatError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+
+	defer listener.Close()
+
+	go New(&servicemock.Mock{}, iso8583.DefaultSpec(), merchantKeys).Serve(listener)
+
+	spec := iso8583.DefaultSpec()
+
+	spec.Fields[5] = iso8583.Field{Name: "amount_settlement", Type: iso8583.Fixed, Length: 12, Charset: iso8583.Numeric}
+
+	client, err := iso8583.Dial(listener.Addr().String(), spec)
+
+	assert.NoError(t, err)
+
+	defer client.Close()
+
+	req := iso8583.NewMessage("0100")
+
+	req.Fields[5] = "1"
+
+	res, err := client.Send(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0110", res.MTI)
+	assert.Equal(t, FormatError, res.Fields[39])
+}
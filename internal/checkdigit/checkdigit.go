@@ -0,0 +1,58 @@
+// Package checkdigit implements the check digit schemes used by payment
+// identifiers.
+package checkdigit
+
+// Mod97 returns the ISO 7064 MOD 97-10 remainder of an alphanumeric string,
+// reading letters as 10 to 35 the way ISO 13616 (IBAN) and ISO 11649
+// (creditor reference) do. ok is false for any other character.
+func Mod97(value string) (int, bool) {
+	remainder := 0
+
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return 0, false
+		}
+	}
+
+	return remainder, true
+}
+
+// Luhn returns the ISO/IEC 7812 check digit to append to a string of
+// decimal digits, as used by payment card numbers. ok is false for any
+// other character.
+func Luhn(value string) (int, bool) {
+	sum := 0
+
+	// Doubling starts from the rightmost digit of the payload, which ends up
+	// second from the right once the check digit is appended.
+	double := true
+
+	for i := len(value) - 1; i >= 0; i-- {
+		r := value[i]
+
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+
+		digit := int(r - '0')
+
+		if double {
+			digit *= 2
+
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+
+		double = !double
+	}
+
+	return (10 - sum%10) % 10, true
+}
@@ -0,0 +1,56 @@
+package checkdigit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMod97_Ok(t *testing.T) {
+	res, ok := Mod97("539007547034RF18")
+
+	assert.Equal(t, 1, res)
+	assert.True(t, ok)
+
+	res, ok = Mod97("WEST12345698765432GB82")
+
+	assert.Equal(t, 1, res)
+	assert.True(t, ok)
+
+	res, ok = Mod97("12")
+
+	assert.Equal(t, 12, res)
+	assert.True(t, ok)
+}
+
+func TestMod97_Err(t *testing.T) {
+	for _, value := range []string{"rf18", "RF 18", "RF-18"} {
+		res, ok := Mod97(value)
+
+		assert.Equal(t, 0, res, value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestLuhn_Ok(t *testing.T) {
+	for value, expected := range map[string]int{
+		"7992739871":      3,
+		"411111111111111": 1,
+		"555555555555444": 4,
+		"":                0,
+	} {
+		res, ok := Luhn(value)
+
+		assert.Equal(t, expected, res, value)
+		assert.True(t, ok, value)
+	}
+}
+
+func TestLuhn_Err(t *testing.T) {
+	for _, value := range []string{"4111 1111", "41111111111111a"} {
+		res, ok := Luhn(value)
+
+		assert.Equal(t, 0, res, value)
+		assert.False(t, ok, value)
+	}
+}
@@ -0,0 +1,184 @@
+// Package cop implements confirmation of payee: checking the name a payer
+// gives for an account against the name of its holder before money moves.
+package cop
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+)
+
+const (
+	// maxTypos is the most single character edits a close match may need.
+	maxTypos = 2
+
+	// typoRatio keeps short names strict: the edits may be at most one in
+	// typoRatio characters of the holder's name.
+	typoRatio = 5
+)
+
+var titles = map[string]struct{}{
+	"mr":   {},
+	"mrs":  {},
+	"ms":   {},
+	"miss": {},
+	"mx":   {},
+	"dr":   {},
+	"prof": {},
+	"sir":  {},
+}
+
+// Match compares the name a payer supplied with the account holder's. Case,
+// punctuation and titles are ignored. A close match is a small typo, the
+// same names in another order, or initials and missing middle names before
+// the right surname.
+func Match(supplied string, actual string) domain.PayeeMatch {
+	suppliedTokens := tokens(supplied)
+	actualTokens := tokens(actual)
+
+	if len(suppliedTokens) == 0 || len(actualTokens) == 0 {
+		return domain.PayeeMatchNone
+	}
+
+	if slices.Equal(suppliedTokens, actualTokens) {
+		return domain.PayeeMatchExact
+	}
+
+	if closeSpelling(strings.Join(suppliedTokens, " "), strings.Join(actualTokens, " ")) ||
+		reordered(suppliedTokens, actualTokens) ||
+		abbreviated(suppliedTokens, actualTokens) {
+		return domain.PayeeMatchClose
+	}
+
+	return domain.PayeeMatchNone
+}
+
+// Best returns the closest of the outcomes, so a name can be checked against
+// every holder of a joint account.
+func Best(matches ...domain.PayeeMatch) domain.PayeeMatch {
+	best := domain.PayeeMatchNone
+
+	for _, match := range matches {
+		switch match {
+		case domain.PayeeMatchExact:
+			return match
+		case domain.PayeeMatchClose:
+			best = match
+		}
+	}
+
+	return best
+}
+
+// tokens lowercases a name and splits it into words, joining across
+// apostrophes so O'Brien and OBrien compare equal, and drops titles.
+func tokens(name string) []string {
+	name = strings.Map(
+		func(r rune) rune {
+			if r == '\'' || r == '’' {
+				return -1
+			}
+
+			return unicode.ToLower(r)
+		},
+		name,
+	)
+
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	res := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		if _, title := titles[field]; title {
+			continue
+		}
+
+		res = append(res, field)
+	}
+
+	return res
+}
+
+func closeSpelling(supplied string, actual string) bool {
+	edits := distance(supplied, actual)
+
+	return edits <= maxTypos && edits*typoRatio <= utf8.RuneCountInString(actual)
+}
+
+func reordered(supplied []string, actual []string) bool {
+	supplied = slices.Clone(supplied)
+	actual = slices.Clone(actual)
+
+	slices.Sort(supplied)
+	slices.Sort(actual)
+
+	return slices.Equal(supplied, actual)
+}
+
+// abbreviated reports whether the supplied name has the holder's surname and,
+// in order, some of their given names, each in full or as an initial.
+func abbreviated(supplied []string, actual []string) bool {
+	last := len(supplied) - 1
+
+	if last < 1 || len(supplied) > len(actual) || supplied[last] != actual[len(actual)-1] {
+		return false
+	}
+
+	given := actual[:len(actual)-1]
+
+	i := 0
+
+	for _, token := range supplied[:last] {
+		for i < len(given) && !abbreviates(token, given[i]) {
+			i++
+		}
+
+		if i == len(given) {
+			return false
+		}
+
+		i++
+	}
+
+	return true
+}
+
+func abbreviates(token string, name string) bool {
+	return token == name || utf8.RuneCountInString(token) == 1 && strings.HasPrefix(name, token)
+}
+
+// distance is the Levenshtein distance between two strings, in runes.
+func distance(a string, b string) int {
+	x := []rune(a)
+	y := []rune(b)
+
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(y)]
+}
@@ -0,0 +1,61 @@
+package cop
+
+import (
+	"testing"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch_Exact(t *testing.T) {
+	for _, supplied := range []string{
+		"Jane Smith",
+		"jane smith",
+		"  JANE   SMITH ",
+		"Mrs Jane Smith",
+		"Dr. Jane Smith",
+	} {
+		assert.Equal(t, domain.PayeeMatchExact, Match(supplied, "Jane Smith"), supplied)
+	}
+
+	assert.Equal(t, domain.PayeeMatchExact, Match("Sean OBrien", "Sean O'Brien"))
+	assert.Equal(t, domain.PayeeMatchExact, Match("Ana Smith Jones", "Ana Smith-Jones"))
+}
+
+func TestMatch_Close(t *testing.T) {
+	for supplied, actual := range map[string]string{
+		"Jon Smith":            "John Smith",
+		"Jane Smyth":           "Jane Smith",
+		"Smith, Jane":          "Jane Smith",
+		"J Smith":              "Jane Smith",
+		"J. A. Smith":          "Jane Alice Smith",
+		"Jane Smith":           "Jane Alice Smith",
+		"Alice Smith":          "Jane Alice Smith",
+		"Mr Jonathon Smithers": "Jonathan Smithers",
+	} {
+		assert.Equal(t, domain.PayeeMatchClose, Match(supplied, actual), supplied)
+	}
+}
+
+func TestMatch_None(t *testing.T) {
+	for supplied, actual := range map[string]string{
+		"":           "Jane Smith",
+		"Jane Smith": "",
+		"Mr":         "Jane Smith",
+		"Smith":      "Jane Smith",
+		"John Doe":   "Jane Smith",
+		"Jim Smith":  "Jane Smith",
+		"Al":         "Bo",
+		"A Jones":    "Alice Smith",
+		"Alice J":    "Alice Jones",
+		"B A Smith":  "Alice Bea Smith",
+	} {
+		assert.Equal(t, domain.PayeeMatchNone, Match(supplied, actual), supplied)
+	}
+}
+
+func TestBest_Ok(t *testing.T) {
+	assert.Equal(t, domain.PayeeMatchNone, Best())
+	assert.Equal(t, domain.PayeeMatchClose, Best(domain.PayeeMatchNone, domain.PayeeMatchClose))
+	assert.Equal(t, domain.PayeeMatchExact, Best(domain.PayeeMatchClose, domain.PayeeMatchExact, domain.PayeeMatchNone))
+}
@@ -1,28 +1,582 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
 
 type User struct {
-	ID         string
-	CreatedAt  time.Time
-	Active     bool
-	Name       string
-	AccountIDs map[string]struct{}
+	ID                 string
+	CreatedAt          time.Time
+	Status             UserStatus
+	PreviousStatus     UserStatus
+	StatusReason       string
+	StatusUpdatedAt    time.Time
+	Name               string
+	AccountIDs         map[string]struct{}
+	KYC                KYC
+	DeactivatedAt      time.Time
+	DeactivationReason string
+	ClosedAt           time.Time
+	Tier               string
+
+	// DeactivatedAccountIDs are the accounts a deactivation closed, which
+	// reactivating reopens.
+	DeactivatedAccountIDs []string
 }
 
+// UserStatus is where a user is in onboarding. PreviousStatus on the user
+// records the status a suspension interrupted, so lifting it can restore it.
+type UserStatus string
+
+const (
+	UserPending     UserStatus = "pending"
+	UserKYCInReview UserStatus = "kyc_in_review"
+	UserVerified    UserStatus = "verified"
+	UserRestricted  UserStatus = "restricted"
+	UserSuspended   UserStatus = "suspended"
+	UserClosed      UserStatus = "closed"
+)
+
+type KYC struct {
+	DateOfBirth time.Time
+	Address     Address
+	IDNumber    string
+	SubmittedAt time.Time
+}
+
+type Address struct {
+	Line1      string
+	Line2      string
+	City       string
+	PostalCode string
+	Country    string
+}
+
+type Role string
+
+const (
+	RoleOwner   Role = "owner"
+	RoleCoOwner Role = "co_owner"
+	RoleViewer  Role = "viewer"
+)
+
 type Account struct {
-	ID           string
-	CreatedAt    time.Time
-	Balance      int
-	Transactions []Transaction
+	ID                    string
+	IBAN                  string
+	CreatedAt             time.Time
+	ClosedAt              time.Time
+	FrozenAt              time.Time
+	MaturesAt             time.Time
+	Balance               money.Money
+	Transactions          []Transaction
+	Holders               map[string]Role
+	DualApprovalThreshold money.Money
+	Product               Product
+}
+
+type ProductType string
+
+const (
+	ProductChecking    ProductType = "checking"
+	ProductSavings     ProductType = "savings"
+	ProductTermDeposit ProductType = "term_deposit"
+)
+
+const (
+	OperationDeposit     = "deposit"
+	OperationWithdraw    = "withdraw"
+	OperationTransfer    = "transfer"
+	OperationTransferIn  = "transfer_in"
+	OperationTransferOut = "transfer_out"
+	OperationFee         = "fee"
+	OperationFeeRefund   = "fee_refund"
+	OperationReversal    = "reversal"
+
+	// OperationHold reserves funds for a pending transfer or a card
+	// authorisation and OperationHoldRelease gives them back. OperationPending
+	// is a notice on the receiver's account with no effect on its balance.
+	OperationHold        = "hold"
+	OperationHoldRelease = "hold_release"
+	OperationPending     = "pending_transfer"
+
+	// OperationCardPayment is a captured card authorisation. The hold placed
+	// when the card was authorised is released as it is booked.
+	OperationCardPayment = "card_payment"
+
+	// OperationLoanDisbursement pays a loan's principal into the borrower's
+	// account and OperationLoanRepayment collects instalments and payoffs.
+	OperationLoanDisbursement = "loan_disbursement"
+	OperationLoanRepayment    = "loan_repayment"
+
+	// OperationPotIn moves money from the account balance into one of the
+	// account's pots and OperationPotOut moves it back. The money stays in
+	// the account but only the balance outside pots can be spent.
+	OperationPotIn  = "pot_in"
+	OperationPotOut = "pot_out"
+)
+
+type Product struct {
+	ID                     string
+	Version                int
+	CreatedAt              time.Time
+	Name                   string
+	Type                   ProductType
+	Currency               money.Currency
+	Operations             []string
+	MonthlyWithdrawalLimit int
+	MinimumBalance         money.Money
+	InterestRateBps        int
+	WithdrawalFee          money.Money
+	TransferFee            money.Money
+	MonthlyFee             money.Money
+	TermDays               int
+	EarlyBreakPenaltyBps   int
 }
 
 type Transaction struct {
+	ID                string
 	Timestamp         time.Time
 	Operation         string
-	Amount            int
+	Amount            money.Money
 	ReceiverUserID    string
 	SenderUserID      string
 	ReceiverAccountID string
 	SenderAccountID   string
+	Description       string
+	Reference         string
+	Metadata          map[string]string
+}
+
+type BatchStatus string
+
+const (
+	BatchPending             BatchStatus = "pending"
+	BatchProcessing          BatchStatus = "processing"
+	BatchCompleted           BatchStatus = "completed"
+	BatchCompletedWithErrors BatchStatus = "completed_with_errors"
+	BatchFailed              BatchStatus = "failed"
+)
+
+type BatchLineStatus string
+
+const (
+	BatchLinePending    BatchLineStatus = "pending"
+	BatchLineCompleted  BatchLineStatus = "completed"
+	BatchLineHeld       BatchLineStatus = "held"
+	BatchLineFailed     BatchLineStatus = "failed"
+	BatchLineRolledBack BatchLineStatus = "rolled_back"
+	BatchLineSkipped    BatchLineStatus = "skipped"
+)
+
+type Batch struct {
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	SenderUserID    string
+	SenderAccountID string
+	Format          string
+	AllOrNothing    bool
+	Status          BatchStatus
+	Lines           []BatchLine
+}
+
+type BatchLine struct {
+	Line              int
+	ReceiverUserID    string
+	ReceiverAccountID string
+	Amount            money.Money
+	Status            BatchLineStatus
+	Error             string
+}
+
+type LimitScope string
+
+const (
+	LimitScopeUser    LimitScope = "user"
+	LimitScopeAccount LimitScope = "account"
+)
+
+// VelocityLimit caps an outgoing operation over a rolling window. A zero
+// MaxAmount or MaxCount leaves that dimension uncapped. A limit with a
+// MaxAmount only counts operations in that amount's currency.
+type VelocityLimit struct {
+	Scope     LimitScope
+	Operation string
+	Window    time.Duration
+	MaxAmount money.Money
+	MaxCount  int
+}
+
+type Tier struct {
+	ID        string
+	UpdatedAt time.Time
+	Limits    []VelocityLimit
+}
+
+// PayeeMatch is the confirmation of payee outcome: how the name a payer gave
+// compares with the name of the account holder.
+type PayeeMatch string
+
+const (
+	PayeeMatchExact PayeeMatch = "exact"
+	PayeeMatchClose PayeeMatch = "close"
+	PayeeMatchNone  PayeeMatch = "no_match"
+)
+
+// Payee is an entry in a user's payee book. Transfers to it are held back
+// until ActiveAt, the end of the cooling-off period after it was added.
+type Payee struct {
+	ID             string
+	UserID         string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ActiveAt       time.Time
+	Nickname       string
+	Name           string
+	Match          PayeeMatch
+	AccountID      string
+	IBAN           string
+	ReceiverUserID string
+}
+
+// PaymentRequestStatus is open while any payer has yet to respond and
+// completed once all have paid or declined. Cancelling or expiring a request
+// closes the shares still pending.
+type PaymentRequestStatus string
+
+const (
+	PaymentRequestOpen      PaymentRequestStatus = "open"
+	PaymentRequestCompleted PaymentRequestStatus = "completed"
+	PaymentRequestCancelled PaymentRequestStatus = "cancelled"
+	PaymentRequestExpired   PaymentRequestStatus = "expired"
+)
+
+type PayerStatus string
+
+const (
+	PayerPending   PayerStatus = "pending"
+	PayerPaid      PayerStatus = "paid"
+	PayerDeclined  PayerStatus = "declined"
+	PayerCancelled PayerStatus = "cancelled"
+	PayerExpired   PayerStatus = "expired"
+)
+
+// PaymentRequest asks one or more payers for money into the requester's
+// account, each for their own share. Version is bumped on every update so
+// concurrent responses cannot overwrite each other.
+type PaymentRequest struct {
+	ID              string
+	Version         int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	ExpiresAt       time.Time
+	RequesterUserID string
+	AccountID       string
+	Amount          money.Money
+	Description     string
+	Status          PaymentRequestStatus
+	Payers          []Payer
+}
+
+type Payer struct {
+	UserID        string
+	Amount        money.Money
+	Status        PayerStatus
+	RespondedAt   time.Time
+	TransactionID string
+}
+
+// PendingTransferStatus is where a pending transfer is. Only a pending one
+// still holds the sender's funds; any other status is final.
+type PendingTransferStatus string
+
+const (
+	PendingTransferPending   PendingTransferStatus = "pending"
+	PendingTransferAccepted  PendingTransferStatus = "accepted"
+	PendingTransferDeclined  PendingTransferStatus = "declined"
+	PendingTransferCancelled PendingTransferStatus = "cancelled"
+	PendingTransferExpired   PendingTransferStatus = "expired"
+)
+
+// PendingTransfer is a transfer the receiver has to accept before ExpiresAt.
+// Amount and Fee are held from the sender's account until then. Version is
+// bumped on every update so an accept and a cancel cannot both go through.
+type PendingTransfer struct {
+	ID                string
+	Version           int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	ExpiresAt         time.Time
+	RespondedAt       time.Time
+	SenderUserID      string
+	SenderAccountID   string
+	ReceiverUserID    string
+	ReceiverAccountID string
+	Amount            money.Money
+	Fee               money.Money
+	Description       string
+	Reference         string
+	Metadata          map[string]string
+	Status            PendingTransferStatus
+	TransactionID     string
+}
+
+// MandateFrequency caps how often a creditor can collect on a mandate: once
+// ever, or once per calendar week, month, quarter or year.
+type MandateFrequency string
+
+const (
+	MandateOneOff    MandateFrequency = "one_off"
+	MandateWeekly    MandateFrequency = "weekly"
+	MandateMonthly   MandateFrequency = "monthly"
+	MandateQuarterly MandateFrequency = "quarterly"
+	MandateYearly    MandateFrequency = "yearly"
+)
+
+type MandateStatus string
+
+const (
+	MandateActive  MandateStatus = "active"
+	MandateRevoked MandateStatus = "revoked"
+)
+
+// CollectionStatus is pending while the debit is being booked. A failed
+// collection is kept so the creditor can see why it failed.
+type CollectionStatus string
+
+const (
+	CollectionPending   CollectionStatus = "pending"
+	CollectionCompleted CollectionStatus = "completed"
+	CollectionFailed    CollectionStatus = "failed"
+	CollectionRefunded  CollectionStatus = "refunded"
+)
+
+// Mandate lets the holders of CreditorAccountID collect up to MaxAmount at a
+// time from the debtor's account. Version is bumped on every update so two
+// collections cannot both take the same period.
+type Mandate struct {
+	ID                string
+	Version           int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	RevokedAt         time.Time
+	DebtorUserID      string
+	DebtorAccountID   string
+	CreditorAccountID string
+	CreditorIBAN      string
+	MaxAmount         money.Money
+	Frequency         MandateFrequency
+	Status            MandateStatus
+	Collections       []Collection
+}
+
+// Collection is one debit made under a mandate. The debtor can have it
+// refunded until RefundableUntil.
+type Collection struct {
+	ID              string
+	CreatedAt       time.Time
+	CreditorUserID  string
+	Amount          money.Money
+	Fee             money.Money
+	Description     string
+	Reference       string
+	Status          CollectionStatus
+	FailureReason   string
+	TransactionID   string
+	RefundableUntil time.Time
+	RefundedAt      time.Time
+}
+
+type CardStatus string
+
+const (
+	CardActive    CardStatus = "active"
+	CardFrozen    CardStatus = "frozen"
+	CardLocked    CardStatus = "locked"
+	CardCancelled CardStatus = "cancelled"
+)
+
+type AuthorisationStatus string
+
+const (
+	AuthorisationHeld     AuthorisationStatus = "held"
+	AuthorisationCaptured AuthorisationStatus = "captured"
+	AuthorisationReversed AuthorisationStatus = "reversed"
+	AuthorisationExpired  AuthorisationStatus = "expired"
+)
+
+// Card is a virtual debit card on an account, issued to one of its holders.
+// Only a salted hash of the CVV is kept. The card is valid until the end of
+// its expiry month.
+type Card struct {
+	ID             string
+	Version        int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	CancelledAt    time.Time
+	UserID         string
+	AccountID      string
+	PAN            string
+	ExpiryMonth    int
+	ExpiryYear     int
+	CVVSalt        string
+	CVVHash        string
+	FailedChecks   int
+	Status         CardStatus
+	Limits         CardLimits
+	Authorisations []CardAuthorisation
+}
+
+// CardLimits caps what the card can authorise. A zero amount is no limit.
+type CardLimits struct {
+	PerTransaction money.Money
+	Daily          money.Money
+}
+
+// CardAuthorisation is a merchant's claim on the card. Its amount is held on
+// the account until the merchant captures it, for at most the held amount,
+// or reverses it, or the hold expires.
+type CardAuthorisation struct {
+	ID                string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	ExpiresAt         time.Time
+	Merchant          string
+	Reference         string
+	Amount            money.Money
+	CapturedAmount    money.Money
+	Status            AuthorisationStatus
+	HoldTransactionID string
+	TransactionID     string
+}
+
+type LoanSchedule string
+
+const (
+	// LoanAnnuity repays the loan in equal monthly instalments, mostly
+	// interest at first. LoanLinear repays the same principal every month,
+	// so instalments shrink as the interest does.
+	LoanAnnuity LoanSchedule = "annuity"
+	LoanLinear  LoanSchedule = "linear"
+)
+
+type LoanStatus string
+
+const (
+	LoanActive LoanStatus = "active"
+	LoanRepaid LoanStatus = "repaid"
+)
+
+type InstalmentStatus string
+
+const (
+	InstalmentScheduled InstalmentStatus = "scheduled"
+	InstalmentOverdue   InstalmentStatus = "overdue"
+	InstalmentPaid      InstalmentStatus = "paid"
+	InstalmentSettled   InstalmentStatus = "settled"
+)
+
+// Loan is money lent to a user, paid into AccountID and collected from it
+// in monthly instalments. InterestRateBps is the nominal yearly rate.
+type Loan struct {
+	ID                        string
+	Version                   int
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	RepaidAt                  time.Time
+	UserID                    string
+	AccountID                 string
+	Principal                 money.Money
+	InterestRateBps           int
+	TermMonths                int
+	Schedule                  LoanSchedule
+	LateFee                   money.Money
+	Status                    LoanStatus
+	DisbursementTransactionID string
+	Instalments               []LoanInstalment
+	Repayments                []LoanRepayment
+}
+
+// LoanInstalment is one month of the amortisation schedule. An instalment
+// not collected on its due date is overdue and is charged the loan's late
+// fee once. Instalments not yet due when the loan is paid off are settled.
+type LoanInstalment struct {
+	Number    int
+	DueAt     time.Time
+	Principal money.Money
+	Interest  money.Money
+	LateFee   money.Money
+	Status    InstalmentStatus
+	PaidAt    time.Time
+}
+
+// LoanRepayment is money collected for a loan: one instalment, or every
+// instalment left when Payoff is set.
+type LoanRepayment struct {
+	TransactionID string
+	CreatedAt     time.Time
+	Instalment    int
+	Payoff        bool
+	Amount        money.Money
+	Principal     money.Money
+	Interest      money.Money
+	Fees          money.Money
+}
+
+type AutoSaveRuleType string
+
+const (
+	AutoSaveRoundUp           AutoSaveRuleType = "round_up"
+	AutoSaveDepositPercentage AutoSaveRuleType = "deposit_percentage"
+	AutoSaveWeekly            AutoSaveRuleType = "weekly"
+)
+
+// Pot ring-fences part of the money in AccountID. Its Balance is not part of
+// the account balance, and a pot is closed by moving its balance back.
+type Pot struct {
+	ID        string
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ClosedAt  time.Time
+	AccountID string
+	Name      string
+	Balance   money.Money
+	Goal      money.Money
+	GoalDate  time.Time
+	AutoSave  []AutoSaveRule
+}
+
+// AutoSaveRule moves money into its pot without the user asking: what every
+// withdrawal needs to round up to a whole unit, PercentageBps of every
+// deposit, or Amount every week from NextRunAt.
+type AutoSaveRule struct {
+	Type          AutoSaveRuleType
+	PercentageBps int
+	Amount        money.Money
+	NextRunAt     time.Time
+}
+
+// Categorisation is how a user sees their transactions categorised: their
+// rules, tried in order before the built-in defaults, and the categories
+// they set by hand, by transaction id, which win over any rule.
+type Categorisation struct {
+	UserID    string
+	Version   int
+	UpdatedAt time.Time
+	Rules     []CategoryRule
+	Overrides map[string]string
+}
+
+// CategoryRule puts a transaction in Category when every condition set
+// matches: the other account of a transfer, a case-insensitive regular
+// expression on the description, and an inclusive range on the amount.
+type CategoryRule struct {
+	Category              string
+	CounterpartyAccountID string
+	DescriptionPattern    string
+	MinAmount             money.Money
+	MaxAmount             money.Money
 }
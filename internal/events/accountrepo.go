@@ -0,0 +1,63 @@
+package events
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+type accountRepo struct {
+	accountrepo.Repo
+	hub Hub
+}
+
+// WrapAccountRepo publishes every balance change and new transaction written
+// through repo, whichever part of the service made it.
+func WrapAccountRepo(repo accountrepo.Repo, hub Hub) accountrepo.Repo {
+	return &accountRepo{
+		Repo: repo,
+		hub:  hub,
+	}
+}
+
+func (r accountRepo) UpdateBalance(req accountrepo.UpdateBalanceRequest) (money.Money, error) {
+	balance, err := r.Repo.UpdateBalance(req)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	r.hub.Publish(
+		Event{
+			Type:      TypeBalance,
+			AccountID: req.ID,
+			Data: BalancePayload{
+				AccountID: req.ID,
+				Balance:   balance,
+			},
+		},
+	)
+
+	return balance, nil
+}
+
+func (r accountRepo) UpdateTransactions(req accountrepo.UpdateTransactionsRequest) error {
+	err := r.Repo.UpdateTransactions(req)
+
+	if err != nil {
+		return err
+	}
+
+	r.hub.Publish(
+		Event{
+			Type:      TypeTransaction,
+			AccountID: req.ID,
+			Data: TransactionPayload{
+				AccountID:   req.ID,
+				Transaction: service.NewTransactionResponse(req.ID, req.Transaction),
+			},
+		},
+	)
+
+	return nil
+}
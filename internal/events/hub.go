@@ -0,0 +1,200 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+const (
+	TypeBalance     = "balance"
+	TypeTransaction = "transaction"
+	TypeReset       = "reset"
+)
+
+const (
+	defaultBufferSize = 1024
+	subscriberBuffer  = 64
+)
+
+// Event is one change to an account. IDs increase across all accounts, so a
+// single Last-Event-ID resumes a stream covering several accounts.
+type Event struct {
+	ID        uint64
+	Type      string
+	AccountID string
+	Data      any
+}
+
+type Hub interface {
+	Publish(Event) Event
+	Subscribe(SubscribeRequest) (*Subscription, error)
+}
+
+// Subscription holds the buffered events after LastEventID followed by live
+// ones on Events. Replay starts with a reset event when the requested events
+// are no longer buffered, telling the client to refetch its state. Events is
+// closed if the subscriber falls too far behind; the client then reconnects
+// with its Last-Event-ID.
+type Subscription struct {
+	Replay []Event
+	Events <-chan Event
+	close  func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+type subscriber struct {
+	accountIDs map[string]struct{}
+	events     chan Event
+}
+
+type hub struct {
+	mux         sync.Mutex
+	size        int
+	buffer      []Event
+	next        int
+	lastID      uint64
+	subscribers map[*subscriber]struct{}
+}
+
+// NewHub returns a hub keeping the last size events for replay.
+func NewHub(size int) Hub {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+
+	return &hub{
+		size:        size,
+		buffer:      make([]Event, 0, size),
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+func (h *hub) Publish(event Event) Event {
+	h.mux.Lock()
+
+	defer h.mux.Unlock()
+
+	h.lastID++
+
+	event.ID = h.lastID
+
+	if len(h.buffer) < h.size {
+		h.buffer = append(h.buffer, event)
+	} else {
+		h.buffer[h.next] = event
+		h.next = (h.next + 1) % h.size
+	}
+
+	for sub := range h.subscribers {
+		if _, exists := sub.accountIDs[event.AccountID]; !exists {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+
+	return event
+}
+
+func (h *hub) Subscribe(req SubscribeRequest) (*Subscription, error) {
+	resume := req.LastEventID != ""
+
+	lastEventID := uint64(0)
+
+	if resume {
+		id, err := strconv.ParseUint(req.LastEventID, 10, 64)
+
+		if err != nil {
+			return nil, errors.New("invalid last event id")
+		}
+
+		lastEventID = id
+	}
+
+	sub := &subscriber{
+		accountIDs: make(map[string]struct{}, len(req.AccountIDs)),
+		events:     make(chan Event, subscriberBuffer),
+	}
+
+	for _, accountID := range req.AccountIDs {
+		sub.accountIDs[accountID] = struct{}{}
+	}
+
+	h.mux.Lock()
+
+	defer h.mux.Unlock()
+
+	replay := []Event{}
+
+	if resume {
+		replay = h.replay(sub, lastEventID)
+	}
+
+	h.subscribers[sub] = struct{}{}
+
+	return &Subscription{
+		Replay: replay,
+		Events: sub.events,
+		close: func() {
+			h.mux.Lock()
+
+			defer h.mux.Unlock()
+
+			h.remove(sub)
+		},
+	}, nil
+}
+
+func (h *hub) replay(sub *subscriber, lastEventID uint64) []Event {
+	oldestID := h.lastID + 1
+
+	if len(h.buffer) > 0 {
+		oldestID = h.buffer[h.next].ID
+	}
+
+	if lastEventID > h.lastID || lastEventID+1 < oldestID {
+		return []Event{
+			{
+				ID:   h.lastID,
+				Type: TypeReset,
+				Data: ResetPayload{
+					Reason: "events since last event id are no longer available",
+				},
+			},
+		}
+	}
+
+	replay := []Event{}
+
+	for i := range h.buffer {
+		event := h.buffer[(h.next+i)%len(h.buffer)]
+
+		if event.ID <= lastEventID {
+			continue
+		}
+
+		if _, exists := sub.accountIDs[event.AccountID]; exists {
+			replay = append(replay, event)
+		}
+	}
+
+	return replay
+}
+
+func (h *hub) remove(sub *subscriber) {
+	if _, exists := h.subscribers[sub]; !exists {
+		return
+	}
+
+	delete(h.subscribers, sub)
+
+	close(sub.events)
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe_ErrInvalidLastEventID(t *testing.T) {
+	hub := NewHub(4)
+
+	sub, err := hub.Subscribe(
+		SubscribeRequest{
+			AccountIDs:  []string{"1"},
+			LastEventID: "abc",
+		},
+	)
+
+	assert.Nil(t, sub)
+	assert.Equal(t, errors.New("invalid last event id"), err)
+}
+
+func TestSubscribe_Live(t *testing.T) {
+	hub := NewHub(4)
+
+	sub, err := hub.Subscribe(
+		SubscribeRequest{
+			AccountIDs: []string{"1"},
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Empty(t, sub.Replay)
+
+	hub.Publish(Event{Type: TypeBalance, AccountID: "2"})
+	hub.Publish(Event{Type: TypeBalance, AccountID: "1"})
+
+	event := <-sub.Events
+
+	assert.Equal(t, uint64(2), event.ID)
+	assert.Equal(t, "1", event.AccountID)
+
+	sub.Close()
+
+	_, ok := <-sub.Events
+
+	assert.False(t, ok)
+}
+
+func TestSubscribe_Replay(t *testing.T) {
+	hub := NewHub(4)
+
+	for _, accountID := range []string{"1", "2", "1", "1"} {
+		hub.Publish(Event{Type: TypeTransaction, AccountID: accountID})
+	}
+
+	sub, err := hub.Subscribe(
+		SubscribeRequest{
+			AccountIDs:  []string{"1"},
+			LastEventID: "1",
+		},
+	)
+
+	assert.Nil(t, err)
+
+	defer sub.Close()
+
+	ids := []uint64{}
+
+	for _, event := range sub.Replay {
+		ids = append(ids, event.ID)
+	}
+
+	assert.Equal(t, []uint64{3, 4}, ids)
+}
+
+func TestSubscribe_ReplayWrapped(t *testing.T) {
+	hub := NewHub(3)
+
+	for i := 0; i < 5; i++ {
+		hub.Publish(Event{Type: TypeBalance, AccountID: "1"})
+	}
+
+	sub, err := hub.Subscribe(
+		SubscribeRequest{
+			AccountIDs:  []string{"1"},
+			LastEventID: "2",
+		},
+	)
+
+	assert.Nil(t, err)
+
+	defer sub.Close()
+
+	ids := []uint64{}
+
+	for _, event := range sub.Replay {
+		ids = append(ids, event.ID)
+	}
+
+	assert.Equal(t, []uint64{3, 4, 5}, ids)
+}
+
+func TestSubscribe_Reset(t *testing.T) {
+	hub := NewHub(2)
+
+	for i := 0; i < 5; i++ {
+		hub.Publish(Event{Type: TypeBalance, AccountID: "1"})
+	}
+
+	for _, lastEventID := range []string{"1", "9"} {
+		sub, err := hub.Subscribe(
+			SubscribeRequest{
+				AccountIDs:  []string{"1"},
+				LastEventID: lastEventID,
+			},
+		)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(sub.Replay))
+		assert.Equal(t, TypeReset, sub.Replay[0].Type)
+		assert.Equal(t, uint64(5), sub.Replay[0].ID)
+
+		sub.Close()
+	}
+}
+
+func TestPublish_DropsLaggingSubscriber(t *testing.T) {
+	hub := NewHub(4)
+
+	sub, err := hub.Subscribe(
+		SubscribeRequest{
+			AccountIDs: []string{"1"},
+		},
+	)
+
+	assert.Nil(t, err)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		hub.Publish(Event{Type: TypeBalance, AccountID: "1"})
+	}
+
+	count := 0
+
+	for range sub.Events {
+		count++
+	}
+
+	assert.Equal(t, subscriberBuffer, count)
+
+	sub.Close()
+}
@@ -0,0 +1,20 @@
+package events
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+type BalancePayload struct {
+	AccountID string      `json:"account_id"`
+	Balance   money.Money `json:"balance"`
+}
+
+type TransactionPayload struct {
+	AccountID   string                      `json:"account_id"`
+	Transaction service.TransactionResponse `json:"transaction"`
+}
+
+type ResetPayload struct {
+	Reason string `json:"reason"`
+}
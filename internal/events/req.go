@@ -0,0 +1,6 @@
+package events
+
+type SubscribeRequest struct {
+	AccountIDs  []string
+	LastEventID string
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/batch"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
+)
+
+const (
+	modeAllOrNothing = "all_or_nothing"
+	modeBestEffort   = "best_effort"
+)
+
+type batchHdl struct {
+	batcher batch.Batcher
+}
+
+func NewBatch(batcher batch.Batcher) Handler {
+	return &batchHdl{
+		batcher: batcher,
+	}
+}
+
+func (h batchHdl) ConfigHandlers(router *gin.Engine) {
+	router.POST(baseURL+":user_id/accounts/:account_id/batches", h.submit)
+	router.GET(baseURL+":user_id/accounts/:account_id/batches/:batch_id", h.status)
+}
+
+func (h batchHdl) Operations() []openapi.Operation {
+	submit := withParams(
+		operation(
+			http.MethodPost,
+			baseURL+":user_id/accounts/:account_id/batches",
+			"Submit a bulk payment file (CSV or ISO 20022 pain.001)",
+			nil,
+			response(http.StatusCreated, "Batch executed", batch.BatchResponse{}),
+			response(http.StatusAccepted, "Batch accepted for asynchronous execution", batch.BatchResponse{}),
+		),
+		openapi.Param{Name: "format", In: "query", Description: "File format, taken from the Content-Type when absent", Enum: []string{batch.FormatCSV, batch.FormatPain001}},
+		openapi.Param{Name: "mode", In: "query", Description: "Execution mode (default all_or_nothing)", Enum: []string{modeAllOrNothing, modeBestEffort}},
+		openapi.Param{Name: "async", In: "query", Description: "Run in the background and poll the batch", Enum: []string{"true", "false"}},
+	)
+
+	submit.RawBody = []string{"text/csv", "application/xml"}
+
+	return []openapi.Operation{
+		submit,
+		operation(http.MethodGet, baseURL+":user_id/accounts/:account_id/batches/:batch_id", "Get the status and per-line results of a batch", nil, response(http.StatusOK, "Batch", batch.BatchResponse{})),
+	}
+}
+
+func (h batchHdl) submit(c *gin.Context) {
+	format := batchFormat(c)
+
+	allOrNothing, ok := batchMode(c.Query("mode"))
+
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch mode"})
+
+		return
+	}
+
+	file, err := c.GetRawData()
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	async := c.Query("async") == "true"
+
+	res, err := h.batcher.Submit(
+		batch.SubmitRequest{
+			UserID:       c.Param("user_id"),
+			AccountID:    c.Param("account_id"),
+			Format:       format,
+			AllOrNothing: allOrNothing,
+			Async:        async,
+			File:         file,
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if async {
+		c.JSON(http.StatusAccepted, res)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h batchHdl) status(c *gin.Context) {
+	res, err := h.batcher.Status(
+		batch.StatusRequest{
+			UserID:    c.Param("user_id"),
+			AccountID: c.Param("account_id"),
+			BatchID:   c.Param("batch_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func batchFormat(c *gin.Context) string {
+	if c.Query("format") != "" {
+		return c.Query("format")
+	}
+
+	contentType := c.ContentType()
+
+	switch {
+	case contentType == "text/csv":
+		return batch.FormatCSV
+	case strings.HasSuffix(contentType, "/xml"):
+		return batch.FormatPain001
+	default:
+		return ""
+	}
+}
+
+func batchMode(mode string) (bool, bool) {
+	switch mode {
+	case "", modeAllOrNothing:
+		return true, true
+	case modeBestEffort:
+		return false, true
+	default:
+		return false, false
+	}
+}
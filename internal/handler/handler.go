@@ -2,17 +2,33 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
 	"github.com/hetfdex/tiny-bank/internal/service"
 )
 
 const (
-	baseURL = "/api/v1/users/"
+	baseURL  = "/api/v1/users/"
+	adminURL = "/api/v1/admin/"
 )
 
 type Handler interface {
 	ConfigHandlers(router *gin.Engine)
+	Operations() []openapi.Operation
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+var statusOK = statusResponse{Status: "ok"}
+
+var transactionsParams = []openapi.Param{
+	{Name: "q", In: "query", Description: "Text searched in descriptions, references and metadata values"},
+	{Name: "reference", In: "query", Description: "Creditor reference, spaces and case ignored"},
+	{Name: "metadata", In: "query", Style: openapi.StyleDeepObject, Description: "Metadata pairs that must all match, e.g. metadata[order]=42"},
 }
 
 type hdl struct {
@@ -26,14 +42,69 @@ func New(svc service.Service) Handler {
 }
 
 func (h hdl) ConfigHandlers(router *gin.Engine) {
-	router.POST(baseURL, h.createUser)
-	router.POST(baseURL+":user_id", h.createAccount)
-	router.DELETE(baseURL+":user_id", h.deactivateUser)
-	router.PUT(baseURL+":user_id/accounts/:account_id", h.deposit)
-	router.PATCH(baseURL+":user_id/accounts/:account_id", h.withdraw)
-	router.POST(baseURL+":user_id/accounts/:account_id", h.transfer)
-	router.GET(baseURL+":user_id/accounts/:account_id", h.balance)
-	router.GET(baseURL+":user_id/accounts/:account_id/transactions", h.transactions)
+	v1 := router.Group("", deprecated)
+
+	v1.POST(baseURL, h.createUser)
+	v1.POST(baseURL+":user_id", h.createAccount)
+	v1.DELETE(baseURL+":user_id", h.deactivateUser)
+	v1.POST(baseURL+":user_id/reactivate", h.reactivateUser)
+	v1.PUT(baseURL+":user_id/kyc", h.submitKYC)
+	v1.GET(baseURL+":user_id/kyc", h.kyc)
+	v1.GET(baseURL+":user_id/accounts", h.accounts)
+	v1.PUT(baseURL+":user_id/accounts/:account_id", h.deposit)
+	v1.PATCH(baseURL+":user_id/accounts/:account_id", h.withdraw)
+	v1.POST(baseURL+":user_id/accounts/:account_id", h.transfer)
+	v1.GET(baseURL+":user_id/accounts/:account_id", h.balance)
+	v1.GET(baseURL+":user_id/accounts/:account_id/transactions", h.transactions)
+	v1.GET(baseURL+":user_id/accounts/:account_id/holders", h.holders)
+	v1.POST(baseURL+":user_id/accounts/:account_id/holders", h.addHolder)
+	v1.DELETE(baseURL+":user_id/accounts/:account_id/holders/:holder_user_id", h.removeHolder)
+	v1.PUT(baseURL+":user_id/accounts/:account_id/rules", h.updateAccountRules)
+
+	router.POST(adminURL+"products", h.createProduct)
+	router.GET(adminURL+"products", h.products)
+	router.GET(adminURL+"products/:product_id", h.product)
+	router.PUT(adminURL+"products/:product_id", h.updateProduct)
+	router.GET(adminURL+"tiers", h.tiers)
+	router.GET(adminURL+"tiers/:tier_id", h.tier)
+	router.PUT(adminURL+"tiers/:tier_id", h.putTier)
+	router.PUT(adminURL+"users/:user_id/tier", h.updateUserTier)
+	router.PUT(adminURL+"users/:user_id/status", h.updateUserStatus)
+	router.POST(adminURL+"loans", h.grantLoan)
+}
+
+func (h hdl) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		v1Operation(http.MethodPost, baseURL, "Create a new user", service.CreateUserRequest{}, response(http.StatusCreated, "User created", service.CreateUserResponse{})),
+		v1Operation(http.MethodPost, baseURL+":user_id", "Create an account for a user", optional(service.CreateAccountRequest{}), response(http.StatusCreated, "Account created", service.CreateAccountResponse{})),
+		v1Operation(http.MethodDelete, baseURL+":user_id", "Deactivate a user, sweeping balances to a payout account and closing all accounts", optional(service.DeactivateUserRequest{}), response(http.StatusOK, "User deactivated, returns the closing statement", service.DeactivateUserResponse{})),
+		v1Operation(http.MethodPost, baseURL+":user_id/reactivate", "Reactivate a deactivated user within the grace period", nil, response(http.StatusOK, "User reactivated", statusResponse{})),
+		v1Operation(http.MethodPut, baseURL+":user_id/kyc", "Submit KYC documents, moving a pending or restricted user to kyc_in_review", service.SubmitKYCRequest{}, response(http.StatusOK, "KYC submitted", service.KYCResponse{})),
+		v1Operation(http.MethodGet, baseURL+":user_id/kyc", "Get a user's onboarding status and KYC record (ID number masked)", nil, response(http.StatusOK, "KYC record", service.KYCResponse{})),
+		v1Operation(http.MethodGet, baseURL+":user_id/accounts", "List the accounts a user holds", nil, response(http.StatusOK, "Accounts", service.AccountsResponse{})),
+		v1Operation(http.MethodPut, baseURL+":user_id/accounts/:account_id", "Deposit money into an account", service.DepositRequest{}, response(http.StatusOK, "Deposit booked", service.DepositResponse{})),
+		v1Operation(http.MethodPatch, baseURL+":user_id/accounts/:account_id", "Withdraw money from an account", service.WithdrawRequest{}, response(http.StatusOK, "Withdrawal booked", service.WithdrawResponse{})),
+		v1Operation(http.MethodPost, baseURL+":user_id/accounts/:account_id", "Transfer money between accounts", omit(service.TransferRequest{}, "sender_user_id", "sender_account_id"), response(http.StatusOK, "Transfer booked", service.TransferResponse{})),
+		v1Operation(http.MethodGet, baseURL+":user_id/accounts/:account_id", "Get account balance", nil, response(http.StatusOK, "Balance", service.BalanceResponse{})),
+		withParams(v1Operation(http.MethodGet, baseURL+":user_id/accounts/:account_id/transactions", "Get transaction history, optionally searched", nil, response(http.StatusOK, "Transactions", service.TransactionsResponse{})), transactionsParams...),
+		v1Operation(http.MethodGet, baseURL+":user_id/accounts/:account_id/holders", "List the holders of an account and its rules", nil, response(http.StatusOK, "Holders", service.HoldersResponse{})),
+		v1Operation(http.MethodPost, baseURL+":user_id/accounts/:account_id/holders", "Add a holder to an account or change their role (owners only)", service.AddHolderRequest{}, response(http.StatusCreated, "Holder added", statusResponse{})),
+		v1Operation(http.MethodDelete, baseURL+":user_id/accounts/:account_id/holders/:holder_user_id", "Remove a holder from an account (owners, or the holder themselves)", nil, response(http.StatusOK, "Holder removed", statusResponse{})),
+		v1Operation(http.MethodPut, baseURL+":user_id/accounts/:account_id/rules", "Update account rules (owners only)", service.UpdateAccountRulesRequest{}, response(http.StatusOK, "Rules updated", statusResponse{})),
+		adminOperation(http.MethodPost, adminURL+"products", "Create a product", service.CreateProductRequest{}, response(http.StatusCreated, "Product created", service.ProductResponse{})),
+		adminOperation(http.MethodGet, adminURL+"products", "List the latest version of every product", nil, response(http.StatusOK, "Products", service.ProductsResponse{})),
+		withParams(
+			adminOperation(http.MethodGet, adminURL+"products/:product_id", "Get a product (latest version unless a version is given)", nil, response(http.StatusOK, "Product", service.ProductResponse{})),
+			openapi.Param{Name: "version", In: "query", Description: "Product version"},
+		),
+		adminOperation(http.MethodPut, adminURL+"products/:product_id", "Publish a new version of a product (existing accounts keep their terms)", service.UpdateProductRequest{}, response(http.StatusOK, "Product version published", service.ProductResponse{})),
+		adminOperation(http.MethodGet, adminURL+"tiers", "List velocity limit tiers", nil, response(http.StatusOK, "Tiers", service.TiersResponse{})),
+		adminOperation(http.MethodGet, adminURL+"tiers/:tier_id", "Get a velocity limit tier", nil, response(http.StatusOK, "Tier", service.TierResponse{})),
+		adminOperation(http.MethodPut, adminURL+"tiers/:tier_id", "Create a tier or replace its limits (applies to every user in the tier)", service.PutTierRequest{}, response(http.StatusOK, "Tier saved", service.TierResponse{})),
+		adminOperation(http.MethodPut, adminURL+"users/:user_id/tier", "Move a user to another tier (an empty tier_id removes all limits)", service.UpdateUserTierRequest{}, response(http.StatusOK, "Tier updated", statusResponse{})),
+		adminOperation(http.MethodPut, adminURL+"users/:user_id/status", "Move a user to another onboarding status (review outcome, restriction, suspension or lifting it)", service.UpdateUserStatusRequest{}, response(http.StatusOK, "Status updated", statusResponse{})),
+		adminOperation(http.MethodPost, adminURL+"loans", "Grant a loan, paying the principal into the borrower's account", service.GrantLoanRequest{}, response(http.StatusCreated, "Loan granted, with its amortisation schedule", service.LoanResponse{})),
+	}
 }
 
 func (h hdl) createUser(c *gin.Context) {
@@ -59,11 +130,19 @@ func (h hdl) createUser(c *gin.Context) {
 }
 
 func (h hdl) createAccount(c *gin.Context) {
-	res, err := h.svc.CreateAccount(
-		service.CreateAccountRequest{
-			UserID: c.Param("user_id"),
-		},
-	)
+	req := service.CreateAccountRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	res, err := h.svc.CreateAccount(req)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -75,8 +154,32 @@ func (h hdl) createAccount(c *gin.Context) {
 }
 
 func (h hdl) deactivateUser(c *gin.Context) {
-	err := h.svc.DeactivateUser(
-		service.DeactivateUserRequest{
+	req := service.DeactivateUserRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	res, err := h.svc.DeactivateUser(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) reactivateUser(c *gin.Context) {
+	err := h.svc.ReactivateUser(
+		service.ReactivateUserRequest{
 			UserID: c.Param("user_id"),
 		},
 	)
@@ -87,7 +190,7 @@ func (h hdl) deactivateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	c.JSON(http.StatusOK, statusOK)
 }
 
 func (h hdl) deposit(c *gin.Context) {
@@ -183,8 +286,20 @@ func (h hdl) balance(c *gin.Context) {
 }
 
 func (h hdl) transactions(c *gin.Context) {
-	res, err := h.svc.Transactions(
-		service.TransactionsRequest{
+	res, err := h.svc.Transactions(transactionsRequest(c, c.Param("user_id")))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) holders(c *gin.Context) {
+	res, err := h.svc.Holders(
+		service.HoldersRequest{
 			UserID:    c.Param("user_id"),
 			AccountID: c.Param("account_id"),
 		},
@@ -198,3 +313,364 @@ func (h hdl) transactions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, res)
 }
+
+func (h hdl) accounts(c *gin.Context) {
+	res, err := h.svc.Accounts(
+		service.AccountsRequest{
+			UserID: c.Param("user_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) addHolder(c *gin.Context) {
+	req := service.AddHolderRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+	req.AccountID = c.Param("account_id")
+
+	err = h.svc.AddHolder(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, statusOK)
+}
+
+func (h hdl) removeHolder(c *gin.Context) {
+	err := h.svc.RemoveHolder(
+		service.RemoveHolderRequest{
+			UserID:       c.Param("user_id"),
+			AccountID:    c.Param("account_id"),
+			HolderUserID: c.Param("holder_user_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, statusOK)
+}
+
+func (h hdl) updateAccountRules(c *gin.Context) {
+	req := service.UpdateAccountRulesRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+	req.AccountID = c.Param("account_id")
+
+	err = h.svc.UpdateAccountRules(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, statusOK)
+}
+
+func (h hdl) createProduct(c *gin.Context) {
+	req := service.CreateProductRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	res, err := h.svc.CreateProduct(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h hdl) updateProduct(c *gin.Context) {
+	req := service.UpdateProductRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.ProductID = c.Param("product_id")
+
+	res, err := h.svc.UpdateProduct(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) product(c *gin.Context) {
+	version := 0
+
+	if c.Query("version") != "" {
+		v, err := strconv.Atoi(c.Query("version"))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+
+			return
+		}
+
+		version = v
+	}
+
+	res, err := h.svc.Product(
+		service.ProductRequest{
+			ProductID: c.Param("product_id"),
+			Version:   version,
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) products(c *gin.Context) {
+	res, err := h.svc.Products(service.ProductsRequest{})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) putTier(c *gin.Context) {
+	req := service.PutTierRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.TierID = c.Param("tier_id")
+
+	res, err := h.svc.PutTier(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) tier(c *gin.Context) {
+	res, err := h.svc.Tier(
+		service.TierRequest{
+			TierID: c.Param("tier_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) tiers(c *gin.Context) {
+	res, err := h.svc.Tiers(service.TiersRequest{})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) updateUserTier(c *gin.Context) {
+	req := service.UpdateUserTierRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	err = h.svc.UpdateUserTier(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, statusOK)
+}
+
+func (h hdl) submitKYC(c *gin.Context) {
+	req := service.SubmitKYCRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	res, err := h.svc.SubmitKYC(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) kyc(c *gin.Context) {
+	res, err := h.svc.KYC(
+		service.KYCRequest{
+			UserID: c.Param("user_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h hdl) updateUserStatus(c *gin.Context) {
+	req := service.UpdateUserStatusRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	err = h.svc.UpdateUserStatus(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, statusOK)
+}
+
+func (h hdl) grantLoan(c *gin.Context) {
+	req := service.GrantLoanRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	res, err := h.svc.GrantLoan(req)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func bindOptionalJSON(c *gin.Context, obj any) error {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return nil
+	}
+
+	return c.BindJSON(obj)
+}
+
+// transactionsRequest reads the history search from the query string.
+func transactionsRequest(c *gin.Context, userID string) service.TransactionsRequest {
+	req := service.TransactionsRequest{
+		UserID:    userID,
+		AccountID: c.Param("account_id"),
+		Query:     c.Query("q"),
+		Reference: c.Query("reference"),
+	}
+
+	metadata := c.QueryMap("metadata")
+
+	if len(metadata) > 0 {
+		req.Metadata = metadata
+	}
+
+	return req
+}
@@ -2,16 +2,21 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/events"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
 	"github.com/hetfdex/tiny-bank/internal/service"
 	"github.com/hetfdex/tiny-bank/test/mock/servicemock"
 	"github.com/stretchr/testify/assert"
@@ -171,6 +176,7 @@ func TestDeactivateUser_ErrDeactivate(t *testing.T) {
 		"DeactivateUser",
 		req,
 	).Return(
+		service.DeactivateUserResponse{},
 		errors.New("error deactivate"),
 	)
 
@@ -200,6 +206,10 @@ func TestDeactivateUser_Ok(t *testing.T) {
 		"DeactivateUser",
 		req,
 	).Return(
+		service.DeactivateUserResponse{
+			UserID:   "1",
+			Accounts: []service.ClosingAccountResponse{},
+		},
 		nil,
 	)
 
@@ -208,7 +218,71 @@ func TestDeactivateUser_Ok(t *testing.T) {
 	rr := setupTest(hdl, httpReq)
 
 	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
-	assert.Equal(t, "{\"status\":\"ok\"}", rr.Body.String())
+	assert.Equal(t, "{\"user_id\":\"1\",\"reason\":\"\",\"deactivated_at\":\"0001-01-01T00:00:00Z\",\"closes_at\":\"0001-01-01T00:00:00Z\",\"accounts\":[]}", rr.Body.String())
+}
+
+func TestDeactivateUser_OkPayout(t *testing.T) {
+	req := service.DeactivateUserRequest{
+		UserID:          "1",
+		Reason:          "moving abroad",
+		PayoutUserID:    "2",
+		PayoutAccountID: "3",
+	}
+
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodDelete,
+		baseURL+"1",
+		makeBody(req),
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"DeactivateUser",
+		req,
+	).Return(
+		service.DeactivateUserResponse{
+			UserID:   "1",
+			Reason:   "moving abroad",
+			Accounts: []service.ClosingAccountResponse{},
+		},
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "{\"user_id\":\"1\",\"reason\":\"moving abroad\",\"deactivated_at\":\"0001-01-01T00:00:00Z\",\"closes_at\":\"0001-01-01T00:00:00Z\",\"accounts\":[]}", rr.Body.String())
+}
+
+func TestReactivateUser_ErrReactivate(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		baseURL+"1/reactivate",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"ReactivateUser",
+		service.ReactivateUserRequest{
+			UserID: "1",
+		},
+	).Return(
+		errors.New("grace period expired"),
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"grace period expired\"}", rr.Body.String())
 }
 
 func TestDeposit_ErrJSON(t *testing.T) {
@@ -234,7 +308,7 @@ func TestDeposit_ErrDeposit(t *testing.T) {
 		baseURL+"1/accounts/2",
 		makeBody(
 			service.DepositRequest{
-				Amount: 3,
+				Amount: money.New(3, money.EUR),
 			},
 		),
 	)
@@ -246,7 +320,7 @@ func TestDeposit_ErrDeposit(t *testing.T) {
 		service.DepositRequest{
 			UserID:    "1",
 			AccountID: "2",
-			Amount:    3,
+			Amount:    money.New(3, money.EUR),
 		},
 	).Return(
 		service.DepositResponse{},
@@ -261,6 +335,22 @@ func TestDeposit_ErrDeposit(t *testing.T) {
 	assert.Equal(t, "{\"error\":\"error deposit\"}", rr.Body.String())
 }
 
+func TestDeposit_ErrFloatAmount(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPut,
+		baseURL+"1/accounts/2",
+		strings.NewReader(`{"amount":12.34}`),
+	)
+
+	hdl := New(&servicemock.Mock{})
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"amount must be a decimal string\"}", rr.Body.String())
+}
+
 func TestDeposit_Ok(t *testing.T) {
 	httpReq := makeHTTPRequest(
 		t,
@@ -268,7 +358,7 @@ func TestDeposit_Ok(t *testing.T) {
 		baseURL+"1/accounts/2",
 		makeBody(
 			service.DepositRequest{
-				Amount: 3,
+				Amount: money.New(3, money.EUR),
 			},
 		),
 	)
@@ -280,11 +370,11 @@ func TestDeposit_Ok(t *testing.T) {
 		service.DepositRequest{
 			UserID:    "1",
 			AccountID: "2",
-			Amount:    3,
+			Amount:    money.New(3, money.EUR),
 		},
 	).Return(
 		service.DepositResponse{
-			Balance: 10,
+			Balance: money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -294,7 +384,7 @@ func TestDeposit_Ok(t *testing.T) {
 	rr := setupTest(hdl, httpReq)
 
 	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
-	assert.Equal(t, "{\"balance\":10}", rr.Body.String())
+	assert.Equal(t, "{\"balance\":{\"amount\":\"0.10\",\"currency\":\"EUR\"}}", rr.Body.String())
 }
 
 func TestWithdraw_ErrJSON(t *testing.T) {
@@ -320,7 +410,7 @@ func TestWithdraw_ErrWithdraw(t *testing.T) {
 		baseURL+"1/accounts/2",
 		makeBody(
 			service.WithdrawRequest{
-				Amount: 3,
+				Amount: money.New(3, money.EUR),
 			},
 		),
 	)
@@ -332,7 +422,7 @@ func TestWithdraw_ErrWithdraw(t *testing.T) {
 		service.WithdrawRequest{
 			UserID:    "1",
 			AccountID: "2",
-			Amount:    3,
+			Amount:    money.New(3, money.EUR),
 		},
 	).Return(
 		service.WithdrawResponse{},
@@ -354,7 +444,7 @@ func TestWithdraw_Ok(t *testing.T) {
 		baseURL+"1/accounts/2",
 		makeBody(
 			service.WithdrawRequest{
-				Amount: 3,
+				Amount: money.New(3, money.EUR),
 			},
 		),
 	)
@@ -366,11 +456,11 @@ func TestWithdraw_Ok(t *testing.T) {
 		service.WithdrawRequest{
 			UserID:    "1",
 			AccountID: "2",
-			Amount:    3,
+			Amount:    money.New(3, money.EUR),
 		},
 	).Return(
 		service.WithdrawResponse{
-			Balance: 10,
+			Balance: money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -380,7 +470,7 @@ func TestWithdraw_Ok(t *testing.T) {
 	rr := setupTest(hdl, httpReq)
 
 	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
-	assert.Equal(t, "{\"balance\":10}", rr.Body.String())
+	assert.Equal(t, "{\"balance\":{\"amount\":\"0.10\",\"currency\":\"EUR\"}}", rr.Body.String())
 }
 
 func TestTransfer_ErrJSON(t *testing.T) {
@@ -408,7 +498,7 @@ func TestTransfer_ErrTransfer(t *testing.T) {
 			service.TransferRequest{
 				ReceiverUserID:    "2",
 				ReceiverAccountID: "4",
-				Amount:            5,
+				Amount:            money.New(5, money.EUR),
 			},
 		),
 	)
@@ -422,7 +512,7 @@ func TestTransfer_ErrTransfer(t *testing.T) {
 			ReceiverUserID:    "2",
 			SenderAccountID:   "3",
 			ReceiverAccountID: "4",
-			Amount:            5,
+			Amount:            money.New(5, money.EUR),
 		},
 	).Return(
 		service.TransferResponse{},
@@ -446,7 +536,7 @@ func TestTransfer_Ok(t *testing.T) {
 			service.TransferRequest{
 				ReceiverUserID:    "2",
 				ReceiverAccountID: "4",
-				Amount:            5,
+				Amount:            money.New(5, money.EUR),
 			},
 		),
 	)
@@ -460,11 +550,11 @@ func TestTransfer_Ok(t *testing.T) {
 			ReceiverUserID:    "2",
 			SenderAccountID:   "3",
 			ReceiverAccountID: "4",
-			Amount:            5,
+			Amount:            money.New(5, money.EUR),
 		},
 	).Return(
 		service.TransferResponse{
-			Balance: 10,
+			Balance: money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -474,7 +564,7 @@ func TestTransfer_Ok(t *testing.T) {
 	rr := setupTest(hdl, httpReq)
 
 	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
-	assert.Equal(t, "{\"balance\":10}", rr.Body.String())
+	assert.Equal(t, "{\"balance\":{\"amount\":\"0.10\",\"currency\":\"EUR\"}}", rr.Body.String())
 }
 
 func TestBalance_ErrBalance(t *testing.T) {
@@ -524,7 +614,7 @@ func TestBalance_Ok(t *testing.T) {
 		},
 	).Return(
 		service.BalanceResponse{
-			Balance: 10,
+			Balance: money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -534,7 +624,7 @@ func TestBalance_Ok(t *testing.T) {
 	rr := setupTest(hdl, httpReq)
 
 	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
-	assert.Equal(t, "{\"balance\":10}", rr.Body.String())
+	assert.Equal(t, "{\"balance\":{\"amount\":\"0.10\",\"currency\":\"EUR\"}}", rr.Body.String())
 }
 
 func TestTransactions_ErrTransaction(t *testing.T) {
@@ -584,11 +674,13 @@ func TestTransactions_Ok(t *testing.T) {
 		},
 	).Return(
 		service.TransactionsResponse{
-			Transactions: []domain.Transaction{
+			Transactions: []service.TransactionResponse{
 				{
+					ID:                "5",
+					AccountID:         "2",
 					Timestamp:         time.Time{},
 					Operation:         "operation",
-					Amount:            666,
+					Amount:            money.New(666, money.EUR),
 					ReceiverUserID:    "1",
 					SenderUserID:      "2",
 					ReceiverAccountID: "3",
@@ -604,46 +696,1717 @@ func TestTransactions_Ok(t *testing.T) {
 	rr := setupTest(hdl, httpReq)
 
 	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
-	assert.Equal(t, "{\"transactions\":[{\"Timestamp\":\"0001-01-01T00:00:00Z\",\"Operation\":\"operation\",\"Amount\":666,\"ReceiverUserID\":\"1\",\"SenderUserID\":\"2\",\"ReceiverAccountID\":\"3\",\"SenderAccountID\":\"4\"}]}", rr.Body.String())
+	assert.Equal(t, "{\"transactions\":[{\"id\":\"5\",\"account_id\":\"2\",\"timestamp\":\"0001-01-01T00:00:00Z\",\"operation\":\"operation\",\"amount\":{\"amount\":\"6.66\",\"currency\":\"EUR\"},\"receiver_user_id\":\"1\",\"sender_user_id\":\"2\",\"receiver_account_id\":\"3\",\"sender_account_id\":\"4\"}]}", rr.Body.String())
 }
 
-func setupTest(hdl Handler, req *http.Request) *httptest.ResponseRecorder {
-	router := gin.Default()
+func TestTransactions_Search(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		baseURL+"1/accounts/2/transactions?q=rent&reference=RF18+5390&metadata[invoice]=2024-03",
+		nil,
+	)
 
-	hdl.ConfigHandlers(router)
+	svc := &servicemock.Mock{}
 
-	rr := httptest.NewRecorder()
+	svc.On(
+		"Transactions",
+		service.TransactionsRequest{
+			UserID:    "1",
+			AccountID: "2",
+			Query:     "rent",
+			Reference: "RF18 5390",
+			Metadata: map[string]string{
+				"invoice": "2024-03",
+			},
+		},
+	).Return(
+		service.TransactionsResponse{
+			Transactions: []service.TransactionResponse{
+				{
+					ID:        "5",
+					AccountID: "2",
+					Operation: "operation",
+					Amount:    money.New(666, money.EUR),
+					TransactionDetails: service.TransactionDetails{
+						Description: "Rent",
+						Reference:   "RF18539007547034",
+						Metadata: map[string]string{
+							"invoice": "2024-03",
+						},
+					},
+				},
+			},
+		},
+		nil,
+	)
 
-	router.ServeHTTP(rr, req)
+	hdl := New(svc)
 
-	return rr
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Contains(t, rr.Body.String(), "\"description\":\"Rent\",\"reference\":\"RF18539007547034\",\"metadata\":{\"invoice\":\"2024-03\"}")
 }
 
-func makeHTTPRequest(
-	t *testing.T,
-	httpMethod string,
-	url string,
-	body io.Reader,
-) *http.Request {
-	req, err := http.NewRequest(
-		httpMethod,
-		url,
-		body,
+func TestAddHolder_ErrJSON(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		baseURL+"1/accounts/2/holders",
+		nil,
 	)
 
-	if err != nil {
-		t.Fatal(err)
-	}
+	hdl := New(&servicemock.Mock{})
 
-	return req
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"invalid request\"}", rr.Body.String())
 }
 
-func makeBody(v interface{}) io.Reader {
-	body, err := json.Marshal(v)
+func TestAddHolder_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		baseURL+"1/accounts/2/holders",
+		makeBody(
+			service.AddHolderRequest{
+				HolderUserID: "3",
+				Role:         domain.RoleCoOwner,
+			},
+		),
+	)
 
-	if err != nil {
-		return nil
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"AddHolder",
+		service.AddHolderRequest{
+			UserID:       "1",
+			AccountID:    "2",
+			HolderUserID: "3",
+			Role:         domain.RoleCoOwner,
+		},
+	).Return(
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, "{\"status\":\"ok\"}", rr.Body.String())
+}
+
+func TestRemoveHolder_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodDelete,
+		baseURL+"1/accounts/2/holders/3",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"RemoveHolder",
+		service.RemoveHolderRequest{
+			UserID:       "1",
+			AccountID:    "2",
+			HolderUserID: "3",
+		},
+	).Return(
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "{\"status\":\"ok\"}", rr.Body.String())
+}
+
+func TestHolders_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		baseURL+"1/accounts/2/holders",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Holders",
+		service.HoldersRequest{
+			UserID:    "1",
+			AccountID: "2",
+		},
+	).Return(
+		service.HoldersResponse{
+			Holders: []service.HolderResponse{
+				{
+					UserID: "1",
+					Role:   domain.RoleOwner,
+				},
+			},
+			DualApprovalThreshold: money.New(50, money.EUR),
+		},
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "{\"holders\":[{\"user_id\":\"1\",\"role\":\"owner\"}],\"dual_approval_threshold\":{\"amount\":\"0.50\",\"currency\":\"EUR\"}}", rr.Body.String())
+}
+
+func TestCreateAccount_OkProduct(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		baseURL+"1",
+		makeBody(
+			service.CreateAccountRequest{
+				ProductID: "savings",
+			},
+		),
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreateAccount",
+		service.CreateAccountRequest{
+			UserID:    "1",
+			ProductID: "savings",
+		},
+	).Return(
+		service.CreateAccountResponse{
+			AccountID:      "2",
+			ProductID:      "savings",
+			ProductVersion: 3,
+		},
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, "{\"account_id\":\"2\",\"product_id\":\"savings\",\"product_version\":3}", rr.Body.String())
+}
+
+func TestCreateProduct_ErrJSON(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		adminURL+"products",
+		nil,
+	)
+
+	hdl := New(&servicemock.Mock{})
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"invalid request\"}", rr.Body.String())
+}
+
+func TestCreateProduct_ErrCreate(t *testing.T) {
+	req := service.CreateProductRequest{
+		ProductID: "savings",
+		ProductTerms: service.ProductTerms{
+			Name: "Savings",
+			Type: domain.ProductSavings,
+		},
 	}
 
-	return bytes.NewBuffer(body)
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		adminURL+"products",
+		makeBody(req),
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreateProduct",
+		req,
+	).Return(
+		service.ProductResponse{},
+		errors.New("duplicate product id"),
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"duplicate product id\"}", rr.Body.String())
+}
+
+func TestProduct_ErrVersion(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		adminURL+"products/savings?version=x",
+		nil,
+	)
+
+	hdl := New(&servicemock.Mock{})
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"invalid version\"}", rr.Body.String())
+}
+
+func TestProduct_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		adminURL+"products/savings?version=2",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Product",
+		service.ProductRequest{
+			ProductID: "savings",
+			Version:   2,
+		},
+	).Return(
+		service.ProductResponse{
+			ProductID: "savings",
+			Version:   2,
+			ProductTerms: service.ProductTerms{
+				Name:       "Savings",
+				Type:       domain.ProductSavings,
+				Operations: []string{"deposit"},
+			},
+		},
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "{\"product_id\":\"savings\",\"version\":2,\"created_at\":\"0001-01-01T00:00:00Z\",\"name\":\"Savings\",\"type\":\"savings\",\"operations\":[\"deposit\"],\"monthly_withdrawal_limit\":0,\"minimum_balance\":{\"amount\":\"0\"},\"interest_rate_bps\":0,\"withdrawal_fee\":{\"amount\":\"0\"},\"transfer_fee\":{\"amount\":\"0\"},\"monthly_fee\":{\"amount\":\"0\"},\"term_days\":0,\"early_break_penalty_bps\":0}", rr.Body.String())
+}
+
+func setupTest(hdl Handler, req *http.Request) *httptest.ResponseRecorder {
+	router := gin.Default()
+
+	hdl.ConfigHandlers(router)
+
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func makeHTTPRequest(
+	t *testing.T,
+	httpMethod string,
+	url string,
+	body io.Reader,
+) *http.Request {
+	req, err := http.NewRequest(
+		httpMethod,
+		url,
+		body,
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return req
+}
+
+func makeBody(v interface{}) io.Reader {
+	body, err := json.Marshal(v)
+
+	if err != nil {
+		return nil
+	}
+
+	return bytes.NewBuffer(body)
+}
+
+func TestUpdateUserStatus_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPut,
+		adminURL+"users/1/status",
+		makeBody(
+			service.UpdateUserStatusRequest{
+				Status: domain.UserRestricted,
+				Reason: "sanctions match",
+			},
+		),
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"UpdateUserStatus",
+		service.UpdateUserStatusRequest{
+			UserID: "1",
+			Status: domain.UserRestricted,
+			Reason: "sanctions match",
+		},
+	).Return(
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "{\"status\":\"ok\"}", rr.Body.String())
+}
+
+func TestAccountEvents_ErrBalance(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		baseURL+"1/accounts/2/events",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Balance",
+		service.BalanceRequest{
+			UserID:    "1",
+			AccountID: "2",
+		},
+	).Return(
+		service.BalanceResponse{},
+		errors.New("unauthorized account id"),
+	)
+
+	hdl := NewStream(svc, events.NewHub(8))
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"unauthorized account id\"}", rr.Body.String())
+}
+
+func TestAccountEvents_Replay(t *testing.T) {
+	hub := events.NewHub(8)
+
+	hub.Publish(
+		events.Event{
+			Type:      events.TypeBalance,
+			AccountID: "2",
+			Data: events.BalancePayload{
+				AccountID: "2",
+				Balance:   money.New(100, money.EUR),
+			},
+		},
+	)
+
+	hub.Publish(
+		events.Event{
+			Type:      events.TypeBalance,
+			AccountID: "2",
+			Data: events.BalancePayload{
+				AccountID: "2",
+				Balance:   money.New(250, money.EUR),
+			},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+	defer cancel()
+
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		baseURL+"1/accounts/2/events",
+		nil,
+	).WithContext(ctx)
+
+	httpReq.Header.Set("Last-Event-ID", "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Balance",
+		service.BalanceRequest{
+			UserID:    "1",
+			AccountID: "2",
+		},
+	).Return(
+		service.BalanceResponse{},
+		nil,
+	)
+
+	hdl := NewStream(svc, hub)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "text/event-stream", rr.Result().Header.Get("Content-Type"))
+	assert.Equal(t, "id:2\nevent:balance\ndata:{\"account_id\":\"2\",\"balance\":{\"amount\":\"2.50\",\"currency\":\"EUR\"}}\n\n", rr.Body.String())
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreateUser",
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	).Return(
+		service.CreateUserResponse{
+			UserID: "1",
+		},
+		nil,
+	).Once()
+
+	router := gin.Default()
+
+	router.Use(Idempotency(time.Hour))
+
+	New(svc).ConfigHandlers(router)
+
+	send := func(name string) *httptest.ResponseRecorder {
+		httpReq := makeHTTPRequest(
+			t,
+			http.MethodPost,
+			baseURL,
+			makeBody(
+				service.CreateUserRequest{
+					Name: name,
+				},
+			),
+		)
+
+		httpReq.Header.Set(IdempotencyKeyHeader, "key-1")
+
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, httpReq)
+
+		return rr
+	}
+
+	first := send("joe")
+
+	assert.Equal(t, http.StatusCreated, first.Result().StatusCode)
+	assert.Equal(t, "", first.Result().Header.Get(IdempotentReplayHeader))
+
+	replay := send("joe")
+
+	assert.Equal(t, http.StatusCreated, replay.Result().StatusCode)
+	assert.Equal(t, "true", replay.Result().Header.Get(IdempotentReplayHeader))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+
+	reused := send("mary")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"idempotency key reused with a different request\"}", reused.Body.String())
+
+	svc.AssertNumberOfCalls(t, "CreateUser", 1)
+}
+
+func TestIdempotency_ScopedByUser(t *testing.T) {
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreateUser",
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	).Return(
+		service.CreateUserResponse{
+			UserID: "1",
+		},
+		nil,
+	).Twice()
+
+	router := gin.Default()
+
+	router.Use(Idempotency(time.Hour))
+
+	New(svc).ConfigHandlers(router)
+
+	for _, userID := range []string{"1", "2"} {
+		httpReq := makeHTTPRequest(
+			t,
+			http.MethodPost,
+			baseURL,
+			makeBody(
+				service.CreateUserRequest{
+					Name: "joe",
+				},
+			),
+		)
+
+		httpReq.Header.Set(IdempotencyKeyHeader, "key-1")
+		httpReq.Header.Set(UserIDHeader, userID)
+
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, httpReq)
+
+		assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+		assert.Equal(t, "", rr.Result().Header.Get(IdempotentReplayHeader))
+	}
+
+	svc.AssertNumberOfCalls(t, "CreateUser", 2)
+}
+
+func TestDurableIdempotency_Restart(t *testing.T) {
+	dir := t.TempDir()
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreateUser",
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	).Return(
+		service.CreateUserResponse{
+			UserID: "1",
+		},
+		nil,
+	).Once()
+
+	send := func() *httptest.ResponseRecorder {
+		log, err := wal.Open(
+			wal.Config{
+				Dir:        dir,
+				SyncPolicy: wal.SyncAlways,
+			},
+		)
+
+		assert.Nil(t, err)
+
+		defer log.Close()
+
+		idempotency, err := DurableIdempotency(log, time.Hour)
+
+		assert.Nil(t, err)
+
+		router := gin.Default()
+
+		router.Use(idempotency)
+
+		New(svc).ConfigHandlers(router)
+
+		httpReq := makeHTTPRequest(
+			t,
+			http.MethodPost,
+			baseURL,
+			makeBody(
+				service.CreateUserRequest{
+					Name: "joe",
+				},
+			),
+		)
+
+		httpReq.Header.Set(IdempotencyKeyHeader, "key-1")
+
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, httpReq)
+
+		return rr
+	}
+
+	first := send()
+
+	assert.Equal(t, http.StatusCreated, first.Result().StatusCode)
+
+	replay := send()
+
+	assert.Equal(t, http.StatusCreated, replay.Result().StatusCode)
+	assert.Equal(t, "true", replay.Result().Header.Get(IdempotentReplayHeader))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+
+	svc.AssertNumberOfCalls(t, "CreateUser", 1)
+}
+
+func TestV1_Deprecated(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		baseURL+"1/accounts",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Accounts",
+		service.AccountsRequest{
+			UserID: "1",
+		},
+	).Return(
+		service.AccountsResponse{},
+		nil,
+	)
+
+	hdl := New(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "true", rr.Header().Get("Deprecation"))
+	assert.Equal(t, "</api/v2/>; rel=\"successor-version\"", rr.Header().Get("Link"))
+}
+
+func TestV2CreateUser_Created(t *testing.T) {
+	req := service.CreateUserRequest{
+		Name: "joe",
+	}
+
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"users",
+		makeBody(req),
+	)
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreateUser",
+		req,
+	).Return(
+		service.CreateUserResponse{
+			UserID: "1",
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"users/1", rr.Header().Get("Location"))
+	assert.Empty(t, rr.Header().Get("Deprecation"))
+	assert.Equal(t, "{\"user_id\":\"1\"}", rr.Body.String())
+}
+
+func TestV2Deposit_ErrMissingUser(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"accounts/2/deposits",
+		makeBody(
+			service.DepositRequest{
+				Amount: money.New(10, money.EUR),
+			},
+		),
+	)
+
+	hdl := NewV2(&servicemock.Mock{})
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"missing X-User-ID header\"}", rr.Body.String())
+}
+
+func TestV2Deposit_Created(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"accounts/2/deposits",
+		makeBody(
+			service.DepositRequest{
+				Amount: money.New(10, money.EUR),
+			},
+		),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Deposit",
+		service.DepositRequest{
+			UserID:    "1",
+			AccountID: "2",
+			Amount:    money.New(10, money.EUR),
+		},
+	).Return(
+		service.DepositResponse{
+			Balance:       money.New(10, money.EUR),
+			TransactionID: "3",
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"transactions/3", rr.Header().Get("Location"))
+	assert.Equal(t, "{\"balance\":{\"amount\":\"0.10\",\"currency\":\"EUR\"},\"transaction_id\":\"3\"}", rr.Body.String())
+}
+
+func TestV2Withdraw_ErrStatusCodes(t *testing.T) {
+	tests := map[string]int{
+		"invalid amount":                      http.StatusBadRequest,
+		"unauthorized account id":             http.StatusForbidden,
+		"account not found":                   http.StatusNotFound,
+		"account closed":                      http.StatusConflict,
+		"suspended user cannot withdraw":      http.StatusConflict,
+		"insuficient funds":                   http.StatusUnprocessableEntity,
+		"velocity limit reached: 1.00 EUR/1h": http.StatusTooManyRequests,
+		"error withdraw":                      http.StatusInternalServerError,
+	}
+
+	for message, statusCode := range tests {
+		httpReq := makeHTTPRequest(
+			t,
+			http.MethodPost,
+			v2URL+"accounts/2/withdrawals",
+			makeBody(
+				service.WithdrawRequest{
+					Amount: money.New(10, money.EUR),
+				},
+			),
+		)
+
+		httpReq.Header.Set(UserIDHeader, "1")
+
+		var err error = errors.New(message)
+
+		if statusCode == http.StatusTooManyRequests {
+			err = service.VelocityLimitError{
+				ResetsAt: time.Now().Add(time.Minute),
+			}
+		}
+
+		svc := &servicemock.Mock{}
+
+		svc.On(
+			"Withdraw",
+			service.WithdrawRequest{
+				UserID:    "1",
+				AccountID: "2",
+				Amount:    money.New(10, money.EUR),
+			},
+		).Return(
+			service.WithdrawResponse{},
+			err,
+		)
+
+		hdl := NewV2(svc)
+
+		rr := setupTest(hdl, httpReq)
+
+		assert.Equal(t, statusCode, rr.Result().StatusCode, message)
+
+		if statusCode == http.StatusTooManyRequests {
+			assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestV2RemoveHolder_NoContent(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodDelete,
+		v2URL+"accounts/2/holders/3",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"RemoveHolder",
+		service.RemoveHolderRequest{
+			UserID:       "1",
+			AccountID:    "2",
+			HolderUserID: "3",
+		},
+	).Return(
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusNoContent, rr.Result().StatusCode)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestV2Transaction_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		v2URL+"transactions/3",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"Transaction",
+		service.TransactionRequest{
+			UserID:        "1",
+			TransactionID: "3",
+		},
+	).Return(
+		service.TransactionResponse{
+			ID:        "3",
+			AccountID: "2",
+			Operation: domain.OperationDeposit,
+			Amount:    money.New(10, money.EUR),
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Contains(t, rr.Body.String(), "\"account_id\":\"2\"")
+	assert.Contains(t, rr.Body.String(), "\"id\":\"3\"")
+}
+
+func TestV2AddPayee_Created(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"payees",
+		makeBody(
+			service.AddPayeeRequest{
+				Nickname:  "Mum",
+				Name:      "Jane Smith",
+				AccountID: "NL91ABNA0417164300",
+			},
+		),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"AddPayee",
+		service.AddPayeeRequest{
+			UserID:    "1",
+			Nickname:  "Mum",
+			Name:      "Jane Smith",
+			AccountID: "NL91ABNA0417164300",
+		},
+	).Return(
+		service.PayeeResponse{
+			PayeeID:   "2",
+			Nickname:  "Mum",
+			Name:      "Jane Smith",
+			Match:     domain.PayeeMatchExact,
+			AccountID: "3",
+			IBAN:      "NL91ABNA0417164300",
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"payees/2", rr.Header().Get("Location"))
+}
+
+func TestV2Transfer_ErrPayee(t *testing.T) {
+	tests := map[int]error{
+		http.StatusConflict: service.PayeeCoolingOffError{
+			Until: time.Now().Add(time.Hour),
+		},
+		http.StatusUnprocessableEntity: service.PayeeNameError{
+			Match: domain.PayeeMatchClose,
+			Name:  "Jane Smith",
+		},
+	}
+
+	for statusCode, err := range tests {
+		httpReq := makeHTTPRequest(
+			t,
+			http.MethodPost,
+			v2URL+"accounts/2/transfers",
+			makeBody(
+				service.TransferRequest{
+					PayeeID: "3",
+					Amount:  money.New(10, money.EUR),
+				},
+			),
+		)
+
+		httpReq.Header.Set(UserIDHeader, "1")
+
+		svc := &servicemock.Mock{}
+
+		svc.On(
+			"Transfer",
+			service.TransferRequest{
+				SenderUserID:    "1",
+				SenderAccountID: "2",
+				PayeeID:         "3",
+				Amount:          money.New(10, money.EUR),
+			},
+		).Return(
+			service.TransferResponse{},
+			err,
+		)
+
+		hdl := NewV2(svc)
+
+		rr := setupTest(hdl, httpReq)
+
+		assert.Equal(t, statusCode, rr.Result().StatusCode, err.Error())
+
+		if statusCode == http.StatusConflict {
+			assert.Equal(t, "3600", rr.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestV2AcceptPaymentRequest_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"payment-requests/2/acceptance",
+		makeBody(
+			service.AcceptPaymentRequestRequest{
+				AccountID: "3",
+			},
+		),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"AcceptPaymentRequest",
+		service.AcceptPaymentRequestRequest{
+			UserID:           "1",
+			PaymentRequestID: "2",
+			AccountID:        "3",
+		},
+	).Return(
+		service.PaymentRequestResponse{
+			PaymentRequestID: "2",
+			Status:           domain.PaymentRequestCompleted,
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Contains(t, rr.Body.String(), `"status":"completed"`)
+}
+
+func TestV2CreatePendingTransfer_Created(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"accounts/2/pending-transfers",
+		makeBody(
+			service.CreatePendingTransferRequest{
+				TransferRequest: service.TransferRequest{
+					ReceiverUserID:    "3",
+					ReceiverAccountID: "4",
+					Amount:            money.New(1000, money.EUR),
+				},
+				ExpiresAt: expiresAt,
+			},
+		),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CreatePendingTransfer",
+		service.CreatePendingTransferRequest{
+			TransferRequest: service.TransferRequest{
+				SenderUserID:      "1",
+				SenderAccountID:   "2",
+				ReceiverUserID:    "3",
+				ReceiverAccountID: "4",
+				Amount:            money.New(1000, money.EUR),
+			},
+			ExpiresAt: expiresAt,
+		},
+	).Return(
+		service.PendingTransferResponse{
+			PendingTransferID: "5",
+			Status:            domain.PendingTransferPending,
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"pending-transfers/5", rr.Header().Get("Location"))
+	assert.Contains(t, rr.Body.String(), `"status":"pending"`)
+}
+
+func TestV2CancelPendingTransfer_ErrClosed(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"pending-transfers/2/cancellation",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CancelPendingTransfer",
+		service.CancelPendingTransferRequest{
+			UserID:            "1",
+			PendingTransferID: "2",
+		},
+	).Return(
+		service.PendingTransferResponse{},
+		errors.New("pending transfer closed"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusConflict, rr.Result().StatusCode)
+}
+
+func TestV2PaymentRequests_Query(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		v2URL+"payment-requests?direction=incoming&status=open",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"PaymentRequests",
+		service.PaymentRequestsRequest{
+			UserID:    "1",
+			Direction: service.DirectionIncoming,
+			Status:    domain.PaymentRequestOpen,
+		},
+	).Return(
+		service.PaymentRequestsResponse{
+			PaymentRequests: []service.PaymentRequestResponse{},
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Equal(t, "{\"payment_requests\":[]}", rr.Body.String())
+}
+
+func TestSpec_MatchesRoutes(t *testing.T) {
+	handlers := []Handler{
+		New(nil),
+		NewV2(nil),
+		NewBatch(nil),
+		NewReconciler(nil),
+		NewStream(nil, nil),
+	}
+
+	spec := Spec(handlers...)
+
+	router := gin.New()
+
+	for _, hdl := range append(handlers, NewDocs(spec)) {
+		hdl.ConfigHandlers(router)
+	}
+
+	routes := []string{}
+
+	for _, route := range router.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+
+	documented := []string{}
+
+	for _, op := range spec.Operations() {
+		documented = append(documented, op.Method+" "+op.Path)
+	}
+
+	assert.ElementsMatch(t, routes, documented)
+}
+
+func TestValidation_ErrFields(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		baseURL+"1/accounts/2",
+		strings.NewReader(`{"receiver_user_id":3,"payee_id":4}`),
+	)
+
+	router := gin.New()
+
+	hdl := New(&servicemock.Mock{})
+
+	router.Use(Validation(Spec(hdl)))
+
+	hdl.ConfigHandlers(router)
+
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"error":"invalid request body","fields":{"receiver_user_id":"must be a string","payee_id":"must be a string","amount":"is required"}}`,
+		rr.Body.String(),
+	)
+}
+
+func TestValidation_ErrJSON(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPut,
+		baseURL+"1/accounts/2",
+		strings.NewReader(`{`),
+	)
+
+	router := gin.New()
+
+	hdl := New(&servicemock.Mock{})
+
+	router.Use(Validation(Spec(hdl)))
+
+	hdl.ConfigHandlers(router)
+
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"invalid json body\"}", rr.Body.String())
+}
+
+func TestOpenAPI_Ok(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		specURL,
+		nil,
+	)
+
+	hdl := NewDocs(Spec(New(nil), NewV2(nil)))
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+
+	err := json.Unmarshal(rr.Body.Bytes(), &doc)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/api/v1/users/{user_id}/accounts/{account_id}")
+	assert.Contains(t, doc.Paths["/api/v2/accounts/{account_id}/transfers"], "post")
+	assert.Contains(t, doc.Paths, docsURL)
+}
+
+func TestV2CollectPayment_Created(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"mandates/2/collections",
+		makeBody(
+			service.CollectPaymentRequest{
+				Amount:      money.New(1000, money.EUR),
+				Description: "Electricity",
+			},
+		),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CollectPayment",
+		service.CollectPaymentRequest{
+			UserID:      "1",
+			MandateID:   "2",
+			Amount:      money.New(1000, money.EUR),
+			Description: "Electricity",
+		},
+	).Return(
+		service.CollectionResponse{
+			CollectionID:  "3",
+			MandateID:     "2",
+			Status:        domain.CollectionFailed,
+			FailureReason: "insuficient funds",
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"mandates/2/collections/3", rr.Header().Get("Location"))
+	assert.Contains(t, rr.Body.String(), `"status":"failed"`)
+}
+
+func TestV2CollectPayment_ErrAlreadyCollected(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"mandates/2/collections",
+		makeBody(
+			service.CollectPaymentRequest{
+				Amount: money.New(1000, money.EUR),
+			},
+		),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CollectPayment",
+		service.CollectPaymentRequest{
+			UserID:    "1",
+			MandateID: "2",
+			Amount:    money.New(1000, money.EUR),
+		},
+	).Return(
+		service.CollectionResponse{},
+		errors.New("mandate already collected this period"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusConflict, rr.Result().StatusCode)
+}
+
+func TestV2AuthoriseCard_Created(t *testing.T) {
+	req := service.AuthoriseCardRequest{
+		PAN:         "4999990000000001",
+		ExpiryMonth: 8,
+		ExpiryYear:  2029,
+		CVV:         "123",
+		Amount:      money.New(1000, money.EUR),
+		Merchant:    "Coffee Shop",
+		MerchantKey: "coffee-key",
+	}
+
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"card-authorisations",
+		makeBody(req),
+	)
+
+	httpReq.Header.Set(MerchantIDHeader, "Coffee Shop")
+	httpReq.Header.Set(MerchantKeyHeader, "coffee-key")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"AuthoriseCard",
+		req,
+	).Return(
+		service.CardAuthorisationResponse{
+			AuthorisationID: "2",
+			CardID:          "1",
+			Status:          domain.AuthorisationHeld,
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"cards/1/authorisations/2", rr.Header().Get("Location"))
+	assert.Contains(t, rr.Body.String(), `"status":"held"`)
+}
+
+func TestV2CaptureAuthorisation_NoBody(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"cards/1/authorisations/2/capture",
+		nil,
+	)
+
+	httpReq.Header.Set(MerchantIDHeader, "Coffee Shop")
+	httpReq.Header.Set(MerchantKeyHeader, "coffee-key")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CaptureAuthorisation",
+		service.CaptureAuthorisationRequest{
+			CardID:          "1",
+			AuthorisationID: "2",
+			Merchant:        "Coffee Shop",
+			MerchantKey:     "coffee-key",
+		},
+	).Return(
+		service.CardAuthorisationResponse{},
+		errors.New("authorisation not held"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusConflict, rr.Result().StatusCode)
+}
+
+func TestV2CaptureAuthorisation_NoMerchant(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"cards/1/authorisations/2/capture",
+		nil,
+	)
+
+	svc := &servicemock.Mock{}
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Result().StatusCode)
+}
+
+func TestV2ReverseAuthorisation_ErrMerchantCredentials(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"cards/1/authorisations/2/reversal",
+		nil,
+	)
+
+	httpReq.Header.Set(MerchantIDHeader, "Coffee Shop")
+	httpReq.Header.Set(MerchantKeyHeader, "wrong-key")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"ReverseAuthorisation",
+		service.ReverseAuthorisationRequest{
+			CardID:          "1",
+			AuthorisationID: "2",
+			Merchant:        "Coffee Shop",
+			MerchantKey:     "wrong-key",
+		},
+	).Return(
+		service.CardAuthorisationResponse{},
+		errors.New("invalid merchant credentials"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Result().StatusCode)
+}
+
+func TestV2FreezeCard_ErrCancelled(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"cards/2/freeze",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"FreezeCard",
+		service.FreezeCardRequest{
+			UserID: "1",
+			CardID: "2",
+		},
+	).Return(
+		service.CardResponse{},
+		errors.New("card cancelled"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusConflict, rr.Result().StatusCode)
+}
+
+func TestV2PayOffLoan_NoBody(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"loans/2/payoff",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"PayOffLoan",
+		service.PayOffLoanRequest{
+			UserID: "1",
+			LoanID: "2",
+		},
+	).Return(
+		service.LoanResponse{
+			LoanID: "2",
+			Status: domain.LoanRepaid,
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	assert.Contains(t, rr.Body.String(), `"status":"repaid"`)
+}
+
+func TestV2LoanPayoffQuote_ErrRepaid(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		v2URL+"loans/2/payoff-quote",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"LoanPayoffQuote",
+		service.LoanPayoffQuoteRequest{
+			UserID: "1",
+			LoanID: "2",
+		},
+	).Return(
+		service.LoanPayoffQuoteResponse{},
+		errors.New("loan repaid"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusConflict, rr.Result().StatusCode)
+}
+
+func TestV2MoveToPot(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"pots/2/deposits",
+		strings.NewReader(`{"amount":"5.00"}`),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"MoveToPot",
+		service.MovePotMoneyRequest{
+			UserID: "1",
+			PotID:  "2",
+			Amount: money.New(500, money.EUR),
+		},
+	).Return(
+		service.PotMoveResponse{
+			Pot: service.PotResponse{
+				PotID:   "2",
+				Balance: money.New(500, money.EUR),
+			},
+			AccountBalance: money.New(1000, money.EUR),
+			TransactionID:  "3",
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusCreated, rr.Result().StatusCode)
+	assert.Equal(t, v2URL+"transactions/3", rr.Header().Get("Location"))
+}
+
+func TestV2MoveFromPot_ErrInsufficientFunds(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPost,
+		v2URL+"pots/2/withdrawals",
+		strings.NewReader(`{"amount":"5.00"}`),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"MoveFromPot",
+		service.MovePotMoneyRequest{
+			UserID: "1",
+			PotID:  "2",
+			Amount: money.New(500, money.EUR),
+		},
+	).Return(
+		service.PotMoveResponse{},
+		errors.New("insuficient funds in pot"),
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Result().StatusCode)
+}
+
+func TestV2CategoriseTransaction(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodPut,
+		v2URL+"transactions/2/category",
+		strings.NewReader(`{"category":"gifts"}`),
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	svc.On(
+		"CategoriseTransaction",
+		service.CategoriseTransactionRequest{
+			UserID:        "1",
+			TransactionID: "2",
+			Category:      "gifts",
+		},
+	).Return(
+		service.TransactionResponse{
+			ID:       "2",
+			Category: "gifts",
+		},
+		nil,
+	)
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusOK, rr.Result().StatusCode)
+}
+
+func TestV2SpendingAnalytics_ErrTop(t *testing.T) {
+	httpReq := makeHTTPRequest(
+		t,
+		http.MethodGet,
+		v2URL+"accounts/2/analytics?top=many",
+		nil,
+	)
+
+	httpReq.Header.Set(UserIDHeader, "1")
+
+	svc := &servicemock.Mock{}
+
+	hdl := NewV2(svc)
+
+	rr := setupTest(hdl, httpReq)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+}
+
+func TestStatusCode(t *testing.T) {
+	errCompensate := errors.New("error compensate")
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{service.ErrAutoSaveChanged, http.StatusConflict},
+		{service.ErrCardChanged, http.StatusConflict},
+		{errors.Join(service.ErrInsufficientFunds, errCompensate), http.StatusUnprocessableEntity},
+		{errors.Join(errors.New("account not found"), errCompensate), http.StatusNotFound},
+		{errors.Join(errCompensate, errors.New("invalid amount")), http.StatusInternalServerError},
+		{errors.New("invalid amount"), http.StatusBadRequest},
+		{errCompensate, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.status, statusCode(test.err), test.err.Error())
+	}
 }
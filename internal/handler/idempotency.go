@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+)
+
+const (
+	IdempotencyKeyHeader   = "Idempotency-Key"
+	IdempotentReplayHeader = "Idempotent-Replayed"
+
+	idempotencyWALKind = "idempotency"
+)
+
+type idempotentResponse struct {
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// idempotencyRecord is a finished response as kept in the write-ahead log.
+type idempotencyRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type idempotencyStore struct {
+	mux       sync.Mutex
+	ttl       time.Duration
+	log       wal.Log
+	responses map[string]*idempotentResponse
+	sweptAt   time.Time
+}
+
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes writes carrying an Idempotency-Key safe to retry. Keys
+// are scoped to the X-User-ID sending them. The first response for a key is
+// kept for ttl and replayed to later requests with the same key; a different
+// request reusing the key gets a 422, and a retry arriving while the first is
+// still running gets a 409. Responses are only kept in memory, so a restart
+// forgets them.
+func Idempotency(ttl time.Duration) gin.HandlerFunc {
+	store := &idempotencyStore{
+		ttl:       ttl,
+		responses: map[string]*idempotentResponse{},
+	}
+
+	return store.handle
+}
+
+// DurableIdempotency is Idempotency with finished responses kept in the
+// write-ahead log, so replays survive a restart. A crash after a write ran
+// but before its response was logged still lets a retry run it again.
+func DurableIdempotency(log wal.Log, ttl time.Duration) (gin.HandlerFunc, error) {
+	store := &idempotencyStore{
+		ttl:       ttl,
+		log:       log,
+		responses: map[string]*idempotentResponse{},
+	}
+
+	now := time.Now().UTC()
+
+	err := log.Replay(idempotencyWALKind, func(rec wal.Record) error {
+		var stored idempotencyRecord
+
+		err := json.Unmarshal(rec.Value, &stored)
+
+		if err != nil {
+			return err
+		}
+
+		if now.After(stored.ExpiresAt) {
+			delete(store.responses, rec.Key)
+
+			return nil
+		}
+
+		store.responses[rec.Key] = &idempotentResponse{
+			fingerprint: stored.Fingerprint,
+			done:        true,
+			status:      stored.Status,
+			contentType: stored.ContentType,
+			body:        stored.Body,
+			expiresAt:   stored.ExpiresAt,
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Register(idempotencyWALKind, store.snapshot)
+
+	return store.handle, nil
+}
+
+func (s *idempotencyStore) handle(c *gin.Context) {
+	if c.GetHeader(IdempotencyKeyHeader) == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		c.Next()
+
+		return
+	}
+
+	userID := c.GetHeader(UserIDHeader)
+
+	key := userID + " " + c.GetHeader(IdempotencyKeyHeader)
+
+	body, err := c.GetRawData()
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.RequestURI()+"\n"+userID+"\n"), body...))
+
+	fingerprint := hex.EncodeToString(sum[:])
+
+	now := time.Now().UTC()
+
+	s.mux.Lock()
+
+	s.sweep(now)
+
+	if res, exists := s.responses[key]; exists {
+		stored := *res
+
+		s.mux.Unlock()
+
+		switch {
+		case stored.fingerprint != fingerprint:
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency key reused with a different request"})
+		case !stored.done:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with this idempotency key in progress"})
+		default:
+			c.Header(IdempotentReplayHeader, "true")
+			c.Data(stored.status, stored.contentType, stored.body)
+			c.Abort()
+		}
+
+		return
+	}
+
+	res := &idempotentResponse{
+		fingerprint: fingerprint,
+	}
+
+	s.responses[key] = res
+
+	s.mux.Unlock()
+
+	writer := &recordingWriter{
+		ResponseWriter: c.Writer,
+	}
+
+	c.Writer = writer
+
+	defer func() {
+		s.mux.Lock()
+
+		defer s.mux.Unlock()
+
+		if !c.Writer.Written() {
+			delete(s.responses, key)
+
+			return
+		}
+
+		res.done = true
+		res.status = c.Writer.Status()
+		res.contentType = c.Writer.Header().Get("Content-Type")
+		res.body = writer.body.Bytes()
+		res.expiresAt = time.Now().UTC().Add(s.ttl)
+
+		err := s.persist(key, res)
+
+		if err != nil {
+			_ = c.Error(err)
+		}
+	}()
+
+	c.Next()
+}
+
+// sweep drops expired responses, at most once a minute.
+func (s *idempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < time.Minute {
+		return
+	}
+
+	s.sweptAt = now
+
+	for key, res := range s.responses {
+		if res.done && now.After(res.expiresAt) {
+			delete(s.responses, key)
+		}
+	}
+}
+
+func (s *idempotencyStore) persist(key string, res *idempotentResponse) error {
+	if s.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(storedResponse(res))
+
+	if err != nil {
+		return err
+	}
+
+	return s.log.Append(
+		wal.Record{
+			Kind:  idempotencyWALKind,
+			Key:   key,
+			Value: value,
+		},
+	)
+}
+
+func (s *idempotencyStore) snapshot() ([]wal.Record, error) {
+	s.mux.Lock()
+
+	defer s.mux.Unlock()
+
+	now := time.Now().UTC()
+
+	records := make([]wal.Record, 0, len(s.responses))
+
+	for key, res := range s.responses {
+		if !res.done || now.After(res.expiresAt) {
+			continue
+		}
+
+		value, err := json.Marshal(storedResponse(res))
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  idempotencyWALKind,
+				Key:   key,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func storedResponse(res *idempotentResponse) idempotencyRecord {
+	return idempotencyRecord{
+		Fingerprint: res.fingerprint,
+		Status:      res.status,
+		ContentType: res.contentType,
+		Body:        res.body,
+		ExpiresAt:   res.expiresAt,
+	}
+}
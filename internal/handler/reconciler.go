@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
+	"github.com/hetfdex/tiny-bank/internal/reconciler"
+)
+
+type reconcilerHdl struct {
+	rec reconciler.Reconciler
+}
+
+func NewReconciler(rec reconciler.Reconciler) Handler {
+	return &reconcilerHdl{
+		rec: rec,
+	}
+}
+
+func (h reconcilerHdl) ConfigHandlers(router *gin.Engine) {
+	router.POST(adminURL+"reconciliations", h.run)
+}
+
+func (h reconcilerHdl) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		adminOperation(http.MethodPost, adminURL+"reconciliations", "Run the ledger reconciliation and return the discrepancy report", nil, response(http.StatusOK, "Report", reconciler.Report{})),
+	}
+}
+
+func (h reconcilerHdl) run(c *gin.Context) {
+	res, err := h.rec.Run()
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"bytes"
+	_ "embed"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
+)
+
+const (
+	specURL = "/openapi.json"
+	docsURL = "/docs"
+
+	decimalPattern = `^-?[0-9]+(\.[0-9]+)?$`
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+var moneySchema = openapi.Schema{
+	Description: `Amount in a currency. Requests may send a bare decimal string such as "12.34" (in EUR) or the object form. JSON numbers are rejected.`,
+	OneOf: []*openapi.Schema{
+		{
+			Type:    "string",
+			Pattern: decimalPattern,
+		},
+		{
+			Type:     "object",
+			Required: []string{"amount"},
+			Properties: map[string]*openapi.Schema{
+				"amount": {
+					Type:    "string",
+					Pattern: decimalPattern,
+				},
+				"currency": {
+					Type: "string",
+					Enum: []string{string(money.EUR), string(money.USD), string(money.GBP), string(money.CHF), string(money.JPY)},
+				},
+			},
+		},
+	},
+}
+
+// Spec builds the OpenAPI document from the operations each handler
+// declares, plus the spec and docs routes themselves.
+func Spec(handlers ...Handler) *openapi.Spec {
+	spec := openapi.New(
+		openapi.Info{
+			Title: "Tiny Bank API",
+			Description: "A simple API for simulating a tiny bank. The v2 API under /api/v2 models users, accounts and transactions as resources; " +
+				"routes under /accounts and /transactions take the acting user from the X-User-ID header. The v1 user routes are deprecated. " +
+				"Any non-GET request may carry an Idempotency-Key header: the first response for a key is replayed (with Idempotent-Replayed: true) " +
+				"to retries of the same request, reusing the key for a different request returns 422 and a retry while the first is still running returns 409.",
+			Version: "2.0.0",
+		},
+		openapi.WithSchema(money.Money{}, moneySchema),
+		openapi.WithEnum(money.EUR, money.USD, money.GBP, money.CHF, money.JPY),
+		openapi.WithEnum(domain.RoleOwner, domain.RoleCoOwner, domain.RoleViewer),
+		openapi.WithEnum(domain.UserPending, domain.UserKYCInReview, domain.UserVerified, domain.UserRestricted, domain.UserSuspended, domain.UserClosed),
+		openapi.WithEnum(domain.ProductChecking, domain.ProductSavings, domain.ProductTermDeposit),
+		openapi.WithEnum(domain.LimitScopeUser, domain.LimitScopeAccount),
+		openapi.WithEnum(domain.BatchPending, domain.BatchProcessing, domain.BatchCompleted, domain.BatchCompletedWithErrors, domain.BatchFailed),
+		openapi.WithEnum(domain.PayeeMatchExact, domain.PayeeMatchClose, domain.PayeeMatchNone),
+		openapi.WithEnum(domain.PaymentRequestOpen, domain.PaymentRequestCompleted, domain.PaymentRequestCancelled, domain.PaymentRequestExpired),
+		openapi.WithEnum(domain.PayerPending, domain.PayerPaid, domain.PayerDeclined, domain.PayerCancelled, domain.PayerExpired),
+		openapi.WithEnum(domain.PendingTransferPending, domain.PendingTransferAccepted, domain.PendingTransferDeclined, domain.PendingTransferCancelled, domain.PendingTransferExpired),
+		openapi.WithEnum(domain.MandateOneOff, domain.MandateWeekly, domain.MandateMonthly, domain.MandateQuarterly, domain.MandateYearly),
+		openapi.WithEnum(domain.MandateActive, domain.MandateRevoked),
+		openapi.WithEnum(domain.CollectionPending, domain.CollectionCompleted, domain.CollectionFailed, domain.CollectionRefunded),
+		openapi.WithEnum(domain.CardActive, domain.CardFrozen, domain.CardLocked, domain.CardCancelled),
+		openapi.WithEnum(domain.AuthorisationHeld, domain.AuthorisationCaptured, domain.AuthorisationReversed),
+		openapi.WithEnum(domain.LoanAnnuity, domain.LoanLinear),
+		openapi.WithEnum(domain.LoanActive, domain.LoanRepaid),
+		openapi.WithEnum(domain.InstalmentScheduled, domain.InstalmentOverdue, domain.InstalmentPaid, domain.InstalmentSettled),
+		openapi.WithEnum(domain.AutoSaveRoundUp, domain.AutoSaveDepositPercentage, domain.AutoSaveWeekly),
+		openapi.WithEnum(domain.BatchLinePending, domain.BatchLineCompleted, domain.BatchLineFailed, domain.BatchLineRolledBack, domain.BatchLineSkipped),
+	)
+
+	for _, hdl := range handlers {
+		spec.Add(hdl.Operations()...)
+	}
+
+	spec.Add(docsOperations()...)
+
+	return spec
+}
+
+// Validation checks JSON request bodies against the spec before they reach
+// the handlers. Malformed bodies get a 400 naming each offending field.
+func Validation(spec *openapi.Spec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.FullPath() == "" {
+			c.Next()
+
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fields, err := spec.Validate(c.Request.Method, c.FullPath(), body)
+
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		if len(fields) > 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "fields": fields})
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+type docsHdl struct {
+	spec *openapi.Spec
+}
+
+// NewDocs serves the generated spec and a page rendering it.
+func NewDocs(spec *openapi.Spec) Handler {
+	return &docsHdl{
+		spec: spec,
+	}
+}
+
+func (h docsHdl) ConfigHandlers(router *gin.Engine) {
+	router.GET(specURL, h.openAPI)
+	router.GET(docsURL, h.docs)
+}
+
+func (h docsHdl) Operations() []openapi.Operation {
+	return docsOperations()
+}
+
+func (h docsHdl) openAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.spec.Document())
+}
+
+func (h docsHdl) docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+func docsOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    specURL,
+			Summary: "This OpenAPI document",
+			Tag:     "docs",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "OpenAPI 3 document"},
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    docsURL,
+			Summary: "API reference rendered from the OpenAPI document",
+			Tag:     "docs",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "HTML page", MediaType: "text/html"},
+			},
+		},
+	}
+}
+
+// requestBody says how a handler binds its body, for bodies that are
+// optional or have fields filled in from elsewhere than the path.
+type requestBody struct {
+	value    any
+	optional bool
+	omit     []string
+}
+
+func optional(value any) requestBody {
+	return requestBody{
+		value:    value,
+		optional: true,
+	}
+}
+
+func omit(value any, fields ...string) requestBody {
+	return requestBody{
+		value: value,
+		omit:  fields,
+	}
+}
+
+func operation(method string, path string, summary string, body any, responses ...openapi.Response) openapi.Operation {
+	op := openapi.Operation{
+		Method:    method,
+		Path:      path,
+		Summary:   summary,
+		Responses: responses,
+	}
+
+	switch b := body.(type) {
+	case nil:
+	case requestBody:
+		op.Body = b.value
+		op.BodyOptional = b.optional
+		op.Omit = b.omit
+	default:
+		op.Body = body
+	}
+
+	return op
+}
+
+func v1Operation(method string, path string, summary string, body any, responses ...openapi.Response) openapi.Operation {
+	op := operation(method, path, summary, body, responses...)
+
+	op.Tag = "v1"
+	op.Deprecated = true
+
+	return op
+}
+
+func adminOperation(method string, path string, summary string, body any, responses ...openapi.Response) openapi.Operation {
+	op := operation(method, path, summary, body, responses...)
+
+	op.Tag = "admin"
+
+	return op
+}
+
+func withParams(op openapi.Operation, params ...openapi.Param) openapi.Operation {
+	op.Params = append(op.Params, params...)
+
+	return op
+}
+
+func response(status int, description string, body any) openapi.Response {
+	return openapi.Response{
+		Status:      status,
+		Description: description,
+		Body:        body,
+	}
+}
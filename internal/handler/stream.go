@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/events"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+const (
+	keepAliveInterval = 15 * time.Second
+)
+
+type streamHdl struct {
+	svc service.Service
+	hub events.Hub
+}
+
+func NewStream(svc service.Service, hub events.Hub) Handler {
+	return &streamHdl{
+		svc: svc,
+		hub: hub,
+	}
+}
+
+func (h streamHdl) ConfigHandlers(router *gin.Engine) {
+	router.GET(baseURL+":user_id/events", h.userEvents)
+	router.GET(baseURL+":user_id/accounts/:account_id/events", h.accountEvents)
+}
+
+func (h streamHdl) Operations() []openapi.Operation {
+	lastEventID := []openapi.Param{
+		{Name: "Last-Event-ID", In: "header", Description: "Resume after this event"},
+		{Name: "last_event_id", In: "query", Description: "Resume after this event, for clients that cannot set headers"},
+	}
+
+	stream := openapi.Response{
+		Status:      http.StatusOK,
+		Description: "Server-Sent Events: balance, transaction and reset events",
+		MediaType:   "text/event-stream",
+	}
+
+	return []openapi.Operation{
+		withParams(operation(http.MethodGet, baseURL+":user_id/events", "Stream balance changes and new transactions on all accounts the user held when connecting", nil, stream), lastEventID...),
+		withParams(operation(http.MethodGet, baseURL+":user_id/accounts/:account_id/events", "Stream balance changes and new transactions on an account", nil, stream), lastEventID...),
+	}
+}
+
+func (h streamHdl) userEvents(c *gin.Context) {
+	res, err := h.svc.Accounts(
+		service.AccountsRequest{
+			UserID: c.Param("user_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	accountIDs := make([]string, 0, len(res.Accounts))
+
+	for _, account := range res.Accounts {
+		accountIDs = append(accountIDs, account.AccountID)
+	}
+
+	h.stream(c, accountIDs)
+}
+
+func (h streamHdl) accountEvents(c *gin.Context) {
+	_, err := h.svc.Balance(
+		service.BalanceRequest{
+			UserID:    c.Param("user_id"),
+			AccountID: c.Param("account_id"),
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	h.stream(c, []string{c.Param("account_id")})
+}
+
+// stream writes the replayed events and then live ones until the client
+// goes away. Browsers send Last-Event-ID on reconnect; clients that cannot
+// set headers may pass last_event_id as a query parameter instead.
+func (h streamHdl) stream(c *gin.Context, accountIDs []string) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub, err := h.hub.Subscribe(
+		events.SubscribeRequest{
+			AccountIDs:  accountIDs,
+			LastEventID: lastEventID,
+		},
+	)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	for _, event := range sub.Replay {
+		renderEvent(c, event)
+	}
+
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+
+			renderEvent(c, event)
+		case <-ticker.C:
+			_, err = c.Writer.WriteString(": keep-alive\n\n")
+
+			if err != nil {
+				return
+			}
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+func renderEvent(c *gin.Context, event events.Event) {
+	c.Render(
+		-1,
+		sse.Event{
+			Id:    strconv.FormatUint(event.ID, 10),
+			Event: event.Type,
+			Data:  event.Data,
+		},
+	)
+}
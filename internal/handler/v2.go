@@ -0,0 +1,2352 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
+	"github.com/hetfdex/tiny-bank/internal/service"
+)
+
+const (
+	v2URL = "/api/v2/"
+
+	// UserIDHeader names the acting user on v2 routes that are not nested
+	// under a user.
+	UserIDHeader = "X-User-ID"
+
+	// MerchantIDHeader and MerchantKeyHeader carry the credentials of the
+	// merchant capturing or reversing a card authorisation.
+	MerchantIDHeader  = "X-Merchant-ID"
+	MerchantKeyHeader = "X-Merchant-Key"
+)
+
+var paymentRequestsParams = []openapi.Param{
+	{Name: "direction", In: "query", Enum: []string{service.DirectionIncoming, service.DirectionOutgoing}, Description: "Only requests to pay (incoming) or made (outgoing); both when left out"},
+	{Name: "status", In: "query", Enum: []string{string(domain.PaymentRequestOpen), string(domain.PaymentRequestCompleted), string(domain.PaymentRequestCancelled), string(domain.PaymentRequestExpired)}, Description: "Only requests in this status"},
+}
+
+var pendingTransfersParams = []openapi.Param{
+	{Name: "direction", In: "query", Enum: []string{service.DirectionIncoming, service.DirectionOutgoing}, Description: "Only transfers to accept (incoming) or sent (outgoing); both when left out"},
+	{Name: "status", In: "query", Enum: []string{string(domain.PendingTransferPending), string(domain.PendingTransferAccepted), string(domain.PendingTransferDeclined), string(domain.PendingTransferCancelled), string(domain.PendingTransferExpired)}, Description: "Only transfers in this status"},
+}
+
+var mandatesParams = []openapi.Param{
+	{Name: "role", In: "query", Enum: []string{service.MandateRoleDebtor, service.MandateRoleCreditor}, Description: "Only mandates the caller granted (debtor) or collects under (creditor); both when left out"},
+}
+
+var cardsParams = []openapi.Param{
+	{Name: "account_id", In: "query", Description: "Only cards on this account, by id or IBAN"},
+}
+
+var loansParams = []openapi.Param{
+	{Name: "status", In: "query", Enum: []string{string(domain.LoanActive), string(domain.LoanRepaid)}, Description: "Only loans in this status"},
+}
+
+var analyticsParams = []openapi.Param{
+	{Name: "from", In: "query", Description: "First month, as YYYY-MM; five months before to when left out"},
+	{Name: "to", In: "query", Description: "Last month, as YYYY-MM; the current month when left out"},
+	{Name: "top", In: "query", Description: "How many counterparties to return, 5 when left out and at most 50"},
+}
+
+var cardAuthorisationsParams = []openapi.Param{
+	{Name: "status", In: "query", Enum: []string{string(domain.AuthorisationHeld), string(domain.AuthorisationCaptured), string(domain.AuthorisationReversed)}, Description: "Only authorisations in this status"},
+}
+
+var collectionsParams = []openapi.Param{
+	{Name: "status", In: "query", Enum: []string{string(domain.CollectionPending), string(domain.CollectionCompleted), string(domain.CollectionFailed), string(domain.CollectionRefunded)}, Description: "Only collections in this status"},
+}
+
+type v2Hdl struct {
+	svc service.Service
+}
+
+// NewV2 serves the resource oriented API. Accounts and transactions are top
+// level resources, so routes under them take the acting user from the
+// X-User-ID header instead of the path.
+func NewV2(svc service.Service) Handler {
+	return &v2Hdl{
+		svc: svc,
+	}
+}
+
+func (h v2Hdl) ConfigHandlers(router *gin.Engine) {
+	router.POST(v2URL+"users", h.createUser)
+	router.GET(v2URL+"users/:user_id", h.user)
+	router.DELETE(v2URL+"users/:user_id", h.deactivateUser)
+	router.POST(v2URL+"users/:user_id/reactivation", h.reactivateUser)
+	router.PUT(v2URL+"users/:user_id/kyc", h.submitKYC)
+	router.GET(v2URL+"users/:user_id/accounts", h.accounts)
+	router.POST(v2URL+"users/:user_id/accounts", h.createAccount)
+	router.GET(v2URL+"accounts/:account_id", h.balance)
+	router.POST(v2URL+"accounts/:account_id/deposits", h.deposit)
+	router.POST(v2URL+"accounts/:account_id/withdrawals", h.withdraw)
+	router.POST(v2URL+"accounts/:account_id/transfers", h.transfer)
+	router.POST(v2URL+"accounts/:account_id/pending-transfers", h.createPendingTransfer)
+	router.POST(v2URL+"accounts/:account_id/cards", h.issueCard)
+	router.GET(v2URL+"accounts/:account_id/pots", h.pots)
+	router.POST(v2URL+"accounts/:account_id/pots", h.createPot)
+	router.GET(v2URL+"accounts/:account_id/transactions", h.transactions)
+	router.GET(v2URL+"accounts/:account_id/analytics", h.spendingAnalytics)
+	router.GET(v2URL+"accounts/:account_id/holders", h.holders)
+	router.POST(v2URL+"accounts/:account_id/holders", h.addHolder)
+	router.GET(v2URL+"accounts/:account_id/holders/:holder_user_id", h.holder)
+	router.DELETE(v2URL+"accounts/:account_id/holders/:holder_user_id", h.removeHolder)
+	router.PUT(v2URL+"accounts/:account_id/rules", h.updateAccountRules)
+	router.GET(v2URL+"transactions/:transaction_id", h.transaction)
+	router.PUT(v2URL+"transactions/:transaction_id/category", h.categoriseTransaction)
+	router.DELETE(v2URL+"transactions/:transaction_id/category", h.uncategoriseTransaction)
+	router.GET(v2URL+"category-rules", h.categoryRules)
+	router.PUT(v2URL+"category-rules", h.putCategoryRules)
+	router.GET(v2URL+"payees", h.payees)
+	router.POST(v2URL+"payees", h.addPayee)
+	router.GET(v2URL+"payees/:payee_id", h.payee)
+	router.PATCH(v2URL+"payees/:payee_id", h.updatePayee)
+	router.DELETE(v2URL+"payees/:payee_id", h.deletePayee)
+	router.GET(v2URL+"payment-requests", h.paymentRequests)
+	router.POST(v2URL+"payment-requests", h.requestPayment)
+	router.GET(v2URL+"payment-requests/:payment_request_id", h.paymentRequest)
+	router.POST(v2URL+"payment-requests/:payment_request_id/acceptance", h.acceptPaymentRequest)
+	router.POST(v2URL+"payment-requests/:payment_request_id/decline", h.declinePaymentRequest)
+	router.POST(v2URL+"payment-requests/:payment_request_id/cancellation", h.cancelPaymentRequest)
+	router.GET(v2URL+"pending-transfers", h.pendingTransfers)
+	router.GET(v2URL+"pending-transfers/:pending_transfer_id", h.pendingTransfer)
+	router.POST(v2URL+"pending-transfers/:pending_transfer_id/acceptance", h.acceptPendingTransfer)
+	router.POST(v2URL+"pending-transfers/:pending_transfer_id/decline", h.declinePendingTransfer)
+	router.POST(v2URL+"pending-transfers/:pending_transfer_id/cancellation", h.cancelPendingTransfer)
+	router.GET(v2URL+"mandates", h.mandates)
+	router.POST(v2URL+"mandates", h.grantMandate)
+	router.GET(v2URL+"mandates/:mandate_id", h.mandate)
+	router.POST(v2URL+"mandates/:mandate_id/revocation", h.revokeMandate)
+	router.GET(v2URL+"mandates/:mandate_id/collections", h.collections)
+	router.POST(v2URL+"mandates/:mandate_id/collections", h.collectPayment)
+	router.GET(v2URL+"mandates/:mandate_id/collections/:collection_id", h.collection)
+	router.POST(v2URL+"mandates/:mandate_id/collections/:collection_id/refund", h.refundCollection)
+	router.GET(v2URL+"cards", h.cards)
+	router.GET(v2URL+"cards/:card_id", h.card)
+	router.POST(v2URL+"cards/:card_id/freeze", h.freezeCard)
+	router.POST(v2URL+"cards/:card_id/unfreeze", h.unfreezeCard)
+	router.POST(v2URL+"cards/:card_id/cancellation", h.cancelCard)
+	router.PUT(v2URL+"cards/:card_id/limits", h.updateCardLimits)
+	router.GET(v2URL+"cards/:card_id/authorisations", h.cardAuthorisations)
+	router.GET(v2URL+"cards/:card_id/authorisations/:authorisation_id", h.cardAuthorisation)
+	router.POST(v2URL+"cards/:card_id/authorisations/:authorisation_id/capture", h.captureAuthorisation)
+	router.POST(v2URL+"cards/:card_id/authorisations/:authorisation_id/reversal", h.reverseAuthorisation)
+	router.POST(v2URL+"card-authorisations", h.authoriseCard)
+	router.GET(v2URL+"loans", h.loans)
+	router.GET(v2URL+"loans/:loan_id", h.loan)
+	router.GET(v2URL+"loans/:loan_id/payoff-quote", h.loanPayoffQuote)
+	router.POST(v2URL+"loans/:loan_id/payoff", h.payOffLoan)
+	router.GET(v2URL+"loans/:loan_id/statement", h.loanStatement)
+	router.GET(v2URL+"pots/:pot_id", h.pot)
+	router.PATCH(v2URL+"pots/:pot_id", h.updatePot)
+	router.POST(v2URL+"pots/:pot_id/closure", h.closePot)
+	router.POST(v2URL+"pots/:pot_id/deposits", h.moveToPot)
+	router.POST(v2URL+"pots/:pot_id/withdrawals", h.moveFromPot)
+	router.PUT(v2URL+"pots/:pot_id/auto-save", h.putAutoSaveRules)
+}
+
+func (h v2Hdl) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		v2Operation(http.MethodPost, v2URL+"users", "Create a new user", service.CreateUserRequest{}, createdResponse("User created, Location points at the user", service.CreateUserResponse{})),
+		v2Operation(http.MethodGet, v2URL+"users/:user_id", "Get a user's status and KYC record (ID number masked)", nil, response(http.StatusOK, "User", service.KYCResponse{})),
+		v2Operation(http.MethodDelete, v2URL+"users/:user_id", "Deactivate a user, sweeping balances to a payout account and closing all accounts", optional(service.DeactivateUserRequest{}), response(http.StatusOK, "User deactivated, returns the closing statement", service.DeactivateUserResponse{})),
+		v2Operation(http.MethodPost, v2URL+"users/:user_id/reactivation", "Reactivate a deactivated user within the grace period", nil, response(http.StatusNoContent, "User reactivated", nil)),
+		v2Operation(http.MethodPut, v2URL+"users/:user_id/kyc", "Submit KYC documents", service.SubmitKYCRequest{}, response(http.StatusOK, "KYC submitted", service.KYCResponse{})),
+		v2Operation(http.MethodGet, v2URL+"users/:user_id/accounts", "List the accounts a user holds", nil, response(http.StatusOK, "Accounts", service.AccountsResponse{})),
+		v2Operation(http.MethodPost, v2URL+"users/:user_id/accounts", "Open an account for a user", optional(service.CreateAccountRequest{}), createdResponse("Account created, Location points at the account", service.CreateAccountResponse{})),
+		actingOperation(http.MethodGet, v2URL+"accounts/:account_id", "Get an account balance", nil, response(http.StatusOK, "Balance", service.BalanceResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/deposits", "Deposit into an account", omit(service.DepositRequest{}, "user_id"), createdResponse("Deposit booked, Location points at the transaction", service.DepositResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/withdrawals", "Withdraw from an account", omit(service.WithdrawRequest{}, "user_id"), createdResponse("Withdrawal booked, Location points at the transaction", service.WithdrawResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/transfers", "Transfer from an account", omit(service.TransferRequest{}, "sender_user_id", "sender_account_id"), createdResponse("Transfer booked, Location points at the sender's transaction, or at the pending transfer when it is held for the receiver to accept", service.TransferResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/pending-transfers", "Hold a transfer from an account until the receiver accepts it", omit(service.CreatePendingTransferRequest{}, "sender_user_id", "sender_account_id"), createdResponse("Funds held, Location points at the pending transfer", service.PendingTransferResponse{})),
+		actingOperation(http.MethodGet, v2URL+"accounts/:account_id/pots", "List an account's open pots", nil, response(http.StatusOK, "Pots, newest first", service.PotsResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/pots", "Open a pot in an account, with an optional goal and auto-save rules", omit(service.CreatePotRequest{}, "user_id", "account_id"), createdResponse("Pot opened, Location points at it", service.PotResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/cards", "Issue a virtual card on an account to the caller", requestBody{value: service.IssueCardRequest{}, optional: true, omit: []string{"user_id", "account_id"}}, createdResponse("Card issued, the only response with the full card number and CVV, Location points at the card", service.IssueCardResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"accounts/:account_id/transactions", "List an account's transactions, optionally searched", nil, response(http.StatusOK, "Transactions", service.TransactionsResponse{})), transactionsParams...),
+		withParams(actingOperation(http.MethodGet, v2URL+"accounts/:account_id/analytics", "Total an account's spending by month and category, with the top counterparties", nil, response(http.StatusOK, "Spending analytics", service.SpendingAnalyticsResponse{})), analyticsParams...),
+		actingOperation(http.MethodGet, v2URL+"accounts/:account_id/holders", "List account holders", nil, response(http.StatusOK, "Holders", service.HoldersResponse{})),
+		actingOperation(http.MethodPost, v2URL+"accounts/:account_id/holders", "Add an account holder", omit(service.AddHolderRequest{}, "user_id"), createdResponse("Holder added, Location points at the holder", service.HolderResponse{})),
+		actingOperation(http.MethodGet, v2URL+"accounts/:account_id/holders/:holder_user_id", "Get one account holder", nil, response(http.StatusOK, "Holder", service.HolderResponse{})),
+		actingOperation(http.MethodDelete, v2URL+"accounts/:account_id/holders/:holder_user_id", "Remove an account holder", nil, response(http.StatusNoContent, "Holder removed", nil)),
+		actingOperation(http.MethodPut, v2URL+"accounts/:account_id/rules", "Update account rules", omit(service.UpdateAccountRulesRequest{}, "user_id"), response(http.StatusNoContent, "Rules updated", nil)),
+		actingOperation(http.MethodGet, v2URL+"transactions/:transaction_id", "Get one transaction from any account the caller can view", nil, response(http.StatusOK, "Transaction", service.TransactionResponse{})),
+		actingOperation(http.MethodPut, v2URL+"transactions/:transaction_id/category", "Set a transaction's category by hand, over any rule", omit(service.CategoriseTransactionRequest{}, "user_id", "transaction_id"), response(http.StatusOK, "Transaction", service.TransactionResponse{})),
+		actingOperation(http.MethodDelete, v2URL+"transactions/:transaction_id/category", "Clear a category set by hand, so the rules apply again", nil, response(http.StatusOK, "Transaction", service.TransactionResponse{})),
+		actingOperation(http.MethodGet, v2URL+"category-rules", "Get the caller's category rules and the built-in categories", nil, response(http.StatusOK, "Category rules, in the order they are tried", service.CategoryRulesResponse{})),
+		actingOperation(http.MethodPut, v2URL+"category-rules", "Replace the caller's category rules", omit(service.PutCategoryRulesRequest{}, "user_id"), response(http.StatusOK, "Category rules, in the order they are tried", service.CategoryRulesResponse{})),
+		actingOperation(http.MethodGet, v2URL+"payees", "List the caller's payees", nil, response(http.StatusOK, "Payees", service.PayeesResponse{})),
+		actingOperation(http.MethodPost, v2URL+"payees", "Add a payee by account id or IBAN, checking the name against the account holders", omit(service.AddPayeeRequest{}, "user_id"), createdResponse("Payee added, Location points at the payee", service.PayeeResponse{})),
+		actingOperation(http.MethodGet, v2URL+"payees/:payee_id", "Get one payee", nil, response(http.StatusOK, "Payee", service.PayeeResponse{})),
+		actingOperation(http.MethodPatch, v2URL+"payees/:payee_id", "Rename a payee or correct the holder name", omit(service.UpdatePayeeRequest{}, "user_id", "payee_id"), response(http.StatusOK, "Payee", service.PayeeResponse{})),
+		actingOperation(http.MethodDelete, v2URL+"payees/:payee_id", "Remove a payee", nil, response(http.StatusNoContent, "Payee removed", nil)),
+		withParams(actingOperation(http.MethodGet, v2URL+"payment-requests", "List payment requests the caller made or is asked to pay", nil, response(http.StatusOK, "Payment requests, newest first", service.PaymentRequestsResponse{})), paymentRequestsParams...),
+		actingOperation(http.MethodPost, v2URL+"payment-requests", "Ask one or more users for money, split evenly or by share", omit(service.RequestPaymentRequest{}, "user_id"), createdResponse("Payment request created, Location points at it", service.PaymentRequestResponse{})),
+		actingOperation(http.MethodGet, v2URL+"payment-requests/:payment_request_id", "Get a payment request the caller made or is asked to pay", nil, response(http.StatusOK, "Payment request", service.PaymentRequestResponse{})),
+		actingOperation(http.MethodPost, v2URL+"payment-requests/:payment_request_id/acceptance", "Pay the caller's share from one of their accounts", omit(service.AcceptPaymentRequestRequest{}, "user_id", "payment_request_id"), response(http.StatusOK, "Share paid", service.PaymentRequestResponse{})),
+		actingOperation(http.MethodPost, v2URL+"payment-requests/:payment_request_id/decline", "Decline the caller's share", nil, response(http.StatusOK, "Share declined", service.PaymentRequestResponse{})),
+		actingOperation(http.MethodPost, v2URL+"payment-requests/:payment_request_id/cancellation", "Cancel the shares not yet paid", nil, response(http.StatusOK, "Payment request cancelled", service.PaymentRequestResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"pending-transfers", "List pending transfers the caller sent or is asked to accept", nil, response(http.StatusOK, "Pending transfers, newest first", service.PendingTransfersResponse{})), pendingTransfersParams...),
+		actingOperation(http.MethodGet, v2URL+"pending-transfers/:pending_transfer_id", "Get a pending transfer the caller sent or is asked to accept", nil, response(http.StatusOK, "Pending transfer", service.PendingTransferResponse{})),
+		actingOperation(http.MethodPost, v2URL+"pending-transfers/:pending_transfer_id/acceptance", "Accept a transfer, moving the held funds to the receiver", nil, response(http.StatusOK, "Transfer accepted", service.PendingTransferResponse{})),
+		actingOperation(http.MethodPost, v2URL+"pending-transfers/:pending_transfer_id/decline", "Decline a transfer, refunding the sender", nil, response(http.StatusOK, "Transfer declined", service.PendingTransferResponse{})),
+		actingOperation(http.MethodPost, v2URL+"pending-transfers/:pending_transfer_id/cancellation", "Cancel a transfer not yet accepted, refunding the sender", nil, response(http.StatusOK, "Transfer cancelled", service.PendingTransferResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"mandates", "List mandates the caller granted or collects under", nil, response(http.StatusOK, "Mandates, newest first", service.MandatesResponse{})), mandatesParams...),
+		actingOperation(http.MethodPost, v2URL+"mandates", "Let a creditor account collect from one of the caller's accounts", omit(service.GrantMandateRequest{}, "user_id"), createdResponse("Mandate granted, Location points at it", service.MandateResponse{})),
+		actingOperation(http.MethodGet, v2URL+"mandates/:mandate_id", "Get a mandate the caller granted or collects under", nil, response(http.StatusOK, "Mandate", service.MandateResponse{})),
+		actingOperation(http.MethodPost, v2URL+"mandates/:mandate_id/revocation", "Revoke a mandate, stopping further collections", nil, response(http.StatusOK, "Mandate revoked", service.MandateResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"mandates/:mandate_id/collections", "List a mandate's collections", nil, response(http.StatusOK, "Collections, newest first", service.CollectionsResponse{})), collectionsParams...),
+		actingOperation(http.MethodPost, v2URL+"mandates/:mandate_id/collections", "Collect from the debtor as a holder of the creditor account", omit(service.CollectPaymentRequest{}, "user_id", "mandate_id"), createdResponse("Collection made, or failed with the reason, Location points at it", service.CollectionResponse{})),
+		actingOperation(http.MethodGet, v2URL+"mandates/:mandate_id/collections/:collection_id", "Get one collection", nil, response(http.StatusOK, "Collection", service.CollectionResponse{})),
+		actingOperation(http.MethodPost, v2URL+"mandates/:mandate_id/collections/:collection_id/refund", "Take a collection back, with its fee, within the refund period", nil, response(http.StatusOK, "Collection refunded", service.CollectionResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"cards", "List the cards issued to the caller", nil, response(http.StatusOK, "Cards, newest first", service.CardsResponse{})), cardsParams...),
+		actingOperation(http.MethodGet, v2URL+"cards/:card_id", "Get a card issued to the caller, with the card number masked", nil, response(http.StatusOK, "Card", service.CardResponse{})),
+		actingOperation(http.MethodPost, v2URL+"cards/:card_id/freeze", "Freeze a card, declining new authorisations", nil, response(http.StatusOK, "Card frozen", service.CardResponse{})),
+		actingOperation(http.MethodPost, v2URL+"cards/:card_id/unfreeze", "Unfreeze a frozen card", nil, response(http.StatusOK, "Card active", service.CardResponse{})),
+		actingOperation(http.MethodPost, v2URL+"cards/:card_id/cancellation", "Cancel a card for good", nil, response(http.StatusOK, "Card cancelled", service.CardResponse{})),
+		actingOperation(http.MethodPut, v2URL+"cards/:card_id/limits", "Replace a card's spending limits", omit(service.UpdateCardLimitsRequest{}, "user_id", "card_id"), response(http.StatusOK, "Card", service.CardResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"cards/:card_id/authorisations", "List a card's authorisations", nil, response(http.StatusOK, "Authorisations, newest first", service.CardAuthorisationsResponse{})), cardAuthorisationsParams...),
+		actingOperation(http.MethodGet, v2URL+"cards/:card_id/authorisations/:authorisation_id", "Get one card authorisation", nil, response(http.StatusOK, "Authorisation", service.CardAuthorisationResponse{})),
+		merchantOperation(http.MethodPost, v2URL+"cards/:card_id/authorisations/:authorisation_id/capture", "Capture a held authorisation, for at most the held amount (merchant)", requestBody{value: service.CaptureAuthorisationRequest{}, optional: true, omit: []string{"card_id", "authorisation_id"}}, response(http.StatusOK, "Card payment booked", service.CardAuthorisationResponse{})),
+		merchantOperation(http.MethodPost, v2URL+"cards/:card_id/authorisations/:authorisation_id/reversal", "Reverse a held authorisation, releasing the hold (merchant)", nil, response(http.StatusOK, "Authorisation reversed", service.CardAuthorisationResponse{})),
+		merchantOperation(http.MethodPost, v2URL+"card-authorisations", "Authorise a card payment, holding the amount on the card's account (merchant)", service.AuthoriseCardRequest{}, createdResponse("Amount held, Location points at the authorisation", service.CardAuthorisationResponse{})),
+		withParams(actingOperation(http.MethodGet, v2URL+"loans", "List the caller's loans", nil, response(http.StatusOK, "Loans, newest first", service.LoansResponse{})), loansParams...),
+		actingOperation(http.MethodGet, v2URL+"loans/:loan_id", "Get a loan with its amortisation schedule", nil, response(http.StatusOK, "Loan", service.LoanResponse{})),
+		actingOperation(http.MethodGet, v2URL+"loans/:loan_id/payoff-quote", "Price paying off a loan today", nil, response(http.StatusOK, "Payoff quote, valid until midnight UTC", service.LoanPayoffQuoteResponse{})),
+		actingOperation(http.MethodPost, v2URL+"loans/:loan_id/payoff", "Pay off a loan early at today's payoff quote", requestBody{value: service.PayOffLoanRequest{}, optional: true, omit: []string{"user_id", "loan_id"}}, response(http.StatusOK, "Loan repaid", service.LoanResponse{})),
+		actingOperation(http.MethodGet, v2URL+"loans/:loan_id/statement", "Get a loan statement with every repayment", nil, response(http.StatusOK, "Loan statement", service.LoanStatementResponse{})),
+		actingOperation(http.MethodGet, v2URL+"pots/:pot_id", "Get a pot and its progress towards the goal", nil, response(http.StatusOK, "Pot", service.PotResponse{})),
+		actingOperation(http.MethodPatch, v2URL+"pots/:pot_id", "Rename a pot or change its goal", omit(service.UpdatePotRequest{}, "user_id", "pot_id"), response(http.StatusOK, "Pot", service.PotResponse{})),
+		actingOperation(http.MethodPost, v2URL+"pots/:pot_id/closure", "Close a pot, moving its balance back to the account", nil, response(http.StatusOK, "Pot closed", service.PotResponse{})),
+		actingOperation(http.MethodPost, v2URL+"pots/:pot_id/deposits", "Move money from the account balance into a pot", omit(service.MovePotMoneyRequest{}, "user_id", "pot_id"), createdResponse("Money moved, Location points at the account transaction", service.PotMoveResponse{})),
+		actingOperation(http.MethodPost, v2URL+"pots/:pot_id/withdrawals", "Move money from a pot back to the account balance", omit(service.MovePotMoneyRequest{}, "user_id", "pot_id"), createdResponse("Money moved, Location points at the account transaction", service.PotMoveResponse{})),
+		actingOperation(http.MethodPut, v2URL+"pots/:pot_id/auto-save", "Replace a pot's auto-save rules", omit(service.PutAutoSaveRulesRequest{}, "user_id", "pot_id"), response(http.StatusOK, "Pot", service.PotResponse{})),
+	}
+}
+
+func (h v2Hdl) createUser(c *gin.Context) {
+	var req service.CreateUserRequest
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	res, err := h.svc.CreateUser(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"users/"+res.UserID, res)
+}
+
+func (h v2Hdl) user(c *gin.Context) {
+	res, err := h.svc.KYC(
+		service.KYCRequest{
+			UserID: c.Param("user_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) deactivateUser(c *gin.Context) {
+	req := service.DeactivateUserRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	res, err := h.svc.DeactivateUser(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) reactivateUser(c *gin.Context) {
+	err := h.svc.ReactivateUser(
+		service.ReactivateUserRequest{
+			UserID: c.Param("user_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	noContent(c)
+}
+
+func (h v2Hdl) submitKYC(c *gin.Context) {
+	req := service.SubmitKYCRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	res, err := h.svc.SubmitKYC(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) accounts(c *gin.Context) {
+	res, err := h.svc.Accounts(
+		service.AccountsRequest{
+			UserID: c.Param("user_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) createAccount(c *gin.Context) {
+	req := service.CreateAccountRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+
+	res, err := h.svc.CreateAccount(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"accounts/"+res.AccountID, res)
+}
+
+func (h v2Hdl) balance(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Balance(
+		service.BalanceRequest{
+			UserID:    userID,
+			AccountID: c.Param("account_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) deposit(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.DepositRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.AccountID = c.Param("account_id")
+
+	res, err := h.svc.Deposit(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"transactions/"+res.TransactionID, res)
+}
+
+func (h v2Hdl) withdraw(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.WithdrawRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.AccountID = c.Param("account_id")
+
+	res, err := h.svc.Withdraw(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"transactions/"+res.TransactionID, res)
+}
+
+func (h v2Hdl) transfer(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.TransferRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.SenderUserID = userID
+	req.SenderAccountID = c.Param("account_id")
+
+	res, err := h.svc.Transfer(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	if res.PendingTransferID != "" {
+		created(c, v2URL+"pending-transfers/"+res.PendingTransferID, res)
+
+		return
+	}
+
+	created(c, v2URL+"transactions/"+res.TransactionID, res)
+}
+
+func (h v2Hdl) transactions(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Transactions(transactionsRequest(c, userID))
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) holders(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Holders(
+		service.HoldersRequest{
+			UserID:    userID,
+			AccountID: c.Param("account_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) holder(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Holders(
+		service.HoldersRequest{
+			UserID:    userID,
+			AccountID: c.Param("account_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	for _, holder := range res.Holders {
+		if holder.UserID == c.Param("holder_user_id") {
+			c.JSON(http.StatusOK, holder)
+
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "holder not found"})
+}
+
+func (h v2Hdl) addHolder(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.AddHolderRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.AccountID = c.Param("account_id")
+
+	err = h.svc.AddHolder(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(
+		c,
+		v2URL+"accounts/"+req.AccountID+"/holders/"+req.HolderUserID,
+		service.HolderResponse{
+			UserID: req.HolderUserID,
+			Role:   req.Role,
+		},
+	)
+}
+
+func (h v2Hdl) removeHolder(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	err := h.svc.RemoveHolder(
+		service.RemoveHolderRequest{
+			UserID:       userID,
+			AccountID:    c.Param("account_id"),
+			HolderUserID: c.Param("holder_user_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	noContent(c)
+}
+
+func (h v2Hdl) updateAccountRules(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.UpdateAccountRulesRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.AccountID = c.Param("account_id")
+
+	err = h.svc.UpdateAccountRules(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	noContent(c)
+}
+
+func (h v2Hdl) transaction(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Transaction(
+		service.TransactionRequest{
+			UserID:        userID,
+			TransactionID: c.Param("transaction_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) payees(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Payees(
+		service.PayeesRequest{
+			UserID: userID,
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) addPayee(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.AddPayeeRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := h.svc.AddPayee(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"payees/"+res.PayeeID, res)
+}
+
+func (h v2Hdl) payee(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Payee(
+		service.PayeeRequest{
+			UserID:  userID,
+			PayeeID: c.Param("payee_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) updatePayee(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.UpdatePayeeRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.PayeeID = c.Param("payee_id")
+
+	res, err := h.svc.UpdatePayee(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) deletePayee(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeletePayee(
+		service.DeletePayeeRequest{
+			UserID:  userID,
+			PayeeID: c.Param("payee_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	noContent(c)
+}
+
+func (h v2Hdl) paymentRequests(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.PaymentRequests(
+		service.PaymentRequestsRequest{
+			UserID:    userID,
+			Direction: c.Query("direction"),
+			Status:    domain.PaymentRequestStatus(c.Query("status")),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) requestPayment(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.RequestPaymentRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := h.svc.RequestPayment(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"payment-requests/"+res.PaymentRequestID, res)
+}
+
+func (h v2Hdl) paymentRequest(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.PaymentRequest(
+		service.PaymentRequestRequest{
+			UserID:           userID,
+			PaymentRequestID: c.Param("payment_request_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) acceptPaymentRequest(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.AcceptPaymentRequestRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.PaymentRequestID = c.Param("payment_request_id")
+
+	res, err := h.svc.AcceptPaymentRequest(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) declinePaymentRequest(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.DeclinePaymentRequest(
+		service.DeclinePaymentRequestRequest{
+			UserID:           userID,
+			PaymentRequestID: c.Param("payment_request_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) cancelPaymentRequest(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CancelPaymentRequest(
+		service.CancelPaymentRequestRequest{
+			UserID:           userID,
+			PaymentRequestID: c.Param("payment_request_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) createPendingTransfer(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.CreatePendingTransferRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.SenderUserID = userID
+	req.SenderAccountID = c.Param("account_id")
+
+	res, err := h.svc.CreatePendingTransfer(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"pending-transfers/"+res.PendingTransferID, res)
+}
+
+func (h v2Hdl) pendingTransfers(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.PendingTransfers(
+		service.PendingTransfersRequest{
+			UserID:    userID,
+			Direction: c.Query("direction"),
+			Status:    domain.PendingTransferStatus(c.Query("status")),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) pendingTransfer(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.PendingTransfer(
+		service.PendingTransferRequest{
+			UserID:            userID,
+			PendingTransferID: c.Param("pending_transfer_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) acceptPendingTransfer(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.AcceptPendingTransfer(
+		service.AcceptPendingTransferRequest{
+			UserID:            userID,
+			PendingTransferID: c.Param("pending_transfer_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) declinePendingTransfer(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.DeclinePendingTransfer(
+		service.DeclinePendingTransferRequest{
+			UserID:            userID,
+			PendingTransferID: c.Param("pending_transfer_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) cancelPendingTransfer(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CancelPendingTransfer(
+		service.CancelPendingTransferRequest{
+			UserID:            userID,
+			PendingTransferID: c.Param("pending_transfer_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) mandates(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Mandates(
+		service.MandatesRequest{
+			UserID: userID,
+			Role:   c.Query("role"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) grantMandate(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.GrantMandateRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := h.svc.GrantMandate(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"mandates/"+res.MandateID, res)
+}
+
+func (h v2Hdl) mandate(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Mandate(
+		service.MandateRequest{
+			UserID:    userID,
+			MandateID: c.Param("mandate_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) revokeMandate(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.RevokeMandate(
+		service.RevokeMandateRequest{
+			UserID:    userID,
+			MandateID: c.Param("mandate_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) collections(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Collections(
+		service.CollectionsRequest{
+			UserID:    userID,
+			MandateID: c.Param("mandate_id"),
+			Status:    domain.CollectionStatus(c.Query("status")),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) collectPayment(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.CollectPaymentRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.MandateID = c.Param("mandate_id")
+
+	res, err := h.svc.CollectPayment(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"mandates/"+res.MandateID+"/collections/"+res.CollectionID, res)
+}
+
+func (h v2Hdl) collection(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Collection(
+		service.CollectionRequest{
+			UserID:       userID,
+			MandateID:    c.Param("mandate_id"),
+			CollectionID: c.Param("collection_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) refundCollection(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.RefundCollection(
+		service.RefundCollectionRequest{
+			UserID:       userID,
+			MandateID:    c.Param("mandate_id"),
+			CollectionID: c.Param("collection_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) issueCard(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.IssueCardRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.AccountID = c.Param("account_id")
+
+	res, err := h.svc.IssueCard(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"cards/"+res.CardID, res)
+}
+
+func (h v2Hdl) cards(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Cards(
+		service.CardsRequest{
+			UserID:    userID,
+			AccountID: c.Query("account_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) card(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Card(
+		service.CardRequest{
+			UserID: userID,
+			CardID: c.Param("card_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) freezeCard(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.FreezeCard(
+		service.FreezeCardRequest{
+			UserID: userID,
+			CardID: c.Param("card_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) unfreezeCard(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.UnfreezeCard(
+		service.UnfreezeCardRequest{
+			UserID: userID,
+			CardID: c.Param("card_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) cancelCard(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CancelCard(
+		service.CancelCardRequest{
+			UserID: userID,
+			CardID: c.Param("card_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) updateCardLimits(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.UpdateCardLimitsRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.CardID = c.Param("card_id")
+
+	res, err := h.svc.UpdateCardLimits(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) cardAuthorisations(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CardAuthorisations(
+		service.CardAuthorisationsRequest{
+			UserID: userID,
+			CardID: c.Param("card_id"),
+			Status: domain.AuthorisationStatus(c.Query("status")),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) cardAuthorisation(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CardAuthorisation(
+		service.CardAuthorisationRequest{
+			UserID:          userID,
+			CardID:          c.Param("card_id"),
+			AuthorisationID: c.Param("authorisation_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// authoriseCard is called by merchants, who send no X-User-ID.
+func (h v2Hdl) authoriseCard(c *gin.Context) {
+	req := service.AuthoriseCardRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	merchant, key, ok := actingMerchant(c)
+
+	if !ok {
+		return
+	}
+
+	req.Merchant = merchant
+	req.MerchantKey = key
+
+	res, err := h.svc.AuthoriseCard(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"cards/"+res.CardID+"/authorisations/"+res.AuthorisationID, res)
+}
+
+func (h v2Hdl) captureAuthorisation(c *gin.Context) {
+	req := service.CaptureAuthorisationRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	merchant, key, ok := actingMerchant(c)
+
+	if !ok {
+		return
+	}
+
+	req.CardID = c.Param("card_id")
+	req.AuthorisationID = c.Param("authorisation_id")
+	req.Merchant = merchant
+	req.MerchantKey = key
+
+	res, err := h.svc.CaptureAuthorisation(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) reverseAuthorisation(c *gin.Context) {
+	merchant, key, ok := actingMerchant(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.ReverseAuthorisation(
+		service.ReverseAuthorisationRequest{
+			CardID:          c.Param("card_id"),
+			AuthorisationID: c.Param("authorisation_id"),
+			Merchant:        merchant,
+			MerchantKey:     key,
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func v2Operation(method string, path string, summary string, body any, responses ...openapi.Response) openapi.Operation {
+	op := operation(method, path, summary, body, responses...)
+
+	op.Tag = "v2"
+
+	return op
+}
+
+// actingOperation is a v2 operation taking the acting user from the
+// X-User-ID header.
+func actingOperation(method string, path string, summary string, body any, responses ...openapi.Response) openapi.Operation {
+	return withParams(
+		v2Operation(method, path, summary, body, responses...),
+		openapi.Param{
+			Name:        UserIDHeader,
+			In:          "header",
+			Required:    true,
+			Description: "User acting on the resource",
+		},
+	)
+}
+
+// merchantOperation is a v2 operation called by a merchant with its
+// credentials in the X-Merchant-ID and X-Merchant-Key headers.
+func merchantOperation(method string, path string, summary string, body any, responses ...openapi.Response) openapi.Operation {
+	return withParams(
+		v2Operation(method, path, summary, body, responses...),
+		openapi.Param{
+			Name:        MerchantIDHeader,
+			In:          "header",
+			Required:    true,
+			Description: "Merchant that took the authorisation",
+		},
+		openapi.Param{
+			Name:        MerchantKeyHeader,
+			In:          "header",
+			Required:    true,
+			Description: "Key issued to the merchant",
+		},
+	)
+}
+
+func createdResponse(description string, body any) openapi.Response {
+	res := response(http.StatusCreated, description, body)
+
+	res.Location = true
+
+	return res
+}
+
+// deprecated marks a v1 response as superseded by the v2 API.
+func deprecated(c *gin.Context) {
+	c.Header("Deprecation", "true")
+	c.Header("Link", "<"+v2URL+">; rel=\"successor-version\"")
+
+	c.Next()
+}
+
+func actingUser(c *gin.Context) (string, bool) {
+	userID := c.GetHeader(UserIDHeader)
+
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing " + UserIDHeader + " header"})
+
+		return "", false
+	}
+
+	return userID, true
+}
+
+func actingMerchant(c *gin.Context) (string, string, bool) {
+	merchant := c.GetHeader(MerchantIDHeader)
+	key := c.GetHeader(MerchantKeyHeader)
+
+	if merchant == "" || key == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing " + MerchantIDHeader + " or " + MerchantKeyHeader + " header"})
+
+		return "", "", false
+	}
+
+	return merchant, key, true
+}
+
+func created(c *gin.Context, location string, res any) {
+	c.Header("Location", location)
+	c.JSON(http.StatusCreated, res)
+}
+
+// noContent writes the status straight away so the idempotency middleware
+// sees a completed response.
+func noContent(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+func v2Error(c *gin.Context, err error) {
+	var limitErr service.VelocityLimitError
+
+	if errors.As(err, &limitErr) && !limitErr.ResetsAt.IsZero() {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(limitErr.ResetsAt).Seconds())+1))
+	}
+
+	var coolingOffErr service.PayeeCoolingOffError
+
+	if errors.As(err, &coolingOffErr) {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(coolingOffErr.Until).Seconds())+1))
+	}
+
+	c.JSON(statusCode(err), gin.H{"error": err.Error()})
+}
+
+// sentinelStatus maps the errors the service exports as values. They are
+// matched with errors.Is, so a joined compensation error still finds them.
+var sentinelStatus = []struct {
+	err    error
+	status int
+}{
+	{money.ErrCurrencyMismatch, http.StatusBadRequest},
+	{money.ErrUnsupportedCurrency, http.StatusBadRequest},
+	{money.ErrInvalidAmount, http.StatusBadRequest},
+	{money.ErrOverflow, http.StatusBadRequest},
+	{service.ErrAccountFrozen, http.StatusConflict},
+	{service.ErrAccountClosed, http.StatusConflict},
+	{service.ErrBalanceNotZero, http.StatusConflict},
+	{service.ErrPaymentRequestChanged, http.StatusConflict},
+	{service.ErrPendingTransferChanged, http.StatusConflict},
+	{service.ErrMandateChanged, http.StatusConflict},
+	{service.ErrCardChanged, http.StatusConflict},
+	{service.ErrLoanChanged, http.StatusConflict},
+	{service.ErrPotChanged, http.StatusConflict},
+	{service.ErrCategorisationChanged, http.StatusConflict},
+	{service.ErrAutoSaveChanged, http.StatusConflict},
+	{service.ErrInsufficientFunds, http.StatusUnprocessableEntity},
+}
+
+// statusCode maps service errors onto the status codes v2 promises: the
+// exported sentinels and typed errors first, then the remaining plain
+// messages. A joined error is mapped by the cause it starts with. Anything
+// unrecognised is a server error.
+func statusCode(err error) int {
+	for _, sentinel := range sentinelStatus {
+		if errors.Is(err, sentinel.err) {
+			return sentinel.status
+		}
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok && len(joined.Unwrap()) > 0 {
+		return statusCode(joined.Unwrap()[0])
+	}
+
+	if errors.As(err, &service.VelocityLimitError{}) {
+		return http.StatusTooManyRequests
+	}
+
+	if errors.As(err, &service.PayeeCoolingOffError{}) {
+		return http.StatusConflict
+	}
+
+	if errors.As(err, &service.PayeeNameError{}) {
+		return http.StatusUnprocessableEntity
+	}
+
+	message := err.Error()
+
+	switch {
+	case message == "invalid merchant credentials":
+		return http.StatusUnauthorized
+	case strings.HasPrefix(message, "invalid "),
+		strings.HasPrefix(message, "amount "),
+		message == "same account",
+		message == "incomplete address",
+		message == "user under minimum age":
+		return http.StatusBadRequest
+	case message == "unauthorized account id",
+		message == "unauthorized payment request id",
+		message == "unauthorized pending transfer id",
+		message == "unauthorized mandate id",
+		message == "insufficient role",
+		message == "approval required":
+		return http.StatusForbidden
+	case strings.HasSuffix(message, " not found"):
+		return http.StatusNotFound
+	case strings.HasSuffix(message, " closed"),
+		strings.HasPrefix(message, "duplicate "),
+		strings.Contains(message, " user cannot "),
+		strings.HasPrefix(message, "cannot move "),
+		message == "user deactivated",
+		message == "user not deactivated",
+		message == "users are closed through deactivation",
+		message == "missing kyc record",
+		message == "last owner",
+		message == "grace period expired",
+		message == "payout account is being closed",
+		message == "payment request not open",
+		message == "payment request already answered",
+		message == "mandate not active",
+		message == "mandate already collected this period",
+		message == "collection not refundable",
+		message == "refund period ended",
+		message == "card frozen",
+		message == "card not frozen",
+		message == "card locked",
+		message == "card cancelled",
+		message == "card expired",
+		message == "authorisation not held",
+		message == "authorisation expired",
+		message == "loan repaid",
+		message == "user has active loans":
+		return http.StatusConflict
+	case strings.HasPrefix(message, "insuficient funds"),
+		message == "minimum balance",
+		message == "card limit exceeded",
+		message == "card daily limit exceeded",
+		message == "operation not allowed by product",
+		message == "funds locked until maturity",
+		message == "monthly withdrawal limit reached",
+		message == "non-zero balance requires payout account":
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h v2Hdl) loans(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Loans(
+		service.LoansRequest{
+			UserID: userID,
+			Status: domain.LoanStatus(c.Query("status")),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) loan(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Loan(
+		service.LoanRequest{
+			UserID: userID,
+			LoanID: c.Param("loan_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) loanPayoffQuote(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.LoanPayoffQuote(
+		service.LoanPayoffQuoteRequest{
+			UserID: userID,
+			LoanID: c.Param("loan_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) payOffLoan(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.PayOffLoanRequest{}
+
+	err := bindOptionalJSON(c, &req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.LoanID = c.Param("loan_id")
+
+	res, err := h.svc.PayOffLoan(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) loanStatement(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.LoanStatement(
+		service.LoanStatementRequest{
+			UserID: userID,
+			LoanID: c.Param("loan_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) pots(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Pots(
+		service.PotsRequest{
+			UserID:    userID,
+			AccountID: c.Param("account_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) createPot(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.CreatePotRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.AccountID = c.Param("account_id")
+
+	res, err := h.svc.CreatePot(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"pots/"+res.PotID, res)
+}
+
+func (h v2Hdl) pot(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.Pot(
+		service.PotRequest{
+			UserID: userID,
+			PotID:  c.Param("pot_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) updatePot(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.UpdatePotRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.PotID = c.Param("pot_id")
+
+	res, err := h.svc.UpdatePot(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) closePot(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.ClosePot(
+		service.ClosePotRequest{
+			UserID: userID,
+			PotID:  c.Param("pot_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) moveToPot(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.MovePotMoneyRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.PotID = c.Param("pot_id")
+
+	res, err := h.svc.MoveToPot(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"transactions/"+res.TransactionID, res)
+}
+
+func (h v2Hdl) moveFromPot(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.MovePotMoneyRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.PotID = c.Param("pot_id")
+
+	res, err := h.svc.MoveFromPot(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	created(c, v2URL+"transactions/"+res.TransactionID, res)
+}
+
+func (h v2Hdl) putAutoSaveRules(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.PutAutoSaveRulesRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+	req.PotID = c.Param("pot_id")
+
+	res, err := h.svc.PutAutoSaveRules(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) categoriseTransaction(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.CategoriseTransactionRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if req.Category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category"})
+
+		return
+	}
+
+	req.UserID = userID
+	req.TransactionID = c.Param("transaction_id")
+
+	res, err := h.svc.CategoriseTransaction(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) uncategoriseTransaction(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CategoriseTransaction(
+		service.CategoriseTransactionRequest{
+			UserID:        userID,
+			TransactionID: c.Param("transaction_id"),
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) categoryRules(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	res, err := h.svc.CategoryRules(
+		service.CategoryRulesRequest{
+			UserID: userID,
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) putCategoryRules(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	req := service.PutCategoryRulesRequest{}
+
+	err := c.BindJSON(&req)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := h.svc.PutCategoryRules(req)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h v2Hdl) spendingAnalytics(c *gin.Context) {
+	userID, ok := actingUser(c)
+
+	if !ok {
+		return
+	}
+
+	top := 0
+
+	if value := c.Query("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid top"})
+
+			return
+		}
+
+		top = parsed
+	}
+
+	res, err := h.svc.SpendingAnalytics(
+		service.SpendingAnalyticsRequest{
+			UserID:    userID,
+			AccountID: c.Param("account_id"),
+			From:      c.Query("from"),
+			To:        c.Query("to"),
+			Top:       top,
+		},
+	)
+
+	if err != nil {
+		v2Error(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
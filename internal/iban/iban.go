@@ -0,0 +1,140 @@
+// Package iban builds and checks ISO 13616 international bank account
+// numbers.
+package iban
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hetfdex/tiny-bank/internal/checkdigit"
+)
+
+const (
+	minLength = 15
+	maxLength = 34
+
+	// accountNumberDigits is the width of the account number in the BBAN,
+	// after the bank code.
+	accountNumberDigits = 10
+)
+
+const MaxAccountNumber = 9999999999
+
+var (
+	ErrInvalid          = errors.New("invalid iban")
+	ErrInvalidCountry   = errors.New("invalid iban country")
+	ErrInvalidBankCode  = errors.New("invalid iban bank code")
+	ErrInvalidAccountNo = errors.New("invalid iban account number")
+)
+
+// Parse accepts an IBAN in electronic or print format, in any case, and
+// returns it in electronic format once its structure and check digits are
+// valid.
+func Parse(value string) (string, error) {
+	iban := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+
+	if len(iban) < minLength || len(iban) > maxLength {
+		return "", ErrInvalid
+	}
+
+	if !letters(iban[:2]) || !digits(iban[2:4]) {
+		return "", ErrInvalid
+	}
+
+	remainder, ok := checkdigit.Mod97(iban[4:] + iban[:4])
+
+	if !ok || remainder != 1 {
+		return "", ErrInvalid
+	}
+
+	return iban, nil
+}
+
+// Print groups an IBAN in blocks of four for display.
+func Print(iban string) string {
+	blocks := make([]string, 0, len(iban)/4+1)
+
+	for len(iban) > 4 {
+		blocks = append(blocks, iban[:4])
+
+		iban = iban[4:]
+	}
+
+	return strings.Join(append(blocks, iban), " ")
+}
+
+// Issuer builds the IBANs of one bank: the country code, check digits, the
+// bank code and a ten digit account number.
+type Issuer struct {
+	country  string
+	bankCode string
+}
+
+func NewIssuer(country string, bankCode string) (Issuer, error) {
+	if len(country) != 2 || !letters(country) {
+		return Issuer{}, ErrInvalidCountry
+	}
+
+	if bankCode == "" || len(country)+2+len(bankCode)+accountNumberDigits > maxLength || !alphanumeric(bankCode) {
+		return Issuer{}, ErrInvalidBankCode
+	}
+
+	return Issuer{
+		country:  country,
+		bankCode: bankCode,
+	}, nil
+}
+
+// MustNewIssuer is NewIssuer for fixed configuration; it panics on error.
+func MustNewIssuer(country string, bankCode string) Issuer {
+	issuer, err := NewIssuer(country, bankCode)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return issuer
+}
+
+func (i Issuer) IBAN(accountNumber uint64) (string, error) {
+	if accountNumber > MaxAccountNumber {
+		return "", ErrInvalidAccountNo
+	}
+
+	bban := fmt.Sprintf("%s%0*d", i.bankCode, accountNumberDigits, accountNumber)
+
+	remainder, _ := checkdigit.Mod97(bban + i.country + "00")
+
+	return fmt.Sprintf("%s%02d%s", i.country, 98-remainder, bban), nil
+}
+
+func letters(value string) bool {
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func digits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func alphanumeric(value string) bool {
+	for _, r := range value {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
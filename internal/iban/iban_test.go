@@ -0,0 +1,92 @@
+package iban
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Ok(t *testing.T) {
+	for value, expected := range map[string]string{
+		"GB82WEST12345698765432":      "GB82WEST12345698765432",
+		"gb82 west 1234 5698 7654 32": "GB82WEST12345698765432",
+		"NL91 ABNA 0417 1643 00":      "NL91ABNA0417164300",
+		"DE89 3704 0044 0532 0130 00": "DE89370400440532013000",
+	} {
+		res, err := Parse(value)
+
+		assert.Equal(t, expected, res, value)
+		assert.Nil(t, err, value)
+	}
+}
+
+func TestParse_Err(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"GB82WEST12345698765433",
+		"GB28WEST12345698765432",
+		"GB82WEST1234569876543-",
+		"1282WEST12345698765432",
+		"GBXXWEST12345698765432",
+		"GB82WEST",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	} {
+		res, err := Parse(value)
+
+		assert.Empty(t, res, value)
+		assert.Equal(t, ErrInvalid, err, value)
+	}
+}
+
+func TestIssuer_Ok(t *testing.T) {
+	issuer, err := NewIssuer("NL", "ABNA")
+
+	assert.Nil(t, err)
+
+	res, err := issuer.IBAN(417164300)
+
+	assert.Equal(t, "NL91ABNA0417164300", res)
+	assert.Nil(t, err)
+
+	for _, accountNumber := range []uint64{0, 1, 42, MaxAccountNumber} {
+		res, err := issuer.IBAN(accountNumber)
+
+		assert.Nil(t, err)
+
+		parsed, err := Parse(res)
+
+		assert.Equal(t, res, parsed)
+		assert.Nil(t, err)
+	}
+
+	_, err = issuer.IBAN(MaxAccountNumber + 1)
+
+	assert.Equal(t, ErrInvalidAccountNo, err)
+}
+
+func TestNewIssuer_Err(t *testing.T) {
+	_, err := NewIssuer("N1", "ABNA")
+
+	assert.Equal(t, ErrInvalidCountry, err)
+
+	_, err = NewIssuer("nl", "ABNA")
+
+	assert.Equal(t, ErrInvalidCountry, err)
+
+	_, err = NewIssuer("NL", "")
+
+	assert.Equal(t, ErrInvalidBankCode, err)
+
+	_, err = NewIssuer("NL", "abna")
+
+	assert.Equal(t, ErrInvalidBankCode, err)
+
+	_, err = NewIssuer("NL", "ABCDEFGHIJKLMNOPQRSTU")
+
+	assert.Equal(t, ErrInvalidBankCode, err)
+}
+
+func TestPrint(t *testing.T) {
+	assert.Equal(t, "GB82 WEST 1234 5698 7654 32", Print("GB82WEST12345698765432"))
+	assert.Equal(t, "NL91 ABNA 0417 1643 00", Print("NL91ABNA0417164300"))
+}
@@ -0,0 +1,69 @@
+package iso8583
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	sendTimeout = 10 * time.Second
+)
+
+// Client sends messages over one connection and waits for each response in
+// turn, as a simple acquirer or terminal would.
+type Client struct {
+	mux  sync.Mutex
+	conn net.Conn
+	spec Spec
+}
+
+func Dial(addr string, spec Spec) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn: conn,
+		spec: spec,
+	}, nil
+}
+
+func (c *Client) Send(msg Message) (Message, error) {
+	data, err := c.spec.Pack(msg)
+
+	if err != nil {
+		return Message{}, err
+	}
+
+	c.mux.Lock()
+
+	defer c.mux.Unlock()
+
+	err = c.conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	if err != nil {
+		return Message{}, err
+	}
+
+	err = WriteFrame(c.conn, data)
+
+	if err != nil {
+		return Message{}, err
+	}
+
+	data, err = ReadFrame(c.conn)
+
+	if err != nil {
+		return Message{}, err
+	}
+
+	return c.spec.Unpack(data)
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,49 @@
+package iso8583
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	headerLength   = 2
+	maxFrameLength = 1<<16 - 1
+)
+
+var ErrFrameTooLong = errors.New("iso8583 frame too long")
+
+// ReadFrame reads one message behind a two byte big-endian length header.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, headerLength)
+
+	_, err := io.ReadFull(r, header)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(header))
+
+	_, err = io.ReadFull(r, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > maxFrameLength {
+		return ErrFrameTooLong
+	}
+
+	frame := make([]byte, headerLength, headerLength+len(data))
+
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+
+	_, err := w.Write(append(frame, data...))
+
+	return err
+}
@@ -0,0 +1,134 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPack_Ok(t *testing.T) {
+	msg := NewMessage("0100")
+
+	msg.Fields[2] = "4999990000000001"
+	msg.Fields[4] = "000000001000"
+	msg.Fields[41] = "TERM1"
+	msg.Fields[48] = "123"
+
+	data, err := DefaultSpec().Pack(msg)
+
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		"0100"+string([]byte{0x50, 0x00, 0x00, 0x00, 0x00, 0x81, 0x00, 0x00})+
+			"164999990000000001"+"000000001000"+"TERM1   "+"003123",
+		string(data),
+	)
+
+	res, err := DefaultSpec().Unpack(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, msg, res)
+}
+
+func TestPack_SecondaryBitmap(t *testing.T) {
+	msg := NewMessage("0400")
+
+	msg.Fields[11] = "000042"
+	msg.Fields[90] = "0100" + strings.Repeat("0", 38)
+
+	data, err := DefaultSpec().Pack(msg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, byte(0x80), data[4]&0x80)
+	assert.Len(t, data, 4+16+6+42)
+
+	res, err := DefaultSpec().Unpack(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, msg, res)
+}
+
+func TestPack_Err(t *testing.T) {
+	for _, tc := range []struct {
+		msg Message
+		err error
+	}{
+		{Message{MTI: "01A0"}, ErrInvalidMTI},
+		{Message{MTI: "0100", Fields: map[int]string{5: "1"}}, ErrUnknownField},
+		{Message{MTI: "0100", Fields: map[int]string{4: "1.00"}}, ErrInvalidField},
+		{Message{MTI: "0100", Fields: map[int]string{39: "000"}}, ErrInvalidField},
+		{Message{MTI: "0100", Fields: map[int]string{2: strings.Repeat("4", 20)}}, ErrInvalidField},
+	} {
+		_, err := DefaultSpec().Pack(tc.msg)
+
+		assert.True(t, errors.Is(err, tc.err), tc.msg)
+	}
+}
+
+func TestUnpack_Err(t *testing.T) {
+	bitmap := string([]byte{0x40, 0, 0, 0, 0, 0, 0, 0})
+
+	for _, tc := range []struct {
+		data string
+		err  error
+	}{
+		{"0100", ErrInvalidMessage},
+		{"0100" + bitmap + "20499999", ErrInvalidField},
+		{"0100" + bitmap + "1649999900000000012", ErrInvalidMessage},
+		{"0100" + string([]byte{0x08, 0, 0, 0, 0, 0, 0, 0}), ErrUnknownField},
+	} {
+		_, err := DefaultSpec().Unpack([]byte(tc.data))
+
+		assert.True(t, errors.Is(err, tc.err), tc.data)
+	}
+}
+
+func TestResponseMTI(t *testing.T) {
+	res, ok := ResponseMTI("0200")
+
+	assert.Equal(t, "0210", res)
+	assert.True(t, ok)
+
+	_, ok = ResponseMTI("0110")
+
+	assert.False(t, ok)
+}
+
+func TestLoadSpec(t *testing.T) {
+	spec, err := LoadSpec(strings.NewReader(`{"fields": {"2": {"name": "pan", "type": "llvar", "length": 19, "charset": "n"}}}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Field{Name: "pan", Type: LLVar, Length: 19, Charset: Numeric}, spec.Fields[2])
+
+	for _, value := range []string{
+		`{}`,
+		`{"fields": {"1": {"type": "fixed", "length": 1, "charset": "n"}}}`,
+		`{"fields": {"2": {"type": "llvar", "length": 100, "charset": "n"}}}`,
+		`{"fields": {"2": {"type": "binary", "length": 8, "charset": "n"}}}`,
+		`{"fields": {"2": {"type": "fixed", "length": 8, "charset": "b"}}}`,
+		`{"fields": `,
+	} {
+		_, err = LoadSpec(strings.NewReader(value))
+
+		assert.True(t, errors.Is(err, ErrInvalidSpec), value)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteFrame(&buf, []byte("0800"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 4, '0', '8', '0', '0'}, buf.Bytes())
+
+	data, err := ReadFrame(&buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("0800"), data)
+
+	assert.Equal(t, ErrFrameTooLong, WriteFrame(&buf, make([]byte, 1<<16)))
+}
@@ -0,0 +1,236 @@
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	mtiLength    = 4
+	bitmapLength = 8
+)
+
+var (
+	ErrInvalidMTI     = errors.New("invalid iso8583 mti")
+	ErrUnknownField   = errors.New("unknown iso8583 field")
+	ErrInvalidField   = errors.New("invalid iso8583 field")
+	ErrInvalidMessage = errors.New("invalid iso8583 message")
+)
+
+// Message is an MTI, such as "0100", and its fields by number. Values are
+// the field contents without length prefix or trailing spaces; numeric
+// fields are left-padded with zeros when packed and keep them when
+// unpacked.
+type Message struct {
+	MTI    string
+	Fields map[int]string
+}
+
+func NewMessage(mti string) Message {
+	return Message{
+		MTI:    mti,
+		Fields: map[int]string{},
+	}
+}
+
+// ResponseMTI is the MTI answering a request MTI, e.g. 0110 for 0100. ok
+// is false when mti is not a request.
+func ResponseMTI(mti string) (string, bool) {
+	if len(mti) != mtiLength || !digits(mti) || (mti[2]-'0')%2 != 0 {
+		return "", false
+	}
+
+	return mti[:2] + string(mti[2]+1) + mti[3:], true
+}
+
+func (s Spec) Pack(msg Message) ([]byte, error) {
+	if len(msg.MTI) != mtiLength || !digits(msg.MTI) {
+		return nil, ErrInvalidMTI
+	}
+
+	numbers := make([]int, 0, len(msg.Fields))
+
+	for number := range msg.Fields {
+		numbers = append(numbers, number)
+	}
+
+	sort.Ints(numbers)
+
+	bitmap := make([]byte, bitmapLength)
+
+	if len(numbers) > 0 && numbers[len(numbers)-1] > 64 {
+		bitmap = make([]byte, 2*bitmapLength)
+
+		setBit(bitmap, 1)
+	}
+
+	var body strings.Builder
+
+	for _, number := range numbers {
+		field, exists := s.Fields[number]
+
+		if !exists {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownField, number)
+		}
+
+		value, err := packField(field, msg.Fields[number])
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %d %v", ErrInvalidField, number, err)
+		}
+
+		setBit(bitmap, number)
+
+		body.WriteString(value)
+	}
+
+	return append(append([]byte(msg.MTI), bitmap...), body.String()...), nil
+}
+
+func (s Spec) Unpack(data []byte) (Message, error) {
+	if len(data) < mtiLength+bitmapLength {
+		return Message{}, ErrInvalidMessage
+	}
+
+	msg := NewMessage(string(data[:mtiLength]))
+
+	if !digits(msg.MTI) {
+		return Message{}, ErrInvalidMTI
+	}
+
+	bitmap := data[mtiLength : mtiLength+bitmapLength]
+
+	if bitSet(bitmap, 1) {
+		if len(data) < mtiLength+2*bitmapLength {
+			return Message{}, ErrInvalidMessage
+		}
+
+		bitmap = data[mtiLength : mtiLength+2*bitmapLength]
+	}
+
+	rest := string(data[mtiLength+len(bitmap):])
+
+	for number := 2; number <= len(bitmap)*8; number++ {
+		if !bitSet(bitmap, number) {
+			continue
+		}
+
+		field, exists := s.Fields[number]
+
+		if !exists {
+			return Message{}, fmt.Errorf("%w: %d", ErrUnknownField, number)
+		}
+
+		value, n, err := unpackField(field, rest)
+
+		if err != nil {
+			return Message{}, fmt.Errorf("%w: %d %v", ErrInvalidField, number, err)
+		}
+
+		msg.Fields[number] = value
+
+		rest = rest[n:]
+	}
+
+	if rest != "" {
+		return Message{}, fmt.Errorf("%w: %d trailing bytes", ErrInvalidMessage, len(rest))
+	}
+
+	return msg, nil
+}
+
+// packField pads fixed fields, numeric ones with leading zeros and others
+// with trailing spaces, and prefixes variable ones with their length.
+func packField(field Field, value string) (string, error) {
+	if !validCharset(field.Charset, value) {
+		return "", errors.New("charset")
+	}
+
+	if len(value) > field.Length {
+		return "", errors.New("too long")
+	}
+
+	switch field.Type {
+	case Fixed:
+		if field.Charset == Numeric {
+			return strings.Repeat("0", field.Length-len(value)) + value, nil
+		}
+
+		return value + strings.Repeat(" ", field.Length-len(value)), nil
+	default:
+		return fmt.Sprintf("%0*d%s", prefixLength(field.Type), len(value), value), nil
+	}
+}
+
+// unpackField reads one field from the start of data and returns it with
+// the number of bytes it took. Padding spaces are trimmed.
+func unpackField(field Field, data string) (string, int, error) {
+	length := field.Length
+
+	prefix := prefixLength(field.Type)
+
+	if prefix > 0 {
+		if len(data) < prefix || !digits(data[:prefix]) {
+			return "", 0, errors.New("length prefix")
+		}
+
+		length, _ = strconv.Atoi(data[:prefix])
+
+		if length > field.Length {
+			return "", 0, errors.New("too long")
+		}
+	}
+
+	if len(data) < prefix+length {
+		return "", 0, errors.New("truncated")
+	}
+
+	value := data[prefix : prefix+length]
+
+	if !validCharset(field.Charset, value) {
+		return "", 0, errors.New("charset")
+	}
+
+	if field.Type == Fixed && field.Charset != Numeric {
+		value = strings.TrimRight(value, " ")
+	}
+
+	return value, prefix + length, nil
+}
+
+func validCharset(charset Charset, value string) bool {
+	for _, r := range value {
+		switch charset {
+		case Numeric:
+			if r < '0' || r > '9' {
+				return false
+			}
+		case Alphanumeric:
+			if !(r >= '0' && r <= '9') && !(r >= 'A' && r <= 'Z') && !(r >= 'a' && r <= 'z') && r != ' ' {
+				return false
+			}
+		default:
+			if r < ' ' || r > '~' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func digits(value string) bool {
+	return validCharset(Numeric, value)
+}
+
+// Bits are numbered from 1, the most significant bit of the first byte.
+func setBit(bitmap []byte, number int) {
+	bitmap[(number-1)/8] |= 0x80 >> ((number - 1) % 8)
+}
+
+func bitSet(bitmap []byte, number int) bool {
+	return bitmap[(number-1)/8]&(0x80>>((number-1)%8)) != 0
+}
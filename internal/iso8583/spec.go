@@ -0,0 +1,140 @@
+// Package iso8583 packs and unpacks ISO 8583 messages with an ASCII MTI, a
+// binary bitmap and fields laid out by a Spec, and frames them on a stream
+// behind a two byte length header.
+package iso8583
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type FieldType string
+
+const (
+	Fixed  FieldType = "fixed"
+	LLVar  FieldType = "llvar"
+	LLLVar FieldType = "lllvar"
+)
+
+// Charset is the ISO 8583 attribute of a field: n is digits only, an
+// letters, digits and spaces, ans any printable ASCII.
+type Charset string
+
+const (
+	Numeric             Charset = "n"
+	Alphanumeric        Charset = "an"
+	AlphanumericSpecial Charset = "ans"
+)
+
+var ErrInvalidSpec = errors.New("invalid iso8583 spec")
+
+// Field lays out one data element. Length is the exact length of a fixed
+// field and the most a variable one can hold.
+type Field struct {
+	Name    string    `json:"name"`
+	Type    FieldType `json:"type"`
+	Length  int       `json:"length"`
+	Charset Charset   `json:"charset"`
+}
+
+// Spec maps field numbers, 2 to 128, to their layout. Fields the spec does
+// not list can be neither packed nor unpacked.
+type Spec struct {
+	Fields map[int]Field `json:"fields"`
+}
+
+// LoadSpec reads a spec from JSON, for example
+// {"fields": {"2": {"name": "pan", "type": "llvar", "length": 19, "charset": "n"}}}.
+func LoadSpec(r io.Reader) (Spec, error) {
+	var spec Spec
+
+	err := json.NewDecoder(r).Decode(&spec)
+
+	if err != nil {
+		return Spec{}, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+
+	err = spec.Validate()
+
+	if err != nil {
+		return Spec{}, err
+	}
+
+	return spec, nil
+}
+
+func (s Spec) Validate() error {
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("%w: no fields", ErrInvalidSpec)
+	}
+
+	for number, field := range s.Fields {
+		if number < 2 || number > 128 {
+			return fmt.Errorf("%w: field %d out of range", ErrInvalidSpec, number)
+		}
+
+		if field.Charset != Numeric && field.Charset != Alphanumeric && field.Charset != AlphanumericSpecial {
+			return fmt.Errorf("%w: field %d charset %q", ErrInvalidSpec, number, field.Charset)
+		}
+
+		if field.Length < 1 || field.Length > maxLength(field.Type) {
+			return fmt.Errorf("%w: field %d type %q length %d", ErrInvalidSpec, number, field.Type, field.Length)
+		}
+	}
+
+	return nil
+}
+
+// DefaultSpec is the subset of ISO 8583:1987 used for card authorisations,
+// with the CVV carried in the private field 48.
+func DefaultSpec() Spec {
+	return Spec{
+		Fields: map[int]Field{
+			2:  {Name: "pan", Type: LLVar, Length: 19, Charset: Numeric},
+			3:  {Name: "processing_code", Type: Fixed, Length: 6, Charset: Numeric},
+			4:  {Name: "amount", Type: Fixed, Length: 12, Charset: Numeric},
+			7:  {Name: "transmission_date_time", Type: Fixed, Length: 10, Charset: Numeric},
+			11: {Name: "stan", Type: Fixed, Length: 6, Charset: Numeric},
+			12: {Name: "local_time", Type: Fixed, Length: 6, Charset: Numeric},
+			13: {Name: "local_date", Type: Fixed, Length: 4, Charset: Numeric},
+			14: {Name: "expiration_date", Type: Fixed, Length: 4, Charset: Numeric},
+			18: {Name: "merchant_type", Type: Fixed, Length: 4, Charset: Numeric},
+			22: {Name: "pos_entry_mode", Type: Fixed, Length: 3, Charset: Numeric},
+			37: {Name: "retrieval_reference_number", Type: Fixed, Length: 12, Charset: Alphanumeric},
+			38: {Name: "approval_code", Type: Fixed, Length: 6, Charset: Alphanumeric},
+			39: {Name: "response_code", Type: Fixed, Length: 2, Charset: Alphanumeric},
+			41: {Name: "terminal_id", Type: Fixed, Length: 8, Charset: AlphanumericSpecial},
+			42: {Name: "merchant_id", Type: Fixed, Length: 15, Charset: AlphanumericSpecial},
+			43: {Name: "merchant_name_location", Type: Fixed, Length: 40, Charset: AlphanumericSpecial},
+			48: {Name: "additional_data", Type: LLLVar, Length: 999, Charset: AlphanumericSpecial},
+			49: {Name: "currency_code", Type: Fixed, Length: 3, Charset: Numeric},
+			90: {Name: "original_data_elements", Type: Fixed, Length: 42, Charset: Numeric},
+		},
+	}
+}
+
+func maxLength(fieldType FieldType) int {
+	switch fieldType {
+	case Fixed:
+		return 999
+	case LLVar:
+		return 99
+	case LLLVar:
+		return 999
+	default:
+		return 0
+	}
+}
+
+func prefixLength(fieldType FieldType) int {
+	switch fieldType {
+	case LLVar:
+		return 2
+	case LLLVar:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,86 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+var errNotDecimalString = errors.New("amount must be a decimal string")
+
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency Currency        `json:"currency,omitempty"`
+}
+
+// MarshalJSON encodes money as {"amount":"12.34","currency":"EUR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, err := json.Marshal(m.Decimal())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(
+		jsonMoney{
+			Amount:   amount,
+			Currency: m.currency,
+		},
+	)
+}
+
+// UnmarshalJSON accepts the object form written by MarshalJSON or a bare
+// decimal string in the default currency. JSON numbers are rejected because
+// it is ambiguous whether they are major or minor units, and floats cannot
+// hold every amount exactly. An object without a currency is in the default
+// currency unless it is zero, which keeps the zero value round-tripping.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	raw := jsonMoney{
+		Amount:   data,
+		Currency: DefaultCurrency,
+	}
+
+	if bytes.HasPrefix(data, []byte("{")) {
+		raw = jsonMoney{}
+
+		err := json.Unmarshal(data, &raw)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	var value string
+
+	err := json.Unmarshal(raw.Amount, &value)
+
+	if err != nil {
+		return errNotDecimalString
+	}
+
+	currency := raw.Currency
+
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	parsed, err := Parse(value, currency)
+
+	if err != nil {
+		return err
+	}
+
+	if parsed.IsZero() && raw.Currency == "" {
+		parsed = Money{}
+	}
+
+	*m = parsed
+
+	return nil
+}
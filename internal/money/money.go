@@ -0,0 +1,275 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Currency string
+
+const (
+	EUR Currency = "EUR"
+	USD Currency = "USD"
+	GBP Currency = "GBP"
+	CHF Currency = "CHF"
+	JPY Currency = "JPY"
+
+	DefaultCurrency = EUR
+)
+
+// exponents is the number of minor unit digits of each supported currency.
+var exponents = map[Currency]int{
+	EUR: 2,
+	USD: 2,
+	GBP: 2,
+	CHF: 2,
+	JPY: 0,
+}
+
+var (
+	ErrOverflow            = errors.New("amount overflow")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidAmount       = errors.New("invalid amount")
+)
+
+func (c Currency) Valid() bool {
+	_, exists := exponents[c]
+
+	return exists
+}
+
+// Money is an amount in minor units of a currency. The zero value is a zero
+// amount without a currency, which combines with an amount of any currency.
+// Arithmetic is checked and fails instead of wrapping around.
+type Money struct {
+	minor    int64
+	currency Currency
+}
+
+func New(minor int64, currency Currency) Money {
+	return Money{
+		minor:    minor,
+		currency: currency,
+	}
+}
+
+// Parse reads a decimal string such as "12.34" or "-5" in the given currency.
+// More fraction digits than the currency has, exponents and anything else
+// that would need rounding are rejected.
+func Parse(value string, currency Currency) (Money, error) {
+	exponent, exists := exponents[currency]
+
+	if !exists {
+		return Money{}, ErrUnsupportedCurrency
+	}
+
+	negative := strings.HasPrefix(value, "-")
+
+	whole, fraction, hasFraction := strings.Cut(strings.TrimPrefix(value, "-"), ".")
+
+	if !digits(whole) || (hasFraction && !digits(fraction)) || len(fraction) > exponent {
+		return Money{}, ErrInvalidAmount
+	}
+
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+
+	if err != nil {
+		return Money{}, ErrOverflow
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return New(minor, currency), nil
+}
+
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.minor > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.common(other)
+
+	if err != nil {
+		return Money{}, err
+	}
+
+	if (other.minor > 0 && m.minor > math.MaxInt64-other.minor) ||
+		(other.minor < 0 && m.minor < math.MinInt64-other.minor) {
+		return Money{}, ErrOverflow
+	}
+
+	return New(m.minor+other.minor, currency), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	currency, err := m.common(other)
+
+	if err != nil {
+		return Money{}, err
+	}
+
+	if (other.minor < 0 && m.minor > math.MaxInt64+other.minor) ||
+		(other.minor > 0 && m.minor < math.MinInt64+other.minor) {
+		return Money{}, ErrOverflow
+	}
+
+	return New(m.minor-other.minor, currency), nil
+}
+
+// Cmp returns -1, 0 or 1 as m is less than, equal to or greater than other.
+func (m Money) Cmp(other Money) (int, error) {
+	_, err := m.common(other)
+
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.minor < other.minor:
+		return -1, nil
+	case m.minor > other.minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// MulBps returns m * bps / 10000, truncated towards zero.
+func (m Money) MulBps(bps int) (Money, error) {
+	if bps != 0 && (m.minor > math.MaxInt64/int64(bps) || m.minor < math.MinInt64/int64(bps)) {
+		return Money{}, ErrOverflow
+	}
+
+	return New(m.minor*int64(bps)/10000, m.currency), nil
+}
+
+// Split divides m into parts amounts that differ by at most one minor unit
+// and add up to m, the larger ones first.
+func (m Money) Split(parts int) []Money {
+	if parts <= 0 {
+		return nil
+	}
+
+	share := m.minor / int64(parts)
+	remainder := m.minor % int64(parts)
+
+	res := make([]Money, parts)
+
+	for i := range res {
+		minor := share
+
+		switch {
+		case int64(i) < remainder:
+			minor++
+		case int64(i) < -remainder:
+			minor--
+		}
+
+		res[i] = New(minor, m.currency)
+	}
+
+	return res
+}
+
+// RoundUp returns what m lacks to reach the next whole major unit, away
+// from zero, or zero if m is already whole or the currency has no minor
+// units.
+func (m Money) RoundUp() Money {
+	unit := int64(1)
+
+	for range exponents[m.currency] {
+		unit *= 10
+	}
+
+	remainder := m.minor % unit
+
+	switch {
+	case remainder > 0:
+		return New(unit-remainder, m.currency)
+	case remainder < 0:
+		return New(-unit-remainder, m.currency)
+	default:
+		return New(0, m.currency)
+	}
+}
+
+// Decimal formats the amount with the currency's minor unit digits.
+func (m Money) Decimal() string {
+	exponent := exponents[m.currency]
+
+	sign := ""
+	minor := strconv.FormatUint(uint64(m.minor), 10)
+
+	if m.minor < 0 {
+		sign = "-"
+		minor = strconv.FormatUint(uint64(-(m.minor+1))+1, 10)
+	}
+
+	if exponent == 0 {
+		return sign + minor
+	}
+
+	if len(minor) <= exponent {
+		minor = strings.Repeat("0", exponent-len(minor)+1) + minor
+	}
+
+	return sign + minor[:len(minor)-exponent] + "." + minor[len(minor)-exponent:]
+}
+
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Decimal()
+	}
+
+	return m.Decimal() + " " + string(m.currency)
+}
+
+func (m Money) common(other Money) (Currency, error) {
+	switch {
+	case m.currency == other.currency:
+		return m.currency, nil
+	case m.currency == "" && m.minor == 0:
+		return other.currency, nil
+	case other.currency == "" && other.minor == 0:
+		return m.currency, nil
+	default:
+		return "", ErrCurrencyMismatch
+	}
+}
+
+func digits(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
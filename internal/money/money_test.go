@@ -0,0 +1,169 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Ok(t *testing.T) {
+	res, err := Parse("12.3", EUR)
+
+	assert.Equal(t, New(1230, EUR), res)
+	assert.Nil(t, err)
+
+	res, err = Parse("-0.05", EUR)
+
+	assert.Equal(t, New(-5, EUR), res)
+	assert.Nil(t, err)
+
+	res, err = Parse("500", JPY)
+
+	assert.Equal(t, New(500, JPY), res)
+	assert.Nil(t, err)
+}
+
+func TestParse_Err(t *testing.T) {
+	for _, value := range []string{"", "1.234", "1e3", ".5", "5.", "1,00", "+1", "--1"} {
+		res, err := Parse(value, EUR)
+
+		assert.Equal(t, Money{}, res, value)
+		assert.Equal(t, ErrInvalidAmount, err, value)
+	}
+
+	_, err := Parse("1.5", JPY)
+
+	assert.Equal(t, ErrInvalidAmount, err)
+
+	_, err = Parse("92233720368547758.08", EUR)
+
+	assert.Equal(t, ErrOverflow, err)
+
+	_, err = Parse("1", "XXX")
+
+	assert.Equal(t, ErrUnsupportedCurrency, err)
+}
+
+func TestAdd_ErrOverflow(t *testing.T) {
+	res, err := New(math.MaxInt64, EUR).Add(New(1, EUR))
+
+	assert.Equal(t, Money{}, res)
+	assert.Equal(t, ErrOverflow, err)
+
+	res, err = New(math.MinInt64, EUR).Add(New(-1, EUR))
+
+	assert.Equal(t, Money{}, res)
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSub_ErrOverflow(t *testing.T) {
+	res, err := New(math.MinInt64, EUR).Sub(New(1, EUR))
+
+	assert.Equal(t, Money{}, res)
+	assert.Equal(t, ErrOverflow, err)
+
+	res, err = New(0, EUR).Sub(New(math.MinInt64, EUR))
+
+	assert.Equal(t, Money{}, res)
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestAdd_ErrCurrencyMismatch(t *testing.T) {
+	res, err := New(1, EUR).Add(New(1, USD))
+
+	assert.Equal(t, Money{}, res)
+	assert.Equal(t, ErrCurrencyMismatch, err)
+}
+
+func TestAdd_ZeroValue(t *testing.T) {
+	res, err := Money{}.Add(New(5, USD))
+
+	assert.Equal(t, New(5, USD), res)
+	assert.Nil(t, err)
+}
+
+func TestCmp_Ok(t *testing.T) {
+	res, err := New(1, EUR).Cmp(New(2, EUR))
+
+	assert.Equal(t, -1, res)
+	assert.Nil(t, err)
+
+	res, err = New(2, EUR).Cmp(Money{})
+
+	assert.Equal(t, 1, res)
+	assert.Nil(t, err)
+}
+
+func TestMulBps(t *testing.T) {
+	res, err := New(1005, EUR).MulBps(1000)
+
+	assert.Equal(t, New(100, EUR), res)
+	assert.Nil(t, err)
+
+	_, err = New(math.MaxInt64, EUR).MulBps(2)
+
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []Money{New(334, EUR), New(333, EUR), New(333, EUR)}, New(1000, EUR).Split(3))
+	assert.Equal(t, []Money{New(-334, EUR), New(-333, EUR), New(-333, EUR)}, New(-1000, EUR).Split(3))
+	assert.Equal(t, []Money{New(5, JPY), New(5, JPY)}, New(10, JPY).Split(2))
+	assert.Nil(t, New(10, EUR).Split(0))
+}
+
+func TestRoundUp(t *testing.T) {
+	assert.Equal(t, New(70, EUR), New(1230, EUR).RoundUp())
+	assert.Equal(t, New(0, EUR), New(1200, EUR).RoundUp())
+	assert.Equal(t, New(-70, EUR), New(-1230, EUR).RoundUp())
+	assert.Equal(t, New(0, JPY), New(123, JPY).RoundUp())
+}
+
+func TestDecimal(t *testing.T) {
+	assert.Equal(t, "0.05", New(5, EUR).Decimal())
+	assert.Equal(t, "-12.34", New(-1234, EUR).Decimal())
+	assert.Equal(t, "-92233720368547758.08", New(math.MinInt64, EUR).Decimal())
+	assert.Equal(t, "7", New(7, JPY).Decimal())
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	for _, value := range []Money{{}, New(1234, EUR), New(-1, USD), New(9, JPY)} {
+		raw, err := json.Marshal(value)
+
+		assert.Nil(t, err)
+
+		var res Money
+
+		err = json.Unmarshal(raw, &res)
+
+		assert.Equal(t, value, res, string(raw))
+		assert.Nil(t, err)
+	}
+
+	raw, err := json.Marshal(New(1234, EUR))
+
+	assert.Equal(t, `{"amount":"12.34","currency":"EUR"}`, string(raw))
+	assert.Nil(t, err)
+}
+
+func TestUnmarshalJSON_DecimalString(t *testing.T) {
+	var res Money
+
+	err := json.Unmarshal([]byte(`"12.34"`), &res)
+
+	assert.Equal(t, New(1234, DefaultCurrency), res)
+	assert.Nil(t, err)
+}
+
+func TestUnmarshalJSON_ErrNumber(t *testing.T) {
+	for _, raw := range []string{`12.34`, `12`, `{"amount":12.34,"currency":"EUR"}`} {
+		var res Money
+
+		err := json.Unmarshal([]byte(raw), &res)
+
+		assert.Equal(t, Money{}, res, raw)
+		assert.Equal(t, errNotDecimalString, err, raw)
+	}
+}
@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI schema object the generator emits and
+// the validator understands.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func ref(name string) *Schema {
+	return &Schema{
+		Ref: "#/components/schemas/" + name,
+	}
+}
+
+// schemaOf returns the schema of a type, registering named structs and
+// overridden types as components and referencing them.
+func (s *Spec) schemaOf(t reflect.Type) *Schema {
+	if override, exists := s.overrides[t]; exists {
+		name := s.componentName(t)
+
+		if _, registered := s.components[name]; !registered {
+			schema := override
+
+			s.components[name] = &schema
+		}
+
+		return ref(name)
+	}
+
+	if enum, exists := s.enums[t]; exists {
+		return &Schema{
+			Type: "string",
+			Enum: enum,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaOf(t.Elem())
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{
+				Type:   "string",
+				Format: "date-time",
+			}
+		}
+
+		if t.Name() == "" {
+			return s.objectSchema(t, nil)
+		}
+
+		name := s.componentName(t)
+
+		if _, registered := s.components[name]; !registered {
+			// Registered before the fields so recursive types terminate.
+			s.components[name] = &Schema{}
+
+			*s.components[name] = *s.objectSchema(t, nil)
+		}
+
+		return ref(name)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{
+				Type:   "string",
+				Format: "byte",
+			}
+		}
+
+		return &Schema{
+			Type:  "array",
+			Items: s.schemaOf(t.Elem()),
+		}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: s.schemaOf(t.Elem()),
+		}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// bodySchema inlines the top level of a request body so fields the handler
+// fills in can be left out; nested types are still referenced.
+func (s *Spec) bodySchema(t reflect.Type, omit []string) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return s.schemaOf(t)
+	}
+
+	if _, exists := s.overrides[t]; exists {
+		return s.schemaOf(t)
+	}
+
+	return s.objectSchema(t, omit)
+}
+
+func (s *Spec) objectSchema(t reflect.Type, omit []string) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	s.addFields(schema, t, omit)
+
+	return schema
+}
+
+// addFields follows encoding/json: the json tag names the property,
+// untagged embedded structs are flattened and unexported fields are skipped.
+// A field tagged openapi:"required" must be present.
+func (s *Spec) addFields(schema *Schema, t reflect.Type, omit []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(schema, embedded, omit)
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if slices.Contains(omit, name) {
+			continue
+		}
+
+		schema.Properties[name] = s.schemaOf(field.Type)
+
+		if field.Tag.Get("openapi") == "required" {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// componentName is the type name, qualified with its package when another
+// package already uses the name.
+func (s *Spec) componentName(t reflect.Type) string {
+	if name, exists := s.names[t]; exists {
+		return name
+	}
+
+	name := t.Name()
+
+	for other, taken := range s.names {
+		if taken == name && other != t {
+			pkg := t.PkgPath()
+
+			pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+
+			break
+		}
+	}
+
+	s.names[t] = name
+
+	return name
+}
@@ -0,0 +1,428 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	version = "3.0.3"
+
+	jsonMediaType = "application/json"
+)
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Operation describes one route. Path uses the router's syntax, so
+// /users/:user_id is published as /users/{user_id}.
+type Operation struct {
+	Method     string
+	Path       string
+	Summary    string
+	Tag        string
+	Deprecated bool
+	Params     []Param
+	// Body is a value of the JSON request body type, or nil when the route
+	// takes none. Fields listed in Omit, and fields named like a path
+	// parameter, are filled in by the handler and left out of the schema.
+	Body         any
+	BodyOptional bool
+	Omit         []string
+	// RawBody lists the media types of a non-JSON upload. Raw bodies are
+	// documented but not validated.
+	RawBody   []string
+	Responses []Response
+}
+
+// StyleDeepObject publishes a query parameter as a string map sent as
+// name[key]=value.
+const StyleDeepObject = "deepObject"
+
+// Param is a query or header parameter. Path parameters are taken from the
+// operation path.
+type Param struct {
+	Name        string
+	In          string
+	Required    bool
+	Description string
+	Enum        []string
+	Style       string
+}
+
+type Response struct {
+	Status      int
+	Description string
+	Body        any
+	// MediaType defaults to application/json.
+	MediaType string
+	Location  bool
+}
+
+type Option func(*Spec)
+
+// WithSchema publishes values of the given type with a hand written schema,
+// for types whose JSON form is not their Go structure.
+func WithSchema(value any, schema Schema) Option {
+	return func(s *Spec) {
+		s.overrides[reflect.TypeOf(value)] = schema
+	}
+}
+
+// WithEnum lists the allowed values of a named string type.
+func WithEnum[T ~string](values ...T) Option {
+	return func(s *Spec) {
+		var zero T
+
+		enum := make([]string, 0, len(values))
+
+		for _, value := range values {
+			enum = append(enum, string(value))
+		}
+
+		s.enums[reflect.TypeOf(zero)] = enum
+	}
+}
+
+// Spec builds an OpenAPI document from operations and the Go types they
+// bind and return, and validates request bodies against it.
+type Spec struct {
+	mux        sync.Mutex
+	info       Info
+	overrides  map[reflect.Type]Schema
+	enums      map[reflect.Type][]string
+	names      map[reflect.Type]string
+	components map[string]*Schema
+	operations map[string]Operation
+	bodies     map[string]*Schema
+}
+
+func New(info Info, opts ...Option) *Spec {
+	s := &Spec{
+		info:       info,
+		overrides:  map[reflect.Type]Schema{},
+		enums:      map[reflect.Type][]string{},
+		names:      map[reflect.Type]string{},
+		components: map[string]*Schema{},
+		operations: map[string]Operation{},
+		bodies:     map[string]*Schema{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.components["Error"] = &Schema{
+		Type:     "object",
+		Required: []string{"error"},
+		Properties: map[string]*Schema{
+			"error": {Type: "string"},
+		},
+	}
+
+	s.components["ValidationError"] = &Schema{
+		Type:     "object",
+		Required: []string{"error", "fields"},
+		Properties: map[string]*Schema{
+			"error": {Type: "string"},
+			"fields": {
+				Type:                 "object",
+				Description:          "Problem with each offending field, keyed by its path in the body, e.g. address.country or transfers[0].amount.",
+				AdditionalProperties: &Schema{Type: "string"},
+			},
+		},
+	}
+
+	return s
+}
+
+func (s *Spec) Add(ops ...Operation) {
+	s.mux.Lock()
+
+	defer s.mux.Unlock()
+
+	for _, op := range ops {
+		key := operationKey(op.Method, op.Path)
+
+		s.operations[key] = op
+
+		if op.Body == nil {
+			continue
+		}
+
+		omit := append(pathParams(op.Path), op.Omit...)
+
+		s.bodies[key] = s.bodySchema(reflect.TypeOf(op.Body), omit)
+	}
+}
+
+// Operations returns the registered operations ordered by path and method.
+func (s *Spec) Operations() []Operation {
+	s.mux.Lock()
+
+	defer s.mux.Unlock()
+
+	keys := make([]string, 0, len(s.operations))
+
+	for key := range s.operations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	ops := make([]Operation, 0, len(keys))
+
+	for _, key := range keys {
+		ops = append(ops, s.operations[key])
+	}
+
+	return ops
+}
+
+type Document struct {
+	OpenAPI    string                             `json:"openapi"`
+	Info       Info                               `json:"info"`
+	Paths      map[string]map[string]operationDoc `json:"paths"`
+	Components componentsDoc                      `json:"components"`
+}
+
+type componentsDoc struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type operationDoc struct {
+	Summary     string                 `json:"summary,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Deprecated  bool                   `json:"deprecated,omitempty"`
+	Parameters  []parameterDoc         `json:"parameters,omitempty"`
+	RequestBody *requestBodyDoc        `json:"requestBody,omitempty"`
+	Responses   map[string]responseDoc `json:"responses"`
+}
+
+type parameterDoc struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Style       string  `json:"style,omitempty"`
+	Explode     bool    `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBodyDoc struct {
+	Required bool                    `json:"required"`
+	Content  map[string]mediaTypeDoc `json:"content"`
+}
+
+type mediaTypeDoc struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type responseDoc struct {
+	Description string                  `json:"description"`
+	Headers     map[string]headerDoc    `json:"headers,omitempty"`
+	Content     map[string]mediaTypeDoc `json:"content,omitempty"`
+}
+
+type headerDoc struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Document renders the spec. Every operation gets a default error response,
+// and those with a JSON body a 400 listing field errors.
+func (s *Spec) Document() Document {
+	ops := s.Operations()
+
+	s.mux.Lock()
+
+	defer s.mux.Unlock()
+
+	paths := map[string]map[string]operationDoc{}
+
+	for _, op := range ops {
+		path := documentPath(op.Path)
+
+		if paths[path] == nil {
+			paths[path] = map[string]operationDoc{}
+		}
+
+		paths[path][strings.ToLower(op.Method)] = s.operationDoc(op)
+	}
+
+	components := make(map[string]*Schema, len(s.components))
+
+	for name, schema := range s.components {
+		components[name] = schema
+	}
+
+	return Document{
+		OpenAPI: version,
+		Info:    s.info,
+		Paths:   paths,
+		Components: componentsDoc{
+			Schemas: components,
+		},
+	}
+}
+
+func (s *Spec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Document())
+}
+
+func (s *Spec) operationDoc(op Operation) operationDoc {
+	doc := operationDoc{
+		Summary:    op.Summary,
+		Deprecated: op.Deprecated,
+		Responses:  map[string]responseDoc{},
+	}
+
+	if op.Tag != "" {
+		doc.Tags = []string{op.Tag}
+	}
+
+	for _, name := range pathParams(op.Path) {
+		doc.Parameters = append(
+			doc.Parameters,
+			parameterDoc{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			},
+		)
+	}
+
+	for _, param := range op.Params {
+		paramDoc := parameterDoc{
+			Name:        param.Name,
+			In:          param.In,
+			Required:    param.Required,
+			Description: param.Description,
+			Schema: &Schema{
+				Type: "string",
+				Enum: param.Enum,
+			},
+		}
+
+		if param.Style == StyleDeepObject {
+			paramDoc.Style = param.Style
+			paramDoc.Explode = true
+			paramDoc.Schema = &Schema{
+				Type:                 "object",
+				AdditionalProperties: &Schema{Type: "string"},
+			}
+		}
+
+		doc.Parameters = append(doc.Parameters, paramDoc)
+	}
+
+	key := operationKey(op.Method, op.Path)
+
+	if body, exists := s.bodies[key]; exists {
+		doc.RequestBody = &requestBodyDoc{
+			Required: !op.BodyOptional,
+			Content: map[string]mediaTypeDoc{
+				jsonMediaType: {Schema: body},
+			},
+		}
+
+		doc.Responses[strconv.Itoa(http.StatusBadRequest)] = responseDoc{
+			Description: "Malformed body",
+			Content: map[string]mediaTypeDoc{
+				jsonMediaType: {Schema: ref("ValidationError")},
+			},
+		}
+	}
+
+	if len(op.RawBody) > 0 {
+		doc.RequestBody = &requestBodyDoc{
+			Required: true,
+			Content:  map[string]mediaTypeDoc{},
+		}
+
+		for _, mediaType := range op.RawBody {
+			doc.RequestBody.Content[mediaType] = mediaTypeDoc{
+				Schema: &Schema{Type: "string"},
+			}
+		}
+	}
+
+	for _, res := range op.Responses {
+		resDoc := responseDoc{
+			Description: res.Description,
+		}
+
+		if res.Location {
+			resDoc.Headers = map[string]headerDoc{
+				"Location": {
+					Description: "URL of the created resource",
+					Schema:      &Schema{Type: "string"},
+				},
+			}
+		}
+
+		mediaType := res.MediaType
+
+		if mediaType == "" {
+			mediaType = jsonMediaType
+		}
+
+		if res.Body != nil {
+			resDoc.Content = map[string]mediaTypeDoc{
+				mediaType: {Schema: s.schemaOf(reflect.TypeOf(res.Body))},
+			}
+		} else if res.MediaType != "" {
+			resDoc.Content = map[string]mediaTypeDoc{
+				mediaType: {},
+			}
+		}
+
+		doc.Responses[strconv.Itoa(res.Status)] = resDoc
+	}
+
+	doc.Responses["default"] = responseDoc{
+		Description: "Error",
+		Content: map[string]mediaTypeDoc{
+			jsonMediaType: {Schema: ref("Error")},
+		},
+	}
+
+	return doc
+}
+
+func operationKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+func pathParams(path string) []string {
+	params := []string{}
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+
+	return params
+}
+
+func documentPath(path string) string {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
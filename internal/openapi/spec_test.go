@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type color string
+
+type amount struct {
+	Value string
+}
+
+type address struct {
+	Country string `json:"country" openapi:"required"`
+}
+
+type base struct {
+	UserID string `json:"user_id"`
+}
+
+type payment struct {
+	base
+	AccountID string            `json:"account_id"`
+	Amount    amount            `json:"amount" openapi:"required"`
+	Color     color             `json:"color,omitempty"`
+	Address   *address          `json:"address,omitempty"`
+	Lines     []address         `json:"lines,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Count     int               `json:"count,omitempty"`
+	At        time.Time         `json:"at,omitempty"`
+	Internal  string            `json:"-"`
+	hidden    string
+}
+
+func testSpec() *Spec {
+	spec := New(
+		Info{
+			Title:   "test",
+			Version: "1",
+		},
+		WithSchema(amount{}, Schema{Type: "string", Pattern: `^[0-9]+$`}),
+		WithEnum[color]("red", "blue"),
+	)
+
+	spec.Add(
+		Operation{
+			Method: http.MethodPost,
+			Path:   "/accounts/:account_id/payments/",
+			Body:   payment{},
+			Omit:   []string{"user_id"},
+			Responses: []Response{
+				{Status: http.StatusCreated, Description: "created", Body: payment{}, Location: true},
+			},
+		},
+		Operation{
+			Method: http.MethodGet,
+			Path:   "/accounts/:account_id",
+		},
+	)
+
+	return spec
+}
+
+func TestDocument_Ok(t *testing.T) {
+	doc := testSpec().Document()
+
+	assert.Equal(t, version, doc.OpenAPI)
+
+	op := doc.Paths["/accounts/{account_id}/payments"]["post"]
+
+	assert.Equal(t, "account_id", op.Parameters[0].Name)
+	assert.Equal(t, "path", op.Parameters[0].In)
+	assert.True(t, op.RequestBody.Required)
+
+	body := op.RequestBody.Content[jsonMediaType].Schema
+
+	assert.NotContains(t, body.Properties, "account_id")
+	assert.NotContains(t, body.Properties, "user_id")
+	assert.NotContains(t, body.Properties, "Internal")
+	assert.NotContains(t, body.Properties, "hidden")
+	assert.Equal(t, []string{"amount"}, body.Required)
+	assert.Equal(t, "#/components/schemas/amount", body.Properties["amount"].Ref)
+	assert.Equal(t, []string{"red", "blue"}, body.Properties["color"].Enum)
+	assert.Equal(t, "#/components/schemas/address", body.Properties["address"].Ref)
+	assert.Equal(t, "array", body.Properties["lines"].Type)
+	assert.Equal(t, "string", body.Properties["tags"].AdditionalProperties.Type)
+	assert.Equal(t, "integer", body.Properties["count"].Type)
+	assert.Equal(t, "date-time", body.Properties["at"].Format)
+
+	assert.Contains(t, op.Responses, "201")
+	assert.Contains(t, op.Responses["201"].Headers, "Location")
+	assert.Contains(t, op.Responses, "400")
+	assert.Contains(t, op.Responses, "default")
+
+	response := doc.Components.Schemas["payment"]
+
+	assert.Contains(t, response.Properties, "user_id")
+	assert.Contains(t, response.Properties, "account_id")
+
+	assert.Nil(t, doc.Paths["/accounts/{account_id}"]["get"].RequestBody)
+	assert.NotContains(t, doc.Paths["/accounts/{account_id}"]["get"].Responses, "400")
+
+	_, err := json.Marshal(testSpec())
+
+	assert.Nil(t, err)
+}
+
+func TestValidate_Ok(t *testing.T) {
+	spec := testSpec()
+
+	fields, err := spec.Validate(
+		http.MethodPost,
+		"/accounts/:account_id/payments/",
+		[]byte(`{"amount":"10","color":"red","address":{"country":"PT"},"lines":[{"country":"ES"}],"count":2,"at":"2024-01-02T03:04:05Z","unknown":true}`),
+	)
+
+	assert.Nil(t, fields)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(http.MethodGet, "/accounts/:account_id", nil)
+
+	assert.Nil(t, fields)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(http.MethodGet, "/unknown", []byte(`{`))
+
+	assert.Nil(t, fields)
+	assert.Nil(t, err)
+}
+
+func TestValidate_ErrFields(t *testing.T) {
+	spec := testSpec()
+
+	fields, err := spec.Validate(
+		http.MethodPost,
+		"/accounts/:account_id/payments/",
+		[]byte(`{"color":"green","address":{},"lines":[{"country":1}],"count":1.5,"at":"yesterday","tags":{"a":true}}`),
+	)
+
+	assert.Equal(
+		t,
+		FieldErrors{
+			"amount":           "is required",
+			"color":            "must be one of red, blue",
+			"address.country":  "is required",
+			"lines[0].country": "must be a string",
+			"count":            "must be an integer",
+			"at":               "must be an RFC 3339 date-time",
+			"tags.a":           "must be a string",
+		},
+		fields,
+	)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(
+		http.MethodPost,
+		"/accounts/:account_id/payments/",
+		[]byte(`{"amount":"1.5"}`),
+	)
+
+	assert.Equal(t, FieldErrors{"amount": "must match ^[0-9]+$"}, fields)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(http.MethodPost, "/accounts/:account_id/payments/", []byte(`[]`))
+
+	assert.Equal(t, FieldErrors{"body": "must be an object"}, fields)
+	assert.Nil(t, err)
+}
+
+func TestValidate_ErrBody(t *testing.T) {
+	spec := testSpec()
+
+	fields, err := spec.Validate(http.MethodPost, "/accounts/:account_id/payments/", nil)
+
+	assert.Equal(t, FieldErrors{"body": "is required"}, fields)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(http.MethodPost, "/accounts/:account_id/payments/", []byte(`{`))
+
+	assert.Nil(t, fields)
+	assert.Equal(t, errInvalidJSON, err)
+}
+
+func TestValidate_OneOf(t *testing.T) {
+	spec := New(
+		Info{},
+		WithSchema(
+			amount{},
+			Schema{
+				OneOf: []*Schema{
+					{Type: "string"},
+					{
+						Type:     "object",
+						Required: []string{"amount"},
+						Properties: map[string]*Schema{
+							"amount": {Type: "string"},
+						},
+					},
+				},
+			},
+		),
+	)
+
+	spec.Add(
+		Operation{
+			Method: http.MethodPost,
+			Path:   "/payments",
+			Body:   payment{},
+		},
+	)
+
+	fields, err := spec.Validate(http.MethodPost, "/payments", []byte(`{"amount":{"amount":"1"}}`))
+
+	assert.Nil(t, fields)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(http.MethodPost, "/payments", []byte(`{"amount":{}}`))
+
+	assert.Equal(t, FieldErrors{"amount.amount": "is required"}, fields)
+	assert.Nil(t, err)
+
+	fields, err = spec.Validate(http.MethodPost, "/payments", []byte(`{"amount":12}`))
+
+	assert.Equal(t, FieldErrors{"amount": "must be string or object"}, fields)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,242 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FieldErrors maps the path of each offending field, e.g. address.country or
+// transfers[0].amount, to what is wrong with it.
+type FieldErrors map[string]string
+
+const bodyField = "body"
+
+var errInvalidJSON = errors.New("invalid json body")
+
+// Validate checks a request body against the operation's schema. It returns
+// an error when the body is not JSON and field errors when it does not fit
+// the schema. Routes without a JSON body always pass.
+func (s *Spec) Validate(method string, path string, body []byte) (FieldErrors, error) {
+	key := operationKey(method, path)
+
+	s.mux.Lock()
+
+	op, known := s.operations[key]
+	schema, hasBody := s.bodies[key]
+
+	s.mux.Unlock()
+
+	if !known || !hasBody {
+		return nil, nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.BodyOptional {
+			return nil, nil
+		}
+
+		return FieldErrors{bodyField: "is required"}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+
+	decoder.UseNumber()
+
+	var value any
+
+	err := decoder.Decode(&value)
+
+	if err != nil {
+		return nil, errInvalidJSON
+	}
+
+	errs := FieldErrors{}
+
+	s.mux.Lock()
+
+	s.validate(value, schema, "", errs)
+
+	s.mux.Unlock()
+
+	if len(errs) == 0 {
+		return nil, nil
+	}
+
+	return errs, nil
+}
+
+func (s *Spec) validate(value any, schema *Schema, path string, errs FieldErrors) {
+	if schema.Ref != "" {
+		resolved, exists := s.components[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+
+		if !exists {
+			return
+		}
+
+		schema = resolved
+	}
+
+	if len(schema.OneOf) > 0 {
+		s.validateOneOf(value, schema.OneOf, path, errs)
+
+		return
+	}
+
+	if value == nil {
+		return
+	}
+
+	if schema.Type != "" && !matchesType(value, schema.Type) {
+		errs[fieldPath(path)] = "must be " + article(schema.Type)
+
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		validateString(v, schema, path, errs)
+	case []any:
+		if schema.Items == nil {
+			return
+		}
+
+		for i, item := range v {
+			s.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if v[name] == nil {
+				errs[join(path, name)] = "is required"
+			}
+		}
+
+		for name, property := range v {
+			if propertySchema, exists := schema.Properties[name]; exists {
+				s.validate(property, propertySchema, join(path, name), errs)
+
+				continue
+			}
+
+			if schema.AdditionalProperties != nil {
+				s.validate(property, schema.AdditionalProperties, join(path, name), errs)
+			}
+		}
+	}
+}
+
+// validateOneOf checks the value against the alternative of its JSON type,
+// so a malformed object reports its fields rather than a bare mismatch.
+func (s *Spec) validateOneOf(value any, alternatives []*Schema, path string, errs FieldErrors) {
+	if value == nil {
+		return
+	}
+
+	types := []string{}
+
+	for _, alternative := range alternatives {
+		if matchesType(value, alternative.Type) {
+			s.validate(value, alternative, path, errs)
+
+			return
+		}
+
+		types = append(types, alternative.Type)
+	}
+
+	errs[fieldPath(path)] = "must be " + strings.Join(types, " or ")
+}
+
+func validateString(value string, schema *Schema, path string, errs FieldErrors) {
+	if len(schema.Enum) > 0 {
+		if slices.Contains(schema.Enum, value) {
+			return
+		}
+
+		errs[fieldPath(path)] = "must be one of " + strings.Join(schema.Enum, ", ")
+
+		return
+	}
+
+	if schema.Pattern != "" && !regexp.MustCompile(schema.Pattern).MatchString(value) {
+		errs[fieldPath(path)] = "must match " + schema.Pattern
+
+		return
+	}
+
+	if schema.Format == "date-time" {
+		_, err := time.Parse(time.RFC3339, value)
+
+		if err != nil {
+			errs[fieldPath(path)] = "must be an RFC 3339 date-time"
+		}
+	}
+}
+
+func matchesType(value any, schemaType string) bool {
+	switch schemaType {
+	case "":
+		return true
+	case "string":
+		_, ok := value.(string)
+
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+
+		if !ok {
+			return false
+		}
+
+		_, err := number.Int64()
+
+		return err == nil
+	case "number":
+		_, ok := value.(json.Number)
+
+		return ok
+	case "array":
+		_, ok := value.([]any)
+
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+
+		return ok
+	default:
+		return false
+	}
+}
+
+func article(schemaType string) string {
+	if schemaType == "array" || schemaType == "object" || schemaType == "integer" {
+		return "an " + schemaType
+	}
+
+	return "a " + schemaType
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func fieldPath(path string) string {
+	if path == "" {
+		return bodyField
+	}
+
+	return path
+}
@@ -0,0 +1,159 @@
+package reconciler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
+
+const (
+	legSender   = "sender"
+	legReceiver = "receiver"
+)
+
+type legKey struct {
+	operation string
+	sender    string
+	receiver  string
+	amount    money.Money
+	timestamp time.Time
+}
+
+// ledgerBalance recomputes an account balance from its history. Transfer and
+// reversal legs carry the counterparty only, so the populated side tells the
+// direction. Pending transfer notices do not move money and are skipped.
+func ledgerBalance(account domain.Account) (money.Money, error) {
+	balance := money.New(0, account.Balance.Currency())
+
+	for _, transaction := range account.Transactions {
+		var err error
+
+		switch transaction.Operation {
+		case domain.OperationDeposit, domain.OperationFeeRefund, domain.OperationHoldRelease, domain.OperationLoanDisbursement, domain.OperationPotOut:
+			balance, err = balance.Add(transaction.Amount)
+		case domain.OperationWithdraw, domain.OperationFee, domain.OperationHold, domain.OperationCardPayment, domain.OperationLoanRepayment, domain.OperationPotIn:
+			balance, err = balance.Sub(transaction.Amount)
+		case domain.OperationTransfer, domain.OperationReversal:
+			if transaction.ReceiverAccountID != "" {
+				balance, err = balance.Sub(transaction.Amount)
+			} else {
+				balance, err = balance.Add(transaction.Amount)
+			}
+		}
+
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	return balance, nil
+}
+
+func checkBalances(accounts []domain.Account) []BalanceMismatch {
+	mismatches := []BalanceMismatch{}
+
+	for _, account := range accounts {
+		mismatch := BalanceMismatch{
+			AccountID: account.ID,
+			Balance:   account.Balance,
+		}
+
+		computed, err := ledgerBalance(account)
+
+		if err != nil {
+			mismatch.Error = err.Error()
+
+			mismatches = append(mismatches, mismatch)
+
+			continue
+		}
+
+		cmp, err := computed.Cmp(account.Balance)
+
+		if err != nil {
+			mismatch.Error = err.Error()
+		}
+
+		if err != nil || cmp != 0 {
+			mismatch.Computed = computed
+
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].AccountID < mismatches[j].AccountID
+	})
+
+	return mismatches
+}
+
+// checkLegs pairs every sender leg with a receiver leg of the same operation,
+// accounts, amount and timestamp. Whatever is left over on either side is
+// missing its counterpart.
+func checkLegs(accounts []domain.Account) []MissingCounterpart {
+	pending := make(map[legKey][]MissingCounterpart)
+
+	for _, account := range accounts {
+		for _, transaction := range account.Transactions {
+			if transaction.Operation != domain.OperationTransfer && transaction.Operation != domain.OperationReversal {
+				continue
+			}
+
+			key := legKey{
+				operation: transaction.Operation,
+				amount:    transaction.Amount,
+				timestamp: transaction.Timestamp,
+			}
+
+			leg := MissingCounterpart{
+				AccountID: account.ID,
+				Operation: transaction.Operation,
+				Amount:    transaction.Amount,
+				Timestamp: transaction.Timestamp,
+			}
+
+			if transaction.ReceiverAccountID != "" {
+				key.sender = account.ID
+				key.receiver = transaction.ReceiverAccountID
+
+				leg.CounterpartyAccountID = transaction.ReceiverAccountID
+				leg.Leg = legSender
+			} else {
+				key.sender = transaction.SenderAccountID
+				key.receiver = account.ID
+
+				leg.CounterpartyAccountID = transaction.SenderAccountID
+				leg.Leg = legReceiver
+			}
+
+			legs := pending[key]
+
+			if len(legs) > 0 && legs[len(legs)-1].Leg != leg.Leg {
+				pending[key] = legs[:len(legs)-1]
+
+				continue
+			}
+
+			pending[key] = append(legs, leg)
+		}
+	}
+
+	missing := []MissingCounterpart{}
+
+	for _, legs := range pending {
+		missing = append(missing, legs...)
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		if !missing[i].Timestamp.Equal(missing[j].Timestamp) {
+			return missing[i].Timestamp.Before(missing[j].Timestamp)
+		}
+
+		return missing[i].AccountID < missing[j].AccountID
+	})
+
+	return missing
+}
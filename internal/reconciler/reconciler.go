@@ -0,0 +1,84 @@
+package reconciler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+)
+
+// Reconciler checks the ledger invariants: every account is owned by a user,
+// every balance matches its history and every transfer has both legs. It reads
+// each repository once and does not lock across them, so a run racing a
+// transfer may report a discrepancy that a later run no longer sees.
+const orphanGrace = time.Minute
+
+type Reconciler interface {
+	Run() (Report, error)
+}
+
+type reconciler struct {
+	userRepo    userrepo.Repo
+	accountRepo accountrepo.Repo
+}
+
+func New(
+	userRepo userrepo.Repo,
+	accountRepo accountrepo.Repo,
+) Reconciler {
+	return &reconciler{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+	}
+}
+
+// Run lists accounts before users, so an account created during the run is
+// either not checked or already has its owner listed. An account is linked
+// to its owner just after it is created, so one too new to be linked yet
+// is not reported as an orphan either.
+func (r reconciler) Run() (Report, error) {
+	accounts, err := r.accountRepo.List(accountrepo.ListRequest{})
+
+	if err != nil {
+		return Report{}, err
+	}
+
+	users, err := r.userRepo.List(userrepo.ListRequest{})
+
+	if err != nil {
+		return Report{}, err
+	}
+
+	linkedBy := time.Now().UTC().Add(-orphanGrace)
+
+	owned := make(map[string]struct{})
+
+	for _, user := range users {
+		for accountID := range user.AccountIDs {
+			owned[accountID] = struct{}{}
+		}
+	}
+
+	orphans := []string{}
+
+	for _, account := range accounts {
+		if _, exists := owned[account.ID]; !exists && account.CreatedAt.Before(linkedBy) {
+			orphans = append(orphans, account.ID)
+		}
+	}
+
+	sort.Strings(orphans)
+
+	mismatches := checkBalances(accounts)
+	missing := checkLegs(accounts)
+
+	return Report{
+		CheckedAt:           time.Now().UTC(),
+		Consistent:          len(orphans) == 0 && len(mismatches) == 0 && len(missing) == 0,
+		AccountsChecked:     len(accounts),
+		OrphanAccountIDs:    orphans,
+		BalanceMismatches:   mismatches,
+		MissingCounterparts: missing,
+	}, nil
+}
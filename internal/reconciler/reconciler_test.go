@@ -0,0 +1,303 @@
+package reconciler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+	"github.com/hetfdex/tiny-bank/test/mock/repository/accountrepomock"
+	"github.com/hetfdex/tiny-bank/test/mock/repository/userrepomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_ErrListUsers(t *testing.T) {
+	errMock := errors.New("error list")
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"List",
+		userrepo.ListRequest{},
+	).Return(
+		[]domain.User(nil),
+		errMock,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"List",
+		accountrepo.ListRequest{},
+	).Return(
+		[]domain.Account{},
+		nil,
+	)
+
+	rec := New(userRepo, accountRepo)
+
+	res, err := rec.Run()
+
+	assert.Equal(t, Report{}, res)
+	assert.Equal(t, errMock, err)
+}
+
+func TestRun_Ok(t *testing.T) {
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"List",
+		userrepo.ListRequest{},
+	).Return(
+		[]domain.User{
+			{
+				ID: "1",
+				AccountIDs: map[string]struct{}{
+					"2": {},
+				},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"List",
+		accountrepo.ListRequest{},
+	).Return(
+		[]domain.Account{
+			{
+				ID: "2",
+			},
+			{
+				ID: "3",
+			},
+			{
+				ID:        "4",
+				CreatedAt: time.Now().UTC(),
+			},
+		},
+		nil,
+	)
+
+	rec := New(userRepo, accountRepo)
+
+	res, err := rec.Run()
+
+	assert.NotEmpty(t, res.CheckedAt)
+	assert.False(t, res.Consistent)
+	assert.Equal(t, 3, res.AccountsChecked)
+	assert.Equal(t, []string{"3"}, res.OrphanAccountIDs)
+	assert.Empty(t, res.BalanceMismatches)
+	assert.Empty(t, res.MissingCounterparts)
+	assert.Nil(t, err)
+}
+
+func TestRun_Discrepancies(t *testing.T) {
+	now := time.Now().UTC()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"List",
+		userrepo.ListRequest{},
+	).Return(
+		[]domain.User{
+			{
+				ID: "1",
+				AccountIDs: map[string]struct{}{
+					"2": {},
+					"3": {},
+				},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"List",
+		accountrepo.ListRequest{},
+	).Return(
+		[]domain.Account{
+			{
+				ID:      "2",
+				Balance: money.New(70, money.EUR),
+				Transactions: []domain.Transaction{
+					{
+						Timestamp: now,
+						Operation: domain.OperationDeposit,
+						Amount:    money.New(100, money.EUR),
+					},
+					{
+						Timestamp:         now,
+						Operation:         domain.OperationTransfer,
+						Amount:            money.New(30, money.EUR),
+						ReceiverUserID:    "1",
+						ReceiverAccountID: "3",
+					},
+					{
+						Timestamp:         now.Add(time.Second),
+						Operation:         domain.OperationTransfer,
+						Amount:            money.New(10, money.EUR),
+						ReceiverUserID:    "1",
+						ReceiverAccountID: "3",
+					},
+				},
+			},
+			{
+				ID:      "3",
+				Balance: money.New(30, money.EUR),
+				Transactions: []domain.Transaction{
+					{
+						Timestamp:       now,
+						Operation:       domain.OperationTransfer,
+						Amount:          money.New(30, money.EUR),
+						SenderUserID:    "1",
+						SenderAccountID: "2",
+					},
+				},
+			},
+		},
+		nil,
+	)
+
+	rec := New(userRepo, accountRepo)
+
+	res, err := rec.Run()
+
+	assert.False(t, res.Consistent)
+	assert.Empty(t, res.OrphanAccountIDs)
+	assert.Equal(
+		t,
+		[]BalanceMismatch{
+			{
+				AccountID: "2",
+				Balance:   money.New(70, money.EUR),
+				Computed:  money.New(60, money.EUR),
+			},
+		},
+		res.BalanceMismatches,
+	)
+	assert.Equal(
+		t,
+		[]MissingCounterpart{
+			{
+				AccountID:             "2",
+				CounterpartyAccountID: "3",
+				Operation:             domain.OperationTransfer,
+				Leg:                   legSender,
+				Amount:                money.New(10, money.EUR),
+				Timestamp:             now.Add(time.Second),
+			},
+		},
+		res.MissingCounterparts,
+	)
+	assert.Nil(t, err)
+}
+
+func TestRun_PendingTransfers(t *testing.T) {
+	now := time.Now().UTC()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"List",
+		userrepo.ListRequest{},
+	).Return(
+		[]domain.User{
+			{
+				ID: "1",
+				AccountIDs: map[string]struct{}{
+					"2": {},
+					"3": {},
+				},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"List",
+		accountrepo.ListRequest{},
+	).Return(
+		[]domain.Account{
+			{
+				ID:      "2",
+				Balance: money.New(50, money.EUR),
+				Transactions: []domain.Transaction{
+					{
+						Timestamp: now,
+						Operation: domain.OperationDeposit,
+						Amount:    money.New(100, money.EUR),
+					},
+					{
+						Timestamp:         now,
+						Operation:         domain.OperationHold,
+						Amount:            money.New(30, money.EUR),
+						ReceiverAccountID: "3",
+					},
+					{
+						Timestamp:         now,
+						Operation:         domain.OperationHold,
+						Amount:            money.New(20, money.EUR),
+						ReceiverAccountID: "3",
+					},
+					{
+						Timestamp:         now.Add(time.Second),
+						Operation:         domain.OperationHoldRelease,
+						Amount:            money.New(30, money.EUR),
+						ReceiverAccountID: "3",
+					},
+					{
+						Timestamp:         now.Add(time.Second),
+						Operation:         domain.OperationTransfer,
+						Amount:            money.New(30, money.EUR),
+						ReceiverAccountID: "3",
+					},
+				},
+			},
+			{
+				ID:      "3",
+				Balance: money.New(30, money.EUR),
+				Transactions: []domain.Transaction{
+					{
+						Timestamp:       now,
+						Operation:       domain.OperationPending,
+						Amount:          money.New(30, money.EUR),
+						SenderAccountID: "2",
+					},
+					{
+						Timestamp:       now,
+						Operation:       domain.OperationPending,
+						Amount:          money.New(20, money.EUR),
+						SenderAccountID: "2",
+					},
+					{
+						Timestamp:       now.Add(time.Second),
+						Operation:       domain.OperationTransfer,
+						Amount:          money.New(30, money.EUR),
+						SenderAccountID: "2",
+					},
+				},
+			},
+		},
+		nil,
+	)
+
+	rec := New(userRepo, accountRepo)
+
+	res, err := rec.Run()
+
+	assert.True(t, res.Consistent)
+	assert.Empty(t, res.BalanceMismatches)
+	assert.Empty(t, res.MissingCounterparts)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,38 @@
+package reconciler
+
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
+
+type Report struct {
+	CheckedAt           time.Time            `json:"checked_at"`
+	Consistent          bool                 `json:"consistent"`
+	AccountsChecked     int                  `json:"accounts_checked"`
+	OrphanAccountIDs    []string             `json:"orphan_account_ids"`
+	BalanceMismatches   []BalanceMismatch    `json:"balance_mismatches"`
+	MissingCounterparts []MissingCounterpart `json:"missing_counterparts"`
+}
+
+// BalanceMismatch is an account whose stored balance differs from the
+// balance recomputed from its transaction history.
+// Error is set when the history cannot be summed at all, for example because
+// it overflows or mixes currencies.
+type BalanceMismatch struct {
+	AccountID string      `json:"account_id"`
+	Balance   money.Money `json:"balance"`
+	Computed  money.Money `json:"computed"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// MissingCounterpart is a transfer or reversal leg with no matching leg on
+// the counterparty account. Leg is the side that was found.
+type MissingCounterpart struct {
+	AccountID             string      `json:"account_id"`
+	CounterpartyAccountID string      `json:"counterparty_account_id"`
+	Operation             string      `json:"operation"`
+	Leg                   string      `json:"leg"`
+	Amount                money.Money `json:"amount"`
+	Timestamp             time.Time   `json:"timestamp"`
+}
@@ -1,27 +1,50 @@
 package accountrepo
 
 import (
+	"encoding/json"
 	"errors"
 	"sync"
 	"time"
 
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
 	"github.com/pborman/uuid"
 )
 
+const (
+	walKind = "account"
+
+	// transactionKind records hold one ledger entry each, so an update does
+	// not write the account's whole history again.
+	transactionKind = "account_transaction"
+)
+
 var (
 	accountsMux sync.Mutex
+
+	ErrInsufficientFunds = errors.New("insuficient funds")
+	ErrAccountFrozen     = errors.New("account frozen")
+	ErrAccountClosed     = errors.New("account closed")
+	ErrBalanceNotZero    = errors.New("account balance not zero")
 )
 
 type Repo interface {
 	Create(CreateRequest) (domain.Account, error)
 	Read(ReadRequest) (domain.Account, error)
-	UpdateBalance(UpdateBalanceRequest) error
+	List(ListRequest) ([]domain.Account, error)
+	Delete(DeleteRequest) error
+	UpdateStatus(UpdateStatusRequest) error
+	UpdateHolders(UpdateHoldersRequest) error
+	UpdateRules(UpdateRulesRequest) error
+	UpdateBalance(UpdateBalanceRequest) (money.Money, error)
 	UpdateTransactions(UpdateTransactionsRequest) error
 }
 
 type repo struct {
 	accounts map[string]domain.Account
+	ibans    map[string]string
+	log      wal.Log
 }
 
 func New(
@@ -30,7 +53,78 @@ func New(
 
 	return &repo{
 		accounts: accounts,
+		ibans:    ibanIndex(accounts),
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	accounts := make(map[string]domain.Account)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		if rec.Deleted {
+			delete(accounts, rec.Key)
+
+			return nil
+		}
+
+		var account domain.Account
+
+		err := json.Unmarshal(rec.Value, &account)
+
+		if err != nil {
+			return err
+		}
+
+		// Records logged before transactions had their own carry the whole
+		// history; later ones carry none.
+		if account.Transactions == nil {
+			account.Transactions = accounts[rec.Key].Transactions
+		}
+
+		accounts[rec.Key] = account
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = log.Replay(transactionKind, func(rec wal.Record) error {
+		account, exists := accounts[rec.Key]
+
+		if !exists {
+			return nil
+		}
+
+		var transaction domain.Transaction
+
+		err := json.Unmarshal(rec.Value, &transaction)
+
+		if err != nil {
+			return err
+		}
+
+		account.Transactions = append(account.Transactions, transaction)
+
+		accounts[rec.Key] = account
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		accounts: accounts,
+		ibans:    ibanIndex(accounts),
+		log:      log,
 	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
 }
 
 func (r repo) Create(req CreateRequest) (domain.Account, error) {
@@ -44,13 +138,41 @@ func (r repo) Create(req CreateRequest) (domain.Account, error) {
 		return domain.Account{}, errors.New("id in use")
 	}
 
+	if _, exists := r.ibans[req.IBAN]; exists && req.IBAN != "" {
+		return domain.Account{}, errors.New("iban in use")
+	}
+
+	now := time.Now().UTC()
+
 	account := domain.Account{
 		ID:        id,
-		CreatedAt: time.Now().UTC(),
+		IBAN:      req.IBAN,
+		CreatedAt: now,
+		Balance:   money.New(0, req.Product.Currency),
+		Holders:   map[string]domain.Role{},
+		Product:   req.Product,
+	}
+
+	if req.Product.TermDays > 0 {
+		account.MaturesAt = now.AddDate(0, 0, req.Product.TermDays)
+	}
+
+	if req.OwnerID != "" {
+		account.Holders[req.OwnerID] = domain.RoleOwner
+	}
+
+	err := r.persist(account)
+
+	if err != nil {
+		return domain.Account{}, err
 	}
 
 	r.accounts[id] = account
 
+	if account.IBAN != "" {
+		r.ibans[account.IBAN] = id
+	}
+
 	return account, nil
 }
 
@@ -59,10 +181,34 @@ func (r repo) Read(req ReadRequest) (domain.Account, error) {
 
 	defer accountsMux.Unlock()
 
+	if req.ID == "" && req.IBAN != "" {
+		id, exists := r.ibans[req.IBAN]
+
+		if !exists {
+			return domain.Account{}, errors.New("account not found")
+		}
+
+		return r.getAccount(id)
+	}
+
 	return r.getAccount(req.ID)
 }
 
-func (r repo) UpdateBalance(req UpdateBalanceRequest) error {
+func (r repo) List(req ListRequest) ([]domain.Account, error) {
+	accountsMux.Lock()
+
+	defer accountsMux.Unlock()
+
+	accounts := make([]domain.Account, 0, len(r.accounts))
+
+	for _, account := range r.accounts {
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func (r repo) Delete(req DeleteRequest) error {
 	accountsMux.Lock()
 
 	defer accountsMux.Unlock()
@@ -73,13 +219,179 @@ func (r repo) UpdateBalance(req UpdateBalanceRequest) error {
 		return err
 	}
 
-	account.Balance = int(req.Balance)
+	if r.log != nil {
+		err = r.log.Append(
+			wal.Record{
+				Kind:    walKind,
+				Key:     req.ID,
+				Deleted: true,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(r.accounts, req.ID)
+
+	delete(r.ibans, account.IBAN)
+
+	return nil
+}
+
+func (r repo) UpdateStatus(req UpdateStatusRequest) error {
+	accountsMux.Lock()
+
+	defer accountsMux.Unlock()
+
+	account, err := r.getAccount(req.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if !req.ClosedAt.IsZero() && !account.Balance.IsZero() {
+		return ErrBalanceNotZero
+	}
+
+	account.FrozenAt = req.FrozenAt
+	account.ClosedAt = req.ClosedAt
+
+	err = r.persist(account)
+
+	if err != nil {
+		return err
+	}
 
 	r.accounts[req.ID] = account
 
 	return nil
 }
 
+func (r repo) UpdateHolders(req UpdateHoldersRequest) error {
+	accountsMux.Lock()
+
+	defer accountsMux.Unlock()
+
+	account, err := r.getAccount(req.ID)
+
+	if err != nil {
+		return err
+	}
+
+	holders := make(map[string]domain.Role, len(account.Holders)+1)
+
+	for userID, role := range account.Holders {
+		holders[userID] = role
+	}
+
+	if req.Role == "" {
+		if _, exists := holders[req.UserID]; !exists {
+			return errors.New("holder not found")
+		}
+
+		delete(holders, req.UserID)
+	} else {
+		holders[req.UserID] = req.Role
+	}
+
+	account.Holders = holders
+
+	err = r.persist(account)
+
+	if err != nil {
+		return err
+	}
+
+	r.accounts[req.ID] = account
+
+	return nil
+}
+
+func (r repo) UpdateRules(req UpdateRulesRequest) error {
+	accountsMux.Lock()
+
+	defer accountsMux.Unlock()
+
+	account, err := r.getAccount(req.ID)
+
+	if err != nil {
+		return err
+	}
+
+	account.DualApprovalThreshold = req.DualApprovalThreshold
+
+	err = r.persist(account)
+
+	if err != nil {
+		return err
+	}
+
+	r.accounts[req.ID] = account
+
+	return nil
+}
+
+// UpdateBalance applies a credit and a debit to the balance as it is when
+// the lock is taken, so writers that read the account earlier cannot undo
+// each other's changes, and returns the new balance.
+func (r repo) UpdateBalance(req UpdateBalanceRequest) (money.Money, error) {
+	accountsMux.Lock()
+
+	defer accountsMux.Unlock()
+
+	account, err := r.getAccount(req.ID)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if !account.FrozenAt.IsZero() && !req.Sweep {
+		return money.Money{}, ErrAccountFrozen
+	}
+
+	if !account.ClosedAt.IsZero() && req.Credit.IsPositive() {
+		return money.Money{}, ErrAccountClosed
+	}
+
+	balance, err := account.Balance.Add(req.Credit)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	balance, err = balance.Sub(req.Debit)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if req.Debit.IsPositive() {
+		cmp, err := balance.Cmp(req.Minimum)
+
+		if err != nil {
+			return money.Money{}, err
+		}
+
+		if cmp < 0 {
+			return money.Money{}, ErrInsufficientFunds
+		}
+	}
+
+	account.Balance = balance
+
+	err = r.persist(account)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	r.accounts[req.ID] = account
+
+	return balance, nil
+}
+
 func (r repo) UpdateTransactions(req UpdateTransactionsRequest) error {
 	accountsMux.Lock()
 
@@ -91,7 +403,25 @@ func (r repo) UpdateTransactions(req UpdateTransactionsRequest) error {
 		return err
 	}
 
-	account.Transactions = append(account.Transactions, req.Transaction)
+	transactions := make([]domain.Transaction, len(account.Transactions), len(account.Transactions)+1)
+
+	copy(transactions, account.Transactions)
+
+	account.Transactions = append(transactions, req.Transaction)
+
+	if r.log != nil {
+		record, err := transactionRecord(req.ID, req.Transaction)
+
+		if err != nil {
+			return err
+		}
+
+		err = r.log.Append(record)
+
+		if err != nil {
+			return err
+		}
+	}
 
 	r.accounts[req.ID] = account
 
@@ -107,3 +437,91 @@ func (r repo) getAccount(id string) (domain.Account, error) {
 
 	return account, nil
 }
+
+func (r repo) persist(account domain.Account) error {
+	if r.log == nil {
+		return nil
+	}
+
+	record, err := accountRecord(account)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(record)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	accountsMux.Lock()
+
+	defer accountsMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.accounts))
+
+	for _, account := range r.accounts {
+		record, err := accountRecord(account)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+
+		for _, transaction := range account.Transactions {
+			record, err := transactionRecord(account.ID, transaction)
+
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// accountRecord logs the account without its transactions, which have
+// records of their own.
+func accountRecord(account domain.Account) (wal.Record, error) {
+	account.Transactions = nil
+
+	value, err := json.Marshal(account)
+
+	if err != nil {
+		return wal.Record{}, err
+	}
+
+	return wal.Record{
+		Kind:  walKind,
+		Key:   account.ID,
+		Value: value,
+	}, nil
+}
+
+func transactionRecord(accountID string, transaction domain.Transaction) (wal.Record, error) {
+	value, err := json.Marshal(transaction)
+
+	if err != nil {
+		return wal.Record{}, err
+	}
+
+	return wal.Record{
+		Kind:  transactionKind,
+		Key:   accountID,
+		Value: value,
+	}, nil
+}
+
+func ibanIndex(accounts map[string]domain.Account) map[string]string {
+	ibans := make(map[string]string, len(accounts))
+
+	for id, account := range accounts {
+		if account.IBAN != "" {
+			ibans[account.IBAN] = id
+		}
+	}
+
+	return ibans
+}
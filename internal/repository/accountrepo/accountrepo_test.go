@@ -0,0 +1,185 @@
+package accountrepo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDurable_Transactions(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	repo, err := NewDurable(log)
+
+	assert.Nil(t, err)
+
+	account, err := repo.Create(
+		CreateRequest{
+			OwnerID: "1",
+			Product: domain.Product{
+				Currency: money.EUR,
+			},
+		},
+	)
+
+	assert.Nil(t, err)
+
+	for _, id := range []string{"t1", "t2"} {
+		err = repo.UpdateTransactions(
+			UpdateTransactionsRequest{
+				ID: account.ID,
+				Transaction: domain.Transaction{
+					ID:     id,
+					Amount: money.New(100, money.EUR),
+				},
+			},
+		)
+
+		assert.Nil(t, err)
+
+		_, err = repo.UpdateBalance(
+			UpdateBalanceRequest{
+				ID:     account.ID,
+				Credit: money.New(100, money.EUR),
+			},
+		)
+
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, log.Snapshot())
+
+	err = repo.UpdateTransactions(
+		UpdateTransactionsRequest{
+			ID: account.ID,
+			Transaction: domain.Transaction{
+				ID:     "t3",
+				Amount: money.New(50, money.EUR),
+			},
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Nil(t, log.Close())
+
+	log = openLog(t, dir)
+
+	repo, err = NewDurable(log)
+
+	assert.Nil(t, err)
+
+	res, err := repo.Read(
+		ReadRequest{
+			ID: account.ID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(200, money.EUR), res.Balance)
+	assert.Len(t, res.Transactions, 3)
+	assert.Equal(t, "t3", res.Transactions[2].ID)
+}
+
+func TestNewDurable_LegacyHistory(t *testing.T) {
+	log := openLog(t, t.TempDir())
+
+	legacy, err := json.Marshal(
+		domain.Account{
+			ID:      "1",
+			Balance: money.New(100, money.EUR),
+			Transactions: []domain.Transaction{
+				{
+					ID: "t1",
+				},
+			},
+		},
+	)
+
+	assert.Nil(t, err)
+
+	current, err := json.Marshal(
+		domain.Account{
+			ID:      "1",
+			Balance: money.New(150, money.EUR),
+		},
+	)
+
+	assert.Nil(t, err)
+
+	transaction, err := json.Marshal(
+		domain.Transaction{
+			ID: "t2",
+		},
+	)
+
+	assert.Nil(t, err)
+
+	for _, rec := range []wal.Record{
+		{Kind: walKind, Key: "1", Value: legacy},
+		{Kind: walKind, Key: "1", Value: current},
+		{Kind: transactionKind, Key: "1", Value: transaction},
+	} {
+		assert.Nil(t, log.Append(rec))
+	}
+
+	repo, err := NewDurable(log)
+
+	assert.Nil(t, err)
+
+	res, err := repo.Read(
+		ReadRequest{
+			ID: "1",
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(150, money.EUR), res.Balance)
+	assert.Len(t, res.Transactions, 2)
+}
+
+func openLog(t *testing.T, dir string) wal.Log {
+	log, err := wal.Open(
+		wal.Config{
+			Dir:        dir,
+			SyncPolicy: wal.SyncAlways,
+		},
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		log.Close()
+	})
+
+	return log
+}
+
+func TestUpdateBalance_ErrAccountClosed(t *testing.T) {
+	repo := New(
+		map[string]domain.Account{
+			"1": {
+				ID:       "1",
+				ClosedAt: time.Now().UTC(),
+				Balance:  money.New(0, money.EUR),
+			},
+		},
+	)
+
+	_, err := repo.UpdateBalance(
+		UpdateBalanceRequest{
+			ID:     "1",
+			Credit: money.New(100, money.EUR),
+		},
+	)
+
+	assert.Equal(t, ErrAccountClosed, err)
+}
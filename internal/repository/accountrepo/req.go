@@ -1,16 +1,59 @@
 package accountrepo
 
-import "github.com/hetfdex/tiny-bank/internal/domain"
+import (
+	"time"
 
-type CreateRequest struct{}
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
 
+type CreateRequest struct {
+	OwnerID string
+	Product domain.Product
+	IBAN    string
+}
+
+// ReadRequest looks an account up by ID or, when ID is empty, by IBAN.
 type ReadRequest struct {
+	ID   string
+	IBAN string
+}
+
+type ListRequest struct{}
+
+type DeleteRequest struct {
 	ID string
 }
 
+// UpdateStatusRequest freezes an account when FrozenAt is set and closes it
+// when ClosedAt is set. Both zero reopens it.
+type UpdateStatusRequest struct {
+	ID       string
+	FrozenAt time.Time
+	ClosedAt time.Time
+}
+
+type UpdateHoldersRequest struct {
+	ID     string
+	UserID string
+	Role   domain.Role
+}
+
+type UpdateRulesRequest struct {
+	ID                    string
+	DualApprovalThreshold money.Money
+}
+
+// UpdateBalanceRequest adds Credit to the balance and takes Debit from it.
+// A debit that would leave less than Minimum fails and changes nothing. A
+// frozen account only takes changes marked Sweep, made while it is emptied
+// to close, and a closed account takes no credits.
 type UpdateBalanceRequest struct {
 	ID      string
-	Balance int
+	Credit  money.Money
+	Debit   money.Money
+	Minimum money.Money
+	Sweep   bool
 }
 
 type UpdateTransactionsRequest struct {
@@ -0,0 +1,103 @@
+package batchrepo
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/pborman/uuid"
+)
+
+var (
+	batchesMux sync.Mutex
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Batch, error)
+	Read(ReadRequest) (domain.Batch, error)
+	Update(UpdateRequest) (domain.Batch, error)
+}
+
+type repo struct {
+	batches map[string]domain.Batch
+}
+
+func New(
+	batches map[string]domain.Batch,
+) Repo {
+
+	return &repo{
+		batches: batches,
+	}
+}
+
+func (r repo) Create(req CreateRequest) (domain.Batch, error) {
+	batchesMux.Lock()
+
+	defer batchesMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.batches[id]; exists {
+		return domain.Batch{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	batch := req.Batch
+
+	batch.ID = id
+	batch.CreatedAt = now
+	batch.UpdatedAt = now
+	batch.Lines = copyLines(req.Batch.Lines)
+
+	r.batches[id] = batch
+
+	return batch, nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Batch, error) {
+	batchesMux.Lock()
+
+	defer batchesMux.Unlock()
+
+	batch, exists := r.batches[req.ID]
+
+	if !exists {
+		return domain.Batch{}, errors.New("batch not found")
+	}
+
+	batch.Lines = copyLines(batch.Lines)
+
+	return batch, nil
+}
+
+func (r repo) Update(req UpdateRequest) (domain.Batch, error) {
+	batchesMux.Lock()
+
+	defer batchesMux.Unlock()
+
+	if _, exists := r.batches[req.Batch.ID]; !exists {
+		return domain.Batch{}, errors.New("batch not found")
+	}
+
+	batch := req.Batch
+
+	batch.UpdatedAt = time.Now().UTC()
+	batch.Lines = copyLines(req.Batch.Lines)
+
+	r.batches[batch.ID] = batch
+
+	batch.Lines = copyLines(batch.Lines)
+
+	return batch, nil
+}
+
+func copyLines(lines []domain.BatchLine) []domain.BatchLine {
+	copied := make([]domain.BatchLine, len(lines))
+
+	copy(copied, lines)
+
+	return copied
+}
@@ -0,0 +1,15 @@
+package batchrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Batch domain.Batch
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+type UpdateRequest struct {
+	Batch domain.Batch
+}
@@ -0,0 +1,249 @@
+package cardrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "card"
+)
+
+var (
+	cardsMux sync.Mutex
+
+	ErrVersionConflict = errors.New("card changed")
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Card, error)
+	Read(ReadRequest) (domain.Card, error)
+	List(ListRequest) ([]domain.Card, error)
+	Update(UpdateRequest) (domain.Card, error)
+}
+
+type repo struct {
+	cards map[string]domain.Card
+	log   wal.Log
+}
+
+func New(
+	cards map[string]domain.Card,
+) Repo {
+
+	return &repo{
+		cards: cards,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	cards := make(map[string]domain.Card)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		var card domain.Card
+
+		err := json.Unmarshal(rec.Value, &card)
+
+		if err != nil {
+			return err
+		}
+
+		cards[rec.Key] = card
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		cards: cards,
+		log:   log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.Card, error) {
+	cardsMux.Lock()
+
+	defer cardsMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.cards[id]; exists {
+		return domain.Card{}, errors.New("id in use")
+	}
+
+	for _, card := range r.cards {
+		if card.PAN == req.Card.PAN {
+			return domain.Card{}, errors.New("pan in use")
+		}
+	}
+
+	now := time.Now().UTC()
+
+	card := req.Card
+
+	card.ID = id
+	card.Version = 1
+	card.CreatedAt = now
+	card.UpdatedAt = now
+	card.Authorisations = slices.Clone(req.Card.Authorisations)
+
+	err := r.persist(card)
+
+	if err != nil {
+		return domain.Card{}, err
+	}
+
+	r.cards[id] = card
+
+	return copyCard(card), nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Card, error) {
+	cardsMux.Lock()
+
+	defer cardsMux.Unlock()
+
+	card, exists := r.cards[req.ID]
+
+	if !exists {
+		return domain.Card{}, errors.New("card not found")
+	}
+
+	return copyCard(card), nil
+}
+
+// List returns the matching cards, newest first.
+func (r repo) List(req ListRequest) ([]domain.Card, error) {
+	cardsMux.Lock()
+
+	defer cardsMux.Unlock()
+
+	cards := []domain.Card{}
+
+	for _, card := range r.cards {
+		if req.UserID != "" && card.UserID != req.UserID {
+			continue
+		}
+
+		if req.AccountID != "" && card.AccountID != req.AccountID {
+			continue
+		}
+
+		if req.PAN != "" && card.PAN != req.PAN {
+			continue
+		}
+
+		cards = append(cards, copyCard(card))
+	}
+
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].CreatedAt.Equal(cards[j].CreatedAt) {
+			return cards[i].ID < cards[j].ID
+		}
+
+		return cards[i].CreatedAt.After(cards[j].CreatedAt)
+	})
+
+	return cards, nil
+}
+
+// Update stores the card and bumps its version. It fails with
+// ErrVersionConflict when the card was updated since it was read.
+func (r repo) Update(req UpdateRequest) (domain.Card, error) {
+	cardsMux.Lock()
+
+	defer cardsMux.Unlock()
+
+	stored, exists := r.cards[req.Card.ID]
+
+	if !exists {
+		return domain.Card{}, errors.New("card not found")
+	}
+
+	if stored.Version != req.Card.Version {
+		return domain.Card{}, ErrVersionConflict
+	}
+
+	card := copyCard(req.Card)
+
+	card.Version++
+	card.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(card)
+
+	if err != nil {
+		return domain.Card{}, err
+	}
+
+	r.cards[card.ID] = card
+
+	return copyCard(card), nil
+}
+
+func (r repo) persist(card domain.Card) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(card)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   card.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	cardsMux.Lock()
+
+	defer cardsMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.cards))
+
+	for id, card := range r.cards {
+		value, err := json.Marshal(card)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyCard(card domain.Card) domain.Card {
+	card.Authorisations = slices.Clone(card.Authorisations)
+
+	return card
+}
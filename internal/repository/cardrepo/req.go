@@ -0,0 +1,25 @@
+package cardrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Card domain.Card
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+// ListRequest matches cards issued to UserID, on AccountID or with PAN, for
+// whichever are set.
+type ListRequest struct {
+	UserID    string
+	AccountID string
+	PAN       string
+}
+
+// UpdateRequest replaces the stored card if its Version is still the one
+// given.
+type UpdateRequest struct {
+	Card domain.Card
+}
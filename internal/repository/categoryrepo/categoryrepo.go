@@ -0,0 +1,180 @@
+package categoryrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+)
+
+const (
+	walKind = "categorisation"
+)
+
+var (
+	categorisationsMux sync.Mutex
+
+	ErrVersionConflict = errors.New("categorisation changed")
+)
+
+type Repo interface {
+	Put(PutRequest) (domain.Categorisation, error)
+	Read(ReadRequest) (domain.Categorisation, error)
+}
+
+type repo struct {
+	categorisations map[string]domain.Categorisation
+	log             wal.Log
+}
+
+func New(
+	categorisations map[string]domain.Categorisation,
+) Repo {
+
+	return &repo{
+		categorisations: categorisations,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	categorisations := make(map[string]domain.Categorisation)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		if rec.Deleted {
+			delete(categorisations, rec.Key)
+
+			return nil
+		}
+
+		var categorisation domain.Categorisation
+
+		err := json.Unmarshal(rec.Value, &categorisation)
+
+		if err != nil {
+			return err
+		}
+
+		categorisations[rec.Key] = categorisation
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		categorisations: categorisations,
+		log:             log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+// Put stores the categorisation and bumps its version. It fails with
+// ErrVersionConflict when it was stored since it was read.
+func (r repo) Put(req PutRequest) (domain.Categorisation, error) {
+	categorisationsMux.Lock()
+
+	defer categorisationsMux.Unlock()
+
+	stored := r.categorisations[req.Categorisation.UserID]
+
+	if stored.Version != req.Categorisation.Version {
+		return domain.Categorisation{}, ErrVersionConflict
+	}
+
+	categorisation := copyCategorisation(req.Categorisation)
+
+	categorisation.Version++
+	categorisation.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(categorisation)
+
+	if err != nil {
+		return domain.Categorisation{}, err
+	}
+
+	r.categorisations[categorisation.UserID] = categorisation
+
+	return copyCategorisation(categorisation), nil
+}
+
+// Read returns the user's categorisation, or an empty one at version zero
+// for a user who never changed theirs, ready to be Put.
+func (r repo) Read(req ReadRequest) (domain.Categorisation, error) {
+	categorisationsMux.Lock()
+
+	defer categorisationsMux.Unlock()
+
+	categorisation, exists := r.categorisations[req.UserID]
+
+	if !exists {
+		return domain.Categorisation{
+			UserID: req.UserID,
+		}, nil
+	}
+
+	return copyCategorisation(categorisation), nil
+}
+
+func (r repo) persist(categorisation domain.Categorisation) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(categorisation)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   categorisation.UserID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	categorisationsMux.Lock()
+
+	defer categorisationsMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.categorisations))
+
+	for userID, categorisation := range r.categorisations {
+		value, err := json.Marshal(categorisation)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   userID,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyCategorisation(categorisation domain.Categorisation) domain.Categorisation {
+	categorisation.Rules = slices.Clone(categorisation.Rules)
+	categorisation.Overrides = maps.Clone(categorisation.Overrides)
+
+	return categorisation
+}
@@ -0,0 +1,13 @@
+package categoryrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+// PutRequest stores the categorisation if its Version is still the one
+// stored, or is zero for a user without one.
+type PutRequest struct {
+	Categorisation domain.Categorisation
+}
+
+type ReadRequest struct {
+	UserID string
+}
@@ -0,0 +1,276 @@
+package loanrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "loan"
+)
+
+var (
+	loansMux sync.Mutex
+
+	ErrVersionConflict = errors.New("loan changed")
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Loan, error)
+	Read(ReadRequest) (domain.Loan, error)
+	List(ListRequest) ([]domain.Loan, error)
+	Update(UpdateRequest) (domain.Loan, error)
+	Delete(DeleteRequest) error
+}
+
+type repo struct {
+	loans map[string]domain.Loan
+	log   wal.Log
+}
+
+func New(
+	loans map[string]domain.Loan,
+) Repo {
+
+	return &repo{
+		loans: loans,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	loans := make(map[string]domain.Loan)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		if rec.Deleted {
+			delete(loans, rec.Key)
+
+			return nil
+		}
+
+		var loan domain.Loan
+
+		err := json.Unmarshal(rec.Value, &loan)
+
+		if err != nil {
+			return err
+		}
+
+		loans[rec.Key] = loan
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		loans: loans,
+		log:   log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.Loan, error) {
+	loansMux.Lock()
+
+	defer loansMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.loans[id]; exists {
+		return domain.Loan{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	loan := req.Loan
+
+	loan.ID = id
+	loan.Version = 1
+	loan.CreatedAt = now
+	loan.UpdatedAt = now
+	loan.Instalments = slices.Clone(req.Loan.Instalments)
+	loan.Repayments = slices.Clone(req.Loan.Repayments)
+
+	err := r.persist(loan)
+
+	if err != nil {
+		return domain.Loan{}, err
+	}
+
+	r.loans[id] = loan
+
+	return copyLoan(loan), nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Loan, error) {
+	loansMux.Lock()
+
+	defer loansMux.Unlock()
+
+	loan, exists := r.loans[req.ID]
+
+	if !exists {
+		return domain.Loan{}, errors.New("loan not found")
+	}
+
+	return copyLoan(loan), nil
+}
+
+// List returns the matching loans, newest first.
+func (r repo) List(req ListRequest) ([]domain.Loan, error) {
+	loansMux.Lock()
+
+	defer loansMux.Unlock()
+
+	loans := []domain.Loan{}
+
+	for _, loan := range r.loans {
+		if req.UserID != "" && loan.UserID != req.UserID {
+			continue
+		}
+
+		if req.Status != "" && loan.Status != req.Status {
+			continue
+		}
+
+		loans = append(loans, copyLoan(loan))
+	}
+
+	sort.Slice(loans, func(i, j int) bool {
+		if loans[i].CreatedAt.Equal(loans[j].CreatedAt) {
+			return loans[i].ID < loans[j].ID
+		}
+
+		return loans[i].CreatedAt.After(loans[j].CreatedAt)
+	})
+
+	return loans, nil
+}
+
+// Update stores the loan and bumps its version. It fails with
+// ErrVersionConflict when the loan was updated since it was read.
+func (r repo) Update(req UpdateRequest) (domain.Loan, error) {
+	loansMux.Lock()
+
+	defer loansMux.Unlock()
+
+	stored, exists := r.loans[req.Loan.ID]
+
+	if !exists {
+		return domain.Loan{}, errors.New("loan not found")
+	}
+
+	if stored.Version != req.Loan.Version {
+		return domain.Loan{}, ErrVersionConflict
+	}
+
+	loan := copyLoan(req.Loan)
+
+	loan.Version++
+	loan.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(loan)
+
+	if err != nil {
+		return domain.Loan{}, err
+	}
+
+	r.loans[loan.ID] = loan
+
+	return copyLoan(loan), nil
+}
+
+func (r repo) Delete(req DeleteRequest) error {
+	loansMux.Lock()
+
+	defer loansMux.Unlock()
+
+	if _, exists := r.loans[req.ID]; !exists {
+		return errors.New("loan not found")
+	}
+
+	if r.log != nil {
+		err := r.log.Append(
+			wal.Record{
+				Kind:    walKind,
+				Key:     req.ID,
+				Deleted: true,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(r.loans, req.ID)
+
+	return nil
+}
+
+func (r repo) persist(loan domain.Loan) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(loan)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   loan.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	loansMux.Lock()
+
+	defer loansMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.loans))
+
+	for id, loan := range r.loans {
+		value, err := json.Marshal(loan)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyLoan(loan domain.Loan) domain.Loan {
+	loan.Instalments = slices.Clone(loan.Instalments)
+	loan.Repayments = slices.Clone(loan.Repayments)
+
+	return loan
+}
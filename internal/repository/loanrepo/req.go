@@ -0,0 +1,27 @@
+package loanrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Loan domain.Loan
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+// ListRequest matches loans to UserID and in Status, for whichever are set.
+type ListRequest struct {
+	UserID string
+	Status domain.LoanStatus
+}
+
+// UpdateRequest replaces the stored loan if its Version is still the one
+// given.
+type UpdateRequest struct {
+	Loan domain.Loan
+}
+
+type DeleteRequest struct {
+	ID string
+}
@@ -0,0 +1,239 @@
+package mandaterepo
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "mandate"
+)
+
+var (
+	mandatesMux sync.Mutex
+
+	ErrVersionConflict = errors.New("mandate changed")
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Mandate, error)
+	Read(ReadRequest) (domain.Mandate, error)
+	List(ListRequest) ([]domain.Mandate, error)
+	Update(UpdateRequest) (domain.Mandate, error)
+}
+
+type repo struct {
+	mandates map[string]domain.Mandate
+	log      wal.Log
+}
+
+func New(
+	mandates map[string]domain.Mandate,
+) Repo {
+
+	return &repo{
+		mandates: mandates,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	mandates := make(map[string]domain.Mandate)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		var mandate domain.Mandate
+
+		err := json.Unmarshal(rec.Value, &mandate)
+
+		if err != nil {
+			return err
+		}
+
+		mandates[rec.Key] = mandate
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		mandates: mandates,
+		log:      log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.Mandate, error) {
+	mandatesMux.Lock()
+
+	defer mandatesMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.mandates[id]; exists {
+		return domain.Mandate{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	mandate := req.Mandate
+
+	mandate.ID = id
+	mandate.Version = 1
+	mandate.CreatedAt = now
+	mandate.UpdatedAt = now
+	mandate.Collections = slices.Clone(req.Mandate.Collections)
+
+	err := r.persist(mandate)
+
+	if err != nil {
+		return domain.Mandate{}, err
+	}
+
+	r.mandates[id] = mandate
+
+	return copyMandate(mandate), nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Mandate, error) {
+	mandatesMux.Lock()
+
+	defer mandatesMux.Unlock()
+
+	mandate, exists := r.mandates[req.ID]
+
+	if !exists {
+		return domain.Mandate{}, errors.New("mandate not found")
+	}
+
+	return copyMandate(mandate), nil
+}
+
+// List returns the matching mandates, newest first.
+func (r repo) List(req ListRequest) ([]domain.Mandate, error) {
+	mandatesMux.Lock()
+
+	defer mandatesMux.Unlock()
+
+	mandates := []domain.Mandate{}
+
+	for _, mandate := range r.mandates {
+		if req.DebtorUserID != "" && mandate.DebtorUserID != req.DebtorUserID {
+			continue
+		}
+
+		if req.CreditorAccountID != "" && mandate.CreditorAccountID != req.CreditorAccountID {
+			continue
+		}
+
+		mandates = append(mandates, copyMandate(mandate))
+	}
+
+	sort.Slice(mandates, func(i, j int) bool {
+		if mandates[i].CreatedAt.Equal(mandates[j].CreatedAt) {
+			return mandates[i].ID < mandates[j].ID
+		}
+
+		return mandates[i].CreatedAt.After(mandates[j].CreatedAt)
+	})
+
+	return mandates, nil
+}
+
+// Update stores the mandate and bumps its version. It fails with
+// ErrVersionConflict when the mandate was updated since it was read.
+func (r repo) Update(req UpdateRequest) (domain.Mandate, error) {
+	mandatesMux.Lock()
+
+	defer mandatesMux.Unlock()
+
+	stored, exists := r.mandates[req.Mandate.ID]
+
+	if !exists {
+		return domain.Mandate{}, errors.New("mandate not found")
+	}
+
+	if stored.Version != req.Mandate.Version {
+		return domain.Mandate{}, ErrVersionConflict
+	}
+
+	mandate := copyMandate(req.Mandate)
+
+	mandate.Version++
+	mandate.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(mandate)
+
+	if err != nil {
+		return domain.Mandate{}, err
+	}
+
+	r.mandates[mandate.ID] = mandate
+
+	return copyMandate(mandate), nil
+}
+
+func (r repo) persist(mandate domain.Mandate) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(mandate)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   mandate.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	mandatesMux.Lock()
+
+	defer mandatesMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.mandates))
+
+	for id, mandate := range r.mandates {
+		value, err := json.Marshal(mandate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyMandate(mandate domain.Mandate) domain.Mandate {
+	mandate.Collections = slices.Clone(mandate.Collections)
+
+	return mandate
+}
@@ -0,0 +1,24 @@
+package mandaterepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Mandate domain.Mandate
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+// ListRequest matches mandates granted by DebtorUserID or to
+// CreditorAccountID, for whichever are set.
+type ListRequest struct {
+	DebtorUserID      string
+	CreditorAccountID string
+}
+
+// UpdateRequest replaces the stored mandate if its Version is still the one
+// given.
+type UpdateRequest struct {
+	Mandate domain.Mandate
+}
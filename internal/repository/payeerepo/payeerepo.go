@@ -0,0 +1,248 @@
+package payeerepo
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "payee"
+)
+
+var (
+	payeesMux sync.Mutex
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Payee, error)
+	Read(ReadRequest) (domain.Payee, error)
+	List(ListRequest) ([]domain.Payee, error)
+	Update(UpdateRequest) (domain.Payee, error)
+	Delete(DeleteRequest) error
+}
+
+type repo struct {
+	payees map[string]domain.Payee
+	log    wal.Log
+}
+
+func New(
+	payees map[string]domain.Payee,
+) Repo {
+
+	return &repo{
+		payees: payees,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	payees := make(map[string]domain.Payee)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		if rec.Deleted {
+			delete(payees, rec.Key)
+
+			return nil
+		}
+
+		var payee domain.Payee
+
+		err := json.Unmarshal(rec.Value, &payee)
+
+		if err != nil {
+			return err
+		}
+
+		payees[rec.Key] = payee
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		payees: payees,
+		log:    log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.Payee, error) {
+	payeesMux.Lock()
+
+	defer payeesMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.payees[id]; exists {
+		return domain.Payee{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	payee := req.Payee
+
+	payee.ID = id
+	payee.CreatedAt = now
+	payee.UpdatedAt = now
+
+	err := r.persist(payee)
+
+	if err != nil {
+		return domain.Payee{}, err
+	}
+
+	r.payees[id] = payee
+
+	return payee, nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Payee, error) {
+	payeesMux.Lock()
+
+	defer payeesMux.Unlock()
+
+	payee, exists := r.payees[req.ID]
+
+	if !exists {
+		return domain.Payee{}, errors.New("payee not found")
+	}
+
+	return payee, nil
+}
+
+// List returns a user's payees, oldest first.
+func (r repo) List(req ListRequest) ([]domain.Payee, error) {
+	payeesMux.Lock()
+
+	defer payeesMux.Unlock()
+
+	payees := []domain.Payee{}
+
+	for _, payee := range r.payees {
+		if payee.UserID == req.UserID {
+			payees = append(payees, payee)
+		}
+	}
+
+	sort.Slice(payees, func(i, j int) bool {
+		if payees[i].CreatedAt.Equal(payees[j].CreatedAt) {
+			return payees[i].ID < payees[j].ID
+		}
+
+		return payees[i].CreatedAt.Before(payees[j].CreatedAt)
+	})
+
+	return payees, nil
+}
+
+func (r repo) Update(req UpdateRequest) (domain.Payee, error) {
+	payeesMux.Lock()
+
+	defer payeesMux.Unlock()
+
+	if _, exists := r.payees[req.Payee.ID]; !exists {
+		return domain.Payee{}, errors.New("payee not found")
+	}
+
+	payee := req.Payee
+
+	payee.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(payee)
+
+	if err != nil {
+		return domain.Payee{}, err
+	}
+
+	r.payees[payee.ID] = payee
+
+	return payee, nil
+}
+
+func (r repo) Delete(req DeleteRequest) error {
+	payeesMux.Lock()
+
+	defer payeesMux.Unlock()
+
+	if _, exists := r.payees[req.ID]; !exists {
+		return errors.New("payee not found")
+	}
+
+	if r.log != nil {
+		err := r.log.Append(
+			wal.Record{
+				Kind:    walKind,
+				Key:     req.ID,
+				Deleted: true,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(r.payees, req.ID)
+
+	return nil
+}
+
+func (r repo) persist(payee domain.Payee) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(payee)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   payee.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	payeesMux.Lock()
+
+	defer payeesMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.payees))
+
+	for id, payee := range r.payees {
+		value, err := json.Marshal(payee)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,23 @@
+package payeerepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Payee domain.Payee
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+type ListRequest struct {
+	UserID string
+}
+
+type UpdateRequest struct {
+	Payee domain.Payee
+}
+
+type DeleteRequest struct {
+	ID string
+}
@@ -0,0 +1,249 @@
+package paymentrequestrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "payment_request"
+)
+
+var (
+	paymentRequestsMux sync.Mutex
+
+	ErrVersionConflict = errors.New("payment request changed")
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.PaymentRequest, error)
+	Read(ReadRequest) (domain.PaymentRequest, error)
+	List(ListRequest) ([]domain.PaymentRequest, error)
+	Update(UpdateRequest) (domain.PaymentRequest, error)
+}
+
+type repo struct {
+	paymentRequests map[string]domain.PaymentRequest
+	log             wal.Log
+}
+
+func New(
+	paymentRequests map[string]domain.PaymentRequest,
+) Repo {
+
+	return &repo{
+		paymentRequests: paymentRequests,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	paymentRequests := make(map[string]domain.PaymentRequest)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		var paymentRequest domain.PaymentRequest
+
+		err := json.Unmarshal(rec.Value, &paymentRequest)
+
+		if err != nil {
+			return err
+		}
+
+		paymentRequests[rec.Key] = paymentRequest
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		paymentRequests: paymentRequests,
+		log:             log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.PaymentRequest, error) {
+	paymentRequestsMux.Lock()
+
+	defer paymentRequestsMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.paymentRequests[id]; exists {
+		return domain.PaymentRequest{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	paymentRequest := req.PaymentRequest
+
+	paymentRequest.ID = id
+	paymentRequest.Version = 1
+	paymentRequest.CreatedAt = now
+	paymentRequest.UpdatedAt = now
+	paymentRequest.Payers = slices.Clone(req.PaymentRequest.Payers)
+
+	err := r.persist(paymentRequest)
+
+	if err != nil {
+		return domain.PaymentRequest{}, err
+	}
+
+	r.paymentRequests[id] = paymentRequest
+
+	return copyPaymentRequest(paymentRequest), nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.PaymentRequest, error) {
+	paymentRequestsMux.Lock()
+
+	defer paymentRequestsMux.Unlock()
+
+	paymentRequest, exists := r.paymentRequests[req.ID]
+
+	if !exists {
+		return domain.PaymentRequest{}, errors.New("payment request not found")
+	}
+
+	return copyPaymentRequest(paymentRequest), nil
+}
+
+// List returns the matching requests, newest first.
+func (r repo) List(req ListRequest) ([]domain.PaymentRequest, error) {
+	paymentRequestsMux.Lock()
+
+	defer paymentRequestsMux.Unlock()
+
+	paymentRequests := []domain.PaymentRequest{}
+
+	for _, paymentRequest := range r.paymentRequests {
+		if req.RequesterUserID != "" && paymentRequest.RequesterUserID != req.RequesterUserID {
+			continue
+		}
+
+		if req.PayerUserID != "" && !hasPayer(paymentRequest, req.PayerUserID) {
+			continue
+		}
+
+		paymentRequests = append(paymentRequests, copyPaymentRequest(paymentRequest))
+	}
+
+	sort.Slice(paymentRequests, func(i, j int) bool {
+		if paymentRequests[i].CreatedAt.Equal(paymentRequests[j].CreatedAt) {
+			return paymentRequests[i].ID < paymentRequests[j].ID
+		}
+
+		return paymentRequests[i].CreatedAt.After(paymentRequests[j].CreatedAt)
+	})
+
+	return paymentRequests, nil
+}
+
+// Update stores the request and bumps its version. It fails with
+// ErrVersionConflict when the request was updated since it was read.
+func (r repo) Update(req UpdateRequest) (domain.PaymentRequest, error) {
+	paymentRequestsMux.Lock()
+
+	defer paymentRequestsMux.Unlock()
+
+	stored, exists := r.paymentRequests[req.PaymentRequest.ID]
+
+	if !exists {
+		return domain.PaymentRequest{}, errors.New("payment request not found")
+	}
+
+	if stored.Version != req.PaymentRequest.Version {
+		return domain.PaymentRequest{}, ErrVersionConflict
+	}
+
+	paymentRequest := copyPaymentRequest(req.PaymentRequest)
+
+	paymentRequest.Version++
+	paymentRequest.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(paymentRequest)
+
+	if err != nil {
+		return domain.PaymentRequest{}, err
+	}
+
+	r.paymentRequests[paymentRequest.ID] = paymentRequest
+
+	return copyPaymentRequest(paymentRequest), nil
+}
+
+func (r repo) persist(paymentRequest domain.PaymentRequest) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(paymentRequest)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   paymentRequest.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	paymentRequestsMux.Lock()
+
+	defer paymentRequestsMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.paymentRequests))
+
+	for id, paymentRequest := range r.paymentRequests {
+		value, err := json.Marshal(paymentRequest)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func hasPayer(paymentRequest domain.PaymentRequest, userID string) bool {
+	for _, payer := range paymentRequest.Payers {
+		if payer.UserID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyPaymentRequest(paymentRequest domain.PaymentRequest) domain.PaymentRequest {
+	paymentRequest.Payers = slices.Clone(paymentRequest.Payers)
+
+	return paymentRequest
+}
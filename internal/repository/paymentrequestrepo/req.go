@@ -0,0 +1,24 @@
+package paymentrequestrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	PaymentRequest domain.PaymentRequest
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+// ListRequest matches requests made by RequesterUserID or addressed to
+// PayerUserID, whichever is set.
+type ListRequest struct {
+	RequesterUserID string
+	PayerUserID     string
+}
+
+// UpdateRequest replaces the stored request if its Version is still the one
+// given.
+type UpdateRequest struct {
+	PaymentRequest domain.PaymentRequest
+}
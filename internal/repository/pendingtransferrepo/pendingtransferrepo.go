@@ -0,0 +1,278 @@
+package pendingtransferrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "pending_transfer"
+)
+
+var (
+	pendingTransfersMux sync.Mutex
+
+	ErrVersionConflict = errors.New("pending transfer changed")
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.PendingTransfer, error)
+	Read(ReadRequest) (domain.PendingTransfer, error)
+	List(ListRequest) ([]domain.PendingTransfer, error)
+	Update(UpdateRequest) (domain.PendingTransfer, error)
+	Delete(DeleteRequest) error
+}
+
+type repo struct {
+	pendingTransfers map[string]domain.PendingTransfer
+	log              wal.Log
+}
+
+func New(
+	pendingTransfers map[string]domain.PendingTransfer,
+) Repo {
+
+	return &repo{
+		pendingTransfers: pendingTransfers,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	pendingTransfers := make(map[string]domain.PendingTransfer)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		if rec.Deleted {
+			delete(pendingTransfers, rec.Key)
+
+			return nil
+		}
+
+		var pendingTransfer domain.PendingTransfer
+
+		err := json.Unmarshal(rec.Value, &pendingTransfer)
+
+		if err != nil {
+			return err
+		}
+
+		pendingTransfers[rec.Key] = pendingTransfer
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		pendingTransfers: pendingTransfers,
+		log:              log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.PendingTransfer, error) {
+	pendingTransfersMux.Lock()
+
+	defer pendingTransfersMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.pendingTransfers[id]; exists {
+		return domain.PendingTransfer{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	pendingTransfer := req.PendingTransfer
+
+	pendingTransfer.ID = id
+	pendingTransfer.Version = 1
+	pendingTransfer.CreatedAt = now
+	pendingTransfer.UpdatedAt = now
+	pendingTransfer.Metadata = maps.Clone(req.PendingTransfer.Metadata)
+
+	err := r.persist(pendingTransfer)
+
+	if err != nil {
+		return domain.PendingTransfer{}, err
+	}
+
+	r.pendingTransfers[id] = pendingTransfer
+
+	return copyPendingTransfer(pendingTransfer), nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.PendingTransfer, error) {
+	pendingTransfersMux.Lock()
+
+	defer pendingTransfersMux.Unlock()
+
+	pendingTransfer, exists := r.pendingTransfers[req.ID]
+
+	if !exists {
+		return domain.PendingTransfer{}, errors.New("pending transfer not found")
+	}
+
+	return copyPendingTransfer(pendingTransfer), nil
+}
+
+// List returns the matching transfers, newest first.
+func (r repo) List(req ListRequest) ([]domain.PendingTransfer, error) {
+	pendingTransfersMux.Lock()
+
+	defer pendingTransfersMux.Unlock()
+
+	pendingTransfers := []domain.PendingTransfer{}
+
+	for _, pendingTransfer := range r.pendingTransfers {
+		if req.SenderUserID != "" && pendingTransfer.SenderUserID != req.SenderUserID {
+			continue
+		}
+
+		if req.ReceiverUserID != "" && pendingTransfer.ReceiverUserID != req.ReceiverUserID {
+			continue
+		}
+
+		if req.Status != "" && pendingTransfer.Status != req.Status {
+			continue
+		}
+
+		pendingTransfers = append(pendingTransfers, copyPendingTransfer(pendingTransfer))
+	}
+
+	sort.Slice(pendingTransfers, func(i, j int) bool {
+		if pendingTransfers[i].CreatedAt.Equal(pendingTransfers[j].CreatedAt) {
+			return pendingTransfers[i].ID < pendingTransfers[j].ID
+		}
+
+		return pendingTransfers[i].CreatedAt.After(pendingTransfers[j].CreatedAt)
+	})
+
+	return pendingTransfers, nil
+}
+
+// Update stores the transfer and bumps its version. It fails with
+// ErrVersionConflict when the transfer was updated since it was read.
+func (r repo) Update(req UpdateRequest) (domain.PendingTransfer, error) {
+	pendingTransfersMux.Lock()
+
+	defer pendingTransfersMux.Unlock()
+
+	stored, exists := r.pendingTransfers[req.PendingTransfer.ID]
+
+	if !exists {
+		return domain.PendingTransfer{}, errors.New("pending transfer not found")
+	}
+
+	if stored.Version != req.PendingTransfer.Version {
+		return domain.PendingTransfer{}, ErrVersionConflict
+	}
+
+	pendingTransfer := copyPendingTransfer(req.PendingTransfer)
+
+	pendingTransfer.Version++
+	pendingTransfer.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(pendingTransfer)
+
+	if err != nil {
+		return domain.PendingTransfer{}, err
+	}
+
+	r.pendingTransfers[pendingTransfer.ID] = pendingTransfer
+
+	return copyPendingTransfer(pendingTransfer), nil
+}
+
+func (r repo) Delete(req DeleteRequest) error {
+	pendingTransfersMux.Lock()
+
+	defer pendingTransfersMux.Unlock()
+
+	if _, exists := r.pendingTransfers[req.ID]; !exists {
+		return errors.New("pending transfer not found")
+	}
+
+	if r.log != nil {
+		err := r.log.Append(
+			wal.Record{
+				Kind:    walKind,
+				Key:     req.ID,
+				Deleted: true,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(r.pendingTransfers, req.ID)
+
+	return nil
+}
+
+func (r repo) persist(pendingTransfer domain.PendingTransfer) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(pendingTransfer)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   pendingTransfer.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	pendingTransfersMux.Lock()
+
+	defer pendingTransfersMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.pendingTransfers))
+
+	for id, pendingTransfer := range r.pendingTransfers {
+		value, err := json.Marshal(pendingTransfer)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyPendingTransfer(pendingTransfer domain.PendingTransfer) domain.PendingTransfer {
+	pendingTransfer.Metadata = maps.Clone(pendingTransfer.Metadata)
+
+	return pendingTransfer
+}
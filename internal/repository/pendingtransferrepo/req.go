@@ -0,0 +1,29 @@
+package pendingtransferrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	PendingTransfer domain.PendingTransfer
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+// ListRequest matches transfers from SenderUserID, to ReceiverUserID and in
+// Status, for whichever are set.
+type ListRequest struct {
+	SenderUserID   string
+	ReceiverUserID string
+	Status         domain.PendingTransferStatus
+}
+
+// UpdateRequest replaces the stored transfer if its Version is still the one
+// given.
+type UpdateRequest struct {
+	PendingTransfer domain.PendingTransfer
+}
+
+type DeleteRequest struct {
+	ID string
+}
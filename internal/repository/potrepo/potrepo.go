@@ -0,0 +1,245 @@
+package potrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+	"github.com/pborman/uuid"
+)
+
+const (
+	walKind = "pot"
+)
+
+var (
+	potsMux sync.Mutex
+
+	ErrVersionConflict = errors.New("pot changed")
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Pot, error)
+	Read(ReadRequest) (domain.Pot, error)
+	List(ListRequest) ([]domain.Pot, error)
+	Update(UpdateRequest) (domain.Pot, error)
+}
+
+type repo struct {
+	pots map[string]domain.Pot
+	log  wal.Log
+}
+
+func New(
+	pots map[string]domain.Pot,
+) Repo {
+
+	return &repo{
+		pots: pots,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	pots := make(map[string]domain.Pot)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		if rec.Deleted {
+			delete(pots, rec.Key)
+
+			return nil
+		}
+
+		var pot domain.Pot
+
+		err := json.Unmarshal(rec.Value, &pot)
+
+		if err != nil {
+			return err
+		}
+
+		pots[rec.Key] = pot
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		pots: pots,
+		log:  log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.Pot, error) {
+	potsMux.Lock()
+
+	defer potsMux.Unlock()
+
+	id := uuid.New()
+
+	if _, exists := r.pots[id]; exists {
+		return domain.Pot{}, errors.New("id in use")
+	}
+
+	now := time.Now().UTC()
+
+	pot := req.Pot
+
+	pot.ID = id
+	pot.Version = 1
+	pot.CreatedAt = now
+	pot.UpdatedAt = now
+	pot.AutoSave = slices.Clone(req.Pot.AutoSave)
+
+	err := r.persist(pot)
+
+	if err != nil {
+		return domain.Pot{}, err
+	}
+
+	r.pots[id] = pot
+
+	return copyPot(pot), nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Pot, error) {
+	potsMux.Lock()
+
+	defer potsMux.Unlock()
+
+	pot, exists := r.pots[req.ID]
+
+	if !exists {
+		return domain.Pot{}, errors.New("pot not found")
+	}
+
+	return copyPot(pot), nil
+}
+
+// List returns the matching pots, newest first.
+func (r repo) List(req ListRequest) ([]domain.Pot, error) {
+	potsMux.Lock()
+
+	defer potsMux.Unlock()
+
+	pots := []domain.Pot{}
+
+	for _, pot := range r.pots {
+		if req.AccountID != "" && pot.AccountID != req.AccountID {
+			continue
+		}
+
+		if req.Open && !pot.ClosedAt.IsZero() {
+			continue
+		}
+
+		pots = append(pots, copyPot(pot))
+	}
+
+	sort.Slice(pots, func(i, j int) bool {
+		if pots[i].CreatedAt.Equal(pots[j].CreatedAt) {
+			return pots[i].ID < pots[j].ID
+		}
+
+		return pots[i].CreatedAt.After(pots[j].CreatedAt)
+	})
+
+	return pots, nil
+}
+
+// Update stores the pot and bumps its version. It fails with
+// ErrVersionConflict when the pot was updated since it was read.
+func (r repo) Update(req UpdateRequest) (domain.Pot, error) {
+	potsMux.Lock()
+
+	defer potsMux.Unlock()
+
+	stored, exists := r.pots[req.Pot.ID]
+
+	if !exists {
+		return domain.Pot{}, errors.New("pot not found")
+	}
+
+	if stored.Version != req.Pot.Version {
+		return domain.Pot{}, ErrVersionConflict
+	}
+
+	pot := copyPot(req.Pot)
+
+	pot.Version++
+	pot.UpdatedAt = time.Now().UTC()
+
+	err := r.persist(pot)
+
+	if err != nil {
+		return domain.Pot{}, err
+	}
+
+	r.pots[pot.ID] = pot
+
+	return copyPot(pot), nil
+}
+
+func (r repo) persist(pot domain.Pot) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(pot)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   pot.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	potsMux.Lock()
+
+	defer potsMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.pots))
+
+	for id, pot := range r.pots {
+		value, err := json.Marshal(pot)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyPot(pot domain.Pot) domain.Pot {
+	pot.AutoSave = slices.Clone(pot.AutoSave)
+
+	return pot
+}
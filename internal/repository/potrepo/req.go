@@ -0,0 +1,24 @@
+package potrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Pot domain.Pot
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+// ListRequest matches pots in AccountID, when set, and leaves out closed
+// pots when Open is set.
+type ListRequest struct {
+	AccountID string
+	Open      bool
+}
+
+// UpdateRequest replaces the stored pot if its Version is still the one
+// given.
+type UpdateRequest struct {
+	Pot domain.Pot
+}
@@ -0,0 +1,219 @@
+package productrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+)
+
+const (
+	walKind = "product"
+)
+
+var (
+	productsMux sync.Mutex
+)
+
+type Repo interface {
+	Create(CreateRequest) (domain.Product, error)
+	Read(ReadRequest) (domain.Product, error)
+	List(ListRequest) ([]domain.Product, error)
+	Update(UpdateRequest) (domain.Product, error)
+}
+
+type repo struct {
+	products map[string][]domain.Product
+	log      wal.Log
+}
+
+func New(
+	products map[string][]domain.Product,
+) Repo {
+
+	return &repo{
+		products: products,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	products := make(map[string][]domain.Product)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		var versions []domain.Product
+
+		err := json.Unmarshal(rec.Value, &versions)
+
+		if err != nil {
+			return err
+		}
+
+		products[rec.Key] = versions
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		products: products,
+		log:      log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+func (r repo) Create(req CreateRequest) (domain.Product, error) {
+	productsMux.Lock()
+
+	defer productsMux.Unlock()
+
+	if _, exists := r.products[req.Product.ID]; exists {
+		return domain.Product{}, errors.New("duplicate product id")
+	}
+
+	product := req.Product
+
+	product.Version = 1
+	product.CreatedAt = time.Now().UTC()
+
+	versions := []domain.Product{product}
+
+	err := r.persist(product.ID, versions)
+
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	r.products[product.ID] = versions
+
+	return product, nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Product, error) {
+	productsMux.Lock()
+
+	defer productsMux.Unlock()
+
+	versions, exists := r.products[req.ID]
+
+	if !exists {
+		return domain.Product{}, errors.New("product not found")
+	}
+
+	if req.Version == 0 {
+		return versions[len(versions)-1], nil
+	}
+
+	if req.Version < 0 || req.Version > len(versions) {
+		return domain.Product{}, errors.New("product version not found")
+	}
+
+	return versions[req.Version-1], nil
+}
+
+func (r repo) List(req ListRequest) ([]domain.Product, error) {
+	productsMux.Lock()
+
+	defer productsMux.Unlock()
+
+	products := make([]domain.Product, 0, len(r.products))
+
+	for _, versions := range r.products {
+		products = append(products, versions[len(versions)-1])
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+
+	return products, nil
+}
+
+func (r repo) Update(req UpdateRequest) (domain.Product, error) {
+	productsMux.Lock()
+
+	defer productsMux.Unlock()
+
+	versions, exists := r.products[req.Product.ID]
+
+	if !exists {
+		return domain.Product{}, errors.New("product not found")
+	}
+
+	product := req.Product
+
+	product.Version = len(versions) + 1
+	product.CreatedAt = time.Now().UTC()
+
+	updated := make([]domain.Product, len(versions), len(versions)+1)
+
+	copy(updated, versions)
+
+	updated = append(updated, product)
+
+	err := r.persist(product.ID, updated)
+
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	r.products[product.ID] = updated
+
+	return product, nil
+}
+
+func (r repo) persist(id string, versions []domain.Product) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(versions)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   id,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	productsMux.Lock()
+
+	defer productsMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.products))
+
+	for id, versions := range r.products {
+		value, err := json.Marshal(versions)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
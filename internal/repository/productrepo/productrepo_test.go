@@ -0,0 +1,105 @@
+package productrepo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate_ErrDuplicateProductID(t *testing.T) {
+	repo := New(
+		map[string][]domain.Product{
+			"savings": {
+				{
+					ID:      "savings",
+					Version: 1,
+				},
+			},
+		},
+	)
+
+	res, err := repo.Create(
+		CreateRequest{
+			Product: domain.Product{
+				ID: "savings",
+			},
+		},
+	)
+
+	assert.Equal(t, domain.Product{}, res)
+	assert.Equal(t, errors.New("duplicate product id"), err)
+}
+
+func TestUpdate_ErrProductNotFound(t *testing.T) {
+	repo := New(make(map[string][]domain.Product))
+
+	res, err := repo.Update(
+		UpdateRequest{
+			Product: domain.Product{
+				ID: "savings",
+			},
+		},
+	)
+
+	assert.Equal(t, domain.Product{}, res)
+	assert.Equal(t, errors.New("product not found"), err)
+}
+
+func TestUpdate_Ok(t *testing.T) {
+	repo := New(make(map[string][]domain.Product))
+
+	created, err := repo.Create(
+		CreateRequest{
+			Product: domain.Product{
+				ID:             "savings",
+				MinimumBalance: money.New(10, money.EUR),
+			},
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, created.Version)
+
+	updated, err := repo.Update(
+		UpdateRequest{
+			Product: domain.Product{
+				ID:             "savings",
+				MinimumBalance: money.New(20, money.EUR),
+			},
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	latest, err := repo.Read(
+		ReadRequest{
+			ID: "savings",
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, updated, latest)
+
+	first, err := repo.Read(
+		ReadRequest{
+			ID:      "savings",
+			Version: 1,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, created, first)
+
+	_, err = repo.Read(
+		ReadRequest{
+			ID:      "savings",
+			Version: 3,
+		},
+	)
+
+	assert.Equal(t, errors.New("product version not found"), err)
+}
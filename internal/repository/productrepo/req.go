@@ -0,0 +1,18 @@
+package productrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type CreateRequest struct {
+	Product domain.Product
+}
+
+type ReadRequest struct {
+	ID      string
+	Version int
+}
+
+type ListRequest struct{}
+
+type UpdateRequest struct {
+	Product domain.Product
+}
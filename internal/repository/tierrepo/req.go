@@ -0,0 +1,13 @@
+package tierrepo
+
+import "github.com/hetfdex/tiny-bank/internal/domain"
+
+type PutRequest struct {
+	Tier domain.Tier
+}
+
+type ReadRequest struct {
+	ID string
+}
+
+type ListRequest struct{}
@@ -0,0 +1,185 @@
+package tierrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
+)
+
+const (
+	walKind = "tier"
+)
+
+var (
+	tiersMux sync.Mutex
+)
+
+type Repo interface {
+	Put(PutRequest) (domain.Tier, error)
+	Read(ReadRequest) (domain.Tier, error)
+	List(ListRequest) ([]domain.Tier, error)
+}
+
+type repo struct {
+	tiers map[string]domain.Tier
+	log   wal.Log
+}
+
+func New(
+	tiers map[string]domain.Tier,
+) Repo {
+
+	return &repo{
+		tiers: tiers,
+	}
+}
+
+func NewDurable(log wal.Log) (Repo, error) {
+	tiers := make(map[string]domain.Tier)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		var tier domain.Tier
+
+		err := json.Unmarshal(rec.Value, &tier)
+
+		if err != nil {
+			return err
+		}
+
+		tiers[rec.Key] = tier
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		tiers: tiers,
+		log:   log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
+// Put creates the tier or replaces its limits. Users reference tiers by id,
+// so a change applies to every user in the tier from the next operation.
+func (r repo) Put(req PutRequest) (domain.Tier, error) {
+	tiersMux.Lock()
+
+	defer tiersMux.Unlock()
+
+	tier := req.Tier
+
+	tier.UpdatedAt = time.Now().UTC()
+	tier.Limits = copyLimits(req.Tier.Limits)
+
+	err := r.persist(tier)
+
+	if err != nil {
+		return domain.Tier{}, err
+	}
+
+	r.tiers[tier.ID] = tier
+
+	return tier, nil
+}
+
+func (r repo) Read(req ReadRequest) (domain.Tier, error) {
+	tiersMux.Lock()
+
+	defer tiersMux.Unlock()
+
+	tier, exists := r.tiers[req.ID]
+
+	if !exists {
+		return domain.Tier{}, errors.New("tier not found")
+	}
+
+	tier.Limits = copyLimits(tier.Limits)
+
+	return tier, nil
+}
+
+func (r repo) List(req ListRequest) ([]domain.Tier, error) {
+	tiersMux.Lock()
+
+	defer tiersMux.Unlock()
+
+	tiers := make([]domain.Tier, 0, len(r.tiers))
+
+	for _, tier := range r.tiers {
+		tier.Limits = copyLimits(tier.Limits)
+
+		tiers = append(tiers, tier)
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].ID < tiers[j].ID
+	})
+
+	return tiers, nil
+}
+
+func (r repo) persist(tier domain.Tier) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(tier)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   tier.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	tiersMux.Lock()
+
+	defer tiersMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.tiers))
+
+	for id, tier := range r.tiers {
+		value, err := json.Marshal(tier)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
+
+func copyLimits(limits []domain.VelocityLimit) []domain.VelocityLimit {
+	copied := make([]domain.VelocityLimit, len(limits))
+
+	copy(copied, limits)
+
+	return copied
+}
@@ -1,19 +1,46 @@
 package userrepo
 
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+)
+
 type CreateRequest struct {
 	Name string
+	Tier string
 }
 
 type ReadRequest struct {
 	ID string
 }
 
+type ListRequest struct{}
+
 type UpdateStatusRequest struct {
-	ID     string
-	Active bool
+	ID                    string
+	Status                domain.UserStatus
+	PreviousStatus        domain.UserStatus
+	StatusReason          string
+	DeactivatedAt         time.Time
+	DeactivationReason    string
+	DeactivatedAccountIDs []string
+	ClosedAt              time.Time
 }
 
 type UpdateAccountIDsRequest struct {
 	ID        string
 	AccountID string
+	Remove    bool
+}
+
+type UpdateTierRequest struct {
+	ID   string
+	Tier string
+}
+
+type UpdateKYCRequest struct {
+	ID     string
+	KYC    domain.KYC
+	Status domain.UserStatus
 }
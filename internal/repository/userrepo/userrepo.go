@@ -1,14 +1,20 @@
 package userrepo
 
 import (
+	"encoding/json"
 	"errors"
 	"sync"
 	"time"
 
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
 	"github.com/pborman/uuid"
 )
 
+const (
+	walKind = "user"
+)
+
 var (
 	usersMux sync.Mutex
 )
@@ -16,12 +22,16 @@ var (
 type Repo interface {
 	Create(CreateRequest) (domain.User, error)
 	Read(ReadRequest) (domain.User, error)
+	List(ListRequest) ([]domain.User, error)
 	UpdateStatus(UpdateStatusRequest) error
 	UpdateAccountIDs(UpdateAccountIDsRequest) error
+	UpdateTier(UpdateTierRequest) error
+	UpdateKYC(UpdateKYCRequest) error
 }
 
 type repo struct {
 	users map[string]domain.User
+	log   wal.Log
 }
 
 func New(
@@ -33,6 +43,45 @@ func New(
 	}
 }
 
+func NewDurable(log wal.Log) (Repo, error) {
+	users := make(map[string]domain.User)
+
+	err := log.Replay(walKind, func(rec wal.Record) error {
+		var user domain.User
+
+		err := json.Unmarshal(rec.Value, &user)
+
+		if err != nil {
+			return err
+		}
+
+		if user.Status == "" {
+			user.Status, user.PreviousStatus, err = legacyStatus(rec.Value, user)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		users[rec.Key] = user
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &repo{
+		users: users,
+		log:   log,
+	}
+
+	log.Register(walKind, r.snapshot)
+
+	return r, nil
+}
+
 func (r repo) Create(req CreateRequest) (domain.User, error) {
 	usersMux.Lock()
 
@@ -47,9 +96,16 @@ func (r repo) Create(req CreateRequest) (domain.User, error) {
 	user := domain.User{
 		ID:         id,
 		CreatedAt:  time.Now().UTC(),
-		Active:     true,
+		Status:     domain.UserPending,
 		Name:       req.Name,
 		AccountIDs: map[string]struct{}{},
+		Tier:       req.Tier,
+	}
+
+	err := r.persist(user)
+
+	if err != nil {
+		return domain.User{}, err
 	}
 
 	r.users[id] = user
@@ -62,7 +118,21 @@ func (r repo) Read(req ReadRequest) (domain.User, error) {
 
 	defer usersMux.Unlock()
 
-	return r.getActiveUser(req.ID)
+	return r.getUser(req.ID)
+}
+
+func (r repo) List(req ListRequest) ([]domain.User, error) {
+	usersMux.Lock()
+
+	defer usersMux.Unlock()
+
+	users := make([]domain.User, 0, len(r.users))
+
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+
+	return users, nil
 }
 
 func (r repo) UpdateStatus(req UpdateStatusRequest) error {
@@ -70,13 +140,26 @@ func (r repo) UpdateStatus(req UpdateStatusRequest) error {
 
 	defer usersMux.Unlock()
 
-	user, exists := r.users[req.ID]
+	user, err := r.getOpenUser(req.ID)
 
-	if !exists {
-		return errors.New("user not found")
+	if err != nil {
+		return err
 	}
 
-	user.Active = req.Active
+	user.Status = req.Status
+	user.PreviousStatus = req.PreviousStatus
+	user.StatusReason = req.StatusReason
+	user.StatusUpdatedAt = time.Now().UTC()
+	user.DeactivatedAt = req.DeactivatedAt
+	user.DeactivationReason = req.DeactivationReason
+	user.DeactivatedAccountIDs = req.DeactivatedAccountIDs
+	user.ClosedAt = req.ClosedAt
+
+	err = r.persist(user)
+
+	if err != nil {
+		return err
+	}
 
 	r.users[req.ID] = user
 
@@ -88,7 +171,11 @@ func (r repo) UpdateAccountIDs(req UpdateAccountIDsRequest) error {
 
 	defer usersMux.Unlock()
 
-	user, err := r.getActiveUser(req.ID)
+	if req.Remove {
+		return r.removeAccountID(req.ID, req.AccountID)
+	}
+
+	user, err := r.getOpenUser(req.ID)
 
 	if err != nil {
 		return err
@@ -98,23 +185,201 @@ func (r repo) UpdateAccountIDs(req UpdateAccountIDsRequest) error {
 		return errors.New("duplicate account id")
 	}
 
-	user.AccountIDs[req.AccountID] = struct{}{}
+	accountIDs := make(map[string]struct{}, len(user.AccountIDs)+1)
+
+	for id := range user.AccountIDs {
+		accountIDs[id] = struct{}{}
+	}
+
+	accountIDs[req.AccountID] = struct{}{}
+
+	user.AccountIDs = accountIDs
+
+	err = r.persist(user)
+
+	if err != nil {
+		return err
+	}
+
+	r.users[req.ID] = user
+
+	return nil
+}
+
+func (r repo) UpdateTier(req UpdateTierRequest) error {
+	usersMux.Lock()
+
+	defer usersMux.Unlock()
+
+	user, err := r.getOpenUser(req.ID)
+
+	if err != nil {
+		return err
+	}
+
+	user.Tier = req.Tier
+
+	err = r.persist(user)
+
+	if err != nil {
+		return err
+	}
 
 	r.users[req.ID] = user
 
 	return nil
 }
 
-func (r repo) getActiveUser(id string) (domain.User, error) {
+func (r repo) UpdateKYC(req UpdateKYCRequest) error {
+	usersMux.Lock()
+
+	defer usersMux.Unlock()
+
+	user, err := r.getOpenUser(req.ID)
+
+	if err != nil {
+		return err
+	}
+
+	user.KYC = req.KYC
+	user.Status = req.Status
+	user.StatusReason = ""
+	user.StatusUpdatedAt = time.Now().UTC()
+
+	err = r.persist(user)
+
+	if err != nil {
+		return err
+	}
+
+	r.users[req.ID] = user
+
+	return nil
+}
+
+func (r repo) removeAccountID(id string, accountID string) error {
+	user, err := r.getUser(id)
+
+	if err != nil {
+		return err
+	}
+
+	if _, exists := user.AccountIDs[accountID]; !exists {
+		return errors.New("account id not found")
+	}
+
+	accountIDs := make(map[string]struct{}, len(user.AccountIDs))
+
+	for id := range user.AccountIDs {
+		if id != accountID {
+			accountIDs[id] = struct{}{}
+		}
+	}
+
+	user.AccountIDs = accountIDs
+
+	err = r.persist(user)
+
+	if err != nil {
+		return err
+	}
+
+	r.users[id] = user
+
+	return nil
+}
+
+func (r repo) getUser(id string) (domain.User, error) {
 	user, exists := r.users[id]
 
 	if !exists {
 		return domain.User{}, errors.New("user not found")
 	}
 
-	if !user.Active {
-		return domain.User{}, errors.New("user not active")
+	return user, nil
+}
+
+func (r repo) getOpenUser(id string) (domain.User, error) {
+	user, err := r.getUser(id)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if user.Status == domain.UserClosed {
+		return domain.User{}, errors.New("user closed")
 	}
 
 	return user, nil
 }
+
+// legacyStatus maps a user logged before onboarding statuses existed onto
+// them: active users were fully onboarded and deactivated ones suspended.
+func legacyStatus(value json.RawMessage, user domain.User) (domain.UserStatus, domain.UserStatus, error) {
+	var legacy struct {
+		Active bool
+	}
+
+	err := json.Unmarshal(value, &legacy)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if !user.ClosedAt.IsZero() {
+		return domain.UserClosed, "", nil
+	}
+
+	if legacy.Active {
+		return domain.UserVerified, "", nil
+	}
+
+	return domain.UserSuspended, domain.UserVerified, nil
+}
+
+func (r repo) persist(user domain.User) error {
+	if r.log == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(user)
+
+	if err != nil {
+		return err
+	}
+
+	return r.log.Append(
+		wal.Record{
+			Kind:  walKind,
+			Key:   user.ID,
+			Value: value,
+		},
+	)
+}
+
+func (r repo) snapshot() ([]wal.Record, error) {
+	usersMux.Lock()
+
+	defer usersMux.Unlock()
+
+	records := make([]wal.Record, 0, len(r.users))
+
+	for id, user := range r.users {
+		value, err := json.Marshal(user)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(
+			records,
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: value,
+			},
+		)
+	}
+
+	return records, nil
+}
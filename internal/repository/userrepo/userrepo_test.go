@@ -1,11 +1,13 @@
 package userrepo
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,7 +22,7 @@ func TestCreate_Ok(t *testing.T) {
 
 	assert.NotEmpty(t, res.ID)
 	assert.NotEmpty(t, res.CreatedAt)
-	assert.True(t, res.Active)
+	assert.Equal(t, domain.UserPending, res.Status)
 	assert.Equal(t, "joe", res.Name)
 	assert.Equal(t, 0, len(res.AccountIDs))
 
@@ -40,11 +42,11 @@ func TestRead_ErrUserNotFound(t *testing.T) {
 	assert.Equal(t, errors.New("user not found"), err)
 }
 
-func TestRead_ErrUserNotActive(t *testing.T) {
+func TestRead_Ok(t *testing.T) {
 	user := domain.User{
 		ID:        "1234",
 		CreatedAt: time.Now().UTC(),
-		Active:    false,
+		Status:    domain.UserVerified,
 		Name:      "joe",
 		AccountIDs: map[string]struct{}{
 			"5678": {},
@@ -63,72 +65,106 @@ func TestRead_ErrUserNotActive(t *testing.T) {
 		},
 	)
 
-	assert.Equal(t, domain.User{}, res)
-	assert.Equal(t, errors.New("user not active"), err)
+	assert.Equal(t, user, res)
+	assert.Nil(t, err)
 }
 
-func TestRead_Ok(t *testing.T) {
-	user := domain.User{
+func TestUpdateStatus_ErrUserNotFound(t *testing.T) {
+	repo := New(make(map[string]domain.User))
+
+	err := repo.UpdateStatus(
+		UpdateStatusRequest{
+			ID: "1234",
+		},
+	)
+
+	assert.Equal(t, errors.New("user not found"), err)
+}
+
+func TestUpdateStatus_Ok(t *testing.T) {
+	users := make(map[string]domain.User)
+
+	users["1234"] = domain.User{
 		ID:        "1234",
 		CreatedAt: time.Now().UTC(),
-		Active:    true,
+		Status:    domain.UserVerified,
 		Name:      "joe",
 		AccountIDs: map[string]struct{}{
 			"5678": {},
 		},
 	}
 
-	users := make(map[string]domain.User)
-
-	users["1234"] = user
-
 	repo := New(users)
 
-	res, err := repo.Read(
-		ReadRequest{
-			ID: "1234",
+	err := repo.UpdateStatus(
+		UpdateStatusRequest{
+			ID:     "1234",
+			Status: domain.UserSuspended,
 		},
 	)
 
-	assert.Equal(t, user, res)
 	assert.Nil(t, err)
 }
 
-func TestUpdateStatus_ErrUserNotFound(t *testing.T) {
-	repo := New(make(map[string]domain.User))
+func TestUpdateStatus_ErrUserClosed(t *testing.T) {
+	users := make(map[string]domain.User)
+
+	users["1234"] = domain.User{
+		ID:        "1234",
+		CreatedAt: time.Now().UTC(),
+		Status:    domain.UserClosed,
+		Name:      "joe",
+	}
+
+	repo := New(users)
 
 	err := repo.UpdateStatus(
 		UpdateStatusRequest{
-			ID: "1234",
+			ID:     "1234",
+			Status: domain.UserVerified,
 		},
 	)
 
-	assert.Equal(t, errors.New("user not found"), err)
+	assert.Equal(t, errors.New("user closed"), err)
 }
 
-func TestUpdateStatus_Ok(t *testing.T) {
+func TestUpdateKYC_Ok(t *testing.T) {
 	users := make(map[string]domain.User)
 
 	users["1234"] = domain.User{
 		ID:        "1234",
 		CreatedAt: time.Now().UTC(),
-		Active:    true,
+		Status:    domain.UserPending,
 		Name:      "joe",
-		AccountIDs: map[string]struct{}{
-			"5678": {},
-		},
 	}
 
 	repo := New(users)
 
-	err := repo.UpdateStatus(
-		UpdateStatusRequest{
+	kyc := domain.KYC{
+		DateOfBirth: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+		IDNumber:    "X1234567",
+	}
+
+	err := repo.UpdateKYC(
+		UpdateKYCRequest{
 			ID:     "1234",
-			Active: false,
+			KYC:    kyc,
+			Status: domain.UserKYCInReview,
+		},
+	)
+
+	assert.Nil(t, err)
+
+	res, err := repo.Read(
+		ReadRequest{
+			ID: "1234",
 		},
 	)
 
 	assert.Nil(t, err)
+	assert.Equal(t, kyc, res.KYC)
+	assert.Equal(t, domain.UserKYCInReview, res.Status)
+	assert.NotEmpty(t, res.StatusUpdatedAt)
 }
 
 func TestUpdateAccountIDs_ErrUserNotFound(t *testing.T) {
@@ -149,7 +185,7 @@ func TestUpdateAccountIDs_ErrDuplicateAccountID(t *testing.T) {
 	users["1234"] = domain.User{
 		ID:        "1234",
 		CreatedAt: time.Now().UTC(),
-		Active:    true,
+		Status:    domain.UserVerified,
 		Name:      "joe",
 		AccountIDs: map[string]struct{}{
 			"5678": {},
@@ -174,7 +210,7 @@ func TestUpdatAccountIDs_Ok(t *testing.T) {
 	users["1234"] = domain.User{
 		ID:        "1234",
 		CreatedAt: time.Now().UTC(),
-		Active:    true,
+		Status:    domain.UserVerified,
 		Name:      "joe",
 		AccountIDs: map[string]struct{}{
 			"5678": {},
@@ -192,3 +228,121 @@ func TestUpdatAccountIDs_Ok(t *testing.T) {
 
 	assert.Nil(t, err)
 }
+
+func TestNewDurable_Ok(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := wal.Open(
+		wal.Config{
+			Dir:        dir,
+			SyncPolicy: wal.SyncAlways,
+		},
+	)
+
+	assert.Nil(t, err)
+
+	repo, err := NewDurable(log)
+
+	assert.Nil(t, err)
+
+	user, err := repo.Create(
+		CreateRequest{
+			Name: "joe",
+		},
+	)
+
+	assert.Nil(t, err)
+
+	err = repo.UpdateAccountIDs(
+		UpdateAccountIDsRequest{
+			ID:        user.ID,
+			AccountID: "5678",
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Nil(t, log.Close())
+
+	log, err = wal.Open(
+		wal.Config{
+			Dir:        dir,
+			SyncPolicy: wal.SyncAlways,
+		},
+	)
+
+	assert.Nil(t, err)
+
+	defer log.Close()
+
+	repo, err = NewDurable(log)
+
+	assert.Nil(t, err)
+
+	res, err := repo.Read(
+		ReadRequest{
+			ID: user.ID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, res.ID)
+	assert.Equal(t, "joe", res.Name)
+	assert.Equal(t, map[string]struct{}{"5678": {}}, res.AccountIDs)
+}
+
+func TestNewDurable_LegacyStatus(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := wal.Open(
+		wal.Config{
+			Dir:        dir,
+			SyncPolicy: wal.SyncAlways,
+		},
+	)
+
+	assert.Nil(t, err)
+
+	defer log.Close()
+
+	legacy := map[string]string{
+		"1": `{"ID":"1","Active":true,"Name":"joe"}`,
+		"2": `{"ID":"2","Active":false,"Name":"ann","DeactivatedAt":"2024-01-01T00:00:00Z"}`,
+		"3": `{"ID":"3","Active":false,"Name":"bob","ClosedAt":"2024-02-01T00:00:00Z"}`,
+	}
+
+	for id, value := range legacy {
+		err = log.Append(
+			wal.Record{
+				Kind:  walKind,
+				Key:   id,
+				Value: json.RawMessage(value),
+			},
+		)
+
+		assert.Nil(t, err)
+	}
+
+	repo, err := NewDurable(log)
+
+	assert.Nil(t, err)
+
+	users, err := repo.List(ListRequest{})
+
+	assert.Nil(t, err)
+
+	statuses := map[string][2]domain.UserStatus{}
+
+	for _, user := range users {
+		statuses[user.ID] = [2]domain.UserStatus{user.Status, user.PreviousStatus}
+	}
+
+	assert.Equal(
+		t,
+		map[string][2]domain.UserStatus{
+			"1": {domain.UserVerified, ""},
+			"2": {domain.UserSuspended, domain.UserVerified},
+			"3": {domain.UserClosed, ""},
+		},
+		statuses,
+	)
+}
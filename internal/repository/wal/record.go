@@ -0,0 +1,93 @@
+package wal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	headerSize = 8
+	maxRecord  = 16 << 20
+)
+
+var (
+	ErrRecordTooLarge = errors.New("record too large")
+	ErrCorruptLog     = errors.New("corrupt log")
+
+	errTornRecord    = errors.New("torn record")
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+type Record struct {
+	Kind    string          `json:"kind"`
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Deleted bool            `json:"deleted,omitempty"`
+}
+
+func encode(rec Record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) > maxRecord {
+		return nil, ErrRecordTooLarge
+	}
+
+	buf := make([]byte, headerSize+len(payload))
+
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+
+	copy(buf[headerSize:], payload)
+
+	return buf, nil
+}
+
+func decode(r io.Reader) (Record, int64, error) {
+	header := make([]byte, headerSize)
+
+	n, err := io.ReadFull(r, header)
+
+	if err == io.EOF {
+		return Record{}, 0, io.EOF
+	}
+
+	if err != nil {
+		return Record{}, int64(n), errTornRecord
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+
+	if size > maxRecord {
+		return Record{}, headerSize, errCorruptRecord
+	}
+
+	payload := make([]byte, size)
+
+	m, err := io.ReadFull(r, payload)
+
+	if err != nil {
+		return Record{}, int64(headerSize + m), errTornRecord
+	}
+
+	if crc32.ChecksumIEEE(payload) != sum {
+		return Record{}, int64(headerSize + m), errCorruptRecord
+	}
+
+	var rec Record
+
+	err = json.Unmarshal(payload, &rec)
+
+	if err != nil {
+		return Record{}, int64(headerSize + m), errCorruptRecord
+	}
+
+	return rec, int64(headerSize + m), nil
+}
@@ -0,0 +1,747 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	logFile      = "wal.log"
+	oldLogFile   = "wal.log.old"
+	snapshotFile = "snapshot"
+	tmpSuffix    = ".tmp"
+
+	// metaKind records are the log's own bookkeeping and are never replayed
+	// to a store. Every log file starts with its generation, and the
+	// snapshot with the last generation it covers.
+	metaKind      = "wal"
+	generationKey = "generation"
+)
+
+// The steps of Snapshot, for tests to crash after.
+const (
+	stepRotated  = "rotated"
+	stepReopened = "reopened"
+	stepWritten  = "written"
+)
+
+type SyncPolicy string
+
+const (
+	SyncAlways   SyncPolicy = "always"
+	SyncBatch    SyncPolicy = "batch"
+	SyncInterval SyncPolicy = "interval"
+)
+
+type Config struct {
+	Dir          string
+	SyncPolicy   SyncPolicy
+	BatchSize    int
+	SyncInterval time.Duration
+}
+
+type SnapshotFunc func() ([]Record, error)
+
+type Log interface {
+	Append(Record) error
+	Replay(kind string, apply func(Record) error) error
+	Register(kind string, fn SnapshotFunc)
+	Snapshot() error
+	Close() error
+}
+
+type log struct {
+	cfg       Config
+	mux       sync.Mutex
+	snapMux   sync.Mutex
+	file      *os.File
+	pending   int
+	snapshots map[string]SnapshotFunc
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	// generation is the highest generation in the current log file, and
+	// err the first failed sync, after which no write is accepted.
+	generation int
+	err        error
+
+	afterStep func(step string) error
+}
+
+func Open(cfg Config) (Log, error) {
+	switch cfg.SyncPolicy {
+	case SyncAlways:
+	case SyncBatch:
+		if cfg.BatchSize <= 0 {
+			return nil, errors.New("invalid batch size")
+		}
+	case SyncInterval:
+		if cfg.SyncInterval <= 0 {
+			return nil, errors.New("invalid sync interval")
+		}
+	default:
+		return nil, errors.New("invalid sync policy")
+	}
+
+	err := os.MkdirAll(cfg.Dir, 0o755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	covered, err := recoverLog(cfg.Dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	generation, records, err := fileGeneration(filepath.Join(cfg.Dir, logFile))
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(cfg.Dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if records == 0 {
+		generation = max(covered, 0) + 1
+
+		err = writeGeneration(file, generation)
+
+		if err != nil {
+			file.Close()
+
+			return nil, err
+		}
+	}
+
+	l := &log{
+		cfg:        cfg,
+		file:       file,
+		snapshots:  map[string]SnapshotFunc{},
+		done:       make(chan struct{}),
+		generation: generation,
+	}
+
+	if cfg.SyncPolicy == SyncInterval {
+		l.wg.Add(1)
+
+		go l.syncLoop()
+	}
+
+	return l, nil
+}
+
+func (l *log) Append(rec Record) error {
+	buf, err := encode(rec)
+
+	if err != nil {
+		return err
+	}
+
+	l.mux.Lock()
+
+	defer l.mux.Unlock()
+
+	if l.file == nil {
+		return errors.New("log closed")
+	}
+
+	if l.err != nil {
+		return l.err
+	}
+
+	_, err = l.file.Write(buf)
+
+	if err != nil {
+		return err
+	}
+
+	switch l.cfg.SyncPolicy {
+	case SyncAlways:
+		return l.sync()
+	case SyncBatch:
+		l.pending++
+
+		if l.pending >= l.cfg.BatchSize {
+			l.pending = 0
+
+			return l.sync()
+		}
+	default:
+		l.pending++
+	}
+
+	return nil
+}
+
+func (l *log) Replay(kind string, apply func(Record) error) error {
+	for _, name := range []string{snapshotFile, logFile} {
+		_, err := readFile(filepath.Join(l.cfg.Dir, name), func(rec Record) error {
+			if rec.Kind != kind {
+				return nil
+			}
+
+			return apply(rec)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *log) Register(kind string, fn SnapshotFunc) {
+	l.mux.Lock()
+
+	defer l.mux.Unlock()
+
+	l.snapshots[kind] = fn
+}
+
+func (l *log) Snapshot() error {
+	l.snapMux.Lock()
+
+	defer l.snapMux.Unlock()
+
+	snapshots, covered, err := l.rotate()
+
+	if err != nil {
+		return err
+	}
+
+	kinds := make([]string, 0, len(snapshots))
+
+	for kind := range snapshots {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	records := []Record{
+		generationRecord(covered),
+	}
+
+	for _, kind := range kinds {
+		recs, err := snapshots[kind]()
+
+		if err != nil {
+			return err
+		}
+
+		records = append(records, recs...)
+	}
+
+	err = writeFile(filepath.Join(l.cfg.Dir, snapshotFile), records)
+
+	if err != nil {
+		return err
+	}
+
+	err = l.step(stepWritten)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(l.cfg.Dir, oldLogFile))
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return syncDir(l.cfg.Dir)
+}
+
+func (l *log) Close() error {
+	l.mux.Lock()
+
+	if l.file == nil {
+		l.mux.Unlock()
+
+		return nil
+	}
+
+	close(l.done)
+
+	l.mux.Unlock()
+
+	l.wg.Wait()
+
+	l.mux.Lock()
+
+	defer l.mux.Unlock()
+
+	err := l.err
+
+	if err == nil {
+		err = l.file.Sync()
+	}
+
+	err = errors.Join(err, l.file.Close())
+
+	l.file = nil
+
+	return err
+}
+
+// rotate moves the log aside for Snapshot and starts the next generation.
+// It returns the snapshot funcs and the generation the snapshot will cover.
+func (l *log) rotate() (map[string]SnapshotFunc, int, error) {
+	l.mux.Lock()
+
+	defer l.mux.Unlock()
+
+	if l.file == nil {
+		return nil, 0, errors.New("log closed")
+	}
+
+	if l.err != nil {
+		return nil, 0, l.err
+	}
+
+	err := l.sync()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = l.file.Close()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	l.file = nil
+	l.pending = 0
+
+	err = os.Rename(filepath.Join(l.cfg.Dir, logFile), filepath.Join(l.cfg.Dir, oldLogFile))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = l.step(stepRotated)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(l.cfg.Dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	covered := l.generation
+
+	err = writeGeneration(file, covered+1)
+
+	if err != nil {
+		file.Close()
+
+		return nil, 0, err
+	}
+
+	l.file = file
+	l.generation = covered + 1
+
+	snapshots := make(map[string]SnapshotFunc, len(l.snapshots))
+
+	for kind, fn := range l.snapshots {
+		snapshots[kind] = fn
+	}
+
+	err = syncDir(l.cfg.Dir)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snapshots, covered, l.step(stepReopened)
+}
+
+// sync flushes the log file. A failed fsync may have dropped writes the
+// log already acknowledged, so the error sticks and every later write and
+// Close reports it.
+func (l *log) sync() error {
+	err := l.file.Sync()
+
+	if err != nil {
+		l.err = err
+	}
+
+	return err
+}
+
+func (l *log) step(name string) error {
+	if l.afterStep == nil {
+		return nil
+	}
+
+	return l.afterStep(name)
+}
+
+func (l *log) syncLoop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.cfg.SyncInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			l.mux.Lock()
+
+			if l.file != nil && l.err == nil && l.pending > 0 {
+				l.pending = 0
+
+				l.sync()
+			}
+
+			l.mux.Unlock()
+		}
+	}
+}
+
+// recoverLog finishes a Snapshot that was cut short and returns the last
+// generation the snapshot covers, or -1 without one. A moved-aside log the
+// snapshot already covers is dropped, as replaying it would undo newer
+// state; one it does not is folded back in front of the current log.
+func recoverLog(dir string) (int, error) {
+	logPath := filepath.Join(dir, logFile)
+	oldPath := filepath.Join(dir, oldLogFile)
+
+	covered, err := snapshotGeneration(filepath.Join(dir, snapshotFile))
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = os.Stat(oldPath)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return covered, truncateTorn(logPath)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	generation, _, err := fileGeneration(oldPath)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if generation > covered {
+		var records []Record
+
+		for _, path := range []string{oldPath, logPath} {
+			_, err := readFile(path, func(rec Record) error {
+				records = append(records, rec)
+
+				return nil
+			})
+
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		err = writeFile(logPath, records)
+
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		err = truncateTorn(logPath)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = os.Remove(oldPath)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return covered, syncDir(dir)
+}
+
+// snapshotGeneration reads the generation a snapshot covers. A snapshot
+// written before generations were recorded covers none.
+func snapshotGeneration(path string) (int, error) {
+	covered := -1
+
+	_, err := readFile(path, func(rec Record) error {
+		generation, ok, err := recordGeneration(rec)
+
+		if ok && covered < 0 {
+			covered = generation
+		}
+
+		return err
+	})
+
+	return covered, err
+}
+
+// fileGeneration returns the highest generation in a log file, zero for a
+// log written before generations were recorded, and how many records it
+// holds.
+func fileGeneration(path string) (int, int, error) {
+	generation := 0
+	records := 0
+
+	_, err := readFile(path, func(rec Record) error {
+		records++
+
+		value, ok, err := recordGeneration(rec)
+
+		if ok {
+			generation = max(generation, value)
+		}
+
+		return err
+	})
+
+	return generation, records, err
+}
+
+func recordGeneration(rec Record) (int, bool, error) {
+	if rec.Kind != metaKind || rec.Key != generationKey {
+		return 0, false, nil
+	}
+
+	var generation int
+
+	err := json.Unmarshal(rec.Value, &generation)
+
+	return generation, err == nil, err
+}
+
+func generationRecord(generation int) Record {
+	return Record{
+		Kind:  metaKind,
+		Key:   generationKey,
+		Value: json.RawMessage(strconv.Itoa(generation)),
+	}
+}
+
+func writeGeneration(file *os.File, generation int) error {
+	buf, err := encode(generationRecord(generation))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(buf)
+
+	if err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+func truncateTorn(path string) error {
+	valid, err := readFile(path, func(Record) error {
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == valid {
+		return nil
+	}
+
+	return os.Truncate(path, valid)
+}
+
+func readFile(path string, apply func(Record) error) (int64, error) {
+	file, err := os.Open(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(file)
+
+	var offset int64
+
+	for {
+		rec, n, err := decode(reader)
+
+		if err == io.EOF {
+			return offset, nil
+		}
+
+		if errors.Is(err, errTornRecord) {
+			return offset, nil
+		}
+
+		if errors.Is(err, errCorruptRecord) {
+			tail, err := blankTail(file, offset+n, info.Size())
+
+			if err != nil {
+				return offset, err
+			}
+
+			if !tail {
+				return offset, fmt.Errorf("%w: %s at offset %d", ErrCorruptLog, path, offset)
+			}
+
+			return offset, nil
+		}
+
+		if err != nil {
+			return offset, err
+		}
+
+		err = apply(rec)
+
+		if err != nil {
+			return offset, err
+		}
+
+		offset += n
+	}
+}
+
+// blankTail reports whether nothing but zeros follows a corrupt record, as
+// when a crash leaves the end of the file allocated but unwritten. Anything
+// else means the log was damaged and must not be cut short.
+func blankTail(file *os.File, from int64, size int64) (bool, error) {
+	if from >= size {
+		return true, nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(file, from, size-from))
+
+	for {
+		b, err := reader.ReadByte()
+
+		if err == io.EOF {
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+func writeFile(path string, records []Record) error {
+	tmpPath := path + tmpSuffix
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+
+	for _, rec := range records {
+		buf, err := encode(rec)
+
+		if err != nil {
+			file.Close()
+
+			return err
+		}
+
+		_, err = writer.Write(buf)
+
+		if err != nil {
+			file.Close()
+
+			return err
+		}
+	}
+
+	err = writer.Flush()
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	err = file.Sync()
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	err = file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
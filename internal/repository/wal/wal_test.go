@@ -0,0 +1,414 @@
+package wal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_ErrInvalidSyncPolicy(t *testing.T) {
+	res, err := Open(
+		Config{
+			Dir: t.TempDir(),
+		},
+	)
+
+	assert.Nil(t, res)
+	assert.EqualError(t, err, "invalid sync policy")
+}
+
+func TestOpen_ErrInvalidBatchSize(t *testing.T) {
+	res, err := Open(
+		Config{
+			Dir:        t.TempDir(),
+			SyncPolicy: SyncBatch,
+		},
+	)
+
+	assert.Nil(t, res)
+	assert.EqualError(t, err, "invalid batch size")
+}
+
+func TestReplay_Ok(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Append(makeRecord("account", "2", "x")))
+	assert.Nil(t, log.Append(makeRecord("user", "1", "jane")))
+	assert.Nil(t, log.Close())
+
+	log = openLog(t, dir)
+
+	assert.Equal(t, []string{"joe", "jane"}, replayValues(t, log, "user"))
+	assert.Equal(t, []string{"x"}, replayValues(t, log, "account"))
+}
+
+func TestReplay_SkipsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Close())
+
+	file, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_APPEND, 0o644)
+
+	assert.Nil(t, err)
+
+	buf, err := encode(makeRecord("user", "2", "jane"))
+
+	assert.Nil(t, err)
+
+	_, err = file.Write(buf[:len(buf)-3])
+
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	log = openLog(t, dir)
+
+	assert.Equal(t, []string{"joe"}, replayValues(t, log, "user"))
+
+	assert.Nil(t, log.Append(makeRecord("user", "3", "bob")))
+
+	assert.Equal(t, []string{"joe", "bob"}, replayValues(t, log, "user"))
+}
+
+func TestReplay_SkipsCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Append(makeRecord("user", "2", "jane")))
+	assert.Nil(t, log.Close())
+
+	path := filepath.Join(dir, logFile)
+
+	data, err := os.ReadFile(path)
+
+	assert.Nil(t, err)
+
+	data[len(data)-2] ^= 0xff
+
+	assert.Nil(t, os.WriteFile(path, data, 0o644))
+
+	log = openLog(t, dir)
+
+	assert.Equal(t, []string{"joe"}, replayValues(t, log, "user"))
+}
+
+func TestOpen_ErrCorruptRecordMidLog(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Append(makeRecord("user", "2", "jane")))
+	assert.Nil(t, log.Close())
+
+	path := filepath.Join(dir, logFile)
+
+	data, err := os.ReadFile(path)
+
+	assert.Nil(t, err)
+
+	first, err := encode(generationRecord(1))
+
+	assert.Nil(t, err)
+
+	data[len(first)+headerSize+2] ^= 0xff
+
+	assert.Nil(t, os.WriteFile(path, data, 0o644))
+
+	res, err := Open(
+		Config{
+			Dir:        dir,
+			SyncPolicy: SyncAlways,
+		},
+	)
+
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, ErrCorruptLog)
+
+	after, err := os.ReadFile(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, data, after)
+}
+
+func TestReplay_SkipsZeroedTail(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Close())
+
+	file, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_APPEND, 0o644)
+
+	assert.Nil(t, err)
+
+	_, err = file.Write(make([]byte, 64))
+
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	log = openLog(t, dir)
+
+	assert.Equal(t, []string{"joe"}, replayValues(t, log, "user"))
+}
+
+func TestAppend_ErrRecordTooLarge(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	err := log.Append(makeRecord("user", "1", strings.Repeat("a", maxRecord)))
+
+	assert.ErrorIs(t, err, ErrRecordTooLarge)
+
+	assert.Nil(t, log.Append(makeRecord("user", "2", "joe")))
+	assert.Nil(t, log.Close())
+
+	log = openLog(t, dir)
+
+	assert.Equal(t, []string{"joe"}, replayValues(t, log, "user"))
+}
+
+func TestSnapshot_Ok(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Append(makeRecord("user", "1", "jane")))
+
+	log.Register("user", func() ([]Record, error) {
+		return []Record{makeRecord("user", "1", "jane")}, nil
+	})
+
+	assert.Nil(t, log.Snapshot())
+
+	generation, records, err := fileGeneration(filepath.Join(dir, logFile))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, generation)
+	assert.Equal(t, 1, records)
+
+	assert.Nil(t, log.Append(makeRecord("user", "2", "bob")))
+	assert.Nil(t, log.Close())
+
+	log = openLog(t, dir)
+
+	assert.Equal(t, []string{"jane", "bob"}, replayValues(t, log, "user"))
+}
+
+func TestOpen_RecoversInterruptedSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	log := openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "1", "joe")))
+	assert.Nil(t, log.Close())
+
+	assert.Nil(t, os.Rename(filepath.Join(dir, logFile), filepath.Join(dir, oldLogFile)))
+
+	log = openLog(t, dir)
+
+	assert.Nil(t, log.Append(makeRecord("user", "2", "bob")))
+
+	_, err := os.Stat(filepath.Join(dir, oldLogFile))
+
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, []string{"joe", "bob"}, replayValues(t, log, "user"))
+}
+
+func TestSnapshot_Crash(t *testing.T) {
+	for _, step := range []string{stepRotated, stepReopened, stepWritten} {
+		t.Run(step, func(t *testing.T) {
+			dir := t.TempDir()
+
+			l := openLog(t, dir).(*log)
+
+			state := map[string]string{}
+
+			put := func(key string, value string) {
+				state[key] = value
+
+				assert.Nil(t, l.Append(makeRecord("user", key, value)))
+			}
+
+			put("1", "joe")
+			put("1", "jane")
+			put("2", "bob")
+
+			l.Register("user", func() ([]Record, error) {
+				var records []Record
+
+				for key, value := range state {
+					records = append(records, makeRecord("user", key, value))
+				}
+
+				return records, nil
+			})
+
+			errCrash := errors.New("crash")
+
+			l.afterStep = func(name string) error {
+				if name == step {
+					return errCrash
+				}
+
+				return nil
+			}
+
+			assert.Equal(t, errCrash, l.Snapshot())
+
+			if l.file != nil {
+				l.file.Close()
+
+				l.file = nil
+			}
+
+			reopened := openLog(t, dir)
+
+			assert.Equal(t, map[string]string{"1": "jane", "2": "bob"}, replayState(t, reopened, "user"))
+
+			_, err := os.Stat(filepath.Join(dir, oldLogFile))
+
+			assert.True(t, os.IsNotExist(err))
+
+			state = replayState(t, reopened, "user")
+
+			reopened.Register("user", l.snapshots["user"])
+
+			put = func(key string, value string) {
+				state[key] = value
+
+				assert.Nil(t, reopened.Append(makeRecord("user", key, value)))
+			}
+
+			put("1", "mary")
+
+			assert.Nil(t, reopened.Snapshot())
+			assert.Nil(t, reopened.Close())
+
+			assert.Equal(t, map[string]string{"1": "mary", "2": "bob"}, replayState(t, openLog(t, dir), "user"))
+		})
+	}
+}
+
+func TestAppend_ErrAfterFailedSync(t *testing.T) {
+	l, err := Open(
+		Config{
+			Dir:          t.TempDir(),
+			SyncPolicy:   SyncInterval,
+			SyncInterval: time.Millisecond,
+		},
+	)
+
+	assert.Nil(t, err)
+
+	wl := l.(*log)
+
+	wl.mux.Lock()
+
+	assert.Nil(t, wl.file.Close())
+
+	wl.pending = 1
+
+	wl.mux.Unlock()
+
+	assert.Eventually(
+		t,
+		func() bool {
+			wl.mux.Lock()
+
+			defer wl.mux.Unlock()
+
+			return wl.err != nil
+		},
+		time.Second,
+		time.Millisecond,
+	)
+
+	assert.Equal(t, wl.err, l.Append(makeRecord("user", "1", "joe")))
+	assert.NotNil(t, l.Close())
+}
+
+func openLog(t *testing.T, dir string) Log {
+	log, err := Open(
+		Config{
+			Dir:        dir,
+			SyncPolicy: SyncAlways,
+		},
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		log.Close()
+	})
+
+	return log
+}
+
+func makeRecord(kind string, key string, value string) Record {
+	raw, _ := json.Marshal(value)
+
+	return Record{
+		Kind:  kind,
+		Key:   key,
+		Value: raw,
+	}
+}
+
+func replayValues(t *testing.T, log Log, kind string) []string {
+	var values []string
+
+	err := log.Replay(kind, func(rec Record) error {
+		var value string
+
+		err := json.Unmarshal(rec.Value, &value)
+
+		values = append(values, value)
+
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+func replayState(t *testing.T, log Log, kind string) map[string]string {
+	state := map[string]string{}
+
+	err := log.Replay(kind, func(rec Record) error {
+		var value string
+
+		err := json.Unmarshal(rec.Value, &value)
+
+		state[rec.Key] = value
+
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return state
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+)
+
+func (s svc) BatchTransfer(req BatchTransferRequest) (BatchTransferResponse, error) {
+	if len(req.Transfers) == 0 {
+		return BatchTransferResponse{}, errors.New("empty batch")
+	}
+
+	transfers := make([]TransferRequest, len(req.Transfers))
+
+	for i, transfer := range req.Transfers {
+		transfer.SenderUserID = req.SenderUserID
+		transfer.SenderAccountID = req.SenderAccountID
+		transfer.batch = true
+
+		transfers[i] = transfer
+	}
+
+	if req.AllOrNothing {
+		return s.batchAllOrNothing(transfers), nil
+	}
+
+	return s.batchBestEffort(transfers), nil
+}
+
+func (s svc) batchBestEffort(transfers []TransferRequest) BatchTransferResponse {
+	results := make([]BatchTransferResult, len(transfers))
+
+	for i, transfer := range transfers {
+		res, err := s.Transfer(transfer)
+
+		if err != nil {
+			results[i] = failedResult(err)
+
+			continue
+		}
+
+		status := domain.BatchLineCompleted
+
+		if res.PendingTransferID != "" {
+			status = domain.BatchLineHeld
+		}
+
+		results[i] = BatchTransferResult{
+			Status:  status,
+			Balance: &res.Balance,
+		}
+	}
+
+	return BatchTransferResponse{
+		Results: results,
+	}
+}
+
+func (s svc) batchAllOrNothing(transfers []TransferRequest) BatchTransferResponse {
+	results := make([]BatchTransferResult, len(transfers))
+
+	failed := false
+	total := money.Money{}
+
+	var senderAccount domain.Account
+
+	for i, transfer := range transfers {
+		plan, err := s.planTransfer(transfer)
+
+		if err == nil && s.requiresAcceptance(transfer, plan) {
+			err = errors.New("transfer requires acceptance")
+		}
+
+		if err != nil {
+			results[i] = failedResult(err)
+
+			failed = true
+
+			continue
+		}
+
+		total, err = total.Add(transfer.Amount)
+
+		if err == nil {
+			total, err = total.Add(plan.fee)
+		}
+
+		if err != nil {
+			results[i] = failedResult(err)
+
+			failed = true
+
+			continue
+		}
+
+		senderAccount = plan.senderAccount
+	}
+
+	if !failed {
+		err := coversBatch(senderAccount, total)
+
+		if err != nil {
+			for i := range results {
+				results[i] = failedResult(err)
+			}
+
+			return BatchTransferResponse{
+				Results: results,
+			}
+		}
+	}
+
+	if failed {
+		skipPending(results)
+
+		return BatchTransferResponse{
+			Results: results,
+		}
+	}
+
+	executed := make([]TransferRequest, len(transfers))
+	fees := make([]money.Money, len(transfers))
+
+	for i, transfer := range transfers {
+		plan, err := s.planTransfer(transfer)
+
+		if err == nil {
+			var res TransferResponse
+
+			res, err = s.executeTransfer(transfer, plan)
+
+			results[i].Balance = &res.Balance
+		}
+
+		if err != nil {
+			results[i] = failedResult(err)
+
+			s.rollbackBatch(executed[:i], fees[:i], results[:i])
+
+			skipPending(results)
+
+			return BatchTransferResponse{
+				Results: results,
+			}
+		}
+
+		executed[i] = plan.resolve(transfer)
+		fees[i] = plan.fee
+
+		results[i].Status = domain.BatchLineCompleted
+	}
+
+	return BatchTransferResponse{
+		Results: results,
+	}
+}
+
+func (s svc) rollbackBatch(transfers []TransferRequest, fees []money.Money, results []BatchTransferResult) {
+	for i := len(transfers) - 1; i >= 0; i-- {
+		err := s.reverseTransfer(transfers[i], fees[i])
+
+		if err != nil {
+			results[i] = failedResult(errors.Join(errors.New("rollback failed"), err))
+
+			continue
+		}
+
+		results[i] = BatchTransferResult{
+			Status: domain.BatchLineRolledBack,
+		}
+	}
+}
+
+func (s svc) reverseTransfer(req TransferRequest, fee money.Money) error {
+	credited, err := req.Amount.Add(fee)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:    req.ReceiverAccountID,
+			Debit: req.Amount,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     req.SenderAccountID,
+			Credit: credited,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: req.ReceiverAccountID,
+			Transaction: domain.Transaction{
+				ID:                newTransactionID(),
+				Timestamp:         now,
+				Operation:         domain.OperationReversal,
+				Amount:            req.Amount,
+				ReceiverUserID:    req.SenderUserID,
+				ReceiverAccountID: req.SenderAccountID,
+			},
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: req.SenderAccountID,
+			Transaction: domain.Transaction{
+				ID:              newTransactionID(),
+				Timestamp:       now,
+				Operation:       domain.OperationReversal,
+				Amount:          req.Amount,
+				SenderUserID:    req.ReceiverUserID,
+				SenderAccountID: req.ReceiverAccountID,
+			},
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if !fee.IsPositive() {
+		return nil
+	}
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: req.SenderAccountID,
+			Transaction: domain.Transaction{
+				ID:        newTransactionID(),
+				Timestamp: now,
+				Operation: domain.OperationFeeRefund,
+				Amount:    fee,
+			},
+		},
+	)
+}
+
+// coversBatch checks that the sender can pay the whole batch and still keep
+// the product minimum balance.
+func coversBatch(senderAccount domain.Account, total money.Money) error {
+	remaining, err := senderAccount.Balance.Sub(total)
+
+	if err != nil {
+		return err
+	}
+
+	cmp, err := remaining.Cmp(senderAccount.Product.MinimumBalance)
+
+	if err != nil {
+		return err
+	}
+
+	if cmp < 0 {
+		return errors.New("insuficient funds for batch")
+	}
+
+	return nil
+}
+
+func failedResult(err error) BatchTransferResult {
+	return BatchTransferResult{
+		Status: domain.BatchLineFailed,
+		Error:  err.Error(),
+	}
+}
+
+func skipPending(results []BatchTransferResult) {
+	for i := range results {
+		if results[i].Status == "" {
+			results[i] = BatchTransferResult{
+				Status: domain.BatchLineSkipped,
+			}
+		}
+	}
+}
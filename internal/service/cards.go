@@ -0,0 +1,1252 @@
+package service
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	guuid "github.com/google/uuid"
+	"github.com/hetfdex/tiny-bank/internal/card"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/cardrepo"
+)
+
+const (
+	// cardAuthorisationMetadataKey links a card hold, its release and the
+	// card payment back to the authorisation.
+	cardAuthorisationMetadataKey = "card_authorisation_id"
+
+	cardValidityYears = 3
+
+	// maxFailedCardChecks locks a card after that many authorisations in a
+	// row with wrong card details.
+	maxFailedCardChecks = 3
+
+	maxMerchantReferenceLength = 40
+)
+
+var (
+	errAuthorisationNotHeld = errors.New("authorisation not held")
+	errCardCheckSkipped     = errors.New("card check skipped")
+)
+
+// IssueCard returns the CVV once and keeps only its hash.
+func (s svc) IssueCard(req IssueCardRequest) (IssueCardResponse, error) {
+	if !validID(req.UserID) {
+		return IssueCardResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	account, err := s.heldAccount(req.UserID, accountID, actionWithdraw)
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return IssueCardResponse{}, errors.New("account closed")
+	}
+
+	limits, err := cardLimits(req.Limits, account.Balance.Currency())
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	accountNumber, err := randomUint64N(s.cardIssuer.MaxAccountNumber() + 1)
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	pan, err := s.cardIssuer.PAN(accountNumber)
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	cvvNumber, err := randomUint64N(1000)
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	cvv := fmt.Sprintf("%03d", cvvNumber)
+
+	salt := guuid.NewString()
+
+	expiresAt := time.Now().UTC().AddDate(cardValidityYears, 0, 0)
+
+	issued, err := s.cardRepo.Create(
+		cardrepo.CreateRequest{
+			Card: domain.Card{
+				UserID:      req.UserID,
+				AccountID:   account.ID,
+				PAN:         pan,
+				ExpiryMonth: int(expiresAt.Month()),
+				ExpiryYear:  expiresAt.Year(),
+				CVVSalt:     salt,
+				CVVHash:     s.hashCVV(salt, cvv),
+				Status:      domain.CardActive,
+				Limits:      limits,
+			},
+		},
+	)
+
+	if err != nil {
+		return IssueCardResponse{}, err
+	}
+
+	return IssueCardResponse{
+		CardResponse: cardResponse(issued),
+		PAN:          issued.PAN,
+		CVV:          cvv,
+	}, nil
+}
+
+func (s svc) Cards(req CardsRequest) (CardsResponse, error) {
+	if !validID(req.UserID) {
+		return CardsResponse{}, errors.New("invalid user id")
+	}
+
+	accountID := req.AccountID
+
+	if accountID != "" {
+		var err error
+
+		accountID, err = s.resolveAccountID(accountID)
+
+		if err != nil {
+			return CardsResponse{}, err
+		}
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return CardsResponse{}, err
+	}
+
+	cards, err := s.cardRepo.List(
+		cardrepo.ListRequest{
+			UserID:    req.UserID,
+			AccountID: accountID,
+		},
+	)
+
+	if err != nil {
+		return CardsResponse{}, err
+	}
+
+	res := make([]CardResponse, 0, len(cards))
+
+	for _, issued := range cards {
+		res = append(res, cardResponse(issued))
+	}
+
+	return CardsResponse{
+		Cards: res,
+	}, nil
+}
+
+func (s svc) Card(req CardRequest) (CardResponse, error) {
+	issued, err := s.userCard(req.UserID, req.CardID, actionView)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	return cardResponse(issued), nil
+}
+
+// FreezeCard leaves held authorisations to be captured or reversed.
+// FreezeCard and CancelCard give back the payments the card still holds.
+func (s svc) FreezeCard(req FreezeCardRequest) (CardResponse, error) {
+	res, err := s.changeCard(
+		req.UserID,
+		req.CardID,
+		func(issued *domain.Card, now time.Time) error {
+			err := checkCardStatus(*issued)
+
+			if err != nil {
+				return err
+			}
+
+			issued.Status = domain.CardFrozen
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	err = s.releaseCardHolds(res.CardID, domain.AuthorisationReversed)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	return res, nil
+}
+
+// UnfreezeCard also unlocks a card locked after too many wrong card details.
+func (s svc) UnfreezeCard(req UnfreezeCardRequest) (CardResponse, error) {
+	return s.changeCard(
+		req.UserID,
+		req.CardID,
+		func(issued *domain.Card, now time.Time) error {
+			if issued.Status != domain.CardFrozen && issued.Status != domain.CardLocked {
+				return errors.New("card not frozen")
+			}
+
+			issued.Status = domain.CardActive
+			issued.FailedChecks = 0
+
+			return nil
+		},
+	)
+}
+
+func (s svc) CancelCard(req CancelCardRequest) (CardResponse, error) {
+	res, err := s.changeCard(
+		req.UserID,
+		req.CardID,
+		func(issued *domain.Card, now time.Time) error {
+			if issued.Status == domain.CardCancelled {
+				return errors.New("card cancelled")
+			}
+
+			issued.Status = domain.CardCancelled
+			issued.CancelledAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	err = s.releaseCardHolds(res.CardID, domain.AuthorisationReversed)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (s svc) UpdateCardLimits(req UpdateCardLimitsRequest) (CardResponse, error) {
+	issued, err := s.userCard(req.UserID, req.CardID, actionWithdraw)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: issued.AccountID,
+		},
+	)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	limits, err := cardLimits(req.Limits, account.Balance.Currency())
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	issued, err = s.updateCard(
+		issued.ID,
+		func(issued *domain.Card, now time.Time) error {
+			if issued.Status == domain.CardCancelled {
+				return errors.New("card cancelled")
+			}
+
+			issued.Limits = limits
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	return cardResponse(issued), nil
+}
+
+// AuthoriseCard reports all wrong card details the same way, so the response
+// does not tell which one was wrong.
+func (s svc) AuthoriseCard(req AuthoriseCardRequest) (CardAuthorisationResponse, error) {
+	pan, err := card.Parse(req.PAN)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, errors.New("invalid card details")
+	}
+
+	if !req.Amount.IsPositive() {
+		return CardAuthorisationResponse{}, errors.New("invalid amount")
+	}
+
+	merchant := strings.TrimSpace(req.Merchant)
+
+	if merchant == "" || utf8.RuneCountInString(merchant) > maxDescriptionLength {
+		return CardAuthorisationResponse{}, errors.New("invalid merchant")
+	}
+
+	err = s.checkMerchantKey(merchant, req.MerchantKey)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	reference := strings.TrimSpace(req.Reference)
+
+	if utf8.RuneCountInString(reference) > maxMerchantReferenceLength {
+		return CardAuthorisationResponse{}, errors.New("invalid merchant reference")
+	}
+
+	cards, err := s.cardRepo.List(
+		cardrepo.ListRequest{
+			PAN: pan,
+		},
+	)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	if len(cards) != 1 {
+		return CardAuthorisationResponse{}, errors.New("invalid card details")
+	}
+
+	if !s.cardDetails(cards[0], req) {
+		return CardAuthorisationResponse{}, s.failCardCheck(cards[0].ID)
+	}
+
+	issued := cards[0]
+
+	now := time.Now().UTC()
+
+	if cardExpired(issued, now) {
+		return CardAuthorisationResponse{}, errors.New("card expired")
+	}
+
+	err = checkCardStatus(issued)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	err = checkCardLimits(issued, req.Amount, now)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	account, err := s.cardAccount(issued, req.Amount, now)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	authorisationID := guuid.NewString()
+
+	issued, err = s.updateCard(
+		issued.ID,
+		func(issued *domain.Card, now time.Time) error {
+			err := checkCardStatus(*issued)
+
+			if err != nil {
+				return err
+			}
+
+			err = checkCardLimits(*issued, req.Amount, now)
+
+			if err != nil {
+				return err
+			}
+
+			if reference != "" && referencedAuthorisation(*issued, reference) >= 0 {
+				return errors.New("duplicate merchant reference")
+			}
+
+			issued.FailedChecks = 0
+			issued.Authorisations = append(
+				issued.Authorisations,
+				domain.CardAuthorisation{
+					ID:                authorisationID,
+					CreatedAt:         now,
+					UpdatedAt:         now,
+					ExpiresAt:         now.Add(s.cardHoldTTL),
+					Merchant:          merchant,
+					Reference:         reference,
+					Amount:            req.Amount,
+					Status:            domain.AuthorisationHeld,
+					HoldTransactionID: newTransactionID(),
+				},
+			)
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	authorisation := issued.Authorisations[authorisationIndex(issued, authorisationID)]
+
+	err = s.holdAuthorisation(account, authorisation)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, s.compensateAuthoriseCard(issued.ID, authorisationID, err)
+	}
+
+	return authorisationResponse(issued.ID, authorisation), nil
+}
+
+func (s svc) CardAuthorisations(req CardAuthorisationsRequest) (CardAuthorisationsResponse, error) {
+	issued, err := s.userCard(req.UserID, req.CardID, actionView)
+
+	if err != nil {
+		return CardAuthorisationsResponse{}, err
+	}
+
+	res := make([]CardAuthorisationResponse, 0, len(issued.Authorisations))
+
+	for i := len(issued.Authorisations) - 1; i >= 0; i-- {
+		authorisation := issued.Authorisations[i]
+
+		if req.Status != "" && authorisation.Status != req.Status {
+			continue
+		}
+
+		res = append(res, authorisationResponse(issued.ID, authorisation))
+	}
+
+	return CardAuthorisationsResponse{
+		Authorisations: res,
+	}, nil
+}
+
+func (s svc) CardAuthorisation(req CardAuthorisationRequest) (CardAuthorisationResponse, error) {
+	issued, err := s.userCard(req.UserID, req.CardID, actionView)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	i := authorisationIndex(issued, req.AuthorisationID)
+
+	if i < 0 {
+		return CardAuthorisationResponse{}, errors.New("authorisation not found")
+	}
+
+	return authorisationResponse(issued.ID, issued.Authorisations[i]), nil
+}
+
+// MerchantAuthorisation skips reversed authorisations, so a merchant
+// reference can be used again after a reversal.
+func (s svc) MerchantAuthorisation(req MerchantAuthorisationRequest) (CardAuthorisationResponse, error) {
+	pan, err := card.Parse(req.PAN)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, errors.New("invalid card details")
+	}
+
+	reference := strings.TrimSpace(req.Reference)
+
+	if reference == "" {
+		return CardAuthorisationResponse{}, errors.New("invalid merchant reference")
+	}
+
+	cards, err := s.cardRepo.List(
+		cardrepo.ListRequest{
+			PAN: pan,
+		},
+	)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	for _, issued := range cards {
+		i := referencedAuthorisation(issued, reference)
+
+		if i >= 0 {
+			return authorisationResponse(issued.ID, issued.Authorisations[i]), nil
+		}
+	}
+
+	return CardAuthorisationResponse{}, errors.New("authorisation not found")
+}
+
+// CaptureAuthorisation debits at most what was held.
+func (s svc) CaptureAuthorisation(req CaptureAuthorisationRequest) (CardAuthorisationResponse, error) {
+	if !validID(req.CardID) {
+		return CardAuthorisationResponse{}, errors.New("invalid card id")
+	}
+
+	if !validID(req.AuthorisationID) {
+		return CardAuthorisationResponse{}, errors.New("invalid authorisation id")
+	}
+
+	if req.Amount.IsNegative() {
+		return CardAuthorisationResponse{}, errors.New("invalid amount")
+	}
+
+	issued, err := s.updateCard(
+		req.CardID,
+		func(issued *domain.Card, now time.Time) error {
+			authorisation, err := heldAuthorisation(issued, req.AuthorisationID)
+
+			if err != nil {
+				return err
+			}
+
+			err = s.checkMerchant(*authorisation, req.Merchant, req.MerchantKey)
+
+			if err != nil {
+				return err
+			}
+
+			if !now.Before(s.holdExpiry(*authorisation)) {
+				return errors.New("authorisation expired")
+			}
+
+			captured := authorisation.Amount
+
+			if !req.Amount.IsZero() {
+				cmp, err := req.Amount.Cmp(authorisation.Amount)
+
+				if err != nil {
+					return err
+				}
+
+				if cmp > 0 {
+					return errors.New("invalid amount, above authorised amount")
+				}
+
+				captured = req.Amount
+			}
+
+			authorisation.Status = domain.AuthorisationCaptured
+			authorisation.CapturedAmount = captured
+			authorisation.TransactionID = newTransactionID()
+			authorisation.UpdatedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	authorisation := issued.Authorisations[authorisationIndex(issued, req.AuthorisationID)]
+
+	err = s.releaseAuthorisation(issued.AccountID, authorisation)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, s.compensateReleaseAuthorisation(issued.ID, authorisation.ID, err)
+	}
+
+	return authorisationResponse(issued.ID, authorisation), nil
+}
+
+func (s svc) ReverseAuthorisation(req ReverseAuthorisationRequest) (CardAuthorisationResponse, error) {
+	if !validID(req.CardID) {
+		return CardAuthorisationResponse{}, errors.New("invalid card id")
+	}
+
+	if !validID(req.AuthorisationID) {
+		return CardAuthorisationResponse{}, errors.New("invalid authorisation id")
+	}
+
+	issued, err := s.updateCard(
+		req.CardID,
+		func(issued *domain.Card, now time.Time) error {
+			authorisation, err := heldAuthorisation(issued, req.AuthorisationID)
+
+			if err != nil {
+				return err
+			}
+
+			err = s.checkMerchant(*authorisation, req.Merchant, req.MerchantKey)
+
+			if err != nil {
+				return err
+			}
+
+			authorisation.Status = domain.AuthorisationReversed
+			authorisation.UpdatedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, err
+	}
+
+	authorisation := issued.Authorisations[authorisationIndex(issued, req.AuthorisationID)]
+
+	err = s.releaseAuthorisation(issued.AccountID, authorisation)
+
+	if err != nil {
+		return CardAuthorisationResponse{}, s.compensateReleaseAuthorisation(issued.ID, authorisation.ID, err)
+	}
+
+	return authorisationResponse(issued.ID, authorisation), nil
+}
+
+// ExpireCardAuthorisations gives back every held payment past its expiry.
+func (s svc) ExpireCardAuthorisations(req ExpireCardAuthorisationsRequest) (ExpireCardAuthorisationsResponse, error) {
+	cards, err := s.cardRepo.List(cardrepo.ListRequest{})
+
+	if err != nil {
+		return ExpireCardAuthorisationsResponse{}, err
+	}
+
+	now := time.Now().UTC()
+
+	authorisationIDs := []string{}
+
+	for _, issued := range cards {
+		for _, authorisation := range issued.Authorisations {
+			if authorisation.Status != domain.AuthorisationHeld || now.Before(s.holdExpiry(authorisation)) {
+				continue
+			}
+
+			err = s.releaseCardHold(issued.ID, authorisation.ID, domain.AuthorisationExpired)
+
+			if errors.Is(err, errAuthorisationNotHeld) {
+				continue
+			}
+
+			if err != nil {
+				return ExpireCardAuthorisationsResponse{}, err
+			}
+
+			authorisationIDs = append(authorisationIDs, authorisation.ID)
+		}
+	}
+
+	sort.Strings(authorisationIDs)
+
+	return ExpireCardAuthorisationsResponse{
+		AuthorisationIDs: authorisationIDs,
+	}, nil
+}
+
+// userCard hides cards issued to co-holders of the account too.
+func (s svc) userCard(userID string, cardID string, act action) (domain.Card, error) {
+	if !validID(userID) {
+		return domain.Card{}, errors.New("invalid user id")
+	}
+
+	if !validID(cardID) {
+		return domain.Card{}, errors.New("invalid card id")
+	}
+
+	_, err := s.readUser(userID, act)
+
+	if err != nil {
+		return domain.Card{}, err
+	}
+
+	issued, err := s.cardRepo.Read(
+		cardrepo.ReadRequest{
+			ID: cardID,
+		},
+	)
+
+	if err != nil {
+		return domain.Card{}, err
+	}
+
+	if issued.UserID != userID {
+		return domain.Card{}, errors.New("card not found")
+	}
+
+	return issued, nil
+}
+
+func (s svc) changeCard(userID string, cardID string, apply func(*domain.Card, time.Time) error) (CardResponse, error) {
+	issued, err := s.userCard(userID, cardID, actionWithdraw)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	issued, err = s.updateCard(issued.ID, apply)
+
+	if err != nil {
+		return CardResponse{}, err
+	}
+
+	return cardResponse(issued), nil
+}
+
+func (s svc) cardAccount(issued domain.Card, amount money.Money, now time.Time) (domain.Account, error) {
+	user, err := s.readUser(issued.UserID, actionWithdraw)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	if !userAccount(user.AccountIDs, issued.AccountID) {
+		return domain.Account{}, errors.New("unauthorized account id")
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: issued.AccountID,
+		},
+	)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	err = authorize(account, issued.UserID, actionWithdraw)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return domain.Account{}, errors.New("account closed")
+	}
+
+	cmp, err := account.Balance.Cmp(amount)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	if cmp < 0 {
+		return domain.Account{}, ErrInsufficientFunds
+	}
+
+	_, err = checkOutgoing(account, domain.OperationWithdraw, amount, false, now)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	err = s.checkVelocity(user, account, domain.OperationWithdraw, amount, now)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	return account, s.approve(account, issued.UserID, "", amount)
+}
+
+func (s svc) holdAuthorisation(account domain.Account, authorisation domain.CardAuthorisation) error {
+	_, err := s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:      account.ID,
+			Debit:   authorisation.Amount,
+			Minimum: account.Product.MinimumBalance,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          account.ID,
+			Transaction: authorisationEntry(authorisation, domain.OperationHold, authorisation.HoldTransactionID, authorisation.Amount, authorisation.CreatedAt),
+		},
+	)
+}
+
+func (s svc) releaseAuthorisation(accountID string, authorisation domain.CardAuthorisation) error {
+	released := authorisation.Amount
+
+	var err error
+
+	if authorisation.Status == domain.AuthorisationCaptured {
+		released, err = released.Sub(authorisation.CapturedAmount)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     accountID,
+			Credit: released,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          accountID,
+			Transaction: authorisationEntry(authorisation, domain.OperationHoldRelease, newTransactionID(), authorisation.Amount, authorisation.UpdatedAt),
+		},
+	)
+
+	if err != nil || authorisation.Status != domain.AuthorisationCaptured {
+		return err
+	}
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          accountID,
+			Transaction: authorisationEntry(authorisation, domain.OperationCardPayment, authorisation.TransactionID, authorisation.CapturedAmount, authorisation.UpdatedAt),
+		},
+	)
+}
+
+// releaseCardHolds gives back every payment still held on the card, leaving
+// each authorisation with the given status.
+func (s svc) releaseCardHolds(cardID string, status domain.AuthorisationStatus) error {
+	issued, err := s.cardRepo.Read(
+		cardrepo.ReadRequest{
+			ID: cardID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for _, authorisation := range issued.Authorisations {
+		if authorisation.Status != domain.AuthorisationHeld {
+			continue
+		}
+
+		err = s.releaseCardHold(issued.ID, authorisation.ID, status)
+
+		if err != nil && !errors.Is(err, errAuthorisationNotHeld) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s svc) releaseCardHold(cardID string, authorisationID string, status domain.AuthorisationStatus) error {
+	issued, err := s.updateCard(
+		cardID,
+		func(issued *domain.Card, now time.Time) error {
+			authorisation, err := heldAuthorisation(issued, authorisationID)
+
+			if err != nil {
+				return err
+			}
+
+			authorisation.Status = status
+			authorisation.UpdatedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.releaseAuthorisation(issued.AccountID, issued.Authorisations[authorisationIndex(issued, authorisationID)])
+
+	if err != nil {
+		return s.compensateReleaseAuthorisation(issued.ID, authorisationID, err)
+	}
+
+	return nil
+}
+
+func (s svc) compensateAuthoriseCard(cardID string, authorisationID string, cause error) error {
+	_, err := s.updateCard(
+		cardID,
+		func(issued *domain.Card, now time.Time) error {
+			i := authorisationIndex(*issued, authorisationID)
+
+			issued.Authorisations = append(issued.Authorisations[:i], issued.Authorisations[i+1:]...)
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) compensateReleaseAuthorisation(cardID string, authorisationID string, cause error) error {
+	_, err := s.updateCard(
+		cardID,
+		func(issued *domain.Card, now time.Time) error {
+			authorisation := &issued.Authorisations[authorisationIndex(*issued, authorisationID)]
+
+			authorisation.Status = domain.AuthorisationHeld
+			authorisation.CapturedAmount = money.Money{}
+			authorisation.TransactionID = ""
+			authorisation.UpdatedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) updateCard(cardID string, apply func(*domain.Card, time.Time) error) (domain.Card, error) {
+	for attempt := 1; ; attempt++ {
+		issued, err := s.cardRepo.Read(
+			cardrepo.ReadRequest{
+				ID: cardID,
+			},
+		)
+
+		if err != nil {
+			return domain.Card{}, err
+		}
+
+		err = apply(&issued, time.Now().UTC())
+
+		if err != nil {
+			return domain.Card{}, err
+		}
+
+		issued, err = s.cardRepo.Update(
+			cardrepo.UpdateRequest{
+				Card: issued,
+			},
+		)
+
+		if errors.Is(err, cardrepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return issued, err
+	}
+}
+
+func heldAuthorisation(issued *domain.Card, authorisationID string) (*domain.CardAuthorisation, error) {
+	i := authorisationIndex(*issued, authorisationID)
+
+	if i < 0 {
+		return nil, errors.New("authorisation not found")
+	}
+
+	if issued.Authorisations[i].Status != domain.AuthorisationHeld {
+		return nil, errAuthorisationNotHeld
+	}
+
+	return &issued.Authorisations[i], nil
+}
+
+func cardLimits(limits CardLimits, currency money.Currency) (domain.CardLimits, error) {
+	for _, limit := range []money.Money{limits.PerTransaction, limits.Daily} {
+		if limit.IsZero() {
+			continue
+		}
+
+		if limit.IsNegative() {
+			return domain.CardLimits{}, errors.New("invalid card limit")
+		}
+
+		_, err := limit.Cmp(money.New(0, currency))
+
+		if err != nil {
+			return domain.CardLimits{}, err
+		}
+	}
+
+	return domain.CardLimits{
+		PerTransaction: limits.PerTransaction,
+		Daily:          limits.Daily,
+	}, nil
+}
+
+func checkCardStatus(issued domain.Card) error {
+	switch issued.Status {
+	case domain.CardFrozen:
+		return errors.New("card frozen")
+	case domain.CardLocked:
+		return errors.New("card locked")
+	case domain.CardCancelled:
+		return errors.New("card cancelled")
+	default:
+		return nil
+	}
+}
+
+// checkCardLimits counts captured authorisations since midnight UTC for what
+// was captured and skips reversed ones.
+func checkCardLimits(issued domain.Card, amount money.Money, now time.Time) error {
+	if issued.Limits.PerTransaction.IsPositive() {
+		cmp, err := amount.Cmp(issued.Limits.PerTransaction)
+
+		if err != nil {
+			return err
+		}
+
+		if cmp > 0 {
+			return errors.New("card limit exceeded")
+		}
+	}
+
+	if !issued.Limits.Daily.IsPositive() {
+		return nil
+	}
+
+	year, month, day := now.Date()
+
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	total := amount
+
+	for _, authorisation := range issued.Authorisations {
+		if authorisation.Status == domain.AuthorisationReversed || authorisation.CreatedAt.Before(midnight) {
+			continue
+		}
+
+		spent := authorisation.Amount
+
+		if authorisation.Status == domain.AuthorisationCaptured {
+			spent = authorisation.CapturedAmount
+		}
+
+		var err error
+
+		total, err = total.Add(spent)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	cmp, err := total.Cmp(issued.Limits.Daily)
+
+	if err != nil {
+		return err
+	}
+
+	if cmp > 0 {
+		return errors.New("card daily limit exceeded")
+	}
+
+	return nil
+}
+
+func (s svc) cardDetails(issued domain.Card, req AuthoriseCardRequest) bool {
+	if issued.ExpiryMonth != req.ExpiryMonth || issued.ExpiryYear != req.ExpiryYear {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(issued.CVVHash), []byte(s.hashCVV(issued.CVVSalt, req.CVV))) == 1
+}
+
+// failCardCheck counts wrong card details against an active card and locks
+// it once they reach maxFailedCardChecks. The caller is still only told the
+// details were invalid.
+func (s svc) failCardCheck(cardID string) error {
+	_, err := s.updateCard(
+		cardID,
+		func(issued *domain.Card, now time.Time) error {
+			if issued.Status != domain.CardActive {
+				return errCardCheckSkipped
+			}
+
+			issued.FailedChecks++
+
+			if issued.FailedChecks >= maxFailedCardChecks {
+				issued.Status = domain.CardLocked
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil && !errors.Is(err, errCardCheckSkipped) {
+		return err
+	}
+
+	return errors.New("invalid card details")
+}
+
+// cardExpired is true after the last day of the expiry month.
+func cardExpired(issued domain.Card, now time.Time) bool {
+	return !now.Before(time.Date(issued.ExpiryYear, time.Month(issued.ExpiryMonth)+1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// hashCVV keys the hash with a secret kept outside the card store.
+func (s svc) hashCVV(salt string, cvv string) string {
+	mac := hmac.New(sha256.New, s.cardSecret)
+
+	mac.Write([]byte(salt + ":" + cvv))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomUint64N draws from crypto/rand, as card numbers and CVVs must not be
+// predictable.
+func randomUint64N(n uint64) (uint64, error) {
+	v, err := crand.Int(crand.Reader, new(big.Int).SetUint64(n))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return v.Uint64(), nil
+}
+
+func randomSecret() []byte {
+	secret := make([]byte, sha256.Size)
+
+	_, err := crand.Read(secret)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return secret
+}
+
+// checkMerchant lets only the merchant that took an authorisation capture
+// or reverse it. Any other merchant is told it does not exist.
+func (s svc) checkMerchant(authorisation domain.CardAuthorisation, merchant string, key string) error {
+	err := s.checkMerchantKey(merchant, key)
+
+	if err != nil {
+		return err
+	}
+
+	if authorisation.Merchant != merchant {
+		return errors.New("authorisation not found")
+	}
+
+	return nil
+}
+
+func (s svc) checkMerchantKey(merchant string, key string) error {
+	expected, ok := s.merchantKeys[merchant]
+
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), []byte(key)) != 1 {
+		return errors.New("invalid merchant credentials")
+	}
+
+	return nil
+}
+
+// holdExpiry falls back to the current hold TTL for authorisations made
+// before holds expired.
+func (s svc) holdExpiry(authorisation domain.CardAuthorisation) time.Time {
+	if !authorisation.ExpiresAt.IsZero() {
+		return authorisation.ExpiresAt
+	}
+
+	return authorisation.CreatedAt.Add(s.cardHoldTTL)
+}
+
+func authorisationIndex(issued domain.Card, authorisationID string) int {
+	for i, authorisation := range issued.Authorisations {
+		if authorisation.ID == authorisationID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// referencedAuthorisation returns -1 when no authorisation matches.
+func referencedAuthorisation(issued domain.Card, reference string) int {
+	for i := len(issued.Authorisations) - 1; i >= 0; i-- {
+		authorisation := issued.Authorisations[i]
+
+		if authorisation.Reference == reference && authorisation.Status != domain.AuthorisationReversed {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func authorisationEntry(authorisation domain.CardAuthorisation, op string, transactionID string, amount money.Money, now time.Time) domain.Transaction {
+	return domain.Transaction{
+		ID:          transactionID,
+		Timestamp:   now,
+		Operation:   op,
+		Amount:      amount,
+		Description: authorisation.Merchant,
+		Metadata: map[string]string{
+			cardAuthorisationMetadataKey: authorisation.ID,
+		},
+	}
+}
+
+func cardResponse(issued domain.Card) CardResponse {
+	return CardResponse{
+		CardID:      issued.ID,
+		UserID:      issued.UserID,
+		AccountID:   issued.AccountID,
+		MaskedPAN:   card.Mask(issued.PAN),
+		ExpiryMonth: issued.ExpiryMonth,
+		ExpiryYear:  issued.ExpiryYear,
+		Status:      issued.Status,
+		Limits: CardLimits{
+			PerTransaction: issued.Limits.PerTransaction,
+			Daily:          issued.Limits.Daily,
+		},
+		CreatedAt:   issued.CreatedAt,
+		CancelledAt: issued.CancelledAt,
+	}
+}
+
+func authorisationResponse(cardID string, authorisation domain.CardAuthorisation) CardAuthorisationResponse {
+	return CardAuthorisationResponse{
+		AuthorisationID:   authorisation.ID,
+		CardID:            cardID,
+		Merchant:          authorisation.Merchant,
+		Reference:         authorisation.Reference,
+		Amount:            authorisation.Amount,
+		CapturedAmount:    authorisation.CapturedAmount,
+		Status:            authorisation.Status,
+		HoldTransactionID: authorisation.HoldTransactionID,
+		TransactionID:     authorisation.TransactionID,
+		CreatedAt:         authorisation.CreatedAt,
+		UpdatedAt:         authorisation.UpdatedAt,
+		ExpiresAt:         authorisation.ExpiresAt,
+	}
+}
@@ -0,0 +1,876 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/categoryrepo"
+)
+
+// The built-in categories transactions fall back to when no rule of the
+// user's matches.
+const (
+	categoryIncome        = "income"
+	categoryTransfers     = "transfers"
+	categoryCash          = "cash"
+	categoryFees          = "fees"
+	categoryLoans         = "loans"
+	categorySavings       = "savings"
+	categoryShopping      = "shopping"
+	categoryGroceries     = "groceries"
+	categoryEatingOut     = "eating_out"
+	categoryTransport     = "transport"
+	categoryBills         = "bills"
+	categoryHousing       = "housing"
+	categoryEntertainment = "entertainment"
+	categoryOther         = "other"
+)
+
+const (
+	maxCategoryRules     = 100
+	maxCategoryLength    = 32
+	maxDescriptionRegexp = 200
+
+	analyticsMonthLayout     = "2006-01"
+	defaultAnalyticsMonths   = 6
+	maxAnalyticsMonths       = 24
+	defaultTopCounterparties = 5
+	maxTopCounterparties     = 50
+)
+
+var (
+	defaultCategories = []string{
+		categoryIncome,
+		categoryTransfers,
+		categoryCash,
+		categoryFees,
+		categoryLoans,
+		categorySavings,
+		categoryShopping,
+		categoryGroceries,
+		categoryEatingOut,
+		categoryTransport,
+		categoryBills,
+		categoryHousing,
+		categoryEntertainment,
+		categoryOther,
+	}
+
+	// categoryKeywords are tried in order, so a "supermarket cafe" is
+	// groceries.
+	categoryKeywords = []struct {
+		category string
+		words    []string
+	}{
+		{categoryGroceries, []string{"grocery", "groceries", "supermarket", "market", "bakery", "butcher"}},
+		{categoryEatingOut, []string{"restaurant", "cafe", "coffee", "bar", "pub", "pizza", "burger", "takeaway"}},
+		{categoryTransport, []string{"taxi", "uber", "train", "rail", "bus", "metro", "fuel", "parking", "airline"}},
+		{categoryHousing, []string{"rent", "mortgage", "landlord"}},
+		{categoryBills, []string{"electricity", "energy", "gas", "water", "internet", "phone", "mobile", "insurance"}},
+		{categoryEntertainment, []string{"cinema", "theatre", "concert", "netflix", "spotify", "games", "tickets"}},
+	}
+
+	categoryPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// categoriser assigns categories to transactions: by hand first, then by
+// the user's rules in order, then by the built-in defaults.
+type categoriser struct {
+	rules     []categoryMatcher
+	overrides map[string]string
+}
+
+type categoryMatcher struct {
+	domain.CategoryRule
+	pattern *regexp.Regexp
+}
+
+func (s svc) CategoryRules(req CategoryRulesRequest) (CategoryRulesResponse, error) {
+	if !validID(req.UserID) {
+		return CategoryRulesResponse{}, errors.New("invalid user id")
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	categorisation, err := s.categoryRepo.Read(
+		categoryrepo.ReadRequest{
+			UserID: req.UserID,
+		},
+	)
+
+	if err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	return categoryRulesResponse(categorisation), nil
+}
+
+// PutCategoryRules keeps categories set by hand, which still win over any
+// rule.
+func (s svc) PutCategoryRules(req PutCategoryRulesRequest) (CategoryRulesResponse, error) {
+	if !validID(req.UserID) {
+		return CategoryRulesResponse{}, errors.New("invalid user id")
+	}
+
+	if len(req.Rules) > maxCategoryRules {
+		return CategoryRulesResponse{}, errors.New("invalid category rules, too many")
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	rules := make([]domain.CategoryRule, 0, len(req.Rules))
+
+	for _, rule := range req.Rules {
+		categoryRule, err := s.categoryRule(rule)
+
+		if err != nil {
+			return CategoryRulesResponse{}, err
+		}
+
+		rules = append(rules, categoryRule)
+	}
+
+	categorisation, err := s.updateCategorisation(
+		req.UserID,
+		func(categorisation *domain.Categorisation) error {
+			categorisation.Rules = rules
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	return categoryRulesResponse(categorisation), nil
+}
+
+// CategoriseTransaction clears the category when it is empty.
+func (s svc) CategoriseTransaction(req CategoriseTransactionRequest) (TransactionResponse, error) {
+	if !validID(req.UserID) {
+		return TransactionResponse{}, errors.New("invalid user id")
+	}
+
+	if !validID(req.TransactionID) {
+		return TransactionResponse{}, errors.New("invalid transaction id")
+	}
+
+	if req.Category != "" {
+		err := checkCategory(req.Category)
+
+		if err != nil {
+			return TransactionResponse{}, err
+		}
+	}
+
+	accountID, transaction, err := s.userTransaction(req.UserID, req.TransactionID)
+
+	if err != nil {
+		return TransactionResponse{}, err
+	}
+
+	if held(transaction) {
+		return TransactionResponse{}, errors.New("invalid transaction, holds have no category")
+	}
+
+	categorisation, err := s.updateCategorisation(
+		req.UserID,
+		func(categorisation *domain.Categorisation) error {
+			if req.Category == "" {
+				delete(categorisation.Overrides, req.TransactionID)
+
+				return nil
+			}
+
+			if categorisation.Overrides == nil {
+				categorisation.Overrides = map[string]string{}
+			}
+
+			categorisation.Overrides[req.TransactionID] = req.Category
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return TransactionResponse{}, err
+	}
+
+	c, err := newCategoriser(categorisation)
+
+	if err != nil {
+		return TransactionResponse{}, err
+	}
+
+	res := transactionResponse(accountID, transaction)
+
+	res.Category = c.category(transaction)
+
+	return res, nil
+}
+
+// SpendingAnalytics compares each month with the one before, including the
+// month before From.
+func (s svc) SpendingAnalytics(req SpendingAnalyticsRequest) (SpendingAnalyticsResponse, error) {
+	if !validID(req.UserID) {
+		return SpendingAnalyticsResponse{}, errors.New("invalid user id")
+	}
+
+	from, to, err := analyticsMonths(req.From, req.To, time.Now().UTC())
+
+	if err != nil {
+		return SpendingAnalyticsResponse{}, err
+	}
+
+	top := req.Top
+
+	if top == 0 {
+		top = defaultTopCounterparties
+	}
+
+	if top < 0 || top > maxTopCounterparties {
+		return SpendingAnalyticsResponse{}, errors.New("invalid top")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return SpendingAnalyticsResponse{}, err
+	}
+
+	account, err := s.heldAccount(req.UserID, accountID, actionView)
+
+	if err != nil {
+		return SpendingAnalyticsResponse{}, err
+	}
+
+	c, err := s.categoriser(req.UserID)
+
+	if err != nil {
+		return SpendingAnalyticsResponse{}, err
+	}
+
+	return spendingAnalytics(account, c, from, to, top), nil
+}
+
+func (s svc) categoryRule(rule CategoryRule) (domain.CategoryRule, error) {
+	err := checkCategory(rule.Category)
+
+	if err != nil {
+		return domain.CategoryRule{}, err
+	}
+
+	if rule.Counterparty == "" && rule.DescriptionPattern == "" && rule.MinAmount.IsZero() && rule.MaxAmount.IsZero() {
+		return domain.CategoryRule{}, errors.New("invalid category rule, no conditions")
+	}
+
+	counterpartyAccountID := ""
+
+	if rule.Counterparty != "" {
+		counterpartyAccountID, err = s.resolveAccountID(rule.Counterparty)
+
+		if err != nil {
+			return domain.CategoryRule{}, err
+		}
+
+		if !validID(counterpartyAccountID) {
+			return domain.CategoryRule{}, errors.New("invalid category rule counterparty")
+		}
+	}
+
+	if len(rule.DescriptionPattern) > maxDescriptionRegexp {
+		return domain.CategoryRule{}, errors.New("invalid category rule description pattern")
+	}
+
+	if rule.DescriptionPattern != "" {
+		_, err = descriptionRegexp(rule.DescriptionPattern)
+
+		if err != nil {
+			return domain.CategoryRule{}, errors.New("invalid category rule description pattern")
+		}
+	}
+
+	err = checkAmountRange(rule.MinAmount, rule.MaxAmount)
+
+	if err != nil {
+		return domain.CategoryRule{}, err
+	}
+
+	return domain.CategoryRule{
+		Category:              rule.Category,
+		CounterpartyAccountID: counterpartyAccountID,
+		DescriptionPattern:    rule.DescriptionPattern,
+		MinAmount:             rule.MinAmount,
+		MaxAmount:             rule.MaxAmount,
+	}, nil
+}
+
+func (s svc) categoriser(userID string) (categoriser, error) {
+	categorisation, err := s.categoryRepo.Read(
+		categoryrepo.ReadRequest{
+			UserID: userID,
+		},
+	)
+
+	if err != nil {
+		return categoriser{}, err
+	}
+
+	return newCategoriser(categorisation)
+}
+
+func (s svc) updateCategorisation(userID string, apply func(*domain.Categorisation) error) (domain.Categorisation, error) {
+	for attempt := 1; ; attempt++ {
+		categorisation, err := s.categoryRepo.Read(
+			categoryrepo.ReadRequest{
+				UserID: userID,
+			},
+		)
+
+		if err != nil {
+			return domain.Categorisation{}, err
+		}
+
+		err = apply(&categorisation)
+
+		if err != nil {
+			return domain.Categorisation{}, err
+		}
+
+		categorisation, err = s.categoryRepo.Put(
+			categoryrepo.PutRequest{
+				Categorisation: categorisation,
+			},
+		)
+
+		if errors.Is(err, categoryrepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return categorisation, err
+	}
+}
+
+func (s svc) userTransaction(userID string, transactionID string) (string, domain.Transaction, error) {
+	user, err := s.readUser(userID, actionView)
+
+	if err != nil {
+		return "", domain.Transaction{}, err
+	}
+
+	for _, accountID := range sortedAccountIDs(user.AccountIDs) {
+		account, err := s.accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: accountID,
+			},
+		)
+
+		if err != nil {
+			return "", domain.Transaction{}, err
+		}
+
+		if authorize(account, userID, actionView) != nil {
+			continue
+		}
+
+		for _, transaction := range account.Transactions {
+			if transaction.ID == transactionID {
+				return account.ID, transaction, nil
+			}
+		}
+	}
+
+	return "", domain.Transaction{}, errors.New("transaction not found")
+}
+
+func newCategoriser(categorisation domain.Categorisation) (categoriser, error) {
+	rules := make([]categoryMatcher, 0, len(categorisation.Rules))
+
+	for _, rule := range categorisation.Rules {
+		matcher := categoryMatcher{
+			CategoryRule: rule,
+		}
+
+		if rule.DescriptionPattern != "" {
+			pattern, err := descriptionRegexp(rule.DescriptionPattern)
+
+			if err != nil {
+				return categoriser{}, err
+			}
+
+			matcher.pattern = pattern
+		}
+
+		rules = append(rules, matcher)
+	}
+
+	return categoriser{
+		rules:     rules,
+		overrides: categorisation.Overrides,
+	}, nil
+}
+
+// category is empty for holds and never empty for anything else.
+func (c categoriser) category(transaction domain.Transaction) string {
+	if held(transaction) {
+		return ""
+	}
+
+	if category, exists := c.overrides[transaction.ID]; exists {
+		return category
+	}
+
+	for _, rule := range c.rules {
+		if rule.matches(transaction) {
+			return rule.Category
+		}
+	}
+
+	return defaultCategory(transaction)
+}
+
+func (c categoriser) transactionResponses(account domain.Account) []TransactionResponse {
+	res := transactionResponses(account)
+
+	for i, transaction := range account.Transactions {
+		res[i].Category = c.category(transaction)
+	}
+
+	return res
+}
+
+func (m categoryMatcher) matches(transaction domain.Transaction) bool {
+	if m.CounterpartyAccountID != "" && m.CounterpartyAccountID != counterpartyAccountID(transaction) {
+		return false
+	}
+
+	if m.pattern != nil && !m.pattern.MatchString(transaction.Description) {
+		return false
+	}
+
+	if !m.MinAmount.IsZero() {
+		cmp, err := transaction.Amount.Cmp(m.MinAmount)
+
+		if err != nil || cmp < 0 {
+			return false
+		}
+	}
+
+	if !m.MaxAmount.IsZero() {
+		cmp, err := transaction.Amount.Cmp(m.MaxAmount)
+
+		if err != nil || cmp > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func defaultCategory(transaction domain.Transaction) string {
+	switch transaction.Operation {
+	case domain.OperationDeposit:
+		return categoryIncome
+	case domain.OperationWithdraw:
+		return categoryCash
+	case domain.OperationFee, domain.OperationFeeRefund:
+		return categoryFees
+	case domain.OperationLoanDisbursement, domain.OperationLoanRepayment:
+		return categoryLoans
+	case domain.OperationPotIn, domain.OperationPotOut:
+		return categorySavings
+	case domain.OperationReversal:
+		return categoryTransfers
+	case domain.OperationTransfer:
+		if !outgoing(transaction) {
+			return categoryIncome
+		}
+
+		if category, exists := keywordCategory(transaction.Description); exists {
+			return category
+		}
+
+		return categoryTransfers
+	case domain.OperationCardPayment:
+		if category, exists := keywordCategory(transaction.Description); exists {
+			return category
+		}
+
+		return categoryShopping
+	default:
+		return categoryOther
+	}
+}
+
+func keywordCategory(description string) (string, bool) {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+
+	for _, keywords := range categoryKeywords {
+		for _, word := range words {
+			if slices.Contains(keywords.words, word) {
+				return keywords.category, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// held reports whether the transaction is a hold or its release.
+func held(transaction domain.Transaction) bool {
+	switch transaction.Operation {
+	case domain.OperationHold, domain.OperationHoldRelease, domain.OperationPending:
+		return true
+	default:
+		return false
+	}
+}
+
+// outgoing relies on transfer and reversal legs naming the receiver only on
+// the sender's side.
+func outgoing(transaction domain.Transaction) bool {
+	switch transaction.Operation {
+	case domain.OperationWithdraw, domain.OperationFee, domain.OperationCardPayment, domain.OperationLoanRepayment, domain.OperationPotIn:
+		return true
+	case domain.OperationTransfer, domain.OperationReversal:
+		return transaction.ReceiverAccountID != ""
+	default:
+		return false
+	}
+}
+
+func counterpartyAccountID(transaction domain.Transaction) string {
+	switch transaction.Operation {
+	case domain.OperationTransfer, domain.OperationReversal:
+		if transaction.ReceiverAccountID != "" {
+			return transaction.ReceiverAccountID
+		}
+
+		return transaction.SenderAccountID
+	default:
+		return ""
+	}
+}
+
+func checkCategory(category string) error {
+	if len(category) > maxCategoryLength || !categoryPattern.MatchString(category) {
+		return errors.New("invalid category")
+	}
+
+	return nil
+}
+
+func checkAmountRange(minAmount money.Money, maxAmount money.Money) error {
+	if minAmount.IsNegative() || maxAmount.IsNegative() {
+		return errors.New("invalid category rule amount")
+	}
+
+	if minAmount.IsZero() || maxAmount.IsZero() {
+		return nil
+	}
+
+	cmp, err := minAmount.Cmp(maxAmount)
+
+	if err != nil || cmp > 0 {
+		return errors.New("invalid category rule amount range")
+	}
+
+	return nil
+}
+
+func descriptionRegexp(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// analyticsMonths defaults to the six months up to the current one.
+func analyticsMonths(fromMonth string, toMonth string, now time.Time) (time.Time, time.Time, error) {
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	if toMonth != "" {
+		month, err := time.Parse(analyticsMonthLayout, toMonth)
+
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to month")
+		}
+
+		to = month
+	}
+
+	from := to.AddDate(0, 1-defaultAnalyticsMonths, 0)
+
+	if fromMonth != "" {
+		month, err := time.Parse(analyticsMonthLayout, fromMonth)
+
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid from month")
+		}
+
+		from = month
+	}
+
+	if from.After(to) || from.AddDate(0, maxAnalyticsMonths, 0).Before(to.AddDate(0, 1, 0)) {
+		return time.Time{}, time.Time{}, errors.New("invalid month range")
+	}
+
+	return from, to, nil
+}
+
+type spendingTotal struct {
+	spent        money.Money
+	received     money.Money
+	transactions int
+}
+
+func (t *spendingTotal) add(transaction domain.Transaction) {
+	var err error
+
+	if outgoing(transaction) {
+		t.spent, err = t.spent.Add(transaction.Amount)
+	} else {
+		t.received, err = t.received.Add(transaction.Amount)
+	}
+
+	if err != nil {
+		return
+	}
+
+	t.transactions++
+}
+
+type monthTotal struct {
+	spendingTotal
+	categories map[string]*spendingTotal
+}
+
+type counterpartyTotal struct {
+	spendingTotal
+	accountID string
+	name      string
+}
+
+func spendingAnalytics(account domain.Account, c categoriser, from time.Time, to time.Time, top int) SpendingAnalyticsResponse {
+	previous := from.AddDate(0, -1, 0)
+	end := to.AddDate(0, 1, 0)
+
+	months := map[time.Time]*monthTotal{}
+	counterparties := map[string]*counterpartyTotal{}
+
+	for _, transaction := range account.Transactions {
+		if held(transaction) || transaction.Timestamp.Before(previous) || !transaction.Timestamp.Before(end) {
+			continue
+		}
+
+		month := time.Date(transaction.Timestamp.Year(), transaction.Timestamp.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		total, exists := months[month]
+
+		if !exists {
+			total = &monthTotal{
+				categories: map[string]*spendingTotal{},
+			}
+
+			months[month] = total
+		}
+
+		category := c.category(transaction)
+
+		categoryTotal, exists := total.categories[category]
+
+		if !exists {
+			categoryTotal = &spendingTotal{}
+
+			total.categories[category] = categoryTotal
+		}
+
+		total.add(transaction)
+		categoryTotal.add(transaction)
+
+		if month.Before(from) {
+			continue
+		}
+
+		accountID, name := counterparty(transaction)
+
+		if accountID == "" && name == "" {
+			continue
+		}
+
+		key := accountID + "\x00" + strings.ToLower(name)
+
+		other, exists := counterparties[key]
+
+		if !exists {
+			other = &counterpartyTotal{
+				accountID: accountID,
+				name:      name,
+			}
+
+			counterparties[key] = other
+		}
+
+		other.add(transaction)
+	}
+
+	res := SpendingAnalyticsResponse{
+		AccountID:         account.ID,
+		From:              from.Format(analyticsMonthLayout),
+		To:                to.Format(analyticsMonthLayout),
+		Months:            []MonthAnalyticsResponse{},
+		TopCounterparties: topCounterparties(counterparties, top),
+	}
+
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		res.Months = append(res.Months, monthAnalytics(month, months[month], months[month.AddDate(0, -1, 0)]))
+	}
+
+	return res
+}
+
+func monthAnalytics(month time.Time, total *monthTotal, previous *monthTotal) MonthAnalyticsResponse {
+	if total == nil {
+		total = &monthTotal{}
+	}
+
+	if previous == nil {
+		previous = &monthTotal{}
+	}
+
+	res := MonthAnalyticsResponse{
+		Month:      month.Format(analyticsMonthLayout),
+		Spent:      total.spent,
+		Received:   total.received,
+		SpentDelta: spentDelta(total.spent, previous.spent),
+		Categories: make([]CategoryTotalResponse, 0, len(total.categories)),
+	}
+
+	for category, categoryTotal := range total.categories {
+		before := spendingTotal{}
+
+		if previousTotal, exists := previous.categories[category]; exists {
+			before = *previousTotal
+		}
+
+		res.Categories = append(
+			res.Categories,
+			CategoryTotalResponse{
+				Category:     category,
+				Spent:        categoryTotal.spent,
+				Received:     categoryTotal.received,
+				SpentDelta:   spentDelta(categoryTotal.spent, before.spent),
+				Transactions: categoryTotal.transactions,
+			},
+		)
+	}
+
+	sort.Slice(res.Categories, func(i, j int) bool {
+		a, b := res.Categories[i], res.Categories[j]
+
+		if a.Spent.Minor() != b.Spent.Minor() {
+			return a.Spent.Minor() > b.Spent.Minor()
+		}
+
+		return a.Category < b.Category
+	})
+
+	return res
+}
+
+func topCounterparties(counterparties map[string]*counterpartyTotal, top int) []CounterpartyTotalResponse {
+	res := make([]CounterpartyTotalResponse, 0, len(counterparties))
+
+	for _, total := range counterparties {
+		res = append(
+			res,
+			CounterpartyTotalResponse{
+				AccountID:    total.accountID,
+				Name:         total.name,
+				Spent:        total.spent,
+				Received:     total.received,
+				Transactions: total.transactions,
+			},
+		)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+
+		if a.Spent.Minor() != b.Spent.Minor() {
+			return a.Spent.Minor() > b.Spent.Minor()
+		}
+
+		if a.Received.Minor() != b.Received.Minor() {
+			return a.Received.Minor() > b.Received.Minor()
+		}
+
+		return a.AccountID+a.Name < b.AccountID+b.Name
+	})
+
+	if len(res) > top {
+		res = res[:top]
+	}
+
+	return res
+}
+
+// counterparty is the other account of a transfer, or the merchant of a
+// card payment and the description of cash moves.
+func counterparty(transaction domain.Transaction) (string, string) {
+	switch transaction.Operation {
+	case domain.OperationTransfer, domain.OperationReversal:
+		return counterpartyAccountID(transaction), ""
+	case domain.OperationCardPayment, domain.OperationDeposit, domain.OperationWithdraw:
+		return "", strings.TrimSpace(transaction.Description)
+	default:
+		return "", ""
+	}
+}
+
+func spentDelta(spent money.Money, previous money.Money) money.Money {
+	delta, err := spent.Sub(previous)
+
+	if err != nil {
+		return money.Money{}
+	}
+
+	return delta
+}
+
+func categoryRulesResponse(categorisation domain.Categorisation) CategoryRulesResponse {
+	rules := make([]CategoryRuleResponse, 0, len(categorisation.Rules))
+
+	for _, rule := range categorisation.Rules {
+		rules = append(
+			rules,
+			CategoryRuleResponse{
+				Category:              rule.Category,
+				CounterpartyAccountID: rule.CounterpartyAccountID,
+				DescriptionPattern:    rule.DescriptionPattern,
+				MinAmount:             rule.MinAmount,
+				MaxAmount:             rule.MaxAmount,
+			},
+		)
+	}
+
+	return CategoryRulesResponse{
+		Rules:             rules,
+		DefaultCategories: slices.Clone(defaultCategories),
+	}
+}
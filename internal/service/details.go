@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hetfdex/tiny-bank/internal/checkdigit"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+)
+
+const (
+	// maxDescriptionLength matches the SEPA unstructured remittance field.
+	maxDescriptionLength = 140
+	maxMetadataKeys      = 20
+	maxMetadataKeyLength = 40
+	maxMetadataValue     = 500
+	maxReferenceLength   = 25
+)
+
+// checkDetails validates the details of a payment and returns them with the
+// reference in its stored form.
+func checkDetails(details TransactionDetails) (TransactionDetails, error) {
+	if utf8.RuneCountInString(details.Description) > maxDescriptionLength {
+		return TransactionDetails{}, errors.New("invalid description")
+	}
+
+	if details.Reference != "" {
+		reference, ok := creditorReference(details.Reference)
+
+		if !ok {
+			return TransactionDetails{}, errors.New("invalid reference")
+		}
+
+		details.Reference = reference
+	}
+
+	if len(details.Metadata) > maxMetadataKeys {
+		return TransactionDetails{}, errors.New("invalid metadata")
+	}
+
+	for key, value := range details.Metadata {
+		if key == "" || utf8.RuneCountInString(key) > maxMetadataKeyLength || utf8.RuneCountInString(value) > maxMetadataValue {
+			return TransactionDetails{}, errors.New("invalid metadata")
+		}
+	}
+
+	if len(details.Metadata) == 0 {
+		details.Metadata = nil
+	}
+
+	return details, nil
+}
+
+// creditorReference checks an ISO 11649 reference: RF, two check digits and
+// up to 21 alphanumerics, optionally printed in groups of four. The check
+// digits are valid when the reference, with its first four characters moved
+// to the end, is 1 mod 97.
+func creditorReference(value string) (string, bool) {
+	reference := normalizeReference(value)
+
+	if len(reference) < 5 || len(reference) > maxReferenceLength || !strings.HasPrefix(reference, "RF") {
+		return "", false
+	}
+
+	if reference[2] < '0' || reference[2] > '9' || reference[3] < '0' || reference[3] > '9' {
+		return "", false
+	}
+
+	remainder, ok := checkdigit.Mod97(reference[4:] + reference[:4])
+
+	if !ok || remainder != 1 {
+		return "", false
+	}
+
+	return reference, true
+}
+
+func normalizeReference(value string) string {
+	return strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+}
+
+// withDetails copies the details onto a ledger entry. Each leg of a transfer
+// gets its own metadata map.
+func withDetails(transaction domain.Transaction, details TransactionDetails) domain.Transaction {
+	transaction.Description = details.Description
+	transaction.Reference = details.Reference
+	transaction.Metadata = maps.Clone(details.Metadata)
+
+	return transaction
+}
+
+func (req TransactionsRequest) filtered() bool {
+	return req.Query != "" || req.Reference != "" || len(req.Metadata) > 0
+}
+
+func (req TransactionsRequest) matches(transaction domain.Transaction) bool {
+	if req.Reference != "" && normalizeReference(req.Reference) != transaction.Reference {
+		return false
+	}
+
+	for key, value := range req.Metadata {
+		actual, exists := transaction.Metadata[key]
+
+		if !exists || actual != value {
+			return false
+		}
+	}
+
+	if req.Query == "" {
+		return true
+	}
+
+	query := strings.ToLower(req.Query)
+
+	if strings.Contains(strings.ToLower(transaction.Description), query) {
+		return true
+	}
+
+	reference := normalizeReference(req.Query)
+
+	if reference != "" && strings.Contains(transaction.Reference, reference) {
+		return true
+	}
+
+	for _, value := range transaction.Metadata {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+)
+
+func (s svc) AddHolder(req AddHolderRequest) error {
+	if !validID(req.HolderUserID) {
+		return errors.New("invalid holder user id")
+	}
+
+	if !validRole(req.Role) {
+		return errors.New("invalid role")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return err
+	}
+
+	req.AccountID = accountID
+
+	account, err := s.heldAccount(req.UserID, req.AccountID, actionManage)
+
+	if err != nil {
+		return err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return errors.New("account closed")
+	}
+
+	_, err = s.readUser(req.HolderUserID, actionOpen)
+
+	if err != nil {
+		return err
+	}
+
+	if len(account.Holders) == 0 {
+		err = s.accountRepo.UpdateHolders(
+			accountrepo.UpdateHoldersRequest{
+				ID:     req.AccountID,
+				UserID: req.UserID,
+				Role:   domain.RoleOwner,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		account.Holders = map[string]domain.Role{
+			req.UserID: domain.RoleOwner,
+		}
+	}
+
+	role, exists := account.Holders[req.HolderUserID]
+
+	if exists && role == domain.RoleOwner && req.Role != domain.RoleOwner && len(owners(account, req.HolderUserID)) == 0 {
+		return errors.New("last owner")
+	}
+
+	err = s.accountRepo.UpdateHolders(
+		accountrepo.UpdateHoldersRequest{
+			ID:     req.AccountID,
+			UserID: req.HolderUserID,
+			Role:   req.Role,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	err = s.userRepo.UpdateAccountIDs(
+		userrepo.UpdateAccountIDsRequest{
+			ID:        req.HolderUserID,
+			AccountID: req.AccountID,
+		},
+	)
+
+	if err != nil {
+		return s.compensateAddHolder(req.AccountID, req.HolderUserID, err)
+	}
+
+	return nil
+}
+
+func (s svc) RemoveHolder(req RemoveHolderRequest) error {
+	if !validID(req.HolderUserID) {
+		return errors.New("invalid holder user id")
+	}
+
+	act := actionManage
+
+	if req.HolderUserID == req.UserID {
+		act = actionView
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return err
+	}
+
+	req.AccountID = accountID
+
+	account, err := s.heldAccount(req.UserID, req.AccountID, act)
+
+	if err != nil {
+		return err
+	}
+
+	role, exists := holderRole(account, req.HolderUserID)
+
+	if !exists || len(account.Holders) == 0 {
+		return errors.New("holder not found")
+	}
+
+	if role == domain.RoleOwner && len(owners(account, req.HolderUserID)) == 0 {
+		return errors.New("last owner")
+	}
+
+	return s.removeHolder(req.AccountID, req.HolderUserID)
+}
+
+func (s svc) Holders(req HoldersRequest) (HoldersResponse, error) {
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return HoldersResponse{}, err
+	}
+
+	req.AccountID = accountID
+
+	account, err := s.heldAccount(req.UserID, req.AccountID, actionView)
+
+	if err != nil {
+		return HoldersResponse{}, err
+	}
+
+	holders := make([]HolderResponse, 0, len(account.Holders))
+
+	for userID, role := range account.Holders {
+		holders = append(
+			holders,
+			HolderResponse{
+				UserID: userID,
+				Role:   role,
+			},
+		)
+	}
+
+	if len(holders) == 0 {
+		holders = append(
+			holders,
+			HolderResponse{
+				UserID: req.UserID,
+				Role:   domain.RoleOwner,
+			},
+		)
+	}
+
+	sort.Slice(holders, func(i, j int) bool {
+		return holders[i].UserID < holders[j].UserID
+	})
+
+	return HoldersResponse{
+		Holders:               holders,
+		DualApprovalThreshold: account.DualApprovalThreshold,
+	}, nil
+}
+
+func (s svc) UpdateAccountRules(req UpdateAccountRulesRequest) error {
+	if req.DualApprovalThreshold.IsNegative() {
+		return errors.New("invalid dual approval threshold")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return err
+	}
+
+	req.AccountID = accountID
+
+	_, err = s.heldAccount(req.UserID, req.AccountID, actionManage)
+
+	if err != nil {
+		return err
+	}
+
+	return s.accountRepo.UpdateRules(
+		accountrepo.UpdateRulesRequest{
+			ID:                    req.AccountID,
+			DualApprovalThreshold: req.DualApprovalThreshold,
+		},
+	)
+}
+
+func (s svc) heldAccount(userID string, accountID string, act action) (domain.Account, error) {
+	if !validID(userID) {
+		return domain.Account{}, errors.New("invalid user id")
+	}
+
+	if !validID(accountID) {
+		return domain.Account{}, errors.New("invalid account id")
+	}
+
+	user, err := s.readUser(userID, act)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	if !userAccount(user.AccountIDs, accountID) {
+		return domain.Account{}, errors.New("unauthorized account id")
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	err = authorize(account, userID, act)
+
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	return account, nil
+}
+
+func (s svc) removeHolder(accountID string, userID string) error {
+	err := s.accountRepo.UpdateHolders(
+		accountrepo.UpdateHoldersRequest{
+			ID:     accountID,
+			UserID: userID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.UpdateAccountIDs(
+		userrepo.UpdateAccountIDsRequest{
+			ID:        userID,
+			AccountID: accountID,
+			Remove:    true,
+		},
+	)
+}
+
+func (s svc) leaveAccount(account domain.Account, userID string) error {
+	if account.Holders[userID] == domain.RoleOwner && len(owners(account, userID)) == 0 {
+		err := s.accountRepo.UpdateHolders(
+			accountrepo.UpdateHoldersRequest{
+				ID:     account.ID,
+				UserID: signers(account, userID)[0],
+				Role:   domain.RoleOwner,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.removeHolder(account.ID, userID)
+}
+
+// rejoinAccount undoes leaveAccount, or as much of it as was done, giving
+// back the roles the account had before.
+func (s svc) rejoinAccount(account domain.Account, userID string) error {
+	err := s.accountRepo.UpdateHolders(
+		accountrepo.UpdateHoldersRequest{
+			ID:     account.ID,
+			UserID: userID,
+			Role:   account.Holders[userID],
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.Read(
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	_, holder := user.AccountIDs[account.ID]
+
+	if !holder {
+		err = s.userRepo.UpdateAccountIDs(
+			userrepo.UpdateAccountIDsRequest{
+				ID:        userID,
+				AccountID: account.ID,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if account.Holders[userID] != domain.RoleOwner || len(owners(account, userID)) > 0 {
+		return nil
+	}
+
+	signer := signers(account, userID)[0]
+
+	return s.accountRepo.UpdateHolders(
+		accountrepo.UpdateHoldersRequest{
+			ID:     account.ID,
+			UserID: signer,
+			Role:   account.Holders[signer],
+		},
+	)
+}
+
+func (s svc) compensateAddHolder(accountID string, userID string, cause error) error {
+	err := s.accountRepo.UpdateHolders(
+		accountrepo.UpdateHoldersRequest{
+			ID:     accountID,
+			UserID: userID,
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
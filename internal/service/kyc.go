@@ -0,0 +1,315 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+)
+
+const (
+	actionOpen    action = "open accounts"
+	actionReceive action = "receive"
+	actionClose   action = "close"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	minimumAge = 18
+)
+
+// statusPermissions lists what a user may do in each onboarding status.
+// Outgoing transfers need a verified user, while pending users can already
+// hold and fund accounts.
+var statusPermissions = map[domain.UserStatus]map[action]struct{}{
+	domain.UserPending: {
+		actionView:    {},
+		actionDeposit: {},
+		actionManage:  {},
+		actionOpen:    {},
+		actionReceive: {},
+		actionClose:   {},
+	},
+	domain.UserKYCInReview: {
+		actionView:    {},
+		actionDeposit: {},
+		actionManage:  {},
+		actionOpen:    {},
+		actionReceive: {},
+		actionClose:   {},
+	},
+	domain.UserVerified: {
+		actionView:     {},
+		actionDeposit:  {},
+		actionWithdraw: {},
+		actionTransfer: {},
+		actionManage:   {},
+		actionOpen:     {},
+		actionReceive:  {},
+		actionClose:    {},
+	},
+	domain.UserRestricted: {
+		actionView:     {},
+		actionDeposit:  {},
+		actionWithdraw: {},
+		actionManage:   {},
+		actionReceive:  {},
+		actionClose:    {},
+	},
+	domain.UserSuspended: {
+		actionView: {},
+	},
+	domain.UserClosed: {},
+}
+
+var userTransitions = map[domain.UserStatus][]domain.UserStatus{
+	domain.UserPending:     {domain.UserKYCInReview, domain.UserSuspended},
+	domain.UserKYCInReview: {domain.UserPending, domain.UserVerified, domain.UserRestricted, domain.UserSuspended},
+	domain.UserVerified:    {domain.UserRestricted, domain.UserSuspended},
+	domain.UserRestricted:  {domain.UserKYCInReview, domain.UserVerified, domain.UserSuspended},
+	domain.UserSuspended:   {domain.UserPending, domain.UserKYCInReview, domain.UserVerified, domain.UserRestricted, domain.UserClosed},
+	domain.UserClosed:      {},
+}
+
+func (s svc) SubmitKYC(req SubmitKYCRequest) (KYCResponse, error) {
+	if !validID(req.UserID) {
+		return KYCResponse{}, errors.New("invalid user id")
+	}
+
+	now := time.Now().UTC()
+
+	kyc, err := kycRecord(req, now)
+
+	if err != nil {
+		return KYCResponse{}, err
+	}
+
+	user, err := s.userRepo.Read(
+		userrepo.ReadRequest{
+			ID: req.UserID,
+		},
+	)
+
+	if err != nil {
+		return KYCResponse{}, err
+	}
+
+	err = transition(user.Status, domain.UserKYCInReview)
+
+	if err != nil {
+		return KYCResponse{}, err
+	}
+
+	err = s.userRepo.UpdateKYC(
+		userrepo.UpdateKYCRequest{
+			ID:     req.UserID,
+			KYC:    kyc,
+			Status: domain.UserKYCInReview,
+		},
+	)
+
+	if err != nil {
+		return KYCResponse{}, err
+	}
+
+	return s.KYC(
+		KYCRequest{
+			UserID: req.UserID,
+		},
+	)
+}
+
+func (s svc) KYC(req KYCRequest) (KYCResponse, error) {
+	if !validID(req.UserID) {
+		return KYCResponse{}, errors.New("invalid user id")
+	}
+
+	user, err := s.userRepo.Read(
+		userrepo.ReadRequest{
+			ID: req.UserID,
+		},
+	)
+
+	if err != nil {
+		return KYCResponse{}, err
+	}
+
+	return kycResponse(user), nil
+}
+
+func (s svc) UpdateUserStatus(req UpdateUserStatusRequest) error {
+	if !validID(req.UserID) {
+		return errors.New("invalid user id")
+	}
+
+	if _, exists := userTransitions[req.Status]; !exists {
+		return errors.New("invalid user status")
+	}
+
+	if req.Status == domain.UserClosed {
+		return errors.New("users are closed through deactivation")
+	}
+
+	user, err := s.userRepo.Read(
+		userrepo.ReadRequest{
+			ID: req.UserID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if !user.DeactivatedAt.IsZero() {
+		return errors.New("user deactivated")
+	}
+
+	err = transition(user.Status, req.Status)
+
+	if err != nil {
+		return err
+	}
+
+	if (req.Status == domain.UserKYCInReview || req.Status == domain.UserVerified) && user.KYC.SubmittedAt.IsZero() {
+		return errors.New("missing kyc record")
+	}
+
+	previous := domain.UserStatus("")
+
+	if req.Status == domain.UserSuspended {
+		previous = user.Status
+	}
+
+	return s.userRepo.UpdateStatus(
+		userrepo.UpdateStatusRequest{
+			ID:             req.UserID,
+			Status:         req.Status,
+			PreviousStatus: previous,
+			StatusReason:   req.Reason,
+		},
+	)
+}
+
+// readUser reads a user and checks their status allows the action.
+func (s svc) readUser(userID string, act action) (domain.User, error) {
+	user, err := s.userRepo.Read(
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	err = checkStatus(user, act)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
+func checkStatus(user domain.User, act action) error {
+	if _, allowed := statusPermissions[user.Status][act]; !allowed {
+		return fmt.Errorf("%s user cannot %s", user.Status, act)
+	}
+
+	return nil
+}
+
+func transition(from domain.UserStatus, to domain.UserStatus) error {
+	for _, status := range userTransitions[from] {
+		if status == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot move %s user to %s", from, to)
+}
+
+func kycRecord(req SubmitKYCRequest, now time.Time) (domain.KYC, error) {
+	dateOfBirth, err := time.Parse(dateLayout, req.DateOfBirth)
+
+	if err != nil || !dateOfBirth.Before(now) {
+		return domain.KYC{}, errors.New("invalid date of birth")
+	}
+
+	if dateOfBirth.AddDate(minimumAge, 0, 0).After(now) {
+		return domain.KYC{}, errors.New("user under minimum age")
+	}
+
+	address := domain.Address{
+		Line1:      strings.TrimSpace(req.Address.Line1),
+		Line2:      strings.TrimSpace(req.Address.Line2),
+		City:       strings.TrimSpace(req.Address.City),
+		PostalCode: strings.TrimSpace(req.Address.PostalCode),
+		Country:    strings.ToUpper(strings.TrimSpace(req.Address.Country)),
+	}
+
+	if address.Line1 == "" || address.City == "" || address.PostalCode == "" {
+		return domain.KYC{}, errors.New("incomplete address")
+	}
+
+	if len(address.Country) != 2 || strings.IndexFunc(address.Country, notLetter) >= 0 {
+		return domain.KYC{}, errors.New("invalid address country")
+	}
+
+	idNumber := strings.ToUpper(strings.ReplaceAll(req.IDNumber, " ", ""))
+
+	if len(idNumber) < 5 || len(idNumber) > 30 || strings.IndexFunc(idNumber, notAlphanumeric) >= 0 {
+		return domain.KYC{}, errors.New("invalid id number")
+	}
+
+	return domain.KYC{
+		DateOfBirth: dateOfBirth,
+		Address:     address,
+		IDNumber:    idNumber,
+		SubmittedAt: now,
+	}, nil
+}
+
+// kycResponse masks all but the last four characters of the ID number.
+func kycResponse(user domain.User) KYCResponse {
+	res := KYCResponse{
+		UserID:          user.ID,
+		Status:          user.Status,
+		StatusReason:    user.StatusReason,
+		StatusUpdatedAt: user.StatusUpdatedAt,
+	}
+
+	if user.KYC.SubmittedAt.IsZero() {
+		return res
+	}
+
+	idNumber := user.KYC.IDNumber
+
+	if len(idNumber) > 4 {
+		idNumber = strings.Repeat("*", len(idNumber)-4) + idNumber[len(idNumber)-4:]
+	}
+
+	res.DateOfBirth = user.KYC.DateOfBirth.Format(dateLayout)
+	res.Address = &AddressTerms{
+		Line1:      user.KYC.Address.Line1,
+		Line2:      user.KYC.Address.Line2,
+		City:       user.KYC.Address.City,
+		PostalCode: user.KYC.Address.PostalCode,
+		Country:    user.KYC.Address.Country,
+	}
+	res.IDNumber = idNumber
+	res.SubmittedAt = user.KYC.SubmittedAt
+
+	return res
+}
+
+func notLetter(r rune) bool {
+	return r < 'A' || r > 'Z'
+}
+
+func notAlphanumeric(r rune) bool {
+	return (r < '0' || r > '9') && notLetter(r)
+}
@@ -0,0 +1,948 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/loanrepo"
+)
+
+const (
+	// loanMetadataKey links a disbursement or repayment back to its loan.
+	loanMetadataKey = "loan_id"
+
+	maxLoanRateBps    = 10000
+	maxLoanTermMonths = 360
+
+	// maxLoanPrincipal keeps a month's interest at the highest rate within
+	// an int64 of minor units.
+	maxLoanPrincipal = (math.MaxInt64 - 60000) / maxLoanRateBps
+)
+
+var errInstalmentClosed = errors.New("instalment already paid")
+
+// GrantLoan fixes the instalments when the loan is granted, the first due a
+// month later.
+func (s svc) GrantLoan(req GrantLoanRequest) (LoanResponse, error) {
+	if !validID(req.UserID) {
+		return LoanResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	account, err := s.heldAccount(req.UserID, accountID, actionWithdraw)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return LoanResponse{}, errors.New("account closed")
+	}
+
+	err = checkIncoming(account, domain.OperationTransferIn)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	terms, err := checkLoanTerms(req.LoanTerms, account.Balance.Currency())
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	now := time.Now().UTC()
+
+	loan, err := s.loanRepo.Create(
+		loanrepo.CreateRequest{
+			Loan: domain.Loan{
+				UserID:                    req.UserID,
+				AccountID:                 account.ID,
+				Principal:                 terms.Principal,
+				InterestRateBps:           terms.InterestRateBps,
+				TermMonths:                terms.TermMonths,
+				Schedule:                  terms.Schedule,
+				LateFee:                   terms.LateFee,
+				Status:                    domain.LoanActive,
+				DisbursementTransactionID: newTransactionID(),
+				Instalments:               amortise(terms, now),
+			},
+		},
+	)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	err = s.bookLoanTransaction(
+		loan.AccountID,
+		loanEntry(loan, domain.OperationLoanDisbursement, loan.DisbursementTransactionID, loan.Principal, loan.CreatedAt),
+	)
+
+	if err != nil {
+		return LoanResponse{}, s.compensateGrantLoan(loan.ID, err)
+	}
+
+	return loanResponse(loan), nil
+}
+
+func (s svc) Loans(req LoansRequest) (LoansResponse, error) {
+	if !validID(req.UserID) {
+		return LoansResponse{}, errors.New("invalid user id")
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return LoansResponse{}, err
+	}
+
+	loans, err := s.loanRepo.List(
+		loanrepo.ListRequest{
+			UserID: req.UserID,
+			Status: req.Status,
+		},
+	)
+
+	if err != nil {
+		return LoansResponse{}, err
+	}
+
+	res := make([]LoanResponse, 0, len(loans))
+
+	for _, loan := range loans {
+		res = append(res, loanResponse(loan))
+	}
+
+	return LoansResponse{
+		Loans: res,
+	}, nil
+}
+
+func (s svc) Loan(req LoanRequest) (LoanResponse, error) {
+	loan, err := s.userLoan(req.UserID, req.LoanID, actionView)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	return loanResponse(loan), nil
+}
+
+// LoanPayoffQuote prices paying off the loan today. Interest accrues daily,
+// so the quote holds until midnight UTC.
+func (s svc) LoanPayoffQuote(req LoanPayoffQuoteRequest) (LoanPayoffQuoteResponse, error) {
+	loan, err := s.userLoan(req.UserID, req.LoanID, actionView)
+
+	if err != nil {
+		return LoanPayoffQuoteResponse{}, err
+	}
+
+	if loan.Status != domain.LoanActive {
+		return LoanPayoffQuoteResponse{}, errors.New("loan repaid")
+	}
+
+	now := time.Now().UTC()
+
+	quote, err := payoffQuote(loan, now)
+
+	if err != nil {
+		return LoanPayoffQuoteResponse{}, err
+	}
+
+	return LoanPayoffQuoteResponse{
+		LoanID:     loan.ID,
+		Amount:     quote.Amount,
+		Principal:  quote.Principal,
+		Interest:   quote.Interest,
+		Fees:       quote.Fees,
+		QuotedAt:   now,
+		ValidUntil: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+// PayOffLoan repays the loan early at the price of its payoff quote.
+func (s svc) PayOffLoan(req PayOffLoanRequest) (LoanResponse, error) {
+	loan, err := s.userLoan(req.UserID, req.LoanID, actionWithdraw)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	accountID := loan.AccountID
+
+	if req.AccountID != "" {
+		accountID, err = s.resolveAccountID(req.AccountID)
+
+		if err != nil {
+			return LoanResponse{}, err
+		}
+	}
+
+	_, err = s.heldAccount(req.UserID, accountID, actionWithdraw)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	transactionID := newTransactionID()
+
+	var instalments []domain.LoanInstalment
+
+	loan, err = s.updateLoan(
+		loan.ID,
+		func(loan *domain.Loan, now time.Time) error {
+			if loan.Status != domain.LoanActive {
+				return errors.New("loan repaid")
+			}
+
+			repayment, err := payoffQuote(*loan, now)
+
+			if err != nil {
+				return err
+			}
+
+			repayment.TransactionID = transactionID
+			repayment.CreatedAt = now
+			repayment.Payoff = true
+
+			instalments = slices.Clone(loan.Instalments)
+
+			for i := range loan.Instalments {
+				instalment := &loan.Instalments[i]
+
+				if !instalmentOpen(*instalment) {
+					continue
+				}
+
+				instalment.Status = domain.InstalmentPaid
+
+				if instalment.DueAt.After(now) {
+					instalment.Status = domain.InstalmentSettled
+				}
+
+				instalment.PaidAt = now
+			}
+
+			loan.Repayments = append(loan.Repayments, repayment)
+			loan.Status = domain.LoanRepaid
+			loan.RepaidAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return LoanResponse{}, err
+	}
+
+	repayment := loan.Repayments[len(loan.Repayments)-1]
+
+	err = s.bookLoanTransaction(
+		accountID,
+		loanEntry(loan, domain.OperationLoanRepayment, repayment.TransactionID, repayment.Amount, repayment.CreatedAt),
+	)
+
+	if err != nil {
+		return LoanResponse{}, s.compensateRepayment(loan.ID, transactionID, instalments, err)
+	}
+
+	return loanResponse(loan), nil
+}
+
+func (s svc) LoanStatement(req LoanStatementRequest) (LoanStatementResponse, error) {
+	loan, err := s.userLoan(req.UserID, req.LoanID, actionView)
+
+	if err != nil {
+		return LoanStatementResponse{}, err
+	}
+
+	zero := money.New(0, loan.Principal.Currency())
+
+	res := LoanStatementResponse{
+		Loan:          loanResponse(loan),
+		Repayments:    make([]LoanRepaymentResponse, 0, len(loan.Repayments)),
+		TotalRepaid:   zero,
+		PrincipalPaid: zero,
+		InterestPaid:  zero,
+		FeesPaid:      zero,
+	}
+
+	for _, repayment := range loan.Repayments {
+		res.Repayments = append(
+			res.Repayments,
+			LoanRepaymentResponse{
+				TransactionID: repayment.TransactionID,
+				CreatedAt:     repayment.CreatedAt,
+				Instalment:    repayment.Instalment,
+				Payoff:        repayment.Payoff,
+				Amount:        repayment.Amount,
+				Principal:     repayment.Principal,
+				Interest:      repayment.Interest,
+				Fees:          repayment.Fees,
+			},
+		)
+
+		res.TotalRepaid, err = res.TotalRepaid.Add(repayment.Amount)
+
+		if err != nil {
+			return LoanStatementResponse{}, err
+		}
+
+		res.PrincipalPaid, err = res.PrincipalPaid.Add(repayment.Principal)
+
+		if err != nil {
+			return LoanStatementResponse{}, err
+		}
+
+		res.InterestPaid, err = res.InterestPaid.Add(repayment.Interest)
+
+		if err != nil {
+			return LoanStatementResponse{}, err
+		}
+
+		res.FeesPaid, err = res.FeesPaid.Add(repayment.Fees)
+
+		if err != nil {
+			return LoanStatementResponse{}, err
+		}
+	}
+
+	return res, nil
+}
+
+// CollectLoanRepayments leaves an instalment the account cannot cover
+// overdue, and the instalments after it wait for a later run.
+func (s svc) CollectLoanRepayments(req CollectLoanRepaymentsRequest) (CollectLoanRepaymentsResponse, error) {
+	loans, err := s.loanRepo.List(
+		loanrepo.ListRequest{
+			Status: domain.LoanActive,
+		},
+	)
+
+	if err != nil {
+		return CollectLoanRepaymentsResponse{}, err
+	}
+
+	collectedLoanIDs := []string{}
+
+	overdueLoanIDs := []string{}
+
+	for _, loan := range loans {
+		collected, overdue, err := s.collectLoan(loan)
+
+		if err != nil {
+			return CollectLoanRepaymentsResponse{}, err
+		}
+
+		if collected {
+			collectedLoanIDs = append(collectedLoanIDs, loan.ID)
+		}
+
+		if overdue {
+			overdueLoanIDs = append(overdueLoanIDs, loan.ID)
+		}
+	}
+
+	sort.Strings(collectedLoanIDs)
+
+	sort.Strings(overdueLoanIDs)
+
+	return CollectLoanRepaymentsResponse{
+		CollectedLoanIDs: collectedLoanIDs,
+		OverdueLoanIDs:   overdueLoanIDs,
+	}, nil
+}
+
+// userLoan tells anyone but the borrower the loan does not exist.
+func (s svc) userLoan(userID string, loanID string, act action) (domain.Loan, error) {
+	if !validID(userID) {
+		return domain.Loan{}, errors.New("invalid user id")
+	}
+
+	if !validID(loanID) {
+		return domain.Loan{}, errors.New("invalid loan id")
+	}
+
+	_, err := s.readUser(userID, act)
+
+	if err != nil {
+		return domain.Loan{}, err
+	}
+
+	loan, err := s.loanRepo.Read(
+		loanrepo.ReadRequest{
+			ID: loanID,
+		},
+	)
+
+	if err != nil {
+		return domain.Loan{}, err
+	}
+
+	if loan.UserID != userID {
+		return domain.Loan{}, errors.New("loan not found")
+	}
+
+	return loan, nil
+}
+
+func (s svc) collectLoan(loan domain.Loan) (bool, bool, error) {
+	collected := false
+
+	for _, instalment := range loan.Instalments {
+		now := time.Now().UTC()
+
+		if !instalmentOpen(instalment) || instalment.DueAt.After(now) {
+			continue
+		}
+
+		amount, err := instalmentAmount(instalment)
+
+		if err != nil {
+			return collected, false, err
+		}
+
+		covered, err := s.covers(loan.AccountID, amount)
+
+		if err != nil {
+			return collected, false, err
+		}
+
+		if !covered {
+			overdue, err := s.markOverdue(loan.ID, instalment.Number)
+
+			return collected, overdue, err
+		}
+
+		err = s.collectInstalment(loan.ID, instalment.Number)
+
+		if errors.Is(err, errInstalmentClosed) {
+			continue
+		}
+
+		if err != nil {
+			return collected, false, err
+		}
+
+		collected = true
+	}
+
+	return collected, false, nil
+}
+
+func (s svc) covers(accountID string, amount money.Money) (bool, error) {
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return false, nil
+	}
+
+	cmp, err := account.Balance.Cmp(amount)
+
+	if err != nil {
+		return false, err
+	}
+
+	return cmp >= 0, nil
+}
+
+// markOverdue reports false when the instalment was already overdue.
+func (s svc) markOverdue(loanID string, number int) (bool, error) {
+	overdue := false
+
+	_, err := s.updateLoan(
+		loanID,
+		func(loan *domain.Loan, now time.Time) error {
+			instalment := &loan.Instalments[number-1]
+
+			overdue = instalment.Status == domain.InstalmentScheduled
+
+			if overdue {
+				instalment.Status = domain.InstalmentOverdue
+				instalment.LateFee = loan.LateFee
+			}
+
+			return nil
+		},
+	)
+
+	return overdue, err
+}
+
+func (s svc) collectInstalment(loanID string, number int) error {
+	transactionID := newTransactionID()
+
+	var instalments []domain.LoanInstalment
+
+	loan, err := s.updateLoan(
+		loanID,
+		func(loan *domain.Loan, now time.Time) error {
+			if loan.Status != domain.LoanActive || !instalmentOpen(loan.Instalments[number-1]) {
+				return errInstalmentClosed
+			}
+
+			instalments = slices.Clone(loan.Instalments)
+
+			instalment := &loan.Instalments[number-1]
+
+			amount, err := instalmentAmount(*instalment)
+
+			if err != nil {
+				return err
+			}
+
+			loan.Repayments = append(
+				loan.Repayments,
+				domain.LoanRepayment{
+					TransactionID: transactionID,
+					CreatedAt:     now,
+					Instalment:    number,
+					Amount:        amount,
+					Principal:     instalment.Principal,
+					Interest:      instalment.Interest,
+					Fees:          instalment.LateFee,
+				},
+			)
+
+			instalment.Status = domain.InstalmentPaid
+			instalment.PaidAt = now
+
+			if !slices.ContainsFunc(loan.Instalments, instalmentOpen) {
+				loan.Status = domain.LoanRepaid
+				loan.RepaidAt = now
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	repayment := loan.Repayments[len(loan.Repayments)-1]
+
+	err = s.bookLoanTransaction(
+		loan.AccountID,
+		loanEntry(loan, domain.OperationLoanRepayment, repayment.TransactionID, repayment.Amount, repayment.CreatedAt),
+	)
+
+	if err != nil {
+		return s.compensateRepayment(loan.ID, transactionID, instalments, err)
+	}
+
+	return nil
+}
+
+func (s svc) bookLoanTransaction(accountID string, transaction domain.Transaction) error {
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return errors.New("account closed")
+	}
+
+	update := accountrepo.UpdateBalanceRequest{
+		ID: accountID,
+	}
+
+	if transaction.Operation == domain.OperationLoanDisbursement {
+		update.Credit = transaction.Amount
+	} else {
+		update.Debit = transaction.Amount
+	}
+
+	_, err = s.accountRepo.UpdateBalance(update)
+
+	if err != nil {
+		return err
+	}
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          accountID,
+			Transaction: transaction,
+		},
+	)
+}
+
+func (s svc) compensateGrantLoan(loanID string, cause error) error {
+	err := s.loanRepo.Delete(
+		loanrepo.DeleteRequest{
+			ID: loanID,
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) compensateRepayment(loanID string, transactionID string, instalments []domain.LoanInstalment, cause error) error {
+	_, err := s.updateLoan(
+		loanID,
+		func(loan *domain.Loan, now time.Time) error {
+			loan.Repayments = slices.DeleteFunc(
+				loan.Repayments,
+				func(repayment domain.LoanRepayment) bool {
+					return repayment.TransactionID == transactionID
+				},
+			)
+
+			loan.Instalments = instalments
+			loan.Status = domain.LoanActive
+			loan.RepaidAt = time.Time{}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) updateLoan(loanID string, apply func(*domain.Loan, time.Time) error) (domain.Loan, error) {
+	for attempt := 1; ; attempt++ {
+		loan, err := s.loanRepo.Read(
+			loanrepo.ReadRequest{
+				ID: loanID,
+			},
+		)
+
+		if err != nil {
+			return domain.Loan{}, err
+		}
+
+		err = apply(&loan, time.Now().UTC())
+
+		if err != nil {
+			return domain.Loan{}, err
+		}
+
+		loan, err = s.loanRepo.Update(
+			loanrepo.UpdateRequest{
+				Loan: loan,
+			},
+		)
+
+		if errors.Is(err, loanrepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return loan, err
+	}
+}
+
+// checkLoanTerms treats a missing late fee as no late fee.
+func checkLoanTerms(terms LoanTerms, currency money.Currency) (LoanTerms, error) {
+	if !terms.Principal.IsPositive() || terms.Principal.Currency() != currency || terms.Principal.Minor() > maxLoanPrincipal {
+		return LoanTerms{}, errors.New("invalid principal")
+	}
+
+	if terms.InterestRateBps < 0 || terms.InterestRateBps > maxLoanRateBps {
+		return LoanTerms{}, errors.New("invalid interest rate")
+	}
+
+	if terms.TermMonths < 1 || terms.TermMonths > maxLoanTermMonths {
+		return LoanTerms{}, errors.New("invalid loan term")
+	}
+
+	if terms.Schedule != domain.LoanAnnuity && terms.Schedule != domain.LoanLinear {
+		return LoanTerms{}, errors.New("invalid loan schedule")
+	}
+
+	if terms.LateFee.IsZero() {
+		terms.LateFee = money.New(0, currency)
+	}
+
+	if terms.LateFee.IsNegative() || terms.LateFee.Currency() != currency {
+		return LoanTerms{}, errors.New("invalid late fee")
+	}
+
+	return terms, nil
+}
+
+// amortise works in minor units. The last instalment takes whatever
+// principal the rounding left.
+func amortise(terms LoanTerms, start time.Time) []domain.LoanInstalment {
+	currency := terms.Principal.Currency()
+
+	balance := terms.Principal.Minor()
+
+	share := balance / int64(terms.TermMonths)
+
+	payment := int64(0)
+
+	if terms.Schedule == domain.LoanAnnuity && terms.InterestRateBps > 0 {
+		payment = annuityPayment(balance, terms.InterestRateBps, terms.TermMonths)
+	}
+
+	instalments := make([]domain.LoanInstalment, 0, terms.TermMonths)
+
+	for i := range terms.TermMonths {
+		interest := monthlyInterest(balance, terms.InterestRateBps)
+
+		principal := share
+
+		if payment > 0 {
+			principal = min(payment-interest, balance)
+		}
+
+		if i == terms.TermMonths-1 {
+			principal = balance
+		}
+
+		balance -= principal
+
+		instalments = append(
+			instalments,
+			domain.LoanInstalment{
+				Number:    i + 1,
+				DueAt:     addMonths(start, i+1),
+				Principal: money.New(principal, currency),
+				Interest:  money.New(interest, currency),
+				LateFee:   money.New(0, currency),
+				Status:    domain.InstalmentScheduled,
+			},
+		)
+	}
+
+	return instalments
+}
+
+// monthlyInterest charges a twelfth of the yearly rate, rounded half up.
+func monthlyInterest(balance int64, bps int) int64 {
+	return (balance*int64(bps) + 60000) / 120000
+}
+
+// annuityPayment is computed exactly and rounded half up.
+func annuityPayment(principal int64, bps int, months int) int64 {
+	one := big.NewRat(1, 1)
+
+	rate := big.NewRat(int64(bps), 120000)
+
+	growth := new(big.Rat).Add(one, rate)
+
+	factor := new(big.Rat).Set(one)
+
+	for range months {
+		factor.Mul(factor, growth)
+	}
+
+	payment := new(big.Rat).SetInt64(principal)
+
+	payment.Mul(payment, rate)
+	payment.Mul(payment, factor)
+	payment.Quo(payment, new(big.Rat).Sub(factor, one))
+	payment.Add(payment, big.NewRat(1, 2))
+
+	return new(big.Int).Quo(payment.Num(), payment.Denom()).Int64()
+}
+
+// addMonths falls back to the last day of shorter months.
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+
+	last := first.AddDate(0, 1, -1).Day()
+
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// payoffQuote accrues interest on the principal left on an actual/365 basis
+// since the last due date.
+func payoffQuote(loan domain.Loan, now time.Time) (domain.LoanRepayment, error) {
+	zero := money.New(0, loan.Principal.Currency())
+
+	quote := domain.LoanRepayment{
+		Principal: zero,
+		Interest:  zero,
+		Fees:      zero,
+	}
+
+	outstanding := zero
+
+	accruedFrom := loan.CreatedAt
+
+	var err error
+
+	for _, instalment := range loan.Instalments {
+		due := !instalment.DueAt.After(now)
+
+		if due && instalment.DueAt.After(accruedFrom) {
+			accruedFrom = instalment.DueAt
+		}
+
+		if !instalmentOpen(instalment) {
+			continue
+		}
+
+		if !due {
+			outstanding, err = outstanding.Add(instalment.Principal)
+
+			if err != nil {
+				return domain.LoanRepayment{}, err
+			}
+
+			continue
+		}
+
+		quote.Interest, err = quote.Interest.Add(instalment.Interest)
+
+		if err != nil {
+			return domain.LoanRepayment{}, err
+		}
+
+		quote.Fees, err = quote.Fees.Add(instalment.LateFee)
+
+		if err != nil {
+			return domain.LoanRepayment{}, err
+		}
+
+		quote.Principal, err = quote.Principal.Add(instalment.Principal)
+
+		if err != nil {
+			return domain.LoanRepayment{}, err
+		}
+	}
+
+	days := int64(now.Sub(accruedFrom) / (24 * time.Hour))
+
+	accrued := int64(math.Round(float64(outstanding.Minor()) * float64(loan.InterestRateBps) / 10000 * float64(days) / 365))
+
+	quote.Principal, err = quote.Principal.Add(outstanding)
+
+	if err != nil {
+		return domain.LoanRepayment{}, err
+	}
+
+	quote.Interest, err = quote.Interest.Add(money.New(accrued, zero.Currency()))
+
+	if err != nil {
+		return domain.LoanRepayment{}, err
+	}
+
+	quote.Amount, err = quote.Principal.Add(quote.Interest)
+
+	if err != nil {
+		return domain.LoanRepayment{}, err
+	}
+
+	quote.Amount, err = quote.Amount.Add(quote.Fees)
+
+	if err != nil {
+		return domain.LoanRepayment{}, err
+	}
+
+	return quote, nil
+}
+
+func instalmentOpen(instalment domain.LoanInstalment) bool {
+	return instalment.Status == domain.InstalmentScheduled || instalment.Status == domain.InstalmentOverdue
+}
+
+func instalmentAmount(instalment domain.LoanInstalment) (money.Money, error) {
+	amount, err := instalment.Principal.Add(instalment.Interest)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return amount.Add(instalment.LateFee)
+}
+
+func loanEntry(loan domain.Loan, op string, transactionID string, amount money.Money, now time.Time) domain.Transaction {
+	return domain.Transaction{
+		ID:        transactionID,
+		Timestamp: now,
+		Operation: op,
+		Amount:    amount,
+		Metadata: map[string]string{
+			loanMetadataKey: loan.ID,
+		},
+	}
+}
+
+func loanResponse(loan domain.Loan) LoanResponse {
+	outstanding := money.New(0, loan.Principal.Currency())
+
+	arrears := outstanding
+
+	instalments := make([]LoanInstalmentResponse, 0, len(loan.Instalments))
+
+	for _, instalment := range loan.Instalments {
+		amount, _ := instalmentAmount(instalment)
+
+		instalments = append(
+			instalments,
+			LoanInstalmentResponse{
+				Number:    instalment.Number,
+				DueAt:     instalment.DueAt,
+				Amount:    amount,
+				Principal: instalment.Principal,
+				Interest:  instalment.Interest,
+				LateFee:   instalment.LateFee,
+				Status:    instalment.Status,
+				PaidAt:    instalment.PaidAt,
+			},
+		)
+
+		if instalmentOpen(instalment) {
+			outstanding, _ = outstanding.Add(instalment.Principal)
+		}
+
+		if instalment.Status == domain.InstalmentOverdue {
+			arrears, _ = arrears.Add(amount)
+		}
+	}
+
+	return LoanResponse{
+		LoanID:                    loan.ID,
+		UserID:                    loan.UserID,
+		AccountID:                 loan.AccountID,
+		Principal:                 loan.Principal,
+		InterestRateBps:           loan.InterestRateBps,
+		TermMonths:                loan.TermMonths,
+		Schedule:                  loan.Schedule,
+		LateFee:                   loan.LateFee,
+		Status:                    loan.Status,
+		OutstandingPrincipal:      outstanding,
+		Arrears:                   arrears,
+		DisbursementTransactionID: loan.DisbursementTransactionID,
+		CreatedAt:                 loan.CreatedAt,
+		RepaidAt:                  loan.RepaidAt,
+		Instalments:               instalments,
+	}
+}
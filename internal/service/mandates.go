@@ -0,0 +1,666 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	guuid "github.com/google/uuid"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/mandaterepo"
+)
+
+const (
+	MandateRoleDebtor   = "debtor"
+	MandateRoleCreditor = "creditor"
+
+	// mandateMetadataKey and collectionMetadataKey link the transfer of a
+	// collection back to it.
+	mandateMetadataKey    = "mandate_id"
+	collectionMetadataKey = "collection_id"
+)
+
+var mandateFrequencies = map[domain.MandateFrequency]struct{}{
+	domain.MandateOneOff:    {},
+	domain.MandateWeekly:    {},
+	domain.MandateMonthly:   {},
+	domain.MandateQuarterly: {},
+	domain.MandateYearly:    {},
+}
+
+// GrantMandate approves the maximum against the dual approval threshold once,
+// as collections are not approved.
+func (s svc) GrantMandate(req GrantMandateRequest) (MandateResponse, error) {
+	if !validID(req.UserID) {
+		return MandateResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	creditorAccountID, err := s.resolveAccountID(req.CreditorAccountID)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	if !validID(creditorAccountID) {
+		return MandateResponse{}, errors.New("invalid creditor account id")
+	}
+
+	if !req.MaxAmount.IsPositive() {
+		return MandateResponse{}, errors.New("invalid max amount")
+	}
+
+	if _, exists := mandateFrequencies[req.Frequency]; !exists {
+		return MandateResponse{}, errors.New("invalid frequency")
+	}
+
+	if accountID == creditorAccountID {
+		return MandateResponse{}, errors.New("same account")
+	}
+
+	account, err := s.heldAccount(req.UserID, accountID, actionTransfer)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return MandateResponse{}, errors.New("account closed")
+	}
+
+	creditorAccount, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: creditorAccountID,
+		},
+	)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	if !creditorAccount.ClosedAt.IsZero() {
+		return MandateResponse{}, errors.New("creditor account closed")
+	}
+
+	err = checkIncoming(creditorAccount, domain.OperationTransferIn)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	for _, party := range []domain.Account{account, creditorAccount} {
+		_, err = party.Balance.Cmp(req.MaxAmount)
+
+		if err != nil {
+			return MandateResponse{}, err
+		}
+	}
+
+	err = s.approve(account, req.UserID, req.ApproverUserID, req.MaxAmount)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	mandate, err := s.mandateRepo.Create(
+		mandaterepo.CreateRequest{
+			Mandate: domain.Mandate{
+				DebtorUserID:      req.UserID,
+				DebtorAccountID:   account.ID,
+				CreditorAccountID: creditorAccount.ID,
+				CreditorIBAN:      creditorAccount.IBAN,
+				MaxAmount:         req.MaxAmount,
+				Frequency:         req.Frequency,
+				Status:            domain.MandateActive,
+			},
+		},
+	)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	return mandateResponse(mandate), nil
+}
+
+func (s svc) Mandates(req MandatesRequest) (MandatesResponse, error) {
+	if !validID(req.UserID) {
+		return MandatesResponse{}, errors.New("invalid user id")
+	}
+
+	if req.Role != "" && req.Role != MandateRoleDebtor && req.Role != MandateRoleCreditor {
+		return MandatesResponse{}, errors.New("invalid role")
+	}
+
+	user, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return MandatesResponse{}, err
+	}
+
+	mandates := []domain.Mandate{}
+
+	if req.Role != MandateRoleCreditor {
+		granted, err := s.mandateRepo.List(
+			mandaterepo.ListRequest{
+				DebtorUserID: req.UserID,
+			},
+		)
+
+		if err != nil {
+			return MandatesResponse{}, err
+		}
+
+		mandates = append(mandates, granted...)
+	}
+
+	if req.Role != MandateRoleDebtor {
+		for _, accountID := range sortedAccountIDs(user.AccountIDs) {
+			received, err := s.mandateRepo.List(
+				mandaterepo.ListRequest{
+					CreditorAccountID: accountID,
+				},
+			)
+
+			if err != nil {
+				return MandatesResponse{}, err
+			}
+
+			for _, mandate := range received {
+				// A mandate between the user's own accounts is already
+				// listed as granted.
+				if req.Role == "" && mandate.DebtorUserID == req.UserID {
+					continue
+				}
+
+				mandates = append(mandates, mandate)
+			}
+		}
+	}
+
+	sort.SliceStable(mandates, func(i, j int) bool {
+		return mandates[i].CreatedAt.After(mandates[j].CreatedAt)
+	})
+
+	res := make([]MandateResponse, 0, len(mandates))
+
+	for _, mandate := range mandates {
+		res = append(res, mandateResponse(mandate))
+	}
+
+	return MandatesResponse{
+		Mandates: res,
+	}, nil
+}
+
+func (s svc) Mandate(req MandateRequest) (MandateResponse, error) {
+	mandate, _, err := s.userMandate(req.UserID, req.MandateID)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	return mandateResponse(mandate), nil
+}
+
+// RevokeMandate leaves collections already made refundable.
+func (s svc) RevokeMandate(req RevokeMandateRequest) (MandateResponse, error) {
+	mandate, role, err := s.userMandate(req.UserID, req.MandateID)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	if role != MandateRoleDebtor {
+		return MandateResponse{}, errors.New("unauthorized mandate id")
+	}
+
+	mandate, err = s.updateMandate(
+		mandate.ID,
+		func(mandate *domain.Mandate, now time.Time) error {
+			if mandate.Status != domain.MandateActive {
+				return errors.New("mandate not active")
+			}
+
+			mandate.Status = domain.MandateRevoked
+			mandate.RevokedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return MandateResponse{}, err
+	}
+
+	return mandateResponse(mandate), nil
+}
+
+// CollectPayment takes the period before booking the debit, so two
+// collections cannot both use it.
+func (s svc) CollectPayment(req CollectPaymentRequest) (CollectionResponse, error) {
+	mandate, role, err := s.userMandate(req.UserID, req.MandateID)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	if role != MandateRoleCreditor {
+		return CollectionResponse{}, errors.New("unauthorized mandate id")
+	}
+
+	if !req.Amount.IsPositive() {
+		return CollectionResponse{}, errors.New("invalid amount")
+	}
+
+	details, err := checkDetails(
+		TransactionDetails{
+			Description: req.Description,
+			Reference:   req.Reference,
+		},
+	)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	_, err = s.heldAccount(req.UserID, mandate.CreditorAccountID, actionDeposit)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	collectionID := guuid.NewString()
+
+	mandate, err = s.updateMandate(
+		mandate.ID,
+		func(mandate *domain.Mandate, now time.Time) error {
+			err := checkCollection(*mandate, req, now)
+
+			if err != nil {
+				return err
+			}
+
+			mandate.Collections = append(
+				mandate.Collections,
+				domain.Collection{
+					ID:             collectionID,
+					CreatedAt:      now,
+					CreditorUserID: req.UserID,
+					Amount:         req.Amount,
+					Description:    details.Description,
+					Reference:      details.Reference,
+					Status:         domain.CollectionPending,
+				},
+			)
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	transfer := TransferRequest{
+		SenderUserID:      mandate.DebtorUserID,
+		ReceiverUserID:    req.UserID,
+		SenderAccountID:   mandate.DebtorAccountID,
+		ReceiverAccountID: mandate.CreditorAccountID,
+		Amount:            req.Amount,
+		TransactionDetails: TransactionDetails{
+			Description: details.Description,
+			Reference:   details.Reference,
+			Metadata: map[string]string{
+				mandateMetadataKey:    mandate.ID,
+				collectionMetadataKey: collectionID,
+			},
+		},
+		mandated: true,
+	}
+
+	plan, err := s.planTransfer(transfer)
+
+	var transferRes TransferResponse
+
+	if err == nil {
+		transferRes, err = s.executeTransfer(transfer, plan)
+	}
+
+	mandate, updateErr := s.updateMandate(
+		mandate.ID,
+		func(mandate *domain.Mandate, now time.Time) error {
+			collection := &mandate.Collections[collectionIndex(*mandate, collectionID)]
+
+			if err != nil {
+				collection.Status = domain.CollectionFailed
+				collection.FailureReason = err.Error()
+
+				return nil
+			}
+
+			collection.Status = domain.CollectionCompleted
+			collection.Fee = plan.fee
+			collection.TransactionID = transferRes.TransactionID
+			collection.RefundableUntil = now.Add(s.refundPeriod)
+
+			return nil
+		},
+	)
+
+	if updateErr != nil {
+		return CollectionResponse{}, updateErr
+	}
+
+	return collectionResponse(mandate.ID, mandate.Collections[collectionIndex(mandate, collectionID)]), nil
+}
+
+func (s svc) Collections(req CollectionsRequest) (CollectionsResponse, error) {
+	mandate, _, err := s.userMandate(req.UserID, req.MandateID)
+
+	if err != nil {
+		return CollectionsResponse{}, err
+	}
+
+	res := make([]CollectionResponse, 0, len(mandate.Collections))
+
+	for i := len(mandate.Collections) - 1; i >= 0; i-- {
+		collection := mandate.Collections[i]
+
+		if req.Status != "" && collection.Status != req.Status {
+			continue
+		}
+
+		res = append(res, collectionResponse(mandate.ID, collection))
+	}
+
+	return CollectionsResponse{
+		Collections: res,
+	}, nil
+}
+
+func (s svc) Collection(req CollectionRequest) (CollectionResponse, error) {
+	mandate, _, err := s.userMandate(req.UserID, req.MandateID)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	i := collectionIndex(mandate, req.CollectionID)
+
+	if i < 0 {
+		return CollectionResponse{}, errors.New("collection not found")
+	}
+
+	return collectionResponse(mandate.ID, mandate.Collections[i]), nil
+}
+
+// RefundCollection does not ask the creditor within the refund period.
+func (s svc) RefundCollection(req RefundCollectionRequest) (CollectionResponse, error) {
+	mandate, role, err := s.userMandate(req.UserID, req.MandateID)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	if role != MandateRoleDebtor {
+		return CollectionResponse{}, errors.New("unauthorized mandate id")
+	}
+
+	mandate, err = s.updateMandate(
+		mandate.ID,
+		func(mandate *domain.Mandate, now time.Time) error {
+			i := collectionIndex(*mandate, req.CollectionID)
+
+			if i < 0 {
+				return errors.New("collection not found")
+			}
+
+			if mandate.Collections[i].Status != domain.CollectionCompleted {
+				return errors.New("collection not refundable")
+			}
+
+			if now.After(mandate.Collections[i].RefundableUntil) {
+				return errors.New("refund period ended")
+			}
+
+			mandate.Collections[i].Status = domain.CollectionRefunded
+			mandate.Collections[i].RefundedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return CollectionResponse{}, err
+	}
+
+	collection := mandate.Collections[collectionIndex(mandate, req.CollectionID)]
+
+	err = s.refundCollection(mandate, collection)
+
+	if err != nil {
+		return CollectionResponse{}, s.compensateRefundCollection(mandate.ID, collection.ID, err)
+	}
+
+	return collectionResponse(mandate.ID, collection), nil
+}
+
+// userMandate says which side of the mandate the user is on. Anyone else is
+// told it does not exist.
+func (s svc) userMandate(userID string, mandateID string) (domain.Mandate, string, error) {
+	if !validID(userID) {
+		return domain.Mandate{}, "", errors.New("invalid user id")
+	}
+
+	if !validID(mandateID) {
+		return domain.Mandate{}, "", errors.New("invalid mandate id")
+	}
+
+	user, err := s.readUser(userID, actionView)
+
+	if err != nil {
+		return domain.Mandate{}, "", err
+	}
+
+	mandate, err := s.mandateRepo.Read(
+		mandaterepo.ReadRequest{
+			ID: mandateID,
+		},
+	)
+
+	if err != nil {
+		return domain.Mandate{}, "", err
+	}
+
+	if mandate.DebtorUserID == userID {
+		return mandate, MandateRoleDebtor, nil
+	}
+
+	if userAccount(user.AccountIDs, mandate.CreditorAccountID) {
+		return mandate, MandateRoleCreditor, nil
+	}
+
+	return domain.Mandate{}, "", errors.New("mandate not found")
+}
+
+// refundCollection needs the creditor account to still hold the amount.
+func (s svc) refundCollection(mandate domain.Mandate, collection domain.Collection) error {
+	creditorAccount, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: mandate.CreditorAccountID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	cmp, err := creditorAccount.Balance.Cmp(collection.Amount)
+
+	if err != nil {
+		return err
+	}
+
+	if cmp < 0 {
+		return errors.New("insuficient funds for refund")
+	}
+
+	return s.reverseTransfer(
+		TransferRequest{
+			SenderUserID:      mandate.DebtorUserID,
+			ReceiverUserID:    collection.CreditorUserID,
+			SenderAccountID:   mandate.DebtorAccountID,
+			ReceiverAccountID: mandate.CreditorAccountID,
+			Amount:            collection.Amount,
+		},
+		collection.Fee,
+	)
+}
+
+func (s svc) compensateRefundCollection(mandateID string, collectionID string, cause error) error {
+	_, err := s.updateMandate(
+		mandateID,
+		func(mandate *domain.Mandate, now time.Time) error {
+			i := collectionIndex(*mandate, collectionID)
+
+			mandate.Collections[i].Status = domain.CollectionCompleted
+			mandate.Collections[i].RefundedAt = time.Time{}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) updateMandate(mandateID string, apply func(*domain.Mandate, time.Time) error) (domain.Mandate, error) {
+	for attempt := 1; ; attempt++ {
+		mandate, err := s.mandateRepo.Read(
+			mandaterepo.ReadRequest{
+				ID: mandateID,
+			},
+		)
+
+		if err != nil {
+			return domain.Mandate{}, err
+		}
+
+		err = apply(&mandate, time.Now().UTC())
+
+		if err != nil {
+			return domain.Mandate{}, err
+		}
+
+		mandate, err = s.mandateRepo.Update(
+			mandaterepo.UpdateRequest{
+				Mandate: mandate,
+			},
+		)
+
+		if errors.Is(err, mandaterepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return mandate, err
+	}
+}
+
+// checkCollection does not count failed collections against the period.
+func checkCollection(mandate domain.Mandate, req CollectPaymentRequest, now time.Time) error {
+	if mandate.Status != domain.MandateActive {
+		return errors.New("mandate not active")
+	}
+
+	cmp, err := req.Amount.Cmp(mandate.MaxAmount)
+
+	if err != nil {
+		return err
+	}
+
+	if cmp > 0 {
+		return errors.New("invalid amount, above mandate maximum")
+	}
+
+	start := periodStart(mandate.Frequency, now)
+
+	for _, collection := range mandate.Collections {
+		if collection.Status != domain.CollectionFailed && !collection.CreatedAt.Before(start) {
+			return errors.New("mandate already collected this period")
+		}
+	}
+
+	return nil
+}
+
+// periodStart is in UTC with weeks starting on Monday. A one-off mandate has
+// a single period.
+func periodStart(frequency domain.MandateFrequency, now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+
+	switch frequency {
+	case domain.MandateWeekly:
+		return time.Date(year, month, day-(int(now.UTC().Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case domain.MandateMonthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case domain.MandateQuarterly:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case domain.MandateYearly:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Time{}
+	}
+}
+
+func collectionIndex(mandate domain.Mandate, collectionID string) int {
+	for i, collection := range mandate.Collections {
+		if collection.ID == collectionID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func mandateResponse(mandate domain.Mandate) MandateResponse {
+	return MandateResponse{
+		MandateID:         mandate.ID,
+		DebtorUserID:      mandate.DebtorUserID,
+		DebtorAccountID:   mandate.DebtorAccountID,
+		CreditorAccountID: mandate.CreditorAccountID,
+		CreditorIBAN:      mandate.CreditorIBAN,
+		MaxAmount:         mandate.MaxAmount,
+		Frequency:         mandate.Frequency,
+		Status:            mandate.Status,
+		CreatedAt:         mandate.CreatedAt,
+		RevokedAt:         mandate.RevokedAt,
+	}
+}
+
+func collectionResponse(mandateID string, collection domain.Collection) CollectionResponse {
+	return CollectionResponse{
+		CollectionID:    collection.ID,
+		MandateID:       mandateID,
+		CreditorUserID:  collection.CreditorUserID,
+		Amount:          collection.Amount,
+		Fee:             collection.Fee,
+		Description:     collection.Description,
+		Reference:       collection.Reference,
+		Status:          collection.Status,
+		FailureReason:   collection.FailureReason,
+		TransactionID:   collection.TransactionID,
+		CreatedAt:       collection.CreatedAt,
+		RefundableUntil: collection.RefundableUntil,
+		RefundedAt:      collection.RefundedAt,
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/card"
+	"github.com/hetfdex/tiny-bank/internal/iban"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/cardrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/categoryrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/loanrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/mandaterepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/payeerepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/paymentrequestrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/pendingtransferrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/potrepo"
+)
+
+const (
+	defaultGracePeriod = 30 * 24 * time.Hour
+	defaultProductID   = "checking"
+	defaultIBANCountry = "NL"
+	defaultBankCode    = "TINY"
+	defaultCoolingOff  = 24 * time.Hour
+	defaultRequestTTL  = 7 * 24 * time.Hour
+	defaultPendingTTL  = 72 * time.Hour
+	defaultHoldTTL     = 7 * 24 * time.Hour
+
+	// defaultRefundPeriod is the eight weeks a SEPA debtor has to ask for
+	// a direct debit back.
+	defaultRefundPeriod = 8 * 7 * 24 * time.Hour
+
+	defaultCardBIN = "499999"
+)
+
+var (
+	defaultIssuer     = iban.MustNewIssuer(defaultIBANCountry, defaultBankCode)
+	defaultCardIssuer = card.MustNewIssuer(defaultCardBIN)
+)
+
+type Option func(*svc)
+
+// PendingTransferPolicy picks the transfers that wait for the receiver to
+// accept them instead of moving at once.
+type PendingTransferPolicy struct {
+	// Threshold holds transfers of at least this amount. Zero holds none.
+	Threshold money.Money
+
+	// FirstTimePayee holds the first transfer from an account to an account
+	// of someone else.
+	FirstTimePayee bool
+}
+
+func WithGracePeriod(gracePeriod time.Duration) Option {
+	return func(s *svc) {
+		s.gracePeriod = gracePeriod
+	}
+}
+
+func WithDefaultProduct(productID string) Option {
+	return func(s *svc) {
+		s.defaultProductID = productID
+	}
+}
+
+// WithDefaultTier sets the velocity limit tier given to new users. Without
+// it new users have no tier and no velocity limits.
+func WithDefaultTier(tierID string) Option {
+	return func(s *svc) {
+		s.defaultTierID = tierID
+	}
+}
+
+// WithIBANIssuer sets the country and bank code of the IBANs given to new
+// accounts.
+func WithIBANIssuer(issuer iban.Issuer) Option {
+	return func(s *svc) {
+		s.ibanIssuer = issuer
+	}
+}
+
+// WithPayeeRepo stores payee books in the given repo. Without it they are
+// kept in memory.
+func WithPayeeRepo(payeeRepo payeerepo.Repo) Option {
+	return func(s *svc) {
+		s.payeeRepo = payeeRepo
+	}
+}
+
+// WithPayeeCoolingOff sets how long transfers to a newly added payee are
+// held back.
+func WithPayeeCoolingOff(coolingOff time.Duration) Option {
+	return func(s *svc) {
+		s.payeeCoolingOff = coolingOff
+	}
+}
+
+// WithPaymentRequestRepo stores payment requests in the given repo. Without
+// it they are kept in memory.
+func WithPaymentRequestRepo(paymentRequestRepo paymentrequestrepo.Repo) Option {
+	return func(s *svc) {
+		s.paymentRequestRepo = paymentRequestRepo
+	}
+}
+
+// WithPaymentRequestTTL sets how long payment requests stay open when the
+// requester does not say.
+func WithPaymentRequestTTL(ttl time.Duration) Option {
+	return func(s *svc) {
+		s.paymentRequestTTL = ttl
+	}
+}
+
+// WithPendingTransferRepo stores pending transfers in the given repo.
+// Without it they are kept in memory.
+func WithPendingTransferRepo(pendingTransferRepo pendingtransferrepo.Repo) Option {
+	return func(s *svc) {
+		s.pendingTransferRepo = pendingTransferRepo
+	}
+}
+
+// WithPendingTransferTTL sets how long the receiver has to accept a pending
+// transfer when the sender does not say.
+func WithPendingTransferTTL(ttl time.Duration) Option {
+	return func(s *svc) {
+		s.pendingTransferTTL = ttl
+	}
+}
+
+// WithPendingTransferPolicy holds the transfers the policy picks. Without it
+// a transfer is only held when the sender asks for it.
+func WithPendingTransferPolicy(policy PendingTransferPolicy) Option {
+	return func(s *svc) {
+		s.pendingPolicy = policy
+	}
+}
+
+// WithMandateRepo stores direct debit mandates and their collections in the
+// given repo. Without it they are kept in memory.
+func WithMandateRepo(mandateRepo mandaterepo.Repo) Option {
+	return func(s *svc) {
+		s.mandateRepo = mandateRepo
+	}
+}
+
+// WithRefundPeriod sets how long a debtor can have a direct debit collection
+// refunded.
+func WithRefundPeriod(refundPeriod time.Duration) Option {
+	return func(s *svc) {
+		s.refundPeriod = refundPeriod
+	}
+}
+
+// WithCardRepo stores cards and their authorisations in the given repo.
+// Without it they are kept in memory.
+func WithCardRepo(cardRepo cardrepo.Repo) Option {
+	return func(s *svc) {
+		s.cardRepo = cardRepo
+	}
+}
+
+// WithCardIssuer sets the BIN of the card numbers given to new cards.
+func WithCardIssuer(issuer card.Issuer) Option {
+	return func(s *svc) {
+		s.cardIssuer = issuer
+	}
+}
+
+// WithCardSecret keys the CVV hashes. Without it a random secret is used,
+// and cards issued before a restart stop authorising.
+func WithCardSecret(secret []byte) Option {
+	return func(s *svc) {
+		s.cardSecret = secret
+	}
+}
+
+// WithCardHoldTTL sets how long a card authorisation holds its amount before
+// it expires and the amount goes back to the account.
+func WithCardHoldTTL(ttl time.Duration) Option {
+	return func(s *svc) {
+		s.cardHoldTTL = ttl
+	}
+}
+
+// WithMerchantKeys sets the key each merchant sends to authorise, capture or
+// reverse its authorisations. Without it no merchant can.
+func WithMerchantKeys(keys map[string]string) Option {
+	return func(s *svc) {
+		s.merchantKeys = keys
+	}
+}
+
+// WithLoanRepo stores loans, their schedules and repayments in the given
+// repo. Without it they are kept in memory.
+func WithLoanRepo(loanRepo loanrepo.Repo) Option {
+	return func(s *svc) {
+		s.loanRepo = loanRepo
+	}
+}
+
+// WithPotRepo stores account pots and their auto-save rules in the given
+// repo instead of in memory.
+func WithPotRepo(potRepo potrepo.Repo) Option {
+	return func(s *svc) {
+		s.potRepo = potRepo
+	}
+}
+
+// WithCategoryRepo stores users' category rules and the categories they set
+// by hand in the given repo instead of in memory.
+func WithCategoryRepo(categoryRepo categoryrepo.Repo) Option {
+	return func(s *svc) {
+		s.categoryRepo = categoryRepo
+	}
+}
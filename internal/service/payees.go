@@ -0,0 +1,464 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hetfdex/tiny-bank/internal/cop"
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/payeerepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+)
+
+const (
+	maxNicknameLength  = 40
+	maxPayeeNameLength = 140
+)
+
+// PayeeNameError is returned when the name given for a receiver is not an
+// exact match for any holder of the account. On a close match Name is the
+// holder's name, so the payer can correct theirs; it is never disclosed
+// otherwise.
+type PayeeNameError struct {
+	Match domain.PayeeMatch
+	Name  string
+}
+
+func (e PayeeNameError) Error() string {
+	if e.Match == domain.PayeeMatchClose {
+		return fmt.Sprintf("payee name is a close match for %s", e.Name)
+	}
+
+	return "payee name does not match"
+}
+
+// PayeeCoolingOffError is returned for a transfer to a payee that was added
+// too recently. Transfers to it are accepted from Until.
+type PayeeCoolingOffError struct {
+	Until time.Time
+}
+
+func (e PayeeCoolingOffError) Error() string {
+	return "payee in cooling-off period until " + e.Until.Format(time.RFC3339)
+}
+
+// AddPayee checks the name against the account's holders before saving the
+// payee. Transfers to it are held for the cooling-off period, unless the
+// account is one the user holds themselves.
+func (s svc) AddPayee(req AddPayeeRequest) (PayeeResponse, error) {
+	if !validID(req.UserID) {
+		return PayeeResponse{}, errors.New("invalid user id")
+	}
+
+	nickname, err := checkNickname(req.Nickname)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	name, err := checkPayeeName(req.Name)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	if !validID(accountID) {
+		return PayeeResponse{}, errors.New("invalid account id")
+	}
+
+	user, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	payees, err := s.payeeRepo.List(
+		payeerepo.ListRequest{
+			UserID: req.UserID,
+		},
+	)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	for _, payee := range payees {
+		if payee.AccountID == accountID {
+			return PayeeResponse{}, errors.New("duplicate payee")
+		}
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return PayeeResponse{}, errors.New("account closed")
+	}
+
+	match, holder, err := s.matchHolders(account, name)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	err = checkMatch(match, holder, req.AcceptNameMismatch)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	activeAt := time.Now().UTC()
+
+	if !userAccount(user.AccountIDs, account.ID) {
+		activeAt = activeAt.Add(s.payeeCoolingOff)
+	}
+
+	payee, err := s.payeeRepo.Create(
+		payeerepo.CreateRequest{
+			Payee: domain.Payee{
+				UserID:         req.UserID,
+				ActiveAt:       activeAt,
+				Nickname:       nickname,
+				Name:           name,
+				Match:          match,
+				AccountID:      account.ID,
+				IBAN:           account.IBAN,
+				ReceiverUserID: holder.ID,
+			},
+		},
+	)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	return payeeResponse(payee), nil
+}
+
+func (s svc) Payees(req PayeesRequest) (PayeesResponse, error) {
+	if !validID(req.UserID) {
+		return PayeesResponse{}, errors.New("invalid user id")
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return PayeesResponse{}, err
+	}
+
+	payees, err := s.payeeRepo.List(
+		payeerepo.ListRequest{
+			UserID: req.UserID,
+		},
+	)
+
+	if err != nil {
+		return PayeesResponse{}, err
+	}
+
+	res := make([]PayeeResponse, 0, len(payees))
+
+	for _, payee := range payees {
+		res = append(res, payeeResponse(payee))
+	}
+
+	return PayeesResponse{
+		Payees: res,
+	}, nil
+}
+
+func (s svc) Payee(req PayeeRequest) (PayeeResponse, error) {
+	payee, err := s.userPayee(req.UserID, req.PayeeID)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	return payeeResponse(payee), nil
+}
+
+func (s svc) UpdatePayee(req UpdatePayeeRequest) (PayeeResponse, error) {
+	payee, err := s.userPayee(req.UserID, req.PayeeID)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	if req.Nickname != "" {
+		payee.Nickname, err = checkNickname(req.Nickname)
+
+		if err != nil {
+			return PayeeResponse{}, err
+		}
+	}
+
+	if req.Name != "" {
+		name, err := checkPayeeName(req.Name)
+
+		if err != nil {
+			return PayeeResponse{}, err
+		}
+
+		account, err := s.accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: payee.AccountID,
+			},
+		)
+
+		if err != nil {
+			return PayeeResponse{}, err
+		}
+
+		match, holder, err := s.matchHolders(account, name)
+
+		if err != nil {
+			return PayeeResponse{}, err
+		}
+
+		err = checkMatch(match, holder, req.AcceptNameMismatch)
+
+		if err != nil {
+			return PayeeResponse{}, err
+		}
+
+		payee.Name = name
+		payee.Match = match
+		payee.ReceiverUserID = holder.ID
+	}
+
+	payee, err = s.payeeRepo.Update(
+		payeerepo.UpdateRequest{
+			Payee: payee,
+		},
+	)
+
+	if err != nil {
+		return PayeeResponse{}, err
+	}
+
+	return payeeResponse(payee), nil
+}
+
+func (s svc) DeletePayee(req DeletePayeeRequest) error {
+	payee, err := s.userPayee(req.UserID, req.PayeeID)
+
+	if err != nil {
+		return err
+	}
+
+	return s.payeeRepo.Delete(
+		payeerepo.DeleteRequest{
+			ID: payee.ID,
+		},
+	)
+}
+
+// userPayee reads a payee from the user's own book. Other users' payees are
+// reported as not found.
+func (s svc) userPayee(userID string, payeeID string) (domain.Payee, error) {
+	if !validID(userID) {
+		return domain.Payee{}, errors.New("invalid user id")
+	}
+
+	if !validID(payeeID) {
+		return domain.Payee{}, errors.New("invalid payee id")
+	}
+
+	_, err := s.readUser(userID, actionView)
+
+	if err != nil {
+		return domain.Payee{}, err
+	}
+
+	payee, err := s.payeeRepo.Read(
+		payeerepo.ReadRequest{
+			ID: payeeID,
+		},
+	)
+
+	if err != nil {
+		return domain.Payee{}, err
+	}
+
+	if payee.UserID != userID {
+		return domain.Payee{}, errors.New("payee not found")
+	}
+
+	return payee, nil
+}
+
+// payeeTransfer fills in the receiver of a transfer to a payee, once the
+// payee is out of its cooling-off period. The payee's name is checked again
+// at transfer time, accepting the match the user already accepted.
+func (s svc) payeeTransfer(req TransferRequest) (TransferRequest, error) {
+	if req.PayeeID == "" {
+		return req, nil
+	}
+
+	if !validID(req.SenderUserID) {
+		return TransferRequest{}, errors.New("invalid sender user id")
+	}
+
+	if req.ReceiverUserID != "" || req.ReceiverAccountID != "" {
+		return TransferRequest{}, errors.New("invalid receiver, payee id given")
+	}
+
+	payee, err := s.userPayee(req.SenderUserID, req.PayeeID)
+
+	if err != nil {
+		return TransferRequest{}, err
+	}
+
+	if time.Now().UTC().Before(payee.ActiveAt) {
+		return TransferRequest{}, PayeeCoolingOffError{
+			Until: payee.ActiveAt,
+		}
+	}
+
+	req.ReceiverUserID = payee.ReceiverUserID
+	req.ReceiverAccountID = payee.AccountID
+
+	if req.ReceiverName == "" {
+		req.ReceiverName = payee.Name
+		req.AcceptNameMismatch = req.AcceptNameMismatch || payee.Match != domain.PayeeMatchExact
+	}
+
+	return req, nil
+}
+
+// confirmPayee checks the name the sender gave, if any, against the holders
+// of the receiving account.
+func (s svc) confirmPayee(account domain.Account, name string, accept bool) error {
+	if name == "" {
+		return nil
+	}
+
+	match, holder, err := s.matchHolders(account, name)
+
+	if err != nil {
+		return err
+	}
+
+	return checkMatch(match, holder, accept)
+}
+
+// matchHolders compares a name with each owner and co-owner of an account
+// and returns the best match and the holder it was made with. With no match
+// the holder is the first owner, so the account can still be paid.
+func (s svc) matchHolders(account domain.Account, name string) (domain.PayeeMatch, domain.User, error) {
+	userIDs := make([]string, 0, len(account.Holders))
+
+	for userID, role := range account.Holders {
+		if role == domain.RoleOwner || role == domain.RoleCoOwner {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	if len(userIDs) == 0 {
+		return "", domain.User{}, errors.New("account holder not found")
+	}
+
+	sort.Slice(userIDs, func(i, j int) bool {
+		if account.Holders[userIDs[i]] != account.Holders[userIDs[j]] {
+			return account.Holders[userIDs[i]] == domain.RoleOwner
+		}
+
+		return userIDs[i] < userIDs[j]
+	})
+
+	best := domain.PayeeMatchNone
+
+	var bestHolder domain.User
+
+	for i, userID := range userIDs {
+		holder, err := s.userRepo.Read(
+			userrepo.ReadRequest{
+				ID: userID,
+			},
+		)
+
+		if err != nil {
+			return "", domain.User{}, err
+		}
+
+		match := cop.Match(name, holder.Name)
+
+		if i == 0 || cop.Best(best, match) != best {
+			best = match
+			bestHolder = holder
+		}
+
+		if best == domain.PayeeMatchExact {
+			break
+		}
+	}
+
+	return best, bestHolder, nil
+}
+
+func checkMatch(match domain.PayeeMatch, holder domain.User, accept bool) error {
+	if match == domain.PayeeMatchExact || accept {
+		return nil
+	}
+
+	err := PayeeNameError{
+		Match: match,
+	}
+
+	if match == domain.PayeeMatchClose {
+		err.Name = holder.Name
+	}
+
+	return err
+}
+
+func checkNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+
+	if nickname == "" || utf8.RuneCountInString(nickname) > maxNicknameLength {
+		return "", errors.New("invalid nickname")
+	}
+
+	return nickname, nil
+}
+
+func checkPayeeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" || utf8.RuneCountInString(name) > maxPayeeNameLength {
+		return "", errors.New("invalid payee name")
+	}
+
+	return name, nil
+}
+
+func payeeResponse(payee domain.Payee) PayeeResponse {
+	return PayeeResponse{
+		PayeeID:   payee.ID,
+		Nickname:  payee.Nickname,
+		Name:      payee.Name,
+		Match:     payee.Match,
+		AccountID: payee.AccountID,
+		IBAN:      payee.IBAN,
+		CreatedAt: payee.CreatedAt,
+		ActiveAt:  payee.ActiveAt,
+	}
+}
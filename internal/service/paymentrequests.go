@@ -0,0 +1,604 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/paymentrequestrepo"
+)
+
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+
+	maxPayers            = 20
+	maxPaymentRequestTTL = 90 * 24 * time.Hour
+
+	// maxUpdateAttempts bounds how often the update helpers read again and
+	// retry when another update got there first.
+	maxUpdateAttempts = 3
+
+	// paymentRequestMetadataKey links the transfers paying a request back to
+	// it.
+	paymentRequestMetadataKey = "payment_request_id"
+)
+
+func (s svc) RequestPayment(req RequestPaymentRequest) (PaymentRequestResponse, error) {
+	if !validID(req.UserID) {
+		return PaymentRequestResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	req.AccountID = accountID
+
+	details, err := checkDetails(
+		TransactionDetails{
+			Description: req.Description,
+		},
+	)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	now := time.Now().UTC()
+
+	expiresAt := req.ExpiresAt.UTC()
+
+	if req.ExpiresAt.IsZero() {
+		expiresAt = now.Add(s.paymentRequestTTL)
+	}
+
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxPaymentRequestTTL)) {
+		return PaymentRequestResponse{}, errors.New("invalid expiry")
+	}
+
+	payers, amount, err := splitPayers(req.UserID, req.Amount, req.Payers)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	account, err := s.heldAccount(req.UserID, req.AccountID, actionDeposit)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return PaymentRequestResponse{}, errors.New("account closed")
+	}
+
+	err = checkIncoming(account, domain.OperationTransferIn)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	_, err = account.Balance.Cmp(amount)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	for _, payer := range payers {
+		_, err = s.readUser(payer.UserID, actionView)
+
+		if err != nil {
+			return PaymentRequestResponse{}, err
+		}
+	}
+
+	paymentRequest, err := s.paymentRequestRepo.Create(
+		paymentrequestrepo.CreateRequest{
+			PaymentRequest: domain.PaymentRequest{
+				ExpiresAt:       expiresAt,
+				RequesterUserID: req.UserID,
+				AccountID:       req.AccountID,
+				Amount:          amount,
+				Description:     details.Description,
+				Status:          domain.PaymentRequestOpen,
+				Payers:          payers,
+			},
+		},
+	)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	return paymentRequestResponse(paymentRequest), nil
+}
+
+func (s svc) PaymentRequests(req PaymentRequestsRequest) (PaymentRequestsResponse, error) {
+	if !validID(req.UserID) {
+		return PaymentRequestsResponse{}, errors.New("invalid user id")
+	}
+
+	if req.Direction != "" && req.Direction != DirectionIncoming && req.Direction != DirectionOutgoing {
+		return PaymentRequestsResponse{}, errors.New("invalid direction")
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return PaymentRequestsResponse{}, err
+	}
+
+	paymentRequests := []domain.PaymentRequest{}
+
+	if req.Direction != DirectionIncoming {
+		outgoing, err := s.paymentRequestRepo.List(
+			paymentrequestrepo.ListRequest{
+				RequesterUserID: req.UserID,
+			},
+		)
+
+		if err != nil {
+			return PaymentRequestsResponse{}, err
+		}
+
+		paymentRequests = append(paymentRequests, outgoing...)
+	}
+
+	if req.Direction != DirectionOutgoing {
+		incoming, err := s.paymentRequestRepo.List(
+			paymentrequestrepo.ListRequest{
+				PayerUserID: req.UserID,
+			},
+		)
+
+		if err != nil {
+			return PaymentRequestsResponse{}, err
+		}
+
+		paymentRequests = append(paymentRequests, incoming...)
+	}
+
+	sort.SliceStable(paymentRequests, func(i, j int) bool {
+		return paymentRequests[i].CreatedAt.After(paymentRequests[j].CreatedAt)
+	})
+
+	res := make([]PaymentRequestResponse, 0, len(paymentRequests))
+
+	for _, paymentRequest := range paymentRequests {
+		paymentRequest, err = s.expirePaymentRequest(paymentRequest)
+
+		if err != nil {
+			return PaymentRequestsResponse{}, err
+		}
+
+		if req.Status != "" && paymentRequest.Status != req.Status {
+			continue
+		}
+
+		res = append(res, paymentRequestResponse(paymentRequest))
+	}
+
+	return PaymentRequestsResponse{
+		PaymentRequests: res,
+	}, nil
+}
+
+func (s svc) PaymentRequest(req PaymentRequestRequest) (PaymentRequestResponse, error) {
+	paymentRequest, err := s.userPaymentRequest(req.UserID, req.PaymentRequestID)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	return paymentRequestResponse(paymentRequest), nil
+}
+
+// AcceptPaymentRequest claims the payer's share before transferring it, so
+// a concurrent accept cannot pay twice, and releases the claim again if the
+// transfer fails.
+func (s svc) AcceptPaymentRequest(req AcceptPaymentRequestRequest) (PaymentRequestResponse, error) {
+	paymentRequest, err := s.userPaymentRequest(req.UserID, req.PaymentRequestID)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	paymentRequest, err = s.respond(paymentRequest.ID, req.UserID, domain.PayerPaid)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	payer := paymentRequest.Payers[payerIndex(paymentRequest, req.UserID)]
+
+	transferRes, err := s.Transfer(
+		TransferRequest{
+			SenderUserID:      req.UserID,
+			ReceiverUserID:    paymentRequest.RequesterUserID,
+			SenderAccountID:   req.AccountID,
+			ReceiverAccountID: paymentRequest.AccountID,
+			Amount:            payer.Amount,
+			ApproverUserID:    req.ApproverUserID,
+			TransactionDetails: TransactionDetails{
+				Description: paymentRequest.Description,
+				Metadata: map[string]string{
+					paymentRequestMetadataKey: paymentRequest.ID,
+				},
+			},
+			requested: true,
+		},
+	)
+
+	if err != nil {
+		return PaymentRequestResponse{}, s.compensateAcceptPaymentRequest(paymentRequest.ID, req.UserID, err)
+	}
+
+	paymentRequest, err = s.updatePaymentRequest(
+		paymentRequest.ID,
+		func(paymentRequest *domain.PaymentRequest, now time.Time) error {
+			paymentRequest.Payers[payerIndex(*paymentRequest, req.UserID)].TransactionID = transferRes.TransactionID
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	return paymentRequestResponse(paymentRequest), nil
+}
+
+func (s svc) DeclinePaymentRequest(req DeclinePaymentRequestRequest) (PaymentRequestResponse, error) {
+	paymentRequest, err := s.userPaymentRequest(req.UserID, req.PaymentRequestID)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	paymentRequest, err = s.respond(paymentRequest.ID, req.UserID, domain.PayerDeclined)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	return paymentRequestResponse(paymentRequest), nil
+}
+
+// CancelPaymentRequest withdraws the shares not yet paid. Shares already
+// paid are kept.
+func (s svc) CancelPaymentRequest(req CancelPaymentRequestRequest) (PaymentRequestResponse, error) {
+	paymentRequest, err := s.userPaymentRequest(req.UserID, req.PaymentRequestID)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	if paymentRequest.RequesterUserID != req.UserID {
+		return PaymentRequestResponse{}, errors.New("unauthorized payment request id")
+	}
+
+	paymentRequest, err = s.updatePaymentRequest(
+		paymentRequest.ID,
+		func(paymentRequest *domain.PaymentRequest, now time.Time) error {
+			if paymentRequest.Status != domain.PaymentRequestOpen {
+				return errors.New("payment request not open")
+			}
+
+			closePaymentRequest(paymentRequest, domain.PaymentRequestCancelled, domain.PayerCancelled, now)
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	return paymentRequestResponse(paymentRequest), nil
+}
+
+// userPaymentRequest reads a request the user made or is asked to pay.
+// Anyone else is told it does not exist.
+func (s svc) userPaymentRequest(userID string, paymentRequestID string) (domain.PaymentRequest, error) {
+	if !validID(userID) {
+		return domain.PaymentRequest{}, errors.New("invalid user id")
+	}
+
+	if !validID(paymentRequestID) {
+		return domain.PaymentRequest{}, errors.New("invalid payment request id")
+	}
+
+	_, err := s.readUser(userID, actionView)
+
+	if err != nil {
+		return domain.PaymentRequest{}, err
+	}
+
+	paymentRequest, err := s.paymentRequestRepo.Read(
+		paymentrequestrepo.ReadRequest{
+			ID: paymentRequestID,
+		},
+	)
+
+	if err != nil {
+		return domain.PaymentRequest{}, err
+	}
+
+	if paymentRequest.RequesterUserID != userID && payerIndex(paymentRequest, userID) < 0 {
+		return domain.PaymentRequest{}, errors.New("payment request not found")
+	}
+
+	return s.expirePaymentRequest(paymentRequest)
+}
+
+// respond records a payer's answer while their share is still pending.
+func (s svc) respond(paymentRequestID string, userID string, status domain.PayerStatus) (domain.PaymentRequest, error) {
+	return s.updatePaymentRequest(
+		paymentRequestID,
+		func(paymentRequest *domain.PaymentRequest, now time.Time) error {
+			i := payerIndex(*paymentRequest, userID)
+
+			if i < 0 {
+				return errors.New("unauthorized payment request id")
+			}
+
+			if paymentRequest.Status != domain.PaymentRequestOpen || !now.Before(paymentRequest.ExpiresAt) {
+				return errors.New("payment request not open")
+			}
+
+			if paymentRequest.Payers[i].Status != domain.PayerPending {
+				return errors.New("payment request already answered")
+			}
+
+			paymentRequest.Payers[i].Status = status
+			paymentRequest.Payers[i].RespondedAt = now
+
+			if pendingPayers(*paymentRequest) == 0 {
+				paymentRequest.Status = domain.PaymentRequestCompleted
+			}
+
+			return nil
+		},
+	)
+}
+
+func (s svc) compensateAcceptPaymentRequest(paymentRequestID string, userID string, cause error) error {
+	_, err := s.updatePaymentRequest(
+		paymentRequestID,
+		func(paymentRequest *domain.PaymentRequest, now time.Time) error {
+			i := payerIndex(*paymentRequest, userID)
+
+			paymentRequest.Payers[i].Status = domain.PayerPending
+			paymentRequest.Payers[i].RespondedAt = time.Time{}
+
+			if paymentRequest.Status == domain.PaymentRequestCompleted {
+				paymentRequest.Status = domain.PaymentRequestOpen
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+// expirePaymentRequest closes an open request once it is past its expiry.
+// Requests expire when they are next read rather than on a schedule.
+func (s svc) expirePaymentRequest(paymentRequest domain.PaymentRequest) (domain.PaymentRequest, error) {
+	if paymentRequest.Status != domain.PaymentRequestOpen || time.Now().UTC().Before(paymentRequest.ExpiresAt) {
+		return paymentRequest, nil
+	}
+
+	return s.updatePaymentRequest(
+		paymentRequest.ID,
+		func(paymentRequest *domain.PaymentRequest, now time.Time) error {
+			if paymentRequest.Status == domain.PaymentRequestOpen && !now.Before(paymentRequest.ExpiresAt) {
+				closePaymentRequest(paymentRequest, domain.PaymentRequestExpired, domain.PayerExpired, now)
+			}
+
+			return nil
+		},
+	)
+}
+
+// updatePaymentRequest applies a change to the latest version of a request,
+// reading it again and retrying when another update got there first.
+func (s svc) updatePaymentRequest(paymentRequestID string, apply func(*domain.PaymentRequest, time.Time) error) (domain.PaymentRequest, error) {
+	for attempt := 1; ; attempt++ {
+		paymentRequest, err := s.paymentRequestRepo.Read(
+			paymentrequestrepo.ReadRequest{
+				ID: paymentRequestID,
+			},
+		)
+
+		if err != nil {
+			return domain.PaymentRequest{}, err
+		}
+
+		err = apply(&paymentRequest, time.Now().UTC())
+
+		if err != nil {
+			return domain.PaymentRequest{}, err
+		}
+
+		paymentRequest, err = s.paymentRequestRepo.Update(
+			paymentrequestrepo.UpdateRequest{
+				PaymentRequest: paymentRequest,
+			},
+		)
+
+		if errors.Is(err, paymentrequestrepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return paymentRequest, err
+	}
+}
+
+// splitPayers checks the payers and works out their shares and the total.
+func splitPayers(requesterUserID string, amount money.Money, terms []PayerTerms) ([]domain.Payer, money.Money, error) {
+	if len(terms) == 0 || len(terms) > maxPayers {
+		return nil, money.Money{}, errors.New("invalid payers")
+	}
+
+	seen := make(map[string]struct{}, len(terms))
+
+	shared := 0
+
+	for _, term := range terms {
+		if !validID(term.UserID) || term.UserID == requesterUserID {
+			return nil, money.Money{}, errors.New("invalid payer user id")
+		}
+
+		if _, exists := seen[term.UserID]; exists {
+			return nil, money.Money{}, errors.New("duplicate payer")
+		}
+
+		seen[term.UserID] = struct{}{}
+
+		if !term.Amount.IsZero() {
+			shared++
+		}
+	}
+
+	payers := make([]domain.Payer, len(terms))
+
+	switch shared {
+	case 0:
+		if !amount.IsPositive() {
+			return nil, money.Money{}, errors.New("invalid amount")
+		}
+
+		for i, share := range amount.Split(len(terms)) {
+			if !share.IsPositive() {
+				return nil, money.Money{}, errors.New("invalid amount")
+			}
+
+			payers[i] = domain.Payer{
+				UserID: terms[i].UserID,
+				Amount: share,
+				Status: domain.PayerPending,
+			}
+		}
+
+		return payers, amount, nil
+	case len(terms):
+		total := money.Money{}
+
+		for i, term := range terms {
+			if !term.Amount.IsPositive() {
+				return nil, money.Money{}, errors.New("invalid payer amount")
+			}
+
+			var err error
+
+			total, err = total.Add(term.Amount)
+
+			if err != nil {
+				return nil, money.Money{}, err
+			}
+
+			payers[i] = domain.Payer{
+				UserID: term.UserID,
+				Amount: term.Amount,
+				Status: domain.PayerPending,
+			}
+		}
+
+		if !amount.IsZero() {
+			cmp, err := amount.Cmp(total)
+
+			if err != nil {
+				return nil, money.Money{}, err
+			}
+
+			if cmp != 0 {
+				return nil, money.Money{}, errors.New("invalid amount, payer amounts do not add up")
+			}
+		}
+
+		return payers, total, nil
+	default:
+		return nil, money.Money{}, errors.New("invalid payer amount, give every payer an amount or none")
+	}
+}
+
+func closePaymentRequest(paymentRequest *domain.PaymentRequest, status domain.PaymentRequestStatus, payerStatus domain.PayerStatus, now time.Time) {
+	paymentRequest.Status = status
+
+	for i := range paymentRequest.Payers {
+		if paymentRequest.Payers[i].Status == domain.PayerPending {
+			paymentRequest.Payers[i].Status = payerStatus
+			paymentRequest.Payers[i].RespondedAt = now
+		}
+	}
+}
+
+func payerIndex(paymentRequest domain.PaymentRequest, userID string) int {
+	for i, payer := range paymentRequest.Payers {
+		if payer.UserID == userID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func pendingPayers(paymentRequest domain.PaymentRequest) int {
+	pending := 0
+
+	for _, payer := range paymentRequest.Payers {
+		if payer.Status == domain.PayerPending {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+func paymentRequestResponse(paymentRequest domain.PaymentRequest) PaymentRequestResponse {
+	payers := make([]PayerResponse, 0, len(paymentRequest.Payers))
+
+	paid := money.New(0, paymentRequest.Amount.Currency())
+
+	for _, payer := range paymentRequest.Payers {
+		payers = append(
+			payers,
+			PayerResponse{
+				UserID:        payer.UserID,
+				Amount:        payer.Amount,
+				Status:        payer.Status,
+				RespondedAt:   payer.RespondedAt,
+				TransactionID: payer.TransactionID,
+			},
+		)
+
+		if payer.Status == domain.PayerPaid {
+			// Shares are in the request's currency, so this cannot fail.
+			paid, _ = paid.Add(payer.Amount)
+		}
+	}
+
+	return PaymentRequestResponse{
+		PaymentRequestID: paymentRequest.ID,
+		RequesterUserID:  paymentRequest.RequesterUserID,
+		AccountID:        paymentRequest.AccountID,
+		Amount:           paymentRequest.Amount,
+		PaidAmount:       paid,
+		Description:      paymentRequest.Description,
+		Status:           paymentRequest.Status,
+		CreatedAt:        paymentRequest.CreatedAt,
+		ExpiresAt:        paymentRequest.ExpiresAt,
+		Payers:           payers,
+	}
+}
@@ -0,0 +1,805 @@
+package service
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/pendingtransferrepo"
+)
+
+const (
+	maxPendingTransferTTL = 30 * 24 * time.Hour
+
+	// pendingTransferMetadataKey links the ledger entries of a pending
+	// transfer back to it, so a release can be matched with its hold.
+	pendingTransferMetadataKey = "pending_transfer_id"
+
+	// pendingStatusMetadataKey says which change a notice on the receiver's
+	// account records.
+	pendingStatusMetadataKey = "pending_transfer_status"
+)
+
+var errPendingTransferClosed = errors.New("pending transfer closed")
+
+// CreatePendingTransfer holds the amount and fee on the sender's account
+// until the transfer is answered or expires.
+func (s svc) CreatePendingTransfer(req CreatePendingTransferRequest) (PendingTransferResponse, error) {
+	now := time.Now().UTC()
+
+	expiresAt := req.ExpiresAt.UTC()
+
+	if req.ExpiresAt.IsZero() {
+		expiresAt = now.Add(s.pendingTransferTTL)
+	}
+
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxPendingTransferTTL)) {
+		return PendingTransferResponse{}, errors.New("invalid expiry")
+	}
+
+	transfer, err := s.payeeTransfer(req.TransferRequest)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	plan, err := s.planTransfer(transfer)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	err = s.confirmPayee(plan.receiverAccount, transfer.ReceiverName, transfer.AcceptNameMismatch)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	pendingTransfer, _, err := s.holdTransfer(transfer, plan, expiresAt)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	return pendingTransferResponse(pendingTransfer), nil
+}
+
+// requiresAcceptance says whether the pending transfer policy holds a
+// transfer. Account closures and paying a payment request are never held,
+// and batch lines such as payroll skip the first-time payee check.
+func (s svc) requiresAcceptance(req TransferRequest, plan transferPlan) bool {
+	if req.closing || req.requested {
+		return false
+	}
+
+	threshold := s.pendingPolicy.Threshold
+
+	if threshold.IsPositive() {
+		cmp, err := req.Amount.Cmp(threshold)
+
+		if err == nil && cmp >= 0 {
+			return true
+		}
+	}
+
+	if !s.pendingPolicy.FirstTimePayee || req.batch {
+		return false
+	}
+
+	_, holder := plan.receiverAccount.Holders[req.SenderUserID]
+
+	if holder {
+		return false
+	}
+
+	for _, transaction := range plan.senderAccount.Transactions {
+		if transaction.Operation == domain.OperationTransfer && transaction.ReceiverAccountID == plan.receiverAccount.ID {
+			return false
+		}
+	}
+
+	return true
+}
+
+// holdTransfer returns the sender's balance after the hold.
+func (s svc) holdTransfer(transfer TransferRequest, plan transferPlan, expiresAt time.Time) (domain.PendingTransfer, money.Money, error) {
+	transfer = plan.resolve(transfer)
+
+	pendingTransfer, err := s.pendingTransferRepo.Create(
+		pendingtransferrepo.CreateRequest{
+			PendingTransfer: domain.PendingTransfer{
+				ExpiresAt:         expiresAt,
+				SenderUserID:      transfer.SenderUserID,
+				SenderAccountID:   transfer.SenderAccountID,
+				ReceiverUserID:    transfer.ReceiverUserID,
+				ReceiverAccountID: transfer.ReceiverAccountID,
+				Amount:            transfer.Amount,
+				Fee:               plan.fee,
+				Description:       plan.details.Description,
+				Reference:         plan.details.Reference,
+				Metadata:          plan.details.Metadata,
+				Status:            domain.PendingTransferPending,
+			},
+		},
+	)
+
+	if err != nil {
+		return domain.PendingTransfer{}, money.Money{}, err
+	}
+
+	balance, err := s.holdPendingTransfer(pendingTransfer, plan)
+
+	if err != nil {
+		return domain.PendingTransfer{}, money.Money{}, s.compensateCreatePendingTransfer(pendingTransfer.ID, err)
+	}
+
+	return pendingTransfer, balance, nil
+}
+
+func (s svc) PendingTransfers(req PendingTransfersRequest) (PendingTransfersResponse, error) {
+	if !validID(req.UserID) {
+		return PendingTransfersResponse{}, errors.New("invalid user id")
+	}
+
+	if req.Direction != "" && req.Direction != DirectionIncoming && req.Direction != DirectionOutgoing {
+		return PendingTransfersResponse{}, errors.New("invalid direction")
+	}
+
+	_, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return PendingTransfersResponse{}, err
+	}
+
+	pendingTransfers := []domain.PendingTransfer{}
+
+	if req.Direction != DirectionIncoming {
+		outgoing, err := s.pendingTransferRepo.List(
+			pendingtransferrepo.ListRequest{
+				SenderUserID: req.UserID,
+			},
+		)
+
+		if err != nil {
+			return PendingTransfersResponse{}, err
+		}
+
+		pendingTransfers = append(pendingTransfers, outgoing...)
+	}
+
+	if req.Direction != DirectionOutgoing {
+		incoming, err := s.pendingTransferRepo.List(
+			pendingtransferrepo.ListRequest{
+				ReceiverUserID: req.UserID,
+			},
+		)
+
+		if err != nil {
+			return PendingTransfersResponse{}, err
+		}
+
+		for _, pendingTransfer := range incoming {
+			// A transfer between the user's own accounts is already listed
+			// as outgoing.
+			if req.Direction == "" && pendingTransfer.SenderUserID == req.UserID {
+				continue
+			}
+
+			pendingTransfers = append(pendingTransfers, pendingTransfer)
+		}
+	}
+
+	sort.SliceStable(pendingTransfers, func(i, j int) bool {
+		return pendingTransfers[i].CreatedAt.After(pendingTransfers[j].CreatedAt)
+	})
+
+	res := make([]PendingTransferResponse, 0, len(pendingTransfers))
+
+	for _, pendingTransfer := range pendingTransfers {
+		pendingTransfer, err = s.expirePendingTransfer(pendingTransfer)
+
+		if err != nil {
+			return PendingTransfersResponse{}, err
+		}
+
+		if req.Status != "" && pendingTransfer.Status != req.Status {
+			continue
+		}
+
+		res = append(res, pendingTransferResponse(pendingTransfer))
+	}
+
+	return PendingTransfersResponse{
+		PendingTransfers: res,
+	}, nil
+}
+
+func (s svc) PendingTransfer(req PendingTransferRequest) (PendingTransferResponse, error) {
+	pendingTransfer, err := s.userPendingTransfer(req.UserID, req.PendingTransferID)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	return pendingTransferResponse(pendingTransfer), nil
+}
+
+// AcceptPendingTransfer claims the transfer before any money moves, so it
+// cannot also be cancelled or expired.
+func (s svc) AcceptPendingTransfer(req AcceptPendingTransferRequest) (PendingTransferResponse, error) {
+	pendingTransfer, err := s.answerPendingTransfer(req.UserID, req.PendingTransferID, domain.PendingTransferAccepted)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	err = s.settlePendingTransfer(pendingTransfer)
+
+	if err != nil {
+		return PendingTransferResponse{}, s.compensateClaimPendingTransfer(pendingTransfer.ID, err)
+	}
+
+	return pendingTransferResponse(pendingTransfer), nil
+}
+
+func (s svc) DeclinePendingTransfer(req DeclinePendingTransferRequest) (PendingTransferResponse, error) {
+	pendingTransfer, err := s.answerPendingTransfer(req.UserID, req.PendingTransferID, domain.PendingTransferDeclined)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	err = s.releasePendingTransfer(pendingTransfer)
+
+	if err != nil {
+		return PendingTransferResponse{}, s.compensateClaimPendingTransfer(pendingTransfer.ID, err)
+	}
+
+	return pendingTransferResponse(pendingTransfer), nil
+}
+
+func (s svc) CancelPendingTransfer(req CancelPendingTransferRequest) (PendingTransferResponse, error) {
+	pendingTransfer, err := s.answerPendingTransfer(req.UserID, req.PendingTransferID, domain.PendingTransferCancelled)
+
+	if err != nil {
+		return PendingTransferResponse{}, err
+	}
+
+	err = s.releasePendingTransfer(pendingTransfer)
+
+	if err != nil {
+		return PendingTransferResponse{}, s.compensateClaimPendingTransfer(pendingTransfer.ID, err)
+	}
+
+	return pendingTransferResponse(pendingTransfer), nil
+}
+
+// ExpirePendingTransfers refunds every pending transfer past its expiry.
+func (s svc) ExpirePendingTransfers(req ExpirePendingTransfersRequest) (ExpirePendingTransfersResponse, error) {
+	pendingTransfers, err := s.pendingTransferRepo.List(
+		pendingtransferrepo.ListRequest{
+			Status: domain.PendingTransferPending,
+		},
+	)
+
+	if err != nil {
+		return ExpirePendingTransfersResponse{}, err
+	}
+
+	pendingTransferIDs := []string{}
+
+	for _, pendingTransfer := range pendingTransfers {
+		expired, err := s.expirePendingTransfer(pendingTransfer)
+
+		if err != nil {
+			return ExpirePendingTransfersResponse{}, err
+		}
+
+		if expired.Status == domain.PendingTransferExpired {
+			pendingTransferIDs = append(pendingTransferIDs, expired.ID)
+		}
+	}
+
+	sort.Strings(pendingTransferIDs)
+
+	return ExpirePendingTransfersResponse{
+		PendingTransferIDs: pendingTransferIDs,
+	}, nil
+}
+
+// userPendingTransfer reads a transfer the user sent or is asked to accept.
+// Anyone else is told it does not exist.
+func (s svc) userPendingTransfer(userID string, pendingTransferID string) (domain.PendingTransfer, error) {
+	if !validID(userID) {
+		return domain.PendingTransfer{}, errors.New("invalid user id")
+	}
+
+	if !validID(pendingTransferID) {
+		return domain.PendingTransfer{}, errors.New("invalid pending transfer id")
+	}
+
+	_, err := s.readUser(userID, actionView)
+
+	if err != nil {
+		return domain.PendingTransfer{}, err
+	}
+
+	pendingTransfer, err := s.pendingTransferRepo.Read(
+		pendingtransferrepo.ReadRequest{
+			ID: pendingTransferID,
+		},
+	)
+
+	if err != nil {
+		return domain.PendingTransfer{}, err
+	}
+
+	if pendingTransfer.SenderUserID != userID && pendingTransfer.ReceiverUserID != userID {
+		return domain.PendingTransfer{}, errors.New("pending transfer not found")
+	}
+
+	return s.expirePendingTransfer(pendingTransfer)
+}
+
+func (s svc) answerPendingTransfer(userID string, pendingTransferID string, status domain.PendingTransferStatus) (domain.PendingTransfer, error) {
+	pendingTransfer, err := s.userPendingTransfer(userID, pendingTransferID)
+
+	if err != nil {
+		return domain.PendingTransfer{}, err
+	}
+
+	answeredBy := pendingTransfer.ReceiverUserID
+
+	if status == domain.PendingTransferCancelled {
+		answeredBy = pendingTransfer.SenderUserID
+	}
+
+	if answeredBy != userID {
+		return domain.PendingTransfer{}, errors.New("unauthorized pending transfer id")
+	}
+
+	return s.claimPendingTransfer(pendingTransfer.ID, status)
+}
+
+// claimPendingTransfer moves a pending transfer to its final status. Only
+// one claim can succeed, so the held funds move exactly once.
+func (s svc) claimPendingTransfer(pendingTransferID string, status domain.PendingTransferStatus) (domain.PendingTransfer, error) {
+	return s.updatePendingTransfer(
+		pendingTransferID,
+		func(pendingTransfer *domain.PendingTransfer, now time.Time) error {
+			if pendingTransfer.Status != domain.PendingTransferPending {
+				return errPendingTransferClosed
+			}
+
+			if now.Before(pendingTransfer.ExpiresAt) == (status == domain.PendingTransferExpired) {
+				return errPendingTransferClosed
+			}
+
+			pendingTransfer.Status = status
+			pendingTransfer.RespondedAt = now
+
+			if status == domain.PendingTransferAccepted {
+				pendingTransfer.TransactionID = newTransactionID()
+			}
+
+			return nil
+		},
+	)
+}
+
+func (s svc) compensateClaimPendingTransfer(pendingTransferID string, cause error) error {
+	_, err := s.updatePendingTransfer(
+		pendingTransferID,
+		func(pendingTransfer *domain.PendingTransfer, now time.Time) error {
+			pendingTransfer.Status = domain.PendingTransferPending
+			pendingTransfer.RespondedAt = time.Time{}
+			pendingTransfer.TransactionID = ""
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) compensateCreatePendingTransfer(pendingTransferID string, cause error) error {
+	err := s.pendingTransferRepo.Delete(
+		pendingtransferrepo.DeleteRequest{
+			ID: pendingTransferID,
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+// expirePendingTransfer returns the transfer as it was left when someone
+// answered it first.
+func (s svc) expirePendingTransfer(pendingTransfer domain.PendingTransfer) (domain.PendingTransfer, error) {
+	if pendingTransfer.Status != domain.PendingTransferPending || time.Now().UTC().Before(pendingTransfer.ExpiresAt) {
+		return pendingTransfer, nil
+	}
+
+	expired, err := s.claimPendingTransfer(pendingTransfer.ID, domain.PendingTransferExpired)
+
+	if errors.Is(err, errPendingTransferClosed) {
+		return s.pendingTransferRepo.Read(
+			pendingtransferrepo.ReadRequest{
+				ID: pendingTransfer.ID,
+			},
+		)
+	}
+
+	if err != nil {
+		return domain.PendingTransfer{}, err
+	}
+
+	err = s.releasePendingTransfer(expired)
+
+	if err != nil {
+		return domain.PendingTransfer{}, s.compensateClaimPendingTransfer(expired.ID, err)
+	}
+
+	return expired, nil
+}
+
+func (s svc) updatePendingTransfer(pendingTransferID string, apply func(*domain.PendingTransfer, time.Time) error) (domain.PendingTransfer, error) {
+	for attempt := 1; ; attempt++ {
+		pendingTransfer, err := s.pendingTransferRepo.Read(
+			pendingtransferrepo.ReadRequest{
+				ID: pendingTransferID,
+			},
+		)
+
+		if err != nil {
+			return domain.PendingTransfer{}, err
+		}
+
+		err = apply(&pendingTransfer, time.Now().UTC())
+
+		if err != nil {
+			return domain.PendingTransfer{}, err
+		}
+
+		pendingTransfer, err = s.pendingTransferRepo.Update(
+			pendingtransferrepo.UpdateRequest{
+				PendingTransfer: pendingTransfer,
+			},
+		)
+
+		if errors.Is(err, pendingtransferrepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return pendingTransfer, err
+	}
+}
+
+func (s svc) holdPendingTransfer(pendingTransfer domain.PendingTransfer, plan transferPlan) (money.Money, error) {
+	balance, err := s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:      pendingTransfer.SenderAccountID,
+			Debit:   plan.debited,
+			Minimum: plan.minimum,
+		},
+	)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          pendingTransfer.SenderAccountID,
+			Transaction: senderEntry(pendingTransfer, domain.OperationHold, newTransactionID(), plan.now),
+		},
+	)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          pendingTransfer.ReceiverAccountID,
+			Transaction: receiverNotice(pendingTransfer, plan.now),
+		},
+	)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	err = s.chargeFee(pendingTransfer.SenderAccountID, plan.fee, plan.now)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return balance, nil
+}
+
+// settlePendingTransfer releases the hold and books the transfer on both
+// accounts, leaving the sender's balance as the hold left it.
+func (s svc) settlePendingTransfer(pendingTransfer domain.PendingTransfer) error {
+	receiver, err := s.readUser(pendingTransfer.ReceiverUserID, actionReceive)
+
+	if err != nil {
+		return err
+	}
+
+	if !userAccount(receiver.AccountIDs, pendingTransfer.ReceiverAccountID) {
+		return errors.New("unauthorized account id")
+	}
+
+	receiverAccount, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: pendingTransfer.ReceiverAccountID,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if !receiverAccount.ClosedAt.IsZero() {
+		return errors.New("receiver account closed")
+	}
+
+	err = checkIncoming(receiverAccount, domain.OperationTransferIn)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     pendingTransfer.ReceiverAccountID,
+			Credit: pendingTransfer.Amount,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.bookPendingTransfer(pendingTransfer)
+
+	if err != nil {
+		return s.reclaim(pendingTransfer.ReceiverAccountID, pendingTransfer.Amount, err)
+	}
+
+	return nil
+}
+
+func (s svc) bookPendingTransfer(pendingTransfer domain.PendingTransfer) error {
+	now := pendingTransfer.RespondedAt
+
+	for _, transaction := range []domain.Transaction{
+		senderEntry(pendingTransfer, domain.OperationHoldRelease, newTransactionID(), now),
+		senderEntry(pendingTransfer, domain.OperationTransfer, pendingTransfer.TransactionID, now),
+	} {
+		err := s.accountRepo.UpdateTransactions(
+			accountrepo.UpdateTransactionsRequest{
+				ID:          pendingTransfer.SenderAccountID,
+				Transaction: transaction,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	transaction := withDetails(
+		domain.Transaction{
+			ID:              newTransactionID(),
+			Timestamp:       now,
+			Operation:       domain.OperationTransfer,
+			Amount:          pendingTransfer.Amount,
+			SenderUserID:    pendingTransfer.SenderUserID,
+			SenderAccountID: pendingTransfer.SenderAccountID,
+		},
+		pendingDetails(pendingTransfer),
+	)
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          pendingTransfer.ReceiverAccountID,
+			Transaction: transaction,
+		},
+	)
+}
+
+// releasePendingTransfer refunds the sender the held amount and fee and
+// tells the receiver why the transfer will not arrive.
+func (s svc) releasePendingTransfer(pendingTransfer domain.PendingTransfer) error {
+	refund, err := pendingTransfer.Amount.Add(pendingTransfer.Fee)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     pendingTransfer.SenderAccountID,
+			Credit: refund,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	now := pendingTransfer.RespondedAt
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          pendingTransfer.SenderAccountID,
+			Transaction: senderEntry(pendingTransfer, domain.OperationHoldRelease, newTransactionID(), now),
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if pendingTransfer.Fee.IsPositive() {
+		err = s.accountRepo.UpdateTransactions(
+			accountrepo.UpdateTransactionsRequest{
+				ID: pendingTransfer.SenderAccountID,
+				Transaction: domain.Transaction{
+					ID:        newTransactionID(),
+					Timestamp: now,
+					Operation: domain.OperationFeeRefund,
+					Amount:    pendingTransfer.Fee,
+				},
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          pendingTransfer.ReceiverAccountID,
+			Transaction: receiverNotice(pendingTransfer, now),
+		},
+	)
+}
+
+// releaseAccountPendingTransfers cancels the pending transfers sent from the
+// account and declines the ones waiting to be paid into it.
+func (s svc) releaseAccountPendingTransfers(accountID string) error {
+	pendingTransfers, err := s.pendingTransferRepo.List(
+		pendingtransferrepo.ListRequest{
+			Status: domain.PendingTransferPending,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for _, pendingTransfer := range pendingTransfers {
+		status := domain.PendingTransferDeclined
+
+		if pendingTransfer.SenderAccountID == accountID {
+			status = domain.PendingTransferCancelled
+		} else if pendingTransfer.ReceiverAccountID != accountID {
+			continue
+		}
+
+		pendingTransfer, err = s.expirePendingTransfer(pendingTransfer)
+
+		if err != nil {
+			return err
+		}
+
+		if pendingTransfer.Status != domain.PendingTransferPending {
+			continue
+		}
+
+		claimed, err := s.claimPendingTransfer(pendingTransfer.ID, status)
+
+		if errors.Is(err, errPendingTransferClosed) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = s.releasePendingTransfer(claimed)
+
+		if err != nil {
+			return s.compensateClaimPendingTransfer(claimed.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func senderEntry(pendingTransfer domain.PendingTransfer, operation string, transactionID string, now time.Time) domain.Transaction {
+	return withDetails(
+		domain.Transaction{
+			ID:                transactionID,
+			Timestamp:         now,
+			Operation:         operation,
+			Amount:            pendingTransfer.Amount,
+			ReceiverUserID:    pendingTransfer.ReceiverUserID,
+			ReceiverAccountID: pendingTransfer.ReceiverAccountID,
+		},
+		pendingDetails(pendingTransfer),
+	)
+}
+
+// receiverNotice does not change the receiver's balance.
+func receiverNotice(pendingTransfer domain.PendingTransfer, now time.Time) domain.Transaction {
+	transaction := withDetails(
+		domain.Transaction{
+			ID:              newTransactionID(),
+			Timestamp:       now,
+			Operation:       domain.OperationPending,
+			Amount:          pendingTransfer.Amount,
+			SenderUserID:    pendingTransfer.SenderUserID,
+			SenderAccountID: pendingTransfer.SenderAccountID,
+		},
+		pendingDetails(pendingTransfer),
+	)
+
+	transaction.Metadata[pendingStatusMetadataKey] = string(pendingTransfer.Status)
+
+	return transaction
+}
+
+// pendingDetails sets the transfer's id last so the sender's metadata cannot
+// override it.
+func pendingDetails(pendingTransfer domain.PendingTransfer) TransactionDetails {
+	metadata := maps.Clone(pendingTransfer.Metadata)
+
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+
+	metadata[pendingTransferMetadataKey] = pendingTransfer.ID
+
+	return TransactionDetails{
+		Description: pendingTransfer.Description,
+		Reference:   pendingTransfer.Reference,
+		Metadata:    metadata,
+	}
+}
+
+func pendingTransferResponse(pendingTransfer domain.PendingTransfer) PendingTransferResponse {
+	return PendingTransferResponse{
+		PendingTransferID: pendingTransfer.ID,
+		SenderUserID:      pendingTransfer.SenderUserID,
+		SenderAccountID:   pendingTransfer.SenderAccountID,
+		ReceiverUserID:    pendingTransfer.ReceiverUserID,
+		ReceiverAccountID: pendingTransfer.ReceiverAccountID,
+		Amount:            pendingTransfer.Amount,
+		Fee:               pendingTransfer.Fee,
+		Description:       pendingTransfer.Description,
+		Reference:         pendingTransfer.Reference,
+		Metadata:          pendingTransfer.Metadata,
+		Status:            pendingTransfer.Status,
+		CreatedAt:         pendingTransfer.CreatedAt,
+		ExpiresAt:         pendingTransfer.ExpiresAt,
+		RespondedAt:       pendingTransfer.RespondedAt,
+		TransactionID:     pendingTransfer.TransactionID,
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
+
+type action string
+
+const (
+	actionView     action = "view"
+	actionDeposit  action = "deposit"
+	actionWithdraw action = "withdraw"
+	actionTransfer action = "transfer"
+	actionManage   action = "manage"
+)
+
+var rolePermissions = map[domain.Role]map[action]struct{}{
+	domain.RoleOwner: {
+		actionView:     {},
+		actionDeposit:  {},
+		actionWithdraw: {},
+		actionTransfer: {},
+		actionManage:   {},
+	},
+	domain.RoleCoOwner: {
+		actionView:     {},
+		actionDeposit:  {},
+		actionWithdraw: {},
+		actionTransfer: {},
+	},
+	domain.RoleViewer: {
+		actionView: {},
+	},
+}
+
+func validRole(role domain.Role) bool {
+	_, exists := rolePermissions[role]
+
+	return exists
+}
+
+func holderRole(account domain.Account, userID string) (domain.Role, bool) {
+	if len(account.Holders) == 0 {
+		return domain.RoleOwner, true
+	}
+
+	role, exists := account.Holders[userID]
+
+	return role, exists
+}
+
+func authorize(account domain.Account, userID string, act action) error {
+	role, exists := holderRole(account, userID)
+
+	if !exists {
+		return errors.New("unauthorized account id")
+	}
+
+	if _, allowed := rolePermissions[role][act]; !allowed {
+		return errors.New("insufficient role")
+	}
+
+	return nil
+}
+
+func signer(role domain.Role) bool {
+	return role == domain.RoleOwner || role == domain.RoleCoOwner
+}
+
+func signers(account domain.Account, except string) []string {
+	userIDs := []string{}
+
+	for userID, role := range account.Holders {
+		if userID != except && signer(role) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	sort.Strings(userIDs)
+
+	return userIDs
+}
+
+func owners(account domain.Account, except string) []string {
+	userIDs := []string{}
+
+	for userID, role := range account.Holders {
+		if userID != except && role == domain.RoleOwner {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	sort.Strings(userIDs)
+
+	return userIDs
+}
+
+func (s svc) approve(account domain.Account, userID string, approverUserID string, amount money.Money) error {
+	if !account.DualApprovalThreshold.IsPositive() {
+		return nil
+	}
+
+	cmp, err := amount.Cmp(account.DualApprovalThreshold)
+
+	if err != nil {
+		return err
+	}
+
+	if cmp <= 0 {
+		return nil
+	}
+
+	if len(signers(account, userID)) == 0 {
+		return nil
+	}
+
+	if approverUserID == "" || approverUserID == userID {
+		return errors.New("approval required")
+	}
+
+	role, exists := account.Holders[approverUserID]
+
+	if !exists || !signer(role) {
+		return errors.New("invalid approver")
+	}
+
+	_, err = s.readUser(approverUserID, actionTransfer)
+
+	return err
+}
@@ -0,0 +1,844 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/potrepo"
+)
+
+const (
+	// potMetadataKey links pot moves in the account history to the pot, and
+	// autoSaveMetadataKey names the rule behind a move no one asked for.
+	potMetadataKey      = "pot_id"
+	autoSaveMetadataKey = "auto_save"
+
+	maxPotNameLength = 40
+	maxAutoSaveBps   = 10000
+
+	autoSaveWeek = 7 * 24 * time.Hour
+)
+
+var errPotClosed = errors.New("pot closed")
+
+// CreatePot is open to holders who can withdraw from the account.
+func (s svc) CreatePot(req CreatePotRequest) (PotResponse, error) {
+	if !validID(req.UserID) {
+		return PotResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	account, err := s.heldAccount(req.UserID, accountID, actionWithdraw)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return PotResponse{}, errors.New("account closed")
+	}
+
+	name, err := checkPotName(req.Name)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	now := time.Now().UTC()
+
+	currency := account.Balance.Currency()
+
+	err = checkPotGoal(req.Goal, req.GoalDate, currency, now)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	rules, err := autoSaveRules(req.AutoSave, currency, now)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	pot, err := s.potRepo.Create(
+		potrepo.CreateRequest{
+			Pot: domain.Pot{
+				AccountID: account.ID,
+				Name:      name,
+				Balance:   money.New(0, currency),
+				Goal:      req.Goal,
+				GoalDate:  req.GoalDate.UTC(),
+				AutoSave:  rules,
+			},
+		},
+	)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	return potResponse(pot), nil
+}
+
+func (s svc) Pots(req PotsRequest) (PotsResponse, error) {
+	if !validID(req.UserID) {
+		return PotsResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return PotsResponse{}, err
+	}
+
+	account, err := s.heldAccount(req.UserID, accountID, actionView)
+
+	if err != nil {
+		return PotsResponse{}, err
+	}
+
+	pots, err := s.potRepo.List(
+		potrepo.ListRequest{
+			AccountID: account.ID,
+			Open:      true,
+		},
+	)
+
+	if err != nil {
+		return PotsResponse{}, err
+	}
+
+	res := make([]PotResponse, 0, len(pots))
+
+	for _, pot := range pots {
+		res = append(res, potResponse(pot))
+	}
+
+	return PotsResponse{
+		Pots: res,
+	}, nil
+}
+
+func (s svc) Pot(req PotRequest) (PotResponse, error) {
+	pot, _, err := s.userPot(req.UserID, req.PotID, actionView)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	return potResponse(pot), nil
+}
+
+func (s svc) UpdatePot(req UpdatePotRequest) (PotResponse, error) {
+	pot, account, err := s.userPot(req.UserID, req.PotID, actionWithdraw)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	name := ""
+
+	if req.Name != "" {
+		name, err = checkPotName(req.Name)
+
+		if err != nil {
+			return PotResponse{}, err
+		}
+	}
+
+	if req.ClearGoal && (!req.Goal.IsZero() || !req.GoalDate.IsZero()) {
+		return PotResponse{}, errors.New("invalid goal, cleared and set")
+	}
+
+	err = checkPotGoal(req.Goal, req.GoalDate, account.Balance.Currency(), time.Now().UTC())
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	pot, err = s.updatePot(
+		pot.ID,
+		func(pot *domain.Pot, now time.Time) error {
+			if !pot.ClosedAt.IsZero() {
+				return errPotClosed
+			}
+
+			if name != "" {
+				pot.Name = name
+			}
+
+			if req.ClearGoal {
+				pot.Goal = money.Money{}
+				pot.GoalDate = time.Time{}
+			}
+
+			if !req.Goal.IsZero() {
+				pot.Goal = req.Goal
+			}
+
+			if !req.GoalDate.IsZero() {
+				pot.GoalDate = req.GoalDate.UTC()
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	return potResponse(pot), nil
+}
+
+// ClosePot keeps the closed pot so the moves in the account history still
+// lead to it.
+func (s svc) ClosePot(req ClosePotRequest) (PotResponse, error) {
+	pot, account, err := s.userPot(req.UserID, req.PotID, actionWithdraw)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return PotResponse{}, errors.New("account closed")
+	}
+
+	pot, err = s.closePot(pot.ID)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	return potResponse(pot), nil
+}
+
+func (s svc) MoveToPot(req MovePotMoneyRequest) (PotMoveResponse, error) {
+	return s.movePot(req, domain.OperationPotIn)
+}
+
+func (s svc) MoveFromPot(req MovePotMoneyRequest) (PotMoveResponse, error) {
+	return s.movePot(req, domain.OperationPotOut)
+}
+
+// PutAutoSaveRules restarts weekly rules from the next run.
+func (s svc) PutAutoSaveRules(req PutAutoSaveRulesRequest) (PotResponse, error) {
+	pot, account, err := s.userPot(req.UserID, req.PotID, actionWithdraw)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	rules, err := autoSaveRules(req.AutoSave, account.Balance.Currency(), time.Now().UTC())
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	pot, err = s.updatePot(
+		pot.ID,
+		func(pot *domain.Pot, now time.Time) error {
+			if !pot.ClosedAt.IsZero() {
+				return errPotClosed
+			}
+
+			pot.AutoSave = rules
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return PotResponse{}, err
+	}
+
+	return potResponse(pot), nil
+}
+
+// RunWeeklyAutoSaves moves a rule's next run on before saving, so a run that
+// fails halfway never saves twice. Missed weeks are not made up.
+func (s svc) RunWeeklyAutoSaves(req RunWeeklyAutoSavesRequest) (RunWeeklyAutoSavesResponse, error) {
+	pots, err := s.potRepo.List(
+		potrepo.ListRequest{
+			Open: true,
+		},
+	)
+
+	if err != nil {
+		return RunWeeklyAutoSavesResponse{}, err
+	}
+
+	res := RunWeeklyAutoSavesResponse{
+		SavedPotIDs:   []string{},
+		SkippedPotIDs: []string{},
+	}
+
+	var errs []error
+
+	for _, pot := range pots {
+		for i, rule := range pot.AutoSave {
+			if rule.Type != domain.AutoSaveWeekly || rule.NextRunAt.After(time.Now().UTC()) {
+				continue
+			}
+
+			_, err = s.updatePot(
+				pot.ID,
+				func(pot *domain.Pot, now time.Time) error {
+					if !pot.ClosedAt.IsZero() {
+						return errPotClosed
+					}
+
+					if i >= len(pot.AutoSave) || pot.AutoSave[i] != rule {
+						return ErrAutoSaveChanged
+					}
+
+					for !pot.AutoSave[i].NextRunAt.After(now) {
+						pot.AutoSave[i].NextRunAt = pot.AutoSave[i].NextRunAt.Add(autoSaveWeek)
+					}
+
+					return nil
+				},
+			)
+
+			if errors.Is(err, errPotClosed) || errors.Is(err, ErrAutoSaveChanged) {
+				continue
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			_, _, err = s.bookPotMove(pot.ID, newTransactionID(), domain.OperationPotIn, rule.Amount, rule.Type)
+
+			if err != nil {
+				res.SkippedPotIDs = append(res.SkippedPotIDs, pot.ID)
+
+				continue
+			}
+
+			res.SavedPotIDs = append(res.SavedPotIDs, pot.ID)
+		}
+	}
+
+	return res, errors.Join(errs...)
+}
+
+// autoSave is best effort: the deposit or withdrawal stands whatever happens
+// to the saving. It returns the account balance after what was saved.
+func (s svc) autoSave(accountID string, ruleType domain.AutoSaveRuleType, amount money.Money, balance money.Money) money.Money {
+	pots, err := s.potRepo.List(
+		potrepo.ListRequest{
+			AccountID: accountID,
+			Open:      true,
+		},
+	)
+
+	if err != nil {
+		log.Printf("auto-save %s: %v", accountID, err)
+
+		return balance
+	}
+
+	for _, pot := range pots {
+		for _, rule := range pot.AutoSave {
+			if rule.Type != ruleType {
+				continue
+			}
+
+			saving, err := autoSaveAmount(rule, amount)
+
+			if err != nil {
+				log.Printf("auto-save pot %s: %v", pot.ID, err)
+
+				continue
+			}
+
+			if !saving.IsPositive() {
+				continue
+			}
+
+			_, booked, err := s.bookPotMove(pot.ID, newTransactionID(), domain.OperationPotIn, saving, rule.Type)
+
+			if errors.Is(err, accountrepo.ErrInsufficientFunds) {
+				continue
+			}
+
+			if err != nil {
+				log.Printf("auto-save pot %s: %v", pot.ID, err)
+
+				continue
+			}
+
+			balance = booked
+		}
+	}
+
+	return balance
+}
+
+func (s svc) movePot(req MovePotMoneyRequest, operation string) (PotMoveResponse, error) {
+	if !req.Amount.IsPositive() {
+		return PotMoveResponse{}, errors.New("invalid amount")
+	}
+
+	pot, account, err := s.userPot(req.UserID, req.PotID, actionWithdraw)
+
+	if err != nil {
+		return PotMoveResponse{}, err
+	}
+
+	if req.Amount.Currency() != account.Balance.Currency() {
+		return PotMoveResponse{}, errors.New("invalid amount, currency mismatch")
+	}
+
+	transactionID := newTransactionID()
+
+	pot, balance, err := s.bookPotMove(pot.ID, transactionID, operation, req.Amount, "")
+
+	if err != nil {
+		return PotMoveResponse{}, err
+	}
+
+	return PotMoveResponse{
+		Pot:            potResponse(pot),
+		AccountBalance: balance,
+		TransactionID:  transactionID,
+	}, nil
+}
+
+// bookPotMove updates the pot first and puts it back if the account side
+// cannot be booked.
+func (s svc) bookPotMove(potID string, transactionID string, operation string, amount money.Money, ruleType domain.AutoSaveRuleType) (domain.Pot, money.Money, error) {
+	pot, err := s.updatePot(
+		potID,
+		func(pot *domain.Pot, now time.Time) error {
+			if !pot.ClosedAt.IsZero() {
+				return errPotClosed
+			}
+
+			return movePotBalance(pot, operation, amount)
+		},
+	)
+
+	if err != nil {
+		return domain.Pot{}, money.Money{}, err
+	}
+
+	metadata := map[string]string{
+		potMetadataKey: pot.ID,
+	}
+
+	if ruleType != "" {
+		metadata[autoSaveMetadataKey] = string(ruleType)
+	}
+
+	balance, err := s.bookPotTransaction(
+		pot.AccountID,
+		domain.Transaction{
+			ID:        transactionID,
+			Timestamp: time.Now().UTC(),
+			Operation: operation,
+			Amount:    amount,
+			Metadata:  metadata,
+		},
+	)
+
+	if err != nil {
+		return domain.Pot{}, money.Money{}, s.compensatePotMove(pot.ID, operation, amount, err)
+	}
+
+	return pot, balance, nil
+}
+
+func (s svc) bookPotTransaction(accountID string, transaction domain.Transaction) (money.Money, error) {
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return money.Money{}, errors.New("account closed")
+	}
+
+	update := accountrepo.UpdateBalanceRequest{
+		ID: accountID,
+	}
+
+	if transaction.Operation == domain.OperationPotOut {
+		update.Credit = transaction.Amount
+	} else {
+		update.Debit = transaction.Amount
+	}
+
+	balance, err := s.accountRepo.UpdateBalance(update)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID:          accountID,
+			Transaction: transaction,
+		},
+	)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return balance, nil
+}
+
+// compensatePotMove also applies to a pot closed in the meantime.
+func (s svc) compensatePotMove(potID string, operation string, amount money.Money, cause error) error {
+	reverse := domain.OperationPotOut
+
+	if operation == domain.OperationPotOut {
+		reverse = domain.OperationPotIn
+	}
+
+	_, err := s.updatePot(
+		potID,
+		func(pot *domain.Pot, now time.Time) error {
+			return movePotBalance(pot, reverse, amount)
+		},
+	)
+
+	return errors.Join(cause, err)
+}
+
+func (s svc) closePot(potID string) (domain.Pot, error) {
+	var swept money.Money
+
+	pot, err := s.updatePot(
+		potID,
+		func(pot *domain.Pot, now time.Time) error {
+			if !pot.ClosedAt.IsZero() {
+				return errPotClosed
+			}
+
+			swept = pot.Balance
+
+			pot.Balance = money.New(0, pot.Balance.Currency())
+			pot.ClosedAt = now
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return domain.Pot{}, err
+	}
+
+	if !swept.IsPositive() {
+		return pot, nil
+	}
+
+	_, err = s.bookPotTransaction(
+		pot.AccountID,
+		domain.Transaction{
+			ID:        newTransactionID(),
+			Timestamp: pot.ClosedAt,
+			Operation: domain.OperationPotOut,
+			Amount:    swept,
+			Metadata: map[string]string{
+				potMetadataKey: pot.ID,
+			},
+		},
+	)
+
+	if err != nil {
+		return domain.Pot{}, s.compensateClosePot(pot.ID, swept, err)
+	}
+
+	return pot, nil
+}
+
+func (s svc) compensateClosePot(potID string, swept money.Money, cause error) error {
+	_, err := s.updatePot(
+		potID,
+		func(pot *domain.Pot, now time.Time) error {
+			pot.Balance = swept
+			pot.ClosedAt = time.Time{}
+
+			return nil
+		},
+	)
+
+	return errors.Join(cause, err)
+}
+
+func (s svc) accountPots(account domain.Account) ([]domain.Pot, money.Money, error) {
+	pots, err := s.potRepo.List(
+		potrepo.ListRequest{
+			AccountID: account.ID,
+			Open:      true,
+		},
+	)
+
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	total := money.New(0, account.Balance.Currency())
+
+	for _, pot := range pots {
+		total, err = total.Add(pot.Balance)
+
+		if err != nil {
+			return nil, money.Money{}, err
+		}
+	}
+
+	return pots, total, nil
+}
+
+// userPot does not find pots of accounts the user cannot act on.
+func (s svc) userPot(userID string, potID string, act action) (domain.Pot, domain.Account, error) {
+	if !validID(userID) {
+		return domain.Pot{}, domain.Account{}, errors.New("invalid user id")
+	}
+
+	if !validID(potID) {
+		return domain.Pot{}, domain.Account{}, errors.New("invalid pot id")
+	}
+
+	user, err := s.readUser(userID, act)
+
+	if err != nil {
+		return domain.Pot{}, domain.Account{}, err
+	}
+
+	pot, err := s.potRepo.Read(
+		potrepo.ReadRequest{
+			ID: potID,
+		},
+	)
+
+	if err != nil {
+		return domain.Pot{}, domain.Account{}, err
+	}
+
+	if !userAccount(user.AccountIDs, pot.AccountID) {
+		return domain.Pot{}, domain.Account{}, errors.New("pot not found")
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: pot.AccountID,
+		},
+	)
+
+	if err != nil {
+		return domain.Pot{}, domain.Account{}, err
+	}
+
+	err = authorize(account, userID, act)
+
+	if err != nil {
+		return domain.Pot{}, domain.Account{}, err
+	}
+
+	return pot, account, nil
+}
+
+func (s svc) updatePot(potID string, apply func(*domain.Pot, time.Time) error) (domain.Pot, error) {
+	for attempt := 1; ; attempt++ {
+		pot, err := s.potRepo.Read(
+			potrepo.ReadRequest{
+				ID: potID,
+			},
+		)
+
+		if err != nil {
+			return domain.Pot{}, err
+		}
+
+		err = apply(&pot, time.Now().UTC())
+
+		if err != nil {
+			return domain.Pot{}, err
+		}
+
+		pot, err = s.potRepo.Update(
+			potrepo.UpdateRequest{
+				Pot: pot,
+			},
+		)
+
+		if errors.Is(err, potrepo.ErrVersionConflict) && attempt < maxUpdateAttempts {
+			continue
+		}
+
+		return pot, err
+	}
+}
+
+func movePotBalance(pot *domain.Pot, operation string, amount money.Money) error {
+	var err error
+
+	balance := pot.Balance
+
+	if operation == domain.OperationPotIn {
+		balance, err = balance.Add(amount)
+	} else {
+		balance, err = balance.Sub(amount)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if balance.IsNegative() {
+		return errors.New("insuficient funds in pot")
+	}
+
+	pot.Balance = balance
+
+	return nil
+}
+
+func checkPotName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" || utf8.RuneCountInString(name) > maxPotNameLength {
+		return "", errors.New("invalid pot name")
+	}
+
+	return name, nil
+}
+
+func checkPotGoal(goal money.Money, goalDate time.Time, currency money.Currency, now time.Time) error {
+	if !goal.IsZero() && (!goal.IsPositive() || goal.Currency() != currency) {
+		return errors.New("invalid goal")
+	}
+
+	if !goalDate.IsZero() && !goalDate.After(now) {
+		return errors.New("invalid goal date")
+	}
+
+	return nil
+}
+
+// autoSaveRules allows at most one rule of each type.
+func autoSaveRules(rules []AutoSaveRule, currency money.Currency, now time.Time) ([]domain.AutoSaveRule, error) {
+	res := make([]domain.AutoSaveRule, 0, len(rules))
+
+	seen := map[domain.AutoSaveRuleType]struct{}{}
+
+	for _, rule := range rules {
+		if _, exists := seen[rule.Type]; exists {
+			return nil, errors.New("invalid auto-save rules, duplicate type")
+		}
+
+		seen[rule.Type] = struct{}{}
+
+		saved := domain.AutoSaveRule{
+			Type: rule.Type,
+		}
+
+		switch rule.Type {
+		case domain.AutoSaveRoundUp:
+			if rule.PercentageBps != 0 || !rule.Amount.IsZero() {
+				return nil, errors.New("invalid round-up rule")
+			}
+		case domain.AutoSaveDepositPercentage:
+			if rule.PercentageBps <= 0 || rule.PercentageBps > maxAutoSaveBps || !rule.Amount.IsZero() {
+				return nil, errors.New("invalid deposit percentage rule")
+			}
+
+			saved.PercentageBps = rule.PercentageBps
+		case domain.AutoSaveWeekly:
+			if rule.PercentageBps != 0 || !rule.Amount.IsPositive() || rule.Amount.Currency() != currency {
+				return nil, errors.New("invalid weekly rule")
+			}
+
+			saved.Amount = rule.Amount
+			saved.NextRunAt = now
+		default:
+			return nil, errors.New("invalid auto-save rule type")
+		}
+
+		res = append(res, saved)
+	}
+
+	return res, nil
+}
+
+func autoSaveAmount(rule domain.AutoSaveRule, amount money.Money) (money.Money, error) {
+	switch rule.Type {
+	case domain.AutoSaveRoundUp:
+		return amount.RoundUp(), nil
+	case domain.AutoSaveDepositPercentage:
+		return amount.MulBps(rule.PercentageBps)
+	default:
+		return money.Money{}, nil
+	}
+}
+
+func potResponse(pot domain.Pot) PotResponse {
+	rules := make([]AutoSaveRuleResponse, 0, len(pot.AutoSave))
+
+	for _, rule := range pot.AutoSave {
+		rules = append(
+			rules,
+			AutoSaveRuleResponse{
+				Type:          rule.Type,
+				PercentageBps: rule.PercentageBps,
+				Amount:        rule.Amount,
+				NextRunAt:     rule.NextRunAt,
+			},
+		)
+	}
+
+	return PotResponse{
+		PotID:           pot.ID,
+		AccountID:       pot.AccountID,
+		Name:            pot.Name,
+		Balance:         pot.Balance,
+		Goal:            pot.Goal,
+		GoalDate:        pot.GoalDate,
+		GoalProgressBps: goalProgress(pot),
+		CreatedAt:       pot.CreatedAt,
+		ClosedAt:        pot.ClosedAt,
+		AutoSave:        rules,
+	}
+}
+
+// goalProgress is in basis points, capped at the goal.
+func goalProgress(pot domain.Pot) int {
+	if !pot.Goal.IsPositive() {
+		return 0
+	}
+
+	cmp, err := pot.Balance.Cmp(pot.Goal)
+
+	if err != nil {
+		return 0
+	}
+
+	if cmp >= 0 {
+		return 10000
+	}
+
+	return int(float64(pot.Balance.Minor()) * 10000 / float64(pot.Goal.Minor()))
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+)
+
+var productOperations = map[string]struct{}{
+	domain.OperationDeposit:     {},
+	domain.OperationWithdraw:    {},
+	domain.OperationTransferIn:  {},
+	domain.OperationTransferOut: {},
+}
+
+func (s svc) CreateProduct(req CreateProductRequest) (ProductResponse, error) {
+	if req.ProductID == "" {
+		return ProductResponse{}, errors.New("invalid product id")
+	}
+
+	err := validProductTerms(req.ProductTerms)
+
+	if err != nil {
+		return ProductResponse{}, err
+	}
+
+	product, err := s.productRepo.Create(
+		productrepo.CreateRequest{
+			Product: productFromTerms(req.ProductID, req.ProductTerms),
+		},
+	)
+
+	if err != nil {
+		return ProductResponse{}, err
+	}
+
+	return productResponse(product), nil
+}
+
+func (s svc) UpdateProduct(req UpdateProductRequest) (ProductResponse, error) {
+	if req.ProductID == "" {
+		return ProductResponse{}, errors.New("invalid product id")
+	}
+
+	err := validProductTerms(req.ProductTerms)
+
+	if err != nil {
+		return ProductResponse{}, err
+	}
+
+	product, err := s.productRepo.Update(
+		productrepo.UpdateRequest{
+			Product: productFromTerms(req.ProductID, req.ProductTerms),
+		},
+	)
+
+	if err != nil {
+		return ProductResponse{}, err
+	}
+
+	return productResponse(product), nil
+}
+
+func (s svc) Product(req ProductRequest) (ProductResponse, error) {
+	if req.ProductID == "" {
+		return ProductResponse{}, errors.New("invalid product id")
+	}
+
+	if req.Version < 0 {
+		return ProductResponse{}, errors.New("invalid product version")
+	}
+
+	product, err := s.productRepo.Read(
+		productrepo.ReadRequest{
+			ID:      req.ProductID,
+			Version: req.Version,
+		},
+	)
+
+	if err != nil {
+		return ProductResponse{}, err
+	}
+
+	return productResponse(product), nil
+}
+
+func (s svc) Products(req ProductsRequest) (ProductsResponse, error) {
+	products, err := s.productRepo.List(productrepo.ListRequest{})
+
+	if err != nil {
+		return ProductsResponse{}, err
+	}
+
+	res := ProductsResponse{
+		Products: make([]ProductResponse, 0, len(products)),
+	}
+
+	for _, product := range products {
+		res.Products = append(res.Products, productResponse(product))
+	}
+
+	return res, nil
+}
+
+func validProductTerms(terms ProductTerms) error {
+	if terms.Name == "" {
+		return errors.New("invalid product name")
+	}
+
+	switch terms.Type {
+	case domain.ProductChecking, domain.ProductSavings:
+		if terms.TermDays != 0 {
+			return errors.New("invalid term days")
+		}
+	case domain.ProductTermDeposit:
+		if terms.TermDays <= 0 {
+			return errors.New("invalid term days")
+		}
+	default:
+		return errors.New("invalid product type")
+	}
+
+	for _, op := range terms.Operations {
+		if _, exists := productOperations[op]; !exists {
+			return errors.New("invalid product operation")
+		}
+	}
+
+	if terms.Currency != "" && !terms.Currency.Valid() {
+		return money.ErrUnsupportedCurrency
+	}
+
+	if terms.MonthlyWithdrawalLimit < 0 ||
+		terms.InterestRateBps < 0 ||
+		terms.EarlyBreakPenaltyBps < 0 {
+		return errors.New("invalid product terms")
+	}
+
+	currency := productCurrency(terms)
+
+	for _, amount := range []money.Money{terms.MinimumBalance, terms.WithdrawalFee, terms.TransferFee, terms.MonthlyFee} {
+		if amount.IsNegative() {
+			return errors.New("invalid product terms")
+		}
+
+		if !amount.IsZero() && amount.Currency() != currency {
+			return money.ErrCurrencyMismatch
+		}
+	}
+
+	return nil
+}
+
+func productCurrency(terms ProductTerms) money.Currency {
+	if terms.Currency == "" {
+		return money.DefaultCurrency
+	}
+
+	return terms.Currency
+}
+
+func productFromTerms(id string, terms ProductTerms) domain.Product {
+	return domain.Product{
+		ID:                     id,
+		Name:                   terms.Name,
+		Type:                   terms.Type,
+		Currency:               productCurrency(terms),
+		Operations:             terms.Operations,
+		MonthlyWithdrawalLimit: terms.MonthlyWithdrawalLimit,
+		MinimumBalance:         terms.MinimumBalance,
+		InterestRateBps:        terms.InterestRateBps,
+		WithdrawalFee:          terms.WithdrawalFee,
+		TransferFee:            terms.TransferFee,
+		MonthlyFee:             terms.MonthlyFee,
+		TermDays:               terms.TermDays,
+		EarlyBreakPenaltyBps:   terms.EarlyBreakPenaltyBps,
+	}
+}
+
+func productResponse(product domain.Product) ProductResponse {
+	return ProductResponse{
+		ProductID: product.ID,
+		Version:   product.Version,
+		CreatedAt: product.CreatedAt,
+		ProductTerms: ProductTerms{
+			Name:                   product.Name,
+			Type:                   product.Type,
+			Currency:               product.Currency,
+			Operations:             product.Operations,
+			MonthlyWithdrawalLimit: product.MonthlyWithdrawalLimit,
+			MinimumBalance:         product.MinimumBalance,
+			InterestRateBps:        product.InterestRateBps,
+			WithdrawalFee:          product.WithdrawalFee,
+			TransferFee:            product.TransferFee,
+			MonthlyFee:             product.MonthlyFee,
+			TermDays:               product.TermDays,
+			EarlyBreakPenaltyBps:   product.EarlyBreakPenaltyBps,
+		},
+	}
+}
+
+func allowsOperation(account domain.Account, op string) bool {
+	if account.Product.ID == "" {
+		return true
+	}
+
+	for _, allowed := range account.Product.Operations {
+		if allowed == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkIncoming(account domain.Account, op string) error {
+	if !allowsOperation(account, op) {
+		return errors.New("operation not allowed by product")
+	}
+
+	return nil
+}
+
+func checkOutgoing(account domain.Account, op string, amount money.Money, breakTerm bool, now time.Time) (money.Money, error) {
+	if !allowsOperation(account, op) {
+		return money.Money{}, errors.New("operation not allowed by product")
+	}
+
+	product := account.Product
+
+	fee := product.WithdrawalFee
+
+	if op == domain.OperationTransferOut {
+		fee = product.TransferFee
+	}
+
+	if now.Before(account.MaturesAt) {
+		if !breakTerm {
+			return money.Money{}, errors.New("funds locked until maturity")
+		}
+
+		penalty, err := amount.MulBps(product.EarlyBreakPenaltyBps)
+
+		if err != nil {
+			return money.Money{}, err
+		}
+
+		fee, err = fee.Add(penalty)
+
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	if product.MonthlyWithdrawalLimit > 0 && monthlyWithdrawals(account, now) >= product.MonthlyWithdrawalLimit {
+		return money.Money{}, errors.New("monthly withdrawal limit reached")
+	}
+
+	balance, err := debit(account.Balance, amount, fee)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	cmp, err := balance.Cmp(product.MinimumBalance)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if cmp < 0 {
+		return money.Money{}, errors.New("minimum balance")
+	}
+
+	return fee, nil
+}
+
+func monthlyWithdrawals(account domain.Account, now time.Time) int {
+	year, month, _ := now.Date()
+
+	count := 0
+
+	for _, transaction := range account.Transactions {
+		y, m, _ := transaction.Timestamp.Date()
+
+		if y != year || m != month {
+			continue
+		}
+
+		if transaction.Operation == domain.OperationWithdraw {
+			count++
+		}
+
+		if transaction.Operation == domain.OperationTransfer && transaction.ReceiverAccountID != "" {
+			count++
+		}
+	}
+
+	return count
+}
@@ -1,35 +1,78 @@
 package service
 
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
+
 type CreateUserRequest struct {
-	Name string `json:"name"`
+	Name string `json:"name" openapi:"required"`
 }
 
 type CreateAccountRequest struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	ProductID string `json:"product_id,omitempty"`
 }
 
 type DeactivateUserRequest struct {
+	UserID          string `json:"user_id"`
+	Reason          string `json:"reason"`
+	PayoutUserID    string `json:"payout_user_id"`
+	PayoutAccountID string `json:"payout_account_id"`
+}
+
+type ReactivateUserRequest struct {
 	UserID string `json:"user_id"`
 }
 
+type CloseUsersRequest struct{}
+
+// TransactionDetails says what a payment is for. Reference is an ISO 11649
+// creditor reference such as RF18 5390 0754 7034, stored without spaces.
+type TransactionDetails struct {
+	Description string            `json:"description,omitempty"`
+	Reference   string            `json:"reference,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
 type DepositRequest struct {
-	UserID    string `json:"user_id"`
-	AccountID string `json:"account_id"`
-	Amount    int    `json:"amount"`
+	UserID    string      `json:"user_id"`
+	AccountID string      `json:"account_id"`
+	Amount    money.Money `json:"amount" openapi:"required"`
+	TransactionDetails
 }
 
 type WithdrawRequest struct {
-	UserID    string `json:"user_id"`
-	AccountID string `json:"account_id"`
-	Amount    int    `json:"amount"`
+	UserID         string      `json:"user_id"`
+	AccountID      string      `json:"account_id"`
+	Amount         money.Money `json:"amount" openapi:"required"`
+	ApproverUserID string      `json:"approver_user_id,omitempty"`
+	BreakTerm      bool        `json:"break_term,omitempty"`
+	TransactionDetails
 }
 
+// TransferRequest names the receiver either by PayeeID or by both receiver
+// ids. When ReceiverName is set, or the payee has a name, it is checked
+// against the receiving account's holders and anything short of an exact
+// match is refused unless AcceptNameMismatch is set.
 type TransferRequest struct {
-	SenderUserID      string `json:"sender_user_id"`
-	ReceiverUserID    string `json:"receiver_user_id"`
-	SenderAccountID   string `json:"sender_account_id"`
-	ReceiverAccountID string `json:"receiver_account_id"`
-	Amount            int    `json:"amount"`
+	SenderUserID       string      `json:"sender_user_id"`
+	ReceiverUserID     string      `json:"receiver_user_id,omitempty"`
+	SenderAccountID    string      `json:"sender_account_id"`
+	ReceiverAccountID  string      `json:"receiver_account_id,omitempty"`
+	PayeeID            string      `json:"payee_id,omitempty"`
+	ReceiverName       string      `json:"receiver_name,omitempty"`
+	AcceptNameMismatch bool        `json:"accept_name_mismatch,omitempty"`
+	Amount             money.Money `json:"amount" openapi:"required"`
+	ApproverUserID     string      `json:"approver_user_id,omitempty"`
+	BreakTerm          bool        `json:"break_term,omitempty"`
+	TransactionDetails
+	closing   bool
+	requested bool
+	mandated  bool
+	batch     bool
 }
 
 type BalanceRequest struct {
@@ -37,7 +80,565 @@ type BalanceRequest struct {
 	AccountID string `json:"account_id"`
 }
 
+// TransactionsRequest filters the history when any of Query, Reference or
+// Metadata is set. Query matches description, reference and metadata values
+// case-insensitively, Reference matches exactly (spaces and case ignored)
+// and every Metadata pair must be present.
 type TransactionsRequest struct {
+	UserID    string            `json:"user_id"`
+	AccountID string            `json:"account_id"`
+	Query     string            `json:"q,omitempty"`
+	Reference string            `json:"reference,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type TransactionRequest struct {
+	UserID        string `json:"user_id"`
+	TransactionID string `json:"transaction_id"`
+}
+
+type AccountsRequest struct {
+	UserID string `json:"user_id"`
+}
+
+type AddHolderRequest struct {
+	UserID       string      `json:"user_id"`
+	AccountID    string      `json:"account_id"`
+	HolderUserID string      `json:"holder_user_id" openapi:"required"`
+	Role         domain.Role `json:"role" openapi:"required"`
+}
+
+type RemoveHolderRequest struct {
+	UserID       string `json:"user_id"`
+	AccountID    string `json:"account_id"`
+	HolderUserID string `json:"holder_user_id"`
+}
+
+type HoldersRequest struct {
+	UserID    string `json:"user_id"`
+	AccountID string `json:"account_id"`
+}
+
+type UpdateAccountRulesRequest struct {
+	UserID                string      `json:"user_id"`
+	AccountID             string      `json:"account_id"`
+	DualApprovalThreshold money.Money `json:"dual_approval_threshold" openapi:"required"`
+}
+
+type ProductTerms struct {
+	Name                   string             `json:"name" openapi:"required"`
+	Type                   domain.ProductType `json:"type" openapi:"required"`
+	Currency               money.Currency     `json:"currency,omitempty"`
+	Operations             []string           `json:"operations" openapi:"required"`
+	MonthlyWithdrawalLimit int                `json:"monthly_withdrawal_limit"`
+	MinimumBalance         money.Money        `json:"minimum_balance"`
+	InterestRateBps        int                `json:"interest_rate_bps"`
+	WithdrawalFee          money.Money        `json:"withdrawal_fee"`
+	TransferFee            money.Money        `json:"transfer_fee"`
+	MonthlyFee             money.Money        `json:"monthly_fee"`
+	TermDays               int                `json:"term_days"`
+	EarlyBreakPenaltyBps   int                `json:"early_break_penalty_bps"`
+}
+
+type CreateProductRequest struct {
+	ProductID string `json:"product_id" openapi:"required"`
+	ProductTerms
+}
+
+type UpdateProductRequest struct {
+	ProductID string `json:"product_id"`
+	ProductTerms
+}
+
+type ProductRequest struct {
+	ProductID string `json:"product_id"`
+	Version   int    `json:"version"`
+}
+
+type ProductsRequest struct{}
+
+type BatchTransferRequest struct {
+	SenderUserID    string            `json:"sender_user_id"`
+	SenderAccountID string            `json:"sender_account_id"`
+	AllOrNothing    bool              `json:"all_or_nothing"`
+	Transfers       []TransferRequest `json:"transfers"`
+}
+
+type LimitTerms struct {
+	Scope     domain.LimitScope `json:"scope" openapi:"required"`
+	Operation string            `json:"operation" openapi:"required"`
+	Window    string            `json:"window" openapi:"required"`
+	MaxAmount money.Money       `json:"max_amount"`
+	MaxCount  int               `json:"max_count"`
+}
+
+type PutTierRequest struct {
+	TierID string       `json:"tier_id"`
+	Limits []LimitTerms `json:"limits" openapi:"required"`
+}
+
+type TierRequest struct {
+	TierID string `json:"tier_id"`
+}
+
+type TiersRequest struct{}
+
+type UpdateUserTierRequest struct {
+	UserID string `json:"user_id"`
+	TierID string `json:"tier_id"`
+}
+
+type AddressTerms struct {
+	Line1      string `json:"line1" openapi:"required"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city" openapi:"required"`
+	PostalCode string `json:"postal_code" openapi:"required"`
+	Country    string `json:"country" openapi:"required"`
+}
+
+type SubmitKYCRequest struct {
+	UserID      string       `json:"user_id"`
+	DateOfBirth string       `json:"date_of_birth" openapi:"required"`
+	Address     AddressTerms `json:"address" openapi:"required"`
+	IDNumber    string       `json:"id_number" openapi:"required"`
+}
+
+type KYCRequest struct {
+	UserID string `json:"user_id"`
+}
+
+type UpdateUserStatusRequest struct {
+	UserID string            `json:"user_id"`
+	Status domain.UserStatus `json:"status" openapi:"required"`
+	Reason string            `json:"reason"`
+}
+
+// AddPayeeRequest takes the payee's account id or IBAN and the name the user
+// believes holds it.
+type AddPayeeRequest struct {
+	UserID             string `json:"user_id"`
+	Nickname           string `json:"nickname" openapi:"required"`
+	Name               string `json:"name" openapi:"required"`
+	AccountID          string `json:"account_id" openapi:"required"`
+	AcceptNameMismatch bool   `json:"accept_name_mismatch,omitempty"`
+}
+
+type PayeesRequest struct {
+	UserID string `json:"user_id"`
+}
+
+type PayeeRequest struct {
+	UserID  string `json:"user_id"`
+	PayeeID string `json:"payee_id"`
+}
+
+// UpdatePayeeRequest leaves empty fields unchanged. A new name is checked
+// again; the account cannot change, so the cooling-off period is kept.
+type UpdatePayeeRequest struct {
+	UserID             string `json:"user_id"`
+	PayeeID            string `json:"payee_id"`
+	Nickname           string `json:"nickname,omitempty"`
+	Name               string `json:"name,omitempty"`
+	AcceptNameMismatch bool   `json:"accept_name_mismatch,omitempty"`
+}
+
+type DeletePayeeRequest struct {
+	UserID  string `json:"user_id"`
+	PayeeID string `json:"payee_id"`
+}
+
+// RequestPaymentRequest asks each payer for a share of Amount, paid into the
+// requester's account (id or IBAN). Either every payer names their share,
+// in which case Amount may be left out, or none does and Amount is split
+// evenly. ExpiresAt defaults to the configured time to live.
+type RequestPaymentRequest struct {
+	UserID      string       `json:"user_id"`
+	AccountID   string       `json:"account_id" openapi:"required"`
+	Amount      money.Money  `json:"amount"`
+	Payers      []PayerTerms `json:"payers" openapi:"required"`
+	Description string       `json:"description,omitempty"`
+	ExpiresAt   time.Time    `json:"expires_at,omitempty"`
+}
+
+type PayerTerms struct {
+	UserID string      `json:"user_id" openapi:"required"`
+	Amount money.Money `json:"amount"`
+}
+
+// PaymentRequestsRequest lists the requests a user made (outgoing), those
+// addressed to them (incoming) or, when Direction is empty, both. Status
+// filters on the request status.
+type PaymentRequestsRequest struct {
+	UserID    string                      `json:"user_id"`
+	Direction string                      `json:"direction"`
+	Status    domain.PaymentRequestStatus `json:"status"`
+}
+
+type PaymentRequestRequest struct {
+	UserID           string `json:"user_id"`
+	PaymentRequestID string `json:"payment_request_id"`
+}
+
+// AcceptPaymentRequestRequest pays the user's share from AccountID.
+type AcceptPaymentRequestRequest struct {
+	UserID           string `json:"user_id"`
+	PaymentRequestID string `json:"payment_request_id"`
+	AccountID        string `json:"account_id" openapi:"required"`
+	ApproverUserID   string `json:"approver_user_id,omitempty"`
+}
+
+type DeclinePaymentRequestRequest struct {
+	UserID           string `json:"user_id"`
+	PaymentRequestID string `json:"payment_request_id"`
+}
+
+type CancelPaymentRequestRequest struct {
+	UserID           string `json:"user_id"`
+	PaymentRequestID string `json:"payment_request_id"`
+}
+
+// CreatePendingTransferRequest is a transfer the receiver has to accept
+// before ExpiresAt, which defaults to the service's pending transfer TTL.
+type CreatePendingTransferRequest struct {
+	TransferRequest
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// PendingTransfersRequest lists the transfers the user sent (outgoing) or is
+// asked to accept (incoming), or both when Direction is empty.
+type PendingTransfersRequest struct {
+	UserID    string                       `json:"user_id"`
+	Direction string                       `json:"direction,omitempty"`
+	Status    domain.PendingTransferStatus `json:"status,omitempty"`
+}
+
+type PendingTransferRequest struct {
+	UserID            string `json:"user_id"`
+	PendingTransferID string `json:"pending_transfer_id"`
+}
+
+type AcceptPendingTransferRequest struct {
+	UserID            string `json:"user_id"`
+	PendingTransferID string `json:"pending_transfer_id"`
+}
+
+type DeclinePendingTransferRequest struct {
+	UserID            string `json:"user_id"`
+	PendingTransferID string `json:"pending_transfer_id"`
+}
+
+type CancelPendingTransferRequest struct {
+	UserID            string `json:"user_id"`
+	PendingTransferID string `json:"pending_transfer_id"`
+}
+
+type ExpirePendingTransfersRequest struct{}
+
+// GrantMandateRequest lets the holders of CreditorAccountID, an account id
+// or IBAN, collect from the user's AccountID.
+type GrantMandateRequest struct {
+	UserID            string                  `json:"user_id"`
+	AccountID         string                  `json:"account_id" openapi:"required"`
+	CreditorAccountID string                  `json:"creditor_account_id" openapi:"required"`
+	MaxAmount         money.Money             `json:"max_amount" openapi:"required"`
+	Frequency         domain.MandateFrequency `json:"frequency" openapi:"required"`
+	ApproverUserID    string                  `json:"approver_user_id,omitempty"`
+}
+
+// MandatesRequest lists the mandates the user granted (debtor) or can
+// collect on (creditor), or both when Role is empty.
+type MandatesRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+}
+
+type MandateRequest struct {
+	UserID    string `json:"user_id"`
+	MandateID string `json:"mandate_id"`
+}
+
+type RevokeMandateRequest struct {
+	UserID    string `json:"user_id"`
+	MandateID string `json:"mandate_id"`
+}
+
+// CollectPaymentRequest debits the debtor's account into the mandate's
+// creditor account.
+type CollectPaymentRequest struct {
+	UserID      string      `json:"user_id"`
+	MandateID   string      `json:"mandate_id"`
+	Amount      money.Money `json:"amount" openapi:"required"`
+	Description string      `json:"description,omitempty"`
+	Reference   string      `json:"reference,omitempty"`
+}
+
+type CollectionsRequest struct {
+	UserID    string                  `json:"user_id"`
+	MandateID string                  `json:"mandate_id"`
+	Status    domain.CollectionStatus `json:"status,omitempty"`
+}
+
+type CollectionRequest struct {
+	UserID       string `json:"user_id"`
+	MandateID    string `json:"mandate_id"`
+	CollectionID string `json:"collection_id"`
+}
+
+type RefundCollectionRequest struct {
+	UserID       string `json:"user_id"`
+	MandateID    string `json:"mandate_id"`
+	CollectionID string `json:"collection_id"`
+}
+
+// CardLimits caps what a card can authorise. A zero or missing amount is no
+// limit.
+type CardLimits struct {
+	PerTransaction money.Money `json:"per_transaction"`
+	Daily          money.Money `json:"daily"`
+}
+
+type IssueCardRequest struct {
+	UserID    string     `json:"user_id"`
+	AccountID string     `json:"account_id"`
+	Limits    CardLimits `json:"limits"`
+}
+
+// CardsRequest lists the cards issued to the user, on AccountID only when
+// it is set.
+type CardsRequest struct {
+	UserID    string `json:"user_id"`
+	AccountID string `json:"account_id,omitempty"`
+}
+
+type CardRequest struct {
+	UserID string `json:"user_id"`
+	CardID string `json:"card_id"`
+}
+
+type FreezeCardRequest struct {
+	UserID string `json:"user_id"`
+	CardID string `json:"card_id"`
+}
+
+type UnfreezeCardRequest struct {
+	UserID string `json:"user_id"`
+	CardID string `json:"card_id"`
+}
+
+type CancelCardRequest struct {
+	UserID string `json:"user_id"`
+	CardID string `json:"card_id"`
+}
+
+type UpdateCardLimitsRequest struct {
+	UserID string     `json:"user_id"`
+	CardID string     `json:"card_id"`
+	Limits CardLimits `json:"limits" openapi:"required"`
+}
+
+// AuthoriseCardRequest's Reference is the merchant's own, such as an ISO 8583
+// retrieval reference number, for MerchantAuthorisation.
+type AuthoriseCardRequest struct {
+	PAN         string      `json:"pan" openapi:"required"`
+	ExpiryMonth int         `json:"expiry_month" openapi:"required"`
+	ExpiryYear  int         `json:"expiry_year" openapi:"required"`
+	CVV         string      `json:"cvv" openapi:"required"`
+	Amount      money.Money `json:"amount" openapi:"required"`
+	Reference   string      `json:"reference,omitempty"`
+	Merchant    string      `json:"-"`
+	MerchantKey string      `json:"-"`
+}
+
+// MerchantAuthorisationRequest finds the latest authorisation on a card
+// made with the merchant's reference.
+type MerchantAuthorisationRequest struct {
+	PAN       string `json:"pan"`
+	Reference string `json:"reference"`
+}
+
+type CardAuthorisationsRequest struct {
+	UserID string                     `json:"user_id"`
+	CardID string                     `json:"card_id"`
+	Status domain.AuthorisationStatus `json:"status,omitempty"`
+}
+
+type CardAuthorisationRequest struct {
+	UserID          string `json:"user_id"`
+	CardID          string `json:"card_id"`
+	AuthorisationID string `json:"authorisation_id"`
+}
+
+// CaptureAuthorisationRequest captures the whole amount held when Amount is
+// left out.
+type CaptureAuthorisationRequest struct {
+	CardID          string      `json:"card_id"`
+	AuthorisationID string      `json:"authorisation_id"`
+	Amount          money.Money `json:"amount"`
+	Merchant        string      `json:"-"`
+	MerchantKey     string      `json:"-"`
+}
+
+type ReverseAuthorisationRequest struct {
+	CardID          string `json:"card_id"`
+	AuthorisationID string `json:"authorisation_id"`
+	Merchant        string `json:"-"`
+	MerchantKey     string `json:"-"`
+}
+
+type ExpireCardAuthorisationsRequest struct{}
+
+// LoanTerms are what a loan is granted on. InterestRateBps is the nominal
+// yearly rate and LateFee, if any, is charged once on every overdue
+// instalment.
+type LoanTerms struct {
+	Principal       money.Money         `json:"principal" openapi:"required"`
+	InterestRateBps int                 `json:"interest_rate_bps"`
+	TermMonths      int                 `json:"term_months" openapi:"required"`
+	Schedule        domain.LoanSchedule `json:"schedule" openapi:"required"`
+	LateFee         money.Money         `json:"late_fee"`
+}
+
+// GrantLoanRequest lends to the user, paying the principal into AccountID,
+// an account id or IBAN they hold. Instalments are collected from the same
+// account.
+type GrantLoanRequest struct {
+	UserID    string `json:"user_id" openapi:"required"`
+	AccountID string `json:"account_id" openapi:"required"`
+	LoanTerms
+}
+
+type LoansRequest struct {
+	UserID string            `json:"user_id"`
+	Status domain.LoanStatus `json:"status,omitempty"`
+}
+
+type LoanRequest struct {
+	UserID string `json:"user_id"`
+	LoanID string `json:"loan_id"`
+}
+
+type LoanPayoffQuoteRequest struct {
+	UserID string `json:"user_id"`
+	LoanID string `json:"loan_id"`
+}
+
+// PayOffLoanRequest repays the whole loan at once. AccountID, an account id
+// or IBAN, defaults to the loan's account.
+type PayOffLoanRequest struct {
+	UserID    string `json:"user_id"`
+	LoanID    string `json:"loan_id"`
+	AccountID string `json:"account_id,omitempty"`
+}
+
+type LoanStatementRequest struct {
+	UserID string `json:"user_id"`
+	LoanID string `json:"loan_id"`
+}
+
+type CollectLoanRepaymentsRequest struct{}
+
+// AutoSaveRule fills a pot by itself. Round-up rules take nothing else,
+// deposit_percentage rules take PercentageBps of each deposit and weekly
+// rules move Amount once a week, starting with the next run.
+type AutoSaveRule struct {
+	Type          domain.AutoSaveRuleType `json:"type" openapi:"required"`
+	PercentageBps int                     `json:"percentage_bps,omitempty"`
+	Amount        money.Money             `json:"amount"`
+}
+
+// CreatePotRequest opens a pot in AccountID, an account id or IBAN the user
+// holds. Goal and GoalDate are optional.
+type CreatePotRequest struct {
+	UserID    string         `json:"user_id"`
+	AccountID string         `json:"account_id"`
+	Name      string         `json:"name" openapi:"required"`
+	Goal      money.Money    `json:"goal"`
+	GoalDate  time.Time      `json:"goal_date,omitempty"`
+	AutoSave  []AutoSaveRule `json:"auto_save,omitempty"`
+}
+
+// PotsRequest lists the open pots of an account.
+type PotsRequest struct {
+	UserID    string `json:"user_id"`
+	AccountID string `json:"account_id"`
+}
+
+type PotRequest struct {
+	UserID string `json:"user_id"`
+	PotID  string `json:"pot_id"`
+}
+
+// UpdatePotRequest leaves empty fields unchanged. ClearGoal removes the goal
+// and its date.
+type UpdatePotRequest struct {
+	UserID    string      `json:"user_id"`
+	PotID     string      `json:"pot_id"`
+	Name      string      `json:"name,omitempty"`
+	Goal      money.Money `json:"goal"`
+	GoalDate  time.Time   `json:"goal_date,omitempty"`
+	ClearGoal bool        `json:"clear_goal,omitempty"`
+}
+
+// ClosePotRequest moves what is left in the pot back to the account balance
+// and closes it.
+type ClosePotRequest struct {
+	UserID string `json:"user_id"`
+	PotID  string `json:"pot_id"`
+}
+
+type MovePotMoneyRequest struct {
+	UserID string      `json:"user_id"`
+	PotID  string      `json:"pot_id"`
+	Amount money.Money `json:"amount" openapi:"required"`
+}
+
+// PutAutoSaveRulesRequest replaces the pot's rules; an empty list turns
+// auto-save off.
+type PutAutoSaveRulesRequest struct {
+	UserID   string         `json:"user_id"`
+	PotID    string         `json:"pot_id"`
+	AutoSave []AutoSaveRule `json:"auto_save" openapi:"required"`
+}
+
+type RunWeeklyAutoSavesRequest struct{}
+
+// CategoryRule needs at least one condition. Counterparty is an account id or
+// IBAN and DescriptionPattern a case-insensitive regular expression.
+type CategoryRule struct {
+	Category           string      `json:"category" openapi:"required"`
+	Counterparty       string      `json:"counterparty,omitempty"`
+	DescriptionPattern string      `json:"description_pattern,omitempty"`
+	MinAmount          money.Money `json:"min_amount"`
+	MaxAmount          money.Money `json:"max_amount"`
+}
+
+type CategoryRulesRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// PutCategoryRulesRequest replaces the user's rules. They are tried in
+// order and the first match wins; an empty list leaves only the built-in
+// defaults.
+type PutCategoryRulesRequest struct {
+	UserID string         `json:"user_id"`
+	Rules  []CategoryRule `json:"rules" openapi:"required"`
+}
+
+// CategoriseTransactionRequest sets the category of one transaction by
+// hand. An empty Category clears it, so the rules apply again.
+type CategoriseTransactionRequest struct {
+	UserID        string `json:"user_id"`
+	TransactionID string `json:"transaction_id"`
+	Category      string `json:"category" openapi:"required"`
+}
+
+// SpendingAnalyticsRequest covers the months From to To, as YYYY-MM, by
+// default the last six months. Top is how many counterparties to return,
+// five by default.
+type SpendingAnalyticsRequest struct {
 	UserID    string `json:"user_id"`
 	AccountID string `json:"account_id"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Top       int    `json:"top,omitempty"`
 }
@@ -1,25 +1,471 @@
 package service
 
-import "github.com/hetfdex/tiny-bank/internal/domain"
+import (
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+)
 
 type CreateUserResponse struct {
 	UserID string `json:"user_id"`
 }
 
 type CreateAccountResponse struct {
-	AccountID string `json:"account_id"`
+	AccountID      string `json:"account_id"`
+	IBAN           string `json:"iban,omitempty"`
+	ProductID      string `json:"product_id,omitempty"`
+	ProductVersion int    `json:"product_version,omitempty"`
+}
+
+type DeactivateUserResponse struct {
+	UserID        string                   `json:"user_id"`
+	Reason        string                   `json:"reason"`
+	DeactivatedAt time.Time                `json:"deactivated_at"`
+	ClosesAt      time.Time                `json:"closes_at"`
+	Accounts      []ClosingAccountResponse `json:"accounts"`
+}
+
+type ClosingAccountResponse struct {
+	AccountID       string                `json:"account_id"`
+	ClosingBalance  money.Money           `json:"closing_balance"`
+	SweptAmount     money.Money           `json:"swept_amount"`
+	PayoutAccountID string                `json:"payout_account_id,omitempty"`
+	RemainsOpen     bool                  `json:"remains_open"`
+	Transactions    []TransactionResponse `json:"transactions"`
+}
+
+type CloseUsersResponse struct {
+	UserIDs []string `json:"user_ids"`
 }
 
 type BalanceResponse struct {
-	Balance int `json:"balance"`
+	Balance money.Money `json:"balance"`
 }
 
-type DepositResponse BalanceResponse
+type DepositResponse struct {
+	Balance       money.Money `json:"balance"`
+	TransactionID string      `json:"transaction_id,omitempty"`
+}
 
 type WithdrawResponse DepositResponse
 
-type TransferResponse DepositResponse
+// TransferResponse carries PendingTransferID instead of TransactionID when
+// the transfer is held for the receiver to accept.
+type TransferResponse struct {
+	Balance           money.Money `json:"balance"`
+	TransactionID     string      `json:"transaction_id,omitempty"`
+	PendingTransferID string      `json:"pending_transfer_id,omitempty"`
+}
 
 type TransactionsResponse struct {
-	Transactions []domain.Transaction `json:"transactions"`
+	Transactions []TransactionResponse `json:"transactions"`
+}
+
+type TransactionResponse struct {
+	ID                string      `json:"id,omitempty"`
+	AccountID         string      `json:"account_id"`
+	Timestamp         time.Time   `json:"timestamp"`
+	Operation         string      `json:"operation"`
+	Amount            money.Money `json:"amount"`
+	ReceiverUserID    string      `json:"receiver_user_id,omitempty"`
+	SenderUserID      string      `json:"sender_user_id,omitempty"`
+	ReceiverAccountID string      `json:"receiver_account_id,omitempty"`
+	SenderAccountID   string      `json:"sender_account_id,omitempty"`
+	Category          string      `json:"category,omitempty"`
+	TransactionDetails
+}
+
+type AccountsResponse struct {
+	Accounts []AccountResponse `json:"accounts"`
+}
+
+type AccountResponse struct {
+	AccountID      string      `json:"account_id"`
+	IBAN           string      `json:"iban,omitempty"`
+	ProductID      string      `json:"product_id"`
+	ProductVersion int         `json:"product_version"`
+	Role           domain.Role `json:"role"`
+	Balance        money.Money `json:"balance"`
+	Closed         bool        `json:"closed"`
+}
+
+type HoldersResponse struct {
+	Holders               []HolderResponse `json:"holders"`
+	DualApprovalThreshold money.Money      `json:"dual_approval_threshold"`
+}
+
+type HolderResponse struct {
+	UserID string      `json:"user_id"`
+	Role   domain.Role `json:"role"`
+}
+
+type ProductResponse struct {
+	ProductID string    `json:"product_id"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	ProductTerms
+}
+
+type ProductsResponse struct {
+	Products []ProductResponse `json:"products"`
+}
+
+type BatchTransferResponse struct {
+	Results []BatchTransferResult `json:"results"`
+}
+
+type BatchTransferResult struct {
+	Status  domain.BatchLineStatus `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Balance *money.Money           `json:"balance,omitempty"`
+}
+
+type TierResponse struct {
+	TierID    string       `json:"tier_id"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Limits    []LimitTerms `json:"limits"`
+}
+
+type TiersResponse struct {
+	Tiers []TierResponse `json:"tiers"`
+}
+
+type KYCResponse struct {
+	UserID          string            `json:"user_id"`
+	Status          domain.UserStatus `json:"status"`
+	StatusReason    string            `json:"status_reason,omitempty"`
+	StatusUpdatedAt time.Time         `json:"status_updated_at"`
+	DateOfBirth     string            `json:"date_of_birth,omitempty"`
+	Address         *AddressTerms     `json:"address,omitempty"`
+	IDNumber        string            `json:"id_number,omitempty"`
+	SubmittedAt     time.Time         `json:"submitted_at"`
+}
+
+type PayeeResponse struct {
+	PayeeID   string            `json:"payee_id"`
+	Nickname  string            `json:"nickname"`
+	Name      string            `json:"name"`
+	Match     domain.PayeeMatch `json:"match"`
+	AccountID string            `json:"account_id"`
+	IBAN      string            `json:"iban,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ActiveAt  time.Time         `json:"active_at"`
+}
+
+type PayeesResponse struct {
+	Payees []PayeeResponse `json:"payees"`
+}
+
+type PaymentRequestResponse struct {
+	PaymentRequestID string                      `json:"payment_request_id"`
+	RequesterUserID  string                      `json:"requester_user_id"`
+	AccountID        string                      `json:"account_id"`
+	Amount           money.Money                 `json:"amount"`
+	PaidAmount       money.Money                 `json:"paid_amount"`
+	Description      string                      `json:"description,omitempty"`
+	Status           domain.PaymentRequestStatus `json:"status"`
+	CreatedAt        time.Time                   `json:"created_at"`
+	ExpiresAt        time.Time                   `json:"expires_at"`
+	Payers           []PayerResponse             `json:"payers"`
+}
+
+type PayerResponse struct {
+	UserID        string             `json:"user_id"`
+	Amount        money.Money        `json:"amount"`
+	Status        domain.PayerStatus `json:"status"`
+	RespondedAt   time.Time          `json:"responded_at"`
+	TransactionID string             `json:"transaction_id,omitempty"`
+}
+
+type PaymentRequestsResponse struct {
+	PaymentRequests []PaymentRequestResponse `json:"payment_requests"`
+}
+
+type PendingTransferResponse struct {
+	PendingTransferID string                       `json:"pending_transfer_id"`
+	SenderUserID      string                       `json:"sender_user_id"`
+	SenderAccountID   string                       `json:"sender_account_id"`
+	ReceiverUserID    string                       `json:"receiver_user_id"`
+	ReceiverAccountID string                       `json:"receiver_account_id"`
+	Amount            money.Money                  `json:"amount"`
+	Fee               money.Money                  `json:"fee"`
+	Description       string                       `json:"description,omitempty"`
+	Reference         string                       `json:"reference,omitempty"`
+	Metadata          map[string]string            `json:"metadata,omitempty"`
+	Status            domain.PendingTransferStatus `json:"status"`
+	CreatedAt         time.Time                    `json:"created_at"`
+	ExpiresAt         time.Time                    `json:"expires_at"`
+	RespondedAt       time.Time                    `json:"responded_at"`
+	TransactionID     string                       `json:"transaction_id,omitempty"`
+}
+
+type PendingTransfersResponse struct {
+	PendingTransfers []PendingTransferResponse `json:"pending_transfers"`
+}
+
+type ExpirePendingTransfersResponse struct {
+	PendingTransferIDs []string `json:"pending_transfer_ids"`
+}
+
+type MandateResponse struct {
+	MandateID         string                  `json:"mandate_id"`
+	DebtorUserID      string                  `json:"debtor_user_id"`
+	DebtorAccountID   string                  `json:"debtor_account_id"`
+	CreditorAccountID string                  `json:"creditor_account_id"`
+	CreditorIBAN      string                  `json:"creditor_iban,omitempty"`
+	MaxAmount         money.Money             `json:"max_amount"`
+	Frequency         domain.MandateFrequency `json:"frequency"`
+	Status            domain.MandateStatus    `json:"status"`
+	CreatedAt         time.Time               `json:"created_at"`
+	RevokedAt         time.Time               `json:"revoked_at"`
+}
+
+type MandatesResponse struct {
+	Mandates []MandateResponse `json:"mandates"`
+}
+
+type CollectionResponse struct {
+	CollectionID    string                  `json:"collection_id"`
+	MandateID       string                  `json:"mandate_id"`
+	CreditorUserID  string                  `json:"creditor_user_id"`
+	Amount          money.Money             `json:"amount"`
+	Fee             money.Money             `json:"fee"`
+	Description     string                  `json:"description,omitempty"`
+	Reference       string                  `json:"reference,omitempty"`
+	Status          domain.CollectionStatus `json:"status"`
+	FailureReason   string                  `json:"failure_reason,omitempty"`
+	TransactionID   string                  `json:"transaction_id,omitempty"`
+	CreatedAt       time.Time               `json:"created_at"`
+	RefundableUntil time.Time               `json:"refundable_until"`
+	RefundedAt      time.Time               `json:"refunded_at"`
+}
+
+type CollectionsResponse struct {
+	Collections []CollectionResponse `json:"collections"`
+}
+
+type CardResponse struct {
+	CardID      string            `json:"card_id"`
+	UserID      string            `json:"user_id"`
+	AccountID   string            `json:"account_id"`
+	MaskedPAN   string            `json:"masked_pan"`
+	ExpiryMonth int               `json:"expiry_month"`
+	ExpiryYear  int               `json:"expiry_year"`
+	Status      domain.CardStatus `json:"status"`
+	Limits      CardLimits        `json:"limits"`
+	CreatedAt   time.Time         `json:"created_at"`
+	CancelledAt time.Time         `json:"cancelled_at"`
+}
+
+// IssueCardResponse is the only response carrying the full card number and
+// the CVV. The CVV cannot be shown again.
+type IssueCardResponse struct {
+	CardResponse
+	PAN string `json:"pan"`
+	CVV string `json:"cvv"`
+}
+
+type CardsResponse struct {
+	Cards []CardResponse `json:"cards"`
+}
+
+type CardAuthorisationResponse struct {
+	AuthorisationID   string                     `json:"authorisation_id"`
+	CardID            string                     `json:"card_id"`
+	Merchant          string                     `json:"merchant"`
+	Reference         string                     `json:"reference,omitempty"`
+	Amount            money.Money                `json:"amount"`
+	CapturedAmount    money.Money                `json:"captured_amount"`
+	Status            domain.AuthorisationStatus `json:"status"`
+	HoldTransactionID string                     `json:"hold_transaction_id"`
+	TransactionID     string                     `json:"transaction_id,omitempty"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	UpdatedAt         time.Time                  `json:"updated_at"`
+	ExpiresAt         time.Time                  `json:"expires_at"`
+}
+
+type CardAuthorisationsResponse struct {
+	Authorisations []CardAuthorisationResponse `json:"authorisations"`
+}
+
+type ExpireCardAuthorisationsResponse struct {
+	AuthorisationIDs []string `json:"authorisation_ids"`
+}
+
+// LoanInstalmentResponse is one instalment of the schedule. Amount is what
+// is collected for it: principal, interest and any late fee.
+type LoanInstalmentResponse struct {
+	Number    int                     `json:"number"`
+	DueAt     time.Time               `json:"due_at"`
+	Amount    money.Money             `json:"amount"`
+	Principal money.Money             `json:"principal"`
+	Interest  money.Money             `json:"interest"`
+	LateFee   money.Money             `json:"late_fee"`
+	Status    domain.InstalmentStatus `json:"status"`
+	PaidAt    time.Time               `json:"paid_at"`
+}
+
+// LoanResponse is a loan and its schedule. OutstandingPrincipal is the
+// principal of the instalments not yet paid and Arrears the amount of the
+// overdue ones.
+type LoanResponse struct {
+	LoanID                    string                   `json:"loan_id"`
+	UserID                    string                   `json:"user_id"`
+	AccountID                 string                   `json:"account_id"`
+	Principal                 money.Money              `json:"principal"`
+	InterestRateBps           int                      `json:"interest_rate_bps"`
+	TermMonths                int                      `json:"term_months"`
+	Schedule                  domain.LoanSchedule      `json:"schedule"`
+	LateFee                   money.Money              `json:"late_fee"`
+	Status                    domain.LoanStatus        `json:"status"`
+	OutstandingPrincipal      money.Money              `json:"outstanding_principal"`
+	Arrears                   money.Money              `json:"arrears"`
+	DisbursementTransactionID string                   `json:"disbursement_transaction_id"`
+	CreatedAt                 time.Time                `json:"created_at"`
+	RepaidAt                  time.Time                `json:"repaid_at"`
+	Instalments               []LoanInstalmentResponse `json:"instalments"`
+}
+
+type LoansResponse struct {
+	Loans []LoanResponse `json:"loans"`
+}
+
+// LoanPayoffQuoteResponse is what paying off the loan costs until
+// ValidUntil: the overdue and due instalments, the principal still to come
+// and the interest accrued on it since the last due date.
+type LoanPayoffQuoteResponse struct {
+	LoanID     string      `json:"loan_id"`
+	Amount     money.Money `json:"amount"`
+	Principal  money.Money `json:"principal"`
+	Interest   money.Money `json:"interest"`
+	Fees       money.Money `json:"fees"`
+	QuotedAt   time.Time   `json:"quoted_at"`
+	ValidUntil time.Time   `json:"valid_until"`
+}
+
+type LoanRepaymentResponse struct {
+	TransactionID string      `json:"transaction_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Instalment    int         `json:"instalment,omitempty"`
+	Payoff        bool        `json:"payoff"`
+	Amount        money.Money `json:"amount"`
+	Principal     money.Money `json:"principal"`
+	Interest      money.Money `json:"interest"`
+	Fees          money.Money `json:"fees"`
+}
+
+// LoanStatementResponse is the loan with every repayment made and their
+// totals.
+type LoanStatementResponse struct {
+	Loan          LoanResponse            `json:"loan"`
+	Repayments    []LoanRepaymentResponse `json:"repayments"`
+	TotalRepaid   money.Money             `json:"total_repaid"`
+	PrincipalPaid money.Money             `json:"principal_paid"`
+	InterestPaid  money.Money             `json:"interest_paid"`
+	FeesPaid      money.Money             `json:"fees_paid"`
+}
+
+// CollectLoanRepaymentsResponse lists the loans instalments were collected
+// for and the loans with an instalment that fell overdue in this run.
+type CollectLoanRepaymentsResponse struct {
+	CollectedLoanIDs []string `json:"collected_loan_ids"`
+	OverdueLoanIDs   []string `json:"overdue_loan_ids"`
+}
+
+type AutoSaveRuleResponse struct {
+	Type          domain.AutoSaveRuleType `json:"type"`
+	PercentageBps int                     `json:"percentage_bps,omitempty"`
+	Amount        money.Money             `json:"amount"`
+	NextRunAt     time.Time               `json:"next_run_at"`
+}
+
+// PotResponse is a pot and how far it is towards its goal. GoalProgressBps
+// is the balance as a share of the goal, capped at 10000.
+type PotResponse struct {
+	PotID           string                 `json:"pot_id"`
+	AccountID       string                 `json:"account_id"`
+	Name            string                 `json:"name"`
+	Balance         money.Money            `json:"balance"`
+	Goal            money.Money            `json:"goal"`
+	GoalDate        time.Time              `json:"goal_date"`
+	GoalProgressBps int                    `json:"goal_progress_bps"`
+	CreatedAt       time.Time              `json:"created_at"`
+	ClosedAt        time.Time              `json:"closed_at"`
+	AutoSave        []AutoSaveRuleResponse `json:"auto_save"`
+}
+
+type PotsResponse struct {
+	Pots []PotResponse `json:"pots"`
+}
+
+// PotMoveResponse is the pot and the account balance after a move.
+type PotMoveResponse struct {
+	Pot            PotResponse `json:"pot"`
+	AccountBalance money.Money `json:"account_balance"`
+	TransactionID  string      `json:"transaction_id"`
+}
+
+// RunWeeklyAutoSavesResponse lists the pots a weekly amount was saved into
+// and those skipped because the account could not cover it.
+type RunWeeklyAutoSavesResponse struct {
+	SavedPotIDs   []string `json:"saved_pot_ids"`
+	SkippedPotIDs []string `json:"skipped_pot_ids"`
+}
+
+type CategoryRuleResponse struct {
+	Category              string      `json:"category"`
+	CounterpartyAccountID string      `json:"counterparty_account_id,omitempty"`
+	DescriptionPattern    string      `json:"description_pattern,omitempty"`
+	MinAmount             money.Money `json:"min_amount"`
+	MaxAmount             money.Money `json:"max_amount"`
+}
+
+// CategoryRulesResponse is the user's rules, in the order they are tried,
+// and the built-in categories transactions fall back to.
+type CategoryRulesResponse struct {
+	Rules             []CategoryRuleResponse `json:"rules"`
+	DefaultCategories []string               `json:"default_categories"`
+}
+
+// CategoryTotalResponse is what went out of and came into the account in
+// one category and month. SpentDelta is the change in Spent from the month
+// before.
+type CategoryTotalResponse struct {
+	Category     string      `json:"category"`
+	Spent        money.Money `json:"spent"`
+	Received     money.Money `json:"received"`
+	SpentDelta   money.Money `json:"spent_delta"`
+	Transactions int         `json:"transactions"`
+}
+
+// MonthAnalyticsResponse is one month, as YYYY-MM, with its categories by
+// most spent.
+type MonthAnalyticsResponse struct {
+	Month      string                  `json:"month"`
+	Spent      money.Money             `json:"spent"`
+	Received   money.Money             `json:"received"`
+	SpentDelta money.Money             `json:"spent_delta"`
+	Categories []CategoryTotalResponse `json:"categories"`
+}
+
+// CounterpartyTotalResponse is the other account of transfers, or the
+// merchant or description of anything else.
+type CounterpartyTotalResponse struct {
+	AccountID    string      `json:"account_id,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	Spent        money.Money `json:"spent"`
+	Received     money.Money `json:"received"`
+	Transactions int         `json:"transactions"`
+}
+
+// SpendingAnalyticsResponse breaks an account's transactions down by month
+// and category. Holds are left out, as the payment or transfer they are
+// for is counted when it is booked.
+type SpendingAnalyticsResponse struct {
+	AccountID         string                      `json:"account_id"`
+	From              string                      `json:"from"`
+	To                string                      `json:"to"`
+	Months            []MonthAnalyticsResponse    `json:"months"`
+	TopCounterparties []CounterpartyTotalResponse `json:"top_counterparties"`
 }
@@ -2,38 +2,213 @@ package service
 
 import (
 	"errors"
+	"math/rand/v2"
+	"sort"
 	"time"
 
 	guuid "github.com/google/uuid"
+	"github.com/hetfdex/tiny-bank/internal/card"
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/iban"
+	"github.com/hetfdex/tiny-bank/internal/money"
 	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/cardrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/categoryrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/loanrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/mandaterepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/payeerepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/paymentrequestrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/pendingtransferrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/potrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
 	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
 )
 
+// Errors that callers can match with errors.Is, also inside the joined
+// errors a failed compensation returns. The changed errors are version
+// conflicts from an update that lost a race more times than it retries.
+var (
+	ErrInsufficientFunds      = accountrepo.ErrInsufficientFunds
+	ErrAccountFrozen          = accountrepo.ErrAccountFrozen
+	ErrAccountClosed          = accountrepo.ErrAccountClosed
+	ErrBalanceNotZero         = accountrepo.ErrBalanceNotZero
+	ErrPaymentRequestChanged  = paymentrequestrepo.ErrVersionConflict
+	ErrPendingTransferChanged = pendingtransferrepo.ErrVersionConflict
+	ErrMandateChanged         = mandaterepo.ErrVersionConflict
+	ErrCardChanged            = cardrepo.ErrVersionConflict
+	ErrLoanChanged            = loanrepo.ErrVersionConflict
+	ErrPotChanged             = potrepo.ErrVersionConflict
+	ErrCategorisationChanged  = categoryrepo.ErrVersionConflict
+	ErrAutoSaveChanged        = errors.New("auto-save rule changed")
+)
+
 type Service interface {
 	CreateUser(CreateUserRequest) (CreateUserResponse, error)
 	CreateAccount(CreateAccountRequest) (CreateAccountResponse, error)
-	DeactivateUser(DeactivateUserRequest) error
+	DeactivateUser(DeactivateUserRequest) (DeactivateUserResponse, error)
+	ReactivateUser(ReactivateUserRequest) error
+	CloseUsers(CloseUsersRequest) (CloseUsersResponse, error)
 	Deposit(DepositRequest) (DepositResponse, error)
 	Withdraw(WithdrawRequest) (WithdrawResponse, error)
 	Transfer(TransferRequest) (TransferResponse, error)
 	Balance(BalanceRequest) (BalanceResponse, error)
 	Transactions(TransactionsRequest) (TransactionsResponse, error)
+	Transaction(TransactionRequest) (TransactionResponse, error)
+	Accounts(AccountsRequest) (AccountsResponse, error)
+	AddHolder(AddHolderRequest) error
+	RemoveHolder(RemoveHolderRequest) error
+	Holders(HoldersRequest) (HoldersResponse, error)
+	UpdateAccountRules(UpdateAccountRulesRequest) error
+	CreateProduct(CreateProductRequest) (ProductResponse, error)
+	UpdateProduct(UpdateProductRequest) (ProductResponse, error)
+	Product(ProductRequest) (ProductResponse, error)
+	Products(ProductsRequest) (ProductsResponse, error)
+	BatchTransfer(BatchTransferRequest) (BatchTransferResponse, error)
+	PutTier(PutTierRequest) (TierResponse, error)
+	Tier(TierRequest) (TierResponse, error)
+	Tiers(TiersRequest) (TiersResponse, error)
+	UpdateUserTier(UpdateUserTierRequest) error
+	SubmitKYC(SubmitKYCRequest) (KYCResponse, error)
+	KYC(KYCRequest) (KYCResponse, error)
+	UpdateUserStatus(UpdateUserStatusRequest) error
+	AddPayee(AddPayeeRequest) (PayeeResponse, error)
+	Payees(PayeesRequest) (PayeesResponse, error)
+	Payee(PayeeRequest) (PayeeResponse, error)
+	UpdatePayee(UpdatePayeeRequest) (PayeeResponse, error)
+	DeletePayee(DeletePayeeRequest) error
+	RequestPayment(RequestPaymentRequest) (PaymentRequestResponse, error)
+	PaymentRequests(PaymentRequestsRequest) (PaymentRequestsResponse, error)
+	PaymentRequest(PaymentRequestRequest) (PaymentRequestResponse, error)
+	AcceptPaymentRequest(AcceptPaymentRequestRequest) (PaymentRequestResponse, error)
+	DeclinePaymentRequest(DeclinePaymentRequestRequest) (PaymentRequestResponse, error)
+	CancelPaymentRequest(CancelPaymentRequestRequest) (PaymentRequestResponse, error)
+	CreatePendingTransfer(CreatePendingTransferRequest) (PendingTransferResponse, error)
+	PendingTransfers(PendingTransfersRequest) (PendingTransfersResponse, error)
+	PendingTransfer(PendingTransferRequest) (PendingTransferResponse, error)
+	AcceptPendingTransfer(AcceptPendingTransferRequest) (PendingTransferResponse, error)
+	DeclinePendingTransfer(DeclinePendingTransferRequest) (PendingTransferResponse, error)
+	CancelPendingTransfer(CancelPendingTransferRequest) (PendingTransferResponse, error)
+	ExpirePendingTransfers(ExpirePendingTransfersRequest) (ExpirePendingTransfersResponse, error)
+	GrantMandate(GrantMandateRequest) (MandateResponse, error)
+	Mandates(MandatesRequest) (MandatesResponse, error)
+	Mandate(MandateRequest) (MandateResponse, error)
+	RevokeMandate(RevokeMandateRequest) (MandateResponse, error)
+	CollectPayment(CollectPaymentRequest) (CollectionResponse, error)
+	Collections(CollectionsRequest) (CollectionsResponse, error)
+	Collection(CollectionRequest) (CollectionResponse, error)
+	RefundCollection(RefundCollectionRequest) (CollectionResponse, error)
+	IssueCard(IssueCardRequest) (IssueCardResponse, error)
+	Cards(CardsRequest) (CardsResponse, error)
+	Card(CardRequest) (CardResponse, error)
+	FreezeCard(FreezeCardRequest) (CardResponse, error)
+	UnfreezeCard(UnfreezeCardRequest) (CardResponse, error)
+	CancelCard(CancelCardRequest) (CardResponse, error)
+	UpdateCardLimits(UpdateCardLimitsRequest) (CardResponse, error)
+	AuthoriseCard(AuthoriseCardRequest) (CardAuthorisationResponse, error)
+	CardAuthorisations(CardAuthorisationsRequest) (CardAuthorisationsResponse, error)
+	CardAuthorisation(CardAuthorisationRequest) (CardAuthorisationResponse, error)
+	MerchantAuthorisation(MerchantAuthorisationRequest) (CardAuthorisationResponse, error)
+	CaptureAuthorisation(CaptureAuthorisationRequest) (CardAuthorisationResponse, error)
+	ReverseAuthorisation(ReverseAuthorisationRequest) (CardAuthorisationResponse, error)
+	ExpireCardAuthorisations(ExpireCardAuthorisationsRequest) (ExpireCardAuthorisationsResponse, error)
+	GrantLoan(GrantLoanRequest) (LoanResponse, error)
+	Loans(LoansRequest) (LoansResponse, error)
+	Loan(LoanRequest) (LoanResponse, error)
+	LoanPayoffQuote(LoanPayoffQuoteRequest) (LoanPayoffQuoteResponse, error)
+	PayOffLoan(PayOffLoanRequest) (LoanResponse, error)
+	LoanStatement(LoanStatementRequest) (LoanStatementResponse, error)
+	CollectLoanRepayments(CollectLoanRepaymentsRequest) (CollectLoanRepaymentsResponse, error)
+	CreatePot(CreatePotRequest) (PotResponse, error)
+	Pots(PotsRequest) (PotsResponse, error)
+	Pot(PotRequest) (PotResponse, error)
+	UpdatePot(UpdatePotRequest) (PotResponse, error)
+	ClosePot(ClosePotRequest) (PotResponse, error)
+	MoveToPot(MovePotMoneyRequest) (PotMoveResponse, error)
+	MoveFromPot(MovePotMoneyRequest) (PotMoveResponse, error)
+	PutAutoSaveRules(PutAutoSaveRulesRequest) (PotResponse, error)
+	RunWeeklyAutoSaves(RunWeeklyAutoSavesRequest) (RunWeeklyAutoSavesResponse, error)
+	CategoryRules(CategoryRulesRequest) (CategoryRulesResponse, error)
+	PutCategoryRules(PutCategoryRulesRequest) (CategoryRulesResponse, error)
+	CategoriseTransaction(CategoriseTransactionRequest) (TransactionResponse, error)
+	SpendingAnalytics(SpendingAnalyticsRequest) (SpendingAnalyticsResponse, error)
+}
+
+type transferPlan struct {
+	senderAccount   domain.Account
+	receiverAccount domain.Account
+	debited         money.Money
+	minimum         money.Money
+	fee             money.Money
+	details         TransactionDetails
+	now             time.Time
 }
 
 type svc struct {
-	userRepo    userrepo.Repo
-	accountRepo accountrepo.Repo
+	userRepo            userrepo.Repo
+	accountRepo         accountrepo.Repo
+	productRepo         productrepo.Repo
+	tierRepo            tierrepo.Repo
+	payeeRepo           payeerepo.Repo
+	paymentRequestRepo  paymentrequestrepo.Repo
+	pendingTransferRepo pendingtransferrepo.Repo
+	mandateRepo         mandaterepo.Repo
+	cardRepo            cardrepo.Repo
+	loanRepo            loanrepo.Repo
+	potRepo             potrepo.Repo
+	categoryRepo        categoryrepo.Repo
+	gracePeriod         time.Duration
+	payeeCoolingOff     time.Duration
+	paymentRequestTTL   time.Duration
+	pendingTransferTTL  time.Duration
+	pendingPolicy       PendingTransferPolicy
+	refundPeriod        time.Duration
+	defaultProductID    string
+	defaultTierID       string
+	ibanIssuer          iban.Issuer
+	cardIssuer          card.Issuer
+	cardSecret          []byte
+	cardHoldTTL         time.Duration
+	merchantKeys        map[string]string
 }
 
 func New(
 	userRepo userrepo.Repo,
 	accountRepo accountrepo.Repo,
+	productRepo productrepo.Repo,
+	tierRepo tierrepo.Repo,
+	opts ...Option,
 ) Service {
-	return &svc{
-		userRepo:    userRepo,
-		accountRepo: accountRepo,
+	s := &svc{
+		userRepo:            userRepo,
+		accountRepo:         accountRepo,
+		productRepo:         productRepo,
+		tierRepo:            tierRepo,
+		payeeRepo:           payeerepo.New(map[string]domain.Payee{}),
+		gracePeriod:         defaultGracePeriod,
+		payeeCoolingOff:     defaultCoolingOff,
+		paymentRequestRepo:  paymentrequestrepo.New(map[string]domain.PaymentRequest{}),
+		paymentRequestTTL:   defaultRequestTTL,
+		pendingTransferRepo: pendingtransferrepo.New(map[string]domain.PendingTransfer{}),
+		pendingTransferTTL:  defaultPendingTTL,
+		mandateRepo:         mandaterepo.New(map[string]domain.Mandate{}),
+		refundPeriod:        defaultRefundPeriod,
+		cardRepo:            cardrepo.New(map[string]domain.Card{}),
+		loanRepo:            loanrepo.New(map[string]domain.Loan{}),
+		potRepo:             potrepo.New(map[string]domain.Pot{}),
+		categoryRepo:        categoryrepo.New(map[string]domain.Categorisation{}),
+		defaultProductID:    defaultProductID,
+		ibanIssuer:          defaultIssuer,
+		cardIssuer:          defaultCardIssuer,
+		cardSecret:          randomSecret(),
+		cardHoldTTL:         defaultHoldTTL,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s svc) CreateUser(req CreateUserRequest) (CreateUserResponse, error) {
@@ -44,6 +219,7 @@ func (s svc) CreateUser(req CreateUserRequest) (CreateUserResponse, error) {
 	user, err := s.userRepo.Create(
 		userrepo.CreateRequest{
 			Name: req.Name,
+			Tier: s.defaultTierID,
 		},
 	)
 
@@ -61,7 +237,41 @@ func (s svc) CreateAccount(req CreateAccountRequest) (CreateAccountResponse, err
 		return CreateAccountResponse{}, errors.New("invalid user id")
 	}
 
-	account, err := s.accountRepo.Create(accountrepo.CreateRequest{})
+	_, err := s.readUser(req.UserID, actionOpen)
+
+	if err != nil {
+		return CreateAccountResponse{}, err
+	}
+
+	productID := req.ProductID
+
+	if productID == "" {
+		productID = s.defaultProductID
+	}
+
+	product, err := s.productRepo.Read(
+		productrepo.ReadRequest{
+			ID: productID,
+		},
+	)
+
+	if err != nil {
+		return CreateAccountResponse{}, err
+	}
+
+	accountIBAN, err := s.ibanIssuer.IBAN(rand.Uint64N(iban.MaxAccountNumber + 1))
+
+	if err != nil {
+		return CreateAccountResponse{}, err
+	}
+
+	account, err := s.accountRepo.Create(
+		accountrepo.CreateRequest{
+			OwnerID: req.UserID,
+			Product: product,
+			IBAN:    accountIBAN,
+		},
+	)
 
 	if err != nil {
 		return CreateAccountResponse{}, err
@@ -75,317 +285,442 @@ func (s svc) CreateAccount(req CreateAccountRequest) (CreateAccountResponse, err
 	)
 
 	if err != nil {
-		return CreateAccountResponse{}, err
+		return CreateAccountResponse{}, s.compensateCreateAccount(account.ID, err)
 	}
 
 	return CreateAccountResponse{
-		AccountID: account.ID,
+		AccountID:      account.ID,
+		IBAN:           account.IBAN,
+		ProductID:      product.ID,
+		ProductVersion: product.Version,
 	}, nil
 }
 
-func (s svc) DeactivateUser(req DeactivateUserRequest) error {
+func (s svc) DeactivateUser(req DeactivateUserRequest) (DeactivateUserResponse, error) {
 	if !validID(req.UserID) {
-		return errors.New("invalid user id")
+		return DeactivateUserResponse{}, errors.New("invalid user id")
 	}
 
-	return s.userRepo.UpdateStatus(
-		userrepo.UpdateStatusRequest{
-			ID:     req.UserID,
-			Active: false,
-		},
-	)
-}
+	payout := req.PayoutUserID != "" || req.PayoutAccountID != ""
 
-func (s svc) Deposit(req DepositRequest) (DepositResponse, error) {
-	if !validID(req.UserID) {
-		return DepositResponse{}, errors.New("invalid user id")
-	}
+	if payout {
+		if !validID(req.PayoutUserID) {
+			return DeactivateUserResponse{}, errors.New("invalid payout user id")
+		}
 
-	if !validID(req.AccountID) {
-		return DepositResponse{}, errors.New("invalid account id")
+		payoutAccountID, err := s.resolveAccountID(req.PayoutAccountID)
+
+		if err != nil {
+			return DeactivateUserResponse{}, err
+		}
+
+		req.PayoutAccountID = payoutAccountID
+
+		if !validID(req.PayoutAccountID) {
+			return DeactivateUserResponse{}, errors.New("invalid payout account id")
+		}
+
+		if req.PayoutUserID == req.UserID {
+			return DeactivateUserResponse{}, errors.New("payout account is being closed")
+		}
 	}
 
-	if req.Amount <= 0 {
-		return DepositResponse{}, errors.New("invalid amount")
+	user, err := s.readUser(req.UserID, actionClose)
+
+	if err != nil {
+		return DeactivateUserResponse{}, err
 	}
 
-	user, err := s.userRepo.Read(
-		userrepo.ReadRequest{
-			ID: req.UserID,
+	loans, err := s.loanRepo.List(
+		loanrepo.ListRequest{
+			UserID: req.UserID,
+			Status: domain.LoanActive,
 		},
 	)
 
 	if err != nil {
-		return DepositResponse{}, err
+		return DeactivateUserResponse{}, err
 	}
 
-	if !userAccount(user.AccountIDs, req.AccountID) {
-		return DepositResponse{}, errors.New("unauthorized account id")
+	if len(loans) > 0 {
+		return DeactivateUserResponse{}, errors.New("user has active loans")
 	}
 
-	account, err := s.accountRepo.Read(
-		accountrepo.ReadRequest{
-			ID: req.AccountID,
-		},
-	)
+	accountIDs := sortedAccountIDs(user.AccountIDs)
 
-	if err != nil {
-		return DepositResponse{}, err
+	statements := make([]ClosingAccountResponse, 0, len(accountIDs))
+
+	joint := []domain.Account{}
+
+	for _, accountID := range accountIDs {
+		account, err := s.accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: accountID,
+			},
+		)
+
+		if err != nil {
+			return DeactivateUserResponse{}, err
+		}
+
+		if !account.ClosedAt.IsZero() {
+			continue
+		}
+
+		if len(signers(account, req.UserID)) > 0 {
+			joint = append(joint, account)
+
+			continue
+		}
+
+		_, potsBalance, err := s.accountPots(account)
+
+		if err != nil {
+			return DeactivateUserResponse{}, err
+		}
+
+		closingBalance, err := account.Balance.Add(potsBalance)
+
+		if err != nil {
+			return DeactivateUserResponse{}, err
+		}
+
+		held, err := s.heldAmount(account)
+
+		if err != nil {
+			return DeactivateUserResponse{}, err
+		}
+
+		closingBalance, err = closingBalance.Add(held)
+
+		if err != nil {
+			return DeactivateUserResponse{}, err
+		}
+
+		if !closingBalance.IsZero() && !payout {
+			return DeactivateUserResponse{}, errors.New("non-zero balance requires payout account")
+		}
+
+		statements = append(
+			statements,
+			ClosingAccountResponse{
+				AccountID:      account.ID,
+				ClosingBalance: closingBalance,
+			},
+		)
 	}
-	balance := account.Balance + req.Amount
 
-	err = s.accountRepo.UpdateBalance(
-		accountrepo.UpdateBalanceRequest{
-			ID:      req.AccountID,
-			Balance: balance,
-		},
-	)
+	now := time.Now().UTC()
 
-	if err != nil {
-		return DepositResponse{}, err
+	closedAccountIDs := make([]string, 0, len(statements))
+
+	for i, statement := range statements {
+		statements[i], err = s.closeAccount(req, statement.AccountID, now)
+
+		if err != nil {
+			return DeactivateUserResponse{}, s.compensateDeactivateUser(req, statements[:i+1], nil, err)
+		}
+
+		closedAccountIDs = append(closedAccountIDs, statement.AccountID)
 	}
 
-	err = s.accountRepo.UpdateTransactions(
-		accountrepo.UpdateTransactionsRequest{
-			ID: account.ID,
-			Transaction: domain.Transaction{
-				Timestamp: time.Now().UTC(),
-				Operation: "deposit",
-				Amount:    req.Amount,
+	closed := statements
+
+	for i, account := range joint {
+		err = s.leaveAccount(account, req.UserID)
+
+		if err != nil {
+			return DeactivateUserResponse{}, s.compensateDeactivateUser(req, closed, joint[:i+1], err)
+		}
+
+		statements = append(
+			statements,
+			ClosingAccountResponse{
+				AccountID:      account.ID,
+				ClosingBalance: account.Balance,
+				RemainsOpen:    true,
+				Transactions:   transactionResponses(account),
 			},
+		)
+	}
+
+	err = s.userRepo.UpdateStatus(
+		userrepo.UpdateStatusRequest{
+			ID:                    req.UserID,
+			Status:                domain.UserSuspended,
+			PreviousStatus:        user.Status,
+			DeactivatedAt:         now,
+			DeactivationReason:    req.Reason,
+			DeactivatedAccountIDs: closedAccountIDs,
 		},
 	)
 
 	if err != nil {
-		return DepositResponse{}, err
+		return DeactivateUserResponse{}, s.compensateDeactivateUser(req, closed, joint, err)
 	}
 
-	return DepositResponse{
-		Balance: balance,
+	return DeactivateUserResponse{
+		UserID:        req.UserID,
+		Reason:        req.Reason,
+		DeactivatedAt: now,
+		ClosesAt:      now.Add(s.gracePeriod),
+		Accounts:      statements,
 	}, nil
 }
 
-func (s svc) Withdraw(req WithdrawRequest) (WithdrawResponse, error) {
-	if !validID(req.UserID) {
-		return WithdrawResponse{}, errors.New("invalid user id")
+// closeAccount's statement says how far it got, for the compensation to
+// undo. Closed pots and released holds stay that way.
+func (s svc) closeAccount(req DeactivateUserRequest, accountID string, now time.Time) (ClosingAccountResponse, error) {
+	statement := ClosingAccountResponse{
+		AccountID: accountID,
 	}
 
-	if !validID(req.AccountID) {
-		return WithdrawResponse{}, errors.New("invalid account id")
-	}
+	err := s.releaseHolds(accountID)
 
-	if req.Amount <= 0 {
-		return WithdrawResponse{}, errors.New("invalid amount")
+	if err != nil {
+		return statement, err
 	}
 
-	user, err := s.userRepo.Read(
-		userrepo.ReadRequest{
-			ID: req.UserID,
+	pots, err := s.potRepo.List(
+		potrepo.ListRequest{
+			AccountID: accountID,
+			Open:      true,
 		},
 	)
 
 	if err != nil {
-		return WithdrawResponse{}, err
+		return statement, err
 	}
 
-	if !userAccount(user.AccountIDs, req.AccountID) {
-		return WithdrawResponse{}, errors.New("unauthorized account id")
+	for _, pot := range pots {
+		_, err = s.closePot(pot.ID)
+
+		if err != nil {
+			return statement, err
+		}
+	}
+
+	err = s.accountRepo.UpdateStatus(
+		accountrepo.UpdateStatusRequest{
+			ID:       accountID,
+			FrozenAt: now,
+		},
+	)
+
+	if err != nil {
+		return statement, err
 	}
 
 	account, err := s.accountRepo.Read(
 		accountrepo.ReadRequest{
-			ID: req.AccountID,
+			ID: accountID,
 		},
 	)
 
 	if err != nil {
-		return WithdrawResponse{}, err
+		return statement, err
 	}
 
-	if account.Balance < req.Amount {
-		return WithdrawResponse{}, errors.New("insuficient funds")
-	}
+	statement.ClosingBalance = account.Balance
+
+	if account.Balance.IsPositive() {
+		if req.PayoutAccountID == "" {
+			return statement, errors.New("non-zero balance requires payout account")
+		}
+
+		_, err = s.Transfer(
+			TransferRequest{
+				SenderUserID:      req.UserID,
+				ReceiverUserID:    req.PayoutUserID,
+				SenderAccountID:   accountID,
+				ReceiverAccountID: req.PayoutAccountID,
+				Amount:            account.Balance,
+				closing:           true,
+			},
+		)
 
-	balance := account.Balance - req.Amount
+		if err != nil {
+			return statement, err
+		}
 
-	err = s.accountRepo.UpdateBalance(
-		accountrepo.UpdateBalanceRequest{
-			ID:      req.AccountID,
-			Balance: balance,
+		statement.SweptAmount = account.Balance
+		statement.PayoutAccountID = req.PayoutAccountID
+	}
+
+	err = s.accountRepo.UpdateStatus(
+		accountrepo.UpdateStatusRequest{
+			ID:       accountID,
+			ClosedAt: now,
 		},
 	)
 
 	if err != nil {
-		return WithdrawResponse{}, err
+		return statement, err
 	}
 
-	err = s.accountRepo.UpdateTransactions(
-		accountrepo.UpdateTransactionsRequest{
-			ID: account.ID,
-			Transaction: domain.Transaction{
-				Timestamp: time.Now().UTC(),
-				Operation: "withdraw",
-				Amount:    req.Amount,
-			},
+	account, err = s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
 		},
 	)
 
 	if err != nil {
-		return WithdrawResponse{}, err
+		return statement, err
 	}
 
-	return WithdrawResponse{
-		Balance: balance,
-	}, nil
+	statement.Transactions = transactionResponses(account)
+
+	return statement, nil
 }
 
-func (s svc) Transfer(req TransferRequest) (TransferResponse, error) {
-	if !validID(req.SenderUserID) {
-		return TransferResponse{}, errors.New("invalid sender user id")
-	}
+// heldAmount is what releasing the account's holds puts back on its balance:
+// the pending transfers it sent, with their fees, and its held card payments.
+func (s svc) heldAmount(account domain.Account) (money.Money, error) {
+	held := money.New(0, account.Balance.Currency())
 
-	if !validID(req.ReceiverUserID) {
-		return TransferResponse{}, errors.New("invalid receiver user id")
-	}
+	pendingTransfers, err := s.pendingTransferRepo.List(
+		pendingtransferrepo.ListRequest{
+			Status: domain.PendingTransferPending,
+		},
+	)
 
-	if !validID(req.SenderAccountID) {
-		return TransferResponse{}, errors.New("invalid sender account id")
+	if err != nil {
+		return money.Money{}, err
 	}
 
-	if !validID(req.ReceiverAccountID) {
-		return TransferResponse{}, errors.New("invalid receiver account id")
-	}
+	for _, pendingTransfer := range pendingTransfers {
+		if pendingTransfer.SenderAccountID != account.ID {
+			continue
+		}
 
-	if req.Amount <= 0 {
-		return TransferResponse{}, errors.New("invalid amount")
-	}
+		held, err = held.Add(pendingTransfer.Amount)
 
-	if req.SenderAccountID == req.ReceiverAccountID {
-		return TransferResponse{}, errors.New("same account")
+		if err != nil {
+			return money.Money{}, err
+		}
+
+		held, err = held.Add(pendingTransfer.Fee)
+
+		if err != nil {
+			return money.Money{}, err
+		}
 	}
 
-	sender, err := s.userRepo.Read(
-		userrepo.ReadRequest{
-			ID: req.SenderUserID,
+	cards, err := s.cardRepo.List(
+		cardrepo.ListRequest{
+			AccountID: account.ID,
 		},
 	)
 
 	if err != nil {
-		return TransferResponse{}, err
+		return money.Money{}, err
 	}
 
-	if !userAccount(sender.AccountIDs, req.SenderAccountID) {
-		return TransferResponse{}, errors.New("unauthorized account id")
-	}
+	for _, issued := range cards {
+		for _, authorisation := range issued.Authorisations {
+			if authorisation.Status != domain.AuthorisationHeld {
+				continue
+			}
 
-	senderAccount, err := s.accountRepo.Read(
-		accountrepo.ReadRequest{
-			ID: req.SenderAccountID,
-		},
-	)
+			held, err = held.Add(authorisation.Amount)
 
-	if err != nil {
-		return TransferResponse{}, err
+			if err != nil {
+				return money.Money{}, err
+			}
+		}
 	}
 
-	if senderAccount.Balance < req.Amount {
-		return TransferResponse{}, errors.New("insuficient funds")
-	}
+	return held, nil
+}
 
-	receiver, err := s.userRepo.Read(
-		userrepo.ReadRequest{
-			ID: req.ReceiverUserID,
-		},
-	)
+// releaseHolds settles the account's pending transfers and gives back its
+// held card payments before it is swept, so nothing is refunded into it once
+// it is closed.
+func (s svc) releaseHolds(accountID string) error {
+	err := s.releaseAccountPendingTransfers(accountID)
 
 	if err != nil {
-		return TransferResponse{}, err
-	}
-
-	if !userAccount(receiver.AccountIDs, req.ReceiverAccountID) {
-		return TransferResponse{}, errors.New("unauthorized account id")
+		return err
 	}
 
-	receiverAccount, err := s.accountRepo.Read(
-		accountrepo.ReadRequest{
-			ID: req.ReceiverAccountID,
+	cards, err := s.cardRepo.List(
+		cardrepo.ListRequest{
+			AccountID: accountID,
 		},
 	)
 
 	if err != nil {
-		return TransferResponse{}, err
+		return err
 	}
 
-	senderBalance := senderAccount.Balance - req.Amount
-
-	err = s.accountRepo.UpdateBalance(
-		accountrepo.UpdateBalanceRequest{
-			ID:      req.SenderAccountID,
-			Balance: senderBalance,
-		},
-	)
+	for _, issued := range cards {
+		err = s.releaseCardHolds(issued.ID, domain.AuthorisationReversed)
 
-	if err != nil {
-		return TransferResponse{}, err
+		if err != nil {
+			return err
+		}
 	}
 
-	err = s.accountRepo.UpdateBalance(
-		accountrepo.UpdateBalanceRequest{
-			ID:      req.ReceiverAccountID,
-			Balance: receiverAccount.Balance + req.Amount,
-		},
-	)
+	return nil
+}
 
-	if err != nil {
-		return TransferResponse{}, err
+// compensateDeactivateUser puts the user back on the joint accounts they
+// left, then reopens the accounts a failed deactivation froze or closed and
+// moves their sweeps back, newest first.
+func (s svc) compensateDeactivateUser(req DeactivateUserRequest, statements []ClosingAccountResponse, left []domain.Account, cause error) error {
+	errs := []error{cause}
+
+	for i := len(left) - 1; i >= 0; i-- {
+		err := s.rejoinAccount(left[i], req.UserID)
+
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	err = s.accountRepo.UpdateTransactions(
-		accountrepo.UpdateTransactionsRequest{
-			ID: senderAccount.ID,
-			Transaction: domain.Transaction{
-				Timestamp:         time.Now().UTC(),
-				Operation:         "transfer",
-				Amount:            req.Amount,
-				ReceiverUserID:    req.ReceiverUserID,
-				ReceiverAccountID: req.ReceiverAccountID,
+	for i := len(statements) - 1; i >= 0; i-- {
+		err := s.accountRepo.UpdateStatus(
+			accountrepo.UpdateStatusRequest{
+				ID: statements[i].AccountID,
 			},
-		},
-	)
+		)
 
-	if err != nil {
-		return TransferResponse{}, err
-	}
+		if err != nil {
+			errs = append(errs, err)
 
-	err = s.accountRepo.UpdateTransactions(
-		accountrepo.UpdateTransactionsRequest{
-			ID: receiverAccount.ID,
-			Transaction: domain.Transaction{
-				Timestamp:       time.Now().UTC(),
-				Operation:       "transfer",
-				Amount:          req.Amount,
-				SenderUserID:    req.SenderUserID,
-				SenderAccountID: req.SenderAccountID,
+			continue
+		}
+
+		if !statements[i].SweptAmount.IsPositive() {
+			continue
+		}
+
+		err = s.reverseTransfer(
+			TransferRequest{
+				SenderUserID:      req.UserID,
+				ReceiverUserID:    req.PayoutUserID,
+				SenderAccountID:   statements[i].AccountID,
+				ReceiverAccountID: statements[i].PayoutAccountID,
+				Amount:            statements[i].SweptAmount,
 			},
-		},
-	)
+			money.Money{},
+		)
 
-	if err != nil {
-		return TransferResponse{}, err
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	return TransferResponse{
-		Balance: senderBalance,
-	}, nil
+	if len(errs) == 1 {
+		return cause
+	}
+
+	return errors.Join(errs...)
 }
 
-func (s svc) Balance(req BalanceRequest) (BalanceResponse, error) {
+func (s svc) ReactivateUser(req ReactivateUserRequest) error {
 	if !validID(req.UserID) {
-		return BalanceResponse{}, errors.New("invalid user id")
-	}
-
-	if !validID(req.AccountID) {
-		return BalanceResponse{}, errors.New("invalid account id")
+		return errors.New("invalid user id")
 	}
 
 	user, err := s.userRepo.Read(
@@ -395,45 +730,719 @@ func (s svc) Balance(req BalanceRequest) (BalanceResponse, error) {
 	)
 
 	if err != nil {
-		return BalanceResponse{}, err
+		return err
 	}
 
-	if !userAccount(user.AccountIDs, req.AccountID) {
-		return BalanceResponse{}, errors.New("unauthorized account id")
+	if user.Status == domain.UserClosed {
+		return errors.New("user closed")
 	}
 
-	account, err := s.accountRepo.Read(
-		accountrepo.ReadRequest{
-			ID: req.AccountID,
-		},
-	)
+	if user.Status != domain.UserSuspended || user.DeactivatedAt.IsZero() {
+		return errors.New("user not deactivated")
+	}
 
-	if err != nil {
-		return BalanceResponse{}, err
+	if time.Now().UTC().After(user.DeactivatedAt.Add(s.gracePeriod)) {
+		return errors.New("grace period expired")
 	}
 
-	return BalanceResponse{
-		Balance: account.Balance,
-	}, nil
-}
+	accountIDs := user.DeactivatedAccountIDs
 
-func (s svc) Transactions(req TransactionsRequest) (TransactionsResponse, error) {
-	if !validID(req.UserID) {
-		return TransactionsResponse{}, errors.New("invalid user id")
+	// Users deactivated before the closed accounts were recorded have every
+	// account reopened.
+	if accountIDs == nil {
+		accountIDs = sortedAccountIDs(user.AccountIDs)
 	}
 
-	if !validID(req.AccountID) {
-		return TransactionsResponse{}, errors.New("invalid account id")
+	for _, accountID := range accountIDs {
+		err = s.accountRepo.UpdateStatus(
+			accountrepo.UpdateStatusRequest{
+				ID: accountID,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
 	}
 
-	user, err := s.userRepo.Read(
-		userrepo.ReadRequest{
-			ID: req.UserID,
+	return s.userRepo.UpdateStatus(
+		userrepo.UpdateStatusRequest{
+			ID:     req.UserID,
+			Status: user.PreviousStatus,
 		},
 	)
+}
+
+func (s svc) CloseUsers(req CloseUsersRequest) (CloseUsersResponse, error) {
+	users, err := s.userRepo.List(userrepo.ListRequest{})
 
 	if err != nil {
-		return TransactionsResponse{}, err
+		return CloseUsersResponse{}, err
+	}
+
+	now := time.Now().UTC()
+
+	userIDs := []string{}
+
+	for _, user := range users {
+		if user.Status != domain.UserSuspended || user.DeactivatedAt.IsZero() {
+			continue
+		}
+
+		if now.Before(user.DeactivatedAt.Add(s.gracePeriod)) {
+			continue
+		}
+
+		err = s.userRepo.UpdateStatus(
+			userrepo.UpdateStatusRequest{
+				ID:                    user.ID,
+				Status:                domain.UserClosed,
+				DeactivatedAt:         user.DeactivatedAt,
+				DeactivationReason:    user.DeactivationReason,
+				DeactivatedAccountIDs: user.DeactivatedAccountIDs,
+				ClosedAt:              now,
+			},
+		)
+
+		if err != nil {
+			return CloseUsersResponse{}, err
+		}
+
+		userIDs = append(userIDs, user.ID)
+	}
+
+	sort.Strings(userIDs)
+
+	return CloseUsersResponse{
+		UserIDs: userIDs,
+	}, nil
+}
+
+func (s svc) Deposit(req DepositRequest) (DepositResponse, error) {
+	if !validID(req.UserID) {
+		return DepositResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	req.AccountID = accountID
+
+	if !validID(req.AccountID) {
+		return DepositResponse{}, errors.New("invalid account id")
+	}
+
+	if !req.Amount.IsPositive() {
+		return DepositResponse{}, errors.New("invalid amount")
+	}
+
+	details, err := checkDetails(req.TransactionDetails)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	user, err := s.readUser(req.UserID, actionDeposit)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	if !userAccount(user.AccountIDs, req.AccountID) {
+		return DepositResponse{}, errors.New("unauthorized account id")
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: req.AccountID,
+		},
+	)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	err = authorize(account, req.UserID, actionDeposit)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return DepositResponse{}, errors.New("account closed")
+	}
+
+	err = checkIncoming(account, domain.OperationDeposit)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	balance, err := s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     req.AccountID,
+			Credit: req.Amount,
+		},
+	)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	transactionID := newTransactionID()
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: account.ID,
+			Transaction: withDetails(
+				domain.Transaction{
+					ID:        transactionID,
+					Timestamp: time.Now().UTC(),
+					Operation: domain.OperationDeposit,
+					Amount:    req.Amount,
+				},
+				details,
+			),
+		},
+	)
+
+	if err != nil {
+		return DepositResponse{}, err
+	}
+
+	balance = s.autoSave(account.ID, domain.AutoSaveDepositPercentage, req.Amount, balance)
+
+	return DepositResponse{
+		Balance:       balance,
+		TransactionID: transactionID,
+	}, nil
+}
+
+func (s svc) Withdraw(req WithdrawRequest) (WithdrawResponse, error) {
+	if !validID(req.UserID) {
+		return WithdrawResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	req.AccountID = accountID
+
+	if !validID(req.AccountID) {
+		return WithdrawResponse{}, errors.New("invalid account id")
+	}
+
+	if !req.Amount.IsPositive() {
+		return WithdrawResponse{}, errors.New("invalid amount")
+	}
+
+	details, err := checkDetails(req.TransactionDetails)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	user, err := s.readUser(req.UserID, actionWithdraw)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	if !userAccount(user.AccountIDs, req.AccountID) {
+		return WithdrawResponse{}, errors.New("unauthorized account id")
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: req.AccountID,
+		},
+	)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	err = authorize(account, req.UserID, actionWithdraw)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	if !account.ClosedAt.IsZero() {
+		return WithdrawResponse{}, errors.New("account closed")
+	}
+
+	cmp, err := account.Balance.Cmp(req.Amount)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	if cmp < 0 {
+		return WithdrawResponse{}, ErrInsufficientFunds
+	}
+
+	now := time.Now().UTC()
+
+	fee, err := checkOutgoing(account, domain.OperationWithdraw, req.Amount, req.BreakTerm, now)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	err = s.checkVelocity(user, account, domain.OperationWithdraw, req.Amount, now)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	err = s.approve(account, req.UserID, req.ApproverUserID, req.Amount)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	debited, err := req.Amount.Add(fee)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	balance, err := s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:      req.AccountID,
+			Debit:   debited,
+			Minimum: account.Product.MinimumBalance,
+		},
+	)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	transactionID := newTransactionID()
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: account.ID,
+			Transaction: withDetails(
+				domain.Transaction{
+					ID:        transactionID,
+					Timestamp: now,
+					Operation: domain.OperationWithdraw,
+					Amount:    req.Amount,
+				},
+				details,
+			),
+		},
+	)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	err = s.chargeFee(account.ID, fee, now)
+
+	if err != nil {
+		return WithdrawResponse{}, err
+	}
+
+	balance = s.autoSave(account.ID, domain.AutoSaveRoundUp, req.Amount, balance)
+
+	return WithdrawResponse{
+		Balance:       balance,
+		TransactionID: transactionID,
+	}, nil
+}
+
+func (s svc) Transfer(req TransferRequest) (TransferResponse, error) {
+	req, err := s.payeeTransfer(req)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	plan, err := s.planTransfer(req)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	err = s.confirmPayee(plan.receiverAccount, req.ReceiverName, req.AcceptNameMismatch)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if s.requiresAcceptance(req, plan) {
+		pendingTransfer, balance, err := s.holdTransfer(req, plan, plan.now.Add(s.pendingTransferTTL))
+
+		if err != nil {
+			return TransferResponse{}, err
+		}
+
+		return TransferResponse{
+			Balance:           balance,
+			PendingTransferID: pendingTransfer.ID,
+		}, nil
+	}
+
+	return s.executeTransfer(req, plan)
+}
+
+func (s svc) planTransfer(req TransferRequest) (transferPlan, error) {
+	if !validID(req.SenderUserID) {
+		return transferPlan{}, errors.New("invalid sender user id")
+	}
+
+	if !validID(req.ReceiverUserID) {
+		return transferPlan{}, errors.New("invalid receiver user id")
+	}
+
+	senderAccountID, err := s.resolveAccountID(req.SenderAccountID)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	req.SenderAccountID = senderAccountID
+
+	if !validID(req.SenderAccountID) {
+		return transferPlan{}, errors.New("invalid sender account id")
+	}
+
+	receiverAccountID, err := s.resolveAccountID(req.ReceiverAccountID)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	req.ReceiverAccountID = receiverAccountID
+
+	if !validID(req.ReceiverAccountID) {
+		return transferPlan{}, errors.New("invalid receiver account id")
+	}
+
+	if !req.Amount.IsPositive() {
+		return transferPlan{}, errors.New("invalid amount")
+	}
+
+	if req.SenderAccountID == req.ReceiverAccountID {
+		return transferPlan{}, errors.New("same account")
+	}
+
+	details, err := checkDetails(req.TransactionDetails)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	act := actionTransfer
+
+	if req.closing {
+		act = actionClose
+	}
+
+	sender, err := s.readUser(req.SenderUserID, act)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	if !userAccount(sender.AccountIDs, req.SenderAccountID) {
+		return transferPlan{}, errors.New("unauthorized account id")
+	}
+
+	senderAccount, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: req.SenderAccountID,
+		},
+	)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	err = authorize(senderAccount, req.SenderUserID, actionTransfer)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	if !senderAccount.ClosedAt.IsZero() {
+		return transferPlan{}, errors.New("sender account closed")
+	}
+
+	cmp, err := senderAccount.Balance.Cmp(req.Amount)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	if cmp < 0 {
+		return transferPlan{}, ErrInsufficientFunds
+	}
+
+	now := time.Now().UTC()
+
+	fee := money.Money{}
+
+	if !req.closing {
+		fee, err = checkOutgoing(senderAccount, domain.OperationTransferOut, req.Amount, req.BreakTerm, now)
+
+		if err != nil {
+			return transferPlan{}, err
+		}
+
+		err = s.checkVelocity(sender, senderAccount, domain.OperationTransferOut, req.Amount, now)
+
+		if err != nil {
+			return transferPlan{}, err
+		}
+	}
+
+	if !req.mandated {
+		err = s.approve(senderAccount, req.SenderUserID, req.ApproverUserID, req.Amount)
+
+		if err != nil {
+			return transferPlan{}, err
+		}
+	}
+
+	receiver, err := s.readUser(req.ReceiverUserID, actionReceive)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	if !userAccount(receiver.AccountIDs, req.ReceiverAccountID) {
+		return transferPlan{}, errors.New("unauthorized account id")
+	}
+
+	receiverAccount, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: req.ReceiverAccountID,
+		},
+	)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	if !receiverAccount.ClosedAt.IsZero() {
+		return transferPlan{}, errors.New("receiver account closed")
+	}
+
+	err = checkIncoming(receiverAccount, domain.OperationTransferIn)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	debited, err := req.Amount.Add(fee)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	// Fail an overflowing credit before the sender is debited.
+	_, err = receiverAccount.Balance.Add(req.Amount)
+
+	if err != nil {
+		return transferPlan{}, err
+	}
+
+	minimum := senderAccount.Product.MinimumBalance
+
+	if req.closing {
+		minimum = money.Money{}
+	}
+
+	return transferPlan{
+		senderAccount:   senderAccount,
+		receiverAccount: receiverAccount,
+		debited:         debited,
+		minimum:         minimum,
+		fee:             fee,
+		details:         details,
+		now:             now,
+	}, nil
+}
+
+func (s svc) executeTransfer(req TransferRequest, plan transferPlan) (TransferResponse, error) {
+	req = plan.resolve(req)
+
+	senderBalance, err := s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:      req.SenderAccountID,
+			Debit:   plan.debited,
+			Minimum: plan.minimum,
+			Sweep:   req.closing,
+		},
+	)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	_, err = s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     req.ReceiverAccountID,
+			Credit: req.Amount,
+		},
+	)
+
+	if err != nil {
+		return TransferResponse{}, s.refund(
+			accountrepo.UpdateBalanceRequest{
+				ID:     req.SenderAccountID,
+				Credit: plan.debited,
+				Sweep:  req.closing,
+			},
+			err,
+		)
+	}
+
+	transactionID := newTransactionID()
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: plan.senderAccount.ID,
+			Transaction: withDetails(
+				domain.Transaction{
+					ID:                transactionID,
+					Timestamp:         plan.now,
+					Operation:         domain.OperationTransfer,
+					Amount:            req.Amount,
+					ReceiverUserID:    req.ReceiverUserID,
+					ReceiverAccountID: req.ReceiverAccountID,
+				},
+				plan.details,
+			),
+		},
+	)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	err = s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: plan.receiverAccount.ID,
+			Transaction: withDetails(
+				domain.Transaction{
+					ID:              newTransactionID(),
+					Timestamp:       plan.now,
+					Operation:       domain.OperationTransfer,
+					Amount:          req.Amount,
+					SenderUserID:    req.SenderUserID,
+					SenderAccountID: req.SenderAccountID,
+				},
+				plan.details,
+			),
+		},
+	)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	err = s.chargeFee(plan.senderAccount.ID, plan.fee, plan.now)
+
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	return TransferResponse{
+		Balance:       senderBalance,
+		TransactionID: transactionID,
+	}, nil
+}
+
+// resolve returns the request with the account ids the plan looked up, in
+// place of any IBANs it was given.
+func (p transferPlan) resolve(req TransferRequest) TransferRequest {
+	req.SenderAccountID = p.senderAccount.ID
+	req.ReceiverAccountID = p.receiverAccount.ID
+
+	return req
+}
+
+func (s svc) Balance(req BalanceRequest) (BalanceResponse, error) {
+	if !validID(req.UserID) {
+		return BalanceResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+
+	req.AccountID = accountID
+
+	if !validID(req.AccountID) {
+		return BalanceResponse{}, errors.New("invalid account id")
+	}
+
+	user, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+
+	if !userAccount(user.AccountIDs, req.AccountID) {
+		return BalanceResponse{}, errors.New("unauthorized account id")
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: req.AccountID,
+		},
+	)
+
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+
+	err = authorize(account, req.UserID, actionView)
+
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+
+	return BalanceResponse{
+		Balance: account.Balance,
+	}, nil
+}
+
+func (s svc) Transactions(req TransactionsRequest) (TransactionsResponse, error) {
+	if !validID(req.UserID) {
+		return TransactionsResponse{}, errors.New("invalid user id")
+	}
+
+	accountID, err := s.resolveAccountID(req.AccountID)
+
+	if err != nil {
+		return TransactionsResponse{}, err
+	}
+
+	req.AccountID = accountID
+
+	if !validID(req.AccountID) {
+		return TransactionsResponse{}, errors.New("invalid account id")
+	}
+
+	user, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return TransactionsResponse{}, err
 	}
 
 	if !userAccount(user.AccountIDs, req.AccountID) {
@@ -450,11 +1459,215 @@ func (s svc) Transactions(req TransactionsRequest) (TransactionsResponse, error)
 		return TransactionsResponse{}, err
 	}
 
+	err = authorize(account, req.UserID, actionView)
+
+	if err != nil {
+		return TransactionsResponse{}, err
+	}
+
+	c, err := s.categoriser(req.UserID)
+
+	if err != nil {
+		return TransactionsResponse{}, err
+	}
+
+	if !req.filtered() {
+		return TransactionsResponse{
+			Transactions: c.transactionResponses(account),
+		}, nil
+	}
+
+	transactions := []TransactionResponse{}
+
+	for _, transaction := range account.Transactions {
+		if req.matches(transaction) {
+			res := transactionResponse(account.ID, transaction)
+
+			res.Category = c.category(transaction)
+
+			transactions = append(transactions, res)
+		}
+	}
+
 	return TransactionsResponse{
-		Transactions: account.Transactions,
+		Transactions: transactions,
+	}, nil
+}
+
+// Transaction finds one ledger entry by id across the accounts the user can
+// view.
+func (s svc) Transaction(req TransactionRequest) (TransactionResponse, error) {
+	if !validID(req.UserID) {
+		return TransactionResponse{}, errors.New("invalid user id")
+	}
+
+	if !validID(req.TransactionID) {
+		return TransactionResponse{}, errors.New("invalid transaction id")
+	}
+
+	accountID, transaction, err := s.userTransaction(req.UserID, req.TransactionID)
+
+	if err != nil {
+		return TransactionResponse{}, err
+	}
+
+	c, err := s.categoriser(req.UserID)
+
+	if err != nil {
+		return TransactionResponse{}, err
+	}
+
+	res := transactionResponse(accountID, transaction)
+
+	res.Category = c.category(transaction)
+
+	return res, nil
+}
+
+func (s svc) Accounts(req AccountsRequest) (AccountsResponse, error) {
+	if !validID(req.UserID) {
+		return AccountsResponse{}, errors.New("invalid user id")
+	}
+
+	user, err := s.readUser(req.UserID, actionView)
+
+	if err != nil {
+		return AccountsResponse{}, err
+	}
+
+	accounts := []AccountResponse{}
+
+	for _, accountID := range sortedAccountIDs(user.AccountIDs) {
+		account, err := s.accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: accountID,
+			},
+		)
+
+		if err != nil {
+			return AccountsResponse{}, err
+		}
+
+		role, exists := holderRole(account, req.UserID)
+
+		if !exists {
+			continue
+		}
+
+		accounts = append(
+			accounts,
+			AccountResponse{
+				AccountID:      account.ID,
+				IBAN:           account.IBAN,
+				ProductID:      account.Product.ID,
+				ProductVersion: account.Product.Version,
+				Role:           role,
+				Balance:        account.Balance,
+				Closed:         !account.ClosedAt.IsZero(),
+			},
+		)
+	}
+
+	return AccountsResponse{
+		Accounts: accounts,
 	}, nil
 }
 
+func (s svc) compensateCreateAccount(accountID string, cause error) error {
+	err := s.accountRepo.Delete(
+		accountrepo.DeleteRequest{
+			ID: accountID,
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (s svc) chargeFee(accountID string, fee money.Money, now time.Time) error {
+	if !fee.IsPositive() {
+		return nil
+	}
+
+	return s.accountRepo.UpdateTransactions(
+		accountrepo.UpdateTransactionsRequest{
+			ID: accountID,
+			Transaction: domain.Transaction{
+				ID:        newTransactionID(),
+				Timestamp: now,
+				Operation: domain.OperationFee,
+				Amount:    fee,
+			},
+		},
+	)
+}
+
+// debit returns the balance left after taking an amount and its fee.
+func debit(balance money.Money, amount money.Money, fee money.Money) (money.Money, error) {
+	balance, err := balance.Sub(amount)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return balance.Sub(fee)
+}
+
+// refund gives back money taken from an account for a step that failed
+// after it.
+func (s svc) refund(credit accountrepo.UpdateBalanceRequest, cause error) error {
+	_, err := s.accountRepo.UpdateBalance(credit)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+// reclaim takes back money paid into an account for a step that failed
+// after it.
+func (s svc) reclaim(accountID string, amount money.Money, cause error) error {
+	_, err := s.accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:    accountID,
+			Debit: amount,
+		},
+	)
+
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+// resolveAccountID swaps an IBAN for the id of its account. Anything else is
+// returned unchanged for the caller's id check, so an IBAN with a typo fails
+// its checksum and is rejected as an invalid id before any lookup.
+func (s svc) resolveAccountID(ref string) (string, error) {
+	accountIBAN, err := iban.Parse(ref)
+
+	if err != nil {
+		return ref, nil
+	}
+
+	account, err := s.accountRepo.Read(
+		accountrepo.ReadRequest{
+			IBAN: accountIBAN,
+		},
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	return account.ID, nil
+}
+
 func validID(id string) bool {
 	if id == "" {
 		return false
@@ -465,6 +1678,57 @@ func validID(id string) bool {
 	return err == nil
 }
 
+func transactionResponses(account domain.Account) []TransactionResponse {
+	res := make([]TransactionResponse, 0, len(account.Transactions))
+
+	for _, transaction := range account.Transactions {
+		res = append(res, transactionResponse(account.ID, transaction))
+	}
+
+	return res
+}
+
+// NewTransactionResponse shows a ledger entry the way the API does, for
+// other packages that publish transactions.
+func NewTransactionResponse(accountID string, transaction domain.Transaction) TransactionResponse {
+	return transactionResponse(accountID, transaction)
+}
+
+func transactionResponse(accountID string, transaction domain.Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:                transaction.ID,
+		AccountID:         accountID,
+		Timestamp:         transaction.Timestamp,
+		Operation:         transaction.Operation,
+		Amount:            transaction.Amount,
+		ReceiverUserID:    transaction.ReceiverUserID,
+		SenderUserID:      transaction.SenderUserID,
+		ReceiverAccountID: transaction.ReceiverAccountID,
+		SenderAccountID:   transaction.SenderAccountID,
+		TransactionDetails: TransactionDetails{
+			Description: transaction.Description,
+			Reference:   transaction.Reference,
+			Metadata:    transaction.Metadata,
+		},
+	}
+}
+
+func newTransactionID() string {
+	return guuid.NewString()
+}
+
+func sortedAccountIDs(accountIDs map[string]struct{}) []string {
+	ids := make([]string, 0, len(accountIDs))
+
+	for id := range accountIDs {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
 func userAccount(userAccountIDs map[string]struct{}, accountID string) bool {
 	_, exists := userAccountIDs[accountID]
 
@@ -2,13 +2,29 @@ package service
 
 import (
 	"errors"
+	"math"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/iban"
+	"github.com/hetfdex/tiny-bank/internal/money"
 	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/loanrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/payeerepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/paymentrequestrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/pendingtransferrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/potrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
 	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
 	"github.com/hetfdex/tiny-bank/test/mock/repository/accountrepomock"
+	"github.com/hetfdex/tiny-bank/test/mock/repository/payeerepomock"
+	"github.com/hetfdex/tiny-bank/test/mock/repository/paymentrequestrepomock"
+	"github.com/hetfdex/tiny-bank/test/mock/repository/pendingtransferrepomock"
+	"github.com/hetfdex/tiny-bank/test/mock/repository/productrepomock"
 	"github.com/hetfdex/tiny-bank/test/mock/repository/userrepomock"
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/assert"
@@ -16,7 +32,7 @@ import (
 )
 
 func TestTransfer_ErrInvalidSenderUserID(t *testing.T) {
-	svc := New(nil, nil)
+	svc := New(nil, nil, nil, nil)
 
 	res, err := svc.Transfer(TransferRequest{})
 
@@ -25,7 +41,7 @@ func TestTransfer_ErrInvalidSenderUserID(t *testing.T) {
 }
 
 func TestTransfer_ErrInvalidReceiverUserID(t *testing.T) {
-	svc := New(nil, nil)
+	svc := New(nil, nil, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -39,7 +55,7 @@ func TestTransfer_ErrInvalidReceiverUserID(t *testing.T) {
 }
 
 func TestTransfer_ErrInvalidSenderAccountID(t *testing.T) {
-	svc := New(nil, nil)
+	svc := New(nil, nil, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -53,7 +69,7 @@ func TestTransfer_ErrInvalidSenderAccountID(t *testing.T) {
 }
 
 func TestTransfer_ErrInvalidReceiverAccountID(t *testing.T) {
-	svc := New(nil, nil)
+	svc := New(nil, nil, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -68,8 +84,27 @@ func TestTransfer_ErrInvalidReceiverAccountID(t *testing.T) {
 	assert.Equal(t, errors.New("invalid receiver account id"), err)
 }
 
+func TestTransfer_ErrInvalidReceiverIBAN(t *testing.T) {
+	accountRepo := &accountrepomock.Mock{}
+
+	svc := New(nil, accountRepo, nil, nil)
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:      uuid.New(),
+			ReceiverUserID:    uuid.New(),
+			SenderAccountID:   uuid.New(),
+			ReceiverAccountID: "NL91 ABNA 0417 1643 01",
+		},
+	)
+
+	assert.Equal(t, TransferResponse{}, res)
+	assert.Equal(t, errors.New("invalid receiver account id"), err)
+	accountRepo.AssertNotCalled(t, "Read", mock.Anything)
+}
+
 func TestTransfer_ErrInvalidAmount(t *testing.T) {
-	svc := New(nil, nil)
+	svc := New(nil, nil, nil, nil)
 
 	userID := uuid.New()
 	accountID := uuid.New()
@@ -80,7 +115,7 @@ func TestTransfer_ErrInvalidAmount(t *testing.T) {
 			ReceiverUserID:    userID,
 			SenderAccountID:   accountID,
 			ReceiverAccountID: accountID,
-			Amount:            -1,
+			Amount:            money.New(-1, money.EUR),
 		},
 	)
 
@@ -89,7 +124,7 @@ func TestTransfer_ErrInvalidAmount(t *testing.T) {
 }
 
 func TestTransfer_ErrSameAccount(t *testing.T) {
-	svc := New(nil, nil)
+	svc := New(nil, nil, nil, nil)
 
 	userID := uuid.New()
 	accountID := uuid.New()
@@ -100,7 +135,7 @@ func TestTransfer_ErrSameAccount(t *testing.T) {
 			ReceiverUserID:    userID,
 			SenderAccountID:   accountID,
 			ReceiverAccountID: accountID,
-			Amount:            1,
+			Amount:            money.New(1, money.EUR),
 		},
 	)
 
@@ -108,6 +143,50 @@ func TestTransfer_ErrSameAccount(t *testing.T) {
 	assert.Equal(t, errors.New("same account"), err)
 }
 
+func TestTransfer_ErrInvalidDetails(t *testing.T) {
+	for _, tc := range []struct {
+		details    TransactionDetails
+		errMessage string
+	}{
+		{TransactionDetails{Description: strings.Repeat("a", 141)}, "invalid description"},
+		{TransactionDetails{Reference: "RF19 5390 0754 7034"}, "invalid reference"},
+		{TransactionDetails{Reference: "RF18"}, "invalid reference"},
+		{TransactionDetails{Reference: "XX18539007547034"}, "invalid reference"},
+		{TransactionDetails{Reference: "RF18 5390 0754 7034 0000 00000"}, "invalid reference"},
+		{TransactionDetails{Metadata: map[string]string{"": "empty key"}}, "invalid metadata"},
+		{TransactionDetails{Metadata: map[string]string{"key": strings.Repeat("v", 501)}}, "invalid metadata"},
+	} {
+		svc := New(nil, nil, nil, nil)
+
+		res, err := svc.Transfer(
+			TransferRequest{
+				SenderUserID:       uuid.New(),
+				ReceiverUserID:     uuid.New(),
+				SenderAccountID:    uuid.New(),
+				ReceiverAccountID:  uuid.New(),
+				Amount:             money.New(10, money.EUR),
+				TransactionDetails: tc.details,
+			},
+		)
+
+		assert.Equal(t, TransferResponse{}, res)
+		assert.Equal(t, errors.New(tc.errMessage), err, tc.errMessage)
+	}
+}
+
+func TestCreditorReference_Ok(t *testing.T) {
+	for value, expected := range map[string]string{
+		"RF18539007547034":    "RF18539007547034",
+		"rf18 5390 0754 7034": "RF18539007547034",
+		"RF712348231":         "RF712348231",
+	} {
+		res, ok := creditorReference(value)
+
+		assert.Equal(t, expected, res, value)
+		assert.True(t, ok, value)
+	}
+}
+
 func TestTransfer_ErrReadSender(t *testing.T) {
 	errMock := errors.New("error user")
 
@@ -125,7 +204,7 @@ func TestTransfer_ErrReadSender(t *testing.T) {
 		errMock,
 	)
 
-	svc := New(userRepo, nil)
+	svc := New(userRepo, nil, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -133,7 +212,7 @@ func TestTransfer_ErrReadSender(t *testing.T) {
 			ReceiverUserID:    uuid.New(),
 			SenderAccountID:   uuid.New(),
 			ReceiverAccountID: uuid.New(),
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -155,7 +234,7 @@ func TestTransfer_ErrUnauthorizedAccountIDSender(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				uuid.New(): {},
@@ -164,7 +243,7 @@ func TestTransfer_ErrUnauthorizedAccountIDSender(t *testing.T) {
 		nil,
 	)
 
-	svc := New(userRepo, nil)
+	svc := New(userRepo, nil, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -172,7 +251,7 @@ func TestTransfer_ErrUnauthorizedAccountIDSender(t *testing.T) {
 			ReceiverUserID:    uuid.New(),
 			SenderAccountID:   uuid.New(),
 			ReceiverAccountID: uuid.New(),
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -197,7 +276,7 @@ func TestTransfer_ErrReadSenderAccount(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -218,7 +297,7 @@ func TestTransfer_ErrReadSenderAccount(t *testing.T) {
 		errMock,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -226,7 +305,7 @@ func TestTransfer_ErrReadSenderAccount(t *testing.T) {
 			ReceiverUserID:    uuid.New(),
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: uuid.New(),
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -249,7 +328,7 @@ func TestTransfer_ErrInsuficientFunds(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -269,12 +348,12 @@ func TestTransfer_ErrInsuficientFunds(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   0,
+			Balance:   money.New(0, money.EUR),
 		},
 		nil,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -282,7 +361,7 @@ func TestTransfer_ErrInsuficientFunds(t *testing.T) {
 			ReceiverUserID:    uuid.New(),
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: uuid.New(),
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -308,7 +387,7 @@ func TestTransfer_ErrReadReceiver(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -338,12 +417,12 @@ func TestTransfer_ErrReadReceiver(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -351,7 +430,7 @@ func TestTransfer_ErrReadReceiver(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: uuid.New(),
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -376,7 +455,7 @@ func TestTransfer_ErrUnauthorizedAccountIDReceiver(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -394,7 +473,7 @@ func TestTransfer_ErrUnauthorizedAccountIDReceiver(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				uuid.New(): {},
@@ -414,12 +493,12 @@ func TestTransfer_ErrUnauthorizedAccountIDReceiver(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -427,7 +506,7 @@ func TestTransfer_ErrUnauthorizedAccountIDReceiver(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -454,7 +533,7 @@ func TestTransfer_ErrReadReceiverAccount(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -472,7 +551,7 @@ func TestTransfer_ErrReadReceiverAccount(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				receiverAccountID: {},
@@ -492,7 +571,7 @@ func TestTransfer_ErrReadReceiverAccount(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
@@ -507,7 +586,7 @@ func TestTransfer_ErrReadReceiverAccount(t *testing.T) {
 		errMock,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -515,7 +594,7 @@ func TestTransfer_ErrReadReceiverAccount(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -523,6 +602,96 @@ func TestTransfer_ErrReadReceiverAccount(t *testing.T) {
 	assert.Equal(t, errMock, err)
 }
 
+func TestTransfer_ErrReceiverBalanceOverflow(t *testing.T) {
+	senderUserID := uuid.New()
+	receiverUserID := uuid.New()
+	senderAccountID := uuid.New()
+	receiverAccountID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: senderUserID,
+		},
+	).Return(
+		domain.User{
+			ID:        senderUserID,
+			CreatedAt: time.Now().UTC(),
+			Status:    domain.UserVerified,
+			Name:      "joe",
+			AccountIDs: map[string]struct{}{
+				senderAccountID: {},
+			},
+		},
+		nil,
+	)
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: receiverUserID,
+		},
+	).Return(
+		domain.User{
+			ID:        receiverUserID,
+			CreatedAt: time.Now().UTC(),
+			Status:    domain.UserVerified,
+			Name:      "mary",
+			AccountIDs: map[string]struct{}{
+				receiverAccountID: {},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"Read",
+		accountrepo.ReadRequest{
+			ID: senderAccountID,
+		},
+	).Return(
+		domain.Account{
+			ID:        senderAccountID,
+			CreatedAt: time.Now().UTC(),
+			Balance:   money.New(20, money.EUR),
+		},
+		nil,
+	)
+
+	accountRepo.On(
+		"Read",
+		accountrepo.ReadRequest{
+			ID: receiverAccountID,
+		},
+	).Return(
+		domain.Account{
+			ID:        receiverAccountID,
+			CreatedAt: time.Now().UTC(),
+			Balance:   money.New(math.MaxInt64, money.EUR),
+		},
+		nil,
+	)
+
+	svc := New(userRepo, accountRepo, nil, nil)
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:      senderUserID,
+			ReceiverUserID:    receiverUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverAccountID: receiverAccountID,
+			Amount:            money.New(10, money.EUR),
+		},
+	)
+
+	assert.Equal(t, TransferResponse{}, res)
+	assert.Equal(t, money.ErrOverflow, err)
+}
+
 func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 	errMock := errors.New("error account")
 
@@ -542,7 +711,7 @@ func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -560,7 +729,7 @@ func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				receiverAccountID: {},
@@ -580,7 +749,7 @@ func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
@@ -594,7 +763,7 @@ func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 		domain.Account{
 			ID:        receiverAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   10,
+			Balance:   money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -602,14 +771,15 @@ func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      senderAccountID,
-			Balance: 10,
+			ID:    senderAccountID,
+			Debit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.Money{},
 		errMock,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -617,7 +787,7 @@ func TestTransfer_ErrUpdateSenderAccount(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -644,7 +814,7 @@ func TestTransfer_ErrUpdateReceiverAccount(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -662,7 +832,7 @@ func TestTransfer_ErrUpdateReceiverAccount(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				receiverAccountID: {},
@@ -682,7 +852,7 @@ func TestTransfer_ErrUpdateReceiverAccount(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
@@ -696,7 +866,7 @@ func TestTransfer_ErrUpdateReceiverAccount(t *testing.T) {
 		domain.Account{
 			ID:        receiverAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   10,
+			Balance:   money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -704,24 +874,37 @@ func TestTransfer_ErrUpdateReceiverAccount(t *testing.T) {
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      senderAccountID,
-			Balance: 10,
+			ID:    senderAccountID,
+			Debit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(10, money.EUR),
 		nil,
 	)
 
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      receiverAccountID,
-			Balance: 20,
+			ID:     receiverAccountID,
+			Credit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.Money{},
 		errMock,
 	)
 
-	svc := New(userRepo, accountRepo)
+	accountRepo.On(
+		"UpdateBalance",
+		accountrepo.UpdateBalanceRequest{
+			ID:     senderAccountID,
+			Credit: money.New(10, money.EUR),
+		},
+	).Return(
+		money.New(20, money.EUR),
+		nil,
+	)
+
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -729,7 +912,7 @@ func TestTransfer_ErrUpdateReceiverAccount(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -756,7 +939,7 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -774,7 +957,7 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				receiverAccountID: {},
@@ -794,7 +977,7 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
@@ -808,7 +991,7 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 		domain.Account{
 			ID:        receiverAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   10,
+			Balance:   money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -816,20 +999,22 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      senderAccountID,
-			Balance: 10,
+			ID:    senderAccountID,
+			Debit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(10, money.EUR),
 		nil,
 	)
 
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      receiverAccountID,
-			Balance: 20,
+			ID:     receiverAccountID,
+			Credit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(20, money.EUR),
 		nil,
 	)
 
@@ -840,7 +1025,7 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 		errMock,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -848,7 +1033,7 @@ func TestTransfer_ErrUpdateSenderTransactions(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -875,7 +1060,7 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -893,7 +1078,7 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				receiverAccountID: {},
@@ -913,7 +1098,7 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
@@ -927,7 +1112,7 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 		domain.Account{
 			ID:        receiverAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   10,
+			Balance:   money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -935,20 +1120,22 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      senderAccountID,
-			Balance: 10,
+			ID:    senderAccountID,
+			Debit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(10, money.EUR),
 		nil,
 	)
 
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      receiverAccountID,
-			Balance: 20,
+			ID:     receiverAccountID,
+			Credit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(20, money.EUR),
 		nil,
 	)
 
@@ -966,7 +1153,7 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 		errMock,
 	).Once()
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -974,7 +1161,7 @@ func TestTransfer_ErrUpdateReceiverTransactions(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
@@ -999,7 +1186,7 @@ func TestTransfer_Ok(t *testing.T) {
 		domain.User{
 			ID:        senderUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "joe",
 			AccountIDs: map[string]struct{}{
 				senderAccountID: {},
@@ -1017,7 +1204,7 @@ func TestTransfer_Ok(t *testing.T) {
 		domain.User{
 			ID:        receiverUserID,
 			CreatedAt: time.Now().UTC(),
-			Active:    true,
+			Status:    domain.UserVerified,
 			Name:      "mary",
 			AccountIDs: map[string]struct{}{
 				receiverAccountID: {},
@@ -1037,7 +1224,7 @@ func TestTransfer_Ok(t *testing.T) {
 		domain.Account{
 			ID:        senderAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   20,
+			Balance:   money.New(20, money.EUR),
 		},
 		nil,
 	)
@@ -1051,7 +1238,7 @@ func TestTransfer_Ok(t *testing.T) {
 		domain.Account{
 			ID:        receiverAccountID,
 			CreatedAt: time.Now().UTC(),
-			Balance:   10,
+			Balance:   money.New(10, money.EUR),
 		},
 		nil,
 	)
@@ -1059,20 +1246,22 @@ func TestTransfer_Ok(t *testing.T) {
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      senderAccountID,
-			Balance: 10,
+			ID:    senderAccountID,
+			Debit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(10, money.EUR),
 		nil,
 	)
 
 	accountRepo.On(
 		"UpdateBalance",
 		accountrepo.UpdateBalanceRequest{
-			ID:      receiverAccountID,
-			Balance: 20,
+			ID:     receiverAccountID,
+			Credit: money.New(10, money.EUR),
 		},
 	).Return(
+		money.New(20, money.EUR),
 		nil,
 	)
 
@@ -1083,7 +1272,7 @@ func TestTransfer_Ok(t *testing.T) {
 		nil,
 	)
 
-	svc := New(userRepo, accountRepo)
+	svc := New(userRepo, accountRepo, nil, nil)
 
 	res, err := svc.Transfer(
 		TransferRequest{
@@ -1091,10 +1280,2496 @@ func TestTransfer_Ok(t *testing.T) {
 			ReceiverUserID:    receiverUserID,
 			SenderAccountID:   senderAccountID,
 			ReceiverAccountID: receiverAccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
-	assert.Equal(t, TransferResponse{Balance: 10}, res)
+	assert.Equal(t, money.New(10, money.EUR), res.Balance)
+	assert.NotEmpty(t, res.TransactionID)
 	assert.Nil(t, err)
 }
+
+func TestCreateAccount_ErrReadUser(t *testing.T) {
+	errMock := errors.New("user not found")
+
+	userID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{},
+		errMock,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	svc := New(userRepo, accountRepo, nil, nil)
+
+	res, err := svc.CreateAccount(
+		CreateAccountRequest{
+			UserID: userID,
+		},
+	)
+
+	assert.Equal(t, CreateAccountResponse{}, res)
+	assert.Equal(t, errMock, err)
+
+	accountRepo.AssertNotCalled(t, "Create", mock.AnythingOfType("accountrepo.CreateRequest"))
+}
+
+func TestCreateAccount_ErrUpdateAccountIDsCompensates(t *testing.T) {
+	errMock := errors.New("user not found")
+
+	userID := uuid.New()
+	accountID := uuid.New()
+
+	product := domain.Product{
+		ID:      "checking",
+		Version: 1,
+	}
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	userRepo.On(
+		"UpdateAccountIDs",
+		userrepo.UpdateAccountIDsRequest{
+			ID:        userID,
+			AccountID: accountID,
+		},
+	).Return(
+		errMock,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	productRepo := &productrepomock.Mock{}
+
+	productRepo.On(
+		"Read",
+		productrepo.ReadRequest{
+			ID: "checking",
+		},
+	).Return(
+		product,
+		nil,
+	)
+
+	accountRepo.On(
+		"Create",
+		mock.MatchedBy(func(req accountrepo.CreateRequest) bool {
+			_, err := iban.Parse(req.IBAN)
+
+			return req.OwnerID == userID && req.Product.ID == product.ID && err == nil
+		}),
+	).Return(
+		domain.Account{
+			ID: accountID,
+		},
+		nil,
+	)
+
+	accountRepo.On(
+		"Delete",
+		accountrepo.DeleteRequest{
+			ID: accountID,
+		},
+	).Return(
+		nil,
+	)
+
+	svc := New(userRepo, accountRepo, productRepo, nil)
+
+	res, err := svc.CreateAccount(
+		CreateAccountRequest{
+			UserID: userID,
+		},
+	)
+
+	assert.Equal(t, CreateAccountResponse{}, res)
+	assert.Equal(t, errMock, err)
+
+	accountRepo.AssertCalled(t, "Delete", accountrepo.DeleteRequest{ID: accountID})
+}
+
+func TestCreateAccount_ErrCompensate(t *testing.T) {
+	errMock := errors.New("user not found")
+	errDelete := errors.New("error delete")
+
+	userID := uuid.New()
+	accountID := uuid.New()
+
+	product := domain.Product{
+		ID:      "checking",
+		Version: 1,
+	}
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	userRepo.On(
+		"UpdateAccountIDs",
+		userrepo.UpdateAccountIDsRequest{
+			ID:        userID,
+			AccountID: accountID,
+		},
+	).Return(
+		errMock,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	productRepo := &productrepomock.Mock{}
+
+	productRepo.On(
+		"Read",
+		productrepo.ReadRequest{
+			ID: "checking",
+		},
+	).Return(
+		product,
+		nil,
+	)
+
+	accountRepo.On(
+		"Create",
+		mock.MatchedBy(func(req accountrepo.CreateRequest) bool {
+			_, err := iban.Parse(req.IBAN)
+
+			return req.OwnerID == userID && req.Product.ID == product.ID && err == nil
+		}),
+	).Return(
+		domain.Account{
+			ID: accountID,
+		},
+		nil,
+	)
+
+	accountRepo.On(
+		"Delete",
+		accountrepo.DeleteRequest{
+			ID: accountID,
+		},
+	).Return(
+		errDelete,
+	)
+
+	svc := New(userRepo, accountRepo, productRepo, nil)
+
+	res, err := svc.CreateAccount(
+		CreateAccountRequest{
+			UserID: userID,
+		},
+	)
+
+	assert.Equal(t, CreateAccountResponse{}, res)
+	assert.ErrorIs(t, err, errMock)
+	assert.ErrorIs(t, err, errDelete)
+}
+
+func TestCheckLimit_AmountResetsAt(t *testing.T) {
+	now := time.Now().UTC()
+
+	limit := domain.VelocityLimit{
+		Scope:     domain.LimitScopeAccount,
+		Operation: domain.OperationWithdraw,
+		Window:    24 * time.Hour,
+		MaxAmount: money.New(100000, money.EUR),
+	}
+
+	history := []domain.Transaction{
+		{
+			Timestamp: now.Add(-2 * time.Hour),
+			Operation: domain.OperationWithdraw,
+			Amount:    money.New(40000, money.EUR),
+		},
+		{
+			Timestamp: now.Add(-30 * time.Hour),
+			Operation: domain.OperationWithdraw,
+			Amount:    money.New(90000, money.EUR),
+		},
+		{
+			Timestamp: now.Add(-3 * time.Hour),
+			Operation: domain.OperationWithdraw,
+			Amount:    money.New(50000, money.EUR),
+		},
+		{
+			Timestamp: now.Add(-time.Hour),
+			Operation: domain.OperationDeposit,
+			Amount:    money.New(90000, money.EUR),
+		},
+	}
+
+	err := checkLimit(limit, history, money.New(10000, money.EUR), now)
+
+	assert.Nil(t, err)
+
+	err = checkLimit(limit, history, money.New(20000, money.EUR), now)
+
+	assert.Equal(
+		t,
+		VelocityLimitError{
+			Limit:    limit,
+			ResetsAt: now.Add(21 * time.Hour),
+		},
+		err,
+	)
+	assert.Equal(
+		t,
+		"velocity limit reached: withdraw of 1000.00 EUR per 24h0m0s per account, resets at "+now.Add(21*time.Hour).Format(time.RFC3339),
+		err.Error(),
+	)
+
+	err = checkLimit(limit, nil, money.New(100001, money.EUR), now)
+
+	assert.Equal(
+		t,
+		VelocityLimitError{
+			Limit: limit,
+		},
+		err,
+	)
+}
+
+func TestCheckVelocity_Currency(t *testing.T) {
+	now := time.Now().UTC()
+
+	userID := uuid.New()
+	eurAccountID := uuid.New()
+	usdAccountID := uuid.New()
+
+	usdLimit := domain.VelocityLimit{
+		Scope:     domain.LimitScopeUser,
+		Operation: domain.OperationWithdraw,
+		Window:    24 * time.Hour,
+		MaxAmount: money.New(50000, money.USD),
+	}
+
+	tierRepo := tierrepo.New(
+		map[string]domain.Tier{
+			"tier": {
+				ID: "tier",
+				Limits: []domain.VelocityLimit{
+					{
+						Scope:     domain.LimitScopeUser,
+						Operation: domain.OperationWithdraw,
+						Window:    24 * time.Hour,
+						MaxAmount: money.New(100000, money.EUR),
+					},
+					usdLimit,
+				},
+			},
+		},
+	)
+
+	user := domain.User{
+		ID:   userID,
+		Tier: "tier",
+		AccountIDs: map[string]struct{}{
+			eurAccountID: {},
+			usdAccountID: {},
+		},
+	}
+
+	usdAccount := domain.Account{
+		ID:      usdAccountID,
+		Balance: money.New(100000, money.USD),
+		Transactions: []domain.Transaction{
+			{
+				Timestamp: now.Add(-time.Hour),
+				Operation: domain.OperationWithdraw,
+				Amount:    money.New(40000, money.USD),
+			},
+		},
+	}
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			eurAccountID: {
+				ID:      eurAccountID,
+				Balance: money.New(0, money.EUR),
+				Transactions: []domain.Transaction{
+					{
+						Timestamp: now.Add(-time.Hour),
+						Operation: domain.OperationWithdraw,
+						Amount:    money.New(90000, money.EUR),
+					},
+				},
+			},
+			usdAccountID: usdAccount,
+		},
+	)
+
+	s := svc{
+		accountRepo: accountRepo,
+		tierRepo:    tierRepo,
+	}
+
+	err := s.checkVelocity(user, usdAccount, domain.OperationWithdraw, money.New(10000, money.USD), now)
+
+	assert.Nil(t, err)
+
+	err = s.checkVelocity(user, usdAccount, domain.OperationWithdraw, money.New(10001, money.USD), now)
+
+	assert.Equal(
+		t,
+		VelocityLimitError{
+			Limit:    usdLimit,
+			ResetsAt: now.Add(23 * time.Hour),
+		},
+		err,
+	)
+
+	err = s.checkVelocity(user, usdAccount, domain.OperationWithdraw, money.New(100, money.GBP), now)
+
+	assert.Nil(t, err)
+}
+
+func TestCheckLimit_Holds(t *testing.T) {
+	now := time.Now().UTC()
+
+	limit := domain.VelocityLimit{
+		Scope:     domain.LimitScopeAccount,
+		Operation: domain.OperationTransferOut,
+		Window:    24 * time.Hour,
+		MaxAmount: money.New(100000, money.EUR),
+	}
+
+	history := []domain.Transaction{
+		{
+			Timestamp:         now.Add(-2 * time.Hour),
+			Operation:         domain.OperationHold,
+			Amount:            money.New(60000, money.EUR),
+			ReceiverAccountID: "1",
+			Metadata:          map[string]string{pendingTransferMetadataKey: "2"},
+		},
+		{
+			Timestamp:         now.Add(-time.Hour),
+			Operation:         domain.OperationHold,
+			Amount:            money.New(30000, money.EUR),
+			ReceiverAccountID: "1",
+			Metadata:          map[string]string{pendingTransferMetadataKey: "3"},
+		},
+	}
+
+	err := checkLimit(limit, history, money.New(20000, money.EUR), now)
+
+	assert.Equal(
+		t,
+		VelocityLimitError{
+			Limit:    limit,
+			ResetsAt: now.Add(22 * time.Hour),
+		},
+		err,
+	)
+
+	history = append(
+		history,
+		domain.Transaction{
+			Timestamp:         now.Add(-time.Minute),
+			Operation:         domain.OperationHoldRelease,
+			Amount:            money.New(60000, money.EUR),
+			ReceiverAccountID: "1",
+			Metadata:          map[string]string{pendingTransferMetadataKey: "2"},
+		},
+	)
+
+	err = checkLimit(limit, history, money.New(20000, money.EUR), now)
+
+	assert.Nil(t, err)
+}
+
+func TestCheckLimit_CountResetsAt(t *testing.T) {
+	now := time.Now().UTC()
+
+	limit := domain.VelocityLimit{
+		Scope:     domain.LimitScopeAccount,
+		Operation: domain.OperationTransferOut,
+		Window:    time.Hour,
+		MaxCount:  2,
+	}
+
+	history := []domain.Transaction{
+		{
+			Timestamp:         now.Add(-10 * time.Minute),
+			Operation:         domain.OperationTransfer,
+			Amount:            money.New(1, money.EUR),
+			ReceiverAccountID: "2",
+		},
+		{
+			Timestamp:       now.Add(-5 * time.Minute),
+			Operation:       domain.OperationTransfer,
+			Amount:          money.New(1, money.EUR),
+			SenderAccountID: "3",
+		},
+		{
+			Timestamp:         now.Add(-20 * time.Minute),
+			Operation:         domain.OperationTransfer,
+			Amount:            money.New(1, money.EUR),
+			ReceiverAccountID: "2",
+		},
+	}
+
+	err := checkLimit(limit, history, money.New(1, money.EUR), now)
+
+	assert.Equal(
+		t,
+		VelocityLimitError{
+			Limit:    limit,
+			ResetsAt: now.Add(40 * time.Minute),
+		},
+		err,
+	)
+}
+
+func TestTransfer_ErrPendingSender(t *testing.T) {
+	senderUserID := uuid.New()
+	senderAccountID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: senderUserID,
+		},
+	).Return(
+		domain.User{
+			ID:     senderUserID,
+			Status: domain.UserPending,
+			AccountIDs: map[string]struct{}{
+				senderAccountID: {},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	svc := New(userRepo, accountRepo, nil, nil)
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:      senderUserID,
+			ReceiverUserID:    uuid.New(),
+			SenderAccountID:   senderAccountID,
+			ReceiverAccountID: uuid.New(),
+			Amount:            money.New(10, money.EUR),
+		},
+	)
+
+	assert.Equal(t, TransferResponse{}, res)
+	assert.Equal(t, errors.New("pending user cannot transfer"), err)
+
+	accountRepo.AssertNotCalled(t, "Read", mock.AnythingOfType("accountrepo.ReadRequest"))
+}
+
+// failCloseRepo fails closing one account, to exercise the undo of a
+// deactivation.
+type failCloseRepo struct {
+	accountrepo.Repo
+	accountID string
+	err       error
+}
+
+func (r failCloseRepo) UpdateStatus(req accountrepo.UpdateStatusRequest) error {
+	if req.ID == r.accountID && !req.ClosedAt.IsZero() {
+		return r.err
+	}
+
+	return r.Repo.UpdateStatus(req)
+}
+
+func TestDeactivateUser_ErrUndoesSweeps(t *testing.T) {
+	userID := uuid.New()
+	payoutUserID := uuid.New()
+	payoutAccountID := uuid.New()
+
+	accountIDs := []string{uuid.New(), uuid.New()}
+
+	sort.Strings(accountIDs)
+
+	errMock := errors.New("error close")
+
+	userRepo := userrepo.New(
+		map[string]domain.User{
+			userID: {
+				ID:     userID,
+				Status: domain.UserVerified,
+				AccountIDs: map[string]struct{}{
+					accountIDs[0]: {},
+					accountIDs[1]: {},
+				},
+			},
+			payoutUserID: {
+				ID:     payoutUserID,
+				Status: domain.UserVerified,
+				AccountIDs: map[string]struct{}{
+					payoutAccountID: {},
+				},
+			},
+		},
+	)
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			accountIDs[0]: {
+				ID:      accountIDs[0],
+				Balance: money.New(100, money.EUR),
+				Holders: map[string]domain.Role{userID: domain.RoleOwner},
+			},
+			accountIDs[1]: {
+				ID:      accountIDs[1],
+				Balance: money.New(50, money.EUR),
+				Holders: map[string]domain.Role{userID: domain.RoleOwner},
+			},
+			payoutAccountID: {
+				ID:      payoutAccountID,
+				Balance: money.New(0, money.EUR),
+				Holders: map[string]domain.Role{payoutUserID: domain.RoleOwner},
+			},
+		},
+	)
+
+	svc := New(
+		userRepo,
+		failCloseRepo{
+			Repo:      accountRepo,
+			accountID: accountIDs[1],
+			err:       errMock,
+		},
+		nil,
+		nil,
+	)
+
+	_, err := svc.DeactivateUser(
+		DeactivateUserRequest{
+			UserID:          userID,
+			PayoutUserID:    payoutUserID,
+			PayoutAccountID: payoutAccountID,
+		},
+	)
+
+	assert.Equal(t, errMock, err)
+
+	for accountID, balance := range map[string]money.Money{
+		accountIDs[0]:   money.New(100, money.EUR),
+		accountIDs[1]:   money.New(50, money.EUR),
+		payoutAccountID: money.New(0, money.EUR),
+	} {
+		account, err := accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: accountID,
+			},
+		)
+
+		assert.Nil(t, err)
+		assert.Equal(t, balance, account.Balance)
+		assert.True(t, account.FrozenAt.IsZero())
+		assert.True(t, account.ClosedAt.IsZero())
+	}
+
+	user, err := userRepo.Read(
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, domain.UserVerified, user.Status)
+}
+
+// failLeaveRepo fails removing the holder from one account, to exercise the
+// undo of the joint accounts a deactivation already left.
+type failLeaveRepo struct {
+	accountrepo.Repo
+	accountID string
+	err       error
+}
+
+func (r failLeaveRepo) UpdateHolders(req accountrepo.UpdateHoldersRequest) error {
+	if req.ID == r.accountID && req.Role == "" {
+		return r.err
+	}
+
+	return r.Repo.UpdateHolders(req)
+}
+
+func TestDeactivateUser_ErrUndoesLeftJointAccounts(t *testing.T) {
+	userID := uuid.New()
+	coOwnerID := uuid.New()
+	accountID := uuid.New()
+
+	jointIDs := []string{uuid.New(), uuid.New()}
+
+	sort.Strings(jointIDs)
+
+	errMock := errors.New("error leave")
+
+	userRepo := userrepo.New(
+		map[string]domain.User{
+			userID: {
+				ID:     userID,
+				Status: domain.UserVerified,
+				AccountIDs: map[string]struct{}{
+					accountID:   {},
+					jointIDs[0]: {},
+					jointIDs[1]: {},
+				},
+			},
+		},
+	)
+
+	holders := map[string]domain.Role{
+		userID:    domain.RoleOwner,
+		coOwnerID: domain.RoleCoOwner,
+	}
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			accountID: {
+				ID:      accountID,
+				Balance: money.New(0, money.EUR),
+				Holders: map[string]domain.Role{userID: domain.RoleOwner},
+			},
+			jointIDs[0]: {
+				ID:      jointIDs[0],
+				Balance: money.New(100, money.EUR),
+				Holders: holders,
+			},
+			jointIDs[1]: {
+				ID:      jointIDs[1],
+				Balance: money.New(100, money.EUR),
+				Holders: holders,
+			},
+		},
+	)
+
+	svc := New(
+		userRepo,
+		failLeaveRepo{
+			Repo:      accountRepo,
+			accountID: jointIDs[1],
+			err:       errMock,
+		},
+		nil,
+		nil,
+	)
+
+	_, err := svc.DeactivateUser(
+		DeactivateUserRequest{
+			UserID: userID,
+		},
+	)
+
+	assert.Equal(t, errMock, err)
+
+	for _, id := range jointIDs {
+		account, err := accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: id,
+			},
+		)
+
+		assert.Nil(t, err)
+		assert.Equal(t, holders, account.Holders)
+	}
+
+	account, err := accountRepo.Read(
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.True(t, account.ClosedAt.IsZero())
+
+	user, err := userRepo.Read(
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, domain.UserVerified, user.Status)
+	assert.Len(t, user.AccountIDs, 3)
+}
+
+func TestReactivateUser_ReopensDeactivatedAccounts(t *testing.T) {
+	userID := uuid.New()
+	openID := uuid.New()
+	closedID := uuid.New()
+
+	closedAt := time.Now().UTC().Add(-time.Hour)
+
+	userRepo := userrepo.New(
+		map[string]domain.User{
+			userID: {
+				ID:     userID,
+				Status: domain.UserVerified,
+				AccountIDs: map[string]struct{}{
+					openID:   {},
+					closedID: {},
+				},
+			},
+		},
+	)
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			openID: {
+				ID:      openID,
+				Balance: money.New(0, money.EUR),
+				Holders: map[string]domain.Role{userID: domain.RoleOwner},
+			},
+			closedID: {
+				ID:       closedID,
+				ClosedAt: closedAt,
+				Balance:  money.New(0, money.EUR),
+				Holders:  map[string]domain.Role{userID: domain.RoleOwner},
+			},
+		},
+	)
+
+	svc := New(userRepo, accountRepo, nil, nil)
+
+	res, err := svc.DeactivateUser(
+		DeactivateUserRequest{
+			UserID: userID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Len(t, res.Accounts, 1)
+
+	err = svc.ReactivateUser(
+		ReactivateUserRequest{
+			UserID: userID,
+		},
+	)
+
+	assert.Nil(t, err)
+
+	for accountID, expected := range map[string]time.Time{
+		openID:   {},
+		closedID: closedAt,
+	} {
+		account, err := accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: accountID,
+			},
+		)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, account.ClosedAt)
+	}
+}
+
+func TestAccountRepo_ErrFrozen(t *testing.T) {
+	accountID := uuid.New()
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			accountID: {
+				ID:       accountID,
+				FrozenAt: time.Now().UTC(),
+				Balance:  money.New(100, money.EUR),
+			},
+		},
+	)
+
+	_, err := accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     accountID,
+			Credit: money.New(10, money.EUR),
+		},
+	)
+
+	assert.Equal(t, accountrepo.ErrAccountFrozen, err)
+
+	err = accountRepo.UpdateStatus(
+		accountrepo.UpdateStatusRequest{
+			ID:       accountID,
+			ClosedAt: time.Now().UTC(),
+		},
+	)
+
+	assert.Equal(t, accountrepo.ErrBalanceNotZero, err)
+
+	balance, err := accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:    accountID,
+			Debit: money.New(100, money.EUR),
+			Sweep: true,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.True(t, balance.IsZero())
+}
+
+func TestSubmitKYC_ErrMinimumAge(t *testing.T) {
+	svc := New(nil, nil, nil, nil)
+
+	res, err := svc.SubmitKYC(
+		SubmitKYCRequest{
+			UserID:      uuid.New(),
+			DateOfBirth: time.Now().UTC().AddDate(-17, 0, 0).Format(dateLayout),
+			Address: AddressTerms{
+				Line1:      "1 Main Street",
+				City:       "Lisbon",
+				PostalCode: "1000-001",
+				Country:    "PT",
+			},
+			IDNumber: "X1234567",
+		},
+	)
+
+	assert.Equal(t, KYCResponse{}, res)
+	assert.Equal(t, errors.New("user under minimum age"), err)
+}
+
+func TestSubmitKYC_Ok(t *testing.T) {
+	userID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserPending,
+		},
+		nil,
+	).Once()
+
+	userRepo.On(
+		"UpdateKYC",
+		mock.MatchedBy(func(req userrepo.UpdateKYCRequest) bool {
+			return req.ID == userID && req.Status == domain.UserKYCInReview && req.KYC.IDNumber == "X1234567" && req.KYC.Address.Country == "PT"
+		}),
+	).Return(
+		nil,
+	)
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserKYCInReview,
+			KYC: domain.KYC{
+				DateOfBirth: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+				Address: domain.Address{
+					Line1:      "1 Main Street",
+					City:       "Lisbon",
+					PostalCode: "1000-001",
+					Country:    "PT",
+				},
+				IDNumber:    "X1234567",
+				SubmittedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		nil,
+	)
+
+	svc := New(userRepo, nil, nil, nil)
+
+	res, err := svc.SubmitKYC(
+		SubmitKYCRequest{
+			UserID:      userID,
+			DateOfBirth: "1990-01-02",
+			Address: AddressTerms{
+				Line1:      "1 Main Street",
+				City:       "Lisbon",
+				PostalCode: "1000-001",
+				Country:    "pt",
+			},
+			IDNumber: "x123 4567",
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, domain.UserKYCInReview, res.Status)
+	assert.Equal(t, "1990-01-02", res.DateOfBirth)
+	assert.Equal(t, "****4567", res.IDNumber)
+}
+
+func TestUpdateUserStatus_ErrTransition(t *testing.T) {
+	userID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	svc := New(userRepo, nil, nil, nil)
+
+	err := svc.UpdateUserStatus(
+		UpdateUserStatusRequest{
+			UserID: userID,
+			Status: domain.UserPending,
+		},
+	)
+
+	assert.Equal(t, errors.New("cannot move verified user to pending"), err)
+
+	userRepo.AssertNotCalled(t, "UpdateStatus", mock.AnythingOfType("userrepo.UpdateStatusRequest"))
+}
+
+func TestTransaction_Ok(t *testing.T) {
+	userID := uuid.New()
+	accountID := uuid.New()
+	transactionID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+			AccountIDs: map[string]struct{}{
+				accountID: {},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"Read",
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	).Return(
+		domain.Account{
+			ID: accountID,
+			Transactions: []domain.Transaction{
+				{
+					ID:        uuid.New(),
+					Operation: domain.OperationDeposit,
+					Amount:    money.New(10, money.EUR),
+				},
+				{
+					ID:        transactionID,
+					Operation: domain.OperationWithdraw,
+					Amount:    money.New(5, money.EUR),
+				},
+			},
+		},
+		nil,
+	)
+
+	svc := New(userRepo, accountRepo, nil, nil)
+
+	res, err := svc.Transaction(
+		TransactionRequest{
+			UserID:        userID,
+			TransactionID: transactionID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, accountID, res.AccountID)
+	assert.Equal(t, domain.OperationWithdraw, res.Operation)
+	assert.Equal(t, transactionID, res.ID)
+
+	_, err = svc.Transaction(
+		TransactionRequest{
+			UserID:        userID,
+			TransactionID: uuid.New(),
+		},
+	)
+
+	assert.Equal(t, errors.New("transaction not found"), err)
+}
+
+func TestTransfer_ErrPayeeCoolingOff(t *testing.T) {
+	senderUserID := uuid.New()
+	payeeID := uuid.New()
+	activeAt := time.Now().UTC().Add(time.Hour)
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: senderUserID,
+		},
+	).Return(
+		domain.User{
+			ID:     senderUserID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	payeeRepo := &payeerepomock.Mock{}
+
+	payeeRepo.On(
+		"Read",
+		payeerepo.ReadRequest{
+			ID: payeeID,
+		},
+	).Return(
+		domain.Payee{
+			ID:             payeeID,
+			UserID:         senderUserID,
+			ActiveAt:       activeAt,
+			AccountID:      uuid.New(),
+			ReceiverUserID: uuid.New(),
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	svc := New(userRepo, accountRepo, nil, nil, WithPayeeRepo(payeeRepo))
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:    senderUserID,
+			SenderAccountID: uuid.New(),
+			PayeeID:         payeeID,
+			Amount:          money.New(10, money.EUR),
+		},
+	)
+
+	assert.Equal(t, TransferResponse{}, res)
+	assert.Equal(t, PayeeCoolingOffError{Until: activeAt}, err)
+
+	accountRepo.AssertNotCalled(t, "Read", mock.AnythingOfType("accountrepo.ReadRequest"))
+}
+
+func TestTransfer_ErrPayeeOfOtherUser(t *testing.T) {
+	senderUserID := uuid.New()
+	payeeID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: senderUserID,
+		},
+	).Return(
+		domain.User{
+			ID:     senderUserID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	payeeRepo := &payeerepomock.Mock{}
+
+	payeeRepo.On(
+		"Read",
+		payeerepo.ReadRequest{
+			ID: payeeID,
+		},
+	).Return(
+		domain.Payee{
+			ID:     payeeID,
+			UserID: uuid.New(),
+		},
+		nil,
+	)
+
+	svc := New(userRepo, nil, nil, nil, WithPayeeRepo(payeeRepo))
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:    senderUserID,
+			SenderAccountID: uuid.New(),
+			PayeeID:         payeeID,
+			Amount:          money.New(10, money.EUR),
+		},
+	)
+
+	assert.Equal(t, TransferResponse{}, res)
+	assert.Equal(t, errors.New("payee not found"), err)
+}
+
+func TestTransfer_ErrPayeeAndReceiver(t *testing.T) {
+	svc := New(nil, nil, nil, nil)
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:      uuid.New(),
+			SenderAccountID:   uuid.New(),
+			ReceiverAccountID: uuid.New(),
+			PayeeID:           uuid.New(),
+			Amount:            money.New(10, money.EUR),
+		},
+	)
+
+	assert.Equal(t, TransferResponse{}, res)
+	assert.Equal(t, errors.New("invalid receiver, payee id given"), err)
+}
+
+func TestAddPayee_ErrCloseMatch(t *testing.T) {
+	userID := uuid.New()
+	holderUserID := uuid.New()
+	accountID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: holderUserID,
+		},
+	).Return(
+		domain.User{
+			ID:   holderUserID,
+			Name: "Jane Smith",
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"Read",
+		accountrepo.ReadRequest{
+			ID: accountID,
+		},
+	).Return(
+		domain.Account{
+			ID: accountID,
+			Holders: map[string]domain.Role{
+				holderUserID: domain.RoleOwner,
+			},
+		},
+		nil,
+	)
+
+	payeeRepo := &payeerepomock.Mock{}
+
+	payeeRepo.On(
+		"List",
+		payeerepo.ListRequest{
+			UserID: userID,
+		},
+	).Return(
+		[]domain.Payee{},
+		nil,
+	)
+
+	svc := New(userRepo, accountRepo, nil, nil, WithPayeeRepo(payeeRepo))
+
+	res, err := svc.AddPayee(
+		AddPayeeRequest{
+			UserID:    userID,
+			Nickname:  "Jane",
+			Name:      "Jane Smyth",
+			AccountID: accountID,
+		},
+	)
+
+	assert.Equal(t, PayeeResponse{}, res)
+	assert.Equal(t, PayeeNameError{Match: domain.PayeeMatchClose, Name: "Jane Smith"}, err)
+
+	payeeRepo.AssertNotCalled(t, "Create", mock.AnythingOfType("payeerepo.CreateRequest"))
+}
+
+func TestSplitPayers_Ok(t *testing.T) {
+	requesterUserID := uuid.New()
+	first := uuid.New()
+	second := uuid.New()
+	third := uuid.New()
+
+	payers, amount, err := splitPayers(
+		requesterUserID,
+		money.New(1000, money.EUR),
+		[]PayerTerms{{UserID: first}, {UserID: second}, {UserID: third}},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(1000, money.EUR), amount)
+	assert.Equal(
+		t,
+		[]domain.Payer{
+			{UserID: first, Amount: money.New(334, money.EUR), Status: domain.PayerPending},
+			{UserID: second, Amount: money.New(333, money.EUR), Status: domain.PayerPending},
+			{UserID: third, Amount: money.New(333, money.EUR), Status: domain.PayerPending},
+		},
+		payers,
+	)
+
+	payers, amount, err = splitPayers(
+		requesterUserID,
+		money.Money{},
+		[]PayerTerms{{UserID: first, Amount: money.New(700, money.EUR)}, {UserID: second, Amount: money.New(300, money.EUR)}},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(1000, money.EUR), amount)
+	assert.Equal(t, money.New(300, money.EUR), payers[1].Amount)
+}
+
+func TestSplitPayers_Err(t *testing.T) {
+	requesterUserID := uuid.New()
+	payerUserID := uuid.New()
+
+	for _, tc := range []struct {
+		amount     money.Money
+		terms      []PayerTerms
+		errMessage string
+	}{
+		{money.New(100, money.EUR), nil, "invalid payers"},
+		{money.New(100, money.EUR), []PayerTerms{{UserID: "1"}}, "invalid payer user id"},
+		{money.New(100, money.EUR), []PayerTerms{{UserID: requesterUserID}}, "invalid payer user id"},
+		{money.New(100, money.EUR), []PayerTerms{{UserID: payerUserID}, {UserID: payerUserID}}, "duplicate payer"},
+		{money.Money{}, []PayerTerms{{UserID: payerUserID}}, "invalid amount"},
+		{money.New(1, money.EUR), []PayerTerms{{UserID: payerUserID}, {UserID: uuid.New()}}, "invalid amount"},
+		{money.New(100, money.EUR), []PayerTerms{{UserID: payerUserID, Amount: money.New(50, money.EUR)}}, "invalid amount, payer amounts do not add up"},
+		{money.New(100, money.EUR), []PayerTerms{{UserID: payerUserID, Amount: money.New(-50, money.EUR)}}, "invalid payer amount"},
+		{money.New(100, money.EUR), []PayerTerms{{UserID: payerUserID, Amount: money.New(50, money.EUR)}, {UserID: uuid.New()}}, "invalid payer amount, give every payer an amount or none"},
+	} {
+		_, _, err := splitPayers(requesterUserID, tc.amount, tc.terms)
+
+		assert.Equal(t, errors.New(tc.errMessage), err, tc.errMessage)
+	}
+}
+
+func TestPaymentRequest_Expires(t *testing.T) {
+	userID := uuid.New()
+	paymentRequestID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	stored := domain.PaymentRequest{
+		ID:              paymentRequestID,
+		Version:         1,
+		ExpiresAt:       time.Now().UTC().Add(-time.Minute),
+		RequesterUserID: userID,
+		Amount:          money.New(1000, money.EUR),
+		Status:          domain.PaymentRequestOpen,
+		Payers: []domain.Payer{
+			{UserID: uuid.New(), Amount: money.New(1000, money.EUR), Status: domain.PayerPending},
+		},
+	}
+
+	expired := stored
+
+	expired.Version = 2
+	expired.Status = domain.PaymentRequestExpired
+	expired.Payers = []domain.Payer{
+		{UserID: stored.Payers[0].UserID, Amount: money.New(1000, money.EUR), Status: domain.PayerExpired},
+	}
+
+	paymentRequestRepo := &paymentrequestrepomock.Mock{}
+
+	paymentRequestRepo.On(
+		"Read",
+		paymentrequestrepo.ReadRequest{
+			ID: paymentRequestID,
+		},
+	).Return(
+		stored,
+		nil,
+	)
+
+	paymentRequestRepo.On(
+		"Update",
+		mock.MatchedBy(func(req paymentrequestrepo.UpdateRequest) bool {
+			return req.PaymentRequest.Status == domain.PaymentRequestExpired &&
+				req.PaymentRequest.Payers[0].Status == domain.PayerExpired
+		}),
+	).Return(
+		expired,
+		nil,
+	)
+
+	svc := New(userRepo, nil, nil, nil, WithPaymentRequestRepo(paymentRequestRepo))
+
+	res, err := svc.PaymentRequest(
+		PaymentRequestRequest{
+			UserID:           userID,
+			PaymentRequestID: paymentRequestID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, domain.PaymentRequestExpired, res.Status)
+	assert.Equal(t, domain.PayerExpired, res.Payers[0].Status)
+	assert.Equal(t, money.New(0, money.EUR), res.PaidAmount)
+}
+
+func TestBatchTransfer_FirstTimePayee(t *testing.T) {
+	senderUserID := uuid.New()
+	senderAccountID := uuid.New()
+	receiverUserID := uuid.New()
+
+	receiverAccountIDs := []string{uuid.New(), uuid.New(), uuid.New()}
+
+	users := map[string]domain.User{
+		senderUserID: {
+			ID:         senderUserID,
+			Status:     domain.UserVerified,
+			AccountIDs: map[string]struct{}{senderAccountID: {}},
+		},
+		receiverUserID: {
+			ID:     receiverUserID,
+			Status: domain.UserVerified,
+			AccountIDs: map[string]struct{}{
+				receiverAccountIDs[0]: {},
+				receiverAccountIDs[1]: {},
+				receiverAccountIDs[2]: {},
+			},
+		},
+	}
+
+	accounts := map[string]domain.Account{
+		senderAccountID: {
+			ID:      senderAccountID,
+			Balance: money.New(10000, money.EUR),
+			Holders: map[string]domain.Role{senderUserID: domain.RoleOwner},
+		},
+	}
+
+	for _, accountID := range receiverAccountIDs {
+		accounts[accountID] = domain.Account{
+			ID:      accountID,
+			Balance: money.New(0, money.EUR),
+			Holders: map[string]domain.Role{receiverUserID: domain.RoleOwner},
+		}
+	}
+
+	svc := New(
+		userrepo.New(users),
+		accountrepo.New(accounts),
+		nil,
+		nil,
+		WithPendingTransferPolicy(
+			PendingTransferPolicy{
+				FirstTimePayee: true,
+			},
+		),
+	)
+
+	for _, allOrNothing := range []bool{true, false} {
+		res, err := svc.BatchTransfer(
+			BatchTransferRequest{
+				SenderUserID:    senderUserID,
+				SenderAccountID: senderAccountID,
+				AllOrNothing:    allOrNothing,
+				Transfers: []TransferRequest{
+					{
+						ReceiverUserID:    receiverUserID,
+						ReceiverAccountID: receiverAccountIDs[0],
+						Amount:            money.New(1000, money.EUR),
+					},
+					{
+						ReceiverUserID:    receiverUserID,
+						ReceiverAccountID: receiverAccountIDs[1],
+						Amount:            money.New(1000, money.EUR),
+					},
+				},
+			},
+		)
+
+		assert.Nil(t, err)
+
+		for _, result := range res.Results {
+			assert.Equal(t, domain.BatchLineCompleted, result.Status, result.Error)
+		}
+	}
+
+	res, err := svc.Transfer(
+		TransferRequest{
+			SenderUserID:      senderUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverUserID:    receiverUserID,
+			ReceiverAccountID: receiverAccountIDs[2],
+			Amount:            money.New(1000, money.EUR),
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, res.PendingTransferID)
+}
+
+func TestPendingTransfer_Expires(t *testing.T) {
+	userID := uuid.New()
+	senderAccountID := uuid.New()
+	receiverAccountID := uuid.New()
+	pendingTransferID := uuid.New()
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: userID,
+		},
+	).Return(
+		domain.User{
+			ID:     userID,
+			Status: domain.UserVerified,
+		},
+		nil,
+	)
+
+	stored := domain.PendingTransfer{
+		ID:                pendingTransferID,
+		Version:           1,
+		ExpiresAt:         time.Now().UTC().Add(-time.Minute),
+		SenderUserID:      userID,
+		SenderAccountID:   senderAccountID,
+		ReceiverUserID:    uuid.New(),
+		ReceiverAccountID: receiverAccountID,
+		Amount:            money.New(1000, money.EUR),
+		Fee:               money.New(50, money.EUR),
+		Status:            domain.PendingTransferPending,
+	}
+
+	expired := stored
+
+	expired.Version = 2
+	expired.Status = domain.PendingTransferExpired
+	expired.RespondedAt = time.Now().UTC()
+
+	pendingTransferRepo := &pendingtransferrepomock.Mock{}
+
+	pendingTransferRepo.On(
+		"Read",
+		pendingtransferrepo.ReadRequest{
+			ID: pendingTransferID,
+		},
+	).Return(
+		stored,
+		nil,
+	)
+
+	pendingTransferRepo.On(
+		"Update",
+		mock.MatchedBy(func(req pendingtransferrepo.UpdateRequest) bool {
+			return req.PendingTransfer.Status == domain.PendingTransferExpired
+		}),
+	).Return(
+		expired,
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"UpdateBalance",
+		accountrepo.UpdateBalanceRequest{
+			ID:     senderAccountID,
+			Credit: money.New(1050, money.EUR),
+		},
+	).Return(
+		money.New(3050, money.EUR),
+		nil,
+	)
+
+	accountRepo.On(
+		"UpdateTransactions",
+		mock.MatchedBy(func(req accountrepo.UpdateTransactionsRequest) bool {
+			return req.ID == senderAccountID &&
+				req.Transaction.Operation == domain.OperationHoldRelease &&
+				req.Transaction.Metadata[pendingTransferMetadataKey] == pendingTransferID
+		}),
+	).Return(
+		nil,
+	).Once()
+
+	accountRepo.On(
+		"UpdateTransactions",
+		mock.MatchedBy(func(req accountrepo.UpdateTransactionsRequest) bool {
+			return req.ID == senderAccountID && req.Transaction.Operation == domain.OperationFeeRefund
+		}),
+	).Return(
+		nil,
+	).Once()
+
+	accountRepo.On(
+		"UpdateTransactions",
+		mock.MatchedBy(func(req accountrepo.UpdateTransactionsRequest) bool {
+			return req.ID == receiverAccountID &&
+				req.Transaction.Operation == domain.OperationPending &&
+				req.Transaction.Metadata[pendingStatusMetadataKey] == string(domain.PendingTransferExpired)
+		}),
+	).Return(
+		nil,
+	).Once()
+
+	svc := New(userRepo, accountRepo, nil, nil, WithPendingTransferRepo(pendingTransferRepo))
+
+	res, err := svc.PendingTransfer(
+		PendingTransferRequest{
+			UserID:            userID,
+			PendingTransferID: pendingTransferID,
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, domain.PendingTransferExpired, res.Status)
+	accountRepo.AssertExpectations(t)
+}
+
+func TestSettlePendingTransfer_ErrReclaimsCredit(t *testing.T) {
+	receiverUserID := uuid.New()
+	senderAccountID := uuid.New()
+	receiverAccountID := uuid.New()
+
+	errMock := errors.New("error transactions")
+
+	userRepo := &userrepomock.Mock{}
+
+	userRepo.On(
+		"Read",
+		userrepo.ReadRequest{
+			ID: receiverUserID,
+		},
+	).Return(
+		domain.User{
+			ID:     receiverUserID,
+			Status: domain.UserVerified,
+			AccountIDs: map[string]struct{}{
+				receiverAccountID: {},
+			},
+		},
+		nil,
+	)
+
+	accountRepo := &accountrepomock.Mock{}
+
+	accountRepo.On(
+		"Read",
+		accountrepo.ReadRequest{
+			ID: receiverAccountID,
+		},
+	).Return(
+		domain.Account{
+			ID:      receiverAccountID,
+			Balance: money.New(500, money.EUR),
+		},
+		nil,
+	)
+
+	accountRepo.On(
+		"UpdateBalance",
+		accountrepo.UpdateBalanceRequest{
+			ID:     receiverAccountID,
+			Credit: money.New(1000, money.EUR),
+		},
+	).Return(
+		money.New(1500, money.EUR),
+		nil,
+	)
+
+	accountRepo.On(
+		"UpdateTransactions",
+		mock.Anything,
+	).Return(
+		errMock,
+	)
+
+	accountRepo.On(
+		"UpdateBalance",
+		accountrepo.UpdateBalanceRequest{
+			ID:    receiverAccountID,
+			Debit: money.New(1000, money.EUR),
+		},
+	).Return(
+		money.New(500, money.EUR),
+		nil,
+	)
+
+	s := svc{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+	}
+
+	err := s.settlePendingTransfer(
+		domain.PendingTransfer{
+			ID:                uuid.New(),
+			SenderUserID:      uuid.New(),
+			SenderAccountID:   senderAccountID,
+			ReceiverUserID:    receiverUserID,
+			ReceiverAccountID: receiverAccountID,
+			Amount:            money.New(1000, money.EUR),
+			Status:            domain.PendingTransferAccepted,
+		},
+	)
+
+	assert.Equal(t, errMock, err)
+	accountRepo.AssertExpectations(t)
+}
+
+func TestRequiresAcceptance(t *testing.T) {
+	senderUserID := uuid.New()
+	receiverAccountID := uuid.New()
+
+	paid := domain.Account{
+		Transactions: []domain.Transaction{
+			{
+				Operation:         domain.OperationTransfer,
+				Amount:            money.New(100, money.EUR),
+				ReceiverAccountID: receiverAccountID,
+			},
+		},
+	}
+
+	policy := PendingTransferPolicy{
+		Threshold:      money.New(100000, money.EUR),
+		FirstTimePayee: true,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		policy   PendingTransferPolicy
+		req      TransferRequest
+		sender   domain.Account
+		receiver domain.Account
+		expected bool
+	}{
+		{"no policy", PendingTransferPolicy{}, TransferRequest{Amount: money.New(500000, money.EUR)}, domain.Account{}, domain.Account{ID: receiverAccountID}, false},
+		{"first time", policy, TransferRequest{Amount: money.New(100, money.EUR)}, domain.Account{}, domain.Account{ID: receiverAccountID}, true},
+		{"paid before", policy, TransferRequest{Amount: money.New(100, money.EUR)}, paid, domain.Account{ID: receiverAccountID}, false},
+		{"high value", policy, TransferRequest{Amount: money.New(100000, money.EUR)}, paid, domain.Account{ID: receiverAccountID}, true},
+		{"own account", policy, TransferRequest{SenderUserID: senderUserID, Amount: money.New(100, money.EUR)}, domain.Account{}, domain.Account{ID: receiverAccountID, Holders: map[string]domain.Role{senderUserID: domain.RoleOwner}}, false},
+		{"payment request", policy, TransferRequest{Amount: money.New(100000, money.EUR), requested: true}, domain.Account{}, domain.Account{ID: receiverAccountID}, false},
+		{"closing", policy, TransferRequest{Amount: money.New(100000, money.EUR), closing: true}, domain.Account{}, domain.Account{ID: receiverAccountID}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := svc{
+				pendingPolicy: tc.policy,
+			}
+
+			plan := transferPlan{
+				senderAccount:   tc.sender,
+				receiverAccount: tc.receiver,
+			}
+
+			assert.Equal(t, tc.expected, s.requiresAcceptance(tc.req, plan))
+		})
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2026, time.August, 13, 15, 4, 5, 0, time.UTC)
+
+	for _, tc := range []struct {
+		frequency domain.MandateFrequency
+		expected  time.Time
+	}{
+		{domain.MandateOneOff, time.Time{}},
+		{domain.MandateWeekly, time.Date(2026, time.August, 10, 0, 0, 0, 0, time.UTC)},
+		{domain.MandateMonthly, time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC)},
+		{domain.MandateQuarterly, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{domain.MandateYearly, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		assert.Equal(t, tc.expected, periodStart(tc.frequency, now), string(tc.frequency))
+	}
+
+	sunday := time.Date(2026, time.August, 16, 23, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, time.August, 10, 0, 0, 0, 0, time.UTC), periodStart(domain.MandateWeekly, sunday))
+}
+
+func TestCheckCollection_Err(t *testing.T) {
+	now := time.Date(2026, time.August, 13, 15, 4, 5, 0, time.UTC)
+
+	active := domain.Mandate{
+		MaxAmount: money.New(5000, money.EUR),
+		Frequency: domain.MandateMonthly,
+		Status:    domain.MandateActive,
+	}
+
+	revoked := active
+
+	revoked.Status = domain.MandateRevoked
+
+	collected := active
+
+	collected.Collections = []domain.Collection{
+		{CreatedAt: now.Add(-24 * time.Hour), Status: domain.CollectionCompleted},
+	}
+
+	for _, tc := range []struct {
+		mandate    domain.Mandate
+		amount     money.Money
+		errMessage string
+	}{
+		{revoked, money.New(1000, money.EUR), "mandate not active"},
+		{active, money.New(5001, money.EUR), "invalid amount, above mandate maximum"},
+		{collected, money.New(1000, money.EUR), "mandate already collected this period"},
+	} {
+		err := checkCollection(tc.mandate, CollectPaymentRequest{Amount: tc.amount}, now)
+
+		assert.Equal(t, errors.New(tc.errMessage), err, tc.errMessage)
+	}
+
+	// Failed collections and collections in an earlier period leave the
+	// period open.
+	collected.Collections = []domain.Collection{
+		{CreatedAt: now.Add(-24 * time.Hour), Status: domain.CollectionFailed},
+		{CreatedAt: now.AddDate(0, -1, 0), Status: domain.CollectionCompleted},
+	}
+
+	assert.Nil(t, checkCollection(collected, CollectPaymentRequest{Amount: money.New(5000, money.EUR)}, now))
+}
+
+func TestCheckLimit_CardPayments(t *testing.T) {
+	now := time.Now().UTC()
+
+	limit := domain.VelocityLimit{
+		Scope:     domain.LimitScopeAccount,
+		Operation: domain.OperationWithdraw,
+		Window:    24 * time.Hour,
+		MaxAmount: money.New(10000, money.EUR),
+	}
+
+	history := []domain.Transaction{
+		{
+			Timestamp: now.Add(-3 * time.Hour),
+			Operation: domain.OperationHold,
+			Amount:    money.New(5000, money.EUR),
+			Metadata:  map[string]string{cardAuthorisationMetadataKey: "1"},
+		},
+		{
+			Timestamp: now.Add(-2 * time.Hour),
+			Operation: domain.OperationHold,
+			Amount:    money.New(4000, money.EUR),
+			Metadata:  map[string]string{cardAuthorisationMetadataKey: "2"},
+		},
+		{
+			Timestamp: now.Add(-time.Hour),
+			Operation: domain.OperationHold,
+			Amount:    money.New(9000, money.EUR),
+			Metadata:  map[string]string{pendingTransferMetadataKey: "3"},
+		},
+	}
+
+	err := checkLimit(limit, history, money.New(2000, money.EUR), now)
+
+	assert.Equal(
+		t,
+		VelocityLimitError{
+			Limit:    limit,
+			ResetsAt: now.Add(21 * time.Hour),
+		},
+		err,
+	)
+
+	// Capturing the first authorisation for less than was held releases the
+	// hold and counts the card payment instead.
+	history = append(
+		history,
+		domain.Transaction{
+			Timestamp: now.Add(-time.Minute),
+			Operation: domain.OperationHoldRelease,
+			Amount:    money.New(5000, money.EUR),
+			Metadata:  map[string]string{cardAuthorisationMetadataKey: "1"},
+		},
+		domain.Transaction{
+			Timestamp: now.Add(-time.Minute),
+			Operation: domain.OperationCardPayment,
+			Amount:    money.New(3000, money.EUR),
+			Metadata:  map[string]string{cardAuthorisationMetadataKey: "1"},
+		},
+	)
+
+	err = checkLimit(limit, history, money.New(2000, money.EUR), now)
+
+	assert.Nil(t, err)
+}
+
+func TestCheckCardLimits(t *testing.T) {
+	now := time.Date(2026, time.August, 13, 15, 4, 5, 0, time.UTC)
+
+	issued := domain.Card{
+		Limits: domain.CardLimits{
+			PerTransaction: money.New(5000, money.EUR),
+			Daily:          money.New(10000, money.EUR),
+		},
+		Authorisations: []domain.CardAuthorisation{
+			{CreatedAt: now.Add(-24 * time.Hour), Amount: money.New(5000, money.EUR), Status: domain.AuthorisationHeld},
+			{CreatedAt: now.Add(-2 * time.Hour), Amount: money.New(5000, money.EUR), Status: domain.AuthorisationReversed},
+			{CreatedAt: now.Add(-time.Hour), Amount: money.New(5000, money.EUR), CapturedAmount: money.New(4000, money.EUR), Status: domain.AuthorisationCaptured},
+			{CreatedAt: now.Add(-time.Minute), Amount: money.New(2000, money.EUR), Status: domain.AuthorisationHeld},
+		},
+	}
+
+	assert.Nil(t, checkCardLimits(issued, money.New(4000, money.EUR), now))
+	assert.Equal(t, errors.New("card daily limit exceeded"), checkCardLimits(issued, money.New(4001, money.EUR), now))
+	assert.Equal(t, errors.New("card limit exceeded"), checkCardLimits(issued, money.New(5001, money.EUR), now))
+
+	issued.Limits = domain.CardLimits{}
+
+	assert.Nil(t, checkCardLimits(issued, money.New(100000, money.EUR), now))
+}
+
+func TestCardExpired(t *testing.T) {
+	issued := domain.Card{
+		ExpiryMonth: 12,
+		ExpiryYear:  2026,
+	}
+
+	assert.False(t, cardExpired(issued, time.Date(2026, time.December, 31, 23, 59, 59, 0, time.UTC)))
+	assert.True(t, cardExpired(issued, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestAmortise_Annuity(t *testing.T) {
+	start := time.Date(2026, time.January, 31, 10, 0, 0, 0, time.UTC)
+
+	instalments := amortise(
+		LoanTerms{
+			Principal:       money.New(100000, money.EUR),
+			InterestRateBps: 600,
+			TermMonths:      12,
+			Schedule:        domain.LoanAnnuity,
+		},
+		start,
+	)
+
+	assert.Len(t, instalments, 12)
+	assert.Equal(t, money.New(500, money.EUR), instalments[0].Interest)
+	assert.Equal(t, money.New(8107, money.EUR), instalments[0].Principal)
+	assert.Equal(t, time.Date(2026, time.February, 28, 10, 0, 0, 0, time.UTC), instalments[0].DueAt)
+	assert.Equal(t, time.Date(2027, time.January, 31, 10, 0, 0, 0, time.UTC), instalments[11].DueAt)
+
+	principal := money.New(0, money.EUR)
+
+	for _, instalment := range instalments[:11] {
+		amount, err := instalmentAmount(instalment)
+
+		assert.NoError(t, err)
+		assert.Equal(t, money.New(8607, money.EUR), amount)
+
+		principal, _ = principal.Add(instalment.Principal)
+	}
+
+	principal, _ = principal.Add(instalments[11].Principal)
+
+	assert.Equal(t, money.New(100000, money.EUR), principal)
+}
+
+func TestAmortise_AnnuityLongTerm(t *testing.T) {
+	instalments := amortise(
+		LoanTerms{
+			Principal:       money.New(25000001, money.EUR),
+			InterestRateBps: 475,
+			TermMonths:      360,
+			Schedule:        domain.LoanAnnuity,
+		},
+		time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+	)
+
+	assert.Len(t, instalments, 360)
+
+	first, err := instalmentAmount(instalments[0])
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(130412, money.EUR), first)
+
+	principal := money.New(0, money.EUR)
+
+	for _, instalment := range instalments {
+		amount, err := instalmentAmount(instalment)
+
+		assert.NoError(t, err)
+
+		if instalment.Number < 360 {
+			assert.Equal(t, first, amount)
+		}
+
+		principal, _ = principal.Add(instalment.Principal)
+	}
+
+	assert.Equal(t, money.New(25000001, money.EUR), principal)
+}
+
+func TestAmortise_Linear(t *testing.T) {
+	instalments := amortise(
+		LoanTerms{
+			Principal:       money.New(100000, money.EUR),
+			InterestRateBps: 1200,
+			TermMonths:      3,
+			Schedule:        domain.LoanLinear,
+		},
+		time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC),
+	)
+
+	assert.Len(t, instalments, 3)
+
+	for i, tc := range []struct {
+		principal int64
+		interest  int64
+	}{
+		{33333, 1000},
+		{33333, 667},
+		{33334, 333},
+	} {
+		assert.Equal(t, money.New(tc.principal, money.EUR), instalments[i].Principal)
+		assert.Equal(t, money.New(tc.interest, money.EUR), instalments[i].Interest)
+		assert.Equal(t, domain.InstalmentScheduled, instalments[i].Status)
+	}
+}
+
+func TestPayoffQuote(t *testing.T) {
+	start := time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC)
+
+	loan := domain.Loan{
+		CreatedAt:       start,
+		Principal:       money.New(120000, money.EUR),
+		InterestRateBps: 1200,
+		Instalments: amortise(
+			LoanTerms{
+				Principal:       money.New(120000, money.EUR),
+				InterestRateBps: 1200,
+				TermMonths:      3,
+				Schedule:        domain.LoanLinear,
+			},
+			start,
+		),
+	}
+
+	loan.Instalments[0].Status = domain.InstalmentOverdue
+	loan.Instalments[0].LateFee = money.New(1500, money.EUR)
+
+	quote, err := payoffQuote(loan, time.Date(2026, time.February, 20, 12, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(120000, money.EUR), quote.Principal)
+	assert.Equal(t, money.New(1200+263, money.EUR), quote.Interest)
+	assert.Equal(t, money.New(1500, money.EUR), quote.Fees)
+	assert.Equal(t, money.New(120000+1200+263+1500, money.EUR), quote.Amount)
+}
+
+func TestCollectLoanRepayments(t *testing.T) {
+	accountID := uuid.New()
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			accountID: {
+				ID:      accountID,
+				Balance: money.New(500, money.EUR),
+			},
+		},
+	)
+
+	loanID := uuid.New()
+
+	now := time.Now().UTC()
+
+	instalments := amortise(
+		LoanTerms{
+			Principal:       money.New(3000, money.EUR),
+			InterestRateBps: 0,
+			TermMonths:      3,
+			Schedule:        domain.LoanLinear,
+		},
+		now.AddDate(0, -2, -1),
+	)
+
+	loanRepo := loanrepo.New(
+		map[string]domain.Loan{
+			loanID: {
+				ID:          loanID,
+				Version:     1,
+				AccountID:   accountID,
+				Principal:   money.New(3000, money.EUR),
+				LateFee:     money.New(200, money.EUR),
+				Status:      domain.LoanActive,
+				Instalments: instalments,
+			},
+		},
+	)
+
+	svc := New(nil, accountRepo, nil, nil, WithLoanRepo(loanRepo))
+
+	res, err := svc.CollectLoanRepayments(CollectLoanRepaymentsRequest{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.CollectedLoanIDs)
+	assert.Equal(t, []string{loanID}, res.OverdueLoanIDs)
+
+	res, err = svc.CollectLoanRepayments(CollectLoanRepaymentsRequest{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.OverdueLoanIDs)
+
+	_, err = accountRepo.UpdateBalance(
+		accountrepo.UpdateBalanceRequest{
+			ID:     accountID,
+			Credit: money.New(4500, money.EUR),
+		},
+	)
+
+	assert.NoError(t, err)
+
+	res, err = svc.CollectLoanRepayments(CollectLoanRepaymentsRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{loanID}, res.CollectedLoanIDs)
+
+	loan, err := loanRepo.Read(loanrepo.ReadRequest{ID: loanID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.InstalmentPaid, loan.Instalments[0].Status)
+	assert.Equal(t, money.New(200, money.EUR), loan.Instalments[0].LateFee)
+	assert.Equal(t, domain.InstalmentPaid, loan.Instalments[1].Status)
+	assert.Equal(t, domain.InstalmentScheduled, loan.Instalments[2].Status)
+	assert.Len(t, loan.Repayments, 2)
+
+	account, err := accountRepo.Read(accountrepo.ReadRequest{ID: accountID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(5000-1200-1000, money.EUR), account.Balance)
+}
+
+func TestAutoSaveRules_Err(t *testing.T) {
+	tests := []struct {
+		rules []AutoSaveRule
+		err   error
+	}{
+		{
+			rules: []AutoSaveRule{{Type: domain.AutoSaveRoundUp}, {Type: domain.AutoSaveRoundUp}},
+			err:   errors.New("invalid auto-save rules, duplicate type"),
+		},
+		{
+			rules: []AutoSaveRule{{Type: domain.AutoSaveRoundUp, PercentageBps: 100}},
+			err:   errors.New("invalid round-up rule"),
+		},
+		{
+			rules: []AutoSaveRule{{Type: domain.AutoSaveDepositPercentage, PercentageBps: 10001}},
+			err:   errors.New("invalid deposit percentage rule"),
+		},
+		{
+			rules: []AutoSaveRule{{Type: domain.AutoSaveWeekly, Amount: money.New(500, money.USD)}},
+			err:   errors.New("invalid weekly rule"),
+		},
+		{
+			rules: []AutoSaveRule{{Type: "monthly"}},
+			err:   errors.New("invalid auto-save rule type"),
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := autoSaveRules(tt.rules, money.EUR, time.Now().UTC())
+
+		assert.Equal(t, tt.err, err)
+	}
+}
+
+func TestAutoSave(t *testing.T) {
+	accountID := uuid.New()
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			accountID: {
+				ID:      accountID,
+				Balance: money.New(1000, money.EUR),
+			},
+		},
+	)
+
+	roundUpID := uuid.New()
+	depositID := uuid.New()
+
+	potRepo := potrepo.New(
+		map[string]domain.Pot{
+			roundUpID: {
+				ID:        roundUpID,
+				Version:   1,
+				AccountID: accountID,
+				Balance:   money.New(0, money.EUR),
+				AutoSave:  []domain.AutoSaveRule{{Type: domain.AutoSaveRoundUp}},
+			},
+			depositID: {
+				ID:        depositID,
+				Version:   1,
+				AccountID: accountID,
+				Balance:   money.New(0, money.EUR),
+				AutoSave:  []domain.AutoSaveRule{{Type: domain.AutoSaveDepositPercentage, PercentageBps: 1000}},
+			},
+		},
+	)
+
+	s := svc{
+		accountRepo: accountRepo,
+		potRepo:     potRepo,
+	}
+
+	balance := s.autoSave(accountID, domain.AutoSaveRoundUp, money.New(230, money.EUR), money.New(1000, money.EUR))
+
+	assert.Equal(t, money.New(930, money.EUR), balance)
+
+	balance = s.autoSave(accountID, domain.AutoSaveDepositPercentage, money.New(2000, money.EUR), balance)
+
+	assert.Equal(t, money.New(730, money.EUR), balance)
+
+	roundUp, err := potRepo.Read(potrepo.ReadRequest{ID: roundUpID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(70, money.EUR), roundUp.Balance)
+
+	deposit, err := potRepo.Read(potrepo.ReadRequest{ID: depositID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(200, money.EUR), deposit.Balance)
+
+	account, err := accountRepo.Read(accountrepo.ReadRequest{ID: accountID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(730, money.EUR), account.Balance)
+	assert.Len(t, account.Transactions, 2)
+	assert.Equal(t, string(domain.AutoSaveRoundUp), account.Transactions[0].Metadata[autoSaveMetadataKey])
+
+	balance = s.autoSave(accountID, domain.AutoSaveDepositPercentage, money.New(10000, money.EUR), balance)
+
+	assert.Equal(t, money.New(730, money.EUR), balance)
+
+	deposit, err = potRepo.Read(potrepo.ReadRequest{ID: depositID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(200, money.EUR), deposit.Balance)
+}
+
+func TestRunWeeklyAutoSaves(t *testing.T) {
+	accountID := uuid.New()
+
+	accountRepo := accountrepo.New(
+		map[string]domain.Account{
+			accountID: {
+				ID:      accountID,
+				Balance: money.New(1500, money.EUR),
+			},
+		},
+	)
+
+	potID := uuid.New()
+
+	now := time.Now().UTC()
+
+	potRepo := potrepo.New(
+		map[string]domain.Pot{
+			potID: {
+				ID:        potID,
+				Version:   1,
+				AccountID: accountID,
+				Balance:   money.New(0, money.EUR),
+				AutoSave: []domain.AutoSaveRule{
+					{
+						Type:      domain.AutoSaveWeekly,
+						Amount:    money.New(1000, money.EUR),
+						NextRunAt: now.Add(-15 * 24 * time.Hour),
+					},
+				},
+			},
+		},
+	)
+
+	svc := New(nil, accountRepo, nil, nil, WithPotRepo(potRepo))
+
+	res, err := svc.RunWeeklyAutoSaves(RunWeeklyAutoSavesRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{potID}, res.SavedPotIDs)
+	assert.Empty(t, res.SkippedPotIDs)
+
+	pot, err := potRepo.Read(potrepo.ReadRequest{ID: potID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(1000, money.EUR), pot.Balance)
+	assert.True(t, pot.AutoSave[0].NextRunAt.After(now))
+	assert.True(t, pot.AutoSave[0].NextRunAt.Before(now.Add(autoSaveWeek)))
+
+	res, err = svc.RunWeeklyAutoSaves(RunWeeklyAutoSavesRequest{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.SavedPotIDs)
+
+	pot.AutoSave[0].NextRunAt = now
+
+	_, err = potRepo.Update(potrepo.UpdateRequest{Pot: pot})
+
+	assert.NoError(t, err)
+
+	res, err = svc.RunWeeklyAutoSaves(RunWeeklyAutoSavesRequest{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.SavedPotIDs)
+	assert.Equal(t, []string{potID}, res.SkippedPotIDs)
+
+	pot, err = potRepo.Read(potrepo.ReadRequest{ID: potID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(1000, money.EUR), pot.Balance)
+	assert.True(t, pot.AutoSave[0].NextRunAt.After(now))
+}
+
+func TestCategoryRule_Err(t *testing.T) {
+	tests := []struct {
+		rule CategoryRule
+		err  error
+	}{
+		{
+			rule: CategoryRule{Category: "Rent", DescriptionPattern: "rent"},
+			err:  errors.New("invalid category"),
+		},
+		{
+			rule: CategoryRule{Category: "rent"},
+			err:  errors.New("invalid category rule, no conditions"),
+		},
+		{
+			rule: CategoryRule{Category: "rent", Counterparty: "landlord"},
+			err:  errors.New("invalid category rule counterparty"),
+		},
+		{
+			rule: CategoryRule{Category: "rent", DescriptionPattern: "rent("},
+			err:  errors.New("invalid category rule description pattern"),
+		},
+		{
+			rule: CategoryRule{Category: "rent", MinAmount: money.New(-100, money.EUR)},
+			err:  errors.New("invalid category rule amount"),
+		},
+		{
+			rule: CategoryRule{Category: "rent", MinAmount: money.New(500, money.EUR), MaxAmount: money.New(100, money.EUR)},
+			err:  errors.New("invalid category rule amount range"),
+		},
+		{
+			rule: CategoryRule{Category: "rent", MinAmount: money.New(100, money.EUR), MaxAmount: money.New(500, money.USD)},
+			err:  errors.New("invalid category rule amount range"),
+		},
+	}
+
+	s := svc{}
+
+	for _, tt := range tests {
+		_, err := s.categoryRule(tt.rule)
+
+		assert.Equal(t, tt.err, err)
+	}
+}
+
+func TestCategoriser(t *testing.T) {
+	landlordID := uuid.New()
+	overriddenID := uuid.New()
+
+	c, err := newCategoriser(
+		domain.Categorisation{
+			Rules: []domain.CategoryRule{
+				{Category: "rent", CounterpartyAccountID: landlordID},
+				{Category: "big_shop", DescriptionPattern: "^albert", MinAmount: money.New(5000, money.EUR)},
+			},
+			Overrides: map[string]string{
+				overriddenID: "gifts",
+			},
+		},
+	)
+
+	assert.NoError(t, err)
+
+	tests := []struct {
+		transaction domain.Transaction
+		category    string
+	}{
+		{
+			transaction: domain.Transaction{Operation: domain.OperationTransfer, Amount: money.New(90000, money.EUR), ReceiverAccountID: landlordID},
+			category:    "rent",
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationTransfer, Amount: money.New(90000, money.EUR), ReceiverAccountID: uuid.New(), Description: "June rent"},
+			category:    categoryHousing,
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationTransfer, Amount: money.New(1000, money.EUR), SenderAccountID: uuid.New()},
+			category:    categoryIncome,
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationCardPayment, Amount: money.New(6000, money.EUR), Description: "Albert Heijn"},
+			category:    "big_shop",
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationCardPayment, Amount: money.New(6000, money.USD), Description: "Albert Heijn"},
+			category:    categoryShopping,
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationCardPayment, Amount: money.New(450, money.EUR), Description: "Corner Cafe"},
+			category:    categoryEatingOut,
+		},
+		{
+			transaction: domain.Transaction{ID: overriddenID, Operation: domain.OperationCardPayment, Amount: money.New(450, money.EUR), Description: "Corner Cafe"},
+			category:    "gifts",
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationWithdraw, Amount: money.New(2000, money.EUR)},
+			category:    categoryCash,
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationPotIn, Amount: money.New(70, money.EUR)},
+			category:    categorySavings,
+		},
+		{
+			transaction: domain.Transaction{Operation: domain.OperationHold, Amount: money.New(6000, money.EUR), Description: "Albert Heijn"},
+			category:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.category, c.category(tt.transaction))
+	}
+}
+
+func TestSpendingAnalytics(t *testing.T) {
+	friendID := uuid.New()
+
+	account := domain.Account{
+		ID: uuid.New(),
+		Transactions: []domain.Transaction{
+			{Timestamp: time.Date(2026, 2, 27, 0, 0, 0, 0, time.UTC), Operation: domain.OperationCardPayment, Amount: money.New(3000, money.EUR), Description: "Corner Cafe"},
+			{Timestamp: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Operation: domain.OperationDeposit, Amount: money.New(200000, money.EUR), Description: "Salary"},
+			{Timestamp: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Operation: domain.OperationCardPayment, Amount: money.New(1000, money.EUR), Description: "Corner Cafe"},
+			{Timestamp: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), Operation: domain.OperationHold, Amount: money.New(5000, money.EUR), Description: "Corner Cafe"},
+			{Timestamp: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), Operation: domain.OperationTransfer, Amount: money.New(4000, money.EUR), ReceiverAccountID: friendID},
+			{Timestamp: time.Date(2026, 4, 5, 0, 0, 0, 0, time.UTC), Operation: domain.OperationCardPayment, Amount: money.New(2500, money.EUR), Description: "corner cafe"},
+			{Timestamp: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), Operation: domain.OperationCardPayment, Amount: money.New(9900, money.EUR), Description: "Outside the range"},
+		},
+	}
+
+	res := spendingAnalytics(account, categoriser{}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), 1)
+
+	assert.Equal(t, "2026-03", res.From)
+	assert.Equal(t, "2026-04", res.To)
+	assert.Len(t, res.Months, 2)
+
+	march := res.Months[0]
+
+	assert.Equal(t, "2026-03", march.Month)
+	assert.Equal(t, money.New(5000, money.EUR), march.Spent)
+	assert.Equal(t, money.New(200000, money.EUR), march.Received)
+	assert.Equal(t, money.New(2000, money.EUR), march.SpentDelta)
+	assert.Equal(
+		t,
+		[]CategoryTotalResponse{
+			{Category: categoryTransfers, Spent: money.New(4000, money.EUR), SpentDelta: money.New(4000, money.EUR), Transactions: 1},
+			{Category: categoryEatingOut, Spent: money.New(1000, money.EUR), SpentDelta: money.New(-2000, money.EUR), Transactions: 1},
+			{Category: categoryIncome, Received: money.New(200000, money.EUR), Transactions: 1},
+		},
+		march.Categories,
+	)
+
+	april := res.Months[1]
+
+	assert.Equal(t, money.New(2500, money.EUR), april.Spent)
+	assert.Equal(t, money.New(-2500, money.EUR), april.SpentDelta)
+
+	assert.Equal(
+		t,
+		[]CounterpartyTotalResponse{
+			{AccountID: friendID, Spent: money.New(4000, money.EUR), Transactions: 1},
+		},
+		res.TopCounterparties,
+	)
+}
+
+func TestAnalyticsMonths(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	from, to, err := analyticsMonths("", "", now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = analyticsMonths("2024-11", "2026-10", now)
+
+	assert.NoError(t, err)
+
+	_, _, err = analyticsMonths("2024-10", "2026-10", now)
+
+	assert.Equal(t, errors.New("invalid month range"), err)
+
+	_, _, err = analyticsMonths("2026-10", "2026-09", now)
+
+	assert.Equal(t, errors.New("invalid month range"), err)
+
+	_, _, err = analyticsMonths("2026-13", "", now)
+
+	assert.Equal(t, errors.New("invalid from month"), err)
+}
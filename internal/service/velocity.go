@@ -0,0 +1,427 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+)
+
+var limitOperations = map[string]struct{}{
+	domain.OperationWithdraw:    {},
+	domain.OperationTransferOut: {},
+}
+
+// VelocityLimitError is returned when an operation would exceed a velocity
+// limit. ResetsAt is when enough of the window has rolled off for the same
+// operation to pass again, and is zero when the amount alone is over the cap.
+type VelocityLimitError struct {
+	Limit    domain.VelocityLimit
+	ResetsAt time.Time
+}
+
+func (e VelocityLimitError) Error() string {
+	limit := ""
+
+	if !e.Limit.MaxAmount.IsZero() {
+		limit = e.Limit.MaxAmount.String()
+	}
+
+	if e.Limit.MaxCount > 0 {
+		if limit != "" {
+			limit += " or "
+		}
+
+		limit += fmt.Sprintf("%d operations", e.Limit.MaxCount)
+	}
+
+	msg := fmt.Sprintf(
+		"velocity limit reached: %s of %s per %s per %s",
+		e.Limit.Operation,
+		limit,
+		e.Limit.Window,
+		e.Limit.Scope,
+	)
+
+	if e.ResetsAt.IsZero() {
+		return msg + ", amount exceeds limit"
+	}
+
+	return msg + ", resets at " + e.ResetsAt.Format(time.RFC3339)
+}
+
+func (s svc) PutTier(req PutTierRequest) (TierResponse, error) {
+	if req.TierID == "" {
+		return TierResponse{}, errors.New("invalid tier id")
+	}
+
+	limits := make([]domain.VelocityLimit, 0, len(req.Limits))
+
+	for _, terms := range req.Limits {
+		limit, err := limitFromTerms(terms)
+
+		if err != nil {
+			return TierResponse{}, err
+		}
+
+		limits = append(limits, limit)
+	}
+
+	tier, err := s.tierRepo.Put(
+		tierrepo.PutRequest{
+			Tier: domain.Tier{
+				ID:     req.TierID,
+				Limits: limits,
+			},
+		},
+	)
+
+	if err != nil {
+		return TierResponse{}, err
+	}
+
+	return tierResponse(tier), nil
+}
+
+func (s svc) Tier(req TierRequest) (TierResponse, error) {
+	if req.TierID == "" {
+		return TierResponse{}, errors.New("invalid tier id")
+	}
+
+	tier, err := s.tierRepo.Read(
+		tierrepo.ReadRequest{
+			ID: req.TierID,
+		},
+	)
+
+	if err != nil {
+		return TierResponse{}, err
+	}
+
+	return tierResponse(tier), nil
+}
+
+func (s svc) Tiers(req TiersRequest) (TiersResponse, error) {
+	tiers, err := s.tierRepo.List(tierrepo.ListRequest{})
+
+	if err != nil {
+		return TiersResponse{}, err
+	}
+
+	res := TiersResponse{
+		Tiers: make([]TierResponse, 0, len(tiers)),
+	}
+
+	for _, tier := range tiers {
+		res.Tiers = append(res.Tiers, tierResponse(tier))
+	}
+
+	return res, nil
+}
+
+func (s svc) UpdateUserTier(req UpdateUserTierRequest) error {
+	if !validID(req.UserID) {
+		return errors.New("invalid user id")
+	}
+
+	if req.TierID != "" {
+		_, err := s.tierRepo.Read(
+			tierrepo.ReadRequest{
+				ID: req.TierID,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.userRepo.UpdateTier(
+		userrepo.UpdateTierRequest{
+			ID:   req.UserID,
+			Tier: req.TierID,
+		},
+	)
+}
+
+// checkVelocity applies the limits of the user's tier to an outgoing
+// operation. User scoped limits sum the history of every account the user
+// holds, including what co-holders did on joint accounts.
+func (s svc) checkVelocity(user domain.User, account domain.Account, op string, amount money.Money, now time.Time) error {
+	if user.Tier == "" {
+		return nil
+	}
+
+	tier, err := s.tierRepo.Read(
+		tierrepo.ReadRequest{
+			ID: user.Tier,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	var userHistory []domain.Transaction
+
+	for _, limit := range tier.Limits {
+		if limit.Operation != op || !limitCurrency(limit, amount.Currency()) {
+			continue
+		}
+
+		history := account.Transactions
+
+		if limit.Scope == domain.LimitScopeUser {
+			if userHistory == nil {
+				userHistory, err = s.userHistory(user, account)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			history = userHistory
+		}
+
+		err = checkLimit(limit, history, amount, now)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s svc) userHistory(user domain.User, current domain.Account) ([]domain.Transaction, error) {
+	history := append([]domain.Transaction{}, current.Transactions...)
+
+	for _, accountID := range sortedAccountIDs(user.AccountIDs) {
+		if accountID == current.ID {
+			continue
+		}
+
+		account, err := s.accountRepo.Read(
+			accountrepo.ReadRequest{
+				ID: accountID,
+			},
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, account.Transactions...)
+	}
+
+	return history, nil
+}
+
+func checkLimit(limit domain.VelocityLimit, history []domain.Transaction, amount money.Money, now time.Time) error {
+	since := now.Add(-limit.Window)
+
+	released := releasedHolds(history)
+
+	window := []domain.Transaction{}
+
+	for _, transaction := range history {
+		if !transaction.Timestamp.After(since) || !limitedOperation(transaction, limit.Operation) {
+			continue
+		}
+
+		if !limitCurrency(limit, transaction.Amount.Currency()) {
+			continue
+		}
+
+		if _, exists := released[holdID(transaction)]; exists && transaction.Operation == domain.OperationHold {
+			continue
+		}
+
+		window = append(window, transaction)
+	}
+
+	sort.Slice(window, func(i, j int) bool {
+		return window[i].Timestamp.Before(window[j].Timestamp)
+	})
+
+	if limit.MaxCount > 0 && len(window) >= limit.MaxCount {
+		oldest := window[len(window)-limit.MaxCount]
+
+		return VelocityLimitError{
+			Limit:    limit,
+			ResetsAt: oldest.Timestamp.Add(limit.Window),
+		}
+	}
+
+	if limit.MaxAmount.IsZero() {
+		return nil
+	}
+
+	cmp, err := amount.Cmp(limit.MaxAmount)
+
+	if err != nil {
+		return err
+	}
+
+	if cmp > 0 {
+		return VelocityLimitError{
+			Limit: limit,
+		}
+	}
+
+	total := amount
+
+	for _, transaction := range window {
+		total, err = total.Add(transaction.Amount)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// Drop the oldest operations until the new one fits; the last one dropped
+	// decides when the limit resets.
+	for i := -1; i < len(window); i++ {
+		if i >= 0 {
+			total, err = total.Sub(window[i].Amount)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		cmp, err = total.Cmp(limit.MaxAmount)
+
+		if err != nil {
+			return err
+		}
+
+		if cmp > 0 {
+			continue
+		}
+
+		if i < 0 {
+			return nil
+		}
+
+		return VelocityLimitError{
+			Limit:    limit,
+			ResetsAt: window[i].Timestamp.Add(limit.Window),
+		}
+	}
+
+	return VelocityLimitError{
+		Limit: limit,
+	}
+}
+
+// limitCurrency reports whether a limit applies to amounts in the currency.
+// An amount cap only applies in its own currency, so a tier holds one limit
+// per currency; a count-only limit applies in every currency.
+func limitCurrency(limit domain.VelocityLimit, currency money.Currency) bool {
+	if limit.MaxAmount.IsZero() {
+		return true
+	}
+
+	return limit.MaxAmount.Currency() == currency
+}
+
+func limitedOperation(transaction domain.Transaction, op string) bool {
+	switch op {
+	case domain.OperationWithdraw:
+		if transaction.Operation == domain.OperationHold {
+			return transaction.Metadata[cardAuthorisationMetadataKey] != ""
+		}
+
+		return transaction.Operation == domain.OperationWithdraw || transaction.Operation == domain.OperationCardPayment
+	case domain.OperationTransferOut:
+		if transaction.Operation == domain.OperationHold {
+			return transaction.Metadata[pendingTransferMetadataKey] != ""
+		}
+
+		return transaction.Operation == domain.OperationTransfer && transaction.ReceiverAccountID != ""
+	default:
+		return false
+	}
+}
+
+// releasedHolds returns the pending transfers and card authorisations whose
+// hold was released, so the hold stops counting against the limits.
+func releasedHolds(history []domain.Transaction) map[string]struct{} {
+	released := make(map[string]struct{})
+
+	for _, transaction := range history {
+		if transaction.Operation == domain.OperationHoldRelease {
+			released[holdID(transaction)] = struct{}{}
+		}
+	}
+
+	return released
+}
+
+// holdID is the pending transfer or card authorisation a hold or its
+// release belongs to.
+func holdID(transaction domain.Transaction) string {
+	if id := transaction.Metadata[pendingTransferMetadataKey]; id != "" {
+		return id
+	}
+
+	return transaction.Metadata[cardAuthorisationMetadataKey]
+}
+
+func limitFromTerms(terms LimitTerms) (domain.VelocityLimit, error) {
+	if terms.Scope != domain.LimitScopeUser && terms.Scope != domain.LimitScopeAccount {
+		return domain.VelocityLimit{}, errors.New("invalid limit scope")
+	}
+
+	if _, exists := limitOperations[terms.Operation]; !exists {
+		return domain.VelocityLimit{}, errors.New("invalid limit operation")
+	}
+
+	window, err := time.ParseDuration(terms.Window)
+
+	if err != nil || window <= 0 {
+		return domain.VelocityLimit{}, errors.New("invalid limit window")
+	}
+
+	if terms.MaxAmount.IsNegative() || terms.MaxCount < 0 || (terms.MaxAmount.IsZero() && terms.MaxCount == 0) {
+		return domain.VelocityLimit{}, errors.New("invalid limit cap")
+	}
+
+	return domain.VelocityLimit{
+		Scope:     terms.Scope,
+		Operation: terms.Operation,
+		Window:    window,
+		MaxAmount: terms.MaxAmount,
+		MaxCount:  terms.MaxCount,
+	}, nil
+}
+
+func tierResponse(tier domain.Tier) TierResponse {
+	limits := make([]LimitTerms, 0, len(tier.Limits))
+
+	for _, limit := range tier.Limits {
+		limits = append(
+			limits,
+			LimitTerms{
+				Scope:     limit.Scope,
+				Operation: limit.Operation,
+				Window:    limit.Window.String(),
+				MaxAmount: limit.MaxAmount,
+				MaxCount:  limit.MaxCount,
+			},
+		)
+	}
+
+	return TierResponse{
+		TierID:    tier.ID,
+		UpdatedAt: tier.UpdatedAt,
+		Limits:    limits,
+	}
+}
@@ -1,48 +1,752 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/hetfdex/tiny-bank/internal/batch"
+	"github.com/hetfdex/tiny-bank/internal/card"
+	"github.com/hetfdex/tiny-bank/internal/cardhost"
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/events"
 	"github.com/hetfdex/tiny-bank/internal/handler"
+	"github.com/hetfdex/tiny-bank/internal/iban"
+	"github.com/hetfdex/tiny-bank/internal/iso8583"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/openapi"
+	"github.com/hetfdex/tiny-bank/internal/reconciler"
 	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/batchrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/cardrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/categoryrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/loanrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/mandaterepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/payeerepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/paymentrequestrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/pendingtransferrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/potrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
 	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/wal"
 	"github.com/hetfdex/tiny-bank/internal/service"
 )
 
+const (
+	defaultWALDir                = "data"
+	defaultWALSyncPolicy         = wal.SyncAlways
+	defaultWALBatchSize          = 64
+	defaultWALSyncInterval       = time.Second
+	shutdownTimeout              = 10 * time.Second
+	defaultSnapshotInterval      = 5 * time.Minute
+	defaultReconcileInterval     = time.Hour
+	defaultGracePeriod           = 30 * 24 * time.Hour
+	defaultCloseUsersInterval    = time.Hour
+	defaultTier                  = "standard"
+	defaultEventBufferSize       = 1024
+	defaultIdempotencyTTL        = 24 * time.Hour
+	defaultIBANCountry           = "NL"
+	defaultIBANBankCode          = "TINY"
+	defaultPayeeCoolingOff       = 24 * time.Hour
+	defaultPaymentRequestTTL     = 7 * 24 * time.Hour
+	defaultPendingTransferTTL    = 72 * time.Hour
+	defaultPendingExpiryInterval = time.Minute
+	defaultCardHoldTTL           = 7 * 24 * time.Hour
+	defaultHoldExpiryInterval    = time.Minute
+	defaultPendingThreshold      = 100000
+	defaultDirectDebitRefund     = 8 * 7 * 24 * time.Hour
+	defaultCardBIN               = "499999"
+	defaultISO8583Addr           = ":8583"
+	defaultLoanCollectInterval   = time.Hour
+	defaultAutoSaveInterval      = time.Hour
+)
+
+type repositories struct {
+	user            userrepo.Repo
+	account         accountrepo.Repo
+	product         productrepo.Repo
+	tier            tierrepo.Repo
+	payee           payeerepo.Repo
+	paymentRequest  paymentrequestrepo.Repo
+	pendingTransfer pendingtransferrepo.Repo
+	mandate         mandaterepo.Repo
+	card            cardrepo.Repo
+	loan            loanrepo.Repo
+	pot             potrepo.Repo
+	category        categoryrepo.Repo
+}
+
 func main() {
-	userRepo, accountRepo := configRepo()
+	walLog := configWAL()
+
+	repos := configRepo(walLog)
+
+	jobs := newScheduler()
+
+	jobs.schedule(envDuration("WAL_SNAPSHOT_INTERVAL", defaultSnapshotInterval), walLog.Snapshot)
+
+	hub := events.NewHub(envInt("EVENT_BUFFER_SIZE", defaultEventBufferSize))
+
+	repos.account = events.WrapAccountRepo(repos.account, hub)
+
+	rec := reconciler.New(repos.user, repos.account)
+
+	jobs.schedule(envDuration("RECONCILE_INTERVAL", defaultReconcileInterval), reconcile(rec))
+
+	svc := configSvc(repos)
+
+	seedProducts(svc)
+
+	seedTiers(svc)
+
+	jobs.schedule(envDuration("CLOSE_USERS_INTERVAL", defaultCloseUsersInterval), closeUsers(svc))
+
+	jobs.schedule(envDuration("PENDING_TRANSFER_EXPIRY_INTERVAL", defaultPendingExpiryInterval), expirePendingTransfers(svc))
+
+	jobs.schedule(envDuration("CARD_HOLD_EXPIRY_INTERVAL", defaultHoldExpiryInterval), expireCardAuthorisations(svc))
+
+	jobs.schedule(envDuration("LOAN_COLLECTION_INTERVAL", defaultLoanCollectInterval), collectLoanRepayments(svc))
+
+	jobs.schedule(envDuration("AUTO_SAVE_INTERVAL", defaultAutoSaveInterval), runWeeklyAutoSaves(svc))
+
+	cardHost := startCardHost(svc)
+
+	handlers := getHandlers(svc, rec, hub)
+
+	spec := handler.Spec(handlers...)
+
+	router := getRouter(spec, walLog)
+
+	configHandlers(router, append(handlers, handler.NewDocs(spec))...)
+
+	serve(router)
+
+	cardHost.Close()
+
+	jobs.stop()
+
+	err := walLog.Close()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func configWAL() wal.Log {
+	walLog, err := wal.Open(
+		wal.Config{
+			Dir:          envString("WAL_DIR", defaultWALDir),
+			SyncPolicy:   wal.SyncPolicy(envString("WAL_SYNC_POLICY", string(defaultWALSyncPolicy))),
+			BatchSize:    defaultWALBatchSize,
+			SyncInterval: envDuration("WAL_SYNC_INTERVAL", defaultWALSyncInterval),
+		},
+	)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return walLog
+}
+
+func configRepo(walLog wal.Log) repositories {
+	userRepo, err := userrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountRepo, err := accountrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	productRepo, err := productrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tierRepo, err := tierrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	payeeRepo, err := payeerepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paymentRequestRepo, err := paymentrequestrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pendingTransferRepo, err := pendingtransferrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mandateRepo, err := mandaterepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cardRepo, err := cardrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	loanRepo, err := loanrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	potRepo, err := potrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	categoryRepo, err := categoryrepo.NewDurable(walLog)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return repositories{
+		user:            userRepo,
+		account:         accountRepo,
+		product:         productRepo,
+		tier:            tierRepo,
+		payee:           payeeRepo,
+		paymentRequest:  paymentRequestRepo,
+		pendingTransfer: pendingTransferRepo,
+		mandate:         mandateRepo,
+		card:            cardRepo,
+		loan:            loanRepo,
+		pot:             potRepo,
+		category:        categoryRepo,
+	}
+}
+
+func reconcile(rec reconciler.Reconciler) func() error {
+	return func() error {
+		report, err := rec.Run()
+
+		if err != nil {
+			return err
+		}
+
+		if report.Consistent {
+			return nil
+		}
+
+		raw, err := json.Marshal(report)
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("reconciler: discrepancies found: %s", raw)
+
+		return nil
+	}
+}
+
+// scheduler runs jobs on their intervals until stopped. stop waits for the
+// jobs still running, so none writes to the WAL after it is closed.
+type scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+}
+
+func newScheduler() scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return scheduler{
+		ctx:    ctx,
+		cancel: cancel,
+		wg:     &sync.WaitGroup{},
+	}
+}
+
+func (s scheduler) schedule(interval time.Duration, job func() error) {
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				err := job()
+
+				if err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}()
+}
+
+func (s scheduler) stop() {
+	s.cancel()
+
+	s.wg.Wait()
+}
+
+func configSvc(repos repositories) service.Service {
+	issuer, err := iban.NewIssuer(envString("IBAN_COUNTRY", defaultIBANCountry), envString("IBAN_BANK_CODE", defaultIBANBankCode))
+
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	svc := configSvc(userRepo, accountRepo)
+	cardIssuer, err := card.NewIssuer(envString("CARD_BIN", defaultCardBIN))
 
-	router := getRouter()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	configHandlers(router, svc)
+	opts := []service.Option{
+		service.WithGracePeriod(envDuration("GRACE_PERIOD", defaultGracePeriod)),
+		service.WithDefaultTier(envString("DEFAULT_TIER", defaultTier)),
+		service.WithIBANIssuer(issuer),
+		service.WithPayeeRepo(repos.payee),
+		service.WithPayeeCoolingOff(envDuration("PAYEE_COOLING_OFF", defaultPayeeCoolingOff)),
+		service.WithPaymentRequestRepo(repos.paymentRequest),
+		service.WithPaymentRequestTTL(envDuration("PAYMENT_REQUEST_TTL", defaultPaymentRequestTTL)),
+		service.WithPendingTransferRepo(repos.pendingTransfer),
+		service.WithPendingTransferTTL(envDuration("PENDING_TRANSFER_TTL", defaultPendingTransferTTL)),
+		service.WithPendingTransferPolicy(
+			service.PendingTransferPolicy{
+				Threshold:      money.New(int64(envInt("PENDING_TRANSFER_THRESHOLD", defaultPendingThreshold)), money.EUR),
+				FirstTimePayee: envBool("PENDING_FIRST_TIME_PAYEE", true),
+			},
+		),
+		service.WithMandateRepo(repos.mandate),
+		service.WithRefundPeriod(envDuration("DIRECT_DEBIT_REFUND_PERIOD", defaultDirectDebitRefund)),
+		service.WithCardRepo(repos.card),
+		service.WithCardIssuer(cardIssuer),
+		service.WithCardHoldTTL(envDuration("CARD_HOLD_TTL", defaultCardHoldTTL)),
+		service.WithMerchantKeys(merchantKeys()),
+		service.WithLoanRepo(repos.loan),
+		service.WithPotRepo(repos.pot),
+		service.WithCategoryRepo(repos.category),
+	}
 
-	startServer(router)
+	cardSecret := os.Getenv("CARD_SECRET")
+
+	if cardSecret == "" {
+		log.Println("CARD_SECRET not set, cards issued now stop authorising after a restart")
+	} else {
+		opts = append(opts, service.WithCardSecret([]byte(cardSecret)))
+	}
+
+	return service.New(repos.user, repos.account, repos.product, repos.tier, opts...)
+}
+
+// merchantKeys reads MERCHANT_KEYS, a comma separated list of merchant=key
+// pairs.
+func merchantKeys() map[string]string {
+	keys := map[string]string{}
+
+	for _, pair := range strings.Split(os.Getenv("MERCHANT_KEYS"), ",") {
+		merchant, key, ok := strings.Cut(pair, "=")
+
+		merchant = strings.TrimSpace(merchant)
+		key = strings.TrimSpace(key)
+
+		if !ok || merchant == "" || key == "" {
+			continue
+		}
+
+		keys[merchant] = key
+	}
+
+	return keys
+}
+
+func seedProducts(svc service.Service) {
+	products := []service.CreateProductRequest{
+		{
+			ProductID: "checking",
+			ProductTerms: service.ProductTerms{
+				Name: "Checking",
+				Type: domain.ProductChecking,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+					domain.OperationTransferIn,
+					domain.OperationTransferOut,
+				},
+			},
+		},
+		{
+			ProductID: "savings",
+			ProductTerms: service.ProductTerms{
+				Name: "Savings",
+				Type: domain.ProductSavings,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+					domain.OperationTransferIn,
+					domain.OperationTransferOut,
+				},
+				MonthlyWithdrawalLimit: 3,
+				InterestRateBps:        150,
+			},
+		},
+		{
+			ProductID: "term-deposit-12m",
+			ProductTerms: service.ProductTerms{
+				Name: "Term deposit 12 months",
+				Type: domain.ProductTermDeposit,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+					domain.OperationTransferOut,
+				},
+				InterestRateBps:      350,
+				TermDays:             365,
+				EarlyBreakPenaltyBps: 200,
+			},
+		},
+	}
+
+	for _, product := range products {
+		_, err := svc.Product(
+			service.ProductRequest{
+				ProductID: product.ProductID,
+			},
+		)
+
+		if err == nil {
+			continue
+		}
+
+		_, err = svc.CreateProduct(product)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// seedTiers creates the default velocity limit tiers on first start. Tiers
+// edited through the admin API are left alone.
+func seedTiers(svc service.Service) {
+	tiers := []service.PutTierRequest{
+		{
+			TierID: "standard",
+			Limits: []service.LimitTerms{
+				{
+					Scope:     domain.LimitScopeUser,
+					Operation: domain.OperationWithdraw,
+					Window:    "24h",
+					MaxAmount: money.New(100000, money.EUR),
+				},
+				{
+					Scope:     domain.LimitScopeAccount,
+					Operation: domain.OperationTransferOut,
+					Window:    "1h",
+					MaxCount:  20,
+				},
+				{
+					Scope:     domain.LimitScopeUser,
+					Operation: domain.OperationTransferOut,
+					Window:    "720h",
+					MaxAmount: money.New(1000000, money.EUR),
+				},
+			},
+		},
+		{
+			TierID: "premium",
+			Limits: []service.LimitTerms{
+				{
+					Scope:     domain.LimitScopeUser,
+					Operation: domain.OperationWithdraw,
+					Window:    "24h",
+					MaxAmount: money.New(500000, money.EUR),
+				},
+				{
+					Scope:     domain.LimitScopeUser,
+					Operation: domain.OperationTransferOut,
+					Window:    "720h",
+					MaxAmount: money.New(10000000, money.EUR),
+				},
+			},
+		},
+	}
+
+	for _, tier := range tiers {
+		_, err := svc.Tier(
+			service.TierRequest{
+				TierID: tier.TierID,
+			},
+		)
+
+		if err == nil {
+			continue
+		}
+
+		_, err = svc.PutTier(tier)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func closeUsers(svc service.Service) func() error {
+	return func() error {
+		res, err := svc.CloseUsers(service.CloseUsersRequest{})
+
+		if err != nil {
+			return err
+		}
+
+		for _, userID := range res.UserIDs {
+			log.Printf("offboarding: user %s closed", userID)
+		}
+
+		return nil
+	}
+}
+
+func expirePendingTransfers(svc service.Service) func() error {
+	return func() error {
+		res, err := svc.ExpirePendingTransfers(service.ExpirePendingTransfersRequest{})
+
+		if err != nil {
+			return err
+		}
+
+		for _, pendingTransferID := range res.PendingTransferIDs {
+			log.Printf("pending transfers: transfer %s expired and refunded", pendingTransferID)
+		}
+
+		return nil
+	}
+}
+
+func expireCardAuthorisations(svc service.Service) func() error {
+	return func() error {
+		res, err := svc.ExpireCardAuthorisations(service.ExpireCardAuthorisationsRequest{})
+
+		if err != nil {
+			return err
+		}
+
+		for _, authorisationID := range res.AuthorisationIDs {
+			log.Printf("cards: authorisation %s expired and released", authorisationID)
+		}
+
+		return nil
+	}
+}
+
+func collectLoanRepayments(svc service.Service) func() error {
+	return func() error {
+		res, err := svc.CollectLoanRepayments(service.CollectLoanRepaymentsRequest{})
+
+		if err != nil {
+			return err
+		}
+
+		for _, loanID := range res.CollectedLoanIDs {
+			log.Printf("loans: instalments of loan %s collected", loanID)
+		}
+
+		for _, loanID := range res.OverdueLoanIDs {
+			log.Printf("loans: loan %s in arrears", loanID)
+		}
+
+		return nil
+	}
+}
+
+func runWeeklyAutoSaves(svc service.Service) func() error {
+	return func() error {
+		res, err := svc.RunWeeklyAutoSaves(service.RunWeeklyAutoSavesRequest{})
+
+		for _, potID := range res.SavedPotIDs {
+			log.Printf("pots: weekly amount saved into pot %s", potID)
+		}
+
+		for _, potID := range res.SkippedPotIDs {
+			log.Printf("pots: weekly amount for pot %s skipped, not covered", potID)
+		}
+
+		return err
+	}
+}
+
+// startCardHost listens for ISO 8583 card authorisations. The field layout
+// is read from the JSON file named by ISO8583_SPEC, if set.
+func startCardHost(svc service.Service) net.Listener {
+	spec := iso8583.DefaultSpec()
+
+	path := os.Getenv("ISO8583_SPEC")
+
+	if path != "" {
+		file, err := os.Open(path)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		spec, err = iso8583.LoadSpec(file)
+
+		file.Close()
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", envString("ISO8583_ADDR", defaultISO8583Addr))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		log.Println(cardhost.New(svc, spec, merchantKeys()).Serve(listener))
+	}()
+
+	return listener
 }
 
-func configRepo() (userrepo.Repo, accountrepo.Repo) {
-	return userrepo.New(make(map[string]domain.User)),
-		accountrepo.New(make(map[string]domain.Account))
+func getRouter(spec *openapi.Spec, walLog wal.Log) *gin.Engine {
+	router := gin.Default()
+
+	idempotency, err := handler.DurableIdempotency(walLog, envDuration("IDEMPOTENCY_TTL", defaultIdempotencyTTL))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	router.Use(idempotency)
+
+	router.Use(handler.Validation(spec))
+
+	return router
 }
 
-func configSvc(
-	userRepo userrepo.Repo,
-	accountRepo accountrepo.Repo,
-) service.Service {
-	return service.New(userRepo, accountRepo)
+func getHandlers(svc service.Service, rec reconciler.Reconciler, hub events.Hub) []handler.Handler {
+	return []handler.Handler{
+		handler.New(svc),
+		handler.NewV2(svc),
+		handler.NewBatch(batch.New(svc, batchrepo.New(map[string]domain.Batch{}))),
+		handler.NewReconciler(rec),
+		handler.NewStream(svc, hub),
+	}
 }
 
-func getRouter() *gin.Engine {
-	return gin.Default()
+func configHandlers(router *gin.Engine, handlers ...handler.Handler) {
+	for _, hdl := range handlers {
+		hdl.ConfigHandlers(router)
+	}
 }
 
-func configHandlers(router *gin.Engine, svc service.Service) {
-	hdl := handler.New(svc)
+// serve runs the API until SIGINT or SIGTERM, then lets requests in flight
+// finish so their writes reach the WAL before it is closed.
+func serve(router *gin.Engine) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	defer stop()
 
-	hdl.ConfigHandlers(router)
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: router,
+	}
+
+	go func() {
+		err := server.ListenAndServe()
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func envString(key string, fallback string) string {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback
+	}
+
+	return value
 }
 
-func startServer(router *gin.Engine) {
-	router.Run(":8080")
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func envBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+
+	if err != nil {
+		return fallback
+	}
+
+	return value
 }
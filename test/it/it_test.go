@@ -2,10 +2,24 @@ package it
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hetfdex/tiny-bank/internal/batch"
+	"github.com/hetfdex/tiny-bank/internal/cardhost"
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/events"
+	"github.com/hetfdex/tiny-bank/internal/iban"
+	"github.com/hetfdex/tiny-bank/internal/iso8583"
+	"github.com/hetfdex/tiny-bank/internal/money"
+	"github.com/hetfdex/tiny-bank/internal/reconciler"
 	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/batchrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
 	"github.com/hetfdex/tiny-bank/internal/repository/userrepo"
 	"github.com/hetfdex/tiny-bank/internal/service"
 	"github.com/stretchr/testify/suite"
@@ -13,20 +27,58 @@ import (
 
 type IntegrationTestSuite struct {
 	suite.Suite
-	svc service.Service
+	svc     service.Service
+	batcher batch.Batcher
+	rec     reconciler.Reconciler
+	hub     events.Hub
 }
 
 func TestIntegrationTestSuite(t *testing.T) {
 	suite.Run(t, new(IntegrationTestSuite))
 }
 
+var merchantKeys = map[string]string{
+	"Grocer": "grocer-key",
+}
+
 func (s *IntegrationTestSuite) SetupSuite() {
 	userRepo := userrepo.New(make(map[string]domain.User))
 	accountRepo := accountrepo.New(make(map[string]domain.Account))
+	productRepo := productrepo.New(make(map[string][]domain.Product))
+	tierRepo := tierrepo.New(make(map[string]domain.Tier))
+
+	hub := events.NewHub(64)
+
+	svc := service.New(
+		userRepo,
+		events.WrapAccountRepo(accountRepo, hub),
+		productRepo,
+		tierRepo,
+		service.WithMerchantKeys(merchantKeys),
+	)
+
+	_, err := svc.CreateProduct(
+		service.CreateProductRequest{
+			ProductID: "checking",
+			ProductTerms: service.ProductTerms{
+				Name: "Checking",
+				Type: domain.ProductChecking,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+					domain.OperationTransferIn,
+					domain.OperationTransferOut,
+				},
+			},
+		},
+	)
 
-	svc := service.New(userRepo, accountRepo)
+	s.Require().Nil(err)
 
 	s.svc = svc
+	s.batcher = batch.New(svc, batchrepo.New(make(map[string]domain.Batch)))
+	s.rec = reconciler.New(userRepo, accountRepo)
+	s.hub = hub
 }
 
 func (s *IntegrationTestSuite) TestCreateUser() {
@@ -49,8 +101,12 @@ func (s *IntegrationTestSuite) TestCreateAccount() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createUserRes.UserID)
+
 	createAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createUserRes),
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
 	)
 
 	s.Assert().NotEmpty(createAccountRes.AccountID)
@@ -66,27 +122,412 @@ func (s *IntegrationTestSuite) TestDeactivate() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createUserRes.UserID)
+
+	createAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	deactivateRes, err := s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(createAccountRes.AccountID, deactivateRes.Accounts[0].AccountID)
+
+	_, err = s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(1000, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("suspended user cannot deposit"), err)
+
+	err = s.svc.ReactivateUser(
+		service.ReactivateUserRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	kycRes, err := s.svc.KYC(
+		service.KYCRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(domain.UserVerified, kycRes.Status)
+}
+
+func (s *IntegrationTestSuite) TestDeactivatePayout() {
+	createUserRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(createUserRes.UserID)
+
 	createAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createUserRes),
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(15, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	payoutUserRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "mary",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(payoutUserRes.UserID)
+
+	payoutAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID: payoutUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
 
-	err = s.svc.DeactivateUser(
-		service.DeactivateUserRequest(createUserRes),
+	_, err = s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("non-zero balance requires payout account"), err)
+
+	deactivateRes, err := s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID:          createUserRes.UserID,
+			Reason:          "moving abroad",
+			PayoutUserID:    payoutUserRes.UserID,
+			PayoutAccountID: payoutAccountRes.AccountID,
+		},
 	)
 
 	s.Assert().Nil(err)
+	s.Assert().Equal("moving abroad", deactivateRes.Reason)
+	s.Assert().Equal(money.New(15, money.EUR), deactivateRes.Accounts[0].ClosingBalance)
+	s.Assert().Equal(money.New(15, money.EUR), deactivateRes.Accounts[0].SweptAmount)
 
 	balanceRes, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    payoutUserRes.UserID,
+			AccountID: payoutAccountRes.AccountID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(service.BalanceResponse{Balance: money.New(15, money.EUR)}, balanceRes)
+
+	err = s.svc.ReactivateUser(
+		service.ReactivateUserRequest(createUserRes),
+	)
+
+	s.Assert().Nil(err)
+
+	balanceRes, err = s.svc.Balance(
 		service.BalanceRequest{
 			UserID:    createUserRes.UserID,
 			AccountID: createAccountRes.AccountID,
 		},
 	)
 
-	s.Assert().Equal(service.BalanceResponse{}, balanceRes)
-	s.Assert().Equal(errors.New("user not active"), err)
+	s.Assert().Nil(err)
+	s.Assert().Equal(service.BalanceResponse{Balance: money.New(0, money.EUR)}, balanceRes)
+}
+
+func (s *IntegrationTestSuite) TestDeactivateReleasesHolds() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	payoutUserID, payoutAccountID := s.fundedAccount("mary", money.Money{})
+	payerUserID, payerAccountID := s.fundedAccount("ann", money.New(5000, money.EUR))
+
+	outgoing, err := s.svc.CreatePendingTransfer(
+		service.CreatePendingTransferRequest{
+			TransferRequest: service.TransferRequest{
+				SenderUserID:      userID,
+				SenderAccountID:   accountID,
+				ReceiverUserID:    payoutUserID,
+				ReceiverAccountID: payoutAccountID,
+				Amount:            money.New(3000, money.EUR),
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	incoming, err := s.svc.CreatePendingTransfer(
+		service.CreatePendingTransferRequest{
+			TransferRequest: service.TransferRequest{
+				SenderUserID:      payerUserID,
+				SenderAccountID:   payerAccountID,
+				ReceiverUserID:    userID,
+				ReceiverAccountID: accountID,
+				Amount:            money.New(1000, money.EUR),
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	issueRes, err := s.svc.IssueCard(
+		service.IssueCardRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	_, err = s.svc.AuthoriseCard(
+		service.AuthoriseCardRequest{
+			PAN:         issueRes.PAN,
+			ExpiryMonth: issueRes.ExpiryMonth,
+			ExpiryYear:  issueRes.ExpiryYear,
+			CVV:         issueRes.CVV,
+			Amount:      money.New(2000, money.EUR),
+			Merchant:    "Grocer",
+			MerchantKey: "grocer-key",
+		},
+	)
+
+	s.Require().Nil(err)
+
+	s.assertBalance(userID, accountID, money.New(5000, money.EUR))
+
+	_, err = s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID: userID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("non-zero balance requires payout account"), err)
+
+	deactivateRes, err := s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID:          userID,
+			PayoutUserID:    payoutUserID,
+			PayoutAccountID: payoutAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(money.New(10000, money.EUR), deactivateRes.Accounts[0].SweptAmount)
+
+	s.assertBalance(payoutUserID, payoutAccountID, money.New(10000, money.EUR))
+	s.assertBalance(payerUserID, payerAccountID, money.New(5000, money.EUR))
+
+	outgoingRes, err := s.svc.PendingTransfer(
+		service.PendingTransferRequest{
+			UserID:            payoutUserID,
+			PendingTransferID: outgoing.PendingTransferID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PendingTransferCancelled, outgoingRes.Status)
+
+	incomingRes, err := s.svc.PendingTransfer(
+		service.PendingTransferRequest{
+			UserID:            payerUserID,
+			PendingTransferID: incoming.PendingTransferID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PendingTransferDeclined, incomingRes.Status)
+}
+
+func (s *IntegrationTestSuite) TestJointAccount() {
+	ownerRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(ownerRes.UserID)
+
+	coOwnerRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "mary",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(coOwnerRes.UserID)
+
+	viewerRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "bob",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(viewerRes.UserID)
+
+	createAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID: ownerRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	err = s.svc.AddHolder(
+		service.AddHolderRequest{
+			UserID:       ownerRes.UserID,
+			AccountID:    createAccountRes.AccountID,
+			HolderUserID: coOwnerRes.UserID,
+			Role:         domain.RoleCoOwner,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	err = s.svc.AddHolder(
+		service.AddHolderRequest{
+			UserID:       coOwnerRes.UserID,
+			AccountID:    createAccountRes.AccountID,
+			HolderUserID: viewerRes.UserID,
+			Role:         domain.RoleViewer,
+		},
+	)
+
+	s.Assert().Equal(errors.New("insufficient role"), err)
+
+	err = s.svc.AddHolder(
+		service.AddHolderRequest{
+			UserID:       ownerRes.UserID,
+			AccountID:    createAccountRes.AccountID,
+			HolderUserID: viewerRes.UserID,
+			Role:         domain.RoleViewer,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    coOwnerRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(100, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    viewerRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(10, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("insufficient role"), err)
+
+	err = s.svc.UpdateAccountRules(
+		service.UpdateAccountRulesRequest{
+			UserID:                ownerRes.UserID,
+			AccountID:             createAccountRes.AccountID,
+			DualApprovalThreshold: money.New(50, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    coOwnerRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(60, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("approval required"), err)
+
+	withdrawRes, err := s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:         coOwnerRes.UserID,
+			AccountID:      createAccountRes.AccountID,
+			Amount:         money.New(60, money.EUR),
+			ApproverUserID: ownerRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(money.New(40, money.EUR), withdrawRes.Balance)
+
+	balanceRes, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    viewerRes.UserID,
+			AccountID: createAccountRes.AccountID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(service.BalanceResponse{Balance: money.New(40, money.EUR)}, balanceRes)
+
+	err = s.svc.RemoveHolder(
+		service.RemoveHolderRequest{
+			UserID:       ownerRes.UserID,
+			AccountID:    createAccountRes.AccountID,
+			HolderUserID: ownerRes.UserID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("last owner"), err)
+
+	err = s.svc.RemoveHolder(
+		service.RemoveHolderRequest{
+			UserID:       viewerRes.UserID,
+			AccountID:    createAccountRes.AccountID,
+			HolderUserID: viewerRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    viewerRes.UserID,
+			AccountID: createAccountRes.AccountID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("unauthorized account id"), err)
 }
 
 func (s *IntegrationTestSuite) TestDeposit() {
@@ -98,8 +539,12 @@ func (s *IntegrationTestSuite) TestDeposit() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createUserRes.UserID)
+
 	createAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createUserRes),
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -108,11 +553,11 @@ func (s *IntegrationTestSuite) TestDeposit() {
 		service.DepositRequest{
 			UserID:    createUserRes.UserID,
 			AccountID: createAccountRes.AccountID,
-			Amount:    10,
+			Amount:    money.New(10, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.DepositResponse{Balance: 10}, depositRes)
+	s.Assert().Equal(money.New(10, money.EUR), depositRes.Balance)
 	s.Assert().Nil(err)
 }
 
@@ -125,8 +570,12 @@ func (s *IntegrationTestSuite) TestWithdraw() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createUserRes.UserID)
+
 	createAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createUserRes),
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -135,22 +584,22 @@ func (s *IntegrationTestSuite) TestWithdraw() {
 		service.DepositRequest{
 			UserID:    createUserRes.UserID,
 			AccountID: createAccountRes.AccountID,
-			Amount:    20,
+			Amount:    money.New(20, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.DepositResponse{Balance: 20}, depositRes)
+	s.Assert().Equal(money.New(20, money.EUR), depositRes.Balance)
 	s.Assert().Nil(err)
 
 	withdrawRes, err := s.svc.Withdraw(
 		service.WithdrawRequest{
 			UserID:    createUserRes.UserID,
 			AccountID: createAccountRes.AccountID,
-			Amount:    10,
+			Amount:    money.New(10, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.WithdrawResponse{Balance: 10}, withdrawRes)
+	s.Assert().Equal(money.New(10, money.EUR), withdrawRes.Balance)
 	s.Assert().Nil(err)
 }
 
@@ -163,8 +612,12 @@ func (s *IntegrationTestSuite) TestTransfer() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createJoeUserRes.UserID)
+
 	createJoeAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createJoeUserRes),
+		service.CreateAccountRequest{
+			UserID: createJoeUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -177,8 +630,12 @@ func (s *IntegrationTestSuite) TestTransfer() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createMaryUserRes.UserID)
+
 	createMaryAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createMaryUserRes),
+		service.CreateAccountRequest{
+			UserID: createMaryUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -187,11 +644,11 @@ func (s *IntegrationTestSuite) TestTransfer() {
 		service.DepositRequest{
 			UserID:    createJoeUserRes.UserID,
 			AccountID: createJoeAccountRes.AccountID,
-			Amount:    20,
+			Amount:    money.New(20, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.DepositResponse{Balance: 20}, depositRes)
+	s.Assert().Equal(money.New(20, money.EUR), depositRes.Balance)
 	s.Assert().Nil(err)
 
 	transferRes, err := s.svc.Transfer(
@@ -200,11 +657,11 @@ func (s *IntegrationTestSuite) TestTransfer() {
 			ReceiverUserID:    createMaryUserRes.UserID,
 			SenderAccountID:   createJoeAccountRes.AccountID,
 			ReceiverAccountID: createMaryAccountRes.AccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.TransferResponse{Balance: 10}, transferRes)
+	s.Assert().Equal(money.New(10, money.EUR), transferRes.Balance)
 	s.Assert().Nil(err)
 
 	balanceMaryRes, err := s.svc.Balance(
@@ -214,7 +671,7 @@ func (s *IntegrationTestSuite) TestTransfer() {
 		},
 	)
 
-	s.Assert().Equal(service.BalanceResponse{Balance: 10}, balanceMaryRes)
+	s.Assert().Equal(service.BalanceResponse{Balance: money.New(10, money.EUR)}, balanceMaryRes)
 	s.Assert().Nil(err)
 }
 
@@ -227,8 +684,12 @@ func (s *IntegrationTestSuite) TestBalance() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createUserRes.UserID)
+
 	createAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createUserRes),
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -240,7 +701,7 @@ func (s *IntegrationTestSuite) TestBalance() {
 		},
 	)
 
-	s.Assert().Equal(service.BalanceResponse{Balance: 0}, balanceRes)
+	s.Assert().Equal(service.BalanceResponse{Balance: money.New(0, money.EUR)}, balanceRes)
 	s.Assert().Nil(err)
 }
 
@@ -253,8 +714,12 @@ func (s *IntegrationTestSuite) TestTransactions() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createJoeUserRes.UserID)
+
 	createJoeAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createJoeUserRes),
+		service.CreateAccountRequest{
+			UserID: createJoeUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -267,8 +732,12 @@ func (s *IntegrationTestSuite) TestTransactions() {
 
 	s.Assert().Nil(err)
 
+	s.verify(createMaryUserRes.UserID)
+
 	createMaryAccountRes, err := s.svc.CreateAccount(
-		service.CreateAccountRequest(createMaryUserRes),
+		service.CreateAccountRequest{
+			UserID: createMaryUserRes.UserID,
+		},
 	)
 
 	s.Assert().Nil(err)
@@ -277,11 +746,11 @@ func (s *IntegrationTestSuite) TestTransactions() {
 		service.DepositRequest{
 			UserID:    createJoeUserRes.UserID,
 			AccountID: createJoeAccountRes.AccountID,
-			Amount:    20,
+			Amount:    money.New(20, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.DepositResponse{Balance: 20}, depositRes)
+	s.Assert().Equal(money.New(20, money.EUR), depositRes.Balance)
 	s.Assert().Nil(err)
 
 	transferRes, err := s.svc.Transfer(
@@ -290,11 +759,11 @@ func (s *IntegrationTestSuite) TestTransactions() {
 			ReceiverUserID:    createMaryUserRes.UserID,
 			SenderAccountID:   createJoeAccountRes.AccountID,
 			ReceiverAccountID: createMaryAccountRes.AccountID,
-			Amount:            10,
+			Amount:            money.New(10, money.EUR),
 		},
 	)
 
-	s.Assert().Equal(service.TransferResponse{Balance: 10}, transferRes)
+	s.Assert().Equal(money.New(10, money.EUR), transferRes.Balance)
 	s.Assert().Nil(err)
 
 	balanceMaryRes, err := s.svc.Balance(
@@ -304,7 +773,7 @@ func (s *IntegrationTestSuite) TestTransactions() {
 		},
 	)
 
-	s.Assert().Equal(service.BalanceResponse{Balance: 10}, balanceMaryRes)
+	s.Assert().Equal(service.BalanceResponse{Balance: money.New(10, money.EUR)}, balanceMaryRes)
 	s.Assert().Nil(err)
 
 	transactionsJoeRes, err := s.svc.Transactions(
@@ -318,7 +787,7 @@ func (s *IntegrationTestSuite) TestTransactions() {
 
 	s.Assert().NotEmpty(transactionsJoeRes.Transactions[0].Timestamp)
 	s.Assert().Equal("deposit", transactionsJoeRes.Transactions[0].Operation)
-	s.Assert().Equal(20, transactionsJoeRes.Transactions[0].Amount)
+	s.Assert().Equal(money.New(20, money.EUR), transactionsJoeRes.Transactions[0].Amount)
 	s.Assert().Empty(transactionsJoeRes.Transactions[0].ReceiverUserID)
 	s.Assert().Empty(transactionsJoeRes.Transactions[0].SenderUserID)
 	s.Assert().Empty(transactionsJoeRes.Transactions[0].ReceiverAccountID)
@@ -326,7 +795,7 @@ func (s *IntegrationTestSuite) TestTransactions() {
 
 	s.Assert().NotEmpty(transactionsJoeRes.Transactions[1].Timestamp)
 	s.Assert().Equal("transfer", transactionsJoeRes.Transactions[1].Operation)
-	s.Assert().Equal(10, transactionsJoeRes.Transactions[1].Amount)
+	s.Assert().Equal(money.New(10, money.EUR), transactionsJoeRes.Transactions[1].Amount)
 	s.Assert().Equal(createMaryUserRes.UserID, transactionsJoeRes.Transactions[1].ReceiverUserID)
 	s.Assert().Empty(transactionsJoeRes.Transactions[1].SenderUserID)
 	s.Assert().Equal(createMaryAccountRes.AccountID, transactionsJoeRes.Transactions[1].ReceiverAccountID)
@@ -345,7 +814,7 @@ func (s *IntegrationTestSuite) TestTransactions() {
 
 	s.Assert().NotEmpty(transactionsMaryRes.Transactions[0].Timestamp)
 	s.Assert().Equal("transfer", transactionsMaryRes.Transactions[0].Operation)
-	s.Assert().Equal(10, transactionsMaryRes.Transactions[0].Amount)
+	s.Assert().Equal(money.New(10, money.EUR), transactionsMaryRes.Transactions[0].Amount)
 	s.Assert().Empty(transactionsMaryRes.Transactions[0].ReceiverUserID)
 	s.Assert().Equal(createJoeUserRes.UserID, transactionsMaryRes.Transactions[0].SenderUserID)
 	s.Assert().Empty(transactionsMaryRes.Transactions[0].ReceiverAccountID)
@@ -353,3 +822,2198 @@ func (s *IntegrationTestSuite) TestTransactions() {
 
 	s.Assert().Nil(err)
 }
+
+func (s *IntegrationTestSuite) TestTermDeposit() {
+	_, err := s.svc.CreateProduct(
+		service.CreateProductRequest{
+			ProductID: "term-deposit-it",
+			ProductTerms: service.ProductTerms{
+				Name: "Term deposit",
+				Type: domain.ProductTermDeposit,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+				},
+				TermDays:             30,
+				EarlyBreakPenaltyBps: 1000,
+			},
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	createUserRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(createUserRes.UserID)
+
+	createAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID:    createUserRes.UserID,
+			ProductID: "term-deposit-it",
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(1, createAccountRes.ProductVersion)
+
+	_, err = s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(200, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(100, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("funds locked until maturity"), err)
+
+	withdrawRes, err := s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(100, money.EUR),
+			BreakTerm: true,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(money.New(90, money.EUR), withdrawRes.Balance)
+
+	_, err = s.svc.GrantLoan(
+		service.GrantLoanRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			LoanTerms: service.LoanTerms{
+				Principal:  money.New(1000, money.EUR),
+				TermMonths: 12,
+				Schedule:   domain.LoanLinear,
+			},
+		},
+	)
+
+	s.Assert().Equal(errors.New("operation not allowed by product"), err)
+}
+
+func (s *IntegrationTestSuite) TestProductVersioning() {
+	_, err := s.svc.CreateProduct(
+		service.CreateProductRequest{
+			ProductID: "savings-it",
+			ProductTerms: service.ProductTerms{
+				Name: "Savings",
+				Type: domain.ProductSavings,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+				},
+				MonthlyWithdrawalLimit: 1,
+			},
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	createUserRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "joe",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	s.verify(createUserRes.UserID)
+
+	createAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID:    createUserRes.UserID,
+			ProductID: "savings-it",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	updateRes, err := s.svc.UpdateProduct(
+		service.UpdateProductRequest{
+			ProductID: "savings-it",
+			ProductTerms: service.ProductTerms{
+				Name: "Savings",
+				Type: domain.ProductSavings,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+				},
+				MonthlyWithdrawalLimit: 5,
+				WithdrawalFee:          money.New(1, money.EUR),
+			},
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(2, updateRes.Version)
+
+	_, err = s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(50, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	withdrawRes, err := s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(10, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(money.New(40, money.EUR), withdrawRes.Balance)
+
+	_, err = s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    createUserRes.UserID,
+			AccountID: createAccountRes.AccountID,
+			Amount:    money.New(10, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("monthly withdrawal limit reached"), err)
+}
+
+func (s *IntegrationTestSuite) TestBatchAllOrNothing() {
+	senderUserID, senderAccountID := s.fundedAccount("payroll", money.New(10000, money.EUR))
+	joeUserID, joeAccountID := s.fundedAccount("joe", money.Money{})
+	maryUserID, maryAccountID := s.fundedAccount("mary", money.Money{})
+
+	file := fmt.Sprintf(
+		"receiver_user_id,receiver_account_id,amount\n%s,%s,60\n%s,%s,60\n",
+		joeUserID, joeAccountID, maryUserID, maryAccountID,
+	)
+
+	res, err := s.batcher.Submit(
+		batch.SubmitRequest{
+			UserID:       senderUserID,
+			AccountID:    senderAccountID,
+			Format:       batch.FormatCSV,
+			AllOrNothing: true,
+			File:         []byte(file),
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(domain.BatchFailed, res.Status)
+	s.Assert().Equal(domain.BatchLineFailed, res.Lines[0].Status)
+	s.Assert().Equal("insuficient funds for batch", res.Lines[0].Error)
+	s.Assert().Equal(domain.BatchLineFailed, res.Lines[1].Status)
+
+	balanceRes, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(money.New(10000, money.EUR), balanceRes.Balance)
+}
+
+func (s *IntegrationTestSuite) TestBatchBestEffort() {
+	senderUserID, senderAccountID := s.fundedAccount("payroll", money.New(10000, money.EUR))
+	joeUserID, joeAccountID := s.fundedAccount("joe", money.Money{})
+	maryUserID, maryAccountID := s.fundedAccount("mary", money.Money{})
+
+	file := fmt.Sprintf(
+		"receiver_user_id,receiver_account_id,amount\n%s,%s,60\n%s,%s,60\n%s,%s,abc\n",
+		joeUserID, joeAccountID, maryUserID, maryAccountID, maryUserID, maryAccountID,
+	)
+
+	res, err := s.batcher.Submit(
+		batch.SubmitRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+			Format:    batch.FormatCSV,
+			File:      []byte(file),
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(domain.BatchCompletedWithErrors, res.Status)
+	s.Assert().Equal(domain.BatchLineCompleted, res.Lines[0].Status)
+	s.Assert().Equal(domain.BatchLineFailed, res.Lines[1].Status)
+	s.Assert().Equal(domain.BatchLineFailed, res.Lines[2].Status)
+
+	statusRes, err := s.batcher.Status(
+		batch.StatusRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+			BatchID:   res.BatchID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(res, statusRes)
+
+	balanceRes, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(money.New(4000, money.EUR), balanceRes.Balance)
+}
+
+func (s *IntegrationTestSuite) TestReconcile() {
+	senderUserID, senderAccountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	receiverUserID, receiverAccountID := s.fundedAccount("mary", money.Money{})
+
+	_, err := s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:      senderUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverUserID:    receiverUserID,
+			ReceiverAccountID: receiverAccountID,
+			Amount:            money.New(40, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.BatchTransfer(
+		service.BatchTransferRequest{
+			SenderUserID:    senderUserID,
+			SenderAccountID: senderAccountID,
+			AllOrNothing:    true,
+			Transfers: []service.TransferRequest{
+				{
+					ReceiverUserID:    receiverUserID,
+					ReceiverAccountID: receiverAccountID,
+					Amount:            money.New(10, money.EUR),
+				},
+				{
+					ReceiverUserID:    receiverUserID,
+					ReceiverAccountID: "invalid",
+					Amount:            money.New(10, money.EUR),
+				},
+			},
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	res, err := s.rec.Run()
+
+	s.Assert().Nil(err)
+	s.Assert().True(res.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestVelocityLimits() {
+	_, err := s.svc.PutTier(
+		service.PutTierRequest{
+			TierID: "velocity-it",
+			Limits: []service.LimitTerms{
+				{
+					Scope:     domain.LimitScopeAccount,
+					Operation: domain.OperationWithdraw,
+					Window:    "1h",
+					MaxCount:  2,
+				},
+				{
+					Scope:     domain.LimitScopeUser,
+					Operation: domain.OperationTransferOut,
+					Window:    "24h",
+					MaxAmount: money.New(5000, money.EUR),
+				},
+			},
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	receiverUserID, receiverAccountID := s.fundedAccount("mary", money.Money{})
+
+	err = s.svc.UpdateUserTier(
+		service.UpdateUserTierRequest{
+			UserID: userID,
+			TierID: "velocity-it",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	for i := 0; i < 2; i++ {
+		_, err = s.svc.Withdraw(
+			service.WithdrawRequest{
+				UserID:    userID,
+				AccountID: accountID,
+				Amount:    money.New(100, money.EUR),
+			},
+		)
+
+		s.Assert().Nil(err)
+	}
+
+	_, err = s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    money.New(100, money.EUR),
+		},
+	)
+
+	var limitErr service.VelocityLimitError
+
+	s.Assert().True(errors.As(err, &limitErr))
+	s.Assert().Equal(domain.OperationWithdraw, limitErr.Limit.Operation)
+	s.Assert().WithinDuration(time.Now().Add(time.Hour), limitErr.ResetsAt, time.Minute)
+
+	transfer := service.TransferRequest{
+		SenderUserID:      userID,
+		SenderAccountID:   accountID,
+		ReceiverUserID:    receiverUserID,
+		ReceiverAccountID: receiverAccountID,
+		Amount:            money.New(3000, money.EUR),
+	}
+
+	_, err = s.svc.Transfer(transfer)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Transfer(transfer)
+
+	s.Assert().True(errors.As(err, &limitErr))
+	s.Assert().Equal(domain.LimitScopeUser, limitErr.Limit.Scope)
+	s.Assert().WithinDuration(time.Now().Add(24*time.Hour), limitErr.ResetsAt, time.Minute)
+}
+
+func (s *IntegrationTestSuite) fundedAccount(name string, amount money.Money) (string, string) {
+	createUserRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: name,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	s.verify(createUserRes.UserID)
+
+	createAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	if amount.IsPositive() {
+		_, err = s.svc.Deposit(
+			service.DepositRequest{
+				UserID:    createUserRes.UserID,
+				AccountID: createAccountRes.AccountID,
+				Amount:    amount,
+			},
+		)
+
+		s.Require().Nil(err)
+	}
+
+	return createUserRes.UserID, createAccountRes.AccountID
+}
+
+func (s *IntegrationTestSuite) TestOnboarding() {
+	userID, accountID := s.fundedAccount("joe", money.New(5000, money.EUR))
+
+	receiverUserID, receiverAccountID := s.fundedAccount("mary", money.Money{})
+
+	createUserRes, err := s.svc.CreateUser(
+		service.CreateUserRequest{
+			Name: "ann",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	createAccountRes, err := s.svc.CreateAccount(
+		service.CreateAccountRequest{
+			UserID: createUserRes.UserID,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:      userID,
+			SenderAccountID:   accountID,
+			ReceiverUserID:    createUserRes.UserID,
+			ReceiverAccountID: createAccountRes.AccountID,
+			Amount:            money.New(2000, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	transfer := service.TransferRequest{
+		SenderUserID:      createUserRes.UserID,
+		SenderAccountID:   createAccountRes.AccountID,
+		ReceiverUserID:    receiverUserID,
+		ReceiverAccountID: receiverAccountID,
+		Amount:            money.New(1000, money.EUR),
+	}
+
+	_, err = s.svc.Transfer(transfer)
+
+	s.Assert().Equal(errors.New("pending user cannot transfer"), err)
+
+	err = s.svc.UpdateUserStatus(
+		service.UpdateUserStatusRequest{
+			UserID: createUserRes.UserID,
+			Status: domain.UserVerified,
+		},
+	)
+
+	s.Assert().Equal(errors.New("cannot move pending user to verified"), err)
+
+	s.verify(createUserRes.UserID)
+
+	_, err = s.svc.Transfer(transfer)
+
+	s.Assert().Nil(err)
+
+	err = s.svc.UpdateUserStatus(
+		service.UpdateUserStatusRequest{
+			UserID: createUserRes.UserID,
+			Status: domain.UserSuspended,
+			Reason: "fraud review",
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Transfer(transfer)
+
+	s.Assert().Equal(errors.New("suspended user cannot transfer"), err)
+
+	err = s.svc.UpdateUserStatus(
+		service.UpdateUserStatusRequest{
+			UserID: createUserRes.UserID,
+			Status: domain.UserVerified,
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	_, err = s.svc.Transfer(transfer)
+
+	s.Assert().Nil(err)
+}
+
+func (s *IntegrationTestSuite) TestEvents() {
+	userID, accountID := s.fundedAccount("joe", money.Money{})
+
+	accountsRes, err := s.svc.Accounts(
+		service.AccountsRequest{
+			UserID: userID,
+		},
+	)
+
+	s.Assert().Nil(err)
+	s.Assert().Equal(1, len(accountsRes.Accounts))
+
+	sub, err := s.hub.Subscribe(
+		events.SubscribeRequest{
+			AccountIDs: []string{accountsRes.Accounts[0].AccountID},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	defer sub.Close()
+
+	_, err = s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    money.New(1500, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	balance := <-sub.Events
+
+	s.Assert().Equal(events.TypeBalance, balance.Type)
+	s.Assert().Equal(events.BalancePayload{AccountID: accountID, Balance: money.New(1500, money.EUR)}, balance.Data)
+
+	transaction := <-sub.Events
+
+	s.Assert().Equal(events.TypeTransaction, transaction.Type)
+	s.Assert().Equal(balance.ID+1, transaction.ID)
+
+	payload, ok := transaction.Data.(events.TransactionPayload)
+
+	s.Require().True(ok)
+	s.Assert().Equal(accountID, payload.Transaction.AccountID)
+	s.Assert().Equal(domain.OperationDeposit, payload.Transaction.Operation)
+	s.Assert().Equal(money.New(1500, money.EUR), payload.Transaction.Amount)
+
+	resumed, err := s.hub.Subscribe(
+		events.SubscribeRequest{
+			AccountIDs:  []string{accountID},
+			LastEventID: fmt.Sprint(balance.ID),
+		},
+	)
+
+	s.Require().Nil(err)
+
+	defer resumed.Close()
+
+	s.Assert().Equal([]events.Event{transaction}, resumed.Replay)
+}
+
+func (s *IntegrationTestSuite) TestTransactionDetails() {
+	senderUserID, senderAccountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	receiverUserID, receiverAccountID := s.fundedAccount("mary", money.Money{})
+
+	_, err := s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:      senderUserID,
+			ReceiverUserID:    receiverUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverAccountID: receiverAccountID,
+			Amount:            money.New(2500, money.EUR),
+			TransactionDetails: service.TransactionDetails{
+				Description: "Rent for March",
+				Reference:   "rf18 5390 0754 7034",
+				Metadata: map[string]string{
+					"invoice": "2024-03",
+				},
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	_, err = s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+			Amount:    money.New(100, money.EUR),
+			TransactionDetails: service.TransactionDetails{
+				Description: "Groceries",
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	for _, leg := range []service.TransactionsRequest{
+		{UserID: senderUserID, AccountID: senderAccountID, Reference: "RF18539007547034"},
+		{UserID: receiverUserID, AccountID: receiverAccountID, Query: "rent"},
+		{UserID: senderUserID, AccountID: senderAccountID, Metadata: map[string]string{"invoice": "2024-03"}},
+	} {
+		res, err := s.svc.Transactions(leg)
+
+		s.Require().Nil(err)
+		s.Require().Len(res.Transactions, 1)
+		s.Assert().Equal(domain.OperationTransfer, res.Transactions[0].Operation)
+		s.Assert().Equal("Rent for March", res.Transactions[0].Description)
+		s.Assert().Equal("RF18539007547034", res.Transactions[0].Reference)
+		s.Assert().Equal(map[string]string{"invoice": "2024-03"}, res.Transactions[0].Metadata)
+	}
+
+	res, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Len(res.Transactions, 3)
+
+	res, err = s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    senderUserID,
+			AccountID: senderAccountID,
+			Query:     "rent",
+			Metadata:  map[string]string{"invoice": "2024-04"},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Empty(res.Transactions)
+}
+
+func (s *IntegrationTestSuite) TestIBAN() {
+	senderUserID, senderAccountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	receiverUserID, receiverAccountID := s.fundedAccount("mary", money.Money{})
+
+	accountsRes, err := s.svc.Accounts(
+		service.AccountsRequest{
+			UserID: receiverUserID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(accountsRes.Accounts, 1)
+
+	receiverIBAN := accountsRes.Accounts[0].IBAN
+
+	_, err = iban.Parse(receiverIBAN)
+
+	s.Require().Nil(err)
+
+	_, err = s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:      senderUserID,
+			ReceiverUserID:    receiverUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverAccountID: strings.ToLower(iban.Print(receiverIBAN)),
+			Amount:            money.New(2500, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+
+	balanceRes, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    receiverUserID,
+			AccountID: receiverIBAN,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(money.New(2500, money.EUR), balanceRes.Balance)
+
+	transactionsRes, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    receiverUserID,
+			AccountID: receiverAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(transactionsRes.Transactions, 1)
+	s.Assert().Equal(senderAccountID, transactionsRes.Transactions[0].SenderAccountID)
+
+	typo := []byte(receiverIBAN)
+
+	typo[len(typo)-1] = '0' + (typo[len(typo)-1]-'0'+1)%10
+
+	_, err = s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    receiverUserID,
+			AccountID: string(typo),
+		},
+	)
+
+	s.Assert().Equal(errors.New("invalid account id"), err)
+
+	_, err = s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    receiverUserID,
+			AccountID: "GB82 WEST 1234 5698 7654 32",
+		},
+	)
+
+	s.Assert().Equal(errors.New("account not found"), err)
+}
+
+func (s *IntegrationTestSuite) TestPayees() {
+	senderUserID, senderAccountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	receiverUserID, receiverAccountID := s.fundedAccount("Mary Jones", money.Money{})
+
+	accountsRes, err := s.svc.Accounts(
+		service.AccountsRequest{
+			UserID: receiverUserID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	receiverIBAN := accountsRes.Accounts[0].IBAN
+
+	_, err = s.svc.AddPayee(
+		service.AddPayeeRequest{
+			UserID:    senderUserID,
+			Nickname:  "Mary",
+			Name:      "Mary Jonse",
+			AccountID: receiverIBAN,
+		},
+	)
+
+	s.Assert().Equal(service.PayeeNameError{Match: domain.PayeeMatchClose, Name: "Mary Jones"}, err)
+
+	payeeRes, err := s.svc.AddPayee(
+		service.AddPayeeRequest{
+			UserID:    senderUserID,
+			Nickname:  "Mary",
+			Name:      "Ms Mary Jones",
+			AccountID: iban.Print(receiverIBAN),
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PayeeMatchExact, payeeRes.Match)
+	s.Assert().Equal(receiverAccountID, payeeRes.AccountID)
+	s.Assert().True(payeeRes.ActiveAt.After(time.Now()))
+
+	_, err = s.svc.AddPayee(
+		service.AddPayeeRequest{
+			UserID:    senderUserID,
+			Nickname:  "Mary again",
+			Name:      "Mary Jones",
+			AccountID: receiverAccountID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("duplicate payee"), err)
+
+	_, err = s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:    senderUserID,
+			SenderAccountID: senderAccountID,
+			PayeeID:         payeeRes.PayeeID,
+			Amount:          money.New(1000, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(service.PayeeCoolingOffError{Until: payeeRes.ActiveAt}, err)
+
+	_, err = s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:      senderUserID,
+			ReceiverUserID:    receiverUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverAccountID: receiverAccountID,
+			ReceiverName:      "Bob",
+			Amount:            money.New(1000, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(service.PayeeNameError{Match: domain.PayeeMatchNone}, err)
+
+	_, err = s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:       senderUserID,
+			ReceiverUserID:     receiverUserID,
+			SenderAccountID:    senderAccountID,
+			ReceiverAccountID:  receiverAccountID,
+			ReceiverName:       "Bob",
+			AcceptNameMismatch: true,
+			Amount:             money.New(1000, money.EUR),
+		},
+	)
+
+	s.Assert().Nil(err)
+
+	updateRes, err := s.svc.UpdatePayee(
+		service.UpdatePayeeRequest{
+			UserID:   senderUserID,
+			PayeeID:  payeeRes.PayeeID,
+			Nickname: "Mum",
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal("Mum", updateRes.Nickname)
+	s.Assert().Equal(payeeRes.ActiveAt, updateRes.ActiveAt)
+
+	_, err = s.svc.Payee(
+		service.PayeeRequest{
+			UserID:  receiverUserID,
+			PayeeID: payeeRes.PayeeID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("payee not found"), err)
+
+	err = s.svc.DeletePayee(
+		service.DeletePayeeRequest{
+			UserID:  senderUserID,
+			PayeeID: payeeRes.PayeeID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	payeesRes, err := s.svc.Payees(
+		service.PayeesRequest{
+			UserID: senderUserID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Empty(payeesRes.Payees)
+}
+
+func (s *IntegrationTestSuite) TestPaymentRequests() {
+	requesterUserID, requesterAccountID := s.fundedAccount("joe", money.Money{})
+	firstUserID, firstAccountID := s.fundedAccount("mary", money.New(5000, money.EUR))
+	secondUserID, _ := s.fundedAccount("ann", money.New(5000, money.EUR))
+	thirdUserID, _ := s.fundedAccount("bob", money.New(5000, money.EUR))
+
+	createRes, err := s.svc.RequestPayment(
+		service.RequestPaymentRequest{
+			UserID:      requesterUserID,
+			AccountID:   requesterAccountID,
+			Amount:      money.New(9000, money.EUR),
+			Description: "Dinner",
+			Payers: []service.PayerTerms{
+				{UserID: firstUserID},
+				{UserID: secondUserID},
+				{UserID: thirdUserID},
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PaymentRequestOpen, createRes.Status)
+	s.Assert().Equal(money.New(3000, money.EUR), createRes.Payers[0].Amount)
+
+	incomingRes, err := s.svc.PaymentRequests(
+		service.PaymentRequestsRequest{
+			UserID:    firstUserID,
+			Direction: service.DirectionIncoming,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(incomingRes.PaymentRequests, 1)
+	s.Assert().Equal(createRes.PaymentRequestID, incomingRes.PaymentRequests[0].PaymentRequestID)
+
+	acceptRes, err := s.svc.AcceptPaymentRequest(
+		service.AcceptPaymentRequestRequest{
+			UserID:           firstUserID,
+			PaymentRequestID: createRes.PaymentRequestID,
+			AccountID:        firstAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PayerPaid, acceptRes.Payers[0].Status)
+	s.Assert().NotEmpty(acceptRes.Payers[0].TransactionID)
+	s.Assert().Equal(money.New(3000, money.EUR), acceptRes.PaidAmount)
+
+	_, err = s.svc.AcceptPaymentRequest(
+		service.AcceptPaymentRequestRequest{
+			UserID:           firstUserID,
+			PaymentRequestID: createRes.PaymentRequestID,
+			AccountID:        firstAccountID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("payment request already answered"), err)
+
+	_, err = s.svc.AcceptPaymentRequest(
+		service.AcceptPaymentRequestRequest{
+			UserID:           secondUserID,
+			PaymentRequestID: createRes.PaymentRequestID,
+			AccountID:        firstAccountID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("unauthorized account id"), err)
+
+	declineRes, err := s.svc.DeclinePaymentRequest(
+		service.DeclinePaymentRequestRequest{
+			UserID:           secondUserID,
+			PaymentRequestID: createRes.PaymentRequestID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PayerDeclined, declineRes.Payers[1].Status)
+
+	_, err = s.svc.CancelPaymentRequest(
+		service.CancelPaymentRequestRequest{
+			UserID:           thirdUserID,
+			PaymentRequestID: createRes.PaymentRequestID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("unauthorized payment request id"), err)
+
+	cancelRes, err := s.svc.CancelPaymentRequest(
+		service.CancelPaymentRequestRequest{
+			UserID:           requesterUserID,
+			PaymentRequestID: createRes.PaymentRequestID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PaymentRequestCancelled, cancelRes.Status)
+	s.Assert().Equal(domain.PayerCancelled, cancelRes.Payers[2].Status)
+
+	balanceRes, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    requesterUserID,
+			AccountID: requesterAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(money.New(3000, money.EUR), balanceRes.Balance)
+
+	transactionsRes, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    requesterUserID,
+			AccountID: requesterAccountID,
+			Metadata: map[string]string{
+				"payment_request_id": createRes.PaymentRequestID,
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(transactionsRes.Transactions, 1)
+	s.Assert().Equal("Dinner", transactionsRes.Transactions[0].Description)
+}
+
+func (s *IntegrationTestSuite) TestPendingTransfers() {
+	senderUserID, senderAccountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+	receiverUserID, receiverAccountID := s.fundedAccount("mary", money.Money{})
+
+	pending := service.CreatePendingTransferRequest{
+		TransferRequest: service.TransferRequest{
+			SenderUserID:      senderUserID,
+			SenderAccountID:   senderAccountID,
+			ReceiverUserID:    receiverUserID,
+			ReceiverAccountID: receiverAccountID,
+			Amount:            money.New(3000, money.EUR),
+			TransactionDetails: service.TransactionDetails{
+				Description: "Deposit for the flat",
+			},
+		},
+	}
+
+	createRes, err := s.svc.CreatePendingTransfer(pending)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PendingTransferPending, createRes.Status)
+	s.Assert().WithinDuration(time.Now().Add(72*time.Hour), createRes.ExpiresAt, time.Minute)
+
+	s.assertBalance(senderUserID, senderAccountID, money.New(7000, money.EUR))
+	s.assertBalance(receiverUserID, receiverAccountID, money.New(0, money.EUR))
+
+	incomingRes, err := s.svc.PendingTransfers(
+		service.PendingTransfersRequest{
+			UserID:    receiverUserID,
+			Direction: service.DirectionIncoming,
+			Status:    domain.PendingTransferPending,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(incomingRes.PendingTransfers, 1)
+	s.Assert().Equal(createRes.PendingTransferID, incomingRes.PendingTransfers[0].PendingTransferID)
+
+	_, err = s.svc.CancelPendingTransfer(
+		service.CancelPendingTransferRequest{
+			UserID:            receiverUserID,
+			PendingTransferID: createRes.PendingTransferID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("unauthorized pending transfer id"), err)
+
+	acceptRes, err := s.svc.AcceptPendingTransfer(
+		service.AcceptPendingTransferRequest{
+			UserID:            receiverUserID,
+			PendingTransferID: createRes.PendingTransferID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PendingTransferAccepted, acceptRes.Status)
+	s.Assert().NotEmpty(acceptRes.TransactionID)
+
+	_, err = s.svc.CancelPendingTransfer(
+		service.CancelPendingTransferRequest{
+			UserID:            senderUserID,
+			PendingTransferID: createRes.PendingTransferID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("pending transfer closed"), err)
+
+	s.assertBalance(senderUserID, senderAccountID, money.New(7000, money.EUR))
+	s.assertBalance(receiverUserID, receiverAccountID, money.New(3000, money.EUR))
+
+	pending.Amount = money.New(1000, money.EUR)
+
+	createRes, err = s.svc.CreatePendingTransfer(pending)
+
+	s.Require().Nil(err)
+
+	cancelRes, err := s.svc.CancelPendingTransfer(
+		service.CancelPendingTransferRequest{
+			UserID:            senderUserID,
+			PendingTransferID: createRes.PendingTransferID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.PendingTransferCancelled, cancelRes.Status)
+
+	s.assertBalance(senderUserID, senderAccountID, money.New(7000, money.EUR))
+
+	transactionsRes, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    receiverUserID,
+			AccountID: receiverAccountID,
+			Metadata: map[string]string{
+				"pending_transfer_id": createRes.PendingTransferID,
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(transactionsRes.Transactions, 2)
+	s.Assert().Equal(domain.OperationPending, transactionsRes.Transactions[1].Operation)
+	s.Assert().Equal("cancelled", transactionsRes.Transactions[1].Metadata["pending_transfer_status"])
+
+	_, err = s.svc.CreatePendingTransfer(
+		service.CreatePendingTransferRequest{
+			TransferRequest: pending.TransferRequest,
+			ExpiresAt:       time.Now().Add(-time.Minute),
+		},
+	)
+
+	s.Assert().Equal(errors.New("invalid expiry"), err)
+
+	res, err := s.rec.Run()
+
+	s.Require().Nil(err)
+	s.Assert().True(res.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestMandates() {
+	debtorUserID, debtorAccountID := s.fundedAccount("joe", money.New(2000, money.EUR))
+	creditorUserID, creditorAccountID := s.fundedAccount("power company", money.Money{})
+
+	grantRes, err := s.svc.GrantMandate(
+		service.GrantMandateRequest{
+			UserID:            debtorUserID,
+			AccountID:         debtorAccountID,
+			CreditorAccountID: creditorAccountID,
+			MaxAmount:         money.New(1500, money.EUR),
+			Frequency:         domain.MandateMonthly,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.MandateActive, grantRes.Status)
+
+	mandatesRes, err := s.svc.Mandates(
+		service.MandatesRequest{
+			UserID: creditorUserID,
+			Role:   service.MandateRoleCreditor,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(mandatesRes.Mandates, 1)
+	s.Assert().Equal(grantRes.MandateID, mandatesRes.Mandates[0].MandateID)
+
+	collect := service.CollectPaymentRequest{
+		UserID:      creditorUserID,
+		MandateID:   grantRes.MandateID,
+		Amount:      money.New(1000, money.EUR),
+		Description: "Electricity for August",
+	}
+
+	_, err = s.svc.CollectPayment(
+		service.CollectPaymentRequest{
+			UserID:    debtorUserID,
+			MandateID: grantRes.MandateID,
+			Amount:    money.New(1000, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("unauthorized mandate id"), err)
+
+	_, err = s.svc.CollectPayment(
+		service.CollectPaymentRequest{
+			UserID:    creditorUserID,
+			MandateID: grantRes.MandateID,
+			Amount:    money.New(1600, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("invalid amount, above mandate maximum"), err)
+
+	collectRes, err := s.svc.CollectPayment(collect)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CollectionCompleted, collectRes.Status)
+	s.Assert().NotEmpty(collectRes.TransactionID)
+	s.Assert().WithinDuration(time.Now().Add(8*7*24*time.Hour), collectRes.RefundableUntil, time.Minute)
+
+	s.assertBalance(debtorUserID, debtorAccountID, money.New(1000, money.EUR))
+	s.assertBalance(creditorUserID, creditorAccountID, money.New(1000, money.EUR))
+
+	_, err = s.svc.CollectPayment(collect)
+
+	s.Assert().Equal(errors.New("mandate already collected this period"), err)
+
+	oneOffRes, err := s.svc.GrantMandate(
+		service.GrantMandateRequest{
+			UserID:            debtorUserID,
+			AccountID:         debtorAccountID,
+			CreditorAccountID: creditorAccountID,
+			MaxAmount:         money.New(5000, money.EUR),
+			Frequency:         domain.MandateOneOff,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	failedRes, err := s.svc.CollectPayment(
+		service.CollectPaymentRequest{
+			UserID:    creditorUserID,
+			MandateID: oneOffRes.MandateID,
+			Amount:    money.New(3000, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CollectionFailed, failedRes.Status)
+	s.Assert().Contains(failedRes.FailureReason, "insuficient funds")
+
+	collectionsRes, err := s.svc.Collections(
+		service.CollectionsRequest{
+			UserID:    creditorUserID,
+			MandateID: oneOffRes.MandateID,
+			Status:    domain.CollectionFailed,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(collectionsRes.Collections, 1)
+	s.Assert().Equal(failedRes.CollectionID, collectionsRes.Collections[0].CollectionID)
+
+	s.assertBalance(debtorUserID, debtorAccountID, money.New(1000, money.EUR))
+
+	refund := service.RefundCollectionRequest{
+		UserID:       debtorUserID,
+		MandateID:    grantRes.MandateID,
+		CollectionID: collectRes.CollectionID,
+	}
+
+	refundRes, err := s.svc.RefundCollection(refund)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CollectionRefunded, refundRes.Status)
+
+	s.assertBalance(debtorUserID, debtorAccountID, money.New(2000, money.EUR))
+	s.assertBalance(creditorUserID, creditorAccountID, money.New(0, money.EUR))
+
+	_, err = s.svc.RefundCollection(refund)
+
+	s.Assert().Equal(errors.New("collection not refundable"), err)
+
+	revokeRes, err := s.svc.RevokeMandate(
+		service.RevokeMandateRequest{
+			UserID:    debtorUserID,
+			MandateID: grantRes.MandateID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.MandateRevoked, revokeRes.Status)
+
+	_, err = s.svc.CollectPayment(collect)
+
+	s.Assert().Equal(errors.New("mandate not active"), err)
+
+	res, err := s.rec.Run()
+
+	s.Require().Nil(err)
+	s.Assert().True(res.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestMandateApproval() {
+	debtorUserID, debtorAccountID := s.fundedAccount("joe", money.New(2000, money.EUR))
+	coOwnerUserID, _ := s.fundedAccount("mary", money.Money{})
+	creditorUserID, creditorAccountID := s.fundedAccount("water company", money.Money{})
+
+	err := s.svc.AddHolder(
+		service.AddHolderRequest{
+			UserID:       debtorUserID,
+			AccountID:    debtorAccountID,
+			HolderUserID: coOwnerUserID,
+			Role:         domain.RoleCoOwner,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	err = s.svc.UpdateAccountRules(
+		service.UpdateAccountRulesRequest{
+			UserID:                debtorUserID,
+			AccountID:             debtorAccountID,
+			DualApprovalThreshold: money.New(500, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+
+	grant := service.GrantMandateRequest{
+		UserID:            debtorUserID,
+		AccountID:         debtorAccountID,
+		CreditorAccountID: creditorAccountID,
+		MaxAmount:         money.New(1500, money.EUR),
+		Frequency:         domain.MandateMonthly,
+	}
+
+	_, err = s.svc.GrantMandate(grant)
+
+	s.Assert().Equal(errors.New("approval required"), err)
+
+	grant.ApproverUserID = coOwnerUserID
+
+	grantRes, err := s.svc.GrantMandate(grant)
+
+	s.Require().Nil(err)
+
+	collectRes, err := s.svc.CollectPayment(
+		service.CollectPaymentRequest{
+			UserID:    creditorUserID,
+			MandateID: grantRes.MandateID,
+			Amount:    money.New(1000, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CollectionCompleted, collectRes.Status)
+
+	s.assertBalance(debtorUserID, debtorAccountID, money.New(1000, money.EUR))
+}
+
+func (s *IntegrationTestSuite) TestCards() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	issueRes, err := s.svc.IssueCard(
+		service.IssueCardRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Limits: service.CardLimits{
+				PerTransaction: money.New(5000, money.EUR),
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CardActive, issueRes.Status)
+	s.Assert().Len(issueRes.PAN, 16)
+	s.Assert().Len(issueRes.CVV, 3)
+	s.Assert().Equal(issueRes.PAN[:6]+"******"+issueRes.PAN[12:], issueRes.MaskedPAN)
+
+	authorise := service.AuthoriseCardRequest{
+		PAN:         issueRes.PAN,
+		ExpiryMonth: issueRes.ExpiryMonth,
+		ExpiryYear:  issueRes.ExpiryYear,
+		CVV:         issueRes.CVV,
+		Amount:      money.New(3000, money.EUR),
+		Merchant:    "Grocer",
+		MerchantKey: "grocer-key",
+	}
+
+	wrongCVV := authorise
+
+	wrongCVV.CVV = issueRes.CVV + "0"
+
+	_, err = s.svc.AuthoriseCard(wrongCVV)
+
+	s.Assert().Equal(errors.New("invalid card details"), err)
+
+	wrongKey := authorise
+
+	wrongKey.MerchantKey = "wrong-key"
+
+	_, err = s.svc.AuthoriseCard(wrongKey)
+
+	s.Assert().Equal(errors.New("invalid merchant credentials"), err)
+
+	authRes, err := s.svc.AuthoriseCard(authorise)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.AuthorisationHeld, authRes.Status)
+	s.Assert().Equal(issueRes.CardID, authRes.CardID)
+
+	s.assertBalance(userID, accountID, money.New(7000, money.EUR))
+
+	_, err = s.svc.CaptureAuthorisation(
+		service.CaptureAuthorisationRequest{
+			CardID:          authRes.CardID,
+			AuthorisationID: authRes.AuthorisationID,
+			Amount:          money.New(2500, money.EUR),
+			Merchant:        "Grocer",
+			MerchantKey:     "wrong-key",
+		},
+	)
+
+	s.Assert().Equal(errors.New("invalid merchant credentials"), err)
+
+	captureRes, err := s.svc.CaptureAuthorisation(
+		service.CaptureAuthorisationRequest{
+			CardID:          authRes.CardID,
+			AuthorisationID: authRes.AuthorisationID,
+			Amount:          money.New(2500, money.EUR),
+			Merchant:        "Grocer",
+			MerchantKey:     merchantKeys["Grocer"],
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.AuthorisationCaptured, captureRes.Status)
+	s.Assert().Equal(money.New(2500, money.EUR), captureRes.CapturedAmount)
+
+	s.assertBalance(userID, accountID, money.New(7500, money.EUR))
+
+	_, err = s.svc.ReverseAuthorisation(
+		service.ReverseAuthorisationRequest{
+			CardID:          authRes.CardID,
+			AuthorisationID: authRes.AuthorisationID,
+			Merchant:        "Grocer",
+			MerchantKey:     merchantKeys["Grocer"],
+		},
+	)
+
+	s.Assert().Equal(errors.New("authorisation not held"), err)
+
+	authorise.Amount = money.New(6000, money.EUR)
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Assert().Equal(errors.New("card limit exceeded"), err)
+
+	authorise.Amount = money.New(1000, money.EUR)
+
+	authRes, err = s.svc.AuthoriseCard(authorise)
+
+	s.Require().Nil(err)
+
+	s.assertBalance(userID, accountID, money.New(6500, money.EUR))
+
+	_, err = s.svc.FreezeCard(
+		service.FreezeCardRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Assert().Equal(errors.New("card frozen"), err)
+
+	s.assertBalance(userID, accountID, money.New(7500, money.EUR))
+
+	_, err = s.svc.ReverseAuthorisation(
+		service.ReverseAuthorisationRequest{
+			CardID:          authRes.CardID,
+			AuthorisationID: authRes.AuthorisationID,
+			Merchant:        "Grocer",
+			MerchantKey:     merchantKeys["Grocer"],
+		},
+	)
+
+	s.Assert().Equal(errors.New("authorisation not held"), err)
+
+	_, err = s.svc.UnfreezeCard(
+		service.UnfreezeCardRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	_, err = s.svc.UpdateCardLimits(
+		service.UpdateCardLimitsRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+			Limits: service.CardLimits{
+				Daily: money.New(4000, money.EUR),
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	authorise.Amount = money.New(2000, money.EUR)
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Assert().Equal(errors.New("card daily limit exceeded"), err)
+
+	cancelRes, err := s.svc.CancelCard(
+		service.CancelCardRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CardCancelled, cancelRes.Status)
+
+	authorise.Amount = money.New(100, money.EUR)
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Assert().Equal(errors.New("card cancelled"), err)
+
+	authorisationsRes, err := s.svc.CardAuthorisations(
+		service.CardAuthorisationsRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(authorisationsRes.Authorisations, 2)
+	s.Assert().Equal(domain.AuthorisationReversed, authorisationsRes.Authorisations[0].Status)
+
+	res, err := s.rec.Run()
+
+	s.Require().Nil(err)
+	s.Assert().True(res.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestCardHoldExpiry() {
+	svc := s.svc
+
+	defer func() {
+		s.svc = svc
+	}()
+
+	s.svc = service.New(
+		userrepo.New(make(map[string]domain.User)),
+		accountrepo.New(make(map[string]domain.Account)),
+		productrepo.New(make(map[string][]domain.Product)),
+		tierrepo.New(make(map[string]domain.Tier)),
+		service.WithMerchantKeys(merchantKeys),
+		service.WithCardHoldTTL(-time.Minute),
+	)
+
+	_, err := s.svc.CreateProduct(
+		service.CreateProductRequest{
+			ProductID: "checking",
+			ProductTerms: service.ProductTerms{
+				Name: "Checking",
+				Type: domain.ProductChecking,
+				Operations: []string{
+					domain.OperationDeposit,
+					domain.OperationWithdraw,
+					domain.OperationTransferIn,
+					domain.OperationTransferOut,
+				},
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	issueRes, err := s.svc.IssueCard(
+		service.IssueCardRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	authRes, err := s.svc.AuthoriseCard(
+		service.AuthoriseCardRequest{
+			PAN:         issueRes.PAN,
+			ExpiryMonth: issueRes.ExpiryMonth,
+			ExpiryYear:  issueRes.ExpiryYear,
+			CVV:         issueRes.CVV,
+			Amount:      money.New(3000, money.EUR),
+			Merchant:    "Grocer",
+			MerchantKey: merchantKeys["Grocer"],
+		},
+	)
+
+	s.Require().Nil(err)
+
+	s.assertBalance(userID, accountID, money.New(7000, money.EUR))
+
+	_, err = s.svc.CaptureAuthorisation(
+		service.CaptureAuthorisationRequest{
+			CardID:          authRes.CardID,
+			AuthorisationID: authRes.AuthorisationID,
+			Amount:          money.New(3000, money.EUR),
+			Merchant:        "Grocer",
+			MerchantKey:     merchantKeys["Grocer"],
+		},
+	)
+
+	s.Assert().Equal(errors.New("authorisation expired"), err)
+
+	expireRes, err := s.svc.ExpireCardAuthorisations(service.ExpireCardAuthorisationsRequest{})
+
+	s.Require().Nil(err)
+	s.Assert().Equal([]string{authRes.AuthorisationID}, expireRes.AuthorisationIDs)
+
+	s.assertBalance(userID, accountID, money.New(10000, money.EUR))
+
+	authorisationsRes, err := s.svc.CardAuthorisations(
+		service.CardAuthorisationsRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(authorisationsRes.Authorisations, 1)
+	s.Assert().Equal(domain.AuthorisationExpired, authorisationsRes.Authorisations[0].Status)
+}
+
+func (s *IntegrationTestSuite) TestCardLockout() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	issueRes, err := s.svc.IssueCard(
+		service.IssueCardRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	authorise := service.AuthoriseCardRequest{
+		PAN:         issueRes.PAN,
+		ExpiryMonth: issueRes.ExpiryMonth,
+		ExpiryYear:  issueRes.ExpiryYear,
+		CVV:         issueRes.CVV,
+		Amount:      money.New(1000, money.EUR),
+		Merchant:    "Grocer",
+		MerchantKey: merchantKeys["Grocer"],
+	}
+
+	wrongCVV := authorise
+
+	wrongCVV.CVV = issueRes.CVV + "0"
+
+	for range 3 {
+		_, err = s.svc.AuthoriseCard(wrongCVV)
+
+		s.Assert().Equal(errors.New("invalid card details"), err)
+	}
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Assert().Equal(errors.New("card locked"), err)
+
+	_, err = s.svc.FreezeCard(
+		service.FreezeCardRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("card locked"), err)
+
+	unfreezeRes, err := s.svc.UnfreezeCard(
+		service.UnfreezeCardRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.CardActive, unfreezeRes.Status)
+
+	for range 2 {
+		_, err = s.svc.AuthoriseCard(wrongCVV)
+
+		s.Assert().Equal(errors.New("invalid card details"), err)
+	}
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Require().Nil(err)
+
+	_, err = s.svc.AuthoriseCard(wrongCVV)
+
+	s.Assert().Equal(errors.New("invalid card details"), err)
+
+	_, err = s.svc.AuthoriseCard(authorise)
+
+	s.Require().Nil(err)
+
+	s.assertBalance(userID, accountID, money.New(8000, money.EUR))
+}
+
+func (s *IntegrationTestSuite) TestCardHost() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	issueRes, err := s.svc.IssueCard(
+		service.IssueCardRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	s.Require().Nil(err)
+
+	defer listener.Close()
+
+	spec := iso8583.DefaultSpec()
+
+	go cardhost.New(s.svc, spec, merchantKeys).Serve(listener)
+
+	client, err := iso8583.Dial(listener.Addr().String(), spec)
+
+	s.Require().Nil(err)
+
+	defer client.Close()
+
+	send := func(mti string, rrn string, amount int64) iso8583.Message {
+		req := iso8583.NewMessage(mti)
+
+		req.Fields[2] = issueRes.PAN
+		req.Fields[4] = fmt.Sprintf("%012d", amount)
+		req.Fields[11] = "000001"
+		req.Fields[14] = fmt.Sprintf("%02d%02d", issueRes.ExpiryYear%100, issueRes.ExpiryMonth)
+		req.Fields[37] = rrn
+		req.Fields[43] = "Grocer"
+		req.Fields[48] = issueRes.CVV
+		req.Fields[49] = "978"
+
+		res, err := client.Send(req)
+
+		s.Require().Nil(err)
+		s.Assert().Equal(rrn, res.Fields[37])
+
+		return res
+	}
+
+	res := send("0100", "000000000001", 3000)
+
+	s.Assert().Equal("0110", res.MTI)
+	s.Assert().Equal(cardhost.Approved, res.Fields[39])
+	s.Assert().Len(res.Fields[38], 6)
+
+	s.assertBalance(userID, accountID, money.New(7000, money.EUR))
+
+	res = send("0100", "000000000001", 1000)
+
+	s.Assert().Equal(cardhost.DuplicateTransaction, res.Fields[39])
+
+	res = send("0200", "000000000001", 2500)
+
+	s.Assert().Equal("0210", res.MTI)
+	s.Assert().Equal(cardhost.Approved, res.Fields[39])
+
+	s.assertBalance(userID, accountID, money.New(7500, money.EUR))
+
+	res = send("0400", "000000000001", 2500)
+
+	s.Assert().Equal("0410", res.MTI)
+	s.Assert().Equal(cardhost.InvalidTransaction, res.Fields[39])
+
+	res = send("0100", "000000000002", 2000)
+
+	s.Assert().Equal(cardhost.Approved, res.Fields[39])
+
+	s.assertBalance(userID, accountID, money.New(5500, money.EUR))
+
+	res = send("0400", "000000000002", 2000)
+
+	s.Assert().Equal(cardhost.Approved, res.Fields[39])
+
+	s.assertBalance(userID, accountID, money.New(7500, money.EUR))
+
+	res = send("0200", "000000000003", 500)
+
+	s.Assert().Equal(cardhost.Approved, res.Fields[39])
+
+	s.assertBalance(userID, accountID, money.New(7000, money.EUR))
+
+	res = send("0100", "000000000004", 8000)
+
+	s.Assert().Equal(cardhost.InsufficientFunds, res.Fields[39])
+
+	res = send("0400", "000000000004", 8000)
+
+	s.Assert().Equal(cardhost.RecordNotFound, res.Fields[39])
+
+	authorisationsRes, err := s.svc.CardAuthorisations(
+		service.CardAuthorisationsRequest{
+			UserID: userID,
+			CardID: issueRes.CardID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Len(authorisationsRes.Authorisations, 3)
+
+	reconcileRes, err := s.rec.Run()
+
+	s.Require().Nil(err)
+	s.Assert().True(reconcileRes.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestLoans() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	terms := service.LoanTerms{
+		Principal:       money.New(120000, money.EUR),
+		InterestRateBps: 500,
+		TermMonths:      12,
+		Schedule:        domain.LoanAnnuity,
+		LateFee:         money.New(1500, money.EUR),
+	}
+
+	_, err := s.svc.GrantLoan(
+		service.GrantLoanRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			LoanTerms: service.LoanTerms{
+				Principal:  terms.Principal,
+				TermMonths: 12,
+				Schedule:   "balloon",
+			},
+		},
+	)
+
+	s.Assert().Equal(errors.New("invalid loan schedule"), err)
+
+	grantRes, err := s.svc.GrantLoan(
+		service.GrantLoanRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			LoanTerms: terms,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.LoanActive, grantRes.Status)
+	s.Assert().Len(grantRes.Instalments, 12)
+	s.Assert().Equal(terms.Principal, grantRes.OutstandingPrincipal)
+	s.Assert().Equal(money.New(0, money.EUR), grantRes.Arrears)
+	s.Assert().Equal(money.New(500, money.EUR), grantRes.Instalments[0].Interest)
+
+	s.assertBalance(userID, accountID, money.New(130000, money.EUR))
+
+	_, err = s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID: userID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("user has active loans"), err)
+
+	collectRes, err := s.svc.CollectLoanRepayments(service.CollectLoanRepaymentsRequest{})
+
+	s.Require().Nil(err)
+	s.Assert().NotContains(collectRes.CollectedLoanIDs, grantRes.LoanID)
+
+	quoteRes, err := s.svc.LoanPayoffQuote(
+		service.LoanPayoffQuoteRequest{
+			UserID: userID,
+			LoanID: grantRes.LoanID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(terms.Principal, quoteRes.Amount)
+	s.Assert().True(quoteRes.ValidUntil.After(quoteRes.QuotedAt))
+
+	payOffRes, err := s.svc.PayOffLoan(
+		service.PayOffLoanRequest{
+			UserID: userID,
+			LoanID: grantRes.LoanID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(domain.LoanRepaid, payOffRes.Status)
+	s.Assert().Equal(domain.InstalmentSettled, payOffRes.Instalments[0].Status)
+	s.Assert().Equal(money.New(0, money.EUR), payOffRes.OutstandingPrincipal)
+
+	s.assertBalance(userID, accountID, money.New(10000, money.EUR))
+
+	_, err = s.svc.PayOffLoan(
+		service.PayOffLoanRequest{
+			UserID: userID,
+			LoanID: grantRes.LoanID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("loan repaid"), err)
+
+	statementRes, err := s.svc.LoanStatement(
+		service.LoanStatementRequest{
+			UserID: userID,
+			LoanID: grantRes.LoanID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(statementRes.Repayments, 1)
+	s.Assert().True(statementRes.Repayments[0].Payoff)
+	s.Assert().Equal(terms.Principal, statementRes.TotalRepaid)
+	s.Assert().Equal(money.New(0, money.EUR), statementRes.InterestPaid)
+
+	otherUserID, _ := s.fundedAccount("jane", money.New(0, money.EUR))
+
+	_, err = s.svc.Loan(
+		service.LoanRequest{
+			UserID: otherUserID,
+			LoanID: grantRes.LoanID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("loan not found"), err)
+
+	transactionsRes, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(transactionsRes.Transactions, 3)
+	s.Assert().Equal(domain.OperationLoanRepayment, transactionsRes.Transactions[2].Operation)
+	s.Assert().Equal(grantRes.LoanID, transactionsRes.Transactions[2].Metadata["loan_id"])
+
+	res, err := s.rec.Run()
+
+	s.Require().Nil(err)
+	s.Assert().True(res.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestPots() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	otherUserID, otherAccountID := s.fundedAccount("jane", money.Money{})
+
+	createRes, err := s.svc.CreatePot(
+		service.CreatePotRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Name:      "Holiday",
+			Goal:      money.New(50000, money.EUR),
+			GoalDate:  time.Now().UTC().AddDate(1, 0, 0),
+			AutoSave: []service.AutoSaveRule{
+				{Type: domain.AutoSaveRoundUp},
+				{Type: domain.AutoSaveDepositPercentage, PercentageBps: 1000},
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Len(createRes.AutoSave, 2)
+
+	moveRes, err := s.svc.MoveToPot(
+		service.MovePotMoneyRequest{
+			UserID: userID,
+			PotID:  createRes.PotID,
+			Amount: money.New(2000, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(money.New(2000, money.EUR), moveRes.Pot.Balance)
+	s.Assert().Equal(400, moveRes.Pot.GoalProgressBps)
+	s.Assert().Equal(money.New(8000, money.EUR), moveRes.AccountBalance)
+
+	s.assertBalance(userID, accountID, money.New(8000, money.EUR))
+
+	_, err = s.svc.MoveFromPot(
+		service.MovePotMoneyRequest{
+			UserID: userID,
+			PotID:  createRes.PotID,
+			Amount: money.New(3000, money.EUR),
+		},
+	)
+
+	s.Assert().Equal(errors.New("insuficient funds in pot"), err)
+
+	withdrawRes, err := s.svc.Withdraw(
+		service.WithdrawRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    money.New(1230, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+
+	s.assertBalance(userID, accountID, withdrawRes.Balance)
+
+	depositRes, err := s.svc.Deposit(
+		service.DepositRequest{
+			UserID:    userID,
+			AccountID: accountID,
+			Amount:    money.New(5000, money.EUR),
+		},
+	)
+
+	s.Require().Nil(err)
+
+	s.assertBalance(userID, accountID, depositRes.Balance)
+
+	potRes, err := s.svc.Pot(
+		service.PotRequest{
+			UserID: userID,
+			PotID:  createRes.PotID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(money.New(2000+70+500, money.EUR), potRes.Balance)
+
+	_, err = s.svc.Pot(
+		service.PotRequest{
+			UserID: otherUserID,
+			PotID:  createRes.PotID,
+		},
+	)
+
+	s.Assert().Equal(errors.New("pot not found"), err)
+
+	transactionsRes, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	autoSaved := 0
+
+	for _, transaction := range transactionsRes.Transactions {
+		if transaction.Operation == domain.OperationPotIn && transaction.Metadata["auto_save"] != "" {
+			autoSaved++
+		}
+	}
+
+	s.Assert().Equal(2, autoSaved)
+
+	deactivateRes, err := s.svc.DeactivateUser(
+		service.DeactivateUserRequest{
+			UserID:          userID,
+			PayoutUserID:    otherUserID,
+			PayoutAccountID: otherAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(deactivateRes.Accounts, 1)
+
+	closing, err := depositRes.Balance.Add(potRes.Balance)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(closing, deactivateRes.Accounts[0].ClosingBalance)
+	s.Assert().Equal(closing, deactivateRes.Accounts[0].SweptAmount)
+
+	s.assertBalance(otherUserID, otherAccountID, closing)
+
+	potsRes, err := s.svc.Pots(
+		service.PotsRequest{
+			UserID:    otherUserID,
+			AccountID: otherAccountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Empty(potsRes.Pots)
+
+	res, err := s.rec.Run()
+
+	s.Require().Nil(err)
+	s.Assert().True(res.Consistent)
+}
+
+func (s *IntegrationTestSuite) TestCategorisation() {
+	userID, accountID := s.fundedAccount("joe", money.New(10000, money.EUR))
+
+	otherUserID, otherAccountID := s.fundedAccount("jane", money.Money{})
+
+	transferRes, err := s.svc.Transfer(
+		service.TransferRequest{
+			SenderUserID:      userID,
+			ReceiverUserID:    otherUserID,
+			SenderAccountID:   accountID,
+			ReceiverAccountID: otherAccountID,
+			Amount:            money.New(2500, money.EUR),
+			TransactionDetails: service.TransactionDetails{
+				Description: "June rent",
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+
+	transactionRes, err := s.svc.Transaction(
+		service.TransactionRequest{
+			UserID:        userID,
+			TransactionID: transferRes.TransactionID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal("housing", transactionRes.Category)
+
+	rulesRes, err := s.svc.PutCategoryRules(
+		service.PutCategoryRulesRequest{
+			UserID: userID,
+			Rules: []service.CategoryRule{
+				{Category: "family", Counterparty: otherAccountID},
+			},
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Len(rulesRes.Rules, 1)
+	s.Assert().Contains(rulesRes.DefaultCategories, "housing")
+
+	transactionsRes, err := s.svc.Transactions(
+		service.TransactionsRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+
+	categories := map[string]string{}
+
+	for _, transaction := range transactionsRes.Transactions {
+		categories[transaction.ID] = transaction.Category
+	}
+
+	s.Assert().Equal("family", categories[transferRes.TransactionID])
+
+	transactionRes, err = s.svc.CategoriseTransaction(
+		service.CategoriseTransactionRequest{
+			UserID:        userID,
+			TransactionID: transferRes.TransactionID,
+			Category:      "gifts",
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal("gifts", transactionRes.Category)
+
+	analyticsRes, err := s.svc.SpendingAnalytics(
+		service.SpendingAnalyticsRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Require().Len(analyticsRes.Months, 6)
+
+	month := analyticsRes.Months[5]
+
+	s.Assert().Equal(time.Now().UTC().Format("2006-01"), month.Month)
+	s.Assert().Equal(money.New(10000, money.EUR), month.Received)
+	s.Assert().Contains(
+		month.Categories,
+		service.CategoryTotalResponse{
+			Category:     "gifts",
+			Spent:        money.New(2500, money.EUR),
+			SpentDelta:   money.New(2500, money.EUR),
+			Transactions: 1,
+		},
+	)
+	s.Require().NotEmpty(analyticsRes.TopCounterparties)
+	s.Assert().Equal(otherAccountID, analyticsRes.TopCounterparties[0].AccountID)
+
+	transactionRes, err = s.svc.CategoriseTransaction(
+		service.CategoriseTransactionRequest{
+			UserID:        userID,
+			TransactionID: transferRes.TransactionID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal("family", transactionRes.Category)
+
+	_, err = s.svc.PutCategoryRules(
+		service.PutCategoryRulesRequest{
+			UserID: userID,
+			Rules: []service.CategoryRule{
+				{Category: "family"},
+			},
+		},
+	)
+
+	s.Assert().Equal(errors.New("invalid category rule, no conditions"), err)
+}
+
+func (s *IntegrationTestSuite) assertBalance(userID string, accountID string, expected money.Money) {
+	res, err := s.svc.Balance(
+		service.BalanceRequest{
+			UserID:    userID,
+			AccountID: accountID,
+		},
+	)
+
+	s.Require().Nil(err)
+	s.Assert().Equal(expected, res.Balance)
+}
+
+func (s *IntegrationTestSuite) verify(userID string) {
+	_, err := s.svc.SubmitKYC(
+		service.SubmitKYCRequest{
+			UserID:      userID,
+			DateOfBirth: "1990-01-02",
+			Address: service.AddressTerms{
+				Line1:      "1 Main Street",
+				City:       "Lisbon",
+				PostalCode: "1000-001",
+				Country:    "PT",
+			},
+			IDNumber: "X1234567",
+		},
+	)
+
+	s.Require().Nil(err)
+
+	err = s.svc.UpdateUserStatus(
+		service.UpdateUserStatusRequest{
+			UserID: userID,
+			Status: domain.UserVerified,
+		},
+	)
+
+	s.Require().Nil(err)
+}
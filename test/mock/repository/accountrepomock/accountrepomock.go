@@ -2,6 +2,7 @@ package accountrepomock
 
 import (
 	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/money"
 	"github.com/hetfdex/tiny-bank/internal/repository/accountrepo"
 	"github.com/stretchr/testify/mock"
 )
@@ -22,12 +23,42 @@ func (m *Mock) Read(req accountrepo.ReadRequest) (domain.Account, error) {
 	return args.Get(0).(domain.Account), args.Error(1)
 }
 
-func (m *Mock) UpdateBalance(req accountrepo.UpdateBalanceRequest) error {
+func (m *Mock) List(req accountrepo.ListRequest) ([]domain.Account, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Account), args.Error(1)
+}
+
+func (m *Mock) Delete(req accountrepo.DeleteRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) UpdateStatus(req accountrepo.UpdateStatusRequest) error {
 	args := m.Called(req)
 
 	return args.Error(0)
 }
 
+func (m *Mock) UpdateHolders(req accountrepo.UpdateHoldersRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) UpdateRules(req accountrepo.UpdateRulesRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) UpdateBalance(req accountrepo.UpdateBalanceRequest) (money.Money, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(money.Money), args.Error(1)
+}
+
 func (m *Mock) UpdateTransactions(req accountrepo.UpdateTransactionsRequest) error {
 	args := m.Called(req)
 
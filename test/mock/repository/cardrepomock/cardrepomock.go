@@ -0,0 +1,35 @@
+package cardrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/cardrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req cardrepo.CreateRequest) (domain.Card, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Card), args.Error(1)
+}
+
+func (m *Mock) Read(req cardrepo.ReadRequest) (domain.Card, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Card), args.Error(1)
+}
+
+func (m *Mock) List(req cardrepo.ListRequest) ([]domain.Card, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Card), args.Error(1)
+}
+
+func (m *Mock) Update(req cardrepo.UpdateRequest) (domain.Card, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Card), args.Error(1)
+}
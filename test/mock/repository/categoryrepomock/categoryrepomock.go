@@ -0,0 +1,23 @@
+package categoryrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/categoryrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Put(req categoryrepo.PutRequest) (domain.Categorisation, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Categorisation), args.Error(1)
+}
+
+func (m *Mock) Read(req categoryrepo.ReadRequest) (domain.Categorisation, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Categorisation), args.Error(1)
+}
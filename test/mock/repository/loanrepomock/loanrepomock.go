@@ -0,0 +1,41 @@
+package loanrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/loanrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req loanrepo.CreateRequest) (domain.Loan, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Loan), args.Error(1)
+}
+
+func (m *Mock) Read(req loanrepo.ReadRequest) (domain.Loan, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Loan), args.Error(1)
+}
+
+func (m *Mock) List(req loanrepo.ListRequest) ([]domain.Loan, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Loan), args.Error(1)
+}
+
+func (m *Mock) Update(req loanrepo.UpdateRequest) (domain.Loan, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Loan), args.Error(1)
+}
+
+func (m *Mock) Delete(req loanrepo.DeleteRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
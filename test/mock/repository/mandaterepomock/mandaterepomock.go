@@ -0,0 +1,35 @@
+package mandaterepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/mandaterepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req mandaterepo.CreateRequest) (domain.Mandate, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Mandate), args.Error(1)
+}
+
+func (m *Mock) Read(req mandaterepo.ReadRequest) (domain.Mandate, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Mandate), args.Error(1)
+}
+
+func (m *Mock) List(req mandaterepo.ListRequest) ([]domain.Mandate, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Mandate), args.Error(1)
+}
+
+func (m *Mock) Update(req mandaterepo.UpdateRequest) (domain.Mandate, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Mandate), args.Error(1)
+}
@@ -0,0 +1,41 @@
+package payeerepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/payeerepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req payeerepo.CreateRequest) (domain.Payee, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Payee), args.Error(1)
+}
+
+func (m *Mock) Read(req payeerepo.ReadRequest) (domain.Payee, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Payee), args.Error(1)
+}
+
+func (m *Mock) List(req payeerepo.ListRequest) ([]domain.Payee, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Payee), args.Error(1)
+}
+
+func (m *Mock) Update(req payeerepo.UpdateRequest) (domain.Payee, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Payee), args.Error(1)
+}
+
+func (m *Mock) Delete(req payeerepo.DeleteRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
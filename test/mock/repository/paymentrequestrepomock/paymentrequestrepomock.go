@@ -0,0 +1,35 @@
+package paymentrequestrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/paymentrequestrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req paymentrequestrepo.CreateRequest) (domain.PaymentRequest, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.PaymentRequest), args.Error(1)
+}
+
+func (m *Mock) Read(req paymentrequestrepo.ReadRequest) (domain.PaymentRequest, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.PaymentRequest), args.Error(1)
+}
+
+func (m *Mock) List(req paymentrequestrepo.ListRequest) ([]domain.PaymentRequest, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.PaymentRequest), args.Error(1)
+}
+
+func (m *Mock) Update(req paymentrequestrepo.UpdateRequest) (domain.PaymentRequest, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.PaymentRequest), args.Error(1)
+}
@@ -0,0 +1,41 @@
+package pendingtransferrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/pendingtransferrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req pendingtransferrepo.CreateRequest) (domain.PendingTransfer, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.PendingTransfer), args.Error(1)
+}
+
+func (m *Mock) Read(req pendingtransferrepo.ReadRequest) (domain.PendingTransfer, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.PendingTransfer), args.Error(1)
+}
+
+func (m *Mock) List(req pendingtransferrepo.ListRequest) ([]domain.PendingTransfer, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.PendingTransfer), args.Error(1)
+}
+
+func (m *Mock) Update(req pendingtransferrepo.UpdateRequest) (domain.PendingTransfer, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.PendingTransfer), args.Error(1)
+}
+
+func (m *Mock) Delete(req pendingtransferrepo.DeleteRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
@@ -0,0 +1,35 @@
+package potrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/potrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req potrepo.CreateRequest) (domain.Pot, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Pot), args.Error(1)
+}
+
+func (m *Mock) Read(req potrepo.ReadRequest) (domain.Pot, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Pot), args.Error(1)
+}
+
+func (m *Mock) List(req potrepo.ListRequest) ([]domain.Pot, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Pot), args.Error(1)
+}
+
+func (m *Mock) Update(req potrepo.UpdateRequest) (domain.Pot, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Pot), args.Error(1)
+}
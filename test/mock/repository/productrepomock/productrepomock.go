@@ -0,0 +1,35 @@
+package productrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/productrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Create(req productrepo.CreateRequest) (domain.Product, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Product), args.Error(1)
+}
+
+func (m *Mock) Read(req productrepo.ReadRequest) (domain.Product, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Product), args.Error(1)
+}
+
+func (m *Mock) List(req productrepo.ListRequest) ([]domain.Product, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *Mock) Update(req productrepo.UpdateRequest) (domain.Product, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Product), args.Error(1)
+}
@@ -0,0 +1,29 @@
+package tierrepomock
+
+import (
+	"github.com/hetfdex/tiny-bank/internal/domain"
+	"github.com/hetfdex/tiny-bank/internal/repository/tierrepo"
+	"github.com/stretchr/testify/mock"
+)
+
+type Mock struct {
+	mock.Mock
+}
+
+func (m *Mock) Put(req tierrepo.PutRequest) (domain.Tier, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Tier), args.Error(1)
+}
+
+func (m *Mock) Read(req tierrepo.ReadRequest) (domain.Tier, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(domain.Tier), args.Error(1)
+}
+
+func (m *Mock) List(req tierrepo.ListRequest) ([]domain.Tier, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.Tier), args.Error(1)
+}
@@ -22,6 +22,12 @@ func (m *Mock) Read(req userrepo.ReadRequest) (domain.User, error) {
 	return args.Get(0).(domain.User), args.Error(1)
 }
 
+func (m *Mock) List(req userrepo.ListRequest) ([]domain.User, error) {
+	args := m.Called(req)
+
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
 func (m *Mock) UpdateStatus(req userrepo.UpdateStatusRequest) error {
 	args := m.Called(req)
 
@@ -33,3 +39,15 @@ func (m *Mock) UpdateAccountIDs(req userrepo.UpdateAccountIDsRequest) error {
 
 	return args.Error(0)
 }
+
+func (m *Mock) UpdateTier(req userrepo.UpdateTierRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) UpdateKYC(req userrepo.UpdateKYCRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
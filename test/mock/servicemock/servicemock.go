@@ -21,12 +21,24 @@ func (m *Mock) CreateAccount(req service.CreateAccountRequest) (service.CreateAc
 	return args.Get(0).(service.CreateAccountResponse), args.Error(1)
 }
 
-func (m *Mock) DeactivateUser(req service.DeactivateUserRequest) error {
+func (m *Mock) DeactivateUser(req service.DeactivateUserRequest) (service.DeactivateUserResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.DeactivateUserResponse), args.Error(1)
+}
+
+func (m *Mock) ReactivateUser(req service.ReactivateUserRequest) error {
 	args := m.Called(req)
 
 	return args.Error(0)
 }
 
+func (m *Mock) CloseUsers(req service.CloseUsersRequest) (service.CloseUsersResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CloseUsersResponse), args.Error(1)
+}
+
 func (m *Mock) Deposit(req service.DepositRequest) (service.DepositResponse, error) {
 	args := m.Called(req)
 
@@ -56,3 +68,471 @@ func (m *Mock) Transactions(req service.TransactionsRequest) (service.Transactio
 
 	return args.Get(0).(service.TransactionsResponse), args.Error(1)
 }
+
+func (m *Mock) Transaction(req service.TransactionRequest) (service.TransactionResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.TransactionResponse), args.Error(1)
+}
+
+func (m *Mock) AddHolder(req service.AddHolderRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) RemoveHolder(req service.RemoveHolderRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) Holders(req service.HoldersRequest) (service.HoldersResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.HoldersResponse), args.Error(1)
+}
+
+func (m *Mock) UpdateAccountRules(req service.UpdateAccountRulesRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) CreateProduct(req service.CreateProductRequest) (service.ProductResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.ProductResponse), args.Error(1)
+}
+
+func (m *Mock) UpdateProduct(req service.UpdateProductRequest) (service.ProductResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.ProductResponse), args.Error(1)
+}
+
+func (m *Mock) Product(req service.ProductRequest) (service.ProductResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.ProductResponse), args.Error(1)
+}
+
+func (m *Mock) Products(req service.ProductsRequest) (service.ProductsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.ProductsResponse), args.Error(1)
+}
+
+func (m *Mock) BatchTransfer(req service.BatchTransferRequest) (service.BatchTransferResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.BatchTransferResponse), args.Error(1)
+}
+
+func (m *Mock) PutTier(req service.PutTierRequest) (service.TierResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.TierResponse), args.Error(1)
+}
+
+func (m *Mock) Tier(req service.TierRequest) (service.TierResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.TierResponse), args.Error(1)
+}
+
+func (m *Mock) Tiers(req service.TiersRequest) (service.TiersResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.TiersResponse), args.Error(1)
+}
+
+func (m *Mock) UpdateUserTier(req service.UpdateUserTierRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) SubmitKYC(req service.SubmitKYCRequest) (service.KYCResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.KYCResponse), args.Error(1)
+}
+
+func (m *Mock) KYC(req service.KYCRequest) (service.KYCResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.KYCResponse), args.Error(1)
+}
+
+func (m *Mock) UpdateUserStatus(req service.UpdateUserStatusRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) Accounts(req service.AccountsRequest) (service.AccountsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.AccountsResponse), args.Error(1)
+}
+
+func (m *Mock) AddPayee(req service.AddPayeeRequest) (service.PayeeResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PayeeResponse), args.Error(1)
+}
+
+func (m *Mock) Payees(req service.PayeesRequest) (service.PayeesResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PayeesResponse), args.Error(1)
+}
+
+func (m *Mock) Payee(req service.PayeeRequest) (service.PayeeResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PayeeResponse), args.Error(1)
+}
+
+func (m *Mock) UpdatePayee(req service.UpdatePayeeRequest) (service.PayeeResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PayeeResponse), args.Error(1)
+}
+
+func (m *Mock) DeletePayee(req service.DeletePayeeRequest) error {
+	args := m.Called(req)
+
+	return args.Error(0)
+}
+
+func (m *Mock) RequestPayment(req service.RequestPaymentRequest) (service.PaymentRequestResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PaymentRequestResponse), args.Error(1)
+}
+
+func (m *Mock) PaymentRequests(req service.PaymentRequestsRequest) (service.PaymentRequestsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PaymentRequestsResponse), args.Error(1)
+}
+
+func (m *Mock) PaymentRequest(req service.PaymentRequestRequest) (service.PaymentRequestResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PaymentRequestResponse), args.Error(1)
+}
+
+func (m *Mock) AcceptPaymentRequest(req service.AcceptPaymentRequestRequest) (service.PaymentRequestResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PaymentRequestResponse), args.Error(1)
+}
+
+func (m *Mock) DeclinePaymentRequest(req service.DeclinePaymentRequestRequest) (service.PaymentRequestResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PaymentRequestResponse), args.Error(1)
+}
+
+func (m *Mock) CancelPaymentRequest(req service.CancelPaymentRequestRequest) (service.PaymentRequestResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PaymentRequestResponse), args.Error(1)
+}
+
+func (m *Mock) CreatePendingTransfer(req service.CreatePendingTransferRequest) (service.PendingTransferResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PendingTransferResponse), args.Error(1)
+}
+
+func (m *Mock) PendingTransfers(req service.PendingTransfersRequest) (service.PendingTransfersResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PendingTransfersResponse), args.Error(1)
+}
+
+func (m *Mock) PendingTransfer(req service.PendingTransferRequest) (service.PendingTransferResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PendingTransferResponse), args.Error(1)
+}
+
+func (m *Mock) AcceptPendingTransfer(req service.AcceptPendingTransferRequest) (service.PendingTransferResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PendingTransferResponse), args.Error(1)
+}
+
+func (m *Mock) DeclinePendingTransfer(req service.DeclinePendingTransferRequest) (service.PendingTransferResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PendingTransferResponse), args.Error(1)
+}
+
+func (m *Mock) CancelPendingTransfer(req service.CancelPendingTransferRequest) (service.PendingTransferResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PendingTransferResponse), args.Error(1)
+}
+
+func (m *Mock) ExpirePendingTransfers(req service.ExpirePendingTransfersRequest) (service.ExpirePendingTransfersResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.ExpirePendingTransfersResponse), args.Error(1)
+}
+
+func (m *Mock) GrantMandate(req service.GrantMandateRequest) (service.MandateResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.MandateResponse), args.Error(1)
+}
+
+func (m *Mock) Mandates(req service.MandatesRequest) (service.MandatesResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.MandatesResponse), args.Error(1)
+}
+
+func (m *Mock) Mandate(req service.MandateRequest) (service.MandateResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.MandateResponse), args.Error(1)
+}
+
+func (m *Mock) RevokeMandate(req service.RevokeMandateRequest) (service.MandateResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.MandateResponse), args.Error(1)
+}
+
+func (m *Mock) CollectPayment(req service.CollectPaymentRequest) (service.CollectionResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CollectionResponse), args.Error(1)
+}
+
+func (m *Mock) Collections(req service.CollectionsRequest) (service.CollectionsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CollectionsResponse), args.Error(1)
+}
+
+func (m *Mock) Collection(req service.CollectionRequest) (service.CollectionResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CollectionResponse), args.Error(1)
+}
+
+func (m *Mock) RefundCollection(req service.RefundCollectionRequest) (service.CollectionResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CollectionResponse), args.Error(1)
+}
+
+func (m *Mock) IssueCard(req service.IssueCardRequest) (service.IssueCardResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.IssueCardResponse), args.Error(1)
+}
+
+func (m *Mock) Cards(req service.CardsRequest) (service.CardsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardsResponse), args.Error(1)
+}
+
+func (m *Mock) Card(req service.CardRequest) (service.CardResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardResponse), args.Error(1)
+}
+
+func (m *Mock) FreezeCard(req service.FreezeCardRequest) (service.CardResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardResponse), args.Error(1)
+}
+
+func (m *Mock) UnfreezeCard(req service.UnfreezeCardRequest) (service.CardResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardResponse), args.Error(1)
+}
+
+func (m *Mock) CancelCard(req service.CancelCardRequest) (service.CardResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardResponse), args.Error(1)
+}
+
+func (m *Mock) UpdateCardLimits(req service.UpdateCardLimitsRequest) (service.CardResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardResponse), args.Error(1)
+}
+
+func (m *Mock) AuthoriseCard(req service.AuthoriseCardRequest) (service.CardAuthorisationResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardAuthorisationResponse), args.Error(1)
+}
+
+func (m *Mock) CardAuthorisations(req service.CardAuthorisationsRequest) (service.CardAuthorisationsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardAuthorisationsResponse), args.Error(1)
+}
+
+func (m *Mock) CardAuthorisation(req service.CardAuthorisationRequest) (service.CardAuthorisationResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardAuthorisationResponse), args.Error(1)
+}
+
+func (m *Mock) CaptureAuthorisation(req service.CaptureAuthorisationRequest) (service.CardAuthorisationResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardAuthorisationResponse), args.Error(1)
+}
+
+func (m *Mock) ReverseAuthorisation(req service.ReverseAuthorisationRequest) (service.CardAuthorisationResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardAuthorisationResponse), args.Error(1)
+}
+
+func (m *Mock) ExpireCardAuthorisations(req service.ExpireCardAuthorisationsRequest) (service.ExpireCardAuthorisationsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.ExpireCardAuthorisationsResponse), args.Error(1)
+}
+
+func (m *Mock) MerchantAuthorisation(req service.MerchantAuthorisationRequest) (service.CardAuthorisationResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CardAuthorisationResponse), args.Error(1)
+}
+
+func (m *Mock) GrantLoan(req service.GrantLoanRequest) (service.LoanResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.LoanResponse), args.Error(1)
+}
+
+func (m *Mock) Loans(req service.LoansRequest) (service.LoansResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.LoansResponse), args.Error(1)
+}
+
+func (m *Mock) Loan(req service.LoanRequest) (service.LoanResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.LoanResponse), args.Error(1)
+}
+
+func (m *Mock) LoanPayoffQuote(req service.LoanPayoffQuoteRequest) (service.LoanPayoffQuoteResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.LoanPayoffQuoteResponse), args.Error(1)
+}
+
+func (m *Mock) PayOffLoan(req service.PayOffLoanRequest) (service.LoanResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.LoanResponse), args.Error(1)
+}
+
+func (m *Mock) LoanStatement(req service.LoanStatementRequest) (service.LoanStatementResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.LoanStatementResponse), args.Error(1)
+}
+
+func (m *Mock) CollectLoanRepayments(req service.CollectLoanRepaymentsRequest) (service.CollectLoanRepaymentsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CollectLoanRepaymentsResponse), args.Error(1)
+}
+
+func (m *Mock) CreatePot(req service.CreatePotRequest) (service.PotResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotResponse), args.Error(1)
+}
+
+func (m *Mock) Pots(req service.PotsRequest) (service.PotsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotsResponse), args.Error(1)
+}
+
+func (m *Mock) Pot(req service.PotRequest) (service.PotResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotResponse), args.Error(1)
+}
+
+func (m *Mock) UpdatePot(req service.UpdatePotRequest) (service.PotResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotResponse), args.Error(1)
+}
+
+func (m *Mock) ClosePot(req service.ClosePotRequest) (service.PotResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotResponse), args.Error(1)
+}
+
+func (m *Mock) MoveToPot(req service.MovePotMoneyRequest) (service.PotMoveResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotMoveResponse), args.Error(1)
+}
+
+func (m *Mock) MoveFromPot(req service.MovePotMoneyRequest) (service.PotMoveResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotMoveResponse), args.Error(1)
+}
+
+func (m *Mock) PutAutoSaveRules(req service.PutAutoSaveRulesRequest) (service.PotResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.PotResponse), args.Error(1)
+}
+
+func (m *Mock) RunWeeklyAutoSaves(req service.RunWeeklyAutoSavesRequest) (service.RunWeeklyAutoSavesResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.RunWeeklyAutoSavesResponse), args.Error(1)
+}
+
+func (m *Mock) CategoryRules(req service.CategoryRulesRequest) (service.CategoryRulesResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CategoryRulesResponse), args.Error(1)
+}
+
+func (m *Mock) PutCategoryRules(req service.PutCategoryRulesRequest) (service.CategoryRulesResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.CategoryRulesResponse), args.Error(1)
+}
+
+func (m *Mock) CategoriseTransaction(req service.CategoriseTransactionRequest) (service.TransactionResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.TransactionResponse), args.Error(1)
+}
+
+func (m *Mock) SpendingAnalytics(req service.SpendingAnalyticsRequest) (service.SpendingAnalyticsResponse, error) {
+	args := m.Called(req)
+
+	return args.Get(0).(service.SpendingAnalyticsResponse), args.Error(1)
+}